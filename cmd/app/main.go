@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"log"
 	"log/slog"
@@ -10,11 +9,14 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"remnawave-tg-shop-bot/internal/audit"
 	"remnawave-tg-shop-bot/internal/broadcast"
 	cabcfg "remnawave-tg-shop-bot/internal/cabinet/config"
 	cabinethttp "remnawave-tg-shop-bot/internal/cabinet/http"
+	"remnawave-tg-shop-bot/internal/cabinet/mail"
 	cabstartup "remnawave-tg-shop-bot/internal/cabinet/startup"
 	"remnawave-tg-shop-bot/internal/cache"
+	"remnawave-tg-shop-bot/internal/channel"
 	"remnawave-tg-shop-bot/internal/config"
 	"remnawave-tg-shop-bot/internal/cryptopay"
 	"remnawave-tg-shop-bot/internal/database"
@@ -25,11 +27,11 @@ import (
 	"remnawave-tg-shop-bot/internal/platega"
 	"remnawave-tg-shop-bot/internal/promo"
 	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/internal/staff"
 	"remnawave-tg-shop-bot/internal/sync"
 	"remnawave-tg-shop-bot/internal/translation"
 	"remnawave-tg-shop-bot/internal/tribute"
 	"remnawave-tg-shop-bot/internal/yookasa"
-	"strconv"
 	"strings"
 	"time"
 
@@ -96,6 +98,12 @@ func main() {
 		panic(fmt.Errorf("apply runtime settings: %w", err))
 	}
 
+	// Сотрудники админки (роли и права) — до регистрации обработчиков бота и кабинета.
+	if err := staff.Init(ctx, database.NewStaffRepository(pool)); err != nil {
+		panic(fmt.Errorf("load staff: %w", err))
+	}
+	audit.Init(database.NewAdminAuditRepository(pool))
+
 	// Инициализация конфигурации web-кабинета. Делаем сразу после миграций,
 	// чтобы startup-check мог обратиться к уже созданной колонке customer.is_web_only
 	// и чтобы падать рано при невалидных CABINET_* переменных.
@@ -120,6 +128,17 @@ func main() {
 	statsRepository := database.NewStatsRepository(pool)
 	infraBillingRepository := database.NewInfraBillingRepository(pool)
 	loyaltyTierRepository := database.NewLoyaltyTierRepository(pool)
+	purchaseRefundRepository := database.NewPurchaseRefundRepository(pool)       // Журнал возвратов
+	autoRenewRepository := database.NewAutoRenewRepository(pool)                 // Сохранённые карты для автопродления
+	giftRepository := database.NewGiftRepository(pool)                           // Подарочные подписки
+	balanceRepository := database.NewBalanceRepository(pool)                     // Внутренний баланс клиентов
+	partnerRepository := database.NewPartnerRepository(pool)                     // Партнёрская программа: комиссии и выплаты
+	moynalogReceiptRepository := database.NewMoynalogReceiptRepository(pool)     // Очередь чеков «Мой налог»
+	purchaseOutboxRepository := database.NewPurchaseOutboxRepository(pool)       // Шаги проведения оплаченных покупок
+	starsSubscriptionRepository := database.NewStarsSubscriptionRepository(pool) // Подписки Telegram Stars
+	familyRepository := database.NewFamilyRepository(pool)                       // Семейные тарифы
+	subscriptionPauseRepository := database.NewSubscriptionPauseRepository(pool) // Паузы подписок
+	trafficPackRepository := database.NewCustomerTrafficPackRepository(pool)     // Купленные пакеты трафика
 
 	// Инициализация клиентов для работы с внешними сервисами
 	cryptoPayClient := cryptopay.NewCryptoPayClient(config.CryptoPayUrl(), config.CryptoPayToken())                // Криптоплатежи
@@ -150,18 +169,70 @@ func main() {
 	promoService := promo.NewService(promoRepository, customerRepository, purchaseRepository, remnawaveClient)
 
 	// Инициализация сервиса платежей, который объединяет все платежные системы
-	paymentService := payment.NewPaymentService(tm, purchaseRepository, tariffRepository, remnawaveClient, customerRepository, b, cryptoPayClient, yookasaClient, plategaClient, referralRepository, cache, moynalogClient, promoService, loyaltyTierRepository)
+	paymentService := payment.NewPaymentService(tm, purchaseRepository, tariffRepository, remnawaveClient, customerRepository, b, cryptoPayClient, yookasaClient, plategaClient, referralRepository, cache, moynalogClient, promoService, loyaltyTierRepository, purchaseRefundRepository, autoRenewRepository, giftRepository, balanceRepository, partnerRepository, moynalogReceiptRepository, purchaseOutboxRepository, starsSubscriptionRepository, familyRepository, subscriptionPauseRepository, trafficPackRepository)
 
 	// Настройка cron-задачи для проверки статуса счетов (каждые 5 секунд)
-	// CryptoPay; YooKassa и Platega — поллинг только если не задан соответствующий WEBHOOK_URL.
-	cronScheduler := setupInvoiceChecker(purchaseRepository, cryptoPayClient, paymentService, yookasaClient, plategaClient)
+	// Поллинг только для провайдеров без WEBHOOK_URL (CryptoPay, YooKassa, Platega).
+	cronScheduler := setupInvoiceChecker(paymentService)
 	if cronScheduler != nil {
 		cronScheduler.Start()
 		defer cronScheduler.Stop()
 	}
 
+	// Автопродление ЮKassa: раз в час списываем продление с сохранённых карт
+	if config.YookasaAutoRenewEnabled() {
+		autoRenewCronScheduler := autoRenewChecker(paymentService)
+		autoRenewCronScheduler.Start()
+		defer autoRenewCronScheduler.Stop()
+		slog.Info("YooKassa auto renew cron started")
+	}
+
+	// Подписки Telegram Stars: раз в час закрываем те, по которым не пришло очередное списание
+	if config.IsTelegramStarsEnabled() {
+		starsSubscriptionCronScheduler := starsSubscriptionChecker(paymentService)
+		starsSubscriptionCronScheduler.Start()
+		defer starsSubscriptionCronScheduler.Stop()
+	}
+
+	// Outbox проведения оплат: раз в минуту повторяем шаги, которые не выполнились сразу после оплаты
+	purchaseOutboxCronScheduler := purchaseOutboxChecker(paymentService)
+	purchaseOutboxCronScheduler.Start()
+	defer purchaseOutboxCronScheduler.Stop()
+
+	// Паузы подписок: раз в 10 минут возобновляем те, у которых наступил срок.
+	// Работает и при выключенной паузе — уже начатые паузы должны закончиться.
+	subscriptionPauseCronScheduler := subscriptionPauseChecker(paymentService)
+	subscriptionPauseCronScheduler.Start()
+	defer subscriptionPauseCronScheduler.Stop()
+
+	// Трафик: раз в 30 минут откатываем пакеты после сброса трафика, предлагаем пакеты тем, у кого трафик закончился,
+	// и предупреждаем о порогах расхода (TRAFFIC_NOTIFY_THRESHOLDS).
+	// Откат работает и при выключенных пакетах — уже купленные должны сняться после сброса.
+	trafficService := notification.NewTrafficService(customerRepository, paymentService, remnawaveClient, b, tm, notification.NewLifecycleRepository(pool))
+	trafficCronScheduler := trafficChecker(paymentService, trafficService)
+	trafficCronScheduler.Start()
+	defer trafficCronScheduler.Stop()
+
+	// Очередь чеков «Мой налог»: раз в минуту повторяем неотправленные и аннулируем чеки возвращённых покупок
+	if moynalogClient != nil {
+		moynalogReceiptCronScheduler := moynalogReceiptChecker(paymentService)
+		moynalogReceiptCronScheduler.Start()
+		defer moynalogReceiptCronScheduler.Stop()
+	}
+
+	// Сверка зависших покупок: раз в 5 минут спрашиваем у провайдеров статус new/pending-счетов
+	if config.PurchaseReconcileAfterMinutes() > 0 {
+		reconcileCronScheduler := purchaseReconcileChecker(paymentService)
+		reconcileCronScheduler.Start()
+		defer reconcileCronScheduler.Stop()
+		slog.Info("Purchase reconcile cron started", "after_minutes", config.PurchaseReconcileAfterMinutes(), "expire_hours", config.PurchaseExpireHours())
+	}
+
+	// Каналы уведомлений: Telegram, а web-only клиентам кабинета — email (если настроен SMTP кабинета)
+	notifyChannels := notificationChannels(b, customerRepository)
+
 	// Инициализация сервиса уведомлений о подписках
-	subService := notification.NewSubscriptionService(customerRepository, purchaseRepository, paymentService, b, tm)
+	subService := notification.NewSubscriptionService(customerRepository, purchaseRepository, paymentService, notifyChannels, tm)
 	infraBillingNotifyService := notification.NewInfraBillingNotifyService(remnawaveClient, infraBillingRepository, b, tm)
 
 	// Настройка cron-задачи для проверки истечения подписок (каждый день в 16:00)
@@ -169,20 +240,21 @@ func main() {
 	subscriptionNotificationCronScheduler := subscriptionChecker(subService, infraBillingNotifyService)
 	subscriptionNotificationCronScheduler.Start()
 
-	// Lifecycle notifications cron (отдельно от daily 16:00)
+	// Lifecycle notifications cron (отдельно от daily 16:00).
+	// Сервис нужен и без cron: вебхук панели user.first_connected снимает напоминания «не подключился».
+	lifecycleRepo := notification.NewLifecycleRepository(pool)
+	lifecycleService := notification.NewLifecycleService(
+		customerRepository,
+		purchaseRepository,
+		promoRepository,
+		promoService,
+		remnawaveClient,
+		notifyChannels,
+		tm,
+		lifecycleRepo,
+	)
 	var lifecycleCronScheduler *cron.Cron
 	if config.LifecycleNotifyEnabled() {
-		lifecycleRepo := notification.NewLifecycleRepository(pool)
-		lifecycleService := notification.NewLifecycleService(
-			customerRepository,
-			purchaseRepository,
-			promoRepository,
-			promoService,
-			remnawaveClient,
-			b,
-			tm,
-			lifecycleRepo,
-		)
 		lifecycleCronScheduler = lifecycleChecker(lifecycleService)
 		lifecycleCronScheduler.Start()
 		slog.Info("Lifecycle notifications cron started", "schedule", config.LifecycleCron())
@@ -192,8 +264,18 @@ func main() {
 	// Инициализация сервиса синхронизации с Remnawave
 	syncService := sync.NewSyncService(remnawaveClient, customerRepository)
 
+	// Последнее подключение клиентов к VPN (онлайн в панели): раз в час, для фильтров сегментов
+	onlineAtCronScheduler := onlineAtChecker(syncService)
+	onlineAtCronScheduler.Start()
+	defer onlineAtCronScheduler.Stop()
+
+	// Рассылки: задания в broadcast_job, воркер отправляет их в фоне и продолжает после перезапуска.
+	// Один Sender на бот и кабинет — общий лимит отправки в Telegram.
+	broadcastSender := broadcast.NewSender(customerRepository, database.NewBroadcastJobRepository(pool), tm, notifyChannels)
+	go broadcastSender.Run(ctx, b)
+
 	// Создание главного обработчика всех команд и callback'ов бота
-	h := handler.NewHandler(syncService, paymentService, tm, customerRepository, purchaseRepository, tariffRepository, cryptoPayClient, yookasaClient, referralRepository, cache, promoRepository, promoService, remnawaveClient, statsRepository, infraBillingRepository, loyaltyTierRepository)
+	h := handler.NewHandler(syncService, paymentService, tm, customerRepository, purchaseRepository, tariffRepository, cryptoPayClient, yookasaClient, referralRepository, cache, promoRepository, promoService, remnawaveClient, statsRepository, infraBillingRepository, loyaltyTierRepository, broadcastSender)
 
 	// Получение информации о боте (username и т.д.)
 	// Используем контекст с таймаутом для GetMe, чтобы избежать зависания при проблемах с сетью
@@ -279,154 +361,175 @@ func main() {
 	b.RegisterHandler(bot.HandlerTypeMessageText, "/connect", bot.MatchTypeExact, h.ConnectCommandHandler, h.SuspiciousUserFilterMiddleware, h.CreateCustomerIfNotExistMiddleware, h.RequireLegalAcceptanceMiddleware)
 
 	// /sync - команда для синхронизации пользователей с Remnawave (только для админа)
-	b.RegisterHandler(bot.HandlerTypeMessageText, "/sync", bot.MatchTypeExact, h.SyncUsersCommandHandler, isAdminMiddleware)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/sync", bot.MatchTypeExact, h.SyncUsersCommandHandler, staffMiddleware(staff.PermInfra))
 
 	// /broadcast - команда для массовой рассылки сообщений всем пользователям (только для админа)
-	b.RegisterHandler(bot.HandlerTypeMessageText, "/broadcast", bot.MatchTypeExact, h.BroadcastCommandHandler, isAdminMiddleware)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/broadcast", bot.MatchTypeExact, h.BroadcastCommandHandler, staffMiddleware(staff.PermBroadcasts))
 
 	// --- Обработчики callback-кнопок (inline кнопки) ---
 
 	// Callback для выбора типа рассылки (только для админа)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastAll, bot.MatchTypeExact, h.BroadcastTypeSelectHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastActive, bot.MatchTypeExact, h.BroadcastActiveMenuHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastInactive, bot.MatchTypeExact, h.BroadcastInactiveMenuHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastActivePaid, bot.MatchTypeExact, h.BroadcastSegmentPickHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastActiveTrial, bot.MatchTypeExact, h.BroadcastSegmentPickHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastActiveAllSeg, bot.MatchTypeExact, h.BroadcastSegmentPickHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastInactivePaid, bot.MatchTypeExact, h.BroadcastSegmentPickHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastInactiveTrial, bot.MatchTypeExact, h.BroadcastSegmentPickHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastInactiveAllSeg, bot.MatchTypeExact, h.BroadcastSegmentPickHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastBackAudience, bot.MatchTypeExact, h.BroadcastBackToAudienceHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastBackAdmin, bot.MatchTypeExact, h.BroadcastBackToAdminHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "bc_pt_", bot.MatchTypePrefix, h.BroadcastPaidTariffCallbacksHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastAll, bot.MatchTypeExact, h.BroadcastTypeSelectHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastActive, bot.MatchTypeExact, h.BroadcastActiveMenuHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastInactive, bot.MatchTypeExact, h.BroadcastInactiveMenuHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastActivePaid, bot.MatchTypeExact, h.BroadcastSegmentPickHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastActiveTrial, bot.MatchTypeExact, h.BroadcastSegmentPickHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastActiveAllSeg, bot.MatchTypeExact, h.BroadcastSegmentPickHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastInactivePaid, bot.MatchTypeExact, h.BroadcastSegmentPickHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastInactiveTrial, bot.MatchTypeExact, h.BroadcastSegmentPickHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastInactiveAllSeg, bot.MatchTypeExact, h.BroadcastSegmentPickHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastBackAudience, bot.MatchTypeExact, h.BroadcastBackToAudienceHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastBackAdmin, bot.MatchTypeExact, h.BroadcastBackToAdminHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "bc_pt_", bot.MatchTypePrefix, h.BroadcastPaidTariffCallbacksHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
 
 	// Callback для подтверждения рассылки (только для админа)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastConfirm, bot.MatchTypeExact, h.BroadcastConfirmHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastConfirm, bot.MatchTypeExact, h.BroadcastConfirmHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
 
 	// Callback для отмены рассылки (только для админа)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastCancel, bot.MatchTypeExact, h.BroadcastCancelHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastCancel, bot.MatchTypeExact, h.BroadcastCancelHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastSchedule, bot.MatchTypeExact, h.BroadcastScheduleHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
+	// Рассылки-задания: список, прогресс, пауза / продолжение / отмена
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastJobPrefix, bot.MatchTypePrefix, h.BroadcastJobCallbackHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
 
 	// Выбор inline-кнопок под рассылку (только для админа)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastToggleMain, bot.MatchTypeExact, h.BroadcastButtonToggleHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastTogglePromo, bot.MatchTypeExact, h.BroadcastButtonToggleHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastToggleVPN, bot.MatchTypeExact, h.BroadcastButtonToggleHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastToggleBuy, bot.MatchTypeExact, h.BroadcastButtonToggleHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastButtonsNext, bot.MatchTypeExact, h.BroadcastButtonsNextHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastToggleMain, bot.MatchTypeExact, h.BroadcastButtonToggleHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastTogglePromo, bot.MatchTypeExact, h.BroadcastButtonToggleHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastToggleVPN, bot.MatchTypeExact, h.BroadcastButtonToggleHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastToggleBuy, bot.MatchTypeExact, h.BroadcastButtonToggleHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBroadcastButtonsNext, bot.MatchTypeExact, h.BroadcastButtonsNextHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
 
 	// Админ-панель и промокоды
 	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackEnterPromo, bot.MatchTypePrefix, h.EnterPromoCallbackHandler, h.SuspiciousUserFilterMiddleware, h.CreateCustomerIfNotExistMiddleware, h.RequireLegalAcceptanceMiddleware, h.AnswerCallbackQueryMiddleware)
 	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminPanel, bot.MatchTypeExact, h.AdminPanelHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminBroadcast, bot.MatchTypeExact, h.AdminBroadcastShortcutHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminSync, bot.MatchTypeExact, h.AdminSyncShortcutHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminPromo, bot.MatchTypeExact, h.AdminPromoOpenHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminTariffs, bot.MatchTypeExact, h.AdminTariffsHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUsersSubmenu, bot.MatchTypeExact, h.AdminUsersSubmenuHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUsersRoot, bot.MatchTypeExact, h.AdminUsersRootHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUsersSearch, bot.MatchTypeExact, h.AdminUsersSearchHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUsersStatsSection, bot.MatchTypeExact, h.AdminUsersStatsSectionHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUsersInactiveMenu, bot.MatchTypeExact, h.AdminUsersInactiveJumpHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUsersListAllPrefix, bot.MatchTypePrefix, h.AdminUsersListAllRouter, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUsersListInactivePrefix, bot.MatchTypePrefix, h.AdminUsersListInactiveRouter, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUsersListPagePickOpenPrefix, bot.MatchTypePrefix, h.AdminUsersListPagePickerOpenHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUsersListPagePickJumpPrefix, bot.MatchTypePrefix, h.AdminUsersListPagePickerJumpHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPaymentsNotifyUserOpenPrefix, bot.MatchTypePrefix, h.AdminPaymentsNotifyOpenUserHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserManagePrefix, bot.MatchTypePrefix, h.AdminUserManageHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserSubscriptionPrefix, bot.MatchTypePrefix, h.AdminUserSubscriptionHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserExtraHwidDecPrefix, bot.MatchTypePrefix, h.AdminUserExtraHwidDecHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserExtraHwidIncPrefix, bot.MatchTypePrefix, h.AdminUserExtraHwidIncHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserTariffMenuPrefix, bot.MatchTypePrefix, h.AdminUserTariffMenuHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserTariffPickPrefix, bot.MatchTypePrefix, h.AdminUserTariffPickHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserDescAskPrefix, bot.MatchTypePrefix, h.AdminUserDescriptionAskHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserDescClearPrefix, bot.MatchTypePrefix, h.AdminUserDescriptionClearHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserReferralsPrefix, bot.MatchTypePrefix, h.AdminUserReferralsHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserSpendPrefix, bot.MatchTypePrefix, h.AdminUserSpendHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserPaymentsPrefix, bot.MatchTypePrefix, h.AdminUserPaymentsHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserMsgHintPrefix, bot.MatchTypePrefix, h.AdminUserMsgHintHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserExtendPrefix, bot.MatchTypePrefix, h.AdminUserExtendHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserResetTrafficAskPrefix, bot.MatchTypePrefix, h.AdminUserResetTrafficAskHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserResetTrafficConfirmPrefix, bot.MatchTypePrefix, h.AdminUserResetTrafficConfirmHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserHwPresetMenuPrefix, bot.MatchTypePrefix, h.AdminUserHwPresetMenuHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserHwPresetSetPrefix, bot.MatchTypePrefix, h.AdminUserHwPresetSetHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserCalOpenPrefix, bot.MatchTypePrefix, h.AdminUserCalOpenHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserCalNavPrefix, bot.MatchTypePrefix, h.AdminUserCalNavHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserCalPickPrefix, bot.MatchTypePrefix, h.AdminUserCalPickHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserCalBlankPrefix, bot.MatchTypePrefix, h.AdminUserCalBlankHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserCalManualPrefix, bot.MatchTypePrefix, h.AdminUserCalManualHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserPanelMenuPrefix, bot.MatchTypePrefix, h.AdminUserPanelMenuHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserSquadMenuPrefix, bot.MatchTypePrefix, h.AdminUserSquadMenuHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserSquadPickPrefix, bot.MatchTypePrefix, h.AdminUserSquadPickHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserStrategyMenuPrefix, bot.MatchTypePrefix, h.AdminUserStrategyMenuHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserStrategySetPrefix, bot.MatchTypePrefix, h.AdminUserStrategySetHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserTrafficMenuPrefix, bot.MatchTypePrefix, h.AdminUserTrafficMenuHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserTrafficSetPrefix, bot.MatchTypePrefix, h.AdminUserTrafficSetHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserTrafficCustomPrefix, bot.MatchTypePrefix, h.AdminUserTrafficCustomAskHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserDisableAskPrefix, bot.MatchTypePrefix, h.AdminUserDisableAskHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserDisableConfirmPrefix, bot.MatchTypePrefix, h.AdminUserDisableConfirmHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserEnableAskPrefix, bot.MatchTypePrefix, h.AdminUserEnableAskHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserEnableConfirmPrefix, bot.MatchTypePrefix, h.AdminUserEnableConfirmHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserDeleteAskPrefix, bot.MatchTypePrefix, h.AdminUserDeleteAskHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserDeleteConfirmPrefix, bot.MatchTypePrefix, h.AdminUserDeleteConfirmHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserDevicesPrefix, bot.MatchTypePrefix, h.AdminUserDevicesHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserDevDelPrefix, bot.MatchTypePrefix, h.AdminUserDevDelHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminSubsRoot, bot.MatchTypeExact, h.AdminSubsRootHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminSubsListPrefix, bot.MatchTypePrefix, h.AdminSubsListRouter, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminSubsExpiringListPrefix, bot.MatchTypePrefix, h.AdminSubsExpiringListRouter, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminSubsExpiring, bot.MatchTypeExact, h.AdminSubsExpiringHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminSubsStatsJump, bot.MatchTypeExact, h.AdminSubsStatsJumpHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminRefRoot, bot.MatchTypeExact, h.AdminRefRootHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyRoot, bot.MatchTypeExact, h.AdminLoyaltyRootHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyLevels, bot.MatchTypeExact, h.AdminLoyaltyLevelsHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyNew, bot.MatchTypeExact, h.AdminLoyaltyNewHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyRecalcAsk, bot.MatchTypeExact, h.AdminLoyaltyRecalcAskHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyRecalcRun, bot.MatchTypeExact, h.AdminLoyaltyRecalcRunHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyRules, bot.MatchTypeExact, h.AdminLoyaltyRulesHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyStats, bot.MatchTypeExact, h.AdminLoyaltyStatsHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyCard, bot.MatchTypePrefix, h.AdminLoyaltyTierCardHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyDelAsk, bot.MatchTypePrefix, h.AdminLoyaltyDelAskHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyDelYes, bot.MatchTypePrefix, h.AdminLoyaltyDelYesHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyEditXP, bot.MatchTypePrefix, h.AdminLoyaltyEditXPAskHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyEditPct, bot.MatchTypePrefix, h.AdminLoyaltyEditPctAskHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyEditDn, bot.MatchTypePrefix, h.AdminLoyaltyEditDnAskHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminStatsRoot, bot.MatchTypeExact, h.AdminStatsRootHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminStatsUsers, bot.MatchTypeExact, h.AdminStatsUsersHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminStatsSubs, bot.MatchTypeExact, h.AdminStatsSubsHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminStatsRevenue, bot.MatchTypeExact, h.AdminStatsRevenueHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminStatsRef, bot.MatchTypeExact, h.AdminStatsRefHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminStatsSummary, bot.MatchTypeExact, h.AdminStatsSummaryHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminStatsFortune, bot.MatchTypeExact, h.AdminStatsFortuneHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminInfraRoot, bot.MatchTypeExact, h.AdminInfraRootHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminInfraNodes, bot.MatchTypeExact, h.AdminInfraNodesHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminInfraNotify, bot.MatchTypeExact, h.AdminInfraNotifyHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminInfraHist, bot.MatchTypePrefix, h.AdminInfraHistoryHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminInfraProv, bot.MatchTypeExact, h.AdminInfraProvidersHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminInfraToggle, bot.MatchTypePrefix, h.AdminInfraToggleHandler, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "ifn", bot.MatchTypePrefix, h.AdminInfraNodeCRUDRouter, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "ifp", bot.MatchTypePrefix, h.AdminInfraProviderCRUDRouter, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "ifh", bot.MatchTypePrefix, h.AdminInfraHistoryCRUDRouter, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "ifb", bot.MatchTypePrefix, h.AdminInfraWizBackRouter, isAdminMiddleware, h.AnswerCallbackQueryMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackTariffNew, bot.MatchTypeExact, h.AdminTariffNewHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "tf_", bot.MatchTypePrefix, h.AdminTariffCallbackRouter, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoRoot, bot.MatchTypeExact, h.PromoRootHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoList, bot.MatchTypePrefix, h.PromoListHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoNew, bot.MatchTypeExact, h.PromoNewMenuHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoStatsAll, bot.MatchTypeExact, h.PromoStatsAllHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoCard, bot.MatchTypePrefix, h.PromoCardHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoEdit, bot.MatchTypePrefix, h.PromoEditMenuHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoEditValid, bot.MatchTypePrefix, h.PromoEditAskValidHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoEditMax, bot.MatchTypePrefix, h.PromoEditAskMaxHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoEditSubDays, bot.MatchTypePrefix, h.PromoEditAskSubDaysHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoEditTrialDays, bot.MatchTypePrefix, h.PromoEditAskTrialDaysHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoEditSubsTariff, bot.MatchTypePrefix, h.PromoEditSubsTariffMenuHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoEditSubsTariffSet, bot.MatchTypePrefix, h.PromoEditSubsTariffApplyHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoEditDiscPay, bot.MatchTypePrefix, h.PromoEditAskDiscPayHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoNewType, bot.MatchTypePrefix, h.PromoNewTypeHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoDiscKind, bot.MatchTypePrefix, h.PromoDiscountKindHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoSubDaysScope, bot.MatchTypePrefix, h.PromoSubDaysScopeHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoToggle, bot.MatchTypePrefix, h.PromoToggleHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoFirstPur, bot.MatchTypePrefix, h.PromoFirstPurchaseToggle, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoStat, bot.MatchTypePrefix, h.PromoStatHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoDel, bot.MatchTypePrefix, h.PromoDeleteAskHandler, isAdminMiddleware)
-	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoDelYes, bot.MatchTypePrefix, h.PromoDeleteYesHandler, isAdminMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminBroadcast, bot.MatchTypeExact, h.AdminBroadcastShortcutHandler, staffMiddleware(staff.PermBroadcasts), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminSync, bot.MatchTypeExact, h.AdminSyncShortcutHandler, staffMiddleware(staff.PermInfra))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminPromo, bot.MatchTypeExact, h.AdminPromoOpenHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminTariffs, bot.MatchTypeExact, h.AdminTariffsHandler, staffMiddleware(staff.PermTariffs))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminGifts, bot.MatchTypeExact, h.AdminGiftsHandler, staffMiddleware(staff.PermPayments), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminPartnerPayouts, bot.MatchTypeExact, h.AdminPartnerPayoutsHandler, staffMiddleware(staff.PermPayments), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminPayoutApprovePrefix, bot.MatchTypePrefix, h.AdminPayoutApproveHandler, staffMiddleware(staff.PermPayments), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminPayoutRejectPrefix, bot.MatchTypePrefix, h.AdminPayoutRejectHandler, staffMiddleware(staff.PermPayments), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminMoynalogReceipts, bot.MatchTypeExact, h.AdminMoynalogReceiptsHandler, staffMiddleware(staff.PermPayments), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminMoynalogRetryPrefix, bot.MatchTypePrefix, h.AdminMoynalogRetryHandler, staffMiddleware(staff.PermPayments), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminPurchaseOutboxRetryPrefix, bot.MatchTypePrefix, h.AdminPurchaseOutboxRetryHandler, staffMiddleware(staff.PermPayments), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUsersSubmenu, bot.MatchTypeExact, h.AdminUsersSubmenuHandler, staffMiddleware(staff.PermUsersRead), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUsersRoot, bot.MatchTypeExact, h.AdminUsersRootHandler, staffMiddleware(staff.PermUsersRead), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUsersSearch, bot.MatchTypeExact, h.AdminUsersSearchHandler, staffMiddleware(staff.PermUsersRead), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUsersStatsSection, bot.MatchTypeExact, h.AdminUsersStatsSectionHandler, staffMiddleware(staff.PermUsersRead), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUsersInactiveMenu, bot.MatchTypeExact, h.AdminUsersInactiveJumpHandler, staffMiddleware(staff.PermUsersRead), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUsersListAllPrefix, bot.MatchTypePrefix, h.AdminUsersListAllRouter, staffMiddleware(staff.PermUsersRead), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUsersListInactivePrefix, bot.MatchTypePrefix, h.AdminUsersListInactiveRouter, staffMiddleware(staff.PermUsersRead), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUsersListPagePickOpenPrefix, bot.MatchTypePrefix, h.AdminUsersListPagePickerOpenHandler, staffMiddleware(staff.PermUsersRead), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUsersListPagePickJumpPrefix, bot.MatchTypePrefix, h.AdminUsersListPagePickerJumpHandler, staffMiddleware(staff.PermUsersRead), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPaymentsNotifyUserOpenPrefix, bot.MatchTypePrefix, h.AdminPaymentsNotifyOpenUserHandler, staffMiddleware(staff.PermUsersRead))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserManagePrefix, bot.MatchTypePrefix, h.AdminUserManageHandler, staffMiddleware(staff.PermUsersRead), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserSubscriptionPrefix, bot.MatchTypePrefix, h.AdminUserSubscriptionHandler, staffMiddleware(staff.PermUsersRead), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserExtraHwidDecPrefix, bot.MatchTypePrefix, h.AdminUserExtraHwidDecHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserExtraHwidIncPrefix, bot.MatchTypePrefix, h.AdminUserExtraHwidIncHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserTariffMenuPrefix, bot.MatchTypePrefix, h.AdminUserTariffMenuHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserTariffPickPrefix, bot.MatchTypePrefix, h.AdminUserTariffPickHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserDescAskPrefix, bot.MatchTypePrefix, h.AdminUserDescriptionAskHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserDescClearPrefix, bot.MatchTypePrefix, h.AdminUserDescriptionClearHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserReferralsPrefix, bot.MatchTypePrefix, h.AdminUserReferralsHandler, staffMiddleware(staff.PermUsersRead), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserSpendPrefix, bot.MatchTypePrefix, h.AdminUserSpendHandler, staffMiddleware(staff.PermUsersRead), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserPaymentsPrefix, bot.MatchTypePrefix, h.AdminUserPaymentsHandler, staffMiddleware(staff.PermUsersRead), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminRefundAskPrefix, bot.MatchTypePrefix, h.AdminUserRefundAskHandler, staffMiddleware(staff.PermPayments), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminRefundConfirmPrefix, bot.MatchTypePrefix, h.AdminUserRefundConfirmHandler, staffMiddleware(staff.PermPayments), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminRefundBalancePrefix, bot.MatchTypePrefix, h.AdminUserRefundConfirmHandler, staffMiddleware(staff.PermPayments), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserMsgHintPrefix, bot.MatchTypePrefix, h.AdminUserMsgHintHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserExtendPrefix, bot.MatchTypePrefix, h.AdminUserExtendHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserResetTrafficAskPrefix, bot.MatchTypePrefix, h.AdminUserResetTrafficAskHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserResetTrafficConfirmPrefix, bot.MatchTypePrefix, h.AdminUserResetTrafficConfirmHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserHwPresetMenuPrefix, bot.MatchTypePrefix, h.AdminUserHwPresetMenuHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserHwPresetSetPrefix, bot.MatchTypePrefix, h.AdminUserHwPresetSetHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserPartnerMenuPrefix, bot.MatchTypePrefix, h.AdminUserPartnerMenuHandler, staffMiddleware(staff.PermPayments), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserPartnerSetPrefix, bot.MatchTypePrefix, h.AdminUserPartnerSetHandler, staffMiddleware(staff.PermPayments), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserCalOpenPrefix, bot.MatchTypePrefix, h.AdminUserCalOpenHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserCalNavPrefix, bot.MatchTypePrefix, h.AdminUserCalNavHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserCalPickPrefix, bot.MatchTypePrefix, h.AdminUserCalPickHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserCalBlankPrefix, bot.MatchTypePrefix, h.AdminUserCalBlankHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserCalManualPrefix, bot.MatchTypePrefix, h.AdminUserCalManualHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserPanelMenuPrefix, bot.MatchTypePrefix, h.AdminUserPanelMenuHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserSquadMenuPrefix, bot.MatchTypePrefix, h.AdminUserSquadMenuHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserSquadPickPrefix, bot.MatchTypePrefix, h.AdminUserSquadPickHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserStrategyMenuPrefix, bot.MatchTypePrefix, h.AdminUserStrategyMenuHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserStrategySetPrefix, bot.MatchTypePrefix, h.AdminUserStrategySetHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserTrafficMenuPrefix, bot.MatchTypePrefix, h.AdminUserTrafficMenuHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserTrafficSetPrefix, bot.MatchTypePrefix, h.AdminUserTrafficSetHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserTrafficCustomPrefix, bot.MatchTypePrefix, h.AdminUserTrafficCustomAskHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserDisableAskPrefix, bot.MatchTypePrefix, h.AdminUserDisableAskHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserDisableConfirmPrefix, bot.MatchTypePrefix, h.AdminUserDisableConfirmHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserEnableAskPrefix, bot.MatchTypePrefix, h.AdminUserEnableAskHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserEnableConfirmPrefix, bot.MatchTypePrefix, h.AdminUserEnableConfirmHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserDeleteAskPrefix, bot.MatchTypePrefix, h.AdminUserDeleteAskHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserDeleteConfirmPrefix, bot.MatchTypePrefix, h.AdminUserDeleteConfirmHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserDevicesPrefix, bot.MatchTypePrefix, h.AdminUserDevicesHandler, staffMiddleware(staff.PermUsersRead), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminUserDevDelPrefix, bot.MatchTypePrefix, h.AdminUserDevDelHandler, staffMiddleware(staff.PermUsersWrite), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminSubsRoot, bot.MatchTypeExact, h.AdminSubsRootHandler, staffMiddleware(staff.PermUsersRead), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminSubsListPrefix, bot.MatchTypePrefix, h.AdminSubsListRouter, staffMiddleware(staff.PermUsersRead), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminSubsExpiringListPrefix, bot.MatchTypePrefix, h.AdminSubsExpiringListRouter, staffMiddleware(staff.PermUsersRead), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminSubsExpiring, bot.MatchTypeExact, h.AdminSubsExpiringHandler, staffMiddleware(staff.PermUsersRead), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminSubsStatsJump, bot.MatchTypeExact, h.AdminSubsStatsJumpHandler, staffMiddleware(staff.PermUsersRead), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminRefRoot, bot.MatchTypeExact, h.AdminRefRootHandler, staffMiddleware(staff.PermUsersRead), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyRoot, bot.MatchTypeExact, h.AdminLoyaltyRootHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyLevels, bot.MatchTypeExact, h.AdminLoyaltyLevelsHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyNew, bot.MatchTypeExact, h.AdminLoyaltyNewHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyRecalcAsk, bot.MatchTypeExact, h.AdminLoyaltyRecalcAskHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyRecalcRun, bot.MatchTypeExact, h.AdminLoyaltyRecalcRunHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyRules, bot.MatchTypeExact, h.AdminLoyaltyRulesHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyStats, bot.MatchTypeExact, h.AdminLoyaltyStatsHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyCard, bot.MatchTypePrefix, h.AdminLoyaltyTierCardHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyDelAsk, bot.MatchTypePrefix, h.AdminLoyaltyDelAskHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyDelYes, bot.MatchTypePrefix, h.AdminLoyaltyDelYesHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyEditXP, bot.MatchTypePrefix, h.AdminLoyaltyEditXPAskHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyEditPct, bot.MatchTypePrefix, h.AdminLoyaltyEditPctAskHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminLoyaltyEditDn, bot.MatchTypePrefix, h.AdminLoyaltyEditDnAskHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminStatsRoot, bot.MatchTypeExact, h.AdminStatsRootHandler, staffMiddleware(staff.PermStats), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminStatsUsers, bot.MatchTypeExact, h.AdminStatsUsersHandler, staffMiddleware(staff.PermStats), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminStatsSubs, bot.MatchTypeExact, h.AdminStatsSubsHandler, staffMiddleware(staff.PermStats), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminStatsRevenue, bot.MatchTypeExact, h.AdminStatsRevenueHandler, staffMiddleware(staff.PermStats), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminStatsRef, bot.MatchTypeExact, h.AdminStatsRefHandler, staffMiddleware(staff.PermStats), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminStatsSummary, bot.MatchTypeExact, h.AdminStatsSummaryHandler, staffMiddleware(staff.PermStats), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminStatsFortune, bot.MatchTypeExact, h.AdminStatsFortuneHandler, staffMiddleware(staff.PermStats), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminStatsSources, bot.MatchTypeExact, h.AdminStatsSourcesHandler, staffMiddleware(staff.PermStats), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminInfraRoot, bot.MatchTypeExact, h.AdminInfraRootHandler, staffMiddleware(staff.PermInfra), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminInfraNodes, bot.MatchTypeExact, h.AdminInfraNodesHandler, staffMiddleware(staff.PermInfra), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminInfraNotify, bot.MatchTypeExact, h.AdminInfraNotifyHandler, staffMiddleware(staff.PermInfra), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminInfraHist, bot.MatchTypePrefix, h.AdminInfraHistoryHandler, staffMiddleware(staff.PermInfra), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminInfraProv, bot.MatchTypeExact, h.AdminInfraProvidersHandler, staffMiddleware(staff.PermInfra), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAdminInfraToggle, bot.MatchTypePrefix, h.AdminInfraToggleHandler, staffMiddleware(staff.PermInfra), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "ifn", bot.MatchTypePrefix, h.AdminInfraNodeCRUDRouter, staffMiddleware(staff.PermInfra), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "ifp", bot.MatchTypePrefix, h.AdminInfraProviderCRUDRouter, staffMiddleware(staff.PermInfra), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "ifh", bot.MatchTypePrefix, h.AdminInfraHistoryCRUDRouter, staffMiddleware(staff.PermInfra), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "ifb", bot.MatchTypePrefix, h.AdminInfraWizBackRouter, staffMiddleware(staff.PermInfra), h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackTariffNew, bot.MatchTypeExact, h.AdminTariffNewHandler, staffMiddleware(staff.PermTariffs))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "tf_", bot.MatchTypePrefix, h.AdminTariffCallbackRouter, staffMiddleware(staff.PermTariffs))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoRoot, bot.MatchTypeExact, h.PromoRootHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoList, bot.MatchTypePrefix, h.PromoListHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoNew, bot.MatchTypeExact, h.PromoNewMenuHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoStatsAll, bot.MatchTypeExact, h.PromoStatsAllHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoCard, bot.MatchTypePrefix, h.PromoCardHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoEdit, bot.MatchTypePrefix, h.PromoEditMenuHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoEditValid, bot.MatchTypePrefix, h.PromoEditAskValidHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoEditMax, bot.MatchTypePrefix, h.PromoEditAskMaxHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoEditSubDays, bot.MatchTypePrefix, h.PromoEditAskSubDaysHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoEditTrialDays, bot.MatchTypePrefix, h.PromoEditAskTrialDaysHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoEditSubsTariff, bot.MatchTypePrefix, h.PromoEditSubsTariffMenuHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoEditSubsTariffSet, bot.MatchTypePrefix, h.PromoEditSubsTariffApplyHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoEditDiscPay, bot.MatchTypePrefix, h.PromoEditAskDiscPayHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoNewType, bot.MatchTypePrefix, h.PromoNewTypeHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoDiscKind, bot.MatchTypePrefix, h.PromoDiscountKindHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoSubDaysScope, bot.MatchTypePrefix, h.PromoSubDaysScopeHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoToggle, bot.MatchTypePrefix, h.PromoToggleHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoFirstPur, bot.MatchTypePrefix, h.PromoFirstPurchaseToggle, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoStat, bot.MatchTypePrefix, h.PromoStatHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoDel, bot.MatchTypePrefix, h.PromoDeleteAskHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoDelYes, bot.MatchTypePrefix, h.PromoDeleteYesHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoBatchList, bot.MatchTypePrefix, h.PromoBatchListHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoBatchCard, bot.MatchTypePrefix, h.PromoBatchCardHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoBatchExport, bot.MatchTypePrefix, h.PromoBatchExportHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoBatchOffAsk, bot.MatchTypePrefix, h.PromoBatchDeactivateAskHandler, staffMiddleware(staff.PermPromos))
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPromoBatchOffYes, bot.MatchTypePrefix, h.PromoBatchDeactivateYesHandler, staffMiddleware(staff.PermPromos))
 
 	// Callback для реферальной системы
 	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackReferral, bot.MatchTypeExact, h.ReferralCallbackHandler, h.SuspiciousUserFilterMiddleware, h.CreateCustomerIfNotExistMiddleware, h.RequireLegalAcceptanceMiddleware, h.AnswerCallbackQueryMiddleware)
@@ -475,6 +578,24 @@ func main() {
 
 	// Callback для истории операций (с префиксом, т.к. содержит параметры страницы)
 	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPurchaseHistory, bot.MatchTypePrefix, h.PurchaseHistoryCallbackHandler, h.SuspiciousUserFilterMiddleware, h.CreateCustomerIfNotExistMiddleware, h.RequireLegalAcceptanceMiddleware, h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackAutoRenew, bot.MatchTypePrefix, h.AutoRenewCallbackHandler, h.SuspiciousUserFilterMiddleware, h.CreateCustomerIfNotExistMiddleware, h.RequireLegalAcceptanceMiddleware, h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackStarsSubscriptions, bot.MatchTypePrefix, h.StarsSubscriptionsCallbackHandler, h.SuspiciousUserFilterMiddleware, h.CreateCustomerIfNotExistMiddleware, h.RequireLegalAcceptanceMiddleware, h.AnswerCallbackQueryMiddleware)
+
+	// Подарочные подписки: покупка, счёт и активация по коду
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackGift, bot.MatchTypePrefix, h.GiftCallbackHandler, h.SuspiciousUserFilterMiddleware, h.CreateCustomerIfNotExistMiddleware, h.RequireLegalAcceptanceMiddleware, h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackGiftPay, bot.MatchTypePrefix, h.GiftPayCallbackHandler, h.SuspiciousUserFilterMiddleware, h.CreateCustomerIfNotExistMiddleware, h.RequireLegalAcceptanceMiddleware, h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackGiftRedeem, bot.MatchTypePrefix, h.GiftRedeemCallbackHandler, h.SuspiciousUserFilterMiddleware, h.CreateCustomerIfNotExistMiddleware, h.RequireLegalAcceptanceMiddleware, h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackFamily, bot.MatchTypeExact, h.FamilyCallbackHandler, h.SuspiciousUserFilterMiddleware, h.CreateCustomerIfNotExistMiddleware, h.RequireLegalAcceptanceMiddleware, h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackFamilyAction, bot.MatchTypePrefix, h.FamilyActionCallbackHandler, h.SuspiciousUserFilterMiddleware, h.CreateCustomerIfNotExistMiddleware, h.RequireLegalAcceptanceMiddleware, h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPause, bot.MatchTypeExact, h.PauseCallbackHandler, h.SuspiciousUserFilterMiddleware, h.CreateCustomerIfNotExistMiddleware, h.RequireLegalAcceptanceMiddleware, h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPauseAction, bot.MatchTypePrefix, h.PauseActionCallbackHandler, h.SuspiciousUserFilterMiddleware, h.CreateCustomerIfNotExistMiddleware, h.RequireLegalAcceptanceMiddleware, h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackTrafficPacks, bot.MatchTypeExact, h.TrafficPacksCallbackHandler, h.SuspiciousUserFilterMiddleware, h.CreateCustomerIfNotExistMiddleware, h.RequireLegalAcceptanceMiddleware, h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackTrafficPackAction, bot.MatchTypePrefix, h.TrafficPackActionCallbackHandler, h.SuspiciousUserFilterMiddleware, h.CreateCustomerIfNotExistMiddleware, h.RequireLegalAcceptanceMiddleware, h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBalance, bot.MatchTypeExact, h.BalanceCallbackHandler, h.SuspiciousUserFilterMiddleware, h.CreateCustomerIfNotExistMiddleware, h.RequireLegalAcceptanceMiddleware, h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBalanceTopUp, bot.MatchTypePrefix, h.BalanceTopUpCallbackHandler, h.SuspiciousUserFilterMiddleware, h.CreateCustomerIfNotExistMiddleware, h.RequireLegalAcceptanceMiddleware, h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackBalanceTopUpPay, bot.MatchTypePrefix, h.BalanceTopUpPayCallbackHandler, h.SuspiciousUserFilterMiddleware, h.CreateCustomerIfNotExistMiddleware, h.RequireLegalAcceptanceMiddleware, h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPartner, bot.MatchTypeExact, h.PartnerCallbackHandler, h.SuspiciousUserFilterMiddleware, h.CreateCustomerIfNotExistMiddleware, h.RequireLegalAcceptanceMiddleware, h.AnswerCallbackQueryMiddleware)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPartnerPayout, bot.MatchTypePrefix, h.PartnerPayoutCallbackHandler, h.SuspiciousUserFilterMiddleware, h.CreateCustomerIfNotExistMiddleware, h.RequireLegalAcceptanceMiddleware, h.AnswerCallbackQueryMiddleware)
 
 	// Callback для обработки платежей (с префиксом, т.к. содержит параметры)
 	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, handler.CallbackPayment, bot.MatchTypePrefix, h.PaymentCallbackHandler, h.SuspiciousUserFilterMiddleware, h.CreateCustomerIfNotExistMiddleware, h.RequireLegalAcceptanceMiddleware, h.AnswerCallbackQueryMiddleware)
@@ -513,7 +634,7 @@ func main() {
 			return false
 		}
 		// Админ: ввод промокода с главной не пересекается с мастером/редактированием в админке
-		if update.Message.From.ID == config.GetAdminTelegramId() {
+		if staff.IsStaff(update.Message.From.ID) {
 			if handler.AdminPromoWaiting(update.Message.From.ID) || handler.AdminPromoEditWaiting(update.Message.From.ID) {
 				return false
 			}
@@ -546,7 +667,7 @@ func main() {
 		return update.Message != nil &&
 			update.Message.Text != "" &&
 			!strings.HasPrefix(update.Message.Text, "/") &&
-			update.Message.From.ID == config.GetAdminTelegramId() &&
+			staff.IsStaff(update.Message.From.ID) &&
 			update.Message.ReplyToMessage == nil &&
 			(handler.AdminPromoWaiting(update.Message.From.ID) || handler.AdminPromoEditWaiting(update.Message.From.ID)) &&
 			!handler.AdminLoyaltyWaiting(update.Message.From.ID) &&
@@ -561,7 +682,7 @@ func main() {
 		return update.Message != nil &&
 			update.Message.Text != "" &&
 			!strings.HasPrefix(update.Message.Text, "/") &&
-			update.Message.From.ID == config.GetAdminTelegramId() &&
+			staff.IsStaff(update.Message.From.ID) &&
 			update.Message.ReplyToMessage == nil &&
 			(handler.AdminTariffWizardWaiting(update.Message.From.ID) || handler.AdminTariffEditWaiting(update.Message.From.ID)) &&
 			!handler.AdminLoyaltyWaiting(update.Message.From.ID) &&
@@ -576,7 +697,7 @@ func main() {
 		return update.Message != nil &&
 			update.Message.Text != "" &&
 			!strings.HasPrefix(update.Message.Text, "/") &&
-			update.Message.From.ID == config.GetAdminTelegramId() &&
+			staff.IsStaff(update.Message.From.ID) &&
 			update.Message.ReplyToMessage == nil &&
 			handler.AdminLoyaltyWaiting(update.Message.From.ID) &&
 			!handler.AdminUsersSearchWaiting(update.Message.From.ID) &&
@@ -590,7 +711,7 @@ func main() {
 		return update.Message != nil &&
 			update.Message.Text != "" &&
 			!strings.HasPrefix(update.Message.Text, "/") &&
-			update.Message.From.ID == config.GetAdminTelegramId() &&
+			staff.IsStaff(update.Message.From.ID) &&
 			update.Message.ReplyToMessage == nil &&
 			handler.InfraBillingWizardWaiting(update.Message.From.ID) &&
 			!handler.AdminLoyaltyWaiting(update.Message.From.ID) &&
@@ -605,7 +726,7 @@ func main() {
 		return update.Message != nil &&
 			update.Message.Text != "" &&
 			!strings.HasPrefix(update.Message.Text, "/") &&
-			update.Message.From.ID == config.GetAdminTelegramId() &&
+			staff.IsStaff(update.Message.From.ID) &&
 			update.Message.ReplyToMessage == nil &&
 			handler.AdminUsersDMWaiting(update.Message.From.ID) &&
 			!handler.AdminUsersSearchWaiting(update.Message.From.ID) &&
@@ -624,7 +745,7 @@ func main() {
 		return update.Message != nil &&
 			update.Message.Text != "" &&
 			!strings.HasPrefix(update.Message.Text, "/") &&
-			update.Message.From.ID == config.GetAdminTelegramId() &&
+			staff.IsStaff(update.Message.From.ID) &&
 			update.Message.ReplyToMessage == nil &&
 			handler.AdminUserTrafficLimitWaiting(update.Message.From.ID) &&
 			!handler.AdminUsersSearchWaiting(update.Message.From.ID) &&
@@ -643,7 +764,7 @@ func main() {
 		return update.Message != nil &&
 			update.Message.Text != "" &&
 			!strings.HasPrefix(update.Message.Text, "/") &&
-			update.Message.From.ID == config.GetAdminTelegramId() &&
+			staff.IsStaff(update.Message.From.ID) &&
 			update.Message.ReplyToMessage == nil &&
 			handler.AdminUserExpireDateWaiting(update.Message.From.ID) &&
 			!handler.AdminUsersSearchWaiting(update.Message.From.ID) &&
@@ -662,7 +783,7 @@ func main() {
 		return update.Message != nil &&
 			update.Message.Text != "" &&
 			!strings.HasPrefix(update.Message.Text, "/") &&
-			update.Message.From.ID == config.GetAdminTelegramId() &&
+			staff.IsStaff(update.Message.From.ID) &&
 			update.Message.ReplyToMessage == nil &&
 			handler.AdminUserDescriptionWaiting(update.Message.From.ID) &&
 			!handler.AdminUsersSearchWaiting(update.Message.From.ID) &&
@@ -681,7 +802,7 @@ func main() {
 		return update.Message != nil &&
 			update.Message.Text != "" &&
 			!strings.HasPrefix(update.Message.Text, "/") &&
-			update.Message.From.ID == config.GetAdminTelegramId() &&
+			staff.IsStaff(update.Message.From.ID) &&
 			update.Message.ReplyToMessage == nil &&
 			handler.AdminUsersSearchWaiting(update.Message.From.ID) &&
 			!handler.AdminUsersDMWaiting(update.Message.From.ID) &&
@@ -702,7 +823,7 @@ func main() {
 		if update.Message == nil || update.Message.ReplyToMessage != nil {
 			return false
 		}
-		if update.Message.From.ID != config.GetAdminTelegramId() {
+		if !staff.IsStaff(update.Message.From.ID) {
 			return false
 		}
 		if !handler.BroadcastAwaitingMessageInput(update.Message.From.ID) {
@@ -711,13 +832,23 @@ func main() {
 		return handler.BroadcastIncomingDraftMessage(update.Message)
 	}, h.BroadcastMessageHandler)
 
+	// Дата запуска отложенной рассылки («Запланировать» на экране подтверждения)
+	b.RegisterHandlerMatchFunc(func(update *models.Update) bool {
+		return update.Message != nil &&
+			update.Message.Text != "" &&
+			!strings.HasPrefix(update.Message.Text, "/") &&
+			update.Message.ReplyToMessage == nil &&
+			staff.IsStaff(update.Message.From.ID) &&
+			handler.BroadcastAwaitingSchedule(update.Message.From.ID)
+	}, h.BroadcastScheduleInputHandler)
+
 	// Обработчик для неизвестных команд от пользователей
 	// Пересылает все команды пользователей админу для просмотра
 	b.RegisterHandlerMatchFunc(func(update *models.Update) bool {
 		return update.Message != nil &&
 			update.Message.Text != "" &&
 			strings.HasPrefix(update.Message.Text, "/") &&
-			update.Message.From.ID != config.GetAdminTelegramId()
+			!staff.IsStaff(update.Message.From.ID)
 	}, h.ForwardUserMessageToAdmin)
 
 	// Обработчик для обычных текстовых сообщений от пользователей (не команд)
@@ -726,7 +857,7 @@ func main() {
 		return update.Message != nil &&
 			update.Message.Text != "" &&
 			!strings.HasPrefix(update.Message.Text, "/") &&
-			update.Message.From.ID != config.GetAdminTelegramId()
+			!staff.IsStaff(update.Message.From.ID)
 	}, h.ForwardUserMessageToAdmin)
 
 	// Обработчик для reply-сообщений от админа
@@ -749,16 +880,30 @@ func main() {
 	// Проверяет доступность БД и Remnawave API
 	mux.Handle("/healthcheck", fullHealthHandler(pool, remnawaveClient))
 
-	// Webhook для платежной системы Tribute (если включена)
+	// Вебхуки платёжных провайдеров из реестра. Методы Platega делят один путь — регистрируем его один раз.
+	// Tribute зависит от payment, поэтому его обработчик подключаем к реестру здесь.
 	if config.GetTributeWebHookUrl() != "" {
 		tributeHandler := tribute.NewClient(paymentService, customerRepository)
-		mux.Handle(config.GetTributeWebHookUrl(), tributeHandler.WebHookHandler())
+		paymentService.Providers().SetWebhookHandler(database.InvoiceTypeTribute, tributeHandler.WebHookHandler())
 	}
-	if config.IsYookasaEnabled() && strings.TrimSpace(config.GetYookasaWebHookURL()) != "" {
-		mux.Handle(config.GetYookasaWebHookURL(), yookasa.NewWebhookHandler(yookasaClient, paymentService, purchaseRepository))
+	webhookPaths := make(map[string]bool)
+	for _, p := range paymentService.Providers().Enabled() {
+		path, handler := paymentService.Providers().WebhookHandler(p)
+		if path == "" || handler == nil || webhookPaths[path] {
+			continue
+		}
+		webhookPaths[path] = true
+		mux.Handle(path, handler)
+		slog.Info("payment webhook registered", "invoice_type", p.Info().InvoiceType, "path", path)
 	}
-	if config.IsPlategaEnabled() && strings.TrimSpace(config.GetPlategaWebHookURL()) != "" {
-		mux.Handle(config.GetPlategaWebHookURL(), platega.NewWebhookHandler(purchaseRepository, paymentService, config.PlategaMerchantID(), config.PlategaSecret()))
+
+	// Вебхуки Remnawave (user.expired, user.traffic_reached, user.first_connected, user_hwid_devices.added…):
+	// при заданном REMNAWAVE_WEBHOOK_SECRET сверяем клиента с панелью и уведомляем сразу, не дожидаясь cron.
+	if config.RemnawaveWebhookSecret() != "" {
+		panelEventService := notification.NewPanelEventService(customerRepository, database.NewCustomerNotificationRepository(pool),
+			remnawaveClient, trafficService, lifecycleService, b, tm, lifecycleRepo)
+		mux.Handle(config.RemnawaveWebhookPath(), panelEventService.WebhookHandler())
+		slog.Info("remnawave webhook registered", "path", config.RemnawaveWebhookPath())
 	}
 
 	// Web-кабинет: при CABINET_ENABLED=true регистрируем /cabinet/api/*
@@ -766,7 +911,6 @@ func main() {
 	// cabcfg.InitConfig() вызван ранее (сразу после миграций), здесь только
 	// монтируем роуты.
 	if cabcfg.IsEnabled() {
-		broadcastSender := broadcast.NewSender(customerRepository, tm)
 		if err := cabinethttp.Mount(ctx, mux, pool, paymentService, remnawaveClient, promoService, syncService, b, broadcastSender); err != nil {
 			panic(fmt.Errorf("failed to mount cabinet routes: %w", err))
 		}
@@ -850,8 +994,8 @@ func fullHealthHandler(pool *pgxpool.Pool, rw *remnawave.Client) http.Handler {
 	})
 }
 
-// isAdminMiddleware - middleware для проверки, что запрос пришел от администратора
-// Пропускает обработчик только если пользователь является админом
+// isAdminMiddleware - middleware для проверки, что запрос пришел от сотрудника (см. пакет staff)
+// Пропускает обработчик только если пользователь является сотрудником; права на раздел проверяет сам обработчик
 // Работает как с обычными сообщениями, так и с callback queries
 func isAdminMiddleware(next bot.HandlerFunc) bot.HandlerFunc {
 	return func(ctx context.Context, b *bot.Bot, update *models.Update) {
@@ -865,8 +1009,8 @@ func isAdminMiddleware(next bot.HandlerFunc) bot.HandlerFunc {
 			return // Неизвестный тип обновления, пропускаем
 		}
 
-		// Проверяем, является ли пользователь админом
-		if userID == config.GetAdminTelegramId() {
+		// Проверяем, является ли пользователь сотрудником
+		if staff.IsStaff(userID) {
 			next(ctx, b, update) // Пропускаем в обработчик
 		} else {
 			return // Не админ, блокируем запрос
@@ -874,6 +1018,44 @@ func isAdminMiddleware(next bot.HandlerFunc) bot.HandlerFunc {
 	}
 }
 
+// staffMiddleware - как isAdminMiddleware, но пропускает только сотрудников с правом perm
+func staffMiddleware(perm staff.Permission) bot.Middleware {
+	return func(next bot.HandlerFunc) bot.HandlerFunc {
+		return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+			var userID int64
+			if update.Message != nil {
+				userID = update.Message.From.ID
+			} else if update.CallbackQuery != nil {
+				userID = update.CallbackQuery.From.ID
+			} else {
+				return
+			}
+			if staff.Can(userID, perm) {
+				next(ctx, b, update)
+			}
+		}
+	}
+}
+
+// notificationChannels — Telegram для всех клиентов с чатом в боте; email для web-only клиентов,
+// только когда кабинет включён и SMTP настроен (иначе письма не ушли бы, а рассылка считала их доставленными).
+func notificationChannels(b *bot.Bot, customerRepository *database.CustomerRepository) *channel.Router {
+	if !cabcfg.IsEnabled() || !cabcfg.SMTPEnabled() {
+		return channel.NewRouter(channel.NewTelegram(b), nil)
+	}
+	mailer := mail.NewMailer(mail.NewSender(mail.Config{
+		Host:     cabcfg.SMTPHost(),
+		Port:     cabcfg.SMTPPort(),
+		Username: cabcfg.SMTPUser(),
+		Password: cabcfg.SMTPPassword(),
+		From:     cabcfg.MailFrom(),
+		UseTLS:   cabcfg.SMTPTLS(),
+	}))
+	email := channel.NewEmail(customerRepository, mailer, cabcfg.PublicURL(), cabcfg.EmailUnsubscribeSecret())
+	slog.Info("Email notification channel enabled for web-only customers")
+	return channel.NewRouter(channel.NewTelegram(b), email)
+}
+
 // subscriptionChecker - настраивает cron-задачу для проверки истечения подписок
 // Запускается каждый день в 16:00 (формат cron: "0 16 * * *")
 // Отправляет уведомления пользователям об истечении подписки
@@ -918,277 +1100,173 @@ func lifecycleChecker(lifecycleService *notification.LifecycleService) *cron.Cro
 	return c
 }
 
-// initDatabase - инициализирует пул соединений с базой данных PostgreSQL
-// Настраивает максимальное и минимальное количество соединений для оптимизации производительности
-func initDatabase(ctx context.Context, connString string) (*pgxpool.Pool, error) {
-	config, err := pgxpool.ParseConfig(connString)
-	if err != nil {
-		return nil, err
-	}
+// autoRenewChecker - настраивает cron для автопродления с сохранённых способов оплаты
+// Запускается каждый час: подписки, истекающие в пределах YOOKASA_AUTORENEW_DAYS_BEFORE, продлеваются списанием
+func autoRenewChecker(paymentService *payment.PaymentService) *cron.Cron {
+	c := cron.New()
 
-	// Настройка пула соединений
-	config.MaxConns = 20 // Максимальное количество одновременных соединений
-	config.MinConns = 5  // Минимальное количество соединений (поддерживается постоянно)
+	_, err := c.AddFunc("0 * * * *", func() {
+		paymentService.ProcessAutoRenewals(context.Background())
+	})
 
-	return pgxpool.ConnectConfig(ctx, config)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to add auto renew cron job: %v", err))
+	}
+	return c
 }
 
-// setupInvoiceChecker - настраивает cron-задачи для проверки статуса счетов
-// Проверяет оплаченные счета в CryptoPay и YooKassa каждые 5 секунд
-// Если счет оплачен, обрабатывает покупку и активирует подписку
-func setupInvoiceChecker(
-	purchaseRepository *database.PurchaseRepository,
-	cryptoPayClient *cryptopay.Client,
-	paymentService *payment.PaymentService,
-	yookasaClient *yookasa.Client,
-	plategaClient *platega.Client,
-) *cron.Cron {
-	yookPoll := config.IsYookasaEnabled() && strings.TrimSpace(config.GetYookasaWebHookURL()) == ""
-	plategaPoll := config.IsPlategaEnabled() && strings.TrimSpace(config.GetPlategaWebHookURL()) == ""
-	if !config.IsCryptoPayEnabled() && !yookPoll && !plategaPoll {
-		return nil
-	}
-	c := cron.New(cron.WithSeconds()) // Включаем поддержку секунд в расписании
+// starsSubscriptionChecker - настраивает cron для подписок Telegram Stars
+// Запускается каждый час: подписки, очередное списание которых не пришло, помечаются истёкшими
+func starsSubscriptionChecker(paymentService *payment.PaymentService) *cron.Cron {
+	c := cron.New()
 
-	// Задача для проверки счетов CryptoPay (каждые 5 секунд)
-	if config.IsCryptoPayEnabled() {
-		_, err := c.AddFunc("*/5 * * * * *", func() {
-			ctx := context.Background()
-			checkCryptoPayInvoice(ctx, purchaseRepository, cryptoPayClient, paymentService)
-		})
+	_, err := c.AddFunc("30 * * * *", func() {
+		paymentService.ExpireStarsSubscriptions(context.Background())
+	})
 
-		if err != nil {
-			panic(err)
-		}
+	if err != nil {
+		panic(fmt.Sprintf("Failed to add stars subscription cron job: %v", err))
 	}
+	return c
+}
 
-	// Задача для проверки счетов YooKassa (каждые 5 секунд), если нет вебхука
-	if yookPoll {
-		_, err := c.AddFunc("*/5 * * * * *", func() {
-			ctx := context.Background()
-			checkYookasaInvoice(ctx, purchaseRepository, yookasaClient, paymentService)
-		})
+// purchaseOutboxChecker - настраивает cron для outbox проведения оплат
+// Запускается каждую минуту: выполняет шаги оплаченных покупок, у которых подошло время следующей попытки
+func purchaseOutboxChecker(paymentService *payment.PaymentService) *cron.Cron {
+	c := cron.New()
 
-		if err != nil {
-			panic(err)
-		}
-	}
+	_, err := c.AddFunc("* * * * *", func() {
+		paymentService.ProcessPurchaseOutbox(context.Background())
+	})
 
-	if plategaPoll && plategaClient != nil && plategaClient.IsConfigured() {
-		_, err := c.AddFunc("*/5 * * * * *", func() {
-			ctx := context.Background()
-			checkPlategaInvoice(ctx, purchaseRepository, plategaClient, paymentService)
-		})
-		if err != nil {
-			panic(err)
-		}
+	if err != nil {
+		panic(fmt.Sprintf("Failed to add purchase outbox cron job: %v", err))
 	}
-
 	return c
 }
 
-// checkYookasaInvoice - проверяет статус счетов YooKassa
-// Находит все ожидающие оплаты покупки и проверяет их статус в YooKassa
-// Если счет оплачен, обрабатывает покупку и активирует подписку
-func checkYookasaInvoice(
-	ctx context.Context,
-	purchaseRepository *database.PurchaseRepository,
-	yookasaClient *yookasa.Client,
-	paymentService *payment.PaymentService,
-) {
-	// Получаем все покупки со статусом "ожидает оплаты" для YooKassa
-	pendingPurchases, err := purchaseRepository.FindByInvoiceTypeAndStatus(
-		ctx,
-		database.InvoiceTypeYookasa,
-		database.PurchaseStatusPending,
-	)
-	if err != nil {
-		log.Printf("Error finding pending purchases: %v", err)
-		return
-	}
-	if len(*pendingPurchases) == 0 {
-		return // Нет ожидающих покупок
-	}
+// subscriptionPauseChecker - настраивает cron для окончания пауз подписок
+// Запускается каждые 10 минут: включает пользователей, у которых наступил resume_at
+func subscriptionPauseChecker(paymentService *payment.PaymentService) *cron.Cron {
+	c := cron.New()
 
-	// Проверяем каждую покупку
-	for _, purchase := range *pendingPurchases {
-		// Получаем информацию о счете из YooKassa
-		invoice, err := yookasaClient.GetPayment(ctx, *purchase.YookasaID)
+	_, err := c.AddFunc("*/10 * * * *", func() {
+		paymentService.ResumeDuePauses(context.Background())
+	})
 
-		if err != nil {
-			if errors.Is(err, yookasa.ErrPaymentNotFound) {
-				slog.Warn("YooKassa invoice not found, canceling purchase", "invoiceId", purchase.YookasaID, "purchaseId", purchase.ID)
-				if cancelErr := paymentService.CancelYookassaPayment(purchase.ID); cancelErr != nil {
-					slog.Error("Error canceling invoice after not found", "invoiceId", purchase.YookasaID, "purchaseId", purchase.ID, "error", cancelErr)
-				}
-				continue
-			}
-			slog.Error("Error getting invoice", "invoiceId", purchase.YookasaID, "error", err)
-			continue
-		}
+	if err != nil {
+		panic(fmt.Sprintf("Failed to add subscription pause cron job: %v", err))
+	}
+	return c
+}
 
-		// Если счет отменен, отменяем покупку в нашей системе
-		if invoice.IsCancelled() {
-			err := paymentService.CancelYookassaPayment(purchase.ID)
-			if err != nil {
-				slog.Error("Error canceling invoice", "invoiceId", invoice.ID, "purchaseId", purchase.ID, "error", err)
-			}
-			continue
-		}
+// trafficChecker - настраивает cron для трафика
+// Запускается каждые 30 минут: снимает пакеты после сброса трафика в панели, затем шлёт уведомления
+// «трафик закончился» и о порогах расхода
+func trafficChecker(paymentService *payment.PaymentService, trafficService *notification.TrafficService) *cron.Cron {
+	c := cron.New()
 
-		// Если счет еще не оплачен, пропускаем
-		if !invoice.Paid {
-			continue
+	_, err := c.AddFunc("*/30 * * * *", func() {
+		ctx := context.Background()
+		paymentService.RollbackTrafficPacks(ctx)
+		if err := trafficService.ProcessTrafficNotifications(ctx); err != nil {
+			slog.Error("Error processing traffic notifications", "error", err)
 		}
+	})
 
-		// Счет оплачен - обрабатываем текущую pending-покупку.
-		// Не зависим от invoice.Metadata["purchaseId"], т.к. некоторые провайдеры/ответы
-		// могут вернуть metadata в нестабильном формате и это приводило к "eternal pending".
-		username := ""
-		if invoice.Metadata != nil {
-			username = invoice.Metadata["username"]
-		}
-		ctxWithValue := context.WithValue(ctx, remnawave.CtxKeyUsername, username)
-		purchaseId := int(purchase.ID)
-		err = paymentService.ProcessPurchaseById(ctxWithValue, purchase.ID)
-		if err != nil {
-			slog.Error("Error processing invoice", "invoiceId", invoice.ID, "purchaseId", purchaseId, "error", err)
-		} else {
-			slog.Info("Invoice processed", "invoiceId", invoice.ID, "purchaseId", purchaseId)
-		}
+	if err != nil {
+		panic(fmt.Sprintf("Failed to add traffic pack cron job: %v", err))
 	}
+	return c
 }
 
-// checkPlategaInvoice — поллинг pending-покупок Platega (если не используется вебхук).
-func checkPlategaInvoice(
-	ctx context.Context,
-	purchaseRepository *database.PurchaseRepository,
-	plategaClient *platega.Client,
-	paymentService *payment.PaymentService,
-) {
-	if plategaClient == nil || !plategaClient.IsConfigured() {
-		return
-	}
-	for _, inv := range database.PlategaInvoiceTypes() {
-		pendingPurchases, err := purchaseRepository.FindByInvoiceTypeAndStatus(
-			ctx,
-			inv,
-			database.PurchaseStatusPending,
-		)
-		if err != nil {
-			slog.Error("platega poll: find pending", "invoice_type", inv, "error", err)
-			continue
-		}
-		if pendingPurchases == nil || len(*pendingPurchases) == 0 {
-			continue
-		}
-		for _, purchase := range *pendingPurchases {
-			if purchase.PlategaID == nil || strings.TrimSpace(*purchase.PlategaID) == "" {
-				continue
-			}
-			tx, err := plategaClient.GetTransaction(ctx, strings.TrimSpace(*purchase.PlategaID))
-			if err != nil {
-				var apiErr *platega.APIError
-				if errors.As(err, &apiErr) && apiErr.IsNotFound() {
-					slog.Warn("Platega transaction not found, canceling purchase", "tx", *purchase.PlategaID, "purchaseId", purchase.ID)
-					if cancelErr := paymentService.CancelPlategaPayment(purchase.ID); cancelErr != nil {
-						slog.Error("Error canceling platega purchase after not found", "purchaseId", purchase.ID, "error", cancelErr)
-					}
-					continue
-				}
-				slog.Error("platega poll: get transaction", "tx", *purchase.PlategaID, "error", err)
-				continue
-			}
-			switch tx.Status {
-			case platega.StatusCanceled, platega.StatusChargebacked:
-				if cancelErr := paymentService.CancelPlategaPayment(purchase.ID); cancelErr != nil {
-					slog.Error("platega poll: cancel", "purchaseId", purchase.ID, "error", cancelErr)
-				}
-			case platega.StatusConfirmed:
-				if err := paymentService.ProcessPurchaseById(ctx, purchase.ID); err != nil {
-					slog.Error("platega poll: process purchase", "purchaseId", purchase.ID, "error", err)
-				} else {
-					slog.Info("platega poll: processed", "purchaseId", purchase.ID)
-				}
-			default:
-			}
-		}
+// onlineAtChecker - настраивает cron для обновления customer.online_at
+// Запускается каждый час: берёт onlineAt пользователей панели (фильтры сегментов по последнему подключению)
+func onlineAtChecker(syncService *sync.SyncService) *cron.Cron {
+	c := cron.New()
+
+	_, err := c.AddFunc("15 * * * *", func() {
+		syncService.SyncOnlineAt(context.Background())
+	})
+
+	if err != nil {
+		panic(fmt.Sprintf("Failed to add online_at cron job: %v", err))
 	}
+	return c
 }
 
-// checkCryptoPayInvoice - проверяет статус счетов CryptoPay
-// Находит все ожидающие оплаты покупки и проверяет их статус в CryptoPay
-// Если счет оплачен, обрабатывает покупку и активирует подписку
-func checkCryptoPayInvoice(
-	ctx context.Context,
-	purchaseRepository *database.PurchaseRepository,
-	cryptoPayClient *cryptopay.Client,
-	paymentService *payment.PaymentService,
-) {
-	// Получаем все покупки со статусом "ожидает оплаты" для CryptoPay
-	pendingPurchases, err := purchaseRepository.FindByInvoiceTypeAndStatus(
-		ctx,
-		database.InvoiceTypeCrypto,
-		database.PurchaseStatusPending,
-	)
+// moynalogReceiptChecker - настраивает cron для очереди чеков «Мой налог»
+// Запускается каждую минуту: берёт чеки, у которых подошло время следующей попытки
+func moynalogReceiptChecker(paymentService *payment.PaymentService) *cron.Cron {
+	c := cron.New()
+
+	_, err := c.AddFunc("* * * * *", func() {
+		paymentService.ProcessMoynalogReceipts(context.Background())
+	})
+
 	if err != nil {
-		log.Printf("Error finding pending purchases: %v", err)
-		return
-	}
-	if len(*pendingPurchases) == 0 {
-		return // Нет ожидающих покупок
+		panic(fmt.Sprintf("Failed to add moynalog receipt cron job: %v", err))
 	}
+	return c
+}
 
-	// Собираем ID всех счетов для массовой проверки
-	var invoiceIDs []string
-	for _, purchase := range *pendingPurchases {
-		if purchase.CryptoInvoiceID != nil {
-			invoiceIDs = append(invoiceIDs, fmt.Sprintf("%d", *purchase.CryptoInvoiceID))
-		}
-	}
+// purchaseReconcileChecker - настраивает cron для сверки зависших покупок с провайдерами
+// Запускается каждые 5 минут: проводит потерянные оплаты, закрывает отменённые и просроченные счета
+func purchaseReconcileChecker(paymentService *payment.PaymentService) *cron.Cron {
+	c := cron.New()
 
-	if len(invoiceIDs) == 0 {
-		return
+	_, err := c.AddFunc("*/5 * * * *", func() {
+		paymentService.ReconcileStalePurchases(context.Background())
+	})
+
+	if err != nil {
+		panic(fmt.Sprintf("Failed to add purchase reconcile cron job: %v", err))
 	}
+	return c
+}
 
-	// Запрашиваем статус всех счетов одним запросом (оптимизация)
-	stringInvoiceIDs := strings.Join(invoiceIDs, ",")
-	invoices, err := cryptoPayClient.GetInvoices("", "", "", stringInvoiceIDs, 0, 0)
+// initDatabase - инициализирует пул соединений с базой данных PostgreSQL
+// Настраивает максимальное и минимальное количество соединений для оптимизации производительности
+func initDatabase(ctx context.Context, connString string) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(connString)
 	if err != nil {
-		log.Printf("Error getting invoices: %v", err)
-		return
+		return nil, err
 	}
 
-	// Обрабатываем оплаченные счета
-	for _, invoice := range *invoices {
-		if invoice.InvoiceID != nil && invoice.IsPaid() {
-			// Извлекаем данные из payload счета (формат: "purchaseId=123&username=user")
-			payload := strings.Split(invoice.Payload, "&")
-			purchaseID, err := strconv.Atoi(strings.Split(payload[0], "=")[1])
-			username := strings.Split(payload[1], "=")[1]
-			// Передаем username в контексте для логирования
-			ctxWithUsername := context.WithValue(ctx, remnawave.CtxKeyUsername, username)
-			feeAmt := ""
-			if invoice.FeeAmount != nil {
-				feeAmt = *invoice.FeeAmount
-			}
-			ctxPaid := payment.WithCryptoNotifyMeta(ctxWithUsername, payment.CryptoNotifyMeta{
-				Hash:          invoice.Hash,
-				Status:        invoice.Status,
-				CurrencyType:  invoice.CurrencyType,
-				Asset:         invoice.Asset,
-				PaidAsset:     invoice.PaidAsset,
-				PaidAmount:    invoice.PaidAmount,
-				PayUrl:        invoice.PayUrl,
-				BotInvoiceUrl: invoice.BotInvoiceUrl,
-				FeeAmount:     feeAmt,
-			})
-			err = paymentService.ProcessPurchaseById(ctxPaid, int64(purchaseID))
-			if err != nil {
-				slog.Error("Error processing invoice", "invoiceId", invoice.InvoiceID, "purchaseId", purchaseID, "error", err)
-			} else {
-				slog.Info("Invoice processed", "invoiceId", invoice.InvoiceID, "purchaseId", purchaseID)
-			}
+	// Настройка пула соединений
+	config.MaxConns = 20 // Максимальное количество одновременных соединений
+	config.MinConns = 5  // Минимальное количество соединений (поддерживается постоянно)
+
+	return pgxpool.ConnectConfig(ctx, config)
+}
+
+// setupInvoiceChecker - настраивает cron-задачи для проверки статуса счетов
+// Для каждого включённого провайдера с поллингом и без WEBHOOK_URL опрашивает pending-счета каждые 5 секунд.
+// Если счет оплачен, обрабатывает покупку и активирует подписку
+func setupInvoiceChecker(paymentService *payment.PaymentService) *cron.Cron {
+	var polled []payment.Provider
+	for _, p := range paymentService.Providers().Enabled() {
+		if !p.Info().Pollable {
+			continue
+		}
+		if path, _ := p.Webhook(); path != "" {
+			continue
 		}
+		polled = append(polled, p)
 	}
+	if len(polled) == 0 {
+		return nil
+	}
+	c := cron.New(cron.WithSeconds()) // Включаем поддержку секунд в расписании
+	for _, p := range polled {
+		p := p
+		_, err := c.AddFunc("*/5 * * * * *", func() {
+			paymentService.PollPendingInvoices(context.Background(), p)
+		})
+		if err != nil {
+			panic(err)
+		}
+		slog.Info("invoice polling enabled", "invoice_type", p.Info().InvoiceType)
+	}
+	return c
 }
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS purchase_refund;
+
+ALTER TABLE purchase DROP COLUMN IF EXISTS refunded_at;
+ALTER TABLE purchase DROP COLUMN IF EXISTS refunded_amount;
+ALTER TABLE purchase DROP COLUMN IF EXISTS telegram_charge_id;
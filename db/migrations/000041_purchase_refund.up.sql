@@ -0,0 +1,32 @@
+-- Возвраты платежей: сколько уже возвращено по покупке и charge id Telegram Stars (нужен для refundStarPayment).
+ALTER TABLE purchase ADD COLUMN IF NOT EXISTS telegram_charge_id TEXT;
+ALTER TABLE purchase ADD COLUMN IF NOT EXISTS refunded_amount DECIMAL(20, 8) NOT NULL DEFAULT 0;
+ALTER TABLE purchase ADD COLUMN IF NOT EXISTS refunded_at TIMESTAMPTZ;
+
+-- Журнал возвратов: одна строка на каждую попытку (полный или частичный возврат).
+-- status: pending → succeeded (провайдер подтвердил) / manual (у провайдера нет API, деньги возвращаются вручную) / failed.
+CREATE TABLE IF NOT EXISTS purchase_refund (
+    id                 BIGSERIAL PRIMARY KEY,
+    purchase_id        BIGINT         NOT NULL REFERENCES purchase (id) ON DELETE CASCADE,
+    customer_id        BIGINT         NOT NULL REFERENCES customer (id) ON DELETE CASCADE,
+    amount             DECIMAL(20, 8) NOT NULL,
+    currency           VARCHAR(10),
+    status             VARCHAR(20)    NOT NULL DEFAULT 'pending',
+    provider_refund_id TEXT,
+    reason             TEXT,
+    source             VARCHAR(20)    NOT NULL,
+    admin_telegram_id  BIGINT,
+    admin_account_id   BIGINT,
+    days_revoked       INTEGER        NOT NULL DEFAULT 0,
+    hwid_revoked       INTEGER        NOT NULL DEFAULT 0,
+    xp_revoked         BIGINT         NOT NULL DEFAULT 0,
+    promo_restored     BOOLEAN        NOT NULL DEFAULT FALSE,
+    error              TEXT,
+    created_at         TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    completed_at       TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_refund_purchase ON purchase_refund (purchase_id);
+-- Не больше одного незавершённого возврата на покупку (защита от двойного клика / двух админов).
+CREATE UNIQUE INDEX IF NOT EXISTS uq_purchase_refund_pending ON purchase_refund (purchase_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_purchase_refund_created ON purchase_refund (created_at DESC);
@@ -0,0 +1,4 @@
+-- Список провайдеров кабинета задаёт реестр payment.ProviderRegistry (ProviderInfo.CheckoutKey):
+-- CHECK в БД больше не нужно править при подключении нового способа оплаты.
+ALTER TABLE cabinet_checkout
+    DROP CONSTRAINT IF EXISTS cabinet_checkout_provider_chk;
@@ -0,0 +1,3 @@
+ALTER TABLE purchase DROP COLUMN IF EXISTS is_auto_renew;
+
+DROP TABLE IF EXISTS customer_auto_renew;
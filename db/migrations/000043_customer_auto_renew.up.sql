@@ -0,0 +1,22 @@
+-- Автопродление: сохранённый у провайдера способ оплаты (ЮKassa payment_method.id) и состояние повторных попыток.
+-- Одна строка на клиента; enabled — переключатель клиента, способ оплаты остаётся сохранённым при выключении.
+CREATE TABLE IF NOT EXISTS customer_auto_renew (
+    customer_id          BIGINT PRIMARY KEY REFERENCES customer (id) ON DELETE CASCADE,
+    invoice_type         VARCHAR(20)  NOT NULL,
+    payment_method_id    TEXT         NOT NULL,
+    payment_method_title TEXT,
+    enabled              BOOLEAN      NOT NULL DEFAULT TRUE,
+    months               INTEGER      NOT NULL,
+    tariff_id            BIGINT,
+    failed_attempts      INTEGER      NOT NULL DEFAULT 0,
+    next_attempt_at      TIMESTAMPTZ,
+    last_error           TEXT,
+    last_charged_at      TIMESTAMPTZ,
+    created_at           TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at           TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_auto_renew_enabled ON customer_auto_renew (enabled) WHERE enabled;
+
+-- Покупка создана автосписанием (без участия клиента).
+ALTER TABLE purchase ADD COLUMN IF NOT EXISTS is_auto_renew BOOLEAN NOT NULL DEFAULT FALSE;
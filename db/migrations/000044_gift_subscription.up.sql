@@ -0,0 +1,19 @@
+-- Подарочные подписки: оплаченная покупка purchase_kind = 'gift' выпускает одноразовый код.
+-- Код активирует получатель (/start gift_<code>); дни начисляются ему, а не покупателю.
+-- status: active → redeemed / revoked (возврат денег до активации). Просроченные — active с expires_at в прошлом.
+CREATE TABLE IF NOT EXISTS gift_subscription (
+    id                      BIGSERIAL PRIMARY KEY,
+    code                    VARCHAR(32) NOT NULL UNIQUE,
+    purchase_id             BIGINT      NOT NULL UNIQUE REFERENCES purchase (id) ON DELETE CASCADE,
+    buyer_customer_id       BIGINT      NOT NULL REFERENCES customer (id) ON DELETE CASCADE,
+    months                  INTEGER     NOT NULL,
+    tariff_id               BIGINT REFERENCES tariff (id) ON DELETE SET NULL,
+    status                  VARCHAR(16) NOT NULL DEFAULT 'active',
+    expires_at              TIMESTAMPTZ NOT NULL,
+    redeemed_by_customer_id BIGINT REFERENCES customer (id) ON DELETE SET NULL,
+    redeemed_at             TIMESTAMPTZ,
+    created_at              TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_gift_subscription_buyer ON gift_subscription (buyer_customer_id);
+CREATE INDEX IF NOT EXISTS idx_gift_subscription_created ON gift_subscription (created_at DESC);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS customer_balance_tx;
+DROP TABLE IF EXISTS customer_balance;
@@ -0,0 +1,30 @@
+-- Внутренний баланс клиента (кошелёк) в рублях: текущий остаток и журнал операций.
+-- Остаток хранится отдельно от журнала, чтобы списание было одним атомарным UPDATE с проверкой balance >= 0.
+CREATE TABLE IF NOT EXISTS customer_balance (
+    customer_id BIGINT PRIMARY KEY REFERENCES customer (id) ON DELETE CASCADE,
+    balance     DECIMAL(20, 2) NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    updated_at  TIMESTAMPTZ    NOT NULL DEFAULT NOW()
+);
+
+-- type: topup | purchase | refund | referral | admin. amount со знаком: + зачисление, − списание.
+CREATE TABLE IF NOT EXISTS customer_balance_tx (
+    id                BIGSERIAL PRIMARY KEY,
+    customer_id       BIGINT         NOT NULL REFERENCES customer (id) ON DELETE CASCADE,
+    type              VARCHAR(20)    NOT NULL,
+    amount            DECIMAL(20, 2) NOT NULL,
+    balance_after     DECIMAL(20, 2) NOT NULL,
+    purchase_id       BIGINT REFERENCES purchase (id) ON DELETE SET NULL,
+    refund_id         BIGINT REFERENCES purchase_refund (id) ON DELETE SET NULL,
+    comment           TEXT,
+    admin_telegram_id BIGINT,
+    admin_account_id  BIGINT,
+    created_at        TIMESTAMPTZ    NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_balance_tx_customer ON customer_balance_tx (customer_id, created_at DESC);
+
+-- Идемпотентность: пополнение и оплата проводятся по покупке ровно один раз (повторы вебхуков и поллера).
+CREATE UNIQUE INDEX IF NOT EXISTS uq_customer_balance_tx_purchase
+    ON customer_balance_tx (purchase_id, type) WHERE purchase_id IS NOT NULL AND type IN ('topup', 'purchase');
+CREATE UNIQUE INDEX IF NOT EXISTS uq_customer_balance_tx_refund
+    ON customer_balance_tx (refund_id) WHERE refund_id IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS referral_payout;
+DROP TABLE IF EXISTS referral_commission;
+DROP TABLE IF EXISTS referral_partner;
@@ -0,0 +1,53 @@
+-- Партнёрская реферальная программа: процент с каждой оплаты приглашённых, выплаты по запросу партнёра.
+-- Персональная ставка реферера; без строки действует REFERRAL_PARTNER_DEFAULT_PERCENT.
+CREATE TABLE IF NOT EXISTS referral_partner (
+    customer_id        BIGINT PRIMARY KEY REFERENCES customer (id) ON DELETE CASCADE,
+    commission_percent DECIMAL(5, 2) NOT NULL CHECK (commission_percent >= 0 AND commission_percent <= 100),
+    enabled            BOOLEAN       NOT NULL DEFAULT TRUE,
+    note               TEXT,
+    created_at         TIMESTAMPTZ   NOT NULL DEFAULT NOW(),
+    updated_at         TIMESTAMPTZ   NOT NULL DEFAULT NOW()
+);
+
+-- Начисление партнёру: одна строка на оплаченную покупку реферала.
+-- reversed_amount растёт при возвратах и отмене покупки; status = reversed, когда сторнировано всё.
+-- available_at — конец холда: до него сумма не доступна к выплате.
+CREATE TABLE IF NOT EXISTS referral_commission (
+    id                  BIGSERIAL PRIMARY KEY,
+    partner_customer_id BIGINT         NOT NULL REFERENCES customer (id) ON DELETE CASCADE,
+    referee_customer_id BIGINT         NOT NULL REFERENCES customer (id) ON DELETE CASCADE,
+    purchase_id         BIGINT         NOT NULL REFERENCES purchase (id) ON DELETE CASCADE,
+    purchase_amount     DECIMAL(20, 2) NOT NULL,
+    commission_percent  DECIMAL(5, 2)  NOT NULL,
+    amount              DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    reversed_amount     DECIMAL(20, 2) NOT NULL DEFAULT 0,
+    status              VARCHAR(20)    NOT NULL DEFAULT 'accrued',
+    available_at        TIMESTAMPTZ    NOT NULL,
+    created_at          TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    reversed_at         TIMESTAMPTZ,
+    CONSTRAINT uq_referral_commission_purchase UNIQUE (purchase_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_referral_commission_partner ON referral_commission (partner_customer_id, created_at DESC);
+
+-- Запрос партнёра на выплату. method: balance — на внутренний баланс, manual — админ переводит сам по details.
+-- status: pending → paid | rejected.
+CREATE TABLE IF NOT EXISTS referral_payout (
+    id                  BIGSERIAL PRIMARY KEY,
+    partner_customer_id BIGINT         NOT NULL REFERENCES customer (id) ON DELETE CASCADE,
+    amount              DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    method              VARCHAR(20)    NOT NULL,
+    details             TEXT,
+    status              VARCHAR(20)    NOT NULL DEFAULT 'pending',
+    admin_comment       TEXT,
+    admin_telegram_id   BIGINT,
+    admin_account_id    BIGINT,
+    created_at          TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    processed_at        TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_referral_payout_status ON referral_payout (status, created_at);
+CREATE INDEX IF NOT EXISTS idx_referral_payout_partner ON referral_payout (partner_customer_id, created_at DESC);
+-- Не больше одного необработанного запроса у партнёра.
+CREATE UNIQUE INDEX IF NOT EXISTS uq_referral_payout_pending
+    ON referral_payout (partner_customer_id) WHERE status = 'pending';
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS moynalog_receipt;
@@ -0,0 +1,28 @@
+-- Очередь чеков «Мой налог»: один чек на оплаченную покупку, отправка и аннулирование с повторами.
+-- status: pending → sent | failed; при возврате sent → cancel_pending → canceled | cancel_failed.
+-- Неотправленный чек при возврате просто закрывается (canceled без receipt_uuid).
+-- next_attempt_at — когда воркер возьмёт чек в работу; attempts — неудачные попытки текущей операции.
+CREATE TABLE IF NOT EXISTS moynalog_receipt (
+    id              BIGSERIAL PRIMARY KEY,
+    purchase_id     BIGINT         NOT NULL REFERENCES purchase (id) ON DELETE CASCADE,
+    amount          DECIMAL(20, 2) NOT NULL CHECK (amount > 0),
+    description     TEXT           NOT NULL,
+    operation_time  TIMESTAMPTZ    NOT NULL,
+    status          VARCHAR(20)    NOT NULL DEFAULT 'pending',
+    receipt_uuid    VARCHAR(64),
+    attempts        INT            NOT NULL DEFAULT 0,
+    last_error      TEXT,
+    next_attempt_at TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    sent_at         TIMESTAMPTZ,
+    canceled_at     TIMESTAMPTZ,
+    CONSTRAINT uq_moynalog_receipt_purchase UNIQUE (purchase_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_moynalog_receipt_due
+    ON moynalog_receipt (next_attempt_at)
+    WHERE status IN ('pending', 'cancel_pending');
+
+CREATE INDEX IF NOT EXISTS idx_moynalog_receipt_status
+    ON moynalog_receipt (status, updated_at DESC);
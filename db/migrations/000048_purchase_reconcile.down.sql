@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_purchase_unfinished;
+
+ALTER TABLE purchase DROP COLUMN IF EXISTS reconciled_at;
@@ -0,0 +1,5 @@
+-- Сверка зависших покупок: когда статус new/pending-покупки последний раз запрашивали у провайдера.
+-- Покупки без сверки и давно проверенные идут первыми, каждая — не чаще раза за PURCHASE_RECONCILE_AFTER_MINUTES.
+ALTER TABLE purchase ADD COLUMN IF NOT EXISTS reconciled_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_purchase_unfinished ON purchase (created_at) WHERE status IN ('new', 'pending');
@@ -0,0 +1,32 @@
+-- Outbox проведения оплаты: переход покупки в paid и строки задач пишутся одной транзакцией,
+-- воркер выполняет побочные эффекты (панель, чек, рефералка, XP, уведомления) с повторами.
+-- kind: apply (начисление в панели / выпуск подарка / зачисление на баланс) выполняется первым,
+-- остальные шаги — только после него. idempotency_key = purchase:<id>:<kind> — повторная обработка
+-- оплаты не создаёт дублей. payload — план шага и результат уже выполненных частей (переживает перезапуск).
+-- status: pending → done | failed; canceled — покупку вернули раньше, чем шаг выполнился.
+CREATE TABLE IF NOT EXISTS purchase_outbox (
+    id              BIGSERIAL PRIMARY KEY,
+    purchase_id     BIGINT      NOT NULL REFERENCES purchase (id) ON DELETE CASCADE,
+    step            SMALLINT    NOT NULL,
+    kind            VARCHAR(32) NOT NULL,
+    idempotency_key VARCHAR(96) NOT NULL,
+    payload         JSONB       NOT NULL DEFAULT '{}'::jsonb,
+    status          VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts        INT         NOT NULL DEFAULT 0,
+    last_error      TEXT,
+    next_attempt_at TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    done_at         TIMESTAMPTZ,
+    CONSTRAINT uq_purchase_outbox_key UNIQUE (idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_outbox_due
+    ON purchase_outbox (next_attempt_at)
+    WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_purchase_outbox_purchase
+    ON purchase_outbox (purchase_id, step);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_outbox_status
+    ON purchase_outbox (status, updated_at DESC);
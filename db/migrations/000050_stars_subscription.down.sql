@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS uq_purchase_telegram_charge_id;
+
+ALTER TABLE purchase DROP COLUMN IF EXISTS stars_subscription_id;
+
+DROP TABLE IF EXISTS stars_subscription;
@@ -0,0 +1,29 @@
+-- Подписки Telegram Stars: счёт с subscription_period, Telegram сам списывает звёзды раз в 30 дней
+-- и присылает successful_payment с is_recurring. Каждое продление проводится отдельной покупкой.
+-- status: active → canceled (отменена в боте, действует до expires_at) → expired (продление не пришло).
+CREATE TABLE IF NOT EXISTS stars_subscription (
+    id                 BIGSERIAL PRIMARY KEY,
+    customer_id        BIGINT      NOT NULL REFERENCES customer (id) ON DELETE CASCADE,
+    purchase_id        BIGINT      NOT NULL UNIQUE REFERENCES purchase (id) ON DELETE CASCADE,
+    telegram_charge_id TEXT        NOT NULL,
+    amount             INTEGER     NOT NULL,
+    months             INTEGER     NOT NULL,
+    tariff_id          BIGINT REFERENCES tariff (id) ON DELETE SET NULL,
+    extra_hwid         INTEGER     NOT NULL DEFAULT 0,
+    status             VARCHAR(16) NOT NULL DEFAULT 'active',
+    expires_at         TIMESTAMPTZ NOT NULL,
+    renewals           INTEGER     NOT NULL DEFAULT 0,
+    last_renewed_at    TIMESTAMPTZ,
+    canceled_at        TIMESTAMPTZ,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stars_subscription_customer ON stars_subscription (customer_id);
+CREATE INDEX IF NOT EXISTS idx_stars_subscription_expires ON stars_subscription (expires_at) WHERE status <> 'expired';
+
+-- Покупка — первая оплата или продление подписки Stars.
+ALTER TABLE purchase ADD COLUMN IF NOT EXISTS stars_subscription_id BIGINT;
+
+-- Повторная доставка successful_payment не создаёт вторую покупку продления.
+CREATE UNIQUE INDEX IF NOT EXISTS uq_purchase_telegram_charge_id ON purchase (telegram_charge_id) WHERE telegram_charge_id IS NOT NULL;
@@ -0,0 +1,11 @@
+-- Цены тарифов в дополнительных валютах (USD, EUR, …) рядом с amount_rub / amount_stars из tariff_price.
+-- currency — ISO-код в верхнем регистре; RUB и Stars здесь не хранятся. Строки живут отдельно от tariff_price:
+-- ReplaceAllPrices пересоздаёт рублёвые цены и не должен стирать валютные.
+CREATE TABLE IF NOT EXISTS tariff_price_currency (
+    tariff_id  BIGINT        NOT NULL REFERENCES tariff (id) ON DELETE CASCADE,
+    months     INTEGER       NOT NULL CHECK (months IN (1, 3, 6, 12)),
+    currency   VARCHAR(8)    NOT NULL CHECK (currency = UPPER(currency) AND currency NOT IN ('RUB', 'STARS', 'XTR')),
+    amount     NUMERIC(12, 2) NOT NULL CHECK (amount > 0),
+    updated_at TIMESTAMPTZ   NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tariff_id, months, currency)
+);
@@ -0,0 +1,7 @@
+DELETE FROM promo_code WHERE batch_id IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_promo_code_batch;
+
+ALTER TABLE promo_code DROP COLUMN IF EXISTS batch_id;
+
+DROP TABLE IF EXISTS promo_batch;
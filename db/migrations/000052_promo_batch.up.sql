@@ -0,0 +1,16 @@
+-- Пакеты одноразовых промокодов: тысячи уникальных кодов по одному шаблону (тип, дни, скидка, тариф, срок),
+-- сгруппированные в кампанию. Коды — обычные строки promo_code с max_uses = 1 и ссылкой на пакет.
+CREATE TABLE IF NOT EXISTS promo_batch (
+    id             BIGSERIAL PRIMARY KEY,
+    campaign       VARCHAR(128) NOT NULL,
+    prefix         VARCHAR(32)  NOT NULL DEFAULT '',
+    code_count     INTEGER      NOT NULL,
+    deactivated_at TIMESTAMPTZ,
+    created_at     TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_promo_batch_campaign ON promo_batch (campaign);
+
+ALTER TABLE promo_code ADD COLUMN IF NOT EXISTS batch_id BIGINT REFERENCES promo_batch (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_promo_code_batch ON promo_code (batch_id) WHERE batch_id IS NOT NULL;
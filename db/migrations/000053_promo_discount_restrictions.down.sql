@@ -0,0 +1,10 @@
+DELETE FROM customer_pending_discount WHERE percent = 0;
+
+ALTER TABLE customer_pending_discount DROP COLUMN IF EXISTS amount;
+
+ALTER TABLE promo_code
+    DROP COLUMN IF EXISTS discount_min_amount,
+    DROP COLUMN IF EXISTS discount_invoice_types,
+    DROP COLUMN IF EXISTS discount_months,
+    DROP COLUMN IF EXISTS discount_tariff_ids,
+    DROP COLUMN IF EXISTS discount_amount;
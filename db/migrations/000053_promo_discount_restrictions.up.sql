@@ -0,0 +1,12 @@
+-- Скидочные промокоды: фиксированная сумма в ₽ вместо процента и ограничения применимости —
+-- тарифы, периоды (мес.), способы оплаты (invoice_type) и минимальная сумма заказа в ₽. NULL — без ограничения.
+ALTER TABLE promo_code
+    ADD COLUMN IF NOT EXISTS discount_amount INTEGER,
+    ADD COLUMN IF NOT EXISTS discount_tariff_ids BIGINT[],
+    ADD COLUMN IF NOT EXISTS discount_months INTEGER[],
+    ADD COLUMN IF NOT EXISTS discount_invoice_types TEXT[],
+    ADD COLUMN IF NOT EXISTS discount_min_amount INTEGER;
+
+-- Фиксированная скидка, ожидающая оплаты (percent при этом 0).
+ALTER TABLE customer_pending_discount
+    ADD COLUMN IF NOT EXISTS amount INTEGER NOT NULL DEFAULT 0;
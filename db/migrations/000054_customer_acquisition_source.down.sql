@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_customer_acquisition_source;
+
+ALTER TABLE customer
+    DROP COLUMN IF EXISTS trial_activated_at,
+    DROP COLUMN IF EXISTS acquisition_utm,
+    DROP COLUMN IF EXISTS acquisition_source;
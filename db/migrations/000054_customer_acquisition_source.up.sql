@@ -0,0 +1,9 @@
+-- Источник привлечения клиента: метка из /start src_<метка> или utm_* регистрации в кабинете.
+-- Пишется один раз при первом контакте; acquisition_utm — исходные utm_* (только кабинет).
+-- trial_activated_at — первая активация триала, шаг воронки «старт → триал → оплата».
+ALTER TABLE customer
+    ADD COLUMN IF NOT EXISTS acquisition_source VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS acquisition_utm JSONB,
+    ADD COLUMN IF NOT EXISTS trial_activated_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_customer_acquisition_source ON customer (acquisition_source) WHERE acquisition_source IS NOT NULL;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS family_member;
+DROP TABLE IF EXISTS family_group;
+
+ALTER TABLE tariff
+    DROP COLUMN IF EXISTS family_max_members;
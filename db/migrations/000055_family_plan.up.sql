@@ -0,0 +1,24 @@
+-- Семейные тарифы: владелец подписки на тарифе с family_max_members > 0 приглашает до N участников
+-- по ссылке /start fam_<invite_code>. У каждого участника свой пользователь Remnawave со сроком владельца.
+-- Устройства — общий пул (лимит тарифа владельца + доп. HWID): участникам выделяется device_limit,
+-- владельцу остаётся пул минус выделенное.
+ALTER TABLE tariff
+    ADD COLUMN IF NOT EXISTS family_max_members INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS family_group (
+    id                BIGSERIAL PRIMARY KEY,
+    owner_customer_id BIGINT      NOT NULL UNIQUE REFERENCES customer (id) ON DELETE CASCADE,
+    invite_code       VARCHAR(32) NOT NULL UNIQUE,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Участник состоит не больше чем в одной семье.
+CREATE TABLE IF NOT EXISTS family_member (
+    id           BIGSERIAL PRIMARY KEY,
+    group_id     BIGINT      NOT NULL REFERENCES family_group (id) ON DELETE CASCADE,
+    customer_id  BIGINT      NOT NULL UNIQUE REFERENCES customer (id) ON DELETE CASCADE,
+    device_limit INTEGER     NOT NULL DEFAULT 1 CHECK (device_limit >= 1),
+    joined_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_family_member_group ON family_member (group_id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS subscription_pause;
+
+ALTER TABLE customer
+    DROP COLUMN IF EXISTS paused_until;
@@ -0,0 +1,23 @@
+-- Пауза подписки: клиент замораживает оставшийся срок, пользователь Remnawave отключается
+-- до resume_at (или досрочного возобновления). paused_until у клиента — для быстрых проверок
+-- (уведомления об истечении, автопродление); история пауз нужна для лимита за период.
+ALTER TABLE customer
+    ADD COLUMN IF NOT EXISTS paused_until TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS subscription_pause (
+    id                BIGSERIAL PRIMARY KEY,
+    customer_id       BIGINT      NOT NULL REFERENCES customer (id) ON DELETE CASCADE,
+    paused_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resume_at         TIMESTAMPTZ NOT NULL,
+    -- Остаток подписки на момент паузы, секунды: при возобновлении срок = now + remaining.
+    remaining_seconds BIGINT      NOT NULL CHECK (remaining_seconds > 0),
+    resumed_at        TIMESTAMPTZ
+);
+
+-- Не больше одной активной паузы на клиента.
+CREATE UNIQUE INDEX IF NOT EXISTS uq_subscription_pause_active
+    ON subscription_pause (customer_id) WHERE resumed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_subscription_pause_due
+    ON subscription_pause (resume_at) WHERE resumed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_subscription_pause_customer
+    ON subscription_pause (customer_id, paused_at);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS customer_traffic_pack;
+
+ALTER TABLE purchase
+    DROP COLUMN IF EXISTS traffic_gb;
+
+DROP TABLE IF EXISTS tariff_traffic_pack;
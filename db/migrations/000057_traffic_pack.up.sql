@@ -0,0 +1,28 @@
+-- Пакеты трафика: клиент докупает +N ГБ к лимиту тарифа до ближайшего сброса трафика в Remnawave.
+-- Набор пакетов и цены задаёт админ для каждого тарифа; price_stars = 0 — цена в Stars по RUB_PER_STAR.
+CREATE TABLE IF NOT EXISTS tariff_traffic_pack (
+    tariff_id   BIGINT      NOT NULL REFERENCES tariff (id) ON DELETE CASCADE,
+    traffic_gb  INTEGER     NOT NULL CHECK (traffic_gb > 0),
+    price_rub   INTEGER     NOT NULL CHECK (price_rub > 0),
+    price_stars INTEGER     NOT NULL DEFAULT 0 CHECK (price_stars >= 0),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tariff_id, traffic_gb)
+);
+
+-- Объём пакета фиксируется в покупке: админ может убрать пакет, пока счёт ещё не оплачен.
+ALTER TABLE purchase ADD COLUMN IF NOT EXISTS traffic_gb INTEGER NOT NULL DEFAULT 0;
+
+-- Применённые пакеты: лимит в панели поднят на bytes. reset_marker — lastTrafficResetAt пользователя
+-- на момент покупки; когда панель сбросит трафик позже него, пакет откатывается (rolled_back_at).
+CREATE TABLE IF NOT EXISTS customer_traffic_pack (
+    id             BIGSERIAL PRIMARY KEY,
+    customer_id    BIGINT      NOT NULL REFERENCES customer (id) ON DELETE CASCADE,
+    purchase_id    BIGINT      NOT NULL UNIQUE REFERENCES purchase (id) ON DELETE CASCADE,
+    bytes          BIGINT      NOT NULL CHECK (bytes > 0),
+    reset_marker   TIMESTAMPTZ,
+    applied_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    rolled_back_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_traffic_pack_active
+    ON customer_traffic_pack (customer_id) WHERE rolled_back_at IS NULL;
@@ -0,0 +1,13 @@
+-- Лента уведомлений кабинета: события панели (вебхуки Remnawave) для клиентов без Telegram-чата.
+-- Текст уже локализован на языке клиента; read_at IS NULL — не прочитано.
+CREATE TABLE IF NOT EXISTS customer_notification (
+    id          BIGSERIAL PRIMARY KEY,
+    customer_id BIGINT      NOT NULL REFERENCES customer (id) ON DELETE CASCADE,
+    kind        TEXT        NOT NULL,
+    text        TEXT        NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    read_at     TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_notification_customer
+    ON customer_notification (customer_id, created_at DESC);
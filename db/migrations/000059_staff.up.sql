@@ -0,0 +1,11 @@
+-- Сотрудники с доступом к админке бота и кабинета. ADMIN_TELEGRAM_ID — всегда владелец и в таблице не хранится.
+-- permissions — явный список прав (users_read, users_write, payments, …); роль owner даёт все права,
+-- включая управление сотрудниками.
+CREATE TABLE IF NOT EXISTS staff (
+    telegram_id BIGINT PRIMARY KEY CHECK (telegram_id > 0),
+    role        TEXT        NOT NULL CHECK (role IN ('owner', 'admin', 'support', 'marketer')),
+    permissions TEXT[]      NOT NULL DEFAULT '{}',
+    note        TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
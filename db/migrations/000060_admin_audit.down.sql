@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS admin_audit;
+DROP FUNCTION IF EXISTS admin_audit_append_only();
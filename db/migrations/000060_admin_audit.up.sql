@@ -0,0 +1,31 @@
+-- Журнал действий сотрудников в админке бота и кабинета. Только добавление: UPDATE и DELETE запрещены триггером.
+-- customer_id без внешнего ключа — записи об удалённых клиентах остаются в журнале.
+-- before_state / after_state — только изменившиеся поля (JSON-объекты) или полное состояние, если второй стороны нет.
+CREATE TABLE IF NOT EXISTS admin_audit (
+    id                BIGSERIAL PRIMARY KEY,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    source            TEXT        NOT NULL CHECK (source IN ('bot', 'cabinet')),
+    actor_telegram_id BIGINT,
+    actor_account_id  BIGINT,
+    action            TEXT        NOT NULL,
+    customer_id       BIGINT,
+    target_type       TEXT        NOT NULL DEFAULT '',
+    target_id         TEXT        NOT NULL DEFAULT '',
+    before_state      JSONB,
+    after_state       JSONB
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_created_at ON admin_audit (created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_customer ON admin_audit (customer_id, created_at DESC) WHERE customer_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_admin_audit_action ON admin_audit (action, created_at DESC);
+
+CREATE OR REPLACE FUNCTION admin_audit_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'admin_audit is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS admin_audit_append_only ON admin_audit;
+CREATE TRIGGER admin_audit_append_only
+    BEFORE UPDATE OR DELETE ON admin_audit
+    FOR EACH ROW EXECUTE FUNCTION admin_audit_append_only();
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS broadcast_delivery;
+DROP TABLE IF EXISTS broadcast_job;
@@ -0,0 +1,55 @@
+-- Рассылки как задания: сообщение, аудитория и время запуска хранятся в БД, воркер в процессе бота
+-- рассылает по строкам broadcast_delivery и после перезапуска продолжает с того же места.
+-- status: scheduled → running → done; paused — остановлена админом (возобновляется),
+-- canceled — отменена, недоставленные получатели закрываются.
+-- Получатели выбираются в момент запуска (scheduled → running), а не при создании задания.
+CREATE TABLE IF NOT EXISTS broadcast_job (
+    id                     BIGSERIAL PRIMARY KEY,
+    status                 TEXT        NOT NULL DEFAULT 'scheduled'
+        CHECK (status IN ('scheduled', 'running', 'paused', 'canceled', 'done')),
+    source                 TEXT        NOT NULL CHECK (source IN ('bot', 'cabinet')),
+    created_by_telegram_id BIGINT,
+    created_by_account_id  BIGINT,
+    notify_chat_id         BIGINT,
+    audience               TEXT        NOT NULL,
+    tariff_id              BIGINT,
+    message_text           TEXT        NOT NULL DEFAULT '',
+    entities               JSONB,
+    media_file_id          TEXT,
+    media_as_photo         BOOLEAN     NOT NULL DEFAULT FALSE,
+    buttons                JSONB       NOT NULL DEFAULT '{}'::jsonb,
+    scheduled_at           TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at             TIMESTAMPTZ,
+    finished_at            TIMESTAMPTZ,
+    total_count            INT         NOT NULL DEFAULT 0,
+    sent_count             INT         NOT NULL DEFAULT 0,
+    failed_count           INT         NOT NULL DEFAULT 0,
+    created_at             TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at             TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_broadcast_job_due
+    ON broadcast_job (scheduled_at)
+    WHERE status = 'scheduled';
+
+CREATE INDEX IF NOT EXISTS idx_broadcast_job_created
+    ON broadcast_job (created_at DESC, id DESC);
+
+-- Получатель рассылки. status: pending → sent | failed; canceled — рассылку отменили до отправки.
+-- 429 от Telegram не считается ошибкой: попытка переносится на next_attempt_at (retry_after).
+CREATE TABLE IF NOT EXISTS broadcast_delivery (
+    job_id          BIGINT      NOT NULL REFERENCES broadcast_job (id) ON DELETE CASCADE,
+    telegram_id     BIGINT      NOT NULL,
+    language        TEXT        NOT NULL DEFAULT 'en',
+    status          TEXT        NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'sent', 'failed', 'canceled')),
+    attempts        INT         NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error      TEXT,
+    sent_at         TIMESTAMPTZ,
+    PRIMARY KEY (job_id, telegram_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_broadcast_delivery_due
+    ON broadcast_delivery (job_id, next_attempt_at)
+    WHERE status = 'pending';
@@ -0,0 +1,4 @@
+ALTER TABLE promo_code DROP COLUMN IF EXISTS segment_id;
+ALTER TABLE broadcast_job DROP COLUMN IF EXISTS segment_filter, DROP COLUMN IF EXISTS segment_id;
+ALTER TABLE customer DROP COLUMN IF EXISTS online_at;
+DROP TABLE IF EXISTS customer_segment;
@@ -0,0 +1,23 @@
+-- Сохранённые сегменты клиентов: набор фильтров (JSON), по которому выбираются получатели рассылки
+-- и клиенты, которым доступен промокод. Фильтр вычисляется на момент использования.
+CREATE TABLE IF NOT EXISTS customer_segment (
+    id         BIGSERIAL PRIMARY KEY,
+    name       TEXT        NOT NULL UNIQUE,
+    filter     JSONB       NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Последнее подключение к VPN (onlineAt из Remnawave): обновляется синхронизацией и вебхуком панели.
+ALTER TABLE customer
+    ADD COLUMN IF NOT EXISTS online_at TIMESTAMPTZ;
+
+-- Рассылка по сегменту: фильтр копируется в задание при создании — правка или удаление сегмента
+-- не меняет уже запланированную рассылку.
+ALTER TABLE broadcast_job
+    ADD COLUMN IF NOT EXISTS segment_id     BIGINT,
+    ADD COLUMN IF NOT EXISTS segment_filter JSONB;
+
+-- Промокод только для клиентов сегмента; сегмент, на который ссылаются промокоды, удалить нельзя.
+ALTER TABLE promo_code
+    ADD COLUMN IF NOT EXISTS segment_id BIGINT REFERENCES customer_segment (id) ON DELETE RESTRICT;
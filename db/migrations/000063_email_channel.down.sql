@@ -0,0 +1,2 @@
+ALTER TABLE broadcast_delivery DROP COLUMN IF EXISTS customer_id, DROP COLUMN IF EXISTS channel;
+ALTER TABLE cabinet_account DROP COLUMN IF EXISTS marketing_email_opt_out_at;
@@ -0,0 +1,11 @@
+-- Отписка от рекламных писем (рассылок) по ссылке из письма. Сервисные уведомления
+-- (окончание подписки, lifecycle) приходят независимо от отметки.
+ALTER TABLE cabinet_account
+    ADD COLUMN IF NOT EXISTS marketing_email_opt_out_at TIMESTAMPTZ;
+
+-- Канал доставки получателя рассылки: web-only клиентам кабинета рассылка уходит на email.
+-- telegram_id у них синтетический и уникален, поэтому первичный ключ не меняется.
+ALTER TABLE broadcast_delivery
+    ADD COLUMN IF NOT EXISTS channel     TEXT NOT NULL DEFAULT 'telegram'
+        CHECK (channel IN ('telegram', 'email')),
+    ADD COLUMN IF NOT EXISTS customer_id BIGINT;
@@ -0,0 +1,80 @@
+// Package acquisition разбирает метки источника привлечения: /start src_<метка> в боте и utm_* при регистрации в кабинете.
+package acquisition
+
+import "strings"
+
+// StartPrefix — префикс параметра /start с меткой источника: t.me/<bot>?start=src_tiktok_oct.
+const StartPrefix = "src_"
+
+const (
+	sourceMaxLen   = 64
+	utmValueMaxLen = 128
+)
+
+// utmKeys — сохраняемые utm-параметры, остальные поля запроса отбрасываются.
+var utmKeys = []string{"utm_source", "utm_medium", "utm_campaign", "utm_content", "utm_term"}
+
+// Tag — источник клиента: нормализованная метка для отчётов и исходные utm_* (только кабинет).
+type Tag struct {
+	Source string
+	UTM    map[string]string
+}
+
+// Empty — метки нет.
+func (t Tag) Empty() bool {
+	return t.Source == ""
+}
+
+// FromStartText — метка из текста «/start src_<метка>»; пустой Tag, если параметр другой (ref_, gift_, …).
+func FromStartText(text string) Tag {
+	fields := strings.Fields(text)
+	if len(fields) < 2 || !strings.HasPrefix(fields[1], StartPrefix) {
+		return Tag{}
+	}
+	return Tag{Source: Normalize(strings.TrimPrefix(fields[1], StartPrefix))}
+}
+
+// FromUTM — метка из utm_* регистрации: utm_source, через «_» — utm_campaign, если он задан.
+func FromUTM(params map[string]string) Tag {
+	utm := make(map[string]string)
+	for _, k := range utmKeys {
+		v := strings.TrimSpace(params[k])
+		if v == "" {
+			continue
+		}
+		if r := []rune(v); len(r) > utmValueMaxLen {
+			v = string(r[:utmValueMaxLen])
+		}
+		utm[k] = v
+	}
+	src := utm["utm_source"]
+	if src == "" {
+		return Tag{}
+	}
+	if c := utm["utm_campaign"]; c != "" {
+		src += "_" + c
+	}
+	t := Tag{Source: Normalize(src), UTM: utm}
+	if t.Source == "" {
+		return Tag{}
+	}
+	return t
+}
+
+// Normalize приводит метку к виду в БД: нижний регистр, латиница, цифры, «_», «-» и «.»; прочие символы — «_».
+// Длина — до 64 символов.
+func Normalize(raw string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(raw)) {
+		if sb.Len() >= sourceMaxLen {
+			break
+		}
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-', r == '.':
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte('_')
+		}
+	}
+	return strings.Trim(sb.String(), "_-.")
+}
@@ -0,0 +1,61 @@
+package acquisition
+
+import "testing"
+
+func TestFromStartText(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"/start src_tiktok_oct", "tiktok_oct"},
+		{"/start src_TikTok-Oct", "tiktok-oct"},
+		{"/start ref_12345", ""},
+		{"/start gift_ABC", ""},
+		{"/start", ""},
+		{"/start src_", ""},
+	}
+	for _, tt := range tests {
+		if got := FromStartText(tt.text).Source; got != tt.want {
+			t.Errorf("FromStartText(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFromUTM(t *testing.T) {
+	tag := FromUTM(map[string]string{
+		"utm_source":   "TikTok",
+		"utm_campaign": "oct 2026",
+		"utm_medium":   "cpc",
+		"ref":          "ignored",
+	})
+	if tag.Source != "tiktok_oct_2026" {
+		t.Fatalf("source = %q", tag.Source)
+	}
+	if len(tag.UTM) != 3 || tag.UTM["utm_medium"] != "cpc" || tag.UTM["ref"] != "" {
+		t.Fatalf("utm = %v", tag.UTM)
+	}
+	if !FromUTM(map[string]string{"utm_campaign": "oct"}).Empty() {
+		t.Fatal("expected empty tag without utm_source")
+	}
+	if !FromUTM(nil).Empty() {
+		t.Fatal("expected empty tag for nil params")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"  Partner.Blog ": "partner.blog",
+		"канал":           "",
+		"a b/c":           "a_b_c",
+		"__x__":           "x",
+	}
+	for in, want := range tests {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+	long := Normalize("abcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyz")
+	if len(long) != sourceMaxLen {
+		t.Fatalf("len = %d, want %d", len(long), sourceMaxLen)
+	}
+}
@@ -0,0 +1,306 @@
+// Package audit — журнал действий сотрудников в админке бота и кабинета (таблица admin_audit).
+//
+// Запись журнала не должна ломать само действие: ошибки записи только логируются.
+// До Init вызовы Record ничего не делают (тесты, утилиты без БД).
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/remnawave"
+)
+
+// Source — откуда выполнено действие.
+type Source string
+
+const (
+	SourceBot     Source = "bot"
+	SourceCabinet Source = "cabinet"
+)
+
+// Действия. Префикс до точки — тип объекта; фильтр журнала по «user.» выбирает все действия над клиентами.
+const (
+	ActionUserExtend        = "user.extend"
+	ActionUserExpireSet     = "user.expire_set"
+	ActionUserTrafficLimit  = "user.traffic_limit"
+	ActionUserTrafficReset  = "user.traffic_reset"
+	ActionUserSquads        = "user.squads"
+	ActionUserStrategy      = "user.strategy"
+	ActionUserHwidLimit     = "user.hwid_limit"
+	ActionUserExtraHwid     = "user.extra_hwid"
+	ActionUserTariff        = "user.tariff"
+	ActionUserDescription   = "user.description"
+	ActionUserDisable       = "user.disable"
+	ActionUserEnable        = "user.enable"
+	ActionUserDelete        = "user.delete"
+	ActionUserDeviceDelete  = "user.device_delete"
+	ActionUserBalanceAdjust = "user.balance_adjust"
+	ActionUserRefund        = "user.refund"
+	ActionUserMessage       = "user.message"
+
+	ActionPromoCreate          = "promo.create"
+	ActionPromoUpdate          = "promo.update"
+	ActionPromoDelete          = "promo.delete"
+	ActionPromoBatchCreate     = "promo_batch.create"
+	ActionPromoBatchDeactivate = "promo_batch.deactivate"
+
+	ActionTariffCreate = "tariff.create"
+	ActionTariffUpdate = "tariff.update"
+	ActionTariffDelete = "tariff.delete"
+
+	ActionLoyaltyTierCreate = "loyalty_tier.create"
+	ActionLoyaltyTierUpdate = "loyalty_tier.update"
+	ActionLoyaltyTierDelete = "loyalty_tier.delete"
+	ActionLoyaltyRecalc     = "loyalty.recalc"
+
+	ActionBroadcastSend   = "broadcast.send"
+	ActionBroadcastPause  = "broadcast.pause"
+	ActionBroadcastResume = "broadcast.resume"
+	ActionBroadcastCancel = "broadcast.cancel"
+
+	ActionSegmentCreate = "segment.create"
+	ActionSegmentUpdate = "segment.update"
+	ActionSegmentDelete = "segment.delete"
+
+	ActionInfraCreate = "infra.create"
+	ActionInfraUpdate = "infra.update"
+	ActionInfraDelete = "infra.delete"
+
+	ActionSettingsUpdate = "settings.update"
+	ActionSyncRun        = "sync.run"
+
+	ActionPartnerUpdate        = "partner.update"
+	ActionPartnerPayoutApprove = "partner_payout.approve"
+	ActionPartnerPayoutReject  = "partner_payout.reject"
+	ActionMoynalogRetry        = "moynalog.retry"
+	ActionPurchaseOutboxRetry  = "purchase_outbox.retry"
+
+	ActionStaffCreate = "staff.create"
+	ActionStaffUpdate = "staff.update"
+	ActionStaffDelete = "staff.delete"
+)
+
+// Actor — кто выполнил действие. В боте известен только Telegram ID, в кабинете — аккаунт
+// и, если у аккаунта привязан Telegram, его ID.
+type Actor struct {
+	Source     Source
+	TelegramID int64
+	AccountID  int64
+}
+
+// Bot — сотрудник в Telegram-боте.
+func Bot(telegramID int64) Actor {
+	return Actor{Source: SourceBot, TelegramID: telegramID}
+}
+
+// Cabinet — сотрудник в web-кабинете.
+func Cabinet(telegramID, accountID int64) Actor {
+	return Actor{Source: SourceCabinet, TelegramID: telegramID, AccountID: accountID}
+}
+
+// Entry — одно действие. Before/After — что угодно, что сериализуется в JSON-объект
+// (структура, map); nil — стороны нет (создание или удаление).
+type Entry struct {
+	Action     string
+	CustomerID int64
+	TargetType string
+	TargetID   string
+	Before     any
+	After      any
+}
+
+var (
+	mu   sync.RWMutex
+	repo *database.AdminAuditRepository
+)
+
+// Init подключает репозиторий журнала.
+func Init(r *database.AdminAuditRepository) {
+	mu.Lock()
+	repo = r
+	mu.Unlock()
+}
+
+// Record пишет действие в журнал. Если заданы обе стороны, сохраняются только изменившиеся поля.
+func Record(ctx context.Context, actor Actor, e Entry) {
+	mu.RLock()
+	r := repo
+	mu.RUnlock()
+	if r == nil {
+		return
+	}
+	before, after, err := Diff(e.Before, e.After)
+	if err != nil {
+		slog.Error("audit: marshal state", "action", e.Action, "error", err)
+	}
+	row := database.AdminAuditEntry{
+		Source:     string(actor.Source),
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		Before:     before,
+		After:      after,
+	}
+	if actor.TelegramID != 0 {
+		row.ActorTelegramID = &actor.TelegramID
+	}
+	if actor.AccountID != 0 {
+		row.ActorAccountID = &actor.AccountID
+	}
+	if e.CustomerID > 0 {
+		row.CustomerID = &e.CustomerID
+		if row.TargetType == "" {
+			row.TargetType = "customer"
+			row.TargetID = strconv.FormatInt(e.CustomerID, 10)
+		}
+	}
+	// Действие уже выполнено — запись не должна пропасть из-за отменённого запроса.
+	if err := r.Insert(context.WithoutCancel(ctx), row); err != nil {
+		slog.Error("audit: insert", "action", e.Action, "error", err)
+	}
+}
+
+// Diff сериализует стороны в JSON. Если обе — объекты, в результате остаются только ключи,
+// значения которых отличаются; иначе стороны возвращаются целиком.
+func Diff(before, after any) (json.RawMessage, json.RawMessage, error) {
+	b, err := marshal(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := marshal(after)
+	if err != nil {
+		return nil, nil, err
+	}
+	if b == nil || a == nil {
+		return b, a, nil
+	}
+	var bm, am map[string]json.RawMessage
+	if json.Unmarshal(b, &bm) != nil || json.Unmarshal(a, &am) != nil {
+		return b, a, nil
+	}
+	keys := make(map[string]struct{}, len(bm)+len(am))
+	for k := range bm {
+		keys[k] = struct{}{}
+	}
+	for k := range am {
+		keys[k] = struct{}{}
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	bd, ad := map[string]json.RawMessage{}, map[string]json.RawMessage{}
+	for _, k := range sorted {
+		bv, bok := bm[k]
+		av, aok := am[k]
+		if bok && aok && bytes.Equal(bv, av) {
+			continue
+		}
+		if bok {
+			bd[k] = bv
+		}
+		if aok {
+			ad[k] = av
+		}
+	}
+	if b, err = json.Marshal(bd); err != nil {
+		return nil, nil, err
+	}
+	if a, err = json.Marshal(ad); err != nil {
+		return nil, nil, err
+	}
+	return b, a, nil
+}
+
+func marshal(v any) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+	return raw, nil
+}
+
+// CustomerState — поля клиента в БД, которые меняет админка.
+func CustomerState(c *database.Customer) map[string]any {
+	if c == nil {
+		return nil
+	}
+	return map[string]any{
+		"telegram_id":           c.TelegramID,
+		"expire_at":             formatTime(c.ExpireAt),
+		"current_tariff_id":     c.CurrentTariffID,
+		"extra_hwid":            c.ExtraHwid,
+		"extra_hwid_expires_at": formatTime(c.ExtraHwidExpiresAt),
+	}
+}
+
+// UserState — поля пользователя Remnawave, которые меняет админка.
+func UserState(u *remnawave.User) map[string]any {
+	if u == nil {
+		return nil
+	}
+	squads := make([]string, 0, len(u.ActiveInternalSquads))
+	for _, s := range u.ActiveInternalSquads {
+		squads = append(squads, s.UUID.String())
+	}
+	sort.Strings(squads)
+	expire := u.ExpireAt.UTC().Format(time.RFC3339)
+	return map[string]any{
+		"status":                 u.Status,
+		"expire_at":              &expire,
+		"traffic_limit_bytes":    u.TrafficLimitBytes,
+		"traffic_limit_strategy": u.TrafficLimitStrategy,
+		"hwid_device_limit":      u.HwidDeviceLimit,
+		"description":            u.Description,
+		"squads":                 squads,
+	}
+}
+
+// Row — строка БД (структура с тегами db) как map «колонка → значение»; nil-указатель — nil.
+// Для объектов без своего DTO: promo_codes, tariffs, loyalty_tiers и т. п.
+func Row(v any) map[string]any {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	rt := rv.Type()
+	out := make(map[string]any, rt.NumField())
+	for i := 0; i < rt.NumField(); i++ {
+		col := rt.Field(i).Tag.Get("db")
+		if col == "" || col == "-" || !rt.Field(i).IsExported() {
+			continue
+		}
+		out[col] = rv.Field(i).Interface()
+	}
+	return out
+}
+
+func formatTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.UTC().Format(time.RFC3339)
+	return &s
+}
@@ -0,0 +1,48 @@
+package audit
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	before := map[string]any{"status": "ACTIVE", "traffic_limit_bytes": 100, "squads": []string{"a"}}
+	after := map[string]any{"status": "ACTIVE", "traffic_limit_bytes": 200, "squads": []string{"a"}, "note": "x"}
+	b, a, err := Diff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"traffic_limit_bytes":100}` {
+		t.Fatalf("before = %s", b)
+	}
+	if string(a) != `{"note":"x","traffic_limit_bytes":200}` {
+		t.Fatalf("after = %s", a)
+	}
+
+	b, a, err = Diff(nil, map[string]any{"code": "SPRING"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b != nil || string(a) != `{"code":"SPRING"}` {
+		t.Fatalf("create: before=%s after=%s", b, a)
+	}
+
+	var nilMap map[string]any
+	b, a, err = Diff(nilMap, nil)
+	if err != nil || b != nil || a != nil {
+		t.Fatalf("empty: before=%s after=%s err=%v", b, a, err)
+	}
+}
+
+func TestRow(t *testing.T) {
+	type tier struct {
+		ID          int64   `db:"id"`
+		DisplayName *string `db:"display_name"`
+	}
+	name := "Gold"
+	got := Row(&tier{ID: 3, DisplayName: &name})
+	if len(got) != 2 || got["id"] != int64(3) || got["display_name"] != &name {
+		t.Fatalf("row = %v", got)
+	}
+	var nilTier *tier
+	if Row(nilTier) != nil {
+		t.Fatal("nil pointer must give nil row")
+	}
+}
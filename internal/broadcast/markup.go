@@ -16,6 +16,15 @@ const (
 
 // BuildReplyMarkup строит inline-клавиатуру под рассылку для языка получателя.
 func BuildReplyMarkup(tm *translation.Manager, lang string, flags RecipientButtons) models.ReplyMarkup {
+	rows := buildKeyboard(tm, lang, flags)
+	if rows == nil {
+		return nil
+	}
+	return models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+// buildKeyboard — ряды кнопок рассылки; nil — кнопок нет.
+func buildKeyboard(tm *translation.Manager, lang string, flags RecipientButtons) [][]models.InlineKeyboardButton {
 	if tm == nil || (!flags.Buy && !flags.MainMenu && !flags.Promo && !flags.Connect) {
 		return nil
 	}
@@ -38,7 +47,7 @@ func BuildReplyMarkup(tm *translation.Manager, lang string, flags RecipientButto
 			tm.WithButton(lang, "broadcast_inline_main", models.InlineKeyboardButton{CallbackData: callbackStart + inlineQuerySuffix}),
 		})
 	}
-	return models.InlineKeyboardMarkup{InlineKeyboard: rows}
+	return rows
 }
 
 func connectRow(tm *translation.Manager, lang string) []models.InlineKeyboardButton {
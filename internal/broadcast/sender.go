@@ -2,6 +2,8 @@ package broadcast
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"time"
@@ -9,158 +11,344 @@ import (
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 
+	"remnawave-tg-shop-bot/internal/channel"
 	"remnawave-tg-shop-bot/internal/database"
 	"remnawave-tg-shop-bot/internal/translation"
-	"remnawave-tg-shop-bot/utils"
 )
 
 const (
 	batchSize             = 29
 	delayBetweenBatches   = time.Second
-	adminResultMessageFmt = "✅ Рассылка завершена!\n\n📊 Статистика:\n• Всего пользователей: %d\n• Успешно отправлено: %d\n• Ошибок: %d"
+	pollInterval          = 5 * time.Second
+	maxDeliveryAttempts   = 5
+	adminResultMessageFmt = "✅ Рассылка #%d завершена!\n\n📊 Статистика:\n• Всего пользователей: %d\n• Успешно отправлено: %d\n• Ошибок: %d"
 )
 
-// Sender отправляет массовые сообщения через Telegram-бота.
+// Sender ставит рассылки в очередь (broadcast_job) и рассылает их в фоне через Run.
+// Прогресс хранится по получателям, поэтому после перезапуска рассылка продолжается с того же места.
+// Web-only клиентам кабинета рассылка уходит письмом (канал выбирает channels при старте рассылки).
 type Sender struct {
 	customers *database.CustomerRepository
+	jobs      *database.BroadcastJobRepository
 	tm        *translation.Manager
+	channels  *channel.Router
+	wake      chan struct{}
 }
 
-func NewSender(customers *database.CustomerRepository, tm *translation.Manager) *Sender {
-	return &Sender{customers: customers, tm: tm}
+func NewSender(customers *database.CustomerRepository, jobs *database.BroadcastJobRepository, tm *translation.Manager, channels *channel.Router) *Sender {
+	return &Sender{customers: customers, jobs: jobs, tm: tm, channels: channels, wake: make(chan struct{}, 1)}
 }
 
-// Send рассылает сообщение выбранной аудитории. При adminID != 0 шлёт итог админу в Telegram.
-func (s *Sender) Send(
-	ctx context.Context,
-	b *bot.Bot,
-	adminID int64,
-	audience string,
-	tariffID *int64,
-	messageText string,
-	entities []models.MessageEntity,
-	media *Media,
-	flags RecipientButtons,
-) SendResult {
-	result := SendResult{}
-	if s == nil || s.customers == nil || b == nil {
-		return result
+// Enqueue сохраняет рассылку. С нулевым ScheduledAt она стартует при ближайшем проходе воркера.
+func (s *Sender) Enqueue(ctx context.Context, d Draft) (*database.BroadcastJob, error) {
+	job := database.BroadcastJob{
+		Source:       d.Source,
+		Audience:     d.Audience,
+		TariffID:     d.TariffID,
+		MessageText:  d.Text,
+		ScheduledAt:  d.ScheduledAt,
+		MediaAsPhoto: d.Media != nil && d.Media.AsPhoto,
 	}
-
-	recipients, err := s.customers.GetBroadcastRecipients(ctx, audience, tariffID)
-	if err != nil {
-		slog.Error("broadcast: get recipients", "error", err, "audience", audience)
-		if adminID != 0 {
-			_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID: adminID,
-				Text:   fmt.Sprintf("❌ Ошибка при получении списка пользователей: %v", err),
-			})
+	if job.ScheduledAt.IsZero() {
+		job.ScheduledAt = time.Now()
+	}
+	if d.CreatedByTelegramID != 0 {
+		job.CreatedByTelegramID = &d.CreatedByTelegramID
+	}
+	if d.CreatedByAccountID != 0 {
+		job.CreatedByAccountID = &d.CreatedByAccountID
+	}
+	if d.NotifyChatID != 0 {
+		job.NotifyChatID = &d.NotifyChatID
+	}
+	if d.Media != nil && d.Media.FileID != "" {
+		job.MediaFileID = &d.Media.FileID
+	}
+	var err error
+	if len(d.Entities) > 0 {
+		if job.Entities, err = json.Marshal(d.Entities); err != nil {
+			return nil, fmt.Errorf("failed to marshal broadcast entities: %w", err)
 		}
-		return result
 	}
-
-	if len(recipients) == 0 {
-		if adminID != 0 {
-			_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID: adminID,
-				Text:   "❌ Нет пользователей для рассылки",
-			})
+	if job.Buttons, err = json.Marshal(d.Buttons); err != nil {
+		return nil, fmt.Errorf("failed to marshal broadcast buttons: %w", err)
+	}
+	if d.Segment != nil {
+		job.Audience = database.BroadcastAudienceSegment
+		job.TariffID = nil
+		job.SegmentID = d.SegmentID
+		if job.SegmentFilter, err = json.Marshal(d.Segment); err != nil {
+			return nil, fmt.Errorf("failed to marshal broadcast segment: %w", err)
 		}
-		return result
 	}
+	out, err := s.jobs.Create(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	s.wakeUp()
+	return out, nil
+}
 
-	totalUsers := len(recipients)
-	eligibleUsers := 0
-	for _, rec := range recipients {
-		if !utils.IsSyntheticTelegramID(rec.TelegramID) {
-			eligibleUsers++
-		}
+// Get — рассылка по ID; nil — не найдена.
+func (s *Sender) Get(ctx context.Context, id int64) (*database.BroadcastJob, error) {
+	return s.jobs.FindByID(ctx, id)
+}
+
+// List — рассылки, новые первыми, и их общее число.
+func (s *Sender) List(ctx context.Context, limit, offset int) ([]database.BroadcastJob, int64, error) {
+	return s.jobs.List(ctx, limit, offset)
+}
+
+// Pause ставит рассылку на паузу; отправка останавливается после текущей пачки.
+// nil, nil — рассылки нет; database.ErrBroadcastJobState — она уже завершена или отменена.
+func (s *Sender) Pause(ctx context.Context, id int64) (*database.BroadcastJob, error) {
+	return s.jobs.Pause(ctx, id)
+}
+
+// Resume снимает рассылку с паузы.
+func (s *Sender) Resume(ctx context.Context, id int64) (*database.BroadcastJob, error) {
+	job, err := s.jobs.Resume(ctx, id)
+	if job != nil {
+		s.wakeUp()
 	}
-	sentCount := 0
-	failedCount := 0
+	return job, err
+}
 
-	for i := 0; i < totalUsers; i += batchSize {
-		end := i + batchSize
-		if end > totalUsers {
-			end = totalUsers
-		}
+// Cancel отменяет рассылку; недоставленным получателям сообщение уже не уйдёт.
+func (s *Sender) Cancel(ctx context.Context, id int64) (*database.BroadcastJob, error) {
+	return s.jobs.Cancel(ctx, id)
+}
 
-		batch := recipients[i:end]
-		for _, rec := range batch {
-			if utils.IsSyntheticTelegramID(rec.TelegramID) {
-				continue
-			}
-			markup := BuildReplyMarkup(s.tm, rec.Language, flags)
-			var sendErr error
-			if media != nil {
-				if media.AsPhoto {
-					pp := &bot.SendPhotoParams{
-						ChatID:          rec.TelegramID,
-						Photo:           &models.InputFileString{Data: media.FileID},
-						Caption:         messageText,
-						CaptionEntities: entities,
-					}
-					if markup != nil {
-						pp.ReplyMarkup = markup
-					}
-					_, sendErr = b.SendPhoto(ctx, pp)
-				} else {
-					dp := &bot.SendDocumentParams{
-						ChatID:          rec.TelegramID,
-						Document:        &models.InputFileString{Data: media.FileID},
-						Caption:         messageText,
-						CaptionEntities: entities,
-					}
-					if markup != nil {
-						dp.ReplyMarkup = markup
-					}
-					_, sendErr = b.SendDocument(ctx, dp)
-				}
-			} else {
-				params := bot.SendMessageParams{
-					ChatID: rec.TelegramID,
-					Text:   messageText,
-				}
-				if len(entities) > 0 {
-					params.Entities = entities
-				}
-				if markup != nil {
-					params.ReplyMarkup = markup
-				}
-				_, sendErr = b.SendMessage(ctx, &params)
-			}
-			if sendErr != nil {
-				slog.Warn("broadcast: send message", "userId", rec.TelegramID, "error", sendErr)
-				failedCount++
-			} else {
-				sentCount++
+func (s *Sender) wakeUp() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run — воркер рассылок: запускает наступившие по расписанию и отправляет идущие пачками
+// не больше batchSize сообщений в секунду на все рассылки вместе. Блокируется до отмены ctx.
+func (s *Sender) Run(ctx context.Context, b *bot.Bot) {
+	if s == nil || s.jobs == nil || s.channels == nil || b == nil {
+		return
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		delay := s.tick(ctx, b)
+		if delay > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
 			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
 		}
+	}
+}
+
+// tick выполняет один проход воркера и возвращает паузу перед следующим; 0 — работы нет.
+func (s *Sender) tick(ctx context.Context, b *bot.Bot) time.Duration {
+	due, err := s.jobs.FindDue(ctx, time.Now())
+	if err != nil {
+		slog.Error("broadcast: find due jobs", "error", err)
+	}
+	for i := range due {
+		s.start(ctx, &due[i])
+	}
 
-		if end < totalUsers {
-			time.Sleep(delayBetweenBatches)
+	running, err := s.jobs.ListByStatus(ctx, database.BroadcastJobStatusRunning)
+	if err != nil {
+		slog.Error("broadcast: list running jobs", "error", err)
+		return 0
+	}
+	budget := batchSize
+	var delay time.Duration
+	for i := range running {
+		if budget == 0 || ctx.Err() != nil {
+			break
+		}
+		job := &running[i]
+		deliveries, err := s.jobs.DueDeliveries(ctx, job.ID, time.Now(), budget)
+		if err != nil {
+			slog.Error("broadcast: due deliveries", "job_id", job.ID, "error", err)
+			continue
 		}
+		if len(deliveries) == 0 {
+			s.finish(ctx, b, job.ID)
+			continue
+		}
+		msg, err := decodeMessage(job)
+		if err != nil {
+			slog.Error("broadcast: decode job", "job_id", job.ID, "error", err)
+			continue
+		}
+		delay = delayBetweenBatches
+		for _, d := range deliveries {
+			budget--
+			if retry := s.deliver(ctx, msg, d); retry > 0 {
+				return retry
+			}
+		}
+	}
+	return delay
+}
+
+func (s *Sender) recipients(ctx context.Context, job *database.BroadcastJob) ([]database.BroadcastRecipient, error) {
+	if len(job.SegmentFilter) == 0 {
+		return s.customers.GetBroadcastRecipients(ctx, job.Audience, job.TariffID)
+	}
+	var f database.SegmentFilter
+	if err := json.Unmarshal(job.SegmentFilter, &f); err != nil {
+		return nil, fmt.Errorf("failed to decode segment filter: %w", err)
 	}
+	return s.customers.GetSegmentRecipients(ctx, f)
+}
 
-	result = SendResult{
-		TotalUsers:  eligibleUsers,
-		SentCount:   sentCount,
-		FailedCount: failedCount,
+// start выбирает получателей запланированной рассылки и переводит её в running.
+// Ошибка выборки оставляет рассылку в scheduled — она повторится на следующем проходе.
+func (s *Sender) start(ctx context.Context, job *database.BroadcastJob) {
+	recipients, err := s.recipients(ctx, job)
+	if err != nil {
+		slog.Error("broadcast: get recipients", "job_id", job.ID, "audience", job.Audience, "error", err)
+		return
+	}
+	eligible := recipients[:0]
+	for _, rec := range recipients {
+		switch s.channels.Kind(recipientOf(rec)) {
+		case database.DeliveryChannelTelegram:
+			eligible = append(eligible, rec)
+		case database.DeliveryChannelEmail:
+			// Клиент с синтетическим telegram_id без отметки web-only — несогласованные данные, пропускаем.
+			if rec.WebOnly {
+				eligible = append(eligible, rec)
+			}
+		}
+	}
+	started, err := s.jobs.Start(ctx, job.ID, eligible, time.Now())
+	if err != nil {
+		slog.Error("broadcast: start job", "job_id", job.ID, "error", err)
+		return
 	}
+	if started != nil {
+		slog.Info("broadcast started", "job_id", job.ID, "audience", job.Audience, "recipients", started.TotalCount)
+	}
+}
 
-	if adminID != 0 {
+// finish закрывает рассылку без получателей в очереди и шлёт итог в notify_chat_id.
+func (s *Sender) finish(ctx context.Context, b *bot.Bot, jobID int64) {
+	job, err := s.jobs.Finish(ctx, jobID)
+	if err != nil {
+		slog.Error("broadcast: finish job", "job_id", jobID, "error", err)
+		return
+	}
+	if job == nil {
+		return
+	}
+	slog.Info("broadcast completed",
+		"job_id", job.ID,
+		"audience", job.Audience,
+		"total", job.TotalCount,
+		"sent", job.SentCount,
+		"failed", job.FailedCount,
+	)
+	if job.NotifyChatID != nil {
 		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID: adminID,
-			Text:   fmt.Sprintf(adminResultMessageFmt, eligibleUsers, sentCount, failedCount),
+			ChatID: *job.NotifyChatID,
+			Text:   fmt.Sprintf(adminResultMessageFmt, job.ID, job.TotalCount, job.SentCount, job.FailedCount),
 		})
 	}
+}
 
-	slog.Info("broadcast completed",
-		"totalUsers", totalUsers,
-		"eligibleUsers", eligibleUsers,
-		"sent", sentCount,
-		"failed", failedCount,
-		"audience", audience,
-	)
-	return result
+func recipientOf(rec database.BroadcastRecipient) channel.Recipient {
+	return channel.Recipient{CustomerID: rec.CustomerID, TelegramID: rec.TelegramID, Language: rec.Language, WebOnly: rec.WebOnly}
+}
+
+// deliver отправляет сообщение одному получателю и записывает результат.
+// > 0 — Telegram ответил 429: попытка отложена, воркер ждёт столько же перед следующей отправкой.
+func (s *Sender) deliver(ctx context.Context, msg *message, d database.BroadcastDelivery) time.Duration {
+	to := channel.Recipient{
+		CustomerID: d.CustomerID,
+		TelegramID: d.TelegramID,
+		Language:   d.Language,
+		WebOnly:    d.Channel == database.DeliveryChannelEmail,
+	}
+	err := s.channels.Send(ctx, to, channel.Message{
+		Subject:   s.tm.GetText(d.Language, "email_subject_broadcast"),
+		Text:      msg.text,
+		Entities:  msg.entities,
+		Media:     msg.media,
+		Keyboard:  buildKeyboard(s.tm, d.Language, msg.buttons),
+		Marketing: true,
+	})
+	if ctx.Err() != nil {
+		// Остановка процесса: получатель остаётся в очереди.
+		return 0
+	}
+	if err == nil {
+		if err := s.jobs.MarkSent(ctx, d.JobID, d.TelegramID); err != nil {
+			slog.Error("broadcast: mark sent", "job_id", d.JobID, "error", err)
+		}
+		return 0
+	}
+	retry, ok := retryDelay(err)
+	if ok && d.Attempts+1 < maxDeliveryAttempts {
+		slog.Warn("broadcast: rate limited", "job_id", d.JobID, "userId", d.TelegramID, "retry_after", retry)
+		if err := s.jobs.Postpone(ctx, d.JobID, d.TelegramID, err.Error(), time.Now().Add(retry)); err != nil {
+			slog.Error("broadcast: postpone delivery", "job_id", d.JobID, "error", err)
+		}
+		return retry
+	}
+	slog.Warn("broadcast: send message", "job_id", d.JobID, "userId", d.TelegramID, "channel", d.Channel, "error", err)
+	if err := s.jobs.MarkFailed(ctx, d.JobID, d.TelegramID, err.Error()); err != nil {
+		slog.Error("broadcast: mark failed", "job_id", d.JobID, "error", err)
+	}
+	if ok {
+		return retry
+	}
+	return 0
+}
+
+// retryDelay — пауза, которую просит Telegram в ответе 429; false — ошибка не про лимит.
+func retryDelay(err error) (time.Duration, bool) {
+	var tooMany *bot.TooManyRequestsError
+	if !errors.As(err, &tooMany) {
+		return 0, false
+	}
+	if tooMany.RetryAfter < 1 {
+		return time.Second, true
+	}
+	return time.Duration(tooMany.RetryAfter) * time.Second, true
+}
+
+// message — содержимое рассылки, восстановленное из broadcast_job.
+type message struct {
+	text     string
+	entities []models.MessageEntity
+	media    *Media
+	buttons  RecipientButtons
+}
+
+func decodeMessage(job *database.BroadcastJob) (*message, error) {
+	msg := &message{text: job.MessageText}
+	if len(job.Entities) > 0 {
+		if err := json.Unmarshal(job.Entities, &msg.entities); err != nil {
+			return nil, fmt.Errorf("entities: %w", err)
+		}
+	}
+	if len(job.Buttons) > 0 {
+		if err := json.Unmarshal(job.Buttons, &msg.buttons); err != nil {
+			return nil, fmt.Errorf("buttons: %w", err)
+		}
+	}
+	if job.MediaFileID != nil && *job.MediaFileID != "" {
+		msg.media = &Media{FileID: *job.MediaFileID, AsPhoto: job.MediaAsPhoto}
+	}
+	return msg, nil
 }
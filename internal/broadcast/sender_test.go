@@ -0,0 +1,27 @@
+package broadcast
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+)
+
+func TestRetryDelay(t *testing.T) {
+	d, ok := retryDelay(&bot.TooManyRequestsError{Message: "too many requests", RetryAfter: 7})
+	if !ok || d != 7*time.Second {
+		t.Fatalf("429: delay=%v ok=%v", d, ok)
+	}
+	d, ok = retryDelay(fmt.Errorf("send: %w", &bot.TooManyRequestsError{}))
+	if !ok || d != time.Second {
+		t.Fatalf("wrapped 429 without retry_after: delay=%v ok=%v", d, ok)
+	}
+	if _, ok := retryDelay(fmt.Errorf("%w, bot was blocked by the user", bot.ErrorForbidden)); ok {
+		t.Fatal("403 must not be retried")
+	}
+	if _, ok := retryDelay(errors.New("network")); ok {
+		t.Fatal("other errors must not be retried")
+	}
+}
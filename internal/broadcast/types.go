@@ -1,22 +1,38 @@
 package broadcast
 
+import (
+	"time"
+
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/channel"
+	"remnawave-tg-shop-bot/internal/database"
+)
+
 // RecipientButtons — inline-кнопки под сообщением рассылки (как в TG-админке).
 type RecipientButtons struct {
-	Buy      bool
-	MainMenu bool
-	Promo    bool
-	Connect  bool
+	Buy      bool `json:"buy"`
+	MainMenu bool `json:"main_menu"`
+	Promo    bool `json:"promo"`
+	Connect  bool `json:"connect"`
 }
 
 // Media — прикреплённое изображение (file_id из Telegram Bot API).
-type Media struct {
-	FileID  string
-	AsPhoto bool
-}
+type Media = channel.Media
 
-// SendResult — итог массовой отправки.
-type SendResult struct {
-	TotalUsers  int
-	SentCount   int
-	FailedCount int
+// Draft — рассылка, которую ставят в очередь из бота или кабинета.
+type Draft struct {
+	Source              string // "bot" или "cabinet"
+	CreatedByTelegramID int64
+	CreatedByAccountID  int64
+	NotifyChatID        int64 // куда прислать итог; 0 — никуда
+	Audience            string
+	TariffID            *int64
+	SegmentID           *int64                  // сохранённый сегмент, из которого взят Segment
+	Segment             *database.SegmentFilter // задан — получатели по сегменту, Audience и TariffID не используются
+	Text                string
+	Entities            []models.MessageEntity
+	Media               *Media
+	Buttons             RecipientButtons
+	ScheduledAt         time.Time // нулевое — отправить сразу
 }
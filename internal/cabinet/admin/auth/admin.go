@@ -1,8 +1,8 @@
-// Package adminauth проверяет, является ли аккаунт кабинета администратором.
+// Package adminauth проверяет, является ли аккаунт кабинета сотрудником админки.
 //
 // Логика: cabinet_identity(provider=telegram, unlinked_at IS NULL) →
-// provider_user_id == ADMIN_TELEGRAM_ID. Без новых env — привязан ли Telegram
-// аккаунта к тому же ID, что и в боте.
+// provider_user_id — ADMIN_TELEGRAM_ID или сотрудник из таблицы staff.
+// Роль и права те же, что и в админке бота (пакет staff).
 package adminauth
 
 import (
@@ -12,7 +12,7 @@ import (
 	"strings"
 
 	"remnawave-tg-shop-bot/internal/cabinet/repository"
-	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/staff"
 )
 
 // Checker проверяет admin-статус аккаунта.
@@ -25,18 +25,15 @@ func NewChecker(ids *repository.IdentityRepo) *Checker {
 	return &Checker{ids: ids}
 }
 
-// IsAdmin возвращает true, если у аккаунта есть привязанный Telegram identity
-// с provider_user_id == ADMIN_TELEGRAM_ID.
-func (c *Checker) IsAdmin(ctx context.Context, accountID int64) bool {
-	adminTgID := config.GetAdminTelegramId()
-	if adminTgID <= 0 {
-		return false
+// Member возвращает сотрудника, чей Telegram привязан к аккаунту; false — аккаунт не сотрудник.
+func (c *Checker) Member(ctx context.Context, accountID int64) (staff.Member, bool) {
+	if !staff.Configured() {
+		return staff.Member{}, false
 	}
-
 	ids, err := c.ids.ListLinkedByAccount(ctx, accountID)
 	if err != nil {
 		slog.Warn("admin checker: list identities", "account_id", accountID, "error", err)
-		return false
+		return staff.Member{}, false
 	}
 
 	for _, id := range ids {
@@ -51,9 +48,21 @@ func (c *Checker) IsAdmin(ctx context.Context, accountID int64) bool {
 		if perr != nil {
 			continue
 		}
-		if v == adminTgID {
-			return true
+		if m, ok := staff.Lookup(v); ok {
+			return m, true
 		}
 	}
-	return false
+	return staff.Member{}, false
+}
+
+// IsAdmin возвращает true, если аккаунт — сотрудник (хотя бы с одним разделом админки).
+func (c *Checker) IsAdmin(ctx context.Context, accountID int64) bool {
+	_, ok := c.Member(ctx, accountID)
+	return ok
+}
+
+// Can возвращает true, если у сотрудника аккаунта есть право perm.
+func (c *Checker) Can(ctx context.Context, accountID int64, perm staff.Permission) bool {
+	m, ok := c.Member(ctx, accountID)
+	return ok && m.Has(perm)
 }
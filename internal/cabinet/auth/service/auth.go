@@ -31,6 +31,7 @@ import (
 
 	"github.com/google/uuid"
 
+	"remnawave-tg-shop-bot/internal/acquisition"
 	"remnawave-tg-shop-bot/internal/cabinet/auth/csrf"
 	"remnawave-tg-shop-bot/internal/cabinet/auth/jwt"
 	"remnawave-tg-shop-bot/internal/cabinet/auth/password"
@@ -193,6 +194,20 @@ func (s *Service) persistTelegramUsernameForAccount(ctx context.Context, account
 	}
 }
 
+// persistAcquisitionForAccount — источник привлечения на customer нового аккаунта (best-effort).
+func (s *Service) persistAcquisitionForAccount(ctx context.Context, accountID int64, tag acquisition.Tag) {
+	if tag.Empty() || s.lookupLinks == nil || s.lookupCustomers == nil {
+		return
+	}
+	link, err := s.lookupLinks.FindByAccountID(ctx, accountID)
+	if err != nil || link == nil {
+		return
+	}
+	if err := s.lookupCustomers.SetAcquisitionSource(ctx, link.CustomerID, tag.Source, tag.UTM); err != nil {
+		slog.Warn("auth: persist acquisition source", "account_id", accountID, "customer_id", link.CustomerID, "error", err)
+	}
+}
+
 // SetTelegramCustomerLookup подключает поиск аккаунта по customer.telegram_id
 // и cabinet_account_customer_link при Telegram Login. Нужен после link/merge:
 // у customer уже реальный telegram_id, а cabinet_identity(telegram) могла не
@@ -257,8 +272,10 @@ type RegisterInput struct {
 	Language string // "ru" / "en" или ""
 	// ReferralCode — опционально: ref_<telegram_id> реферера (как deep-link бота) или число.
 	ReferralCode string
-	UserAgent    string
-	IP           string
+	// Acquisition — источник привлечения из utm_* страницы регистрации; пустой — не записывается.
+	Acquisition acquisition.Tag
+	UserAgent   string
+	IP          string
 }
 
 // RegisterResult — что вернуть клиенту. Сознательно без account.id и прочего:
@@ -324,6 +341,7 @@ func (s *Service) Register(ctx context.Context, in RegisterInput) (*RegisterResu
 	s.ensureCustomer(ctx, acc.ID, acc.Language)
 
 	s.attachReferralBestEffort(ctx, acc.ID, acc.Language, in.ReferralCode)
+	s.persistAcquisitionForAccount(ctx, acc.ID, in.Acquisition)
 
 	if err := s.sendVerifyEmail(ctx, acc); err != nil {
 		slog.Warn("failed to send verify email", "account_id", acc.ID, "error", err)
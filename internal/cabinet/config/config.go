@@ -9,6 +9,8 @@
 package config
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"fmt"
 	"log/slog"
 	"net/url"
@@ -44,6 +46,7 @@ type cabinet struct {
 	cookieDomain   string
 
 	jwtSecret         []byte
+	unsubscribeSecret []byte
 	accessTTLMinutes  int
 	refreshTTLDays    int
 	webTelegramIDBase int64
@@ -128,6 +131,10 @@ func CookieDomain() string { return conf.cookieDomain }
 // JWTSecret — секрет подписи access-токенов. Байты, а не строка (чтобы не ловить случайных trim).
 func JWTSecret() []byte { return conf.jwtSecret }
 
+// EmailUnsubscribeSecret — ключ подписи ссылок отписки от писем. Выводится из CABINET_JWT_SECRET
+// отдельно от ключа JWT, чтобы подпись одного назначения не подходила для другого.
+func EmailUnsubscribeSecret() []byte { return conf.unsubscribeSecret }
+
 // AccessTTLMinutes — TTL access-токена, минуты.
 func AccessTTLMinutes() int { return conf.accessTTLMinutes }
 
@@ -354,6 +361,7 @@ func InitConfig() {
 		panic("CABINET_JWT_SECRET is required and must be >= 32 bytes (openssl rand -hex 32)")
 	}
 	conf.jwtSecret = []byte(secret)
+	conf.unsubscribeSecret = deriveSecret(conf.jwtSecret, "email-unsubscribe")
 
 	conf.accessTTLMinutes = envIntDefault("CABINET_ACCESS_TTL_MINUTES", 15)
 	if conf.accessTTLMinutes <= 0 {
@@ -611,3 +619,10 @@ func envBool(key string, def bool) bool {
 	}
 	return v == "true" || v == "1" || v == "yes" || v == "on"
 }
+
+// deriveSecret — отдельный ключ для назначения purpose: HMAC-SHA256(secret, purpose).
+func deriveSecret(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
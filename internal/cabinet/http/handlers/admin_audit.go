@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/database"
+)
+
+// AdminAuditHandler — журнал действий сотрудников: /cabinet/api/admin/audit
+// и история клиента в карточке (/cabinet/api/admin/users/{id}/audit).
+type AdminAuditHandler struct {
+	repo *database.AdminAuditRepository
+}
+
+// NewAdminAudit — конструктор.
+func NewAdminAudit(repo *database.AdminAuditRepository) *AdminAuditHandler {
+	return &AdminAuditHandler{repo: repo}
+}
+
+type adminAuditEntryDTO struct {
+	ID              int64           `json:"id"`
+	CreatedAt       string          `json:"created_at"`
+	Source          string          `json:"source"`
+	ActorTelegramID *int64          `json:"actor_telegram_id"`
+	ActorAccountID  *int64          `json:"actor_account_id"`
+	Action          string          `json:"action"`
+	CustomerID      *int64          `json:"customer_id"`
+	TargetType      string          `json:"target_type"`
+	TargetID        string          `json:"target_id"`
+	Before          json.RawMessage `json:"before"`
+	After           json.RawMessage `json:"after"`
+}
+
+type adminAuditListResp struct {
+	Items  []adminAuditEntryDTO `json:"items"`
+	Total  int64                `json:"total"`
+	Limit  int                  `json:"limit"`
+	Offset int                  `json:"offset"`
+}
+
+func mapAuditEntryToDTO(e *database.AdminAuditEntry) adminAuditEntryDTO {
+	dto := adminAuditEntryDTO{
+		ID:              e.ID,
+		CreatedAt:       e.CreatedAt.Format(time.RFC3339),
+		Source:          e.Source,
+		ActorTelegramID: e.ActorTelegramID,
+		ActorAccountID:  e.ActorAccountID,
+		Action:          e.Action,
+		CustomerID:      e.CustomerID,
+		TargetType:      e.TargetType,
+		TargetID:        e.TargetID,
+		Before:          e.Before,
+		After:           e.After,
+	}
+	if len(dto.Before) == 0 {
+		dto.Before = json.RawMessage("null")
+	}
+	if len(dto.After) == 0 {
+		dto.After = json.RawMessage("null")
+	}
+	return dto
+}
+
+// List — GET /cabinet/api/admin/audit?customer_id=&actor=&source=&action=&target_type=&from=&to=&limit=&offset=.
+// action с точкой на конце («user.») — все действия с этим префиксом; from/to — RFC3339 или YYYY-MM-DD.
+func (h *AdminAuditHandler) List(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	f := database.AdminAuditFilter{
+		Source:     strings.TrimSpace(q.Get("source")),
+		Action:     strings.TrimSpace(q.Get("action")),
+		TargetType: strings.TrimSpace(q.Get("target_type")),
+	}
+	for _, p := range []struct {
+		key string
+		dst *int64
+	}{{"customer_id", &f.CustomerID}, {"actor", &f.ActorTelegramID}} {
+		if s := q.Get(p.key); s != "" {
+			v, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || v <= 0 {
+				http.Error(w, "invalid "+p.key, http.StatusBadRequest)
+				return
+			}
+			*p.dst = v
+		}
+	}
+	if f.Source != "" && f.Source != "bot" && f.Source != "cabinet" {
+		http.Error(w, "invalid source", http.StatusBadRequest)
+		return
+	}
+	for _, p := range []struct {
+		key string
+		dst **time.Time
+	}{{"from", &f.From}, {"to", &f.To}} {
+		if s := strings.TrimSpace(q.Get(p.key)); s != "" {
+			t, err := parseAuditTime(s)
+			if err != nil {
+				http.Error(w, "invalid "+p.key, http.StatusBadRequest)
+				return
+			}
+			if p.key == "to" && len(s) == len("2006-01-02") {
+				t = t.AddDate(0, 0, 1) // дата в to — включительно
+			}
+			*p.dst = &t
+		}
+	}
+	h.list(w, r, f)
+}
+
+// SetAuditLog подключает историю действий в карточке клиента (/users/{id}/audit).
+func (h *AdminUsersHandler) SetAuditLog(a *AdminAuditHandler) {
+	h.auditLog = a
+}
+
+// Audit — GET /cabinet/api/admin/users/{id}/audit.
+func (h *AdminUsersHandler) Audit(w http.ResponseWriter, r *http.Request) {
+	if h.auditLog == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	id, ok := adminUsersExtractID(r.URL.Path)
+	if !ok {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	h.auditLog.ForCustomer(w, r, id)
+}
+
+// ForCustomer — GET /cabinet/api/admin/users/{id}/audit: действия сотрудников над клиентом.
+func (h *AdminAuditHandler) ForCustomer(w http.ResponseWriter, r *http.Request, customerID int64) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.list(w, r, database.AdminAuditFilter{CustomerID: customerID})
+}
+
+func (h *AdminAuditHandler) list(w http.ResponseWriter, r *http.Request, f database.AdminAuditFilter) {
+	q := r.URL.Query()
+	f.Limit, _ = strconv.Atoi(q.Get("limit"))
+	if f.Limit < 1 {
+		f.Limit = 50
+	}
+	if f.Limit > 200 {
+		f.Limit = 200
+	}
+	f.Offset, _ = strconv.Atoi(q.Get("offset"))
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+	items, total, err := h.repo.List(r.Context(), f)
+	if err != nil {
+		slog.Error("admin audit: list failed", "error", err.Error())
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	out := make([]adminAuditEntryDTO, 0, len(items))
+	for i := range items {
+		out = append(out, mapAuditEntryToDTO(&items[i]))
+	}
+	writeJSON(w, http.StatusOK, adminAuditListResp{Items: out, Total: total, Limit: f.Limit, Offset: f.Offset})
+}
+
+// parseAuditTime — RFC3339 или дата YYYY-MM-DD (начало суток UTC).
+func parseAuditTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
@@ -4,7 +4,9 @@ import (
 	"net/http"
 
 	cabcfg "remnawave-tg-shop-bot/internal/cabinet/config"
+	"remnawave-tg-shop-bot/internal/cabinet/http/middleware"
 	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/staff"
 )
 
 // AdminBootstrapHandler — эндпоинт GET /cabinet/api/admin/bootstrap.
@@ -20,6 +22,9 @@ type adminBootstrapResp struct {
 	SalesMode      string `json:"sales_mode"`
 	LoyaltyEnabled bool   `json:"loyalty_enabled"`
 	FortuneEnabled bool   `json:"fortune_enabled"`
+	// Role и Permissions — роль и права сотрудника: SPA скрывает недоступные разделы.
+	Role        staff.Role         `json:"role"`
+	Permissions []staff.Permission `json:"permissions"`
 }
 
 // Bootstrap — GET /cabinet/api/admin/bootstrap (RequireAdmin).
@@ -33,6 +38,11 @@ func (h *AdminBootstrapHandler) Bootstrap(w http.ResponseWriter, r *http.Request
 		SalesMode:      config.SalesMode(),
 		LoyaltyEnabled: config.LoyaltyEnabled(),
 		FortuneEnabled: fw.Enabled,
+		Permissions:    []staff.Permission{},
+	}
+	if m, ok := middleware.StaffFromContext(r.Context()); ok {
+		resp.Role = m.Role
+		resp.Permissions = m.Permissions
 	}
 	writeJSON(w, http.StatusOK, resp)
 }
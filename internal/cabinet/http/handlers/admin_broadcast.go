@@ -2,18 +2,18 @@ package handlers
 
 import (
 	"bytes"
-	"context"
 	"io"
 	"log/slog"
 	"mime"
 	"net/http"
 	"strconv"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 
+	"remnawave-tg-shop-bot/internal/audit"
 	"remnawave-tg-shop-bot/internal/broadcast"
 	"remnawave-tg-shop-bot/internal/config"
 	"remnawave-tg-shop-bot/internal/database"
@@ -21,30 +21,10 @@ import (
 
 const broadcastMediaMaxBytes = 10 << 20 // 10 MiB
 
-var (
-	broadcastSendMu      sync.Mutex
-	broadcastSendRunning bool
-)
-
-func tryAcquireBroadcastSend() bool {
-	broadcastSendMu.Lock()
-	defer broadcastSendMu.Unlock()
-	if broadcastSendRunning {
-		return false
-	}
-	broadcastSendRunning = true
-	return true
-}
-
-func releaseBroadcastSend() {
-	broadcastSendMu.Lock()
-	broadcastSendRunning = false
-	broadcastSendMu.Unlock()
-}
-
 type AdminBroadcastHandler struct {
 	customers *database.CustomerRepository
 	tariffs   *database.TariffRepository
+	segments  *database.CustomerSegmentRepository
 	sender    *broadcast.Sender
 	bot       *bot.Bot
 }
@@ -52,12 +32,14 @@ type AdminBroadcastHandler struct {
 func NewAdminBroadcast(
 	customers *database.CustomerRepository,
 	tariffs *database.TariffRepository,
+	segments *database.CustomerSegmentRepository,
 	sender *broadcast.Sender,
 	tgBot *bot.Bot,
 ) *AdminBroadcastHandler {
 	return &AdminBroadcastHandler{
 		customers: customers,
 		tariffs:   tariffs,
+		segments:  segments,
 		sender:    sender,
 		bot:       tgBot,
 	}
@@ -131,11 +113,17 @@ type broadcastSendReq struct {
 	Text     string             `json:"text"`
 	Buttons  *broadcastButtonsReq `json:"buttons,omitempty"`
 	Media    *broadcastMediaReq   `json:"media,omitempty"`
+	// SegmentID — сохранённый сегмент, Segment — фильтр без сохранения; заданы — audience не нужен.
+	SegmentID *int64                  `json:"segment_id,omitempty"`
+	Segment   *database.SegmentFilter `json:"segment,omitempty"`
+	// ScheduledAt — время запуска в RFC3339; пусто или в прошлом — сразу.
+	ScheduledAt string `json:"scheduled_at,omitempty"`
 }
 
 func (req *broadcastSendReq) normalize() {
 	req.Text = strings.TrimSpace(req.Text)
 	req.Audience = strings.TrimSpace(req.Audience)
+	req.ScheduledAt = strings.TrimSpace(req.ScheduledAt)
 	if req.Media != nil {
 		req.Media.FileID = strings.TrimSpace(req.Media.FileID)
 		if req.Media.FileID == "" {
@@ -144,6 +132,41 @@ func (req *broadcastSendReq) normalize() {
 	}
 }
 
+// resolveSegment — фильтр сегмента из запроса (сохранённый или inline); nil — рассылка по audience.
+// Ошибка уже записана в w, тогда ok=false.
+func (h *AdminBroadcastHandler) resolveSegment(w http.ResponseWriter, r *http.Request, req *broadcastSendReq) (*database.SegmentFilter, bool) {
+	switch {
+	case req.SegmentID != nil:
+		seg, err := h.segments.FindByID(r.Context(), *req.SegmentID)
+		if err != nil {
+			slog.Error("admin broadcast: load segment", "id", *req.SegmentID, "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return nil, false
+		}
+		if seg == nil {
+			http.Error(w, "segment not found", http.StatusBadRequest)
+			return nil, false
+		}
+		return &seg.Filter, true
+	case req.Segment != nil:
+		req.Segment.Normalize()
+		if err := req.Segment.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+		return req.Segment, true
+	}
+	if req.Audience == "" {
+		http.Error(w, "audience required", http.StatusBadRequest)
+		return nil, false
+	}
+	if !isValidBroadcastAudience(req.Audience) {
+		http.Error(w, "invalid audience", http.StatusBadRequest)
+		return nil, false
+	}
+	return nil, true
+}
+
 func (req *broadcastSendReq) hasContent() bool {
 	return req.Text != "" || req.Media != nil
 }
@@ -170,7 +193,8 @@ func (req *broadcastSendReq) recipientMedia() *broadcast.Media {
 	}
 }
 
-// Preview returns the count of recipients for a given audience/tariff combo.
+// Preview returns the count of recipients for a given audience/tariff combo or segment.
+// Для сегмента дополнительно segment_count: все клиенты сегмента и те, кому уйдёт сообщение в Telegram.
 func (h *AdminBroadcastHandler) Preview(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -182,12 +206,22 @@ func (h *AdminBroadcastHandler) Preview(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	req.normalize()
-	if req.Audience == "" {
-		http.Error(w, "audience required", http.StatusBadRequest)
+	segment, ok := h.resolveSegment(w, r, &req)
+	if !ok {
 		return
 	}
-	if !isValidBroadcastAudience(req.Audience) {
-		http.Error(w, "invalid audience", http.StatusBadRequest)
+	if segment != nil {
+		count, err := h.customers.CountSegment(r.Context(), *segment)
+		if err != nil {
+			slog.Error("admin broadcast: segment preview", "error", err)
+			http.Error(w, "failed to count recipients", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"audience":        database.BroadcastAudienceSegment,
+			"recipient_count": count.Telegram + count.Email,
+			"segment_count":   count,
+		})
 		return
 	}
 
@@ -359,7 +393,8 @@ func extractTelegramFileID(msg *models.Message) (fileID string, asPhoto bool) {
 	return "", false
 }
 
-// Send starts a Telegram broadcast using the same delivery logic as TG admin panel.
+// Send ставит рассылку в очередь (broadcast_job). scheduled_at (RFC3339) — отложенный запуск;
+// получатели выбираются в момент запуска, итог приходит ADMIN_TELEGRAM_ID в Telegram.
 func (h *AdminBroadcastHandler) Send(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -375,70 +410,91 @@ func (h *AdminBroadcastHandler) Send(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	req.normalize()
-	if req.Audience == "" {
-		http.Error(w, "audience required", http.StatusBadRequest)
-		return
-	}
-	if !isValidBroadcastAudience(req.Audience) {
-		http.Error(w, "invalid audience", http.StatusBadRequest)
+	segment, ok := h.resolveSegment(w, r, &req)
+	if !ok {
 		return
 	}
 	if !req.hasContent() {
 		http.Error(w, "text or media required", http.StatusBadRequest)
 		return
 	}
+	var scheduledAt time.Time
+	if req.ScheduledAt != "" {
+		t, err := time.Parse(time.RFC3339, req.ScheduledAt)
+		if err != nil {
+			http.Error(w, "invalid scheduled_at", http.StatusBadRequest)
+			return
+		}
+		if t.After(time.Now()) {
+			scheduledAt = t
+		}
+	}
 
-	recipients, err := h.customers.GetBroadcastRecipients(r.Context(), req.Audience, req.TariffID)
+	var (
+		recipients []database.BroadcastRecipient
+		err        error
+	)
+	if segment != nil {
+		recipients, err = h.customers.GetSegmentRecipients(r.Context(), *segment)
+	} else {
+		recipients, err = h.customers.GetBroadcastRecipients(r.Context(), req.Audience, req.TariffID)
+	}
 	if err != nil {
 		slog.Error("admin broadcast: send", "error", err)
 		http.Error(w, "failed to get recipients", http.StatusInternalServerError)
 		return
 	}
-	if len(recipients) == 0 {
+	// Для отложенной рассылки аудитория ещё может пополниться.
+	if len(recipients) == 0 && scheduledAt.IsZero() {
 		http.Error(w, "no recipients", http.StatusBadRequest)
 		return
 	}
-	if !tryAcquireBroadcastSend() {
-		http.Error(w, "broadcast already in progress", http.StatusConflict)
+
+	actor := adminAuditActor(r)
+	job, err := h.sender.Enqueue(r.Context(), broadcast.Draft{
+		Source:              string(audit.SourceCabinet),
+		CreatedByTelegramID: actor.TelegramID,
+		CreatedByAccountID:  actor.AccountID,
+		NotifyChatID:        config.GetAdminTelegramId(),
+		Audience:            req.Audience,
+		TariffID:            req.TariffID,
+		SegmentID:           req.SegmentID,
+		Segment:             segment,
+		Text:                req.Text,
+		Media:               req.recipientMedia(),
+		Buttons:             req.recipientButtons(),
+		ScheduledAt:         scheduledAt,
+	})
+	if err != nil {
+		slog.Error("admin broadcast: enqueue", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
 
-	adminID := config.GetAdminTelegramId()
-	payload := req
 	recipientCount := len(recipients)
-
-	go func() {
-		defer releaseBroadcastSend()
-		ctx := context.Background()
-		if adminID != 0 {
-			_, _ = h.bot.SendMessage(ctx, &bot.SendMessageParams{
-				ChatID: adminID,
-				Text:   "⏳ Рассылка из web-админки запущена. Ожидайте итоговое сообщение…",
-			})
-		}
-		h.sender.Send(
-			ctx,
-			h.bot,
-			adminID,
-			payload.Audience,
-			payload.TariffID,
-			payload.Text,
-			nil,
-			payload.recipientMedia(),
-			payload.recipientButtons(),
-		)
-	}()
-
-	slog.Info("admin broadcast: send started",
-		"audience", req.Audience,
+	slog.Info("admin broadcast: job created",
+		"job_id", job.ID,
+		"audience", job.Audience,
 		"recipients", recipientCount,
+		"scheduled_at", job.ScheduledAt,
 		"text_len", len(req.Text),
 		"has_media", req.Media != nil,
 	)
+	audit.Record(r.Context(), actor, audit.Entry{
+		Action: audit.ActionBroadcastSend, TargetType: "broadcast", TargetID: strconv.FormatInt(job.ID, 10),
+		After: map[string]any{"audience": job.Audience, "tariff_id": job.TariffID, "segment_id": req.SegmentID, "segment": segment,
+			"recipients": recipientCount, "text": req.Text,
+			"scheduled_at": job.ScheduledAt.UTC().Format(time.RFC3339)},
+	})
 
+	status := "started"
+	if !scheduledAt.IsZero() {
+		status = "scheduled"
+	}
 	writeJSON(w, http.StatusAccepted, map[string]any{
-		"status":          "started",
+		"status":          status,
 		"recipient_count": recipientCount,
+		"job":             broadcastJobToDTO(job),
 	})
 }
 
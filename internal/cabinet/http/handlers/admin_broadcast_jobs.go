@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/audit"
+	"remnawave-tg-shop-bot/internal/database"
+)
+
+type broadcastJobDTO struct {
+	ID           int64           `json:"id"`
+	Status       string          `json:"status"`
+	Source       string          `json:"source"`
+	Audience     string          `json:"audience"`
+	TariffID     *int64          `json:"tariff_id"`
+	SegmentID    *int64          `json:"segment_id"`
+	Segment      json.RawMessage `json:"segment"`
+	Text         string          `json:"text"`
+	HasMedia     bool            `json:"has_media"`
+	Buttons      json.RawMessage `json:"buttons"`
+	ScheduledAt  time.Time       `json:"scheduled_at"`
+	StartedAt    *time.Time      `json:"started_at"`
+	FinishedAt   *time.Time      `json:"finished_at"`
+	TotalCount   int             `json:"total_count"`
+	SentCount    int             `json:"sent_count"`
+	FailedCount  int             `json:"failed_count"`
+	PendingCount int             `json:"pending_count"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+func broadcastJobToDTO(j *database.BroadcastJob) broadcastJobDTO {
+	dto := broadcastJobDTO{
+		ID: j.ID, Status: string(j.Status), Source: j.Source, Audience: j.Audience, TariffID: j.TariffID,
+		SegmentID: j.SegmentID, Segment: j.SegmentFilter,
+		Text: j.MessageText, HasMedia: j.MediaFileID != nil, Buttons: j.Buttons,
+		ScheduledAt: j.ScheduledAt, StartedAt: j.StartedAt, FinishedAt: j.FinishedAt,
+		TotalCount: j.TotalCount, SentCount: j.SentCount, FailedCount: j.FailedCount, CreatedAt: j.CreatedAt,
+	}
+	if len(dto.Buttons) == 0 {
+		dto.Buttons = json.RawMessage("{}")
+	}
+	if len(dto.Segment) == 0 {
+		dto.Segment = json.RawMessage("null")
+	}
+	if j.Status != database.BroadcastJobStatusCanceled {
+		dto.PendingCount = max(j.TotalCount-j.SentCount-j.FailedCount, 0)
+	}
+	return dto
+}
+
+func extractBroadcastJobID(path string) (int64, string, bool) {
+	s := strings.TrimPrefix(path, "/cabinet/api/admin/broadcast/jobs/")
+	s = strings.TrimRight(s, "/")
+	idPart, action, _ := strings.Cut(s, "/")
+	id, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, "", false
+	}
+	return id, action, true
+}
+
+// Jobs — GET /cabinet/api/admin/broadcast/jobs?limit=&offset=: рассылки, новые первыми.
+func (h *AdminBroadcastHandler) Jobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.sender == nil {
+		http.Error(w, "broadcast unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset, _ := strconv.Atoi(q.Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	jobs, total, err := h.sender.List(r.Context(), limit, offset)
+	if err != nil {
+		slog.Error("admin broadcast: list jobs", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	items := make([]broadcastJobDTO, 0, len(jobs))
+	for i := range jobs {
+		items = append(items, broadcastJobToDTO(&jobs[i]))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": items, "total": total, "limit": limit, "offset": offset})
+}
+
+// JobByID — GET /cabinet/api/admin/broadcast/jobs/{id} и POST …/{id}/pause|resume|cancel.
+func (h *AdminBroadcastHandler) JobByID(w http.ResponseWriter, r *http.Request) {
+	if h.sender == nil {
+		http.Error(w, "broadcast unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	id, action, ok := extractBroadcastJobID(r.URL.Path)
+	if !ok {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	var (
+		job       *database.BroadcastJob
+		err       error
+		auditName string
+	)
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		job, err = h.sender.Get(ctx, id)
+	case action == "pause" && r.Method == http.MethodPost:
+		job, err = h.sender.Pause(ctx, id)
+		auditName = audit.ActionBroadcastPause
+	case action == "resume" && r.Method == http.MethodPost:
+		job, err = h.sender.Resume(ctx, id)
+		auditName = audit.ActionBroadcastResume
+	case action == "cancel" && r.Method == http.MethodPost:
+		job, err = h.sender.Cancel(ctx, id)
+		auditName = audit.ActionBroadcastCancel
+	case action != "" && action != "pause" && action != "resume" && action != "cancel":
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if errors.Is(err, database.ErrBroadcastJobState) {
+		http.Error(w, "broadcast is already finished", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		slog.Error("admin broadcast: job", "id", id, "action", action, "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if job == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if auditName != "" {
+		audit.Record(ctx, adminAuditActor(r), audit.Entry{
+			Action: auditName, TargetType: "broadcast", TargetID: strconv.FormatInt(job.ID, 10),
+			After: map[string]any{"status": job.Status},
+		})
+	}
+	writeJSON(w, http.StatusOK, broadcastJobToDTO(job))
+}
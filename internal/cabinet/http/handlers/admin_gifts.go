@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/database"
+)
+
+// AdminGiftsHandler — GET /cabinet/api/admin/gifts?status=&limit=&offset= — выпущенные подарочные коды.
+// status: active | redeemed | revoked | expired; пусто — все.
+type AdminGiftsHandler struct {
+	gifts *database.GiftRepository
+}
+
+// NewAdminGifts — конструктор.
+func NewAdminGifts(gifts *database.GiftRepository) *AdminGiftsHandler {
+	return &AdminGiftsHandler{gifts: gifts}
+}
+
+type adminGiftDTO struct {
+	ID                   int64   `json:"id"`
+	Code                 string  `json:"code"`
+	PurchaseID           int64   `json:"purchase_id"`
+	BuyerCustomerID      int64   `json:"buyer_customer_id"`
+	Months               int     `json:"months"`
+	TariffID             *int64  `json:"tariff_id"`
+	Status               string  `json:"status"`
+	ExpiresAt            string  `json:"expires_at"`
+	RedeemedByCustomerID *int64  `json:"redeemed_by_customer_id"`
+	RedeemedAt           *string `json:"redeemed_at"`
+	CreatedAt            string  `json:"created_at"`
+}
+
+func mapGiftToDTO(g *database.GiftSubscription, now time.Time) adminGiftDTO {
+	dto := adminGiftDTO{
+		ID:                   g.ID,
+		Code:                 g.Code,
+		PurchaseID:           g.PurchaseID,
+		BuyerCustomerID:      g.BuyerCustomerID,
+		Months:               g.Months,
+		TariffID:             g.TariffID,
+		Status:               string(g.EffectiveStatus(now)),
+		ExpiresAt:            g.ExpiresAt.Format(time.RFC3339),
+		RedeemedByCustomerID: g.RedeemedByCustomerID,
+		CreatedAt:            g.CreatedAt.Format(time.RFC3339),
+	}
+	if g.RedeemedAt != nil {
+		s := g.RedeemedAt.Format(time.RFC3339)
+		dto.RedeemedAt = &s
+	}
+	return dto
+}
+
+// List — GET /cabinet/api/admin/gifts.
+func (h *AdminGiftsHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	status := database.GiftStatus(q.Get("status"))
+	switch status {
+	case "", database.GiftStatusActive, database.GiftStatusRedeemed, database.GiftStatusRevoked, database.GiftStatusExpired:
+	default:
+		http.Error(w, "invalid status", http.StatusBadRequest)
+		return
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset, _ := strconv.Atoi(q.Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	items, err := h.gifts.ListRecent(r.Context(), status, limit, offset)
+	if err != nil {
+		slog.Error("admin gifts: list failed", "error", err.Error())
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	now := time.Now().UTC()
+	out := make([]adminGiftDTO, 0, len(items))
+	for i := range items {
+		out = append(out, mapGiftToDTO(&items[i], now))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": out})
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/google/uuid"
 
+	"remnawave-tg-shop-bot/internal/audit"
 	"remnawave-tg-shop-bot/internal/database"
 	"remnawave-tg-shop-bot/internal/remnawave"
 )
@@ -101,6 +102,9 @@ func (h *AdminInfraHandler) CreateNode(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "failed to create node", http.StatusInternalServerError)
 		return
 	}
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionInfraCreate, TargetType: "infra_node", TargetID: req.NodeUUID, After: req,
+	})
 	writeJSON(w, http.StatusOK, body)
 }
 
@@ -144,6 +148,9 @@ func (h *AdminInfraHandler) PatchNode(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "failed to update node", http.StatusInternalServerError)
 		return
 	}
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionInfraUpdate, TargetType: "infra_node", TargetID: req.UUID, After: req,
+	})
 	writeJSON(w, http.StatusOK, body)
 }
 
@@ -169,6 +176,9 @@ func (h *AdminInfraHandler) DeleteNode(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "failed to delete node", http.StatusInternalServerError)
 		return
 	}
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionInfraDelete, TargetType: "infra_node", TargetID: id.String(),
+	})
 	writeJSON(w, http.StatusOK, body)
 }
 
@@ -235,6 +245,9 @@ func (h *AdminInfraHandler) CreateProvider(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "failed to create provider", http.StatusInternalServerError)
 		return
 	}
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionInfraCreate, TargetType: "infra_provider", After: req,
+	})
 	writeJSON(w, http.StatusOK, body)
 }
 
@@ -285,6 +298,9 @@ func (h *AdminInfraHandler) PatchProvider(w http.ResponseWriter, r *http.Request
 		http.Error(w, "failed to update provider", http.StatusInternalServerError)
 		return
 	}
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionInfraUpdate, TargetType: "infra_provider", TargetID: req.UUID, After: req,
+	})
 	writeJSON(w, http.StatusOK, body)
 }
 
@@ -309,6 +325,9 @@ func (h *AdminInfraHandler) DeleteProvider(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "failed to delete provider", http.StatusInternalServerError)
 		return
 	}
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionInfraDelete, TargetType: "infra_provider", TargetID: id.String(),
+	})
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
 
@@ -390,6 +409,9 @@ func (h *AdminInfraHandler) CreateHistory(w http.ResponseWriter, r *http.Request
 		http.Error(w, "failed to create history record", http.StatusInternalServerError)
 		return
 	}
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionInfraCreate, TargetType: "infra_history", After: req,
+	})
 	writeJSON(w, http.StatusOK, body)
 }
 
@@ -414,6 +436,9 @@ func (h *AdminInfraHandler) DeleteHistory(w http.ResponseWriter, r *http.Request
 		http.Error(w, "failed to delete history record", http.StatusInternalServerError)
 		return
 	}
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionInfraDelete, TargetType: "infra_history", TargetID: id.String(),
+	})
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
 
@@ -460,6 +485,9 @@ func (h *AdminInfraHandler) UpdateSettings(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionInfraUpdate, TargetType: "infra_settings", After: req,
+	})
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
 
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"sync/atomic"
 
+	"remnawave-tg-shop-bot/internal/audit"
 	"remnawave-tg-shop-bot/internal/database"
 	"remnawave-tg-shop-bot/internal/loyalty"
 )
@@ -118,6 +119,9 @@ func (h *AdminLoyaltyHandler) CreateTier(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	dto := tierToDTO(tier)
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionLoyaltyTierCreate, TargetType: "loyalty_tier", TargetID: strconv.FormatInt(id, 10), After: dto,
+	})
 	writeJSON(w, http.StatusCreated, dto)
 }
 
@@ -189,6 +193,10 @@ func (h *AdminLoyaltyHandler) UpdateTier(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	dto := tierToDTO(tier)
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionLoyaltyTierUpdate, TargetType: "loyalty_tier", TargetID: strconv.FormatInt(id, 10),
+		Before: tierToDTO(existing), After: dto,
+	})
 	writeJSON(w, http.StatusOK, dto)
 }
 
@@ -221,6 +229,10 @@ func (h *AdminLoyaltyHandler) DeleteTier(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionLoyaltyTierDelete, TargetType: "loyalty_tier", TargetID: strconv.FormatInt(id, 10),
+		Before: tierToDTO(existing),
+	})
 	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
 }
 
@@ -235,6 +247,7 @@ func (h *AdminLoyaltyHandler) TriggerRecalc(w http.ResponseWriter, r *http.Reque
 		http.Error(w, "recalc already in progress", http.StatusConflict)
 		return
 	}
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{Action: audit.ActionLoyaltyRecalc})
 
 	go func() {
 		defer atomic.StoreInt32(&recalcRunning, 0)
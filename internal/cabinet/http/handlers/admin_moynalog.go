@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/audit"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/payment"
+)
+
+// AdminMoynalogHandler — очередь чеков «Мой налог»:
+//
+//	GET  /cabinet/api/admin/moynalog-receipts?status=&limit=&offset= — чеки (по умолчанию failed + cancel_failed)
+//	POST /cabinet/api/admin/moynalog-receipts/{id}/retry             — повторить сейчас
+type AdminMoynalogHandler struct {
+	payments *payment.PaymentService
+}
+
+// NewAdminMoynalog — конструктор.
+func NewAdminMoynalog(payments *payment.PaymentService) *AdminMoynalogHandler {
+	return &AdminMoynalogHandler{payments: payments}
+}
+
+type adminMoynalogReceiptDTO struct {
+	ID            int64   `json:"id"`
+	PurchaseID    int64   `json:"purchase_id"`
+	Amount        float64 `json:"amount"`
+	Description   string  `json:"description"`
+	OperationTime string  `json:"operation_time"`
+	Status        string  `json:"status"`
+	ReceiptUUID   *string `json:"receipt_uuid"`
+	Attempts      int     `json:"attempts"`
+	LastError     *string `json:"last_error"`
+	NextAttemptAt *string `json:"next_attempt_at"`
+	CreatedAt     string  `json:"created_at"`
+	UpdatedAt     string  `json:"updated_at"`
+	SentAt        *string `json:"sent_at"`
+	CanceledAt    *string `json:"canceled_at"`
+}
+
+func mapMoynalogReceiptToDTO(rc *database.MoynalogReceipt) adminMoynalogReceiptDTO {
+	dto := adminMoynalogReceiptDTO{
+		ID:            rc.ID,
+		PurchaseID:    rc.PurchaseID,
+		Amount:        rc.Amount,
+		Description:   rc.Description,
+		OperationTime: rc.OperationTime.Format(time.RFC3339),
+		Status:        string(rc.Status),
+		ReceiptUUID:   rc.ReceiptUUID,
+		Attempts:      rc.Attempts,
+		LastError:     rc.LastError,
+		CreatedAt:     rc.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:     rc.UpdatedAt.Format(time.RFC3339),
+	}
+	if rc.NextAttemptAt != nil {
+		s := rc.NextAttemptAt.Format(time.RFC3339)
+		dto.NextAttemptAt = &s
+	}
+	if rc.SentAt != nil {
+		s := rc.SentAt.Format(time.RFC3339)
+		dto.SentAt = &s
+	}
+	if rc.CanceledAt != nil {
+		s := rc.CanceledAt.Format(time.RFC3339)
+		dto.CanceledAt = &s
+	}
+	return dto
+}
+
+// List — GET /cabinet/api/admin/moynalog-receipts.
+func (h *AdminMoynalogHandler) List(w http.ResponseWriter, r *http.Request) {
+	var statuses []database.MoynalogReceiptStatus
+	switch status := database.MoynalogReceiptStatus(strings.TrimSpace(r.URL.Query().Get("status"))); status {
+	case "":
+		statuses = []database.MoynalogReceiptStatus{database.MoynalogReceiptStatusFailed, database.MoynalogReceiptStatusCancelFailed,
+			database.MoynalogReceiptStatusNeedsAttention}
+	case "all":
+	case database.MoynalogReceiptStatusPending, database.MoynalogReceiptStatusSent, database.MoynalogReceiptStatusFailed,
+		database.MoynalogReceiptStatusCancelPending, database.MoynalogReceiptStatusCanceled, database.MoynalogReceiptStatusCancelFailed,
+		database.MoynalogReceiptStatusNeedsAttention:
+		statuses = []database.MoynalogReceiptStatus{status}
+	default:
+		http.Error(w, "invalid status", http.StatusBadRequest)
+		return
+	}
+	limit, offset := adminPartnersPaging(r)
+	items, err := h.payments.ListMoynalogReceipts(r.Context(), statuses, limit, offset)
+	if err != nil {
+		if errors.Is(err, payment.ErrMoynalogDisabled) {
+			http.Error(w, "moynalog disabled", http.StatusServiceUnavailable)
+			return
+		}
+		slog.Error("admin moynalog: list receipts failed", "error", err.Error())
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	out := make([]adminMoynalogReceiptDTO, 0, len(items))
+	for i := range items {
+		out = append(out, mapMoynalogReceiptToDTO(&items[i]))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": out})
+}
+
+// ByID — POST /cabinet/api/admin/moynalog-receipts/{id}/retry.
+func (h *AdminMoynalogHandler) ByID(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.HasSuffix(r.URL.Path, "/retry") {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	id, ok := adminPathExtractID(r.URL.Path, "moynalog-receipts")
+	if !ok {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	rc, err := h.payments.RetryMoynalogReceipt(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, payment.ErrMoynalogDisabled):
+			http.Error(w, "moynalog disabled", http.StatusServiceUnavailable)
+		case errors.Is(err, database.ErrMoynalogReceiptNotRetryable):
+			http.Error(w, "receipt not found or not in a failed state", http.StatusConflict)
+		default:
+			slog.Error("admin moynalog: retry receipt failed", "receipt_id", id, "error", err.Error())
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+	if rc == nil {
+		http.Error(w, "receipt not found", http.StatusNotFound)
+		return
+	}
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionMoynalogRetry, TargetType: "moynalog_receipt", TargetID: strconv.FormatInt(id, 10),
+		After: mapMoynalogReceiptToDTO(rc),
+	})
+	writeJSON(w, http.StatusOK, mapMoynalogReceiptToDTO(rc))
+}
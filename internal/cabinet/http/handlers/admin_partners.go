@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/audit"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/payment"
+)
+
+// AdminPartnersHandler — партнёрская программа:
+//
+//	GET  /cabinet/api/admin/partner-payouts?status=&limit=&offset= — запросы на выплату (по умолчанию pending)
+//	POST /cabinet/api/admin/partner-payouts/{id}/approve {comment}  — выплата проведена
+//	POST /cabinet/api/admin/partner-payouts/{id}/reject  {comment}  — отклонить
+//	GET  /cabinet/api/admin/partners?limit=&offset=                 — персональные ставки
+//	PUT  /cabinet/api/admin/partners/{customerId} {commission_percent, enabled, note}
+type AdminPartnersHandler struct {
+	payments *payment.PaymentService
+}
+
+// NewAdminPartners — конструктор.
+func NewAdminPartners(payments *payment.PaymentService) *AdminPartnersHandler {
+	return &AdminPartnersHandler{payments: payments}
+}
+
+type adminPayoutDTO struct {
+	ID                int64   `json:"id"`
+	PartnerCustomerID int64   `json:"partner_customer_id"`
+	Amount            float64 `json:"amount"`
+	Method            string  `json:"method"`
+	Details           *string `json:"details"`
+	Status            string  `json:"status"`
+	AdminComment      *string `json:"admin_comment"`
+	AdminTelegramID   *int64  `json:"admin_telegram_id"`
+	AdminAccountID    *int64  `json:"admin_account_id"`
+	CreatedAt         string  `json:"created_at"`
+	ProcessedAt       *string `json:"processed_at"`
+}
+
+func mapPayoutToDTO(p *database.ReferralPayout) adminPayoutDTO {
+	dto := adminPayoutDTO{
+		ID:                p.ID,
+		PartnerCustomerID: p.PartnerCustomerID,
+		Amount:            p.Amount,
+		Method:            string(p.Method),
+		Details:           p.Details,
+		Status:            string(p.Status),
+		AdminComment:      p.AdminComment,
+		AdminTelegramID:   p.AdminTelegramID,
+		AdminAccountID:    p.AdminAccountID,
+		CreatedAt:         p.CreatedAt.Format(time.RFC3339),
+	}
+	if p.ProcessedAt != nil {
+		s := p.ProcessedAt.Format(time.RFC3339)
+		dto.ProcessedAt = &s
+	}
+	return dto
+}
+
+type adminPartnerDTO struct {
+	CustomerID        int64   `json:"customer_id"`
+	CommissionPercent float64 `json:"commission_percent"`
+	Enabled           bool    `json:"enabled"`
+	Note              *string `json:"note"`
+	CreatedAt         string  `json:"created_at"`
+	UpdatedAt         string  `json:"updated_at"`
+}
+
+func mapPartnerToDTO(p *database.ReferralPartner) adminPartnerDTO {
+	return adminPartnerDTO{
+		CustomerID:        p.CustomerID,
+		CommissionPercent: p.CommissionPercent,
+		Enabled:           p.Enabled,
+		Note:              p.Note,
+		CreatedAt:         p.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:         p.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+type adminPayoutActionReq struct {
+	Comment string `json:"comment"`
+}
+
+type adminPartnerRateReq struct {
+	CommissionPercent *float64 `json:"commission_percent"`
+	Enabled           *bool    `json:"enabled"`
+	Note              string   `json:"note"`
+}
+
+// ListPayouts — GET /cabinet/api/admin/partner-payouts.
+func (h *AdminPartnersHandler) ListPayouts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	status := database.PayoutStatus(strings.TrimSpace(q.Get("status")))
+	switch status {
+	case "":
+		status = database.PayoutStatusPending
+	case "all":
+		status = ""
+	case database.PayoutStatusPending, database.PayoutStatusPaid, database.PayoutStatusRejected:
+	default:
+		http.Error(w, "invalid status", http.StatusBadRequest)
+		return
+	}
+	limit, offset := adminPartnersPaging(r)
+	items, err := h.payments.PartnerPayouts(r.Context(), status, 0, limit, offset)
+	if err != nil {
+		slog.Error("admin partners: list payouts failed", "error", err.Error())
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	out := make([]adminPayoutDTO, 0, len(items))
+	for i := range items {
+		out = append(out, mapPayoutToDTO(&items[i]))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": out})
+}
+
+// PayoutByID — POST /cabinet/api/admin/partner-payouts/{id}/approve|reject.
+func (h *AdminPartnersHandler) PayoutByID(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, ok := adminPathExtractID(r.URL.Path, "partner-payouts")
+	if !ok {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	var req adminPayoutActionReq
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	var (
+		p   *database.ReferralPayout
+		err error
+	)
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/approve"):
+		p, err = h.payments.ApprovePartnerPayout(r.Context(), id, req.Comment, 0, adminAccountID(r))
+	case strings.HasSuffix(r.URL.Path, "/reject"):
+		p, err = h.payments.RejectPartnerPayout(r.Context(), id, req.Comment, 0, adminAccountID(r))
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, payment.ErrPayoutNotFound):
+			http.Error(w, "payout not found", http.StatusNotFound)
+		case errors.Is(err, database.ErrPayoutStatusConflict):
+			http.Error(w, "payout already processed", http.StatusConflict)
+		default:
+			slog.Error("admin partners: process payout failed", "payout_id", id, "error", err.Error())
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+	action := audit.ActionPartnerPayoutApprove
+	if p.Status == database.PayoutStatusRejected {
+		action = audit.ActionPartnerPayoutReject
+	}
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: action, CustomerID: p.PartnerCustomerID,
+		TargetType: "partner_payout", TargetID: strconv.FormatInt(id, 10), After: mapPayoutToDTO(p),
+	})
+	writeJSON(w, http.StatusOK, mapPayoutToDTO(p))
+}
+
+// ListPartners — GET /cabinet/api/admin/partners.
+func (h *AdminPartnersHandler) ListPartners(w http.ResponseWriter, r *http.Request) {
+	limit, offset := adminPartnersPaging(r)
+	items, err := h.payments.ListPartners(r.Context(), limit, offset)
+	if err != nil {
+		slog.Error("admin partners: list failed", "error", err.Error())
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	out := make([]adminPartnerDTO, 0, len(items))
+	for i := range items {
+		out = append(out, mapPartnerToDTO(&items[i]))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": out})
+}
+
+// PartnerByID — PUT /cabinet/api/admin/partners/{customerId}.
+func (h *AdminPartnersHandler) PartnerByID(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, ok := adminPathExtractID(r.URL.Path, "partners")
+	if !ok {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	var req adminPartnerRateReq
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.CommissionPercent == nil {
+		http.Error(w, "commission_percent is required", http.StatusBadRequest)
+		return
+	}
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	p, err := h.payments.SetPartnerRate(r.Context(), id, *req.CommissionPercent, enabled, req.Note)
+	if err != nil {
+		switch {
+		case errors.Is(err, payment.ErrPartnerPercent):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case strings.Contains(err.Error(), "23503"):
+			http.Error(w, "customer not found", http.StatusNotFound)
+		default:
+			slog.Error("admin partners: set rate failed", "customer_id", id, "error", err.Error())
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionPartnerUpdate, CustomerID: id, After: mapPartnerToDTO(p),
+	})
+	writeJSON(w, http.StatusOK, mapPartnerToDTO(p))
+}
+
+func adminPartnersPaging(r *http.Request) (limit, offset int) {
+	q := r.URL.Query()
+	limit, _ = strconv.Atoi(q.Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset, _ = strconv.Atoi(q.Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+func adminPathExtractID(path, segment string) (int64, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, p := range parts {
+		if p == segment && i+1 < len(parts) {
+			id, err := strconv.ParseInt(parts[i+1], 10, 64)
+			if err == nil && id > 0 {
+				return id, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/audit"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/promo"
+)
+
+// extractPromoBatchID — id из /cabinet/api/admin/promo-batches/{id}[/codes.csv|/deactivate].
+func extractPromoBatchID(path string) (int64, string, bool) {
+	s := strings.TrimPrefix(path, "/cabinet/api/admin/promo-batches/")
+	s = strings.TrimRight(s, "/")
+	idPart, action, _ := strings.Cut(s, "/")
+	id, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, "", false
+	}
+	return id, action, true
+}
+
+type promoBatchDTO struct {
+	ID            int64      `json:"id"`
+	Campaign      string     `json:"campaign"`
+	Prefix        string     `json:"prefix"`
+	CodeCount     int        `json:"code_count"`
+	Codes         int        `json:"codes"`
+	ActiveCodes   int        `json:"active_codes"`
+	RedeemedCodes int        `json:"redeemed_codes"`
+	DeactivatedAt *time.Time `json:"deactivated_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func promoBatchToDTO(s *database.PromoBatchStats) promoBatchDTO {
+	return promoBatchDTO{
+		ID: s.ID, Campaign: s.Campaign, Prefix: s.Prefix, CodeCount: s.CodeCount,
+		Codes: s.Codes, ActiveCodes: s.ActiveCodes, RedeemedCodes: s.RedeemedCodes,
+		DeactivatedAt: s.DeactivatedAt, CreatedAt: s.CreatedAt,
+	}
+}
+
+type promoBatchListResp struct {
+	Items []promoBatchDTO `json:"items"`
+	Total int             `json:"total"`
+	Page  int             `json:"page"`
+	Limit int             `json:"limit"`
+}
+
+// ListBatches — GET /cabinet/api/admin/promo-batches?campaign=&page=&limit=
+func (h *AdminPromosHandler) ListBatches(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	campaign := strings.TrimSpace(r.URL.Query().Get("campaign"))
+
+	items, total, err := h.promos.ListBatches(r.Context(), campaign, (page-1)*limit, limit)
+	if err != nil {
+		slog.Error("admin promo batches list", "error", err.Error())
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	dtos := make([]promoBatchDTO, 0, len(items))
+	for i := range items {
+		dtos = append(dtos, promoBatchToDTO(&items[i]))
+	}
+	writeJSON(w, http.StatusOK, promoBatchListResp{Items: dtos, Total: total, Page: page, Limit: limit})
+}
+
+type createPromoBatchReq struct {
+	Campaign                                   string     `json:"campaign"`
+	Prefix                                     string     `json:"prefix"`
+	Count                                      int        `json:"count"`
+	Type                                       string     `json:"type"`
+	SubscriptionDays                           *int       `json:"subscription_days"`
+	TrialDays                                  *int       `json:"trial_days"`
+	ExtraHwidDelta                             *int       `json:"extra_hwid_delta"`
+	DiscountPercent                            *int       `json:"discount_percent"`
+	DiscountAmount                             *int       `json:"discount_amount"`
+	DiscountTTLHours                           *int       `json:"discount_ttl_hours"`
+	DiscountTariffIDs                          []int64    `json:"discount_tariff_ids"`
+	DiscountMonths                             []int      `json:"discount_months"`
+	DiscountInvoiceTypes                       []string   `json:"discount_invoice_types"`
+	DiscountMinAmount                          *int       `json:"discount_min_amount"`
+	ValidUntil                                 *time.Time `json:"valid_until"`
+	FirstPurchaseOnly                          bool       `json:"first_purchase_only"`
+	TariffID                                   *int64     `json:"tariff_id"`
+	DiscountMaxSubscriptionPaymentsPerCustomer int        `json:"discount_max_subscription_payments_per_customer"`
+	SegmentID                                  *int64     `json:"segment_id"`
+}
+
+// CreateBatch — POST /cabinet/api/admin/promo-batches: пакет одноразовых кодов по общему шаблону.
+func (h *AdminPromosHandler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	if h.promoService == nil {
+		http.Error(w, "promo service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	var req createPromoBatchReq
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	batch, err := h.promoService.GenerateBatch(r.Context(), promo.BatchRequest{
+		Campaign: req.Campaign,
+		Prefix:   req.Prefix,
+		Count:    req.Count,
+		Template: database.PromoCode{
+			Type:                     strings.TrimSpace(req.Type),
+			SubscriptionDays:         req.SubscriptionDays,
+			TrialDays:                req.TrialDays,
+			ExtraHwidDelta:           req.ExtraHwidDelta,
+			DiscountPercent:          req.DiscountPercent,
+			DiscountAmount:           req.DiscountAmount,
+			DiscountTTLHours:         req.DiscountTTLHours,
+			DiscountTariffIDs:        req.DiscountTariffIDs,
+			DiscountMonths:           req.DiscountMonths,
+			DiscountInvoiceTypes:     req.DiscountInvoiceTypes,
+			DiscountMinAmount:        req.DiscountMinAmount,
+			ValidUntil:               req.ValidUntil,
+			FirstPurchaseOnly:        req.FirstPurchaseOnly,
+			AllowTrialWithoutPayment: true,
+			TariffID:                 req.TariffID,
+			SegmentID:                req.SegmentID,
+			DiscountMaxSubscriptionPaymentsPerCustomer: req.DiscountMaxSubscriptionPaymentsPerCustomer,
+		},
+	})
+	if err != nil {
+		if errors.Is(err, promo.ErrBatchInvalid) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, database.ErrSegmentNotFound) {
+			http.Error(w, "segment not found", http.StatusBadRequest)
+			return
+		}
+		slog.Error("admin promo batches create", "error", err.Error())
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	stats, err := h.promos.FindBatch(r.Context(), batch.ID)
+	if err != nil || stats == nil {
+		slog.Error("admin promo batches get after create", "batch_id", batch.ID, "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionPromoBatchCreate, TargetType: "promo_batch", TargetID: strconv.FormatInt(batch.ID, 10),
+		After: promoBatchToDTO(stats),
+	})
+	writeJSON(w, http.StatusCreated, promoBatchToDTO(stats))
+}
+
+// GetBatch — GET /cabinet/api/admin/promo-batches/{id}
+func (h *AdminPromosHandler) GetBatch(w http.ResponseWriter, r *http.Request, id int64) {
+	stats, err := h.promos.FindBatch(r.Context(), id)
+	if err != nil {
+		slog.Error("admin promo batches get", "error", err.Error())
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if stats == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, promoBatchToDTO(stats))
+}
+
+// ExportBatchCSV — GET /cabinet/api/admin/promo-batches/{id}/codes.csv
+func (h *AdminPromosHandler) ExportBatchCSV(w http.ResponseWriter, r *http.Request, id int64) {
+	stats, err := h.promos.FindBatch(r.Context(), id)
+	if err != nil || stats == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	codes, err := h.promos.ListBatchCodes(r.Context(), id)
+	if err != nil {
+		slog.Error("admin promo batches export", "batch_id", id, "error", err.Error())
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="promo-batch-%d.csv"`, id))
+	if err := promo.WriteBatchCSV(w, codes); err != nil {
+		slog.Error("admin promo batches export write", "batch_id", id, "error", err.Error())
+	}
+}
+
+// DeactivateBatch — POST /cabinet/api/admin/promo-batches/{id}/deactivate: выключает все коды пакета.
+func (h *AdminPromosHandler) DeactivateBatch(w http.ResponseWriter, r *http.Request, id int64) {
+	stats, err := h.promos.FindBatch(r.Context(), id)
+	if err != nil || stats == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	n, err := h.promos.DeactivateBatch(r.Context(), id)
+	if err != nil {
+		slog.Error("admin promo batches deactivate", "batch_id", id, "error", err.Error())
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionPromoBatchDeactivate, TargetType: "promo_batch", TargetID: strconv.FormatInt(id, 10),
+		After: map[string]any{"deactivated": n},
+	})
+	writeJSON(w, http.StatusOK, map[string]int64{"deactivated": n})
+}
+
+// HandleBatches dispatches /cabinet/api/admin/promo-batches (no trailing path).
+func (h *AdminPromosHandler) HandleBatches(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.ListBatches(w, r)
+	case http.MethodPost:
+		h.CreateBatch(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleBatchByID dispatches /cabinet/api/admin/promo-batches/{id}[/codes.csv|/deactivate].
+func (h *AdminPromosHandler) HandleBatchByID(w http.ResponseWriter, r *http.Request) {
+	id, action, ok := extractPromoBatchID(r.URL.Path)
+	if !ok {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		h.GetBatch(w, r, id)
+	case action == "codes.csv" && r.Method == http.MethodGet:
+		h.ExportBatchCSV(w, r, id)
+	case action == "deactivate" && r.Method == http.MethodPost:
+		h.DeactivateBatch(w, r, id)
+	case action != "" && action != "codes.csv" && action != "deactivate":
+		http.Error(w, "not found", http.StatusNotFound)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -2,13 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"remnawave-tg-shop-bot/internal/audit"
 	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/promo"
 )
 
 func extractPromoID(path string) (int64, bool) {
@@ -24,10 +27,12 @@ func extractPromoID(path string) (int64, bool) {
 
 type AdminPromosHandler struct {
 	promos *database.PromoRepository
+	// promoService — генерация пакетов кодов; nil — POST /promo-batches отвечает 503.
+	promoService *promo.Service
 }
 
-func NewAdminPromos(promos *database.PromoRepository) *AdminPromosHandler {
-	return &AdminPromosHandler{promos: promos}
+func NewAdminPromos(promos *database.PromoRepository, promoService *promo.Service) *AdminPromosHandler {
+	return &AdminPromosHandler{promos: promos, promoService: promoService}
 }
 
 type promoDTO struct {
@@ -38,7 +43,12 @@ type promoDTO struct {
 	TrialDays                                  *int       `json:"trial_days"`
 	ExtraHwidDelta                             *int       `json:"extra_hwid_delta"`
 	DiscountPercent                            *int       `json:"discount_percent"`
+	DiscountAmount                             *int       `json:"discount_amount"`
 	DiscountTTLHours                           *int       `json:"discount_ttl_hours"`
+	DiscountTariffIDs                          []int64    `json:"discount_tariff_ids"`
+	DiscountMonths                             []int      `json:"discount_months"`
+	DiscountInvoiceTypes                       []string   `json:"discount_invoice_types"`
+	DiscountMinAmount                          *int       `json:"discount_min_amount"`
 	MaxUses                                    *int       `json:"max_uses"`
 	UsesCount                                  int        `json:"uses_count"`
 	ValidUntil                                 *time.Time `json:"valid_until"`
@@ -49,6 +59,8 @@ type promoDTO struct {
 	CreatedAt                                  time.Time  `json:"created_at"`
 	DiscountMaxSubscriptionPaymentsPerCustomer int        `json:"discount_max_subscription_payments_per_customer"`
 	TariffID                                   *int64     `json:"tariff_id"`
+	BatchID                                    *int64     `json:"batch_id"`
+	SegmentID                                  *int64     `json:"segment_id"`
 }
 
 func promoToDTO(p *database.PromoCode) promoDTO {
@@ -56,11 +68,13 @@ func promoToDTO(p *database.PromoCode) promoDTO {
 		ID: p.ID, Code: p.Code, Type: p.Type,
 		SubscriptionDays: p.SubscriptionDays, TrialDays: p.TrialDays,
 		ExtraHwidDelta: p.ExtraHwidDelta, DiscountPercent: p.DiscountPercent,
-		DiscountTTLHours: p.DiscountTTLHours, MaxUses: p.MaxUses,
-		UsesCount: p.UsesCount, ValidUntil: p.ValidUntil,
+		DiscountAmount: p.DiscountAmount, DiscountTTLHours: p.DiscountTTLHours,
+		DiscountTariffIDs: p.DiscountTariffIDs, DiscountMonths: p.DiscountMonths,
+		DiscountInvoiceTypes: p.DiscountInvoiceTypes, DiscountMinAmount: p.DiscountMinAmount,
+		MaxUses: p.MaxUses, UsesCount: p.UsesCount, ValidUntil: p.ValidUntil,
 		Active: p.Active, FirstPurchaseOnly: p.FirstPurchaseOnly,
 		RequireCustomerInDB: p.RequireCustomerInDB, AllowTrialWithoutPayment: p.AllowTrialWithoutPayment,
-		CreatedAt: p.CreatedAt, TariffID: p.TariffID,
+		CreatedAt: p.CreatedAt, TariffID: p.TariffID, BatchID: p.BatchID, SegmentID: p.SegmentID,
 		DiscountMaxSubscriptionPaymentsPerCustomer: p.DiscountMaxSubscriptionPaymentsPerCustomer,
 	}
 }
@@ -115,12 +129,18 @@ type createPromoReq struct {
 	TrialDays                                  *int       `json:"trial_days"`
 	ExtraHwidDelta                             *int       `json:"extra_hwid_delta"`
 	DiscountPercent                            *int       `json:"discount_percent"`
+	DiscountAmount                             *int       `json:"discount_amount"`
 	DiscountTTLHours                           *int       `json:"discount_ttl_hours"`
+	DiscountTariffIDs                          []int64    `json:"discount_tariff_ids"`
+	DiscountMonths                             []int      `json:"discount_months"`
+	DiscountInvoiceTypes                       []string   `json:"discount_invoice_types"`
+	DiscountMinAmount                          *int       `json:"discount_min_amount"`
 	MaxUses                                    *int       `json:"max_uses"`
 	ValidUntil                                 *time.Time `json:"valid_until"`
 	FirstPurchaseOnly                          bool       `json:"first_purchase_only"`
 	TariffID                                   *int64     `json:"tariff_id"`
 	DiscountMaxSubscriptionPaymentsPerCustomer int        `json:"discount_max_subscription_payments_per_customer"`
+	SegmentID                                  *int64     `json:"segment_id"`
 }
 
 // Create — POST /cabinet/api/admin/promos
@@ -146,23 +166,42 @@ func (h *AdminPromosHandler) Create(w http.ResponseWriter, r *http.Request) {
 		TrialDays:                req.TrialDays,
 		ExtraHwidDelta:           req.ExtraHwidDelta,
 		DiscountPercent:          req.DiscountPercent,
+		DiscountAmount:           req.DiscountAmount,
 		DiscountTTLHours:         req.DiscountTTLHours,
+		DiscountTariffIDs:        req.DiscountTariffIDs,
+		DiscountMonths:           req.DiscountMonths,
+		DiscountInvoiceTypes:     req.DiscountInvoiceTypes,
+		DiscountMinAmount:        req.DiscountMinAmount,
 		MaxUses:                  req.MaxUses,
 		ValidUntil:               req.ValidUntil,
 		Active:                   true,
 		FirstPurchaseOnly:        req.FirstPurchaseOnly,
 		AllowTrialWithoutPayment: true,
 		TariffID:                 req.TariffID,
+		SegmentID:                req.SegmentID,
 		DiscountMaxSubscriptionPaymentsPerCustomer: req.DiscountMaxSubscriptionPaymentsPerCustomer,
 	}
+	if p.Type == database.PromoTypeDiscount {
+		if err := promo.ValidateDiscount(&p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 
 	id, err := h.promos.Create(r.Context(), &p)
+	if errors.Is(err, database.ErrSegmentNotFound) {
+		http.Error(w, "segment not found", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		slog.Error("admin promos create", "error", err.Error())
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
 	p.ID = id
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionPromoCreate, TargetType: "promo", TargetID: strconv.FormatInt(id, 10), After: promoToDTO(&p),
+	})
 	writeJSON(w, http.StatusCreated, promoToDTO(&p))
 }
 
@@ -231,6 +270,8 @@ func (h *AdminPromosHandler) Update(w http.ResponseWriter, r *http.Request) {
 		"first_purchase_only": true, "subscription_days": true, "trial_days": true,
 		"extra_hwid_delta": true, "discount_percent": true, "discount_ttl_hours": true,
 		"discount_max_subscription_payments_per_customer": true, "tariff_id": true,
+		"discount_amount": true, "discount_tariff_ids": true, "discount_months": true,
+		"discount_invoice_types": true, "discount_min_amount": true, "segment_id": true,
 	}
 
 	fields, err := parsePromoPatchFields(raw, allowed)
@@ -248,8 +289,18 @@ func (h *AdminPromosHandler) Update(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if existing.Type == database.PromoTypeDiscount {
+		if err := validatePromoDiscountPatch(existing, fields); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 
 	if err := h.promos.UpdateFields(r.Context(), id, fields); err != nil {
+		if errors.Is(err, database.ErrSegmentNotFound) {
+			http.Error(w, "segment not found", http.StatusBadRequest)
+			return
+		}
 		slog.Error("admin promos update", "error", err.Error())
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
@@ -261,6 +312,10 @@ func (h *AdminPromosHandler) Update(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionPromoUpdate, TargetType: "promo", TargetID: strconv.FormatInt(id, 10),
+		Before: promoToDTO(existing), After: promoToDTO(p),
+	})
 	writeJSON(w, http.StatusOK, promoToDTO(p))
 }
 
@@ -277,11 +332,17 @@ func (h *AdminPromosHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	existing, _ := h.promos.FindByID(r.Context(), id)
 	if err := h.promos.Delete(r.Context(), id); err != nil {
 		slog.Error("admin promos delete", "error", err.Error())
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
+	entry := audit.Entry{Action: audit.ActionPromoDelete, TargetType: "promo", TargetID: strconv.FormatInt(id, 10)}
+	if existing != nil {
+		entry.Before = promoToDTO(existing)
+	}
+	audit.Record(r.Context(), adminAuditActor(r), entry)
 	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
 }
 
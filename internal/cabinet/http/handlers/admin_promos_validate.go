@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"time"
+
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/promo"
 )
 
 func parsePromoPatchFields(raw map[string]json.RawMessage, allowed map[string]bool) (map[string]interface{}, error) {
@@ -31,6 +34,30 @@ func parsePromoPatchFields(raw map[string]json.RawMessage, allowed map[string]bo
 				return nil, fmt.Errorf("invalid valid_until")
 			}
 			fields[k] = t
+		case "discount_tariff_ids":
+			var ids []int64
+			if err := json.Unmarshal(v, &ids); err != nil {
+				return nil, fmt.Errorf("invalid discount_tariff_ids")
+			}
+			fields[k] = emptyToNil(ids)
+		case "discount_months":
+			var months []int
+			if err := json.Unmarshal(v, &months); err != nil {
+				return nil, fmt.Errorf("invalid discount_months")
+			}
+			fields[k] = emptyToNil(months)
+		case "segment_id":
+			var segmentID *int64
+			if err := json.Unmarshal(v, &segmentID); err != nil || (segmentID != nil && *segmentID <= 0) {
+				return nil, fmt.Errorf("invalid segment_id")
+			}
+			fields[k] = segmentID
+		case "discount_invoice_types":
+			var types []string
+			if err := json.Unmarshal(v, &types); err != nil {
+				return nil, fmt.Errorf("invalid discount_invoice_types")
+			}
+			fields[k] = emptyToNil(types)
 		default:
 			var val interface{}
 			if err := json.Unmarshal(v, &val); err != nil {
@@ -78,7 +105,7 @@ func validatePromoUpdateFields(promoType string, fields map[string]interface{})
 			}
 		}
 	}
-	if v, ok := fields["discount_percent"]; ok {
+	if v, ok := fields["discount_percent"]; ok && v != nil {
 		if promoType == "discount" {
 			n, err := promoFieldInt(v)
 			if err != nil || n < 1 || n > 100 {
@@ -86,6 +113,13 @@ func validatePromoUpdateFields(promoType string, fields map[string]interface{})
 			}
 		}
 	}
+	for _, k := range []string{"discount_amount", "discount_min_amount"} {
+		if v, ok := fields[k]; ok && v != nil {
+			if _, err := promoFieldInt(v); err != nil {
+				return fmt.Errorf("invalid %s", k)
+			}
+		}
+	}
 	if v, ok := fields["discount_ttl_hours"]; ok && v != nil {
 		if promoType == "discount" {
 			n, err := promoFieldInt(v)
@@ -97,6 +131,45 @@ func validatePromoUpdateFields(promoType string, fields map[string]interface{})
 	return nil
 }
 
+// validatePromoDiscountPatch проверяет скидку промокода целиком: PATCH может менять процент, сумму
+// и ограничения по отдельности, поэтому поля накладываются на текущие значения.
+func validatePromoDiscountPatch(existing *database.PromoCode, fields map[string]interface{}) error {
+	p := *existing
+	for k, v := range fields {
+		switch k {
+		case "discount_percent":
+			p.DiscountPercent = promoFieldIntPtr(v)
+		case "discount_amount":
+			p.DiscountAmount = promoFieldIntPtr(v)
+		case "discount_min_amount":
+			p.DiscountMinAmount = promoFieldIntPtr(v)
+		case "discount_tariff_ids":
+			p.DiscountTariffIDs, _ = v.([]int64)
+		case "discount_months":
+			p.DiscountMonths, _ = v.([]int)
+		case "discount_invoice_types":
+			p.DiscountInvoiceTypes, _ = v.([]string)
+		}
+	}
+	return promo.ValidateDiscount(&p)
+}
+
+// emptyToNil — пустой список ограничений хранится как NULL.
+func emptyToNil[T any](s []T) interface{} {
+	if len(s) == 0 {
+		return nil
+	}
+	return s
+}
+
+func promoFieldIntPtr(v interface{}) *int {
+	n, err := promoFieldInt(v)
+	if v == nil || err != nil {
+		return nil
+	}
+	return &n
+}
+
 func promoFieldInt(v interface{}) (int, error) {
 	switch n := v.(type) {
 	case int:
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/audit"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/payment"
+)
+
+// AdminRefundsHandler — эндпоинты возвратов:
+//
+//	POST /cabinet/api/admin/purchases/{id}/refund   — вернуть сумму (amount=0 — весь остаток)
+//	GET  /cabinet/api/admin/purchases/{id}/refunds  — история возвратов по покупке
+//	GET  /cabinet/api/admin/refunds?limit=&offset=  — последние возвраты
+type AdminRefundsHandler struct {
+	payments *payment.PaymentService
+	refunds  *database.PurchaseRefundRepository
+}
+
+// NewAdminRefunds — конструктор.
+func NewAdminRefunds(payments *payment.PaymentService, refunds *database.PurchaseRefundRepository) *AdminRefundsHandler {
+	return &AdminRefundsHandler{payments: payments, refunds: refunds}
+}
+
+type adminRefundDTO struct {
+	ID               int64   `json:"id"`
+	PurchaseID       int64   `json:"purchase_id"`
+	CustomerID       int64   `json:"customer_id"`
+	Amount           float64 `json:"amount"`
+	Currency         string  `json:"currency"`
+	Status           string  `json:"status"`
+	ProviderRefundID *string `json:"provider_refund_id"`
+	Reason           *string `json:"reason"`
+	Source           string  `json:"source"`
+	AdminTelegramID  *int64  `json:"admin_telegram_id"`
+	AdminAccountID   *int64  `json:"admin_account_id"`
+	DaysRevoked      int     `json:"days_revoked"`
+	HwidRevoked      int     `json:"hwid_revoked"`
+	XPRevoked        int64   `json:"xp_revoked"`
+	PromoRestored    bool    `json:"promo_restored"`
+	Error            *string `json:"error"`
+	CreatedAt        string  `json:"created_at"`
+	CompletedAt      *string `json:"completed_at"`
+}
+
+func mapRefundToDTO(r *database.PurchaseRefund) adminRefundDTO {
+	dto := adminRefundDTO{
+		ID:               r.ID,
+		PurchaseID:       r.PurchaseID,
+		CustomerID:       r.CustomerID,
+		Amount:           r.Amount,
+		Currency:         r.Currency,
+		Status:           string(r.Status),
+		ProviderRefundID: r.ProviderRefundID,
+		Reason:           r.Reason,
+		Source:           string(r.Source),
+		AdminTelegramID:  r.AdminTelegramID,
+		AdminAccountID:   r.AdminAccountID,
+		DaysRevoked:      r.DaysRevoked,
+		HwidRevoked:      r.HwidRevoked,
+		XPRevoked:        r.XPRevoked,
+		PromoRestored:    r.PromoRestored,
+		Error:            r.Error,
+		CreatedAt:        r.CreatedAt.Format(time.RFC3339),
+	}
+	if r.CompletedAt != nil {
+		s := r.CompletedAt.Format(time.RFC3339)
+		dto.CompletedAt = &s
+	}
+	return dto
+}
+
+type adminRefundReq struct {
+	Amount float64 `json:"amount"`
+	Reason string  `json:"reason"`
+	// ToBalance — зачислить на внутренний баланс клиента вместо возврата через провайдера.
+	ToBalance bool `json:"to_balance"`
+}
+
+type adminRefundResp struct {
+	Refund           adminRefundDTO `json:"refund"`
+	PurchaseStatus   string         `json:"purchase_status"`
+	RefundedAmount   float64        `json:"refunded_amount"`
+	ProviderRefunded bool           `json:"provider_refunded"`
+}
+
+// HandleByID — /cabinet/api/admin/purchases/{id}/refund[s].
+func (h *AdminRefundsHandler) HandleByID(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/refund"):
+		h.Refund(w, r)
+	case strings.HasSuffix(r.URL.Path, "/refunds"):
+		h.ListByPurchase(w, r)
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+// Refund — POST /cabinet/api/admin/purchases/{id}/refund.
+func (h *AdminRefundsHandler) Refund(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, ok := adminPurchasesExtractID(r.URL.Path)
+	if !ok {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	var req adminRefundReq
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.Amount < 0 {
+		http.Error(w, "invalid amount", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.payments.RefundPurchase(r.Context(), payment.RefundRequest{
+		PurchaseID:     id,
+		Amount:         req.Amount,
+		Reason:         req.Reason,
+		Source:         database.RefundSourceCabinet,
+		AdminAccountID: adminAccountID(r),
+		ToBalance:      req.ToBalance,
+	})
+	if err != nil {
+		writeRefundErr(w, err, id)
+		return
+	}
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionUserRefund, CustomerID: res.Refund.CustomerID,
+		TargetType: "purchase", TargetID: strconv.FormatInt(id, 10),
+		After: mapRefundToDTO(res.Refund),
+	})
+
+	writeJSON(w, http.StatusOK, adminRefundResp{
+		Refund:           mapRefundToDTO(res.Refund),
+		PurchaseStatus:   string(res.Purchase.Status),
+		RefundedAmount:   res.Purchase.RefundedAmount,
+		ProviderRefunded: res.ProviderRefunded,
+	})
+}
+
+// ListByPurchase — GET /cabinet/api/admin/purchases/{id}/refunds.
+func (h *AdminRefundsHandler) ListByPurchase(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id, ok := adminPurchasesExtractID(r.URL.Path)
+	if !ok {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	items, err := h.refunds.ListByPurchase(r.Context(), id)
+	if err != nil {
+		slog.Error("admin refunds: list by purchase failed", "error", err.Error())
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": mapRefundsToDTO(items)})
+}
+
+// List — GET /cabinet/api/admin/refunds?limit=&offset=.
+func (h *AdminRefundsHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset, _ := strconv.Atoi(q.Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	items, err := h.refunds.ListRecent(r.Context(), limit, offset)
+	if err != nil {
+		slog.Error("admin refunds: list failed", "error", err.Error())
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"items": mapRefundsToDTO(items)})
+}
+
+func mapRefundsToDTO(items []database.PurchaseRefund) []adminRefundDTO {
+	out := make([]adminRefundDTO, 0, len(items))
+	for i := range items {
+		out = append(out, mapRefundToDTO(&items[i]))
+	}
+	return out
+}
+
+func writeRefundErr(w http.ResponseWriter, err error, purchaseID int64) {
+	switch {
+	case errors.Is(err, payment.ErrRefundPurchaseNotFound):
+		http.Error(w, "purchase not found", http.StatusNotFound)
+	case errors.Is(err, payment.ErrRefundNotAllowed):
+		http.Error(w, "purchase is not refundable", http.StatusConflict)
+	case errors.Is(err, database.ErrRefundInProgress):
+		http.Error(w, "refund already in progress", http.StatusConflict)
+	case errors.Is(err, payment.ErrRefundInvalidAmount), errors.Is(err, payment.ErrRefundPartialUnsupported),
+		errors.Is(err, payment.ErrRefundToBalanceUnsupported):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, payment.ErrBalanceTopUpSpent):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, payment.ErrRefundProviderFailed):
+		slog.Error("admin refunds: provider failed", "purchase_id", purchaseID, "error", err.Error())
+		http.Error(w, "provider refund failed", http.StatusBadGateway)
+	default:
+		slog.Error("admin refunds: refund failed", "purchase_id", purchaseID, "error", err.Error())
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+func adminPurchasesExtractID(path string) (int64, bool) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, p := range parts {
+		if p == "purchases" && i+1 < len(parts) {
+			id, err := strconv.ParseInt(parts[i+1], 10, 64)
+			if err == nil && id > 0 {
+				return id, true
+			}
+		}
+	}
+	return 0, false
+}
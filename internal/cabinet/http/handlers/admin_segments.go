@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/audit"
+	"remnawave-tg-shop-bot/internal/database"
+)
+
+const segmentNameMaxLen = 100
+
+type segmentDTO struct {
+	ID        int64                  `json:"id"`
+	Name      string                 `json:"name"`
+	Filter    database.SegmentFilter `json:"filter"`
+	Count     *database.SegmentCount `json:"count,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+func segmentToDTO(s *database.CustomerSegment) segmentDTO {
+	return segmentDTO{ID: s.ID, Name: s.Name, Filter: s.Filter, CreatedAt: s.CreatedAt, UpdatedAt: s.UpdatedAt}
+}
+
+type segmentReq struct {
+	Name   string                 `json:"name"`
+	Filter database.SegmentFilter `json:"filter"`
+}
+
+func (req *segmentReq) validate() error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return errors.New("name required")
+	}
+	if len([]rune(req.Name)) > segmentNameMaxLen {
+		return errors.New("name too long")
+	}
+	req.Filter.Normalize()
+	return req.Filter.Validate()
+}
+
+func extractSegmentID(path string) (int64, bool) {
+	s := strings.TrimPrefix(path, "/cabinet/api/admin/segments/")
+	s = strings.TrimRight(s, "/")
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// Segments — GET /cabinet/api/admin/segments (список) и POST (создать сегмент).
+func (h *AdminBroadcastHandler) Segments(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	switch r.Method {
+	case http.MethodGet:
+		list, err := h.segments.List(ctx)
+		if err != nil {
+			slog.Error("admin segments: list", "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		items := make([]segmentDTO, 0, len(list))
+		for i := range list {
+			items = append(items, segmentToDTO(&list[i]))
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"items": items})
+	case http.MethodPost:
+		var req segmentReq
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+		if err := req.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		seg, err := h.segments.Create(ctx, req.Name, req.Filter)
+		if errors.Is(err, database.ErrSegmentNameTaken) {
+			http.Error(w, "segment name already exists", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			slog.Error("admin segments: create", "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		dto := segmentToDTO(seg)
+		audit.Record(ctx, adminAuditActor(r), audit.Entry{
+			Action: audit.ActionSegmentCreate, TargetType: "segment", TargetID: strconv.FormatInt(seg.ID, 10), After: dto,
+		})
+		writeJSON(w, http.StatusCreated, dto)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// SegmentByID — GET (с числом клиентов), PUT и DELETE /cabinet/api/admin/segments/{id}.
+func (h *AdminBroadcastHandler) SegmentByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := extractSegmentID(r.URL.Path)
+	if !ok {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	existing, err := h.segments.FindByID(ctx, id)
+	if err != nil {
+		slog.Error("admin segments: get", "id", id, "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if existing == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		dto := segmentToDTO(existing)
+		count, err := h.customers.CountSegment(ctx, existing.Filter)
+		if err != nil {
+			slog.Error("admin segments: count", "id", id, "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		dto.Count = &count
+		writeJSON(w, http.StatusOK, dto)
+	case http.MethodPut:
+		var req segmentReq
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+		if err := req.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		seg, err := h.segments.Update(ctx, id, req.Name, req.Filter)
+		if errors.Is(err, database.ErrSegmentNameTaken) {
+			http.Error(w, "segment name already exists", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			slog.Error("admin segments: update", "id", id, "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if seg == nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		dto := segmentToDTO(seg)
+		audit.Record(ctx, adminAuditActor(r), audit.Entry{
+			Action: audit.ActionSegmentUpdate, TargetType: "segment", TargetID: strconv.FormatInt(id, 10),
+			Before: segmentToDTO(existing), After: dto,
+		})
+		writeJSON(w, http.StatusOK, dto)
+	case http.MethodDelete:
+		deleted, err := h.segments.Delete(ctx, id)
+		if errors.Is(err, database.ErrSegmentInUse) {
+			http.Error(w, "segment is used by promo codes", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			slog.Error("admin segments: delete", "id", id, "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if !deleted {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		audit.Record(ctx, adminAuditActor(r), audit.Entry{
+			Action: audit.ActionSegmentDelete, TargetType: "segment", TargetID: strconv.FormatInt(id, 10),
+			Before: segmentToDTO(existing),
+		})
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
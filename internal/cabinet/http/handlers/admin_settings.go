@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	cabcfg "remnawave-tg-shop-bot/internal/cabinet/config"
+	"remnawave-tg-shop-bot/internal/audit"
 	"remnawave-tg-shop-bot/internal/cabinet/http/middleware"
 	"remnawave-tg-shop-bot/internal/config"
 	"remnawave-tg-shop-bot/internal/database"
@@ -138,6 +139,15 @@ func (h *AdminSettingsHandler) Patch(w http.ResponseWriter, r *http.Request) {
 		accountID = claims.AccountID
 	}
 	slog.Info("admin bot settings updated", "admin_account_id", accountID, "changed_keys", changed)
+	if len(changed) > 0 {
+		before := make(map[string]string, len(changed))
+		for _, key := range changed {
+			before[key] = beforeValues[key]
+		}
+		audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+			Action: audit.ActionSettingsUpdate, TargetType: "settings", Before: before, After: toSave,
+		})
+	}
 
 	writeJSON(w, http.StatusOK, adminSettingsPatchResp{OK: true, Changed: changed})
 }
@@ -146,7 +156,7 @@ func buildAdminSettingsResponse() adminSettingsGetResp {
 	// Порядок групп: сверху важнее для ежедневной работы.
 	order := []string{
 		"cabinet", "tariffs",
-		"trial", "hwid", "referral", "stars", "loyalty",
+		"trial", "hwid", "pause", "referral", "stars", "loyalty",
 		"payments_notify", "access", "links", "tags",
 		"lifecycle", "fortune",
 	}
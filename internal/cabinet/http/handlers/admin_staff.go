@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"remnawave-tg-shop-bot/internal/audit"
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/staff"
+)
+
+// AdminStaffHandler — /cabinet/api/admin/staff[/{telegram_id}]: сотрудники админки (только владелец).
+type AdminStaffHandler struct{}
+
+// NewAdminStaff — конструктор.
+func NewAdminStaff() *AdminStaffHandler {
+	return &AdminStaffHandler{}
+}
+
+type adminStaffListResp struct {
+	OwnerTelegramID int64                             `json:"owner_telegram_id"`
+	Members         []staff.Member                    `json:"members"`
+	Permissions     []staff.Permission                `json:"permissions"`
+	RolePresets     map[staff.Role][]staff.Permission `json:"role_presets"`
+}
+
+type adminStaffUpsertReq struct {
+	TelegramID  int64              `json:"telegram_id"`
+	Role        staff.Role         `json:"role"`
+	Permissions []staff.Permission `json:"permissions"`
+	Note        string             `json:"note"`
+}
+
+type adminStaffPatchReq struct {
+	Role        *staff.Role         `json:"role"`
+	Permissions *[]staff.Permission `json:"permissions"`
+	Note        *string             `json:"note"`
+}
+
+// Handle dispatches /cabinet/api/admin/staff (no trailing path).
+func (h *AdminStaffHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.List(w, r)
+	case http.MethodPost:
+		h.Create(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleByID dispatches /cabinet/api/admin/staff/{telegram_id}.
+func (h *AdminStaffHandler) HandleByID(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPatch:
+		h.Update(w, r)
+	case http.MethodDelete:
+		h.Delete(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// List — GET /cabinet/api/admin/staff.
+func (h *AdminStaffHandler) List(w http.ResponseWriter, r *http.Request) {
+	presets := make(map[staff.Role][]staff.Permission, len(staff.Roles))
+	for _, role := range staff.Roles {
+		presets[role] = staff.RolePermissions(role)
+	}
+	writeJSON(w, http.StatusOK, adminStaffListResp{
+		OwnerTelegramID: config.GetAdminTelegramId(),
+		Members:         staff.List(),
+		Permissions:     staff.AllPermissions,
+		RolePresets:     presets,
+	})
+}
+
+// Create — POST /cabinet/api/admin/staff: добавляет сотрудника; пустой permissions — права роли.
+func (h *AdminStaffHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req adminStaffUpsertReq
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if _, exists := staff.Lookup(req.TelegramID); exists {
+		http.Error(w, "already exists", http.StatusConflict)
+		return
+	}
+	h.save(w, r, nil, staff.Member{TelegramID: req.TelegramID, Role: req.Role, Permissions: req.Permissions, Note: req.Note}, http.StatusCreated)
+}
+
+// Update — PATCH /cabinet/api/admin/staff/{telegram_id}: меняет роль, права или заметку.
+// Смена роли без permissions сбрасывает права на набор новой роли.
+func (h *AdminStaffHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := extractStaffTelegramID(r.URL.Path)
+	if !ok {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	var req adminStaffPatchReq
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if id == config.GetAdminTelegramId() {
+		http.Error(w, staff.ErrPrimaryOwner.Error(), http.StatusBadRequest)
+		return
+	}
+	m, exists := staff.Lookup(id)
+	if !exists {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	before := m
+	if req.Role != nil && *req.Role != m.Role {
+		m.Role = *req.Role
+		m.Permissions = nil
+	}
+	if req.Permissions != nil {
+		m.Permissions = *req.Permissions
+	}
+	if req.Note != nil {
+		m.Note = *req.Note
+	}
+	h.save(w, r, &before, m, http.StatusOK)
+}
+
+// Delete — DELETE /cabinet/api/admin/staff/{telegram_id}.
+func (h *AdminStaffHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := extractStaffTelegramID(r.URL.Path)
+	if !ok {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	before, _ := staff.Lookup(id)
+	removed, err := staff.Remove(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, staff.ErrPrimaryOwner) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		slog.Error("admin staff delete", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if !removed {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionStaffDelete, TargetType: "staff", TargetID: strconv.FormatInt(id, 10), Before: before,
+	})
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+func (h *AdminStaffHandler) save(w http.ResponseWriter, r *http.Request, before *staff.Member, m staff.Member, status int) {
+	saved, err := staff.Save(r.Context(), m)
+	if err != nil {
+		switch {
+		case errors.Is(err, staff.ErrInvalidRole), errors.Is(err, staff.ErrInvalidPermission),
+			errors.Is(err, staff.ErrInvalidTelegramID), errors.Is(err, staff.ErrPrimaryOwner):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			slog.Error("admin staff save", "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+		return
+	}
+	entry := audit.Entry{Action: audit.ActionStaffCreate, TargetType: "staff", TargetID: strconv.FormatInt(saved.TelegramID, 10), After: saved}
+	if before != nil {
+		entry.Action, entry.Before = audit.ActionStaffUpdate, *before
+	}
+	audit.Record(r.Context(), adminAuditActor(r), entry)
+	writeJSON(w, status, saved)
+}
+
+func extractStaffTelegramID(path string) (int64, bool) {
+	s := strings.Trim(strings.TrimPrefix(path, "/cabinet/api/admin/staff/"), "/")
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
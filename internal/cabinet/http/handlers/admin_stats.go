@@ -3,6 +3,7 @@ package handlers
 import (
 	"errors"
 	"log/slog"
+	"math"
 	"net/http"
 	"time"
 
@@ -97,6 +98,10 @@ type adminStatsResp struct {
 	UniquePayersHalfYear int64             `json:"unique_payers_half_year"`
 	UniquePayersYear    int64              `json:"unique_payers_year"`
 	PaymentRubByInvoice map[string]float64 `json:"payment_rub_by_invoice"`
+	RevenueMonthByCurrency      map[string]float64 `json:"revenue_month_by_currency"`
+	RevenueAllTimeByCurrency    map[string]float64 `json:"revenue_all_time_by_currency"`
+	RevenueMonthNormalizedRub   float64            `json:"revenue_month_normalized_rub"`
+	RevenueAllTimeNormalizedRub float64            `json:"revenue_all_time_normalized_rub"`
 	DistinctReferrers   int64              `json:"distinct_referrers"`
 	ActiveReferrers     int64              `json:"active_referrers"`
 	RefBonusDaysAll     int64              `json:"ref_bonus_days_all"`
@@ -242,6 +247,10 @@ func (h *AdminStatsHandler) Stats(w http.ResponseWriter, r *http.Request) {
 		UniquePayersHalfYear: snap.UniquePayersHalfYear,
 		UniquePayersYear:     snap.UniquePayersYear,
 		PaymentRubByInvoice:  snap.PaymentRubByInvoice,
+		RevenueMonthByCurrency:      snap.RevenueMonthByCurrency,
+		RevenueAllTimeByCurrency:    snap.RevenueAllTimeByCurrency,
+		RevenueMonthNormalizedRub:   snap.RevenueMonthNormalizedRub,
+		RevenueAllTimeNormalizedRub: snap.RevenueAllTimeNormalizedRub,
 		DistinctReferrers:    snap.DistinctReferrers,
 		ActiveReferrers:      snap.ActiveReferrers,
 		RefBonusDaysAll:      snap.RefBonusDaysAll,
@@ -501,6 +510,93 @@ func (h *AdminStatsHandler) PromoStats(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+type adminAcquisitionSourceDTO struct {
+	Source            string             `json:"source"`
+	Starts            int64              `json:"starts"`
+	Trials            int64              `json:"trials"`
+	Paid              int64              `json:"paid"`
+	TrialRatePct      float64            `json:"trial_rate_pct"`
+	PaidRatePct       float64            `json:"paid_rate_pct"`
+	RevenueRub        float64            `json:"revenue_rub"`
+	RevenueByCurrency map[string]float64 `json:"revenue_by_currency"`
+}
+
+type adminAcquisitionStatsResp struct {
+	CapturedAt string                      `json:"captured_at"`
+	From       string                      `json:"from,omitempty"`
+	To         string                      `json:"to,omitempty"`
+	Sources    []adminAcquisitionSourceDTO `json:"sources"`
+}
+
+// AcquisitionStats — GET /cabinet/api/admin/stats/sources[?from=YYYY-MM-DD&to=YYYY-MM-DD] (RequireAdmin).
+// Воронка по источникам привлечения для клиентов, пришедших в период (обе даты включительно); без дат — за всё время.
+func (h *AdminStatsHandler) AcquisitionStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	fromStr, toStr := q.Get("from"), q.Get("to")
+	var from, to *time.Time
+	if fromStr != "" {
+		t, err := time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			http.Error(w, "invalid from date (YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		from = &t
+	}
+	if toStr != "" {
+		t, err := time.Parse("2006-01-02", toStr)
+		if err != nil {
+			http.Error(w, "invalid to date (YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		end := t.Add(24 * time.Hour)
+		to = &end
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		http.Error(w, "from after to", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.stats.FetchAcquisitionFunnel(r.Context(), from, to)
+	if err != nil {
+		slog.Error("admin stats: acquisition funnel failed", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	sources := make([]adminAcquisitionSourceDTO, 0, len(list))
+	for _, st := range list {
+		sources = append(sources, adminAcquisitionSourceDTO{
+			Source:            st.Source,
+			Starts:            st.Starts,
+			Trials:            st.Trials,
+			Paid:              st.Paid,
+			TrialRatePct:      ratePct(st.Trials, st.Starts),
+			PaidRatePct:       ratePct(st.Paid, st.Starts),
+			RevenueRub:        st.RevenueRub,
+			RevenueByCurrency: st.RevenueByCurrency,
+		})
+	}
+	writeJSON(w, http.StatusOK, adminAcquisitionStatsResp{
+		CapturedAt: time.Now().UTC().Format(time.RFC3339),
+		From:       fromStr,
+		To:         toStr,
+		Sources:    sources,
+	})
+}
+
+// ratePct — доля part от total в процентах с одним знаком после запятой.
+func ratePct(part, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return math.Round(float64(part)*1000/float64(total)) / 10
+}
+
 func mapFortunePeriod(p database.AdminFortunePeriodAgg) adminFortunePeriodDTO {
 	byReward := p.ByReward
 	if byReward == nil {
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"sync/atomic"
 
+	"remnawave-tg-shop-bot/internal/audit"
 	"remnawave-tg-shop-bot/internal/sync"
 )
 
@@ -30,6 +31,7 @@ func (h *AdminSyncHandler) TriggerSync(w http.ResponseWriter, r *http.Request) {
 	}
 
 	slog.Info("admin: sync triggered via cabinet")
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{Action: audit.ActionSyncRun})
 	go func() {
 		defer atomic.StoreInt32(&syncRunning, 0)
 		h.syncService.Sync()
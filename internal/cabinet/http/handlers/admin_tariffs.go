@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"remnawave-tg-shop-bot/internal/audit"
 	"remnawave-tg-shop-bot/internal/config"
 	"remnawave-tg-shop-bot/internal/database"
 )
@@ -51,7 +54,24 @@ type tariffDTO struct {
 	TierLevel                 *int            `json:"tier_level"`
 	Description               *string         `json:"description"`
 	DescriptionDetail         *string         `json:"description_detail"`
+	FamilyMaxMembers          int             `json:"family_max_members"`
 	Prices                    []tariffPriceDTO `json:"prices"`
+	CurrencyPrices            []tariffCurrencyPriceDTO `json:"currency_prices"`
+	TrafficPacks              []tariffTrafficPackDTO   `json:"traffic_packs"`
+}
+
+// tariffTrafficPackDTO — пакет трафика тарифа; price_stars 0 — Stars по RUB_PER_STAR.
+type tariffTrafficPackDTO struct {
+	TrafficGB  int `json:"traffic_gb"`
+	PriceRub   int `json:"price_rub"`
+	PriceStars int `json:"price_stars"`
+}
+
+// tariffCurrencyPriceDTO — цена за период в валюте из PRICE_CURRENCIES (USD, EUR, …).
+type tariffCurrencyPriceDTO struct {
+	Months   int     `json:"months"`
+	Currency string  `json:"currency"`
+	Amount   float64 `json:"amount"`
 }
 
 func tariffToDTO(t *database.Tariff, prices []database.TariffPrice) tariffDTO {
@@ -75,10 +95,76 @@ func tariffToDTO(t *database.Tariff, prices []database.TariffPrice) tariffDTO {
 		ActiveInternalSquadUUIDs:  t.ActiveInternalSquadUUIDs,
 		ExternalSquadUUID: extUUID, RemnawaveTag: t.RemnawaveTag,
 		TierLevel: t.TierLevel, Description: t.Description, DescriptionDetail: t.DescriptionDetail,
+		FamilyMaxMembers: t.FamilyMaxMembers,
 		Prices: priceDTOs,
 	}
 }
 
+// toDTO — tariffToDTO с валютными ценами тарифа.
+func (h *AdminTariffsHandler) toDTO(ctx context.Context, t *database.Tariff, prices []database.TariffPrice) tariffDTO {
+	dto := tariffToDTO(t, prices)
+	dto.CurrencyPrices = []tariffCurrencyPriceDTO{}
+	cps, err := h.tariffs.ListCurrencyPrices(ctx, t.ID)
+	if err != nil {
+		slog.Error("admin tariffs list currency prices", "tariff_id", t.ID, "error", err.Error())
+		return dto
+	}
+	for _, p := range cps {
+		dto.CurrencyPrices = append(dto.CurrencyPrices, tariffCurrencyPriceDTO{Months: p.Months, Currency: p.Currency, Amount: p.Amount})
+	}
+	dto.TrafficPacks = []tariffTrafficPackDTO{}
+	packs, err := h.tariffs.ListTrafficPacks(ctx, t.ID)
+	if err != nil {
+		slog.Error("admin tariffs list traffic packs", "tariff_id", t.ID, "error", err.Error())
+		return dto
+	}
+	for _, p := range packs {
+		dto.TrafficPacks = append(dto.TrafficPacks, tariffTrafficPackDTO{TrafficGB: p.TrafficGB, PriceRub: p.PriceRub, PriceStars: p.PriceStars})
+	}
+	return dto
+}
+
+// parseCurrencyPrices проверяет валютные цены из запроса: периоды 1/3/6/12, валюта не RUB и не Stars
+// (они задаются в rub / stars), сумма не отрицательная; 0 — убрать цену.
+func parseCurrencyPrices(in []tariffCurrencyPriceDTO) ([]database.TariffCurrencyPrice, error) {
+	out := make([]database.TariffCurrencyPrice, 0, len(in))
+	for _, p := range in {
+		cur := config.NormalizeCurrency(p.Currency)
+		switch {
+		case p.Months != 1 && p.Months != 3 && p.Months != 6 && p.Months != 12:
+			return nil, fmt.Errorf("invalid months: %d", p.Months)
+		case cur == config.CurrencyRUB || cur == config.CurrencyStars || len(cur) < 3 || len(cur) > 8:
+			return nil, fmt.Errorf("invalid currency: %s", p.Currency)
+		case p.Amount < 0:
+			return nil, fmt.Errorf("invalid amount for %s", cur)
+		}
+		out = append(out, database.TariffCurrencyPrice{Months: p.Months, Currency: cur, Amount: p.Amount})
+	}
+	return out, nil
+}
+
+// parseTrafficPacks проверяет пакеты трафика из запроса: объём и цена в рублях положительные,
+// Stars не отрицательные, объёмы не повторяются.
+func parseTrafficPacks(in []tariffTrafficPackDTO) ([]database.TariffTrafficPack, error) {
+	out := make([]database.TariffTrafficPack, 0, len(in))
+	seen := make(map[int]bool, len(in))
+	for _, p := range in {
+		switch {
+		case p.TrafficGB <= 0:
+			return nil, fmt.Errorf("invalid traffic_gb: %d", p.TrafficGB)
+		case seen[p.TrafficGB]:
+			return nil, fmt.Errorf("duplicate traffic_gb: %d", p.TrafficGB)
+		case p.PriceRub <= 0:
+			return nil, fmt.Errorf("invalid price_rub for %d GB", p.TrafficGB)
+		case p.PriceStars < 0:
+			return nil, fmt.Errorf("invalid price_stars for %d GB", p.TrafficGB)
+		}
+		seen[p.TrafficGB] = true
+		out = append(out, database.TariffTrafficPack{TrafficGB: p.TrafficGB, PriceRub: p.PriceRub, PriceStars: p.PriceStars})
+	}
+	return out, nil
+}
+
 // List — GET /cabinet/api/admin/tariffs
 func (h *AdminTariffsHandler) List(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -103,7 +189,7 @@ func (h *AdminTariffsHandler) List(w http.ResponseWriter, r *http.Request) {
 		if prices == nil {
 			prices = []database.TariffPrice{}
 		}
-		result = append(result, tariffToDTO(&tariffs[i], prices))
+		result = append(result, h.toDTO(r.Context(), &tariffs[i], prices))
 	}
 	writeJSON(w, http.StatusOK, result)
 }
@@ -121,8 +207,11 @@ type createTariffReq struct {
 	TierLevel                 *int    `json:"tier_level"`
 	Description               *string `json:"description"`
 	DescriptionDetail         *string `json:"description_detail"`
+	FamilyMaxMembers          int     `json:"family_max_members"`
 	Rub                       [4]int  `json:"rub"`
 	Stars                     [4]*int `json:"stars"`
+	CurrencyPrices            []tariffCurrencyPriceDTO `json:"currency_prices"`
+	TrafficPacks              []tariffTrafficPackDTO   `json:"traffic_packs"`
 }
 
 // Create — POST /cabinet/api/admin/tariffs
@@ -140,6 +229,20 @@ func (h *AdminTariffsHandler) Create(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "slug is required", http.StatusBadRequest)
 		return
 	}
+	currencyPrices, err := parseCurrencyPrices(req.CurrencyPrices)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	trafficPacks, err := parseTrafficPacks(req.TrafficPacks)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.FamilyMaxMembers < 0 || req.FamilyMaxMembers > 20 {
+		http.Error(w, "family_max_members must be between 0 and 20", http.StatusBadRequest)
+		return
+	}
 
 	t := database.Tariff{
 		Slug:                      req.Slug,
@@ -153,6 +256,7 @@ func (h *AdminTariffsHandler) Create(w http.ResponseWriter, r *http.Request) {
 		TierLevel:                 req.TierLevel,
 		Description:               req.Description,
 		DescriptionDetail:         req.DescriptionDetail,
+		FamilyMaxMembers:          req.FamilyMaxMembers,
 	}
 	if tag := config.RemnawaveTag(); tag != "" {
 		t.RemnawaveTag = &tag
@@ -165,11 +269,29 @@ func (h *AdminTariffsHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	t.ID = id
+	if len(currencyPrices) > 0 {
+		if err := h.tariffs.ReplaceCurrencyPrices(r.Context(), id, currencyPrices); err != nil {
+			slog.Error("admin tariffs create currency prices", "error", err.Error())
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+	}
+	if len(trafficPacks) > 0 {
+		if err := h.tariffs.ReplaceTrafficPacks(r.Context(), id, trafficPacks); err != nil {
+			slog.Error("admin tariffs create traffic packs", "error", err.Error())
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+	}
 	prices, _ := h.tariffs.ListPricesForTariff(r.Context(), id)
 	if prices == nil {
 		prices = []database.TariffPrice{}
 	}
-	writeJSON(w, http.StatusCreated, tariffToDTO(&t, prices))
+	dto := h.toDTO(r.Context(), &t, prices)
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionTariffCreate, TargetType: "tariff", TargetID: strconv.FormatInt(id, 10), After: dto,
+	})
+	writeJSON(w, http.StatusCreated, dto)
 }
 
 // Get — GET /cabinet/api/admin/tariffs/{id}
@@ -201,7 +323,7 @@ func (h *AdminTariffsHandler) Get(w http.ResponseWriter, r *http.Request) {
 		prices = []database.TariffPrice{}
 	}
 
-	writeJSON(w, http.StatusOK, tariffToDTO(t, prices))
+	writeJSON(w, http.StatusOK, h.toDTO(r.Context(), t, prices))
 }
 
 // Update — PATCH /cabinet/api/admin/tariffs/{id}
@@ -221,12 +343,14 @@ func (h *AdminTariffsHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if !decodeJSON(w, r, &raw) {
 		return
 	}
+	before := h.auditState(r.Context(), id)
 
 	tariffFields := map[string]bool{
 		"slug": true, "name": true, "sort_order": true, "is_active": true,
 		"device_limit": true, "traffic_limit_bytes": true,
 		"traffic_limit_reset_strategy": true, "active_internal_squad_uuids": true,
 		"tier_level": true, "description": true, "description_detail": true,
+		"family_max_members": true,
 	}
 
 	fields := make(map[string]interface{})
@@ -239,6 +363,12 @@ func (h *AdminTariffsHandler) Update(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "invalid field: "+k, http.StatusBadRequest)
 			return
 		}
+		if k == "family_max_members" {
+			if n, ok := val.(float64); !ok || n < 0 || n > 20 || n != float64(int(n)) {
+				http.Error(w, "family_max_members must be an integer between 0 and 20", http.StatusBadRequest)
+				return
+			}
+		}
 		fields[k] = val
 	}
 
@@ -270,6 +400,42 @@ func (h *AdminTariffsHandler) Update(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if rawCur, ok := raw["currency_prices"]; ok {
+		var in []tariffCurrencyPriceDTO
+		if err := json.Unmarshal(rawCur, &in); err != nil {
+			http.Error(w, "invalid currency_prices", http.StatusBadRequest)
+			return
+		}
+		currencyPrices, err := parseCurrencyPrices(in)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := h.tariffs.ReplaceCurrencyPrices(r.Context(), id, currencyPrices); err != nil {
+			slog.Error("admin tariffs replace currency prices", "error", err.Error())
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if rawPacks, ok := raw["traffic_packs"]; ok {
+		var in []tariffTrafficPackDTO
+		if err := json.Unmarshal(rawPacks, &in); err != nil {
+			http.Error(w, "invalid traffic_packs", http.StatusBadRequest)
+			return
+		}
+		trafficPacks, err := parseTrafficPacks(in)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := h.tariffs.ReplaceTrafficPacks(r.Context(), id, trafficPacks); err != nil {
+			slog.Error("admin tariffs replace traffic packs", "error", err.Error())
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+	}
+
 	t, err := h.tariffs.GetByID(r.Context(), id)
 	if err != nil {
 		slog.Error("admin tariffs get after update", "error", err.Error())
@@ -280,7 +446,11 @@ func (h *AdminTariffsHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if prices == nil {
 		prices = []database.TariffPrice{}
 	}
-	writeJSON(w, http.StatusOK, tariffToDTO(t, prices))
+	dto := h.toDTO(r.Context(), t, prices)
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionTariffUpdate, TargetType: "tariff", TargetID: strconv.FormatInt(id, 10), Before: before, After: dto,
+	})
+	writeJSON(w, http.StatusOK, dto)
 }
 
 // Delete — DELETE /cabinet/api/admin/tariffs/{id}
@@ -296,14 +466,31 @@ func (h *AdminTariffsHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := h.auditState(r.Context(), id)
 	if err := h.tariffs.DeleteTariff(r.Context(), id); err != nil {
 		slog.Error("admin tariffs delete", "error", err.Error())
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
+	audit.Record(r.Context(), adminAuditActor(r), audit.Entry{
+		Action: audit.ActionTariffDelete, TargetType: "tariff", TargetID: strconv.FormatInt(id, 10), Before: before,
+	})
 	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
 }
 
+// auditState — тариф с ценами для журнала действий; nil, если тариф не найден.
+func (h *AdminTariffsHandler) auditState(ctx context.Context, id int64) any {
+	t, err := h.tariffs.GetByID(ctx, id)
+	if err != nil || t == nil {
+		return nil
+	}
+	prices, _ := h.tariffs.ListPricesForTariff(ctx, id)
+	if prices == nil {
+		prices = []database.TariffPrice{}
+	}
+	return h.toDTO(ctx, t, prices)
+}
+
 // Handle dispatches /cabinet/api/admin/tariffs (no trailing path).
 func (h *AdminTariffsHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/audit"
+	"remnawave-tg-shop-bot/internal/cabinet/http/middleware"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/payment"
+	"remnawave-tg-shop-bot/internal/staff"
+)
+
+// BalanceManager — внутренний баланс клиента (реализует payment.PaymentService).
+type BalanceManager interface {
+	Balance(ctx context.Context, customerID int64) (float64, error)
+	BalanceHistory(ctx context.Context, customerID int64, limit, offset int) ([]database.BalanceTx, error)
+	AdjustBalance(ctx context.Context, customerID int64, amount float64, comment string, adminTelegramID, adminAccountID int64) (*database.BalanceTx, error)
+}
+
+// SetBalanceManager подключает /users/{id}/balance (без платёжного сервиса эндпоинт отвечает 404).
+func (h *AdminUsersHandler) SetBalanceManager(m BalanceManager) {
+	h.balance = m
+}
+
+type adminBalanceTxDTO struct {
+	ID             int64   `json:"id"`
+	Type           string  `json:"type"`
+	Amount         float64 `json:"amount"`
+	BalanceAfter   float64 `json:"balance_after"`
+	PurchaseID     *int64  `json:"purchase_id"`
+	RefundID       *int64  `json:"refund_id"`
+	Comment        *string `json:"comment"`
+	AdminAccountID *int64  `json:"admin_account_id"`
+	CreatedAt      string  `json:"created_at"`
+}
+
+func mapBalanceTxToDTO(t *database.BalanceTx) adminBalanceTxDTO {
+	return adminBalanceTxDTO{
+		ID:             t.ID,
+		Type:           string(t.Type),
+		Amount:         t.Amount,
+		BalanceAfter:   t.BalanceAfter,
+		PurchaseID:     t.PurchaseID,
+		RefundID:       t.RefundID,
+		Comment:        t.Comment,
+		AdminAccountID: t.AdminAccountID,
+		CreatedAt:      t.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+type adminBalanceAdjustReq struct {
+	// Amount — со знаком: > 0 начислить, < 0 списать.
+	Amount  float64 `json:"amount"`
+	Comment string  `json:"comment"`
+}
+
+// Balance — GET /cabinet/api/admin/users/{id}/balance (остаток и журнал),
+// POST — ручная корректировка {amount, comment}.
+func (h *AdminUsersHandler) Balance(w http.ResponseWriter, r *http.Request) {
+	if h.balance == nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	id, ok := adminUsersExtractID(r.URL.Path)
+	if !ok {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		// Ручная корректировка баланса — деньги клиента: кроме users_write нужно право payments.
+		if m, ok := middleware.StaffFromContext(ctx); !ok || !m.Has(staff.PermPayments) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		var req adminBalanceAdjustReq
+		if !decodeJSON(w, r, &req) {
+			return
+		}
+		tx, err := h.balance.AdjustBalance(ctx, id, req.Amount, req.Comment, 0, adminAccountID(r))
+		if err != nil {
+			switch {
+			case errors.Is(err, database.ErrBalanceInsufficient):
+				http.Error(w, "insufficient balance", http.StatusConflict)
+			case errors.Is(err, payment.ErrBalanceAdjustZero), errors.Is(err, payment.ErrBalanceDisabled):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				slog.Error("admin users: balance adjust failed", "customer_id", id, "error", err.Error())
+				http.Error(w, "internal error", http.StatusInternalServerError)
+			}
+			return
+		}
+		audit.Record(ctx, adminAuditActor(r), audit.Entry{
+			Action: audit.ActionUserBalanceAdjust, CustomerID: id,
+			Before: map[string]any{"balance": tx.BalanceAfter - tx.Amount},
+			After:  map[string]any{"balance": tx.BalanceAfter, "comment": req.Comment},
+		})
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	balance, err := h.balance.Balance(ctx, id)
+	if err != nil {
+		slog.Error("admin users: balance failed", "customer_id", id, "error", err.Error())
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	items, err := h.balance.BalanceHistory(ctx, id, limit, 0)
+	if err != nil {
+		slog.Error("admin users: balance history failed", "customer_id", id, "error", err.Error())
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	out := make([]adminBalanceTxDTO, 0, len(items))
+	for i := range items {
+		out = append(out, mapBalanceTxToDTO(&items[i]))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"balance": balance, "items": out})
+}
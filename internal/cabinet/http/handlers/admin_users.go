@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"remnawave-tg-shop-bot/internal/audit"
 	"remnawave-tg-shop-bot/internal/cabinet/http/middleware"
 	"remnawave-tg-shop-bot/internal/config"
 	"remnawave-tg-shop-bot/internal/database"
@@ -25,6 +26,8 @@ type AdminUsersHandler struct {
 	tariffs   *database.TariffRepository
 	loyalty   *database.LoyaltyTierRepository
 	rw        *remnawave.Client
+	balance   BalanceManager // опционально nil: /balance отвечает 404
+	auditLog  *AdminAuditHandler // опционально nil: /audit отвечает 404
 }
 
 // NewAdminUsers — конструктор.
@@ -364,6 +367,15 @@ func adminAccountID(r *http.Request) int64 {
 	return 0
 }
 
+// adminAuditActor — сотрудник кабинета для журнала действий.
+func adminAuditActor(r *http.Request) audit.Actor {
+	var telegramID int64
+	if m, ok := middleware.StaffFromContext(r.Context()); ok {
+		telegramID = m.TelegramID
+	}
+	return audit.Cabinet(telegramID, adminAccountID(r))
+}
+
 func (h *AdminUsersHandler) syncCustomerAfterPatch(ctx context.Context, custID int64, rwUser *remnawave.User) error {
 	return h.customers.UpdateFields(ctx, custID, map[string]interface{}{
 		"subscription_link": rwUser.SubscriptionUrl,
@@ -449,6 +461,10 @@ func (h *AdminUsersHandler) SetExpire(w http.ResponseWriter, r *http.Request) {
 	}
 
 	slog.Info("admin: set expire", "customer_id", id, "expire_at", parsed.Format(time.RFC3339), "admin_account_id", adminAccountID(r))
+	audit.Record(ctx, adminAuditActor(r), audit.Entry{
+		Action: audit.ActionUserExpireSet, CustomerID: id,
+		Before: audit.CustomerState(cust), After: audit.CustomerState(updated),
+	})
 	writeJSON(w, http.StatusOK, mapCustomerToDTO(updated))
 }
 
@@ -517,6 +533,10 @@ func (h *AdminUsersHandler) Extend(w http.ResponseWriter, r *http.Request) {
 	}
 
 	slog.Info("admin: extended user", "customer_id", id, "days", body.Days, "admin_account_id", adminAccountID(r))
+	audit.Record(ctx, adminAuditActor(r), audit.Entry{
+		Action: audit.ActionUserExtend, CustomerID: id,
+		Before: audit.CustomerState(cust), After: audit.CustomerState(updated),
+	})
 	writeJSON(w, http.StatusOK, mapCustomerToDTO(updated))
 }
 
@@ -575,6 +595,10 @@ func (h *AdminUsersHandler) Disable(w http.ResponseWriter, r *http.Request) {
 	}
 
 	slog.Info("admin: disabled user", "customer_id", id, "admin_account_id", adminAccountID(r))
+	audit.Record(ctx, adminAuditActor(r), audit.Entry{
+		Action: audit.ActionUserDisable, CustomerID: id,
+		Before: audit.UserState(rwUser), After: audit.UserState(out),
+	})
 	writeJSON(w, http.StatusOK, map[string]string{"status": "disabled"})
 }
 
@@ -633,6 +657,10 @@ func (h *AdminUsersHandler) Enable(w http.ResponseWriter, r *http.Request) {
 	}
 
 	slog.Info("admin: enabled user", "customer_id", id, "admin_account_id", adminAccountID(r))
+	audit.Record(ctx, adminAuditActor(r), audit.Entry{
+		Action: audit.ActionUserEnable, CustomerID: id,
+		Before: audit.UserState(rwUser), After: audit.UserState(out),
+	})
 	writeJSON(w, http.StatusOK, map[string]string{"status": "enabled"})
 }
 
@@ -681,6 +709,9 @@ func (h *AdminUsersHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	slog.Info("admin: deleted user", "customer_id", id, "telegram_id", cust.TelegramID, "admin_account_id", adminAccountID(r))
+	audit.Record(ctx, adminAuditActor(r), audit.Entry{
+		Action: audit.ActionUserDelete, CustomerID: id, Before: audit.CustomerState(cust),
+	})
 	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
 
@@ -731,6 +762,11 @@ func (h *AdminUsersHandler) ResetTraffic(w http.ResponseWriter, r *http.Request)
 	}
 
 	slog.Info("admin: reset traffic", "customer_id", id, "admin_account_id", adminAccountID(r))
+	audit.Record(ctx, adminAuditActor(r), audit.Entry{
+		Action: audit.ActionUserTrafficReset, CustomerID: id,
+		Before: map[string]any{"used_traffic_bytes": rwUser.UserTraffic.UsedTrafficBytes},
+		After:  map[string]any{"used_traffic_bytes": 0},
+	})
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
@@ -775,13 +811,17 @@ func (h *AdminUsersHandler) SetHwidLimit(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if _, err := h.rw.UpdateUserDeviceLimitByCustomer(ctx, cust.ID, cust.TelegramID, body.Limit); err != nil {
+	out, err := h.rw.UpdateUserDeviceLimitByCustomer(ctx, cust.ID, cust.TelegramID, body.Limit)
+	if err != nil {
 		slog.Error("admin users: set hwid — rw failed", "error", err.Error())
 		http.Error(w, "remnawave operation failed", http.StatusInternalServerError)
 		return
 	}
 
 	slog.Info("admin: set hwid limit", "customer_id", id, "limit", body.Limit, "admin_account_id", adminAccountID(r))
+	audit.Record(ctx, adminAuditActor(r), audit.Entry{
+		Action: audit.ActionUserHwidLimit, CustomerID: id, After: map[string]any{"hwid_device_limit": out.HwidDeviceLimit},
+	})
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
@@ -798,6 +838,8 @@ type adminPurchaseDTO struct {
 	TariffID   *int64  `json:"tariff_id"`
 	PromoID    *int64  `json:"promo_code_id"`
 	DiscountPc *int    `json:"discount_percent"`
+	Status     string  `json:"status"`
+	Refunded   float64 `json:"refunded_amount"`
 }
 
 type adminPaymentsResp struct {
@@ -873,6 +915,8 @@ func (h *AdminUsersHandler) Payments(w http.ResponseWriter, r *http.Request) {
 			TariffID:   p.TariffID,
 			PromoID:    p.PromoCodeID,
 			DiscountPc: p.DiscountPercentApplied,
+			Status:     string(p.Status),
+			Refunded:   p.RefundedAmount,
 		}
 		if p.PaidAt != nil {
 			s := p.PaidAt.Format(time.RFC3339)
@@ -1046,6 +1090,10 @@ func (h *AdminUsersHandler) HandleByID(w http.ResponseWriter, r *http.Request) {
 		h.Devices(w, r)
 	case strings.HasSuffix(path, "/extra-hwid"):
 		h.ExtraHwid(w, r)
+	case strings.HasSuffix(path, "/balance"):
+		h.Balance(w, r)
+	case strings.HasSuffix(path, "/audit"):
+		h.Audit(w, r)
 	default:
 		h.Get(w, r)
 	}
@@ -12,6 +12,7 @@ import (
 	"github.com/google/uuid"
 
 	cabsvc "remnawave-tg-shop-bot/internal/cabinet/service"
+	"remnawave-tg-shop-bot/internal/audit"
 	"remnawave-tg-shop-bot/internal/config"
 	"remnawave-tg-shop-bot/internal/database"
 	"remnawave-tg-shop-bot/internal/payment"
@@ -225,6 +226,10 @@ func (h *AdminUsersHandler) SetSquads(w http.ResponseWriter, r *http.Request) {
 	}
 	_ = h.syncCustomerAfterPatch(ctx, cust.ID, out)
 	slog.Info("admin: set squads", "customer_id", id, "admin_account_id", adminAccountID(r))
+	audit.Record(ctx, adminAuditActor(r), audit.Entry{
+		Action: audit.ActionUserSquads, CustomerID: id,
+		Before: audit.UserState(rw), After: audit.UserState(out),
+	})
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
@@ -277,6 +282,10 @@ func (h *AdminUsersHandler) SetTraffic(w http.ResponseWriter, r *http.Request) {
 	}
 	_ = h.syncCustomerAfterPatch(ctx, cust.ID, out)
 	slog.Info("admin: set traffic", "customer_id", id, "limit_bytes", body.LimitBytes, "admin_account_id", adminAccountID(r))
+	audit.Record(ctx, adminAuditActor(r), audit.Entry{
+		Action: audit.ActionUserTrafficLimit, CustomerID: id,
+		Before: audit.UserState(rw), After: audit.UserState(out),
+	})
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
@@ -337,6 +346,10 @@ func (h *AdminUsersHandler) SetStrategy(w http.ResponseWriter, r *http.Request)
 	}
 	_ = h.syncCustomerAfterPatch(ctx, cust.ID, out)
 	slog.Info("admin: set strategy", "customer_id", id, "strategy", strategy, "admin_account_id", adminAccountID(r))
+	audit.Record(ctx, adminAuditActor(r), audit.Entry{
+		Action: audit.ActionUserStrategy, CustomerID: id,
+		Before: audit.UserState(rw), After: audit.UserState(out),
+	})
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
@@ -388,6 +401,10 @@ func (h *AdminUsersHandler) SetDescription(w http.ResponseWriter, r *http.Reques
 	}
 	_ = h.syncCustomerAfterPatch(ctx, cust.ID, out)
 	slog.Info("admin: set description", "customer_id", id, "admin_account_id", adminAccountID(r))
+	audit.Record(ctx, adminAuditActor(r), audit.Entry{
+		Action: audit.ActionUserDescription, CustomerID: id,
+		Before: audit.UserState(rw), After: audit.UserState(out),
+	})
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
@@ -453,12 +470,17 @@ func (h *AdminUsersHandler) SetTariff(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := cust
 	cust, _ = h.customers.FindById(ctx, id)
 	if err := h.adminRelimitExtraAfterTariff(ctx, cust, tariff); err != nil {
 		slog.Warn("admin users: set tariff extra relimit", "error", err.Error())
 	}
 
 	slog.Info("admin: set tariff", "customer_id", id, "tariff_id", body.TariffID, "admin_account_id", adminAccountID(r))
+	audit.Record(ctx, adminAuditActor(r), audit.Entry{
+		Action: audit.ActionUserTariff, CustomerID: id,
+		Before: audit.CustomerState(before), After: audit.CustomerState(cust),
+	})
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
@@ -546,6 +568,9 @@ func (h *AdminUsersHandler) DeleteDevice(w http.ResponseWriter, r *http.Request)
 	}
 
 	slog.Info("admin: delete device", "customer_id", id, "hwid", hwid, "admin_account_id", adminAccountID(r))
+	audit.Record(ctx, adminAuditActor(r), audit.Entry{
+		Action: audit.ActionUserDeviceDelete, CustomerID: id, Before: map[string]any{"hwid": hwid},
+	})
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
@@ -597,6 +622,10 @@ func (h *AdminUsersHandler) ExtraHwid(w http.ResponseWriter, r *http.Request) {
 	}
 
 	slog.Info("admin: extra hwid", "customer_id", id, "delta", body.Delta, "admin_account_id", adminAccountID(r))
+	audit.Record(ctx, adminAuditActor(r), audit.Entry{
+		Action: audit.ActionUserExtraHwid, CustomerID: id,
+		Before: audit.CustomerState(cust), After: audit.CustomerState(updated),
+	})
 	writeJSON(w, http.StatusOK, mapCustomerToDTO(updated))
 }
 
@@ -15,11 +15,13 @@ import (
 	"net/http"
 	"strings"
 
+	"remnawave-tg-shop-bot/internal/acquisition"
 	"remnawave-tg-shop-bot/internal/cabinet/auth/service"
 	cabcfg "remnawave-tg-shop-bot/internal/cabinet/config"
 	"remnawave-tg-shop-bot/internal/cabinet/http/middleware"
 	cabmetrics "remnawave-tg-shop-bot/internal/cabinet/metrics"
 	botcfg "remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/payment"
 )
 
 // AuthHandler — группа эндпоинтов /cabinet/api/auth/*.
@@ -32,6 +34,7 @@ type AuthHandler struct {
 	telegramLoginBotUser string // username без @ для Login Widget; "" — вход через Telegram не настроен
 	telegramOIDCEnabled  bool
 	telegramWebAuthMode  string
+	payments             *payment.PaymentService // nil — payment_providers пуст
 }
 
 // NewAuth — конструктор. googleOAuthEnabled и telegramLoginBotUser — публичные
@@ -45,6 +48,7 @@ func NewAuth(
 	telegramLoginBotUser string,
 	telegramOIDCEnabled bool,
 	telegramWebAuthMode string,
+	payments *payment.PaymentService,
 ) *AuthHandler {
 	return &AuthHandler{
 		svc: svc, cookieDomain: cookieDomain,
@@ -54,6 +58,7 @@ func NewAuth(
 		telegramLoginBotUser: telegramLoginBotUser,
 		telegramOIDCEnabled:  telegramOIDCEnabled,
 		telegramWebAuthMode:  telegramWebAuthMode,
+		payments:             payments,
 	}
 }
 
@@ -66,6 +71,8 @@ type registerReq struct {
 	Password     string `json:"password"`
 	Language     string `json:"language"`
 	ReferralCode string `json:"referral_code"`
+	// UTM — utm_* из адреса страницы регистрации (источник привлечения).
+	UTM map[string]string `json:"utm"`
 }
 
 type loginReq struct {
@@ -121,6 +128,20 @@ func cabinetSiteLinks() map[string]string {
 	return m
 }
 
+// paymentProviders — ключ провайдера кабинета → включён ли он (из реестра payment).
+func (h *AuthHandler) paymentProviders() map[string]bool {
+	m := map[string]bool{}
+	if h.payments == nil {
+		return m
+	}
+	for _, p := range h.payments.Providers().All() {
+		if key := p.Info().CheckoutKey; key != "" {
+			m[key] = p.Enabled()
+		}
+	}
+	return m
+}
+
 // AuthBootstrap — GET /cabinet/api/auth/bootstrap.
 // Публичные флаги для страницы логина: какие альтернативные провайдеры доступны.
 func (h *AuthHandler) AuthBootstrap(w http.ResponseWriter, r *http.Request) {
@@ -143,16 +164,7 @@ func (h *AuthHandler) AuthBootstrap(w http.ResponseWriter, r *http.Request) {
 		// что для Happ/INCY надо запросить зашифрованную ссылку вместо .../add/.
 		"deeplink_happ_encrypt": cabcfg.DeeplinkHappEncryptEnabled(),
 		"deeplink_incy_encrypt": cabcfg.DeeplinkIncyEncryptEnabled(),
-		"payment_providers": map[string]bool{
-			"yookassa":          botcfg.IsYookasaEnabled(),
-			"cryptopay":         botcfg.IsCryptoPayEnabled(),
-			"telegram":          botcfg.IsTelegramStarsEnabled(),
-			"platega_sbp":       botcfg.IsPlategaEnabled() && botcfg.IsPlategaSBPEnabled(),
-			"platega_cards":     botcfg.IsPlategaEnabled() && botcfg.IsPlategaCardsEnabled(),
-			"platega_acquiring": botcfg.IsPlategaEnabled() && botcfg.IsPlategaAcquiringEnabled(),
-			"platega_worldwide": botcfg.IsPlategaEnabled() && botcfg.IsPlategaWorldwideEnabled(),
-			"platega_crypto":    botcfg.IsPlategaEnabled() && botcfg.IsPlategaCryptoEnabled(),
-		},
+		"payment_providers": h.paymentProviders(),
 	}
 	if cabcfg.TurnstileEnabled() && strings.TrimSpace(cabcfg.TurnstileSiteKey()) != "" {
 		body["turnstile_site_key"] = cabcfg.TurnstileSiteKey()
@@ -190,6 +202,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		Password:     req.Password,
 		Language:     req.Language,
 		ReferralCode: req.ReferralCode,
+		Acquisition:  acquisition.FromUTM(req.UTM),
 		UserAgent:    r.UserAgent(),
 		IP:           middleware.ClientIP(r),
 	})
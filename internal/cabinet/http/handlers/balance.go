@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"remnawave-tg-shop-bot/internal/cabinet/http/middleware"
+	cabmetrics "remnawave-tg-shop-bot/internal/cabinet/metrics"
+	"remnawave-tg-shop-bot/internal/cabinet/payments"
+)
+
+type balanceTopUpReq struct {
+	Amount   int    `json:"amount"`
+	Provider string `json:"provider"`
+}
+
+// Balance — GET /cabinet/api/me/balance?limit=&offset= — остаток внутреннего баланса и журнал операций.
+func (h *PaymentsHandler) Balance(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.AuthClaims(r)
+	if claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset, _ := strconv.Atoi(q.Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	result, err := h.svc.Balance(r.Context(), claims.AccountID, limit, offset)
+	if err != nil {
+		writePaymentsErr(w, err, "balance")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// BalanceTopUp — POST /cabinet/api/payments/balance/topup {amount, provider} + Idempotency-Key.
+// Ответ как у /payments/checkout: статус оплаты поллится через /payments/{id}/status.
+func (h *PaymentsHandler) BalanceTopUp(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.AuthClaims(r)
+	if claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req balanceTopUpReq
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	result, err := h.svc.CreateTopUp(r.Context(), claims.AccountID, payments.TopUpRequest{
+		Amount:         req.Amount,
+		Provider:       req.Provider,
+		IdempotencyKey: strings.TrimSpace(r.Header.Get("Idempotency-Key")),
+	})
+	if err != nil {
+		writePaymentsErr(w, err, "balance_topup")
+		return
+	}
+	status := http.StatusCreated
+	if result.Reused {
+		status = http.StatusOK
+	} else {
+		cabmetrics.RecordCheckoutStarted(result.Provider)
+	}
+	writeJSON(w, status, result)
+}
@@ -13,14 +13,15 @@ import (
 	"remnawave-tg-shop-bot/utils"
 )
 
-// CabinetActivityHandler — GET /cabinet/api/me/referrals и /cabinet/api/me/purchases.
+// CabinetActivityHandler — GET /cabinet/api/me/referrals, /cabinet/api/me/purchases и лента /cabinet/api/me/notifications.
 type CabinetActivityHandler struct {
-	links     *repository.AccountCustomerLinkRepo
-	ids       *repository.IdentityRepo
-	customers *database.CustomerRepository
-	referrals *database.ReferralRepository
-	purchases *database.PurchaseRepository
-	publicURL string
+	links         *repository.AccountCustomerLinkRepo
+	ids           *repository.IdentityRepo
+	customers     *database.CustomerRepository
+	referrals     *database.ReferralRepository
+	purchases     *database.PurchaseRepository
+	notifications *database.CustomerNotificationRepository
+	publicURL     string
 }
 
 // NewCabinetActivity — конструктор.
@@ -30,10 +31,11 @@ func NewCabinetActivity(
 	customers *database.CustomerRepository,
 	referrals *database.ReferralRepository,
 	purchases *database.PurchaseRepository,
+	notifications *database.CustomerNotificationRepository,
 	publicURL string,
 ) *CabinetActivityHandler {
 	return &CabinetActivityHandler{
-		links: links, ids: ids, customers: customers, referrals: referrals, purchases: purchases,
+		links: links, ids: ids, customers: customers, referrals: referrals, purchases: purchases, notifications: notifications,
 		publicURL: strings.TrimRight(publicURL, "/"),
 	}
 }
@@ -3,6 +3,7 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"mime"
 	"net/http"
 	"os"
@@ -93,6 +94,29 @@ func (h *CabinetContentHandler) i18nFileCandidates(lang string) []string {
 	return out
 }
 
+// i18nSection — строки раздела section из i18n-бандла кабинета для страниц, которые рендерит бэкенд.
+func (h *CabinetContentHandler) i18nSection(lang, section string) (map[string]string, error) {
+	body, err := h.readFirstExisting(h.i18nFileCandidates(lang))
+	if err != nil {
+		return nil, err
+	}
+	var bundle struct {
+		Translation map[string]json.RawMessage `json:"translation"`
+	}
+	if err := json.Unmarshal(body, &bundle); err != nil {
+		return nil, fmt.Errorf("parse i18n bundle %q: %w", lang, err)
+	}
+	raw, ok := bundle.Translation[section]
+	if !ok {
+		return nil, fmt.Errorf("i18n bundle %q: section %q not found", lang, section)
+	}
+	var out map[string]string
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("parse i18n section %q: %w", section, err)
+	}
+	return out, nil
+}
+
 // AppConfig — GET /cabinet/api/content/app-config
 func (h *CabinetContentHandler) AppConfig(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
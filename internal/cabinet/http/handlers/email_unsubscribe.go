@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+
+	"remnawave-tg-shop-bot/internal/cabinet/mail"
+	"remnawave-tg-shop-bot/internal/cabinet/repository"
+)
+
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!doctype html>
+<html lang="{{ .Lang }}">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{ .Title }}</title>
+</head>
+<body style="margin:0;padding:24px;background:#f4f5f7;font-family:Segoe UI,Arial,sans-serif;color:#222;">
+<div style="max-width:480px;margin:0 auto;background:#ffffff;border-radius:12px;padding:32px;box-shadow:0 4px 16px rgba(0,0,0,0.04);">
+    <h1 style="margin:0 0 16px;font-size:22px;line-height:1.3;">{{ .Title }}</h1>
+    <p style="margin:0 0 16px;font-size:15px;line-height:1.5;">{{ .Text }}</p>
+    {{- if .Button }}
+    <form method="post" style="margin:24px 0;text-align:center;">
+        <button type="submit" style="padding:12px 20px;background:#2563eb;color:#ffffff;border:0;border-radius:8px;font-weight:600;font-size:15px;cursor:pointer;">{{ .Button }}</button>
+    </form>
+    {{- end }}
+</div>
+</body>
+</html>
+`))
+
+type unsubscribePageData struct {
+	Lang, Title, Text, Button string
+}
+
+// EmailUnsubscribe — публичная отписка от рекламных писем: GET /cabinet/api/email/unsubscribe?token=
+// показывает страницу с кнопкой подтверждения (почтовые сканеры открывают ссылки сами),
+// POST отписывает — его же шлёт почтовый клиент по List-Unsubscribe-Post (one-click).
+// Сервисные письма (окончание подписки, коды) отписка не отключает.
+// Тексты страницы — раздел emailUnsubscribe i18n-бандла кабинета.
+func EmailUnsubscribe(accounts *repository.AccountRepo, secret []byte) http.HandlerFunc {
+	content := NewCabinetContentHandler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		accountID, ok := mail.ParseUnsubscribeToken(secret, r.URL.Query().Get("token"))
+		if !ok {
+			writeUnsubscribePage(w, content, http.StatusBadRequest, "ru", "invalid")
+			return
+		}
+		acc, err := accounts.FindByID(r.Context(), accountID)
+		if errors.Is(err, repository.ErrNotFound) {
+			writeUnsubscribePage(w, content, http.StatusNotFound, "ru", "invalid")
+			return
+		}
+		if err != nil {
+			slog.Error("email unsubscribe: find account", "account_id", accountID, "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if r.Method == http.MethodGet {
+			writeUnsubscribePage(w, content, http.StatusOK, acc.Language, "confirm")
+			return
+		}
+		if err := accounts.OptOutMarketingEmail(r.Context(), accountID); err != nil {
+			slog.Error("email unsubscribe: opt out", "account_id", accountID, "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		slog.Info("email unsubscribe: marketing opt-out", "account_id", accountID)
+		writeUnsubscribePage(w, content, http.StatusOK, acc.Language, "done")
+	}
+}
+
+func writeUnsubscribePage(w http.ResponseWriter, content *CabinetContentHandler, status int, lang, state string) {
+	if lang != "en" {
+		lang = "ru"
+	}
+	texts, err := content.i18nSection(lang, "emailUnsubscribe")
+	if err != nil {
+		slog.Warn("email unsubscribe: load page texts", "lang", lang, "error", err)
+	}
+	text := func(key string) string {
+		if v, ok := texts[key]; ok {
+			return v
+		}
+		return key
+	}
+	data := unsubscribePageData{Lang: lang}
+	switch state {
+	case "confirm":
+		data.Title, data.Text, data.Button = text("confirmTitle"), text("confirmText"), text("confirmButton")
+	case "done":
+		data.Title, data.Text = text("doneTitle"), text("doneText")
+	default:
+		data.Title, data.Text = text("invalidTitle"), text("invalidText")
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = unsubscribePage.Execute(w, data)
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteUnsubscribePageTexts(t *testing.T) {
+	content := &CabinetContentHandler{i18nCandidates: []string{filepath.Join("..", "..", "..", "..", "web", "cabinet", "src", "i18n")}}
+	for _, lang := range []string{"ru", "en"} {
+		for _, state := range []string{"confirm", "done", "invalid"} {
+			rec := httptest.NewRecorder()
+			writeUnsubscribePage(rec, content, 200, lang, state)
+			body := rec.Body.String()
+			for _, key := range []string{"Title", "Text", "Button"} {
+				if strings.Contains(body, state+key) {
+					t.Fatalf("%s/%s: missing text %s%s", lang, state, state, key)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"remnawave-tg-shop-bot/internal/cabinet/http/middleware"
+)
+
+type familyJoinReq struct {
+	Code string `json:"code"`
+}
+
+type familyMemberReq struct {
+	DeviceLimit int `json:"device_limit"`
+}
+
+// Family — GET /cabinet/api/me/family — семья владельца (участники, пул устройств, приглашение) или участие в чужой.
+func (h *PaymentsHandler) Family(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.AuthClaims(r)
+	if claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	result, err := h.svc.Family(r.Context(), claims.AccountID)
+	if err != nil {
+		writePaymentsErr(w, err, "family")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// FamilyInvite — POST /cabinet/api/me/family/invite — новая ссылка-приглашение.
+func (h *PaymentsHandler) FamilyInvite(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.AuthClaims(r)
+	if claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	result, err := h.svc.RegenerateFamilyInvite(r.Context(), claims.AccountID)
+	if err != nil {
+		writePaymentsErr(w, err, "family_invite")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// FamilyJoin — POST /cabinet/api/me/family/join {code}; 409 — мест нет или клиент уже в семье.
+func (h *PaymentsHandler) FamilyJoin(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.AuthClaims(r)
+	if claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req familyJoinReq
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	result, err := h.svc.JoinFamily(r.Context(), claims.AccountID, req.Code)
+	if err != nil {
+		writePaymentsErr(w, err, "family_join")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// FamilyLeave — POST /cabinet/api/me/family/leave.
+func (h *PaymentsHandler) FamilyLeave(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.AuthClaims(r)
+	if claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := h.svc.LeaveFamily(r.Context(), claims.AccountID); err != nil {
+		writePaymentsErr(w, err, "family_leave")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// FamilyMember — PATCH /cabinet/api/me/family/members/{customer_id} {device_limit} — устройства участника;
+// DELETE — исключить участника.
+func (h *PaymentsHandler) FamilyMember(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.AuthClaims(r)
+	if claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	memberID, err := strconv.ParseInt(r.PathValue("customer_id"), 10, 64)
+	if err != nil || memberID <= 0 {
+		http.Error(w, "invalid customer id", http.StatusBadRequest)
+		return
+	}
+	if r.Method == http.MethodDelete {
+		result, err := h.svc.RemoveFamilyMember(r.Context(), claims.AccountID, memberID)
+		if err != nil {
+			writePaymentsErr(w, err, "family_remove")
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+	var req familyMemberReq
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	result, err := h.svc.SetFamilyMemberDevices(r.Context(), claims.AccountID, memberID, req.DeviceLimit)
+	if err != nil {
+		writePaymentsErr(w, err, "family_devices")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/cabinet/http/middleware"
+)
+
+type notificationDTO struct {
+	ID        int64      `json:"id"`
+	Kind      string     `json:"kind"`
+	Text      string     `json:"text"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+}
+
+type notificationsResp struct {
+	Items  []notificationDTO `json:"items"`
+	Unread int64             `json:"unread"`
+}
+
+// GetNotifications — GET /cabinet/api/me/notifications?limit= — лента уведомлений и число непрочитанных.
+func (h *CabinetActivityHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.AuthClaims(r)
+	if claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	cid, ok := h.resolveCustomerID(r, claims.AccountID)
+	if !ok || h.notifications == nil {
+		writeJSON(w, http.StatusOK, notificationsResp{Items: []notificationDTO{}})
+		return
+	}
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 200 {
+			limit = n
+		}
+	}
+	list, err := h.notifications.ListByCustomer(r.Context(), cid, limit)
+	if err != nil {
+		slog.Error("cabinet_activity: notifications list", "error", err.Error())
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	unread, err := h.notifications.CountUnread(r.Context(), cid)
+	if err != nil {
+		slog.Error("cabinet_activity: notifications unread", "error", err.Error())
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := notificationsResp{Items: make([]notificationDTO, 0, len(list)), Unread: unread}
+	for _, n := range list {
+		resp.Items = append(resp.Items, notificationDTO{
+			ID:        n.ID,
+			Kind:      n.Kind,
+			Text:      n.Text,
+			CreatedAt: n.CreatedAt,
+			ReadAt:    n.ReadAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// MarkNotificationsRead — POST /cabinet/api/me/notifications/read — отмечает всю ленту прочитанной.
+func (h *CabinetActivityHandler) MarkNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.AuthClaims(r)
+	if claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	cid, ok := h.resolveCustomerID(r, claims.AccountID)
+	if ok && h.notifications != nil {
+		if err := h.notifications.MarkAllRead(r.Context(), cid); err != nil {
+			slog.Error("cabinet_activity: notifications read", "error", err.Error())
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
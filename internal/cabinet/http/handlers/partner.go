@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"remnawave-tg-shop-bot/internal/cabinet/http/middleware"
+	"remnawave-tg-shop-bot/internal/cabinet/payments"
+)
+
+type partnerPayoutReq struct {
+	Amount  float64 `json:"amount"`
+	Method  string  `json:"method"`
+	Details string  `json:"details"`
+}
+
+// Partner — GET /cabinet/api/me/partner?limit=&offset= — ставка, суммы, начисления и запросы на выплату.
+func (h *PaymentsHandler) Partner(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.AuthClaims(r)
+	if claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	offset, _ := strconv.Atoi(q.Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	result, err := h.svc.Partner(r.Context(), claims.AccountID, limit, offset)
+	if err != nil {
+		writePaymentsErr(w, err, "partner")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// PartnerPayout — POST /cabinet/api/me/partner/payouts {amount, method, details}.
+// amount 0 — всё доступное; 409 — предыдущий запрос ещё не обработан.
+func (h *PaymentsHandler) PartnerPayout(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.AuthClaims(r)
+	if claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req partnerPayoutReq
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	result, err := h.svc.RequestPartnerPayout(r.Context(), claims.AccountID, payments.PartnerPayoutRequest{
+		Amount:  req.Amount,
+		Method:  req.Method,
+		Details: req.Details,
+	})
+	if err != nil {
+		writePaymentsErr(w, err, "partner_payout")
+		return
+	}
+	writeJSON(w, http.StatusCreated, result)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+
+	"remnawave-tg-shop-bot/internal/cabinet/http/middleware"
+)
+
+type pauseReq struct {
+	Days int `json:"days"`
+}
+
+// SubscriptionPause — GET /cabinet/api/me/subscription/pause — активная пауза, лимиты и доступность новой.
+func (h *PaymentsHandler) SubscriptionPause(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.AuthClaims(r)
+	if claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	result, err := h.svc.PauseStatus(r.Context(), claims.AccountID)
+	if err != nil {
+		writePaymentsErr(w, err, "subscription_pause")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// SubscriptionPauseStart — POST /cabinet/api/me/subscription/pause {days}; 409 — подписка уже на паузе.
+func (h *PaymentsHandler) SubscriptionPauseStart(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.AuthClaims(r)
+	if claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req pauseReq
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	result, err := h.svc.PauseSubscription(r.Context(), claims.AccountID, req.Days)
+	if err != nil {
+		writePaymentsErr(w, err, "subscription_pause_start")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// SubscriptionPauseResume — POST /cabinet/api/me/subscription/resume — досрочное возобновление.
+func (h *PaymentsHandler) SubscriptionPauseResume(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.AuthClaims(r)
+	if claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	result, err := h.svc.ResumeSubscription(r.Context(), claims.AccountID)
+	if err != nil {
+		writePaymentsErr(w, err, "subscription_pause_resume")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
@@ -10,6 +10,7 @@ import (
 	"remnawave-tg-shop-bot/internal/cabinet/http/middleware"
 	cabmetrics "remnawave-tg-shop-bot/internal/cabinet/metrics"
 	"remnawave-tg-shop-bot/internal/cabinet/payments"
+	"remnawave-tg-shop-bot/internal/database"
 )
 
 // PaymentsHandler — HTTP-адаптер над payments.CheckoutService.
@@ -29,6 +30,8 @@ type checkoutReq struct {
 	Provider string `json:"provider"`
 	// RenewExtraHwid — продлевать активные доп. HWID вместе с подпиской.
 	RenewExtraHwid bool `json:"renew_extra_hwid"`
+	// Currency — валюта счёта (USD, EUR, …); пусто — по языку клиента.
+	Currency string `json:"currency,omitempty"`
 }
 
 // Checkout — POST /cabinet/api/payments/checkout.
@@ -54,6 +57,7 @@ func (h *PaymentsHandler) Checkout(w http.ResponseWriter, r *http.Request) {
 		Provider:       strings.ToLower(strings.TrimSpace(req.Provider)),
 		IdempotencyKey: idemKey,
 		RenewExtraHwid: req.RenewExtraHwid,
+		Currency:       strings.TrimSpace(req.Currency),
 	})
 	if err != nil {
 		writePaymentsErr(w, err, "checkout")
@@ -69,7 +73,7 @@ func (h *PaymentsHandler) Checkout(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, status, result)
 }
 
-// Preview — GET /cabinet/api/payments/preview?period=&tariff_id= (tariff_id в режиме tariffs).
+// Preview — GET /cabinet/api/payments/preview?period=&tariff_id=&currency= (tariff_id в режиме tariffs).
 //
 // Сумма и сценарий совпадают с Create без создания счёта.
 func (h *PaymentsHandler) Preview(w http.ResponseWriter, r *http.Request) {
@@ -110,7 +114,8 @@ func (h *PaymentsHandler) Preview(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := payments.WithPreviewRenewExtraHwid(r.Context(), renewExtraHwid)
-	result, err := h.svc.Preview(ctx, claims.AccountID, period, tariffID, provider)
+	currency := strings.TrimSpace(r.URL.Query().Get("currency"))
+	result, err := h.svc.Preview(ctx, claims.AccountID, period, tariffID, provider, currency)
 	if err != nil {
 		writePaymentsErr(w, err, "preview")
 		return
@@ -248,6 +253,16 @@ func writePaymentsErr(w http.ResponseWriter, err error, op string) {
 		http.Error(w, "not found", http.StatusNotFound)
 	case errors.Is(err, payments.ErrForbidden):
 		http.Error(w, "forbidden", http.StatusForbidden)
+	case errors.Is(err, payments.ErrInsufficientBalance):
+		http.Error(w, "insufficient balance", http.StatusPaymentRequired)
+	case errors.Is(err, database.ErrPayoutPendingExists):
+		http.Error(w, "payout request already pending", http.StatusConflict)
+	case errors.Is(err, database.ErrFamilyFull):
+		http.Error(w, "family is full", http.StatusConflict)
+	case errors.Is(err, database.ErrFamilyMemberExists):
+		http.Error(w, "already in a family", http.StatusConflict)
+	case errors.Is(err, database.ErrSubscriptionPauseActive):
+		http.Error(w, "subscription is already paused", http.StatusConflict)
 	default:
 		slog.Error("cabinet payments handler error", "op", op, "error", err.Error())
 		http.Error(w, "internal error", http.StatusInternalServerError)
@@ -42,12 +42,14 @@ type promoApplyResp struct {
 	TrialDays             int  `json:"trial_days,omitempty"`
 	ExtraHwidDelta        int  `json:"extra_hwid_delta,omitempty"`
 	DiscountPercent       int  `json:"discount_percent,omitempty"`
+	DiscountAmount        int  `json:"discount_amount,omitempty"`
 	TrialSkippedActiveSub bool `json:"trial_skipped_active_sub,omitempty"`
 }
 
 type pendingDiscountDTO struct {
 	PromoCodeID                   int    `json:"promo_code_id"`
 	Percent                       int    `json:"percent"`
+	Amount                        int    `json:"amount"`
 	UntilFirstPurchase            bool   `json:"until_first_purchase"`
 	SubscriptionPaymentsRemaining int    `json:"subscription_payments_remaining"`
 	ExpiresAt                     string `json:"expires_at,omitempty"`
@@ -115,6 +117,7 @@ func (h *PromoCodesHandler) GetState(w http.ResponseWriter, r *http.Request) {
 			dto := &pendingDiscountDTO{
 				PromoCodeID:                   int(d.PromoCodeID),
 				Percent:                       d.Percent,
+				Amount:                        d.Amount,
 				UntilFirstPurchase:            d.UntilFirstPurchase,
 				SubscriptionPaymentsRemaining: d.SubscriptionPaymentsRemaining,
 			}
@@ -175,6 +178,7 @@ func (h *PromoCodesHandler) Apply(w http.ResponseWriter, r *http.Request) {
 		TrialDays:             res.TrialDays,
 		ExtraHwidDelta:        res.ExtraHwidDelta,
 		DiscountPercent:       res.DiscountPercent,
+		DiscountAmount:        res.DiscountAmount,
 		TrialSkippedActiveSub: res.TrialSkippedActiveSub,
 	}
 	writeJSON(w, http.StatusOK, out)
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -10,6 +11,7 @@ import (
 	"remnawave-tg-shop-bot/internal/cabinet/deeplink"
 	"remnawave-tg-shop-bot/internal/cabinet/http/middleware"
 	cabsvc "remnawave-tg-shop-bot/internal/cabinet/service"
+	"remnawave-tg-shop-bot/internal/payment"
 )
 
 // SubscriptionHandler — эндпоинт GET /cabinet/api/me/subscription.
@@ -51,6 +53,43 @@ func (h *SubscriptionHandler) Get(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, resp)
 }
 
+type autoRenewPatchReq struct {
+	AutoRenew *bool `json:"auto_renew"`
+}
+
+// Patch — PATCH /cabinet/api/me/subscription {"auto_renew": true|false}.
+// Включить можно только при сохранённой карте (после оплаты через ЮKassa): иначе 409.
+func (h *SubscriptionHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.AuthClaims(r)
+	if claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req autoRenewPatchReq
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	if req.AutoRenew == nil {
+		http.Error(w, "auto_renew is required", http.StatusBadRequest)
+		return
+	}
+	block, err := h.svc.SetAutoRenew(r.Context(), claims.AccountID, *req.AutoRenew)
+	switch {
+	case errors.Is(err, payment.ErrAutoRenewUnavailable):
+		http.Error(w, "auto renew disabled", http.StatusForbidden)
+		return
+	case errors.Is(err, payment.ErrAutoRenewNoMethod):
+		http.Error(w, "no saved payment method", http.StatusConflict)
+		return
+	case err != nil:
+		slog.Error("subscription: auto renew toggle failed", "account_id", claims.AccountID, "error", err.Error())
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]any{"auto_renew": block})
+}
+
 // Deeplink — GET /cabinet/api/me/deeplink?app=happ|incy.
 //
 // Возвращает зашифрованный deep link ({"deeplink": "..."}), которым фронт
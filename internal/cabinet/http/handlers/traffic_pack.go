@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"remnawave-tg-shop-bot/internal/cabinet/http/middleware"
+	cabmetrics "remnawave-tg-shop-bot/internal/cabinet/metrics"
+	"remnawave-tg-shop-bot/internal/cabinet/payments"
+)
+
+type trafficPackCheckoutReq struct {
+	TrafficGB int    `json:"traffic_gb"`
+	Provider  string `json:"provider"`
+}
+
+// TrafficPacks — GET /cabinet/api/me/traffic-packs — пакеты трафика тарифа и расход за период.
+func (h *PaymentsHandler) TrafficPacks(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.AuthClaims(r)
+	if claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	result, err := h.svc.TrafficPacks(r.Context(), claims.AccountID)
+	if err != nil {
+		writePaymentsErr(w, err, "traffic_packs")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// TrafficPackCheckout — POST /cabinet/api/me/traffic-packs/checkout {traffic_gb, provider} + Idempotency-Key.
+// Ответ как у /payments/checkout: статус оплаты поллится через /payments/{id}/status.
+func (h *PaymentsHandler) TrafficPackCheckout(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.AuthClaims(r)
+	if claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req trafficPackCheckoutReq
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	result, err := h.svc.CheckoutTrafficPack(r.Context(), claims.AccountID, payments.TrafficPackCreateRequest{
+		TrafficGB:      req.TrafficGB,
+		Provider:       req.Provider,
+		IdempotencyKey: strings.TrimSpace(r.Header.Get("Idempotency-Key")),
+	})
+	if err != nil {
+		writePaymentsErr(w, err, "traffic_pack_checkout")
+		return
+	}
+	status := http.StatusCreated
+	if result.Reused {
+		status = http.StatusOK
+	} else {
+		cabmetrics.RecordCheckoutStarted(result.Provider)
+	}
+	writeJSON(w, status, result)
+}
@@ -6,12 +6,15 @@ import (
 
 	adminauth "remnawave-tg-shop-bot/internal/cabinet/admin/auth"
 	"remnawave-tg-shop-bot/internal/cabinet/auth/jwt"
+	"remnawave-tg-shop-bot/internal/staff"
 )
 
 // RequireAdmin — middleware поверх RequireAuth. Отклоняет 403, если аккаунт не
-// является администратором (по привязанному Telegram == ADMIN_TELEGRAM_ID).
-// Результат кешируется в контексте запроса, чтобы не дёргать БД повторно.
-func RequireAdmin(checker *adminauth.Checker) func(http.Handler) http.Handler {
+// является сотрудником (по привязанному Telegram) или у него нет всех прав perms.
+// Для GET/HEAD право users_write ослабляется до users_read: на одном маршруте
+// карточка пользователя читается и изменяется.
+// Сотрудник кешируется в контексте запроса, чтобы не дёргать БД повторно.
+func RequireAdmin(checker *adminauth.Checker, perms ...staff.Permission) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			claims := AuthClaims(r)
@@ -19,17 +22,45 @@ func RequireAdmin(checker *adminauth.Checker) func(http.Handler) http.Handler {
 				http.Error(w, "unauthorized", http.StatusUnauthorized)
 				return
 			}
-			if !checker.IsAdmin(r.Context(), claims.AccountID) {
+			member, ok := checker.Member(r.Context(), claims.AccountID)
+			if !ok {
 				http.Error(w, "forbidden", http.StatusForbidden)
 				return
 			}
+			safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+			for _, perm := range perms {
+				if safe && perm == staff.PermUsersWrite {
+					perm = staff.PermUsersRead
+				}
+				if !member.Has(perm) {
+					http.Error(w, "forbidden", http.StatusForbidden)
+					return
+				}
+			}
 			ctx := context.WithValue(r.Context(), ctxKeyIsAdmin, true)
+			ctx = context.WithValue(ctx, ctxKeyStaffMember, member)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-const ctxKeyIsAdmin ctxKey = "is_admin"
+// RequireOwner — RequireAdmin только для владельца (управление сотрудниками).
+func RequireOwner(checker *adminauth.Checker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return RequireAdmin(checker)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if m, ok := StaffFromContext(r.Context()); !ok || m.Role != staff.RoleOwner {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
+
+const (
+	ctxKeyIsAdmin     ctxKey = "is_admin"
+	ctxKeyStaffMember ctxKey = "staff_member"
+)
 
 // IsAdminFromContext возвращает true, если RequireAdmin уже подтвердил админа.
 func IsAdminFromContext(ctx context.Context) bool {
@@ -37,6 +68,12 @@ func IsAdminFromContext(ctx context.Context) bool {
 	return v
 }
 
+// StaffFromContext возвращает сотрудника, подтверждённого RequireAdmin.
+func StaffFromContext(ctx context.Context) (staff.Member, bool) {
+	m, ok := ctx.Value(ctxKeyStaffMember).(staff.Member)
+	return m, ok
+}
+
 // ResolveIsAdmin — хелпер для /me: проверяет admin-статус по claims без middleware.
 func ResolveIsAdmin(ctx context.Context, checker *adminauth.Checker, claims *jwt.Claims) bool {
 	if checker == nil || claims == nil {
@@ -45,6 +45,7 @@ import (
 	botpayment "remnawave-tg-shop-bot/internal/payment"
 	"remnawave-tg-shop-bot/internal/promo"
 	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/internal/staff"
 	"remnawave-tg-shop-bot/internal/sync"
 )
 
@@ -100,6 +101,9 @@ func Mount(ctx context.Context, mux *http.ServeMux, pool *pgxpool.Pool, paymentS
 	purchaseRepo := database.NewPurchaseRepository(pool)
 	fortRepo := repository.NewFortuneRepo(pool)
 	subscriptionSvc := cabsvc.NewSubscription(customerRepo, tariffRepo, loyaltyRepo, linkRepo, customerBootstrap, rw, purchaseRepo, fortRepo)
+	if paymentService != nil {
+		subscriptionSvc.SetAutoRenewManager(paymentService)
+	}
 
 	// SMTP + mailer. Если SMTP не настроен — DryRun, чтобы сервис работал в dev.
 	mailerSender := mail.NewSender(mail.Config{
@@ -220,6 +224,7 @@ func Mount(ctx context.Context, mux *http.ServeMux, pool *pgxpool.Pool, paymentS
 		tgWidgetBot,
 		cabcfg.TelegramOIDCEnabled(),
 		cabcfg.TelegramWebAuthMode(),
+		paymentService,
 	)
 	adminChecker := adminauth.NewChecker(identityRepo)
 
@@ -229,7 +234,8 @@ func Mount(ctx context.Context, mux *http.ServeMux, pool *pgxpool.Pool, paymentS
 	tariffsHandler := handlers.NewTariffs(catalogSvc)
 	subscriptionHandler := handlers.NewSubscription(subscriptionSvc)
 
-	activityHandler := handlers.NewCabinetActivity(linkRepo, identityRepo, customerRepo, referralRepo, purchaseRepo, cabcfg.PublicURL())
+	activityHandler := handlers.NewCabinetActivity(linkRepo, identityRepo, customerRepo, referralRepo, purchaseRepo,
+		database.NewCustomerNotificationRepository(pool), cabcfg.PublicURL())
 
 	promoRepo := database.NewPromoRepository(pool)
 	fortuneSvc := cabsvc.NewFortuneService(pool, linkRepo, customerBootstrap, customerRepo, purchaseRepo, promoRepo, fortRepo, rw)
@@ -343,10 +349,10 @@ func Mount(ctx context.Context, mux *http.ServeMux, pool *pgxpool.Pool, paymentS
 
 	adminStatsHandler := handlers.NewAdminStats(statsRepo, loyaltyRepo, customerRepo, promoRepo)
 	adminUsersHandler := handlers.NewAdminUsers(customerRepo, purchaseRepo, referralRepo, tariffRepo, loyaltyRepo, rw)
-	adminPromosHandler := handlers.NewAdminPromos(promoRepo)
+	adminPromosHandler := handlers.NewAdminPromos(promoRepo, promoService)
 	adminTariffsHandler := handlers.NewAdminTariffs(tariffRepo)
 	adminLoyaltyHandler := handlers.NewAdminLoyalty(loyaltyRepo, customerRepo, purchaseRepo)
-	adminBroadcastHandler := handlers.NewAdminBroadcast(customerRepo, tariffRepo, broadcastSender, tgBot)
+	adminBroadcastHandler := handlers.NewAdminBroadcast(customerRepo, tariffRepo, database.NewCustomerSegmentRepository(pool), broadcastSender, tgBot)
 	adminInfraHandler := handlers.NewAdminInfra(rw, infraBillingRepo)
 	adminSettingsHandler := handlers.NewAdminSettings(runtimeSettingsRepo)
 	adminSquadsHandler := handlers.NewAdminSquads(rw)
@@ -354,12 +360,34 @@ func Mount(ctx context.Context, mux *http.ServeMux, pool *pgxpool.Pool, paymentS
 	if syncService != nil {
 		adminSyncHandler = handlers.NewAdminSync(syncService)
 	}
+	var adminRefundsHandler *handlers.AdminRefundsHandler
+	var adminPartnersHandler *handlers.AdminPartnersHandler
+	var adminMoynalogHandler *handlers.AdminMoynalogHandler
+	if paymentService != nil {
+		adminRefundsHandler = handlers.NewAdminRefunds(paymentService, database.NewPurchaseRefundRepository(pool))
+		adminPartnersHandler = handlers.NewAdminPartners(paymentService)
+		adminMoynalogHandler = handlers.NewAdminMoynalog(paymentService)
+		adminUsersHandler.SetBalanceManager(paymentService)
+	}
+	adminGiftsHandler := handlers.NewAdminGifts(database.NewGiftRepository(pool))
+	adminStaffHandler := handlers.NewAdminStaff()
+	adminAuditHandler := handlers.NewAdminAudit(database.NewAdminAuditRepository(pool))
+	adminUsersHandler.SetAuditLog(adminAuditHandler)
 
 	registerAPIRoutes(api, authHandler, contentHandler, meHandler, tariffsHandler, subscriptionHandler, activityHandler, promoCodesHandler, oauthHandler, paymentsHandler, linkHandler, fortuneHandler, supportHandler, jwtIssuer,
-		adminChecker, adminBootstrapHandler, adminStatsHandler, adminUsersHandler, adminPromosHandler, adminTariffsHandler, adminLoyaltyHandler, adminBroadcastHandler, adminInfraHandler, adminSettingsHandler, adminSquadsHandler, adminSyncHandler, adminAcctLim,
+		adminChecker, adminBootstrapHandler, adminStatsHandler, adminUsersHandler, adminPromosHandler, adminTariffsHandler, adminLoyaltyHandler, adminBroadcastHandler, adminInfraHandler, adminSettingsHandler, adminSquadsHandler, adminSyncHandler, adminRefundsHandler, adminGiftsHandler, adminPartnersHandler, adminMoynalogHandler, adminStaffHandler, adminAuditHandler, adminAcctLim,
 		loginIPLim, loginEmailLim, registerIPLim, forgotEmailLim, resendVerifyAcctLim, verifyEmailConfirmIPLim, verifyResendPublicIPLim, paymentsAcctLim, subscriptionAcctLim, deleteAcctLim, trialActivateAcctLim, supportAcctLim, supportWebhookIPLim,
 		oauthIPLim, telegramIPLim, linkAcctLim)
 
+	// GET/POST /email/unsubscribe?token= — отписка от рассылок по ссылке из письма. Без JWT и CSRF:
+	// доступ даёт подписанный токен, POST без cookie шлёт почтовый клиент (List-Unsubscribe-Post).
+	api.Handle(mail.UnsubscribePath,
+		middleware.Chain(
+			handlers.EmailUnsubscribe(accountRepo, cabcfg.EmailUnsubscribeSecret()),
+			middleware.RateLimit(verifyEmailConfirmIPLim, ipKey("email_unsubscribe")),
+		),
+	)
+
 	// 404 JSON на любой неизвестный /cabinet/api/*.
 	api.HandleFunc("/cabinet/api/", func(w http.ResponseWriter, _ *http.Request) {
 		w.Header().Set("Content-Type", "application/json; charset=utf-8")
@@ -452,6 +480,12 @@ func registerAPIRoutes(
 	adminSettings *handlers.AdminSettingsHandler,
 	adminSquads *handlers.AdminSquadsHandler,
 	adminSync *handlers.AdminSyncHandler,
+	adminRefunds *handlers.AdminRefundsHandler,
+	adminGifts *handlers.AdminGiftsHandler,
+	adminPartners *handlers.AdminPartnersHandler,
+	adminMoynalog *handlers.AdminMoynalogHandler,
+	adminStaff *handlers.AdminStaffHandler,
+	adminAudit *handlers.AdminAuditHandler,
 	adminAcctLim,
 	loginIPLim, loginEmailLim, registerIPLim, forgotEmailLim, resendVerifyAcctLim, verifyEmailConfirmIPLim, verifyResendPublicIPLim, paymentsAcctLim, subscriptionAcctLim, deleteAcctLim, trialActivateAcctLim, supportAcctLim, supportWebhookIPLim,
 	oauthIPLim, telegramIPLim, linkAcctLim *ratelimit.Limiter,
@@ -851,6 +885,14 @@ func registerAPIRoutes(
 				middleware.RequireVerifiedEmail(),
 				middleware.RateLimit(subscriptionAcctLim, accountKey("subscription")),
 			),
+			// PATCH — переключатель автопродления ({"auto_renew": bool}).
+			http.MethodPatch: middleware.Chain(
+				http.HandlerFunc(subscription.Patch),
+				middleware.RequireAuth(jwtIssuer),
+				middleware.RequireVerifiedEmail(),
+				middleware.CSRF(),
+				middleware.RateLimit(subscriptionAcctLim, accountKey("subscription-patch")),
+			),
 		}),
 	)
 	// GET /me/deeplink?app=happ|incy — зашифрованный deep link подключения.
@@ -939,6 +981,24 @@ func registerAPIRoutes(
 				),
 			}),
 		)
+		// Лента уведомлений по событиям панели (для клиентов без Telegram-чата).
+		api.Handle("/cabinet/api/me/notifications",
+			methodRouter(map[string]http.Handler{
+				http.MethodGet: middleware.Chain(
+					http.HandlerFunc(activity.GetNotifications),
+					middleware.RequireAuth(jwtIssuer),
+					middleware.RateLimit(subscriptionAcctLim, accountKey("notifications")),
+				),
+			}),
+		)
+		api.Handle("/cabinet/api/me/notifications/read",
+			onlyPOST(middleware.Chain(
+				http.HandlerFunc(activity.MarkNotificationsRead),
+				middleware.RequireAuth(jwtIssuer),
+				middleware.CSRF(),
+				middleware.RateLimit(subscriptionAcctLim, accountKey("notifications")),
+			)),
+		)
 	}
 
 	if support != nil {
@@ -1048,6 +1108,137 @@ func registerAPIRoutes(
 			)),
 		)
 
+		// Внутренний баланс: остаток/журнал и счёт на пополнение (тот же лимит, что у checkout).
+		api.Handle("/cabinet/api/me/balance",
+			methodRouter(map[string]http.Handler{
+				http.MethodGet: middleware.Chain(
+					http.HandlerFunc(pay.Balance),
+					middleware.RequireAuth(jwtIssuer),
+					middleware.RateLimit(subscriptionAcctLim, accountKey("balance")),
+				),
+			}),
+		)
+		api.Handle("/cabinet/api/payments/balance/topup",
+			onlyPOST(middleware.Chain(
+				http.HandlerFunc(pay.BalanceTopUp),
+				middleware.RequireAuth(jwtIssuer),
+				middleware.CSRF(),
+				middleware.RateLimit(paymentsAcctLim, accountKey("payments")),
+			)),
+		)
+
+		// Партнёрская программа: сводка с начислениями и запрос на выплату.
+		api.Handle("/cabinet/api/me/partner",
+			methodRouter(map[string]http.Handler{
+				http.MethodGet: middleware.Chain(
+					http.HandlerFunc(pay.Partner),
+					middleware.RequireAuth(jwtIssuer),
+					middleware.RateLimit(subscriptionAcctLim, accountKey("partner")),
+				),
+			}),
+		)
+		api.Handle("/cabinet/api/me/partner/payouts",
+			onlyPOST(middleware.Chain(
+				http.HandlerFunc(pay.PartnerPayout),
+				middleware.RequireAuth(jwtIssuer),
+				middleware.RequireVerifiedEmail(),
+				middleware.CSRF(),
+				middleware.RateLimit(paymentsAcctLim, accountKey("partner_payout")),
+			)),
+		)
+
+		// Семейный тариф: сводка, новая ссылка-приглашение, вступление и выход, управление участниками.
+		api.Handle("/cabinet/api/me/family",
+			methodRouter(map[string]http.Handler{
+				http.MethodGet: middleware.Chain(
+					http.HandlerFunc(pay.Family),
+					middleware.RequireAuth(jwtIssuer),
+					middleware.RateLimit(subscriptionAcctLim, accountKey("family")),
+				),
+			}),
+		)
+		api.Handle("/cabinet/api/me/family/invite",
+			onlyPOST(middleware.Chain(
+				http.HandlerFunc(pay.FamilyInvite),
+				middleware.RequireAuth(jwtIssuer),
+				middleware.CSRF(),
+				middleware.RateLimit(paymentsAcctLim, accountKey("family_write")),
+			)),
+		)
+		api.Handle("/cabinet/api/me/family/join",
+			onlyPOST(middleware.Chain(
+				http.HandlerFunc(pay.FamilyJoin),
+				middleware.RequireAuth(jwtIssuer),
+				middleware.RequireVerifiedEmail(),
+				middleware.CSRF(),
+				middleware.RateLimit(paymentsAcctLim, accountKey("family_write")),
+			)),
+		)
+		api.Handle("/cabinet/api/me/family/leave",
+			onlyPOST(middleware.Chain(
+				http.HandlerFunc(pay.FamilyLeave),
+				middleware.RequireAuth(jwtIssuer),
+				middleware.CSRF(),
+				middleware.RateLimit(paymentsAcctLim, accountKey("family_write")),
+			)),
+		)
+		familyMember := middleware.Chain(
+			http.HandlerFunc(pay.FamilyMember),
+			middleware.RequireAuth(jwtIssuer),
+			middleware.CSRF(),
+			middleware.RateLimit(paymentsAcctLim, accountKey("family_write")),
+		)
+		api.Handle("/cabinet/api/me/family/members/{customer_id}",
+			methodRouter(map[string]http.Handler{
+				http.MethodPatch:  familyMember,
+				http.MethodDelete: familyMember,
+			}),
+		)
+
+		// Пауза подписки: статус и лимиты, постановка на паузу, досрочное возобновление.
+		api.Handle("/cabinet/api/me/subscription/pause",
+			methodRouter(map[string]http.Handler{
+				http.MethodGet: middleware.Chain(
+					http.HandlerFunc(pay.SubscriptionPause),
+					middleware.RequireAuth(jwtIssuer),
+					middleware.RateLimit(subscriptionAcctLim, accountKey("subscription_pause")),
+				),
+				http.MethodPost: middleware.Chain(
+					http.HandlerFunc(pay.SubscriptionPauseStart),
+					middleware.RequireAuth(jwtIssuer),
+					middleware.CSRF(),
+					middleware.RateLimit(paymentsAcctLim, accountKey("subscription_pause_write")),
+				),
+			}),
+		)
+		api.Handle("/cabinet/api/me/subscription/resume",
+			onlyPOST(middleware.Chain(
+				http.HandlerFunc(pay.SubscriptionPauseResume),
+				middleware.RequireAuth(jwtIssuer),
+				middleware.CSRF(),
+				middleware.RateLimit(paymentsAcctLim, accountKey("subscription_pause_write")),
+			)),
+		)
+
+		// Пакеты трафика: предложение по тарифу клиента и счёт на пакет.
+		api.Handle("/cabinet/api/me/traffic-packs",
+			methodRouter(map[string]http.Handler{
+				http.MethodGet: middleware.Chain(
+					http.HandlerFunc(pay.TrafficPacks),
+					middleware.RequireAuth(jwtIssuer),
+					middleware.RateLimit(subscriptionAcctLim, accountKey("traffic_packs")),
+				),
+			}),
+		)
+		api.Handle("/cabinet/api/me/traffic-packs/checkout",
+			onlyPOST(middleware.Chain(
+				http.HandlerFunc(pay.TrafficPackCheckout),
+				middleware.RequireAuth(jwtIssuer),
+				middleware.CSRF(),
+				middleware.RateLimit(paymentsAcctLim, accountKey("payments")),
+			)),
+		)
+
 		// GET /payments/{id}/status. Префиксный маршрут на ServeMux — сам хендлер
 		// разбирает :id из пути. Без CSRF (идемпотентный GET), но тот же 20/min/account.
 		api.Handle("/cabinet/api/payments/",
@@ -1132,7 +1323,7 @@ func registerAPIRoutes(
 			http.MethodGet: middleware.Chain(
 				http.HandlerFunc(adminStats.Stats),
 				middleware.RequireAuth(jwtIssuer),
-				middleware.RequireAdmin(adminChecker),
+				middleware.RequireAdmin(adminChecker, staff.PermStats),
 				middleware.RateLimit(adminAcctLim, accountKey("admin_stats")),
 			),
 		}),
@@ -1142,7 +1333,7 @@ func registerAPIRoutes(
 			http.MethodGet: middleware.Chain(
 				http.HandlerFunc(adminStats.TimeSeries),
 				middleware.RequireAuth(jwtIssuer),
-				middleware.RequireAdmin(adminChecker),
+				middleware.RequireAdmin(adminChecker, staff.PermStats),
 				middleware.RateLimit(adminAcctLim, accountKey("admin_stats_timeseries")),
 			),
 		}),
@@ -1152,7 +1343,7 @@ func registerAPIRoutes(
 			http.MethodGet: middleware.Chain(
 				http.HandlerFunc(adminStats.FortuneStats),
 				middleware.RequireAuth(jwtIssuer),
-				middleware.RequireAdmin(adminChecker),
+				middleware.RequireAdmin(adminChecker, staff.PermStats),
 				middleware.RateLimit(adminAcctLim, accountKey("admin_stats_fortune")),
 			),
 		}),
@@ -1162,17 +1353,27 @@ func registerAPIRoutes(
 			http.MethodGet: middleware.Chain(
 				http.HandlerFunc(adminStats.LoyaltyStats),
 				middleware.RequireAuth(jwtIssuer),
-				middleware.RequireAdmin(adminChecker),
+				middleware.RequireAdmin(adminChecker, staff.PermStats),
 				middleware.RateLimit(adminAcctLim, accountKey("admin_stats_loyalty")),
 			),
 		}),
 	)
+	api.Handle("/cabinet/api/admin/stats/sources",
+		methodRouter(map[string]http.Handler{
+			http.MethodGet: middleware.Chain(
+				http.HandlerFunc(adminStats.AcquisitionStats),
+				middleware.RequireAuth(jwtIssuer),
+				middleware.RequireAdmin(adminChecker, staff.PermStats),
+				middleware.RateLimit(adminAcctLim, accountKey("admin_stats_sources")),
+			),
+		}),
+	)
 	api.Handle("/cabinet/api/admin/stats/promos",
 		methodRouter(map[string]http.Handler{
 			http.MethodGet: middleware.Chain(
 				http.HandlerFunc(adminStats.PromoStats),
 				middleware.RequireAuth(jwtIssuer),
-				middleware.RequireAdmin(adminChecker),
+				middleware.RequireAdmin(adminChecker, staff.PermStats),
 				middleware.RateLimit(adminAcctLim, accountKey("admin_stats_promos")),
 			),
 		}),
@@ -1184,7 +1385,7 @@ func registerAPIRoutes(
 			http.MethodGet: middleware.Chain(
 				http.HandlerFunc(adminUsers.List),
 				middleware.RequireAuth(jwtIssuer),
-				middleware.RequireAdmin(adminChecker),
+				middleware.RequireAdmin(adminChecker, staff.PermUsersRead),
 				middleware.RateLimit(adminAcctLim, accountKey("admin_users")),
 			),
 		}),
@@ -1194,7 +1395,7 @@ func registerAPIRoutes(
 			http.MethodGet: middleware.Chain(
 				http.HandlerFunc(adminUsers.Search),
 				middleware.RequireAuth(jwtIssuer),
-				middleware.RequireAdmin(adminChecker),
+				middleware.RequireAdmin(adminChecker, staff.PermUsersRead),
 				middleware.RateLimit(adminAcctLim, accountKey("admin_users_search")),
 			),
 		}),
@@ -1203,7 +1404,7 @@ func registerAPIRoutes(
 		middleware.Chain(
 			http.HandlerFunc(adminUsers.HandleByID),
 			middleware.RequireAuth(jwtIssuer),
-			middleware.RequireAdmin(adminChecker),
+			middleware.RequireAdmin(adminChecker, staff.PermUsersWrite),
 			middleware.CSRF(),
 			middleware.RateLimit(adminAcctLim, accountKey("admin_users_byid")),
 		),
@@ -1214,7 +1415,7 @@ func registerAPIRoutes(
 		middleware.Chain(
 			http.HandlerFunc(adminPromos.Handle),
 			middleware.RequireAuth(jwtIssuer),
-			middleware.RequireAdmin(adminChecker),
+			middleware.RequireAdmin(adminChecker, staff.PermPromos),
 			middleware.CSRF(),
 			middleware.RateLimit(adminAcctLim, accountKey("admin_promos")),
 		),
@@ -1223,18 +1424,38 @@ func registerAPIRoutes(
 		middleware.Chain(
 			http.HandlerFunc(adminPromos.HandleByID),
 			middleware.RequireAuth(jwtIssuer),
-			middleware.RequireAdmin(adminChecker),
+			middleware.RequireAdmin(adminChecker, staff.PermPromos),
 			middleware.CSRF(),
 			middleware.RateLimit(adminAcctLim, accountKey("admin_promos_byid")),
 		),
 	)
 
+	// Admin Promo batches (пакеты одноразовых кодов)
+	api.Handle("/cabinet/api/admin/promo-batches",
+		middleware.Chain(
+			http.HandlerFunc(adminPromos.HandleBatches),
+			middleware.RequireAuth(jwtIssuer),
+			middleware.RequireAdmin(adminChecker, staff.PermPromos),
+			middleware.CSRF(),
+			middleware.RateLimit(adminAcctLim, accountKey("admin_promo_batches")),
+		),
+	)
+	api.Handle("/cabinet/api/admin/promo-batches/",
+		middleware.Chain(
+			http.HandlerFunc(adminPromos.HandleBatchByID),
+			middleware.RequireAuth(jwtIssuer),
+			middleware.RequireAdmin(adminChecker, staff.PermPromos),
+			middleware.CSRF(),
+			middleware.RateLimit(adminAcctLim, accountKey("admin_promo_batches_byid")),
+		),
+	)
+
 	// Admin Tariffs
 	api.Handle("/cabinet/api/admin/tariffs",
 		middleware.Chain(
 			http.HandlerFunc(adminTariffs.Handle),
 			middleware.RequireAuth(jwtIssuer),
-			middleware.RequireAdmin(adminChecker),
+			middleware.RequireAdmin(adminChecker, staff.PermTariffs),
 			middleware.CSRF(),
 			middleware.RateLimit(adminAcctLim, accountKey("admin_tariffs")),
 		),
@@ -1243,7 +1464,7 @@ func registerAPIRoutes(
 		middleware.Chain(
 			http.HandlerFunc(adminTariffs.HandleByID),
 			middleware.RequireAuth(jwtIssuer),
-			middleware.RequireAdmin(adminChecker),
+			middleware.RequireAdmin(adminChecker, staff.PermTariffs),
 			middleware.CSRF(),
 			middleware.RateLimit(adminAcctLim, accountKey("admin_tariffs_byid")),
 		),
@@ -1254,7 +1475,7 @@ func registerAPIRoutes(
 		middleware.Chain(
 			http.HandlerFunc(adminLoyalty.Handle),
 			middleware.RequireAuth(jwtIssuer),
-			middleware.RequireAdmin(adminChecker),
+			middleware.RequireAdmin(adminChecker, staff.PermPromos),
 			middleware.CSRF(),
 			middleware.RateLimit(adminAcctLim, accountKey("admin_loyalty")),
 		),
@@ -1263,7 +1484,7 @@ func registerAPIRoutes(
 		middleware.Chain(
 			http.HandlerFunc(adminLoyalty.HandleByID),
 			middleware.RequireAuth(jwtIssuer),
-			middleware.RequireAdmin(adminChecker),
+			middleware.RequireAdmin(adminChecker, staff.PermPromos),
 			middleware.CSRF(),
 			middleware.RateLimit(adminAcctLim, accountKey("admin_loyalty_byid")),
 		),
@@ -1272,7 +1493,7 @@ func registerAPIRoutes(
 		onlyPOST(middleware.Chain(
 			http.HandlerFunc(adminLoyalty.TriggerRecalc),
 			middleware.RequireAuth(jwtIssuer),
-			middleware.RequireAdmin(adminChecker),
+			middleware.RequireAdmin(adminChecker, staff.PermPromos),
 			middleware.CSRF(),
 			middleware.RateLimit(adminAcctLim, accountKey("admin_loyalty_recalc")),
 		)),
@@ -1284,7 +1505,7 @@ func registerAPIRoutes(
 			http.MethodGet: middleware.Chain(
 				http.HandlerFunc(adminBroadcast.Audiences),
 				middleware.RequireAuth(jwtIssuer),
-				middleware.RequireAdmin(adminChecker),
+				middleware.RequireAdmin(adminChecker, staff.PermBroadcasts),
 				middleware.RateLimit(adminAcctLim, accountKey("admin_broadcast_audiences")),
 			),
 		}),
@@ -1293,7 +1514,7 @@ func registerAPIRoutes(
 		onlyPOST(middleware.Chain(
 			http.HandlerFunc(adminBroadcast.Preview),
 			middleware.RequireAuth(jwtIssuer),
-			middleware.RequireAdmin(adminChecker),
+			middleware.RequireAdmin(adminChecker, staff.PermBroadcasts),
 			middleware.CSRF(),
 			middleware.RateLimit(adminAcctLim, accountKey("admin_broadcast_preview")),
 		)),
@@ -1303,7 +1524,7 @@ func registerAPIRoutes(
 			http.MethodGet: middleware.Chain(
 				http.HandlerFunc(adminBroadcast.Tariffs),
 				middleware.RequireAuth(jwtIssuer),
-				middleware.RequireAdmin(adminChecker),
+				middleware.RequireAdmin(adminChecker, staff.PermBroadcasts),
 				middleware.RateLimit(adminAcctLim, accountKey("admin_broadcast_tariffs")),
 			),
 		}),
@@ -1312,7 +1533,7 @@ func registerAPIRoutes(
 		onlyPOST(middleware.Chain(
 			http.HandlerFunc(adminBroadcast.Send),
 			middleware.RequireAuth(jwtIssuer),
-			middleware.RequireAdmin(adminChecker),
+			middleware.RequireAdmin(adminChecker, staff.PermBroadcasts),
 			middleware.CSRF(),
 			middleware.RateLimit(adminAcctLim, accountKey("admin_broadcast_send")),
 		)),
@@ -1321,18 +1542,55 @@ func registerAPIRoutes(
 		onlyPOST(middleware.Chain(
 			http.HandlerFunc(adminBroadcast.UploadMedia),
 			middleware.RequireAuth(jwtIssuer),
-			middleware.RequireAdmin(adminChecker),
+			middleware.RequireAdmin(adminChecker, staff.PermBroadcasts),
 			middleware.CSRF(),
 			middleware.RateLimit(adminAcctLim, accountKey("admin_broadcast_upload")),
 		)),
 	)
+	api.Handle("/cabinet/api/admin/broadcast/jobs",
+		methodRouter(map[string]http.Handler{
+			http.MethodGet: middleware.Chain(
+				http.HandlerFunc(adminBroadcast.Jobs),
+				middleware.RequireAuth(jwtIssuer),
+				middleware.RequireAdmin(adminChecker, staff.PermBroadcasts),
+				middleware.RateLimit(adminAcctLim, accountKey("admin_broadcast_jobs")),
+			),
+		}),
+	)
+	api.Handle("/cabinet/api/admin/broadcast/jobs/",
+		middleware.Chain(
+			http.HandlerFunc(adminBroadcast.JobByID),
+			middleware.RequireAuth(jwtIssuer),
+			middleware.RequireAdmin(adminChecker, staff.PermBroadcasts),
+			middleware.CSRF(),
+			middleware.RateLimit(adminAcctLim, accountKey("admin_broadcast_jobs_byid")),
+		),
+	)
+	api.Handle("/cabinet/api/admin/segments",
+		middleware.Chain(
+			http.HandlerFunc(adminBroadcast.Segments),
+			middleware.RequireAuth(jwtIssuer),
+			middleware.RequireAdmin(adminChecker, staff.PermBroadcasts),
+			middleware.CSRF(),
+			middleware.RateLimit(adminAcctLim, accountKey("admin_segments")),
+		),
+	)
+	api.Handle("/cabinet/api/admin/segments/",
+		middleware.Chain(
+			http.HandlerFunc(adminBroadcast.SegmentByID),
+			middleware.RequireAuth(jwtIssuer),
+			middleware.RequireAdmin(adminChecker, staff.PermBroadcasts),
+			middleware.CSRF(),
+			middleware.RateLimit(adminAcctLim, accountKey("admin_segments_byid")),
+		),
+	)
 
 	// Admin Infra
 	api.Handle("/cabinet/api/admin/infra/nodes",
 		middleware.Chain(
 			http.HandlerFunc(adminInfra.HandleNodes),
 			middleware.RequireAuth(jwtIssuer),
-			middleware.RequireAdmin(adminChecker),
+			middleware.RequireAdmin(adminChecker, staff.PermInfra),
 			middleware.CSRF(),
 			middleware.RateLimit(adminAcctLim, accountKey("admin_infra_nodes")),
 		),
@@ -1341,7 +1599,7 @@ func registerAPIRoutes(
 		middleware.Chain(
 			http.HandlerFunc(adminInfra.DeleteNode),
 			middleware.RequireAuth(jwtIssuer),
-			middleware.RequireAdmin(adminChecker),
+			middleware.RequireAdmin(adminChecker, staff.PermInfra),
 			middleware.CSRF(),
 			middleware.RateLimit(adminAcctLim, accountKey("admin_infra_nodes_del")),
 		),
@@ -1350,7 +1608,7 @@ func registerAPIRoutes(
 		middleware.Chain(
 			http.HandlerFunc(adminInfra.HandleProviders),
 			middleware.RequireAuth(jwtIssuer),
-			middleware.RequireAdmin(adminChecker),
+			middleware.RequireAdmin(adminChecker, staff.PermInfra),
 			middleware.CSRF(),
 			middleware.RateLimit(adminAcctLim, accountKey("admin_infra_providers")),
 		),
@@ -1359,7 +1617,7 @@ func registerAPIRoutes(
 		middleware.Chain(
 			http.HandlerFunc(adminInfra.DeleteProvider),
 			middleware.RequireAuth(jwtIssuer),
-			middleware.RequireAdmin(adminChecker),
+			middleware.RequireAdmin(adminChecker, staff.PermInfra),
 			middleware.CSRF(),
 			middleware.RateLimit(adminAcctLim, accountKey("admin_infra_providers_del")),
 		),
@@ -1368,7 +1626,7 @@ func registerAPIRoutes(
 		middleware.Chain(
 			http.HandlerFunc(adminInfra.HandleHistory),
 			middleware.RequireAuth(jwtIssuer),
-			middleware.RequireAdmin(adminChecker),
+			middleware.RequireAdmin(adminChecker, staff.PermInfra),
 			middleware.CSRF(),
 			middleware.RateLimit(adminAcctLim, accountKey("admin_infra_history")),
 		),
@@ -1377,7 +1635,7 @@ func registerAPIRoutes(
 		middleware.Chain(
 			http.HandlerFunc(adminInfra.DeleteHistory),
 			middleware.RequireAuth(jwtIssuer),
-			middleware.RequireAdmin(adminChecker),
+			middleware.RequireAdmin(adminChecker, staff.PermInfra),
 			middleware.CSRF(),
 			middleware.RateLimit(adminAcctLim, accountKey("admin_infra_history_del")),
 		),
@@ -1386,7 +1644,7 @@ func registerAPIRoutes(
 		middleware.Chain(
 			http.HandlerFunc(adminInfra.HandleSettings),
 			middleware.RequireAuth(jwtIssuer),
-			middleware.RequireAdmin(adminChecker),
+			middleware.RequireAdmin(adminChecker, staff.PermInfra),
 			middleware.CSRF(),
 			middleware.RateLimit(adminAcctLim, accountKey("admin_infra_settings")),
 		),
@@ -1396,7 +1654,7 @@ func registerAPIRoutes(
 		middleware.Chain(
 			http.HandlerFunc(adminSettings.Handle),
 			middleware.RequireAuth(jwtIssuer),
-			middleware.RequireAdmin(adminChecker),
+			middleware.RequireAdmin(adminChecker, staff.PermSettings),
 			middleware.CSRF(),
 			middleware.RateLimit(adminAcctLim, accountKey("admin_bot_settings")),
 		),
@@ -1408,12 +1666,144 @@ func registerAPIRoutes(
 			onlyPOST(middleware.Chain(
 				http.HandlerFunc(adminSync.TriggerSync),
 				middleware.RequireAuth(jwtIssuer),
-				middleware.RequireAdmin(adminChecker),
+				middleware.RequireAdmin(adminChecker, staff.PermInfra),
 				middleware.CSRF(),
 				middleware.RateLimit(adminAcctLim, accountKey("admin_sync")),
 			)),
 		)
 	}
+
+	// Admin Refunds — только при наличии PaymentService (как /payments/*).
+	if adminRefunds != nil {
+		api.Handle("/cabinet/api/admin/refunds",
+			methodRouter(map[string]http.Handler{
+				http.MethodGet: middleware.Chain(
+					http.HandlerFunc(adminRefunds.List),
+					middleware.RequireAuth(jwtIssuer),
+					middleware.RequireAdmin(adminChecker, staff.PermPayments),
+					middleware.RateLimit(adminAcctLim, accountKey("admin_refunds")),
+				),
+			}),
+		)
+		api.Handle("/cabinet/api/admin/purchases/",
+			middleware.Chain(
+				http.HandlerFunc(adminRefunds.HandleByID),
+				middleware.RequireAuth(jwtIssuer),
+				middleware.RequireAdmin(adminChecker, staff.PermPayments),
+				middleware.CSRF(),
+				middleware.RateLimit(adminAcctLim, accountKey("admin_refunds_byid")),
+			),
+		)
+	}
+
+	// Admin Partners — очередь выплат и персональные ставки партнёров.
+	if adminPartners != nil {
+		api.Handle("/cabinet/api/admin/partner-payouts",
+			methodRouter(map[string]http.Handler{
+				http.MethodGet: middleware.Chain(
+					http.HandlerFunc(adminPartners.ListPayouts),
+					middleware.RequireAuth(jwtIssuer),
+					middleware.RequireAdmin(adminChecker, staff.PermPayments),
+					middleware.RateLimit(adminAcctLim, accountKey("admin_partner_payouts")),
+				),
+			}),
+		)
+		api.Handle("/cabinet/api/admin/partner-payouts/",
+			middleware.Chain(
+				http.HandlerFunc(adminPartners.PayoutByID),
+				middleware.RequireAuth(jwtIssuer),
+				middleware.RequireAdmin(adminChecker, staff.PermPayments),
+				middleware.CSRF(),
+				middleware.RateLimit(adminAcctLim, accountKey("admin_partner_payouts_byid")),
+			),
+		)
+		api.Handle("/cabinet/api/admin/partners",
+			methodRouter(map[string]http.Handler{
+				http.MethodGet: middleware.Chain(
+					http.HandlerFunc(adminPartners.ListPartners),
+					middleware.RequireAuth(jwtIssuer),
+					middleware.RequireAdmin(adminChecker, staff.PermPayments),
+					middleware.RateLimit(adminAcctLim, accountKey("admin_partners")),
+				),
+			}),
+		)
+		api.Handle("/cabinet/api/admin/partners/",
+			middleware.Chain(
+				http.HandlerFunc(adminPartners.PartnerByID),
+				middleware.RequireAuth(jwtIssuer),
+				middleware.RequireAdmin(adminChecker, staff.PermPayments),
+				middleware.CSRF(),
+				middleware.RateLimit(adminAcctLim, accountKey("admin_partners_byid")),
+			),
+		)
+	}
+
+	// Admin Moynalog — очередь чеков «Мой налог»: ошибки и повтор отправки.
+	if adminMoynalog != nil {
+		api.Handle("/cabinet/api/admin/moynalog-receipts",
+			methodRouter(map[string]http.Handler{
+				http.MethodGet: middleware.Chain(
+					http.HandlerFunc(adminMoynalog.List),
+					middleware.RequireAuth(jwtIssuer),
+					middleware.RequireAdmin(adminChecker, staff.PermPayments),
+					middleware.RateLimit(adminAcctLim, accountKey("admin_moynalog_receipts")),
+				),
+			}),
+		)
+		api.Handle("/cabinet/api/admin/moynalog-receipts/",
+			middleware.Chain(
+				http.HandlerFunc(adminMoynalog.ByID),
+				middleware.RequireAuth(jwtIssuer),
+				middleware.RequireAdmin(adminChecker, staff.PermPayments),
+				middleware.CSRF(),
+				middleware.RateLimit(adminAcctLim, accountKey("admin_moynalog_receipts_byid")),
+			),
+		)
+	}
+
+	// Admin Gifts — выпущенные подарочные подписки (только чтение).
+	api.Handle("/cabinet/api/admin/gifts",
+		methodRouter(map[string]http.Handler{
+			http.MethodGet: middleware.Chain(
+				http.HandlerFunc(adminGifts.List),
+				middleware.RequireAuth(jwtIssuer),
+				middleware.RequireAdmin(adminChecker, staff.PermPayments),
+				middleware.RateLimit(adminAcctLim, accountKey("admin_gifts")),
+			),
+		}),
+	)
+
+	// Admin Audit — журнал действий сотрудников (только чтение; история клиента — /users/{id}/audit).
+	api.Handle("/cabinet/api/admin/audit",
+		methodRouter(map[string]http.Handler{
+			http.MethodGet: middleware.Chain(
+				http.HandlerFunc(adminAudit.List),
+				middleware.RequireAuth(jwtIssuer),
+				middleware.RequireAdmin(adminChecker, staff.PermAudit),
+				middleware.RateLimit(adminAcctLim, accountKey("admin_audit")),
+			),
+		}),
+	)
+
+	// Admin Staff — сотрудники админки и их права (только владелец).
+	api.Handle("/cabinet/api/admin/staff",
+		middleware.Chain(
+			http.HandlerFunc(adminStaff.Handle),
+			middleware.RequireAuth(jwtIssuer),
+			middleware.RequireOwner(adminChecker),
+			middleware.CSRF(),
+			middleware.RateLimit(adminAcctLim, accountKey("admin_staff")),
+		),
+	)
+	api.Handle("/cabinet/api/admin/staff/",
+		middleware.Chain(
+			http.HandlerFunc(adminStaff.HandleByID),
+			middleware.RequireAuth(jwtIssuer),
+			middleware.RequireOwner(adminChecker),
+			middleware.CSRF(),
+			middleware.RateLimit(adminAcctLim, accountKey("admin_staff_byid")),
+		),
+	)
 }
 
 // ============================================================================
@@ -105,12 +105,50 @@ func (m *Mailer) SendEmailMergeCode(ctx context.Context, toEmail, language, code
 	return m.render(ctx, tplName, subject, toEmail, EmailMergeCodeData{Code: code, TTLHuman: ttlHuman})
 }
 
+// NotificationLink — кнопка-ссылка письма уведомления.
+type NotificationLink struct {
+	Label string
+	URL   string
+}
+
+// NotificationData — контекст шаблона notification_*: уведомление клиенту,
+// которому некуда написать в Telegram (web-only), и рассылки.
+type NotificationData struct {
+	Title          string        // тема письма и заголовок; пусто — общий заголовок
+	Body           template.HTML // готовый HTML текста, вызывающий код отвечает за экранирование
+	Links          []NotificationLink
+	UnsubscribeURL string // задан — рекламное письмо: ссылка отписки в подвале и List-Unsubscribe
+}
+
+// SendNotification отправляет письмо-уведомление.
+func (m *Mailer) SendNotification(ctx context.Context, toEmail, language string, data NotificationData) error {
+	if data.Title == "" {
+		data.Title = subjectFor("notification", language)
+	}
+	html, err := m.execute(pickTemplate("notification", language), data)
+	if err != nil {
+		return err
+	}
+	if data.UnsubscribeURL != "" {
+		return m.sender.SendBulk(ctx, toEmail, data.Title, html, "", data.UnsubscribeURL)
+	}
+	return m.sender.Send(ctx, toEmail, data.Title, html, "")
+}
+
 func (m *Mailer) render(ctx context.Context, tplName, subject, toEmail string, data any) error {
+	html, err := m.execute(tplName, data)
+	if err != nil {
+		return err
+	}
+	return m.sender.Send(ctx, toEmail, subject, html, "")
+}
+
+func (m *Mailer) execute(tplName string, data any) (string, error) {
 	var buf bytes.Buffer
 	if err := m.tpls.ExecuteTemplate(&buf, tplName, data); err != nil {
-		return fmt.Errorf("mail: render %s: %w", tplName, err)
+		return "", fmt.Errorf("mail: render %s: %w", tplName, err)
 	}
-	return m.sender.Send(ctx, toEmail, subject, buf.String(), "")
+	return buf.String(), nil
 }
 
 // pickTemplate возвращает имя файла-шаблона для заданной категории + языка.
@@ -158,6 +196,11 @@ func subjectFor(kind, language string) string {
 			return "Merge confirmation code"
 		}
 		return "Код подтверждения объединения аккаунтов"
+	case "notification":
+		if language == "en" {
+			return "Notification"
+		}
+		return "Уведомление"
 	default:
 		return "Cabinet notification"
 	}
@@ -49,6 +49,16 @@ func NewSender(cfg Config) *Sender {
 // Plain-text часть генерируется автоматически (go-mail умеет через WithBody,
 // но для простоты и читабельности шлём только HTML + явный AltBody).
 func (s *Sender) Send(ctx context.Context, toEmail, subject, htmlBody, plainBody string) error {
+	return s.send(ctx, toEmail, subject, htmlBody, plainBody, "")
+}
+
+// SendBulk отправляет рекламное письмо: добавляет заголовки List-Unsubscribe и
+// List-Unsubscribe-Post (RFC 8058), чтобы почтовый клиент показал кнопку «Отписаться».
+func (s *Sender) SendBulk(ctx context.Context, toEmail, subject, htmlBody, plainBody, unsubscribeURL string) error {
+	return s.send(ctx, toEmail, subject, htmlBody, plainBody, unsubscribeURL)
+}
+
+func (s *Sender) send(ctx context.Context, toEmail, subject, htmlBody, plainBody, unsubscribeURL string) error {
 	if s.cfg.DryRun {
 		slog.Info("mail: dry-run",
 			"to", toEmail,
@@ -69,6 +79,10 @@ func (s *Sender) Send(ctx context.Context, toEmail, subject, htmlBody, plainBody
 		return fmt.Errorf("mail: to: %w", err)
 	}
 	msg.Subject(subject)
+	if unsubscribeURL != "" {
+		msg.SetGenHeader(gomail.HeaderListUnsubscribe, "<"+unsubscribeURL+">")
+		msg.SetGenHeader(gomail.HeaderListUnsubscribePost, "List-Unsubscribe=One-Click")
+	}
 	msg.SetBodyString(gomail.TypeTextHTML, htmlBody)
 	if plainBody != "" {
 		msg.AddAlternativeString(gomail.TypeTextPlain, plainBody)
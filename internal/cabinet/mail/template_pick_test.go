@@ -1,6 +1,11 @@
 package mail
 
-import "testing"
+import (
+	"context"
+	"html/template"
+	"strings"
+	"testing"
+)
 
 func TestPickTemplate(t *testing.T) {
 	if pickTemplate("email_verify", "en") != "email_verify_en.html" {
@@ -25,3 +30,22 @@ func TestSubjectFor(t *testing.T) {
 		t.Fatal()
 	}
 }
+
+func TestSendNotificationDryRun(t *testing.T) {
+	m := NewMailer(NewSender(Config{DryRun: true}))
+	err := m.SendNotification(context.Background(), "user@example.com", "en", NotificationData{
+		Body:           template.HTML("Hello<br><b>world</b>"),
+		Links:          []NotificationLink{{Label: "Open", URL: "https://example.com/cabinet"}},
+		UnsubscribeURL: "https://example.com/unsubscribe",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	html, err := m.execute("notification_en.html", NotificationData{Title: "T", Body: "<b>x</b>", UnsubscribeURL: "https://example.com/u"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html, "<b>x</b>") || !strings.Contains(html, "https://example.com/u") {
+		t.Fatal(html)
+	}
+}
@@ -0,0 +1,41 @@
+package mail
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+// UnsubscribePath — публичный эндпоинт отписки от рекламных писем (токен в query ?token=).
+const UnsubscribePath = "/cabinet/api/email/unsubscribe"
+
+// UnsubscribeToken подписывает id аккаунта кабинета для ссылки отписки. Токен бессрочный:
+// ссылка из старого письма должна работать, а отписка — единственное, что он разрешает.
+func UnsubscribeToken(secret []byte, accountID int64) string {
+	id := strconv.FormatInt(accountID, 10)
+	return id + "." + unsubscribeSignature(secret, id)
+}
+
+// ParseUnsubscribeToken проверяет подпись и возвращает id аккаунта.
+func ParseUnsubscribeToken(secret []byte, token string) (int64, bool) {
+	id, sig, ok := strings.Cut(token, ".")
+	if !ok || len(secret) == 0 {
+		return 0, false
+	}
+	accountID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || accountID <= 0 {
+		return 0, false
+	}
+	if !hmac.Equal([]byte(sig), []byte(unsubscribeSignature(secret, id))) {
+		return 0, false
+	}
+	return accountID, true
+}
+
+func unsubscribeSignature(secret []byte, id string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("email-unsubscribe:" + id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
@@ -0,0 +1,26 @@
+package mail
+
+import "testing"
+
+func TestUnsubscribeToken(t *testing.T) {
+	secret := []byte("secret")
+	token := UnsubscribeToken(secret, 42)
+	id, ok := ParseUnsubscribeToken(secret, token)
+	if !ok || id != 42 {
+		t.Fatalf("got %d %v", id, ok)
+	}
+	if _, ok := ParseUnsubscribeToken([]byte("other"), token); ok {
+		t.Fatal("token signed with another secret must be rejected")
+	}
+	if _, ok := ParseUnsubscribeToken(secret, "43"+token[2:]); ok {
+		t.Fatal("token with changed account id must be rejected")
+	}
+	for _, bad := range []string{"", "42", "x.y", "-1." + token[3:]} {
+		if _, ok := ParseUnsubscribeToken(secret, bad); ok {
+			t.Fatalf("%q must be rejected", bad)
+		}
+	}
+	if _, ok := ParseUnsubscribeToken(nil, token); ok {
+		t.Fatal("empty secret must reject tokens")
+	}
+}
@@ -0,0 +1,171 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/cabinet/repository"
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/payment"
+)
+
+// TopUpRequest — POST /cabinet/api/payments/balance/topup.
+type TopUpRequest struct {
+	// Amount — сумма пополнения в рублях (BALANCE_TOPUP_MIN..BALANCE_TOPUP_MAX).
+	Amount         int
+	Provider       string
+	IdempotencyKey string
+}
+
+// BalanceTxItem — строка журнала баланса для кабинета.
+type BalanceTxItem struct {
+	ID           int64     `json:"id"`
+	Type         string    `json:"type"`
+	Amount       float64   `json:"amount"`
+	BalanceAfter float64   `json:"balance_after"`
+	PurchaseID   *int64    `json:"purchase_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// BalanceResult — ответ GET /cabinet/api/me/balance.
+type BalanceResult struct {
+	Enabled  bool            `json:"enabled"`
+	Balance  float64         `json:"balance"`
+	Currency string          `json:"currency"`
+	TopUpMin int             `json:"topup_min"`
+	TopUpMax int             `json:"topup_max"`
+	Presets  []int           `json:"presets"`
+	Items    []BalanceTxItem `json:"items"`
+}
+
+// Balance — остаток и журнал операций клиента, привязанного к аккаунту.
+func (s *CheckoutService) Balance(ctx context.Context, accountID int64, limit, offset int) (*BalanceResult, error) {
+	out := &BalanceResult{
+		Enabled:  config.BalanceEnabled(),
+		Currency: "RUB",
+		TopUpMin: config.BalanceTopUpMin(),
+		TopUpMax: config.BalanceTopUpMax(),
+		Presets:  config.BalanceTopUpPresets(),
+		Items:    []BalanceTxItem{},
+	}
+	if !out.Enabled {
+		return out, nil
+	}
+	customer, err := s.customerForAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if out.Balance, err = s.payments.Balance(ctx, customer.ID); err != nil {
+		return nil, fmt.Errorf("payments: balance: %w", err)
+	}
+	items, err := s.payments.BalanceHistory(ctx, customer.ID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("payments: balance history: %w", err)
+	}
+	for _, t := range items {
+		out.Items = append(out.Items, BalanceTxItem{
+			ID:           t.ID,
+			Type:         string(t.Type),
+			Amount:       t.Amount,
+			BalanceAfter: t.BalanceAfter,
+			PurchaseID:   t.PurchaseID,
+			CreatedAt:    t.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
+// CreateTopUp выставляет счёт на пополнение баланса (как wallet_pay в боте).
+func (s *CheckoutService) CreateTopUp(ctx context.Context, accountID int64, req TopUpRequest) (*CreateResult, error) {
+	if !config.BalanceEnabled() {
+		return nil, fmt.Errorf("%w: balance disabled", ErrInvalidInput)
+	}
+	if err := s.validateHwidIdempotencyKey(req.IdempotencyKey); err != nil {
+		return nil, err
+	}
+	if req.Amount < config.BalanceTopUpMin() || req.Amount > config.BalanceTopUpMax() {
+		return nil, fmt.Errorf("%w: amount must be between %d and %d", ErrInvalidInput, config.BalanceTopUpMin(), config.BalanceTopUpMax())
+	}
+	provider := strings.ToLower(strings.TrimSpace(req.Provider))
+	if provider == "" {
+		provider = repository.CheckoutProviderYookassa
+	}
+	invoiceType, err := s.mapProviderToInvoiceType(provider)
+	if err != nil {
+		return nil, err
+	}
+	if !payment.BalanceTopUpMethodAllowed(invoiceType) {
+		return nil, fmt.Errorf("%w: provider %q cannot top up balance", ErrInvalidInput, provider)
+	}
+	if err := s.ensureProviderEnabled(provider); err != nil {
+		return nil, err
+	}
+
+	if existing, err := s.checkouts.FindByIdempotencyKey(ctx, accountID, req.IdempotencyKey); err == nil {
+		return s.reuseExisting(ctx, existing)
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("payments: find existing: %w", err)
+	}
+
+	customer, err := s.customerForAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	acc, err := s.accounts.FindByID(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("payments: load account: %w", err)
+	}
+
+	checkout, err := s.checkouts.Create(ctx, accountID, req.IdempotencyKey, provider)
+	if err != nil {
+		if errors.Is(err, repository.ErrCheckoutConflict) {
+			existing, ferr := s.checkouts.FindByIdempotencyKey(ctx, accountID, req.IdempotencyKey)
+			if ferr != nil {
+				return nil, fmt.Errorf("payments: read after conflict: %w", ferr)
+			}
+			return s.reuseExisting(ctx, existing)
+		}
+		return nil, fmt.Errorf("payments: create checkout: %w", err)
+	}
+
+	returnURL := s.buildReturnURL(checkout.ID)
+	providerCtx := s.withProviderOverrides(ctx, provider, returnURL, acc)
+	paymentURL, purchaseID, err := s.payments.CreateBalanceTopUp(providerCtx, customer, invoiceType, req.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("payments: create top-up: %w", err)
+	}
+	if err := s.checkouts.AttachPurchase(ctx, checkout.ID, purchaseID, returnURL); err != nil {
+		slog.Error("payments: top-up attach purchase failed",
+			"checkout_id", checkout.ID,
+			"purchase_id", purchaseID,
+			"error", err,
+		)
+	}
+
+	return &CreateResult{
+		CheckoutID: checkout.ID,
+		Provider:   provider,
+		Status:     repository.CheckoutStatusPending,
+		PaymentURL: paymentURL,
+	}, nil
+}
+
+func (s *CheckoutService) customerForAccount(ctx context.Context, accountID int64) (*database.Customer, error) {
+	link, err := s.bootstrap.EnsureForAccount(ctx, accountID, "")
+	if err != nil {
+		return nil, fmt.Errorf("payments: bootstrap customer: %w", err)
+	}
+	customer, err := s.customers.FindById(ctx, link.CustomerID)
+	if err != nil {
+		return nil, fmt.Errorf("payments: load customer: %w", err)
+	}
+	if customer == nil {
+		return nil, fmt.Errorf("payments: customer %d not found after bootstrap", link.CustomerID)
+	}
+	return customer, nil
+}
@@ -52,6 +52,8 @@ var (
 	ErrCheckoutNotFound = errors.New("payments: checkout not found")
 	// ErrForbidden — 403: чужой checkout.
 	ErrForbidden = errors.New("payments: forbidden")
+	// ErrInsufficientBalance — 402: оплата с внутреннего баланса, а средств не хватает.
+	ErrInsufficientBalance = database.ErrBalanceInsufficient
 )
 
 // supportedMonths — те же значения, что в витрине тарифов. Храним дубликат
@@ -148,6 +150,8 @@ type CreateRequest struct {
 	IdempotencyKey string
 	// RenewExtraHwid — если true, при наличии активных extra_hwid добавляем их продление к счёту (как в Telegram-боте).
 	RenewExtraHwid bool
+	// Currency — валюта счёта (USD, EUR, …); пусто — валюта языка клиента или валюта провайдера по умолчанию.
+	Currency string
 }
 
 // CreateResult — ответ для POST /cabinet/api/payments/checkout.
@@ -177,19 +181,25 @@ type StatusResult struct {
 
 // PreviewResult — ответ GET /payments/preview: сумма с учётом upgrade/downgrade (как у бота).
 type PreviewResult struct {
-	Amount           int    `json:"amount"`
-	Currency         string `json:"currency"`
-	AmountRub        int    `json:"amount_rub"`
-	SalesMode        string `json:"sales_mode"`
-	Scenario         string `json:"scenario"` // new | renew | upgrade | downgrade | classic_new | classic_renew
-	PurchaseKind     string `json:"purchase_kind,omitempty"`
-	IsEarlyDowngrade bool   `json:"is_early_downgrade,omitempty"`
-	ListPriceRub     int    `json:"list_price_rub,omitempty"` // полная цена прайса за период (для сравнения в UI)
+	// Amount — к оплате в Currency (рубли, Stars или валюта из PRICE_CURRENCIES); остальные *_rub — в рублях.
+	Amount           float64 `json:"amount"`
+	Currency         string  `json:"currency"`
+	AmountRub        int     `json:"amount_rub"`
+	SalesMode        string  `json:"sales_mode"`
+	Scenario         string  `json:"scenario"` // new | renew | upgrade | downgrade | classic_new | classic_renew
+	PurchaseKind     string  `json:"purchase_kind,omitempty"`
+	IsEarlyDowngrade bool    `json:"is_early_downgrade,omitempty"`
+	ListPriceRub     int     `json:"list_price_rub,omitempty"` // полная цена прайса за период (для сравнения в UI)
 	// BaseAmountRub — сумма до скидок лояльности и pending-промокода (как в боте).
 	BaseAmountRub      int `json:"base_amount_rub,omitempty"`
 	LoyaltyDiscountPct int `json:"loyalty_discount_pct,omitempty"`
 	PromoDiscountPct   int `json:"promo_discount_pct,omitempty"`
 	TotalDiscountPct   int `json:"total_discount_pct,omitempty"`
+	// PromoDiscountAmountRub — фиксированная скидка промокода в ₽ (вычитается после процентов).
+	PromoDiscountAmountRub int `json:"promo_discount_amount_rub,omitempty"`
+	// PromoDiscountRejected — у клиента есть скидка по промокоду, но к этому заказу она не подходит
+	// (tariff | months | provider | min_amount | currency, см. promosvc.DiscountReject*).
+	PromoDiscountRejected string `json:"promo_discount_rejected,omitempty"`
 	// TariffSwitch* — только при scenario upgrade/downgrade (активная подписка, смена тарифа).
 	TariffSwitchRemainingDays int `json:"tariff_switch_remaining_days,omitempty"` // календарных дней до expire (ceil)
 	TariffSwitchBonusDays     int `json:"tariff_switch_bonus_days,omitempty"`     // остаток в днях по дневной цене нового тарифа
@@ -218,7 +228,7 @@ func (s *CheckoutService) Create(ctx context.Context, accountID int64, req Creat
 		return nil, err
 	}
 	provider := req.Provider
-	invoiceType, err := mapProviderToInvoiceType(provider)
+	invoiceType, err := s.mapProviderToInvoiceType(provider)
 	if err != nil {
 		return nil, err
 	}
@@ -255,7 +265,21 @@ func (s *CheckoutService) Create(ctx context.Context, accountID int64, req Creat
 		return nil, err
 	}
 
-	amount, _, _, meta := s.applyCheckoutDiscounts(ctx, customer, invoiceType, amount)
+	baseAmount := amount
+	amount, loyaltyPct, disc := s.applyCheckoutDiscounts(ctx, customer, promosvc.NewCheckoutOrder(invoiceType, tariffID, req.Period, amount))
+	meta := payment.PromoMetaFor(disc)
+	currency, err := s.checkoutCurrency(ctx, invoiceType, customer, req.Currency, tariffID, req.Period, extraHwid)
+	if err != nil {
+		return nil, err
+	}
+	invoiceAmount := float64(amount)
+	if payment.IsForeignCurrency(currency) {
+		promoPct, promoAmount := appliedPromoDiscount(disc)
+		invoiceAmount, err = s.currencyAmount(ctx, tariffID, req.Period, extraHwid, currency, loyaltyPct, promoPct, promoAmount, baseAmount)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	checkout, err := s.checkouts.Create(ctx, accountID, req.IdempotencyKey, provider)
 	if err != nil {
@@ -271,14 +295,14 @@ func (s *CheckoutService) Create(ctx context.Context, accountID int64, req Creat
 	}
 
 	returnURL := s.buildReturnURL(checkout.ID)
-	providerCtx := s.withProviderOverrides(ctx, provider, returnURL, acc)
+	providerCtx := payment.WithInvoiceCurrency(s.withProviderOverrides(ctx, provider, returnURL, acc), currency)
 
 	var paymentURL string
 	var purchaseID int64
 	if extraHwid > 0 {
 		paymentURL, purchaseID, err = s.payments.CreatePurchaseWithExtra(
 			providerCtx,
-			float64(amount),
+			invoiceAmount,
 			req.Period,
 			extraHwid,
 			customer,
@@ -290,7 +314,7 @@ func (s *CheckoutService) Create(ctx context.Context, accountID int64, req Creat
 	} else {
 		paymentURL, purchaseID, err = s.payments.CreatePurchase(
 			providerCtx,
-			float64(amount),
+			invoiceAmount,
 			req.Period,
 			customer,
 			invoiceType,
@@ -321,20 +345,21 @@ func (s *CheckoutService) Create(ctx context.Context, accountID int64, req Creat
 	return &CreateResult{
 		CheckoutID: checkout.ID,
 		Provider:   provider,
-		Status:     repository.CheckoutStatusPending,
+		Status:     s.createdStatus(ctx, invoiceType, purchaseID),
 		PaymentURL: paymentURL,
 	}, nil
 }
 
 // Preview считает сумму к оплате без создания checkout (та же логика, что Create → resolveAmount).
-func (s *CheckoutService) Preview(ctx context.Context, accountID int64, period int, tariffID *int64, provider string) (*PreviewResult, error) {
+// currency — желаемая валюта счёта, как CreateRequest.Currency.
+func (s *CheckoutService) Preview(ctx context.Context, accountID int64, period int, tariffID *int64, provider, currency string) (*PreviewResult, error) {
 	if !supportedMonths[period] {
 		return nil, fmt.Errorf("%w: period must be one of 1/3/6/12", ErrInvalidInput)
 	}
 	if provider == "" {
 		provider = repository.CheckoutProviderYookassa
 	}
-	invoiceType, err := mapProviderToInvoiceType(provider)
+	invoiceType, err := s.mapProviderToInvoiceType(provider)
 	if err != nil {
 		return nil, err
 	}
@@ -375,7 +400,7 @@ func (s *CheckoutService) Preview(ctx context.Context, accountID int64, period i
 		if rerr != nil {
 			return nil, fmt.Errorf("payments: resolve tariff: %w", rerr)
 		}
-		out.Amount = amt
+		out.Amount = float64(amt)
 		out.AmountRub = amt
 		out.PurchaseKind = string(pk)
 		out.IsEarlyDowngrade = early
@@ -386,7 +411,7 @@ func (s *CheckoutService) Preview(ctx context.Context, accountID int64, period i
 		if activeExtra > 0 && reqBoolFalse(reqRenewExtraHwidFromContext(ctx)) {
 			extra := s.subscriptionExtraAmount(period, invoiceType, activeExtra)
 			out.ExtraHwidAmountRub = extra
-			out.Amount += extra
+			out.Amount += float64(extra)
 			out.AmountRub += extra
 			out.ExtraHwidIncluded = true
 			if out.ListPriceRub > 0 {
@@ -410,7 +435,10 @@ func (s *CheckoutService) Preview(ctx context.Context, accountID int64, period i
 				}
 			}
 		}
-		s.applyPreviewDiscounts(ctx, customer, invoiceType, out)
+		s.applyPreviewDiscounts(ctx, customer, promosvc.NewCheckoutOrder(invoiceType, tariffID, period, out.AmountRub), out)
+		if err := s.applyPreviewCurrency(ctx, customer, invoiceType, currency, tariffID, period, out); err != nil {
+			return nil, err
+		}
 		return out, nil
 	}
 
@@ -421,7 +449,7 @@ func (s *CheckoutService) Preview(ctx context.Context, accountID int64, period i
 	if price <= 0 {
 		return nil, fmt.Errorf("payments: no price configured for %d months", period)
 	}
-	out.Amount = price
+	out.Amount = float64(price)
 	out.AmountRub = price
 	out.PurchaseKind = string(database.PurchaseKindSubscription)
 	now := time.Now().UTC()
@@ -433,11 +461,14 @@ func (s *CheckoutService) Preview(ctx context.Context, accountID int64, period i
 	if activeExtra > 0 && reqBoolFalse(reqRenewExtraHwidFromContext(ctx)) {
 		extra := s.subscriptionExtraAmount(period, invoiceType, activeExtra)
 		out.ExtraHwidAmountRub = extra
-		out.Amount += extra
+		out.Amount += float64(extra)
 		out.AmountRub += extra
 		out.ExtraHwidIncluded = true
 	}
-	s.applyPreviewDiscounts(ctx, customer, invoiceType, out)
+	s.applyPreviewDiscounts(ctx, customer, promosvc.NewCheckoutOrder(invoiceType, nil, period, out.AmountRub), out)
+	if err := s.applyPreviewCurrency(ctx, customer, invoiceType, currency, nil, period, out); err != nil {
+		return nil, err
+	}
 	return out, nil
 }
 
@@ -536,6 +567,9 @@ type HwidPreviewResult struct {
 	LoyaltyDiscountPct int    `json:"loyalty_discount_pct,omitempty"`
 	PromoDiscountPct   int    `json:"promo_discount_pct,omitempty"`
 	TotalDiscountPct   int    `json:"total_discount_pct,omitempty"`
+	// PromoDiscountAmountRub / PromoDiscountRejected — как в PreviewResult.
+	PromoDiscountAmountRub int    `json:"promo_discount_amount_rub,omitempty"`
+	PromoDiscountRejected  string `json:"promo_discount_rejected,omitempty"`
 }
 
 // HwidCreateRequest — тело POST /cabinet/api/payments/hwid/checkout.
@@ -610,7 +644,7 @@ func (s *CheckoutService) PreviewHwid(ctx context.Context, accountID int64, targ
 	if provider == "" {
 		provider = repository.CheckoutProviderYookassa
 	}
-	invoiceType, err := mapProviderToInvoiceType(provider)
+	invoiceType, err := s.mapProviderToInvoiceType(provider)
 	if err != nil {
 		return nil, err
 	}
@@ -639,16 +673,21 @@ func (s *CheckoutService) PreviewHwid(ctx context.Context, accountID int64, targ
 		priceMonth = config.HwidAddStarsPrice()
 	}
 	base := cabsvc.CalcHwidProportionalRub(priceMonth, delta, lim.DaysLeft)
-	final, loyPct, proPct, _ := s.applyCheckoutDiscounts(ctx, customer, invoiceType, base)
+	final, loyPct, disc := s.applyCheckoutDiscounts(ctx, customer, promosvc.NewCheckoutOrder(invoiceType, nil, 0, base))
+	proPct, proAmount := appliedPromoDiscount(disc)
 	out := &HwidPreviewResult{
-		CurrentLimit:       lim.CurrentLimit,
-		TargetLimit:        targetLimit,
-		Delta:              delta,
-		DaysLeft:           lim.DaysLeft,
-		AmountRub:          final,
-		BaseAmountRub:      base,
-		LoyaltyDiscountPct: loyPct,
-		PromoDiscountPct:   proPct,
+		CurrentLimit:           lim.CurrentLimit,
+		TargetLimit:            targetLimit,
+		Delta:                  delta,
+		DaysLeft:               lim.DaysLeft,
+		AmountRub:              final,
+		BaseAmountRub:          base,
+		LoyaltyDiscountPct:     loyPct,
+		PromoDiscountPct:       proPct,
+		PromoDiscountAmountRub: proAmount,
+	}
+	if disc != nil {
+		out.PromoDiscountRejected = disc.Reject
 	}
 	cap := config.LoyaltyMaxTotalDiscountPercent()
 	out.TotalDiscountPct = loyalty.CombinedDiscountPercent(loyPct, proPct, cap)
@@ -672,7 +711,7 @@ func (s *CheckoutService) CreateHwid(ctx context.Context, accountID int64, req H
 	if provider == "" {
 		provider = repository.CheckoutProviderYookassa
 	}
-	invoiceType, err := mapProviderToInvoiceType(provider)
+	invoiceType, err := s.mapProviderToInvoiceType(provider)
 	if err != nil {
 		return nil, err
 	}
@@ -708,7 +747,8 @@ func (s *CheckoutService) CreateHwid(ctx context.Context, accountID int64, req H
 		priceMonth = config.HwidAddStarsPrice()
 	}
 	base := cabsvc.CalcHwidProportionalRub(priceMonth, delta, lim.DaysLeft)
-	amount, _, _, meta := s.applyCheckoutDiscounts(ctx, customer, invoiceType, base)
+	amount, _, disc := s.applyCheckoutDiscounts(ctx, customer, promosvc.NewCheckoutOrder(invoiceType, nil, 0, base))
+	meta := payment.PromoMetaFor(disc)
 
 	checkout, err := s.checkouts.Create(ctx, accountID, req.IdempotencyKey, provider)
 	if err != nil {
@@ -745,7 +785,7 @@ func (s *CheckoutService) CreateHwid(ctx context.Context, accountID int64, req H
 	return &CreateResult{
 		CheckoutID: checkout.ID,
 		Provider:   provider,
-		Status:     repository.CheckoutStatusPending,
+		Status:     s.createdStatus(ctx, invoiceType, purchaseID),
 		PaymentURL: paymentURL,
 	}, nil
 }
@@ -758,7 +798,7 @@ func (s *CheckoutService) validateCreate(req CreateRequest) error {
 	if !supportedMonths[req.Period] {
 		return fmt.Errorf("%w: period must be one of 1/3/6/12", ErrInvalidInput)
 	}
-	if _, err := mapProviderToInvoiceType(req.Provider); err != nil {
+	if _, err := s.mapProviderToInvoiceType(req.Provider); err != nil {
 		return err
 	}
 	l := len(req.IdempotencyKey)
@@ -771,55 +811,39 @@ func (s *CheckoutService) validateCreate(req CreateRequest) error {
 	return nil
 }
 
+// ensureProviderEnabled — провайдер зарегистрирован и включён в env (см. payment.ProviderRegistry).
 func (s *CheckoutService) ensureProviderEnabled(provider string) error {
-	switch provider {
-	case repository.CheckoutProviderYookassa:
-		if !config.IsYookasaEnabled() {
-			return fmt.Errorf("%w: yookassa disabled", ErrProviderDisabled)
-		}
-	case repository.CheckoutProviderCryptoPay:
-		if !config.IsCryptoPayEnabled() {
-			return fmt.Errorf("%w: cryptopay disabled", ErrProviderDisabled)
-		}
-	case repository.CheckoutProviderTelegram:
-		if !config.IsTelegramStarsEnabled() {
-			return fmt.Errorf("%w: telegram stars disabled", ErrProviderDisabled)
-		}
-	case repository.CheckoutProviderPlategaSBP:
-		if !config.IsPlategaEnabled() || !config.IsPlategaSBPEnabled() {
-			return fmt.Errorf("%w: platega sbp disabled", ErrProviderDisabled)
-		}
-	case repository.CheckoutProviderPlategaCards:
-		if !config.IsPlategaEnabled() || !config.IsPlategaCardsEnabled() {
-			return fmt.Errorf("%w: platega cards disabled", ErrProviderDisabled)
-		}
-	case repository.CheckoutProviderPlategaAcquiring:
-		if !config.IsPlategaEnabled() || !config.IsPlategaAcquiringEnabled() {
-			return fmt.Errorf("%w: platega acquiring disabled", ErrProviderDisabled)
-		}
-	case repository.CheckoutProviderPlategaWorldwide:
-		if !config.IsPlategaEnabled() || !config.IsPlategaWorldwideEnabled() {
-			return fmt.Errorf("%w: platega worldwide disabled", ErrProviderDisabled)
-		}
-	case repository.CheckoutProviderPlategaCrypto:
-		if !config.IsPlategaEnabled() || !config.IsPlategaCryptoEnabled() {
-			return fmt.Errorf("%w: platega crypto disabled", ErrProviderDisabled)
-		}
-	default:
-		return fmt.Errorf("%w: unknown provider %q", ErrInvalidInput, provider)
+	p, err := s.checkoutProvider(provider)
+	if err != nil {
+		return err
+	}
+	if !p.Enabled() {
+		return fmt.Errorf("%w: %s disabled", ErrProviderDisabled, provider)
 	}
 	return nil
 }
 
+// checkoutProvider ищет провайдера по значению provider из API кабинета.
+func (s *CheckoutService) checkoutProvider(provider string) (payment.Provider, error) {
+	if s.payments == nil {
+		return nil, fmt.Errorf("%w: unknown provider %q", ErrInvalidInput, provider)
+	}
+	p, ok := s.payments.Providers().ByCheckoutKey(provider)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown provider %q", ErrInvalidInput, provider)
+	}
+	return p, nil
+}
+
 // resolveAmount возвращает сумму (в рублях/stars), tariffID для purchase-строки
 // и extras (kind/isEarlyDowngrade). В classic-режиме всегда tariffID==nil, extras==nil.
-// applyCheckoutDiscounts — как handler.checkoutPromoMeta: лояльность + pending-промо, Tribute не используется в кабинете.
-func (s *CheckoutService) applyCheckoutDiscounts(ctx context.Context, customer *database.Customer, invoiceType database.InvoiceType, base int) (final int, loyaltyPct, promoPct int, meta *payment.PromoMeta) {
-	final = base
-	if customer == nil || invoiceType == database.InvoiceTypeTribute {
-		return final, 0, 0, nil
+// applyCheckoutDiscounts — как handler.checkoutPromoMeta: лояльность + pending-промо с ограничениями промокода,
+// Tribute не используется в кабинете. order.Amount — сумма до скидок; disc — промокод, в т.ч. не подошедший к заказу.
+func (s *CheckoutService) applyCheckoutDiscounts(ctx context.Context, customer *database.Customer, order promosvc.CheckoutOrder) (final int, loyaltyPct int, disc *promosvc.CheckoutDiscount) {
+	final = int(order.Amount)
+	if customer == nil || order.InvoiceType == database.InvoiceTypeTribute {
+		return final, 0, nil
 	}
-	loyaltyPct = 0
 	if config.LoyaltyEnabled() && s.loyalty != nil {
 		var err error
 		loyaltyPct, err = s.loyalty.DiscountPercentForXP(ctx, customer.LoyaltyXP)
@@ -828,35 +852,97 @@ func (s *CheckoutService) applyCheckoutDiscounts(ctx context.Context, customer *
 			loyaltyPct = 0
 		}
 	}
-	promoPct = 0
 	if s.promo != nil {
-		pct, pid, err := s.promo.PendingDiscountForPayment(ctx, customer.ID)
+		d, err := s.promo.PendingDiscountForCheckout(ctx, customer.ID, order)
 		if err != nil {
 			slog.Error("cabinet checkout pending promo", "error", err)
-		} else if pct > 0 && pid != 0 {
-			promoPct = pct
-			pc := pct
-			p := pid
-			meta = &payment.PromoMeta{PromoCodeID: &p, DiscountPercentApplied: &pc}
+		} else {
+			disc = d
 		}
 	}
-	cap := config.LoyaltyMaxTotalDiscountPercent()
-	final = loyalty.ApplyCombinedPercentDiscount(base, loyaltyPct, promoPct, cap)
-	return final, loyaltyPct, promoPct, meta
+	promoPct, _ := appliedPromoDiscount(disc)
+	fixed := 0.0
+	if disc.Applied() {
+		fixed = disc.Fixed
+	}
+	final = loyalty.ApplyCombinedDiscount(final, loyaltyPct, promoPct, fixed, config.LoyaltyMaxTotalDiscountPercent())
+	return final, loyaltyPct, disc
+}
+
+// appliedPromoDiscount — процент и фиксированная скидка в ₽ промокода, если он применяется к заказу.
+func appliedPromoDiscount(d *promosvc.CheckoutDiscount) (pct, amountRub int) {
+	if !d.Applied() {
+		return 0, 0
+	}
+	return d.Percent, d.AmountRub
 }
 
-func (s *CheckoutService) applyPreviewDiscounts(ctx context.Context, customer *database.Customer, invoiceType database.InvoiceType, out *PreviewResult) {
+func (s *CheckoutService) applyPreviewDiscounts(ctx context.Context, customer *database.Customer, order promosvc.CheckoutOrder, out *PreviewResult) {
 	base := out.AmountRub
-	final, loyPct, proPct, _ := s.applyCheckoutDiscounts(ctx, customer, invoiceType, base)
+	final, loyPct, disc := s.applyCheckoutDiscounts(ctx, customer, order)
+	proPct, proAmount := appliedPromoDiscount(disc)
 	out.BaseAmountRub = base
 	out.AmountRub = final
-	out.Amount = final
+	out.Amount = float64(final)
 	out.LoyaltyDiscountPct = loyPct
 	out.PromoDiscountPct = proPct
+	out.PromoDiscountAmountRub = proAmount
+	if disc != nil {
+		out.PromoDiscountRejected = disc.Reject
+	}
 	cap := config.LoyaltyMaxTotalDiscountPercent()
 	out.TotalDiscountPct = loyalty.CombinedDiscountPercent(loyPct, proPct, cap)
 }
 
+// checkoutCurrency — валюта счёта (см. PaymentService.CheckoutCurrency). Явно запрошенная валюта, которую провайдер
+// не принимает или для которой нет цены, — ошибка ввода, а не тихая подмена.
+func (s *CheckoutService) checkoutCurrency(ctx context.Context, invoiceType database.InvoiceType, customer *database.Customer, requested string, tariffID *int64, period, extraHwid int) (string, error) {
+	currency := s.payments.CheckoutCurrency(ctx, invoiceType, customer, requested, tariffID, period, extraHwid)
+	if requested != "" && currency != config.NormalizeCurrency(requested) {
+		return "", fmt.Errorf("%w: currency %s is not available for this provider", ErrInvalidInput, strings.ToUpper(requested))
+	}
+	return currency, nil
+}
+
+// currencyAmount — сумма к оплате в валюте из PRICE_CURRENCIES с теми же скидками, что и рублёвая.
+// Фиксированная скидка promoAmountRub пересчитывается в валюту пропорционально baseRub — рублёвой сумме до скидок.
+func (s *CheckoutService) currencyAmount(ctx context.Context, tariffID *int64, period, extraHwid int, currency string, loyaltyPct, promoPct, promoAmountRub, baseRub int) (float64, error) {
+	base, ok, err := payment.SubscriptionPriceIn(ctx, s.tariffs, tariffID, period, extraHwid, currency)
+	if err != nil {
+		return 0, fmt.Errorf("payments: currency price: %w", err)
+	}
+	if !ok {
+		return 0, fmt.Errorf("%w: no %s price for %d months", ErrInvalidInput, currency, period)
+	}
+	fixed := 0.0
+	if promoAmountRub > 0 && baseRub > 0 {
+		fixed = float64(promoAmountRub) * base / float64(baseRub)
+	}
+	return loyalty.ApplyCombinedDiscountAmount(base, loyaltyPct, promoPct, fixed, config.LoyaltyMaxTotalDiscountPercent()), nil
+}
+
+// applyPreviewCurrency переводит Amount превью в валюту счёта, если это не рубли / Stars (после applyPreviewDiscounts).
+func (s *CheckoutService) applyPreviewCurrency(ctx context.Context, customer *database.Customer, invoiceType database.InvoiceType, requested string, tariffID *int64, period int, out *PreviewResult) error {
+	extra := 0
+	if out.ExtraHwidIncluded {
+		extra = out.ExtraHwidActive
+	}
+	currency, err := s.checkoutCurrency(ctx, invoiceType, customer, requested, tariffID, period, extra)
+	if err != nil {
+		return err
+	}
+	if !payment.IsForeignCurrency(currency) {
+		return nil
+	}
+	amount, err := s.currencyAmount(ctx, tariffID, period, extra, currency, out.LoyaltyDiscountPct, out.PromoDiscountPct, out.PromoDiscountAmountRub, out.BaseAmountRub)
+	if err != nil {
+		return err
+	}
+	out.Amount = amount
+	out.Currency = currency
+	return nil
+}
+
 func (s *CheckoutService) resolveAmount(
 	ctx context.Context,
 	customer *database.Customer,
@@ -1010,28 +1096,29 @@ func (s *CheckoutService) reuseExisting(ctx context.Context, checkout *repositor
 // mapProviderToInvoiceType превращает значение из API в InvoiceType purchase-строки.
 // Обратите внимание: в API и cabinet_checkout.provider — "yookassa" (официальное
 // написание бренда), а в purchase.invoice_type — исторически "yookasa" (одна 's').
-// Эта асимметрия закрепилась в миграциях бота и здесь мы её просто переводим.
-func mapProviderToInvoiceType(provider string) (database.InvoiceType, error) {
-	switch provider {
-	case repository.CheckoutProviderYookassa:
-		return database.InvoiceTypeYookasa, nil
-	case repository.CheckoutProviderCryptoPay:
-		return database.InvoiceTypeCrypto, nil
-	case repository.CheckoutProviderTelegram:
-		return database.InvoiceTypeTelegram, nil
-	case repository.CheckoutProviderPlategaSBP:
-		return database.InvoiceTypePlategaSBP, nil
-	case repository.CheckoutProviderPlategaCards:
-		return database.InvoiceTypePlategaCards, nil
-	case repository.CheckoutProviderPlategaAcquiring:
-		return database.InvoiceTypePlategaAcquiring, nil
-	case repository.CheckoutProviderPlategaWorldwide:
-		return database.InvoiceTypePlategaWorldwide, nil
-	case repository.CheckoutProviderPlategaCrypto:
-		return database.InvoiceTypePlategaCrypto, nil
-	default:
-		return "", fmt.Errorf("%w: unknown provider %q", ErrInvalidInput, provider)
+// Соответствие задаёт ProviderInfo.CheckoutKey в реестре провайдеров.
+func (s *CheckoutService) mapProviderToInvoiceType(provider string) (database.InvoiceType, error) {
+	p, err := s.checkoutProvider(provider)
+	if err != nil {
+		return "", err
+	}
+	return p.Info().InvoiceType, nil
+}
+
+// createdStatus — статус только что созданного checkout: оплата с баланса проводится сразу
+// (payment_url пустой), остальные провайдеры ждут оплаты.
+func (s *CheckoutService) createdStatus(ctx context.Context, invoiceType database.InvoiceType, purchaseID int64) string {
+	if invoiceType != database.InvoiceTypeBalance {
+		return repository.CheckoutStatusPending
+	}
+	p, err := s.purchases.FindById(ctx, purchaseID)
+	if err != nil || p == nil {
+		return repository.CheckoutStatusPending
+	}
+	if st := checkoutStatusForPurchase(p.Status); st != "" {
+		return st
 	}
+	return repository.CheckoutStatusPending
 }
 
 // checkoutStatusForPurchase — маппинг purchase.status → cabinet_checkout.status.
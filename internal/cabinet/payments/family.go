@@ -0,0 +1,181 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/payment"
+)
+
+// FamilyMemberItem — участник семьи для кабинета владельца.
+type FamilyMemberItem struct {
+	CustomerID  int64     `json:"customer_id"`
+	Name        string    `json:"name"`
+	DeviceLimit int       `json:"device_limit"`
+	JoinedAt    time.Time `json:"joined_at"`
+}
+
+// FamilyResult — ответ GET /cabinet/api/me/family. Role: owner — владелец семейного тарифа,
+// member — участник чужой семьи, none — раздел недоступен.
+type FamilyResult struct {
+	Role         string             `json:"role"`
+	TariffName   string             `json:"tariff_name,omitempty"`
+	ExpireAt     *time.Time         `json:"expire_at,omitempty"`
+	DevicePool   int                `json:"device_pool,omitempty"`
+	OwnerDevices int                `json:"owner_devices,omitempty"`
+	MaxMembers   int                `json:"max_members,omitempty"`
+	InviteCode   string             `json:"invite_code,omitempty"`
+	InviteLink   string             `json:"invite_link,omitempty"`
+	Members      []FamilyMemberItem `json:"members"`
+	// Для участника: владелец семьи и выделенные устройства.
+	OwnerName   string `json:"owner_name,omitempty"`
+	DeviceLimit int    `json:"device_limit,omitempty"`
+}
+
+// Family — семейный раздел клиента, привязанного к аккаунту.
+func (s *CheckoutService) Family(ctx context.Context, accountID int64) (*FamilyResult, error) {
+	customer, err := s.customerForAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return s.familyResult(ctx, customer)
+}
+
+// RegenerateFamilyInvite — новая ссылка-приглашение; старая перестаёт работать.
+func (s *CheckoutService) RegenerateFamilyInvite(ctx context.Context, accountID int64) (*FamilyResult, error) {
+	customer, err := s.customerForAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.payments.RegenerateFamilyInvite(ctx, customer); err != nil {
+		return nil, mapFamilyErr(err)
+	}
+	return s.familyResult(ctx, customer)
+}
+
+// JoinFamily — вступление в семью по коду или ссылке-приглашению.
+func (s *CheckoutService) JoinFamily(ctx context.Context, accountID int64, code string) (*FamilyResult, error) {
+	if strings.TrimSpace(code) == "" {
+		return nil, fmt.Errorf("%w: code is required", ErrInvalidInput)
+	}
+	customer, err := s.customerForAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	// Код может прийти целой ссылкой t.me/<бот>?start=fam_<код>.
+	if _, after, ok := strings.Cut(code, "start="); ok {
+		code = after
+	}
+	if _, err := s.payments.JoinFamily(ctx, code, customer); err != nil {
+		return nil, mapFamilyErr(err)
+	}
+	if customer, err = s.customers.FindById(ctx, customer.ID); err != nil {
+		return nil, fmt.Errorf("payments: reload customer: %w", err)
+	}
+	return s.familyResult(ctx, customer)
+}
+
+// LeaveFamily — участник выходит из семьи.
+func (s *CheckoutService) LeaveFamily(ctx context.Context, accountID int64) error {
+	customer, err := s.customerForAccount(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	return mapFamilyErr(s.payments.LeaveFamily(ctx, customer))
+}
+
+// SetFamilyMemberDevices — владелец меняет число устройств участника.
+func (s *CheckoutService) SetFamilyMemberDevices(ctx context.Context, accountID, memberCustomerID int64, limit int) (*FamilyResult, error) {
+	if limit < 1 {
+		return nil, fmt.Errorf("%w: device_limit must be at least 1", ErrInvalidInput)
+	}
+	customer, err := s.customerForAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.payments.SetFamilyMemberDevices(ctx, customer, memberCustomerID, limit); err != nil {
+		return nil, mapFamilyErr(err)
+	}
+	return s.familyResult(ctx, customer)
+}
+
+// RemoveFamilyMember — владелец исключает участника.
+func (s *CheckoutService) RemoveFamilyMember(ctx context.Context, accountID, memberCustomerID int64) (*FamilyResult, error) {
+	customer, err := s.customerForAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.payments.RemoveFamilyMember(ctx, customer, memberCustomerID); err != nil {
+		return nil, mapFamilyErr(err)
+	}
+	return s.familyResult(ctx, customer)
+}
+
+func (s *CheckoutService) familyResult(ctx context.Context, customer *database.Customer) (*FamilyResult, error) {
+	out := &FamilyResult{Role: "none", Members: []FamilyMemberItem{}}
+	membership, err := s.payments.Membership(ctx, customer)
+	if err != nil {
+		return nil, fmt.Errorf("payments: family membership: %w", err)
+	}
+	if membership != nil {
+		out.Role = "member"
+		out.ExpireAt = customer.ExpireAt
+		out.DeviceLimit = membership.Member.DeviceLimit
+		if membership.Owner != nil {
+			out.OwnerName = payment.FamilyMemberLabel(membership.Owner)
+		}
+		return out, nil
+	}
+	overview, err := s.payments.Family(ctx, customer)
+	if errors.Is(err, payment.ErrFamilyNotAvailable) || errors.Is(err, payment.ErrFamilyDisabled) {
+		return out, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("payments: family: %w", err)
+	}
+	out.Role = "owner"
+	out.TariffName = overview.Tariff.Slug
+	if overview.Tariff.Name != nil && strings.TrimSpace(*overview.Tariff.Name) != "" {
+		out.TariffName = strings.TrimSpace(*overview.Tariff.Name)
+	}
+	out.ExpireAt = &overview.ExpireAt
+	out.DevicePool = overview.DevicePool
+	out.OwnerDevices = overview.OwnerDevices
+	out.MaxMembers = overview.MaxMembers()
+	out.InviteCode = overview.Group.InviteCode
+	out.InviteLink = overview.InviteLink
+	for _, m := range overview.Members {
+		name := fmt.Sprintf("ID %d", m.CustomerID)
+		if m.Customer != nil {
+			name = payment.FamilyMemberLabel(m.Customer)
+		}
+		out.Members = append(out.Members, FamilyMemberItem{
+			CustomerID:  m.CustomerID,
+			Name:        name,
+			DeviceLimit: m.DeviceLimit,
+			JoinedAt:    m.JoinedAt,
+		})
+	}
+	return out, nil
+}
+
+// mapFamilyErr — ошибки семейного раздела в sentinel-ошибки HTTP-слоя; конфликты (мест нет,
+// клиент уже в семье) отдаются как есть — writePaymentsErr отвечает на них 409.
+func mapFamilyErr(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, payment.ErrFamilyNotAvailable), errors.Is(err, payment.ErrFamilyDisabled):
+		return ErrForbidden
+	case errors.Is(err, payment.ErrFamilyInviteNotFound), errors.Is(err, payment.ErrFamilyMemberNotFound):
+		return ErrCheckoutNotFound
+	case errors.Is(err, payment.ErrFamilyOwnInvite), errors.Is(err, payment.ErrFamilyHasSubscription),
+		errors.Is(err, payment.ErrFamilyDevicePool):
+		return fmt.Errorf("%w: %s", ErrInvalidInput, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,172 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/payment"
+)
+
+// PartnerPayoutRequest — POST /cabinet/api/me/partner/payouts.
+type PartnerPayoutRequest struct {
+	// Amount — сумма в рублях; 0 — всё доступное.
+	Amount  float64
+	Method  string
+	Details string
+}
+
+// PartnerCommissionItem — начисление партнёру для кабинета.
+type PartnerCommissionItem struct {
+	ID             int64      `json:"id"`
+	PurchaseID     int64      `json:"purchase_id"`
+	PurchaseAmount float64    `json:"purchase_amount"`
+	Percent        float64    `json:"percent"`
+	Amount         float64    `json:"amount"`
+	ReversedAmount float64    `json:"reversed_amount"`
+	Status         string     `json:"status"`
+	AvailableAt    time.Time  `json:"available_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ReversedAt     *time.Time `json:"reversed_at,omitempty"`
+}
+
+// PartnerPayoutItem — запрос на выплату для кабинета.
+type PartnerPayoutItem struct {
+	ID           int64      `json:"id"`
+	Amount       float64    `json:"amount"`
+	Method       string     `json:"method"`
+	Status       string     `json:"status"`
+	AdminComment *string    `json:"admin_comment,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ProcessedAt  *time.Time `json:"processed_at,omitempty"`
+}
+
+// PartnerResult — ответ GET /cabinet/api/me/partner. Partner=false — у клиента нет ставки
+// и истории начислений; суммы тогда нулевые.
+type PartnerResult struct {
+	Enabled          bool                    `json:"enabled"`
+	Partner          bool                    `json:"partner"`
+	Percent          float64                 `json:"percent"`
+	Currency         string                  `json:"currency"`
+	Earned           float64                 `json:"earned"`
+	OnHold           float64                 `json:"on_hold"`
+	Available        float64                 `json:"available"`
+	PayoutPending    float64                 `json:"payout_pending"`
+	PaidOut          float64                 `json:"paid_out"`
+	HoldDays         int                     `json:"hold_days"`
+	MinPayout        int                     `json:"min_payout"`
+	CanRequestPayout bool                    `json:"can_request_payout"`
+	PayoutMethods    []string                `json:"payout_methods"`
+	Commissions      []PartnerCommissionItem `json:"commissions"`
+	Payouts          []PartnerPayoutItem     `json:"payouts"`
+}
+
+// Partner — сводка партнёрской программы клиента, привязанного к аккаунту.
+func (s *CheckoutService) Partner(ctx context.Context, accountID int64, limit, offset int) (*PartnerResult, error) {
+	out := &PartnerResult{
+		Enabled:       config.ReferralPartnerEnabled(),
+		Currency:      "RUB",
+		HoldDays:      config.ReferralPartnerHoldDays(),
+		MinPayout:     config.ReferralPartnerMinPayout(),
+		PayoutMethods: []string{},
+		Commissions:   []PartnerCommissionItem{},
+		Payouts:       []PartnerPayoutItem{},
+	}
+	if !out.Enabled {
+		return out, nil
+	}
+	for _, m := range []database.PayoutMethod{database.PayoutMethodBalance, database.PayoutMethodManual} {
+		if payment.PayoutMethodAvailable(m) {
+			out.PayoutMethods = append(out.PayoutMethods, string(m))
+		}
+	}
+	customer, err := s.customerForAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	dash, err := s.payments.PartnerDashboard(ctx, customer.ID)
+	if errors.Is(err, payment.ErrPartnerNotPartner) {
+		return out, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("payments: partner dashboard: %w", err)
+	}
+	out.Partner = true
+	out.Percent = dash.Percent
+	out.Earned = dash.Summary.Earned
+	out.OnHold = dash.Summary.OnHold
+	out.Available = dash.Available()
+	out.PayoutPending = dash.Summary.PayoutPending
+	out.PaidOut = dash.Summary.PaidOut
+	out.CanRequestPayout = dash.CanRequestPayout()
+
+	commissions, err := s.payments.PartnerCommissions(ctx, customer.ID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("payments: partner commissions: %w", err)
+	}
+	for _, c := range commissions {
+		out.Commissions = append(out.Commissions, PartnerCommissionItem{
+			ID:             c.ID,
+			PurchaseID:     c.PurchaseID,
+			PurchaseAmount: c.PurchaseAmount,
+			Percent:        c.CommissionPercent,
+			Amount:         c.Amount,
+			ReversedAmount: c.ReversedAmount,
+			Status:         string(c.Status),
+			AvailableAt:    c.AvailableAt,
+			CreatedAt:      c.CreatedAt,
+			ReversedAt:     c.ReversedAt,
+		})
+	}
+	payouts, err := s.payments.PartnerPayouts(ctx, "", customer.ID, limit, 0)
+	if err != nil {
+		return nil, fmt.Errorf("payments: partner payouts: %w", err)
+	}
+	for i := range payouts {
+		out.Payouts = append(out.Payouts, mapPartnerPayout(&payouts[i]))
+	}
+	return out, nil
+}
+
+// RequestPartnerPayout ставит запрос на выплату в очередь админа.
+func (s *CheckoutService) RequestPartnerPayout(ctx context.Context, accountID int64, req PartnerPayoutRequest) (*PartnerPayoutItem, error) {
+	if !config.ReferralPartnerEnabled() {
+		return nil, fmt.Errorf("%w: partner program disabled", ErrInvalidInput)
+	}
+	method := database.PayoutMethod(strings.ToLower(strings.TrimSpace(req.Method)))
+	if req.Amount < 0 {
+		return nil, fmt.Errorf("%w: amount must not be negative", ErrInvalidInput)
+	}
+	customer, err := s.customerForAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.payments.RequestPartnerPayout(ctx, customer, req.Amount, method, req.Details)
+	if err != nil {
+		switch {
+		case errors.Is(err, payment.ErrPayoutAmount), errors.Is(err, payment.ErrPayoutMethod):
+			return nil, fmt.Errorf("%w: %s", ErrInvalidInput, err.Error())
+		case errors.Is(err, payment.ErrPartnerNotPartner):
+			return nil, ErrForbidden
+		}
+		return nil, err
+	}
+	item := mapPartnerPayout(p)
+	return &item, nil
+}
+
+func mapPartnerPayout(p *database.ReferralPayout) PartnerPayoutItem {
+	return PartnerPayoutItem{
+		ID:           p.ID,
+		Amount:       p.Amount,
+		Method:       string(p.Method),
+		Status:       string(p.Status),
+		AdminComment: p.AdminComment,
+		CreatedAt:    p.CreatedAt,
+		ProcessedAt:  p.ProcessedAt,
+	}
+}
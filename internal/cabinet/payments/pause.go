@@ -0,0 +1,124 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/payment"
+)
+
+// PauseResult — ответ GET /cabinet/api/me/subscription/pause. Reason — почему новая пауза недоступна:
+// disabled, no_subscription, too_little_left, family, limit (пусто — можно, paused — уже на паузе).
+type PauseResult struct {
+	Enabled       bool       `json:"enabled"`
+	Paused        bool       `json:"paused"`
+	PausedAt      *time.Time `json:"paused_at,omitempty"`
+	ResumeAt      *time.Time `json:"resume_at,omitempty"`
+	RemainingDays int        `json:"remaining_days,omitempty"`
+	MinDays       int        `json:"min_days"`
+	MaxDays       int        `json:"max_days"`
+	Limit         int        `json:"limit"`
+	Used          int        `json:"used"`
+	PeriodDays    int        `json:"period_days"`
+	CanPause      bool       `json:"can_pause"`
+	Reason        string     `json:"reason,omitempty"`
+}
+
+// PauseStatus — состояние паузы подписки клиента, привязанного к аккаунту.
+func (s *CheckoutService) PauseStatus(ctx context.Context, accountID int64) (*PauseResult, error) {
+	customer, err := s.customerForAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return s.pauseResult(ctx, customer)
+}
+
+// PauseSubscription ставит подписку на паузу на days дней.
+func (s *CheckoutService) PauseSubscription(ctx context.Context, accountID int64, days int) (*PauseResult, error) {
+	if days < 1 {
+		return nil, fmt.Errorf("%w: days must be positive", ErrInvalidInput)
+	}
+	customer, err := s.customerForAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.payments.PauseSubscription(ctx, customer, days); err != nil {
+		return nil, mapPauseErr(err)
+	}
+	return s.pauseResult(ctx, customer)
+}
+
+// ResumeSubscription досрочно снимает паузу.
+func (s *CheckoutService) ResumeSubscription(ctx context.Context, accountID int64) (*PauseResult, error) {
+	customer, err := s.customerForAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.payments.ResumeSubscription(ctx, customer); err != nil {
+		return nil, mapPauseErr(err)
+	}
+	return s.pauseResult(ctx, customer)
+}
+
+func (s *CheckoutService) pauseResult(ctx context.Context, customer *database.Customer) (*PauseResult, error) {
+	st, err := s.payments.PauseStatus(ctx, customer)
+	if err != nil {
+		return nil, fmt.Errorf("payments: pause status: %w", err)
+	}
+	out := &PauseResult{
+		Enabled:    st.Enabled,
+		MinDays:    st.MinDays,
+		MaxDays:    st.MaxDays,
+		Limit:      st.Limit,
+		Used:       st.Used,
+		PeriodDays: st.PeriodDays,
+		CanPause:   st.CanPause(),
+		Reason:     pauseReasonCode(st.Reason),
+	}
+	if st.Active != nil {
+		out.Paused = true
+		out.PausedAt = &st.Active.PausedAt
+		out.ResumeAt = &st.Active.ResumeAt
+		out.RemainingDays = int((st.Active.Remaining() + 24*time.Hour - 1) / (24 * time.Hour))
+	}
+	return out, nil
+}
+
+func pauseReasonCode(reason error) string {
+	switch {
+	case reason == nil:
+		return ""
+	case errors.Is(reason, payment.ErrPauseAlreadyPaused):
+		return "paused"
+	case errors.Is(reason, payment.ErrPauseDisabled):
+		return "disabled"
+	case errors.Is(reason, payment.ErrPauseNoSubscription):
+		return "no_subscription"
+	case errors.Is(reason, payment.ErrPauseTooLittleLeft):
+		return "too_little_left"
+	case errors.Is(reason, payment.ErrPauseFamily):
+		return "family"
+	case errors.Is(reason, payment.ErrPauseLimit):
+		return "limit"
+	}
+	return "unavailable"
+}
+
+// mapPauseErr — ошибки паузы в sentinel-ошибки HTTP-слоя; повторная пауза отдаётся как есть —
+// writePaymentsErr отвечает на неё 409.
+func mapPauseErr(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, payment.ErrPauseDisabled):
+		return ErrForbidden
+	case errors.Is(err, payment.ErrPauseNoSubscription), errors.Is(err, payment.ErrPauseTooLittleLeft),
+		errors.Is(err, payment.ErrPauseFamily), errors.Is(err, payment.ErrPauseLimit),
+		errors.Is(err, payment.ErrPauseDays), errors.Is(err, payment.ErrPauseNotActive):
+		return fmt.Errorf("%w: %s", ErrInvalidInput, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,173 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/cabinet/repository"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/payment"
+)
+
+// TrafficPackItem — пакет трафика тарифа клиента; AmountStars — цена при оплате Stars (0 — Stars недоступны).
+type TrafficPackItem struct {
+	TrafficGB   int `json:"traffic_gb"`
+	AmountRub   int `json:"amount_rub"`
+	AmountStars int `json:"amount_stars,omitempty"`
+}
+
+// TrafficPacksResult — ответ GET /cabinet/api/me/traffic-packs. Reason — почему пакеты недоступны:
+// disabled, no_subscription, unlimited, not_found (пусто — можно купить).
+type TrafficPacksResult struct {
+	Available   bool              `json:"available"`
+	Reason      string            `json:"reason,omitempty"`
+	Packs       []TrafficPackItem `json:"packs"`
+	LimitBytes  int64             `json:"limit_bytes,omitempty"`
+	UsedBytes   int64             `json:"used_bytes,omitempty"`
+	ExtraBytes  int64             `json:"extra_bytes,omitempty"`
+	Exhausted   bool              `json:"exhausted,omitempty"`
+	LastResetAt *time.Time        `json:"last_reset_at,omitempty"`
+}
+
+// TrafficPackCreateRequest — POST /cabinet/api/me/traffic-packs/checkout.
+type TrafficPackCreateRequest struct {
+	TrafficGB      int
+	Provider       string
+	IdempotencyKey string
+}
+
+// TrafficPacks — пакеты трафика, доступные клиенту аккаунта, и расход трафика за текущий период.
+func (s *CheckoutService) TrafficPacks(ctx context.Context, accountID int64) (*TrafficPacksResult, error) {
+	customer, err := s.customerForAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	out := &TrafficPacksResult{Packs: []TrafficPackItem{}}
+	offer, err := s.payments.TrafficPackOffer(ctx, customer)
+	if err != nil {
+		if reason := trafficPackReasonCode(err); reason != "" {
+			out.Reason = reason
+			return out, nil
+		}
+		return nil, fmt.Errorf("payments: traffic pack offer: %w", err)
+	}
+	out.Available = true
+	out.LimitBytes = offer.LimitBytes
+	out.UsedBytes = offer.UsedBytes
+	out.ExtraBytes = offer.ExtraBytes
+	out.Exhausted = offer.Exhausted()
+	out.LastResetAt = offer.LastResetAt
+	for _, p := range offer.Packs {
+		item := TrafficPackItem{TrafficGB: p.TrafficGB, AmountRub: p.PriceRub}
+		if stars, err := payment.TrafficPackPrice(p, database.InvoiceTypeTelegram); err == nil {
+			item.AmountStars = stars
+		}
+		out.Packs = append(out.Packs, item)
+	}
+	return out, nil
+}
+
+// CheckoutTrafficPack выставляет счёт на пакет трафика (как tpk_pay в боте); цена — из пакета тарифа.
+func (s *CheckoutService) CheckoutTrafficPack(ctx context.Context, accountID int64, req TrafficPackCreateRequest) (*CreateResult, error) {
+	if err := s.validateHwidIdempotencyKey(req.IdempotencyKey); err != nil {
+		return nil, err
+	}
+	if req.TrafficGB <= 0 {
+		return nil, fmt.Errorf("%w: bad traffic_gb", ErrInvalidInput)
+	}
+	provider := strings.ToLower(strings.TrimSpace(req.Provider))
+	if provider == "" {
+		provider = repository.CheckoutProviderYookassa
+	}
+	invoiceType, err := s.mapProviderToInvoiceType(provider)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureProviderEnabled(provider); err != nil {
+		return nil, err
+	}
+
+	if existing, err := s.checkouts.FindByIdempotencyKey(ctx, accountID, req.IdempotencyKey); err == nil {
+		return s.reuseExisting(ctx, existing)
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("payments: find existing: %w", err)
+	}
+
+	customer, err := s.customerForAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	// Проверяем до создания checkout, чтобы отказ не оставлял висящую запись.
+	if _, err := s.payments.TrafficPackOffer(ctx, customer); err != nil {
+		return nil, mapTrafficPackErr(err)
+	}
+	acc, err := s.accounts.FindByID(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("payments: load account: %w", err)
+	}
+
+	checkout, err := s.checkouts.Create(ctx, accountID, req.IdempotencyKey, provider)
+	if err != nil {
+		if errors.Is(err, repository.ErrCheckoutConflict) {
+			existing, ferr := s.checkouts.FindByIdempotencyKey(ctx, accountID, req.IdempotencyKey)
+			if ferr != nil {
+				return nil, fmt.Errorf("payments: read after conflict: %w", ferr)
+			}
+			return s.reuseExisting(ctx, existing)
+		}
+		return nil, fmt.Errorf("payments: create checkout: %w", err)
+	}
+
+	returnURL := s.buildReturnURL(checkout.ID)
+	providerCtx := s.withProviderOverrides(ctx, provider, returnURL, acc)
+	paymentURL, purchaseID, err := s.payments.CreateTrafficPackPurchase(providerCtx, customer, invoiceType, req.TrafficGB)
+	if err != nil {
+		return nil, mapTrafficPackErr(err)
+	}
+	if err := s.checkouts.AttachPurchase(ctx, checkout.ID, purchaseID, returnURL); err != nil {
+		slog.Error("payments: traffic pack attach purchase failed",
+			"checkout_id", checkout.ID,
+			"purchase_id", purchaseID,
+			"error", err,
+		)
+	}
+
+	return &CreateResult{
+		CheckoutID: checkout.ID,
+		Provider:   provider,
+		Status:     s.createdStatus(ctx, invoiceType, purchaseID),
+		PaymentURL: paymentURL,
+	}, nil
+}
+
+func trafficPackReasonCode(err error) string {
+	switch {
+	case errors.Is(err, payment.ErrTrafficPacksDisabled):
+		return "disabled"
+	case errors.Is(err, payment.ErrTrafficPackNoSubscription):
+		return "no_subscription"
+	case errors.Is(err, payment.ErrTrafficPackUnlimited):
+		return "unlimited"
+	case errors.Is(err, payment.ErrTrafficPackNotFound):
+		return "not_found"
+	}
+	return ""
+}
+
+// mapTrafficPackErr — ошибки пакетов трафика в sentinel-ошибки HTTP-слоя.
+func mapTrafficPackErr(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, payment.ErrTrafficPacksDisabled):
+		return ErrForbidden
+	case errors.Is(err, payment.ErrTrafficPackNoSubscription), errors.Is(err, payment.ErrTrafficPackUnlimited),
+		errors.Is(err, payment.ErrTrafficPackNotFound):
+		return fmt.Errorf("%w: %s", ErrInvalidInput, err.Error())
+	}
+	return fmt.Errorf("payments: create traffic pack purchase: %w", err)
+}
@@ -189,6 +189,21 @@ func (r *AccountRepo) UpdateLastLogin(ctx context.Context, id int64) error {
 	return nil
 }
 
+// OptOutMarketingEmail отписывает аккаунт от рекламных писем (ссылка из письма рассылки).
+// Идемпотентно: повторная отписка не сдвигает время первой.
+func (r *AccountRepo) OptOutMarketingEmail(ctx context.Context, id int64) error {
+	const q = `
+		UPDATE cabinet_account
+		   SET marketing_email_opt_out_at = COALESCE(marketing_email_opt_out_at, NOW()),
+		       updated_at = NOW()
+		 WHERE id = $1`
+	_, err := r.pool.Exec(ctx, q, id)
+	if err != nil {
+		return fmt.Errorf("opt out marketing email: %w", err)
+	}
+	return nil
+}
+
 // UpdateLanguage меняет язык аккаунта (PUT /me/language).
 func (r *AccountRepo) UpdateLanguage(ctx context.Context, id int64, lang string) error {
 	if lang != "ru" && lang != "en" {
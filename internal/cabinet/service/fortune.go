@@ -525,7 +525,7 @@ func (s *FortuneService) Spin(ctx context.Context, accountID int64) (*FortuneSpi
 			apply = false
 		}
 		if apply {
-			if err := s.promo.UpsertPendingDiscount(ctx, tx, cust.ID, promoID, rewardVal, nil, true, database.PendingDiscountUnlimitedPayments); err != nil {
+			if err := s.promo.UpsertPendingDiscount(ctx, tx, cust.ID, promoID, rewardVal, 0, nil, true, database.PendingDiscountUnlimitedPayments); err != nil {
 				return nil, err
 			}
 		}
@@ -14,6 +14,7 @@ import (
 	"remnawave-tg-shop-bot/internal/config"
 	"remnawave-tg-shop-bot/internal/database"
 	"remnawave-tg-shop-bot/internal/loyalty"
+	"remnawave-tg-shop-bot/internal/payment"
 	"remnawave-tg-shop-bot/internal/remnawave"
 	"remnawave-tg-shop-bot/utils"
 )
@@ -39,6 +40,18 @@ type Subscription struct {
 	rw        *remnawave.Client
 	purchases *database.PurchaseRepository
 	fortune   *repository.FortuneRepo // опционально nil: слияние истории XP с колесом не выполняется
+	autoRenew AutoRenewManager        // опционально nil: блок auto_renew не отдаётся
+}
+
+// AutoRenewManager — автопродление с сохранённой картой (реализует payment.PaymentService).
+type AutoRenewManager interface {
+	AutoRenewStatus(ctx context.Context, customerID int64) (payment.AutoRenewStatus, error)
+	SetAutoRenew(ctx context.Context, customerID int64, enabled bool) error
+}
+
+// SetAutoRenewManager подключает автопродление (без платёжного сервиса кабинет работает и без него).
+func (s *Subscription) SetAutoRenewManager(m AutoRenewManager) {
+	s.autoRenew = m
 }
 
 // NewSubscription — конструктор. Все репозитории обязательны, кроме loyalty,
@@ -113,6 +126,19 @@ type SubscriptionResponse struct {
 	IsTrial bool `json:"is_trial"`
 	// HwidExtra — настройки и лимиты докупки HWID; nil если HWID_EXTRA_DEVICES_ENABLED=false.
 	HwidExtra *SubscriptionHwidExtra `json:"hwid_extra,omitempty"`
+	// AutoRenew — автопродление ЮKassa; nil если выключено в env и карты нет.
+	AutoRenew *SubscriptionAutoRenew `json:"auto_renew,omitempty"`
+}
+
+// SubscriptionAutoRenew — состояние автопродления для переключателя в SPA.
+type SubscriptionAutoRenew struct {
+	Available          bool       `json:"available"`
+	Enabled            bool       `json:"enabled"`
+	HasPaymentMethod   bool       `json:"has_payment_method"`
+	PaymentMethodTitle string     `json:"payment_method_title,omitempty"`
+	FailedAttempts     int        `json:"failed_attempts"`
+	NextAttemptAt      *time.Time `json:"next_attempt_at,omitempty"`
+	LastChargedAt      *time.Time `json:"last_charged_at,omitempty"`
 }
 
 type LoyaltyHistoryItem struct {
@@ -306,9 +332,56 @@ func (s *Subscription) Get(ctx context.Context, accountID int64) (*SubscriptionR
 		resp.HwidExtra = block
 	}
 
+	if block, err := s.autoRenewBlock(ctx, customer.ID); err != nil {
+		slog.Warn("subscription: auto renew status failed", "customer_id", customer.ID, "error", err)
+	} else {
+		resp.AutoRenew = block
+	}
+
 	return resp, nil
 }
 
+func (s *Subscription) autoRenewBlock(ctx context.Context, customerID int64) (*SubscriptionAutoRenew, error) {
+	if s.autoRenew == nil {
+		return nil, nil
+	}
+	st, err := s.autoRenew.AutoRenewStatus(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	if !st.Available && !st.HasMethod {
+		return nil, nil
+	}
+	return &SubscriptionAutoRenew{
+		Available:          st.Available,
+		Enabled:            st.Enabled,
+		HasPaymentMethod:   st.HasMethod,
+		PaymentMethodTitle: st.MethodTitle,
+		FailedAttempts:     st.FailedAttempts,
+		NextAttemptAt:      st.NextAttemptAt,
+		LastChargedAt:      st.LastChargedAt,
+	}, nil
+}
+
+// SetAutoRenew — PATCH /me/subscription {"auto_renew": bool}. Ошибки payment.ErrAutoRenewNoMethod /
+// payment.ErrAutoRenewUnavailable отдаются как есть — их маппит HTTP-слой.
+func (s *Subscription) SetAutoRenew(ctx context.Context, accountID int64, enabled bool) (*SubscriptionAutoRenew, error) {
+	if s.autoRenew == nil {
+		return nil, payment.ErrAutoRenewUnavailable
+	}
+	link, err := s.links.FindByAccountID(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, payment.ErrAutoRenewNoMethod
+		}
+		return nil, fmt.Errorf("subscription: find link: %w", err)
+	}
+	if err := s.autoRenew.SetAutoRenew(ctx, link.CustomerID, enabled); err != nil {
+		return nil, err
+	}
+	return s.autoRenewBlock(ctx, link.CustomerID)
+}
+
 func (s *Subscription) LoyaltyHistory(ctx context.Context, accountID int64, limit, offset int) (*LoyaltyHistoryResponse, error) {
 	if accountID <= 0 {
 		return nil, fmt.Errorf("subscription loyalty history: invalid account_id %d", accountID)
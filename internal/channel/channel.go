@@ -0,0 +1,98 @@
+// Package channel — доставка уведомлений клиенту по доступному каналу связи.
+//
+// Сообщение описывается в формате Telegram (HTML или текст с entities, inline-кнопки).
+// Клиенту с чатом в боте оно уходит в Telegram, web-only клиенту кабинета — письмом
+// на подтверждённый email привязанного аккаунта (см. Email).
+package channel
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/utils"
+)
+
+var (
+	// ErrNoChannel — клиенту нечем доставить сообщение (web-only без email или email-канал выключен).
+	ErrNoChannel = errors.New("channel: no delivery channel for customer")
+	// ErrUnsubscribed — клиент отписался от рекламных писем.
+	ErrUnsubscribed = errors.New("channel: customer unsubscribed from marketing email")
+)
+
+// Message — уведомление клиенту.
+type Message struct {
+	Subject   string           // тема письма; в Telegram не показывается
+	Text      string           // текст в ParseMode; пустой ParseMode — обычный текст (с Entities)
+	ParseMode models.ParseMode // models.ParseModeHTML или пусто
+	Entities  []models.MessageEntity
+	Media     *Media // в письмо не попадает
+	Keyboard  [][]models.InlineKeyboardButton
+	Marketing bool // рассылка: в письме ссылка отписки, отписавшимся письмо не уходит
+}
+
+// Media — фото или документ по file_id Telegram.
+type Media struct {
+	FileID  string
+	AsPhoto bool
+}
+
+// Recipient — адресат уведомления.
+type Recipient struct {
+	CustomerID int64
+	TelegramID int64
+	Language   string
+	WebOnly    bool
+}
+
+// RecipientOf — адресат-клиент.
+func RecipientOf(c *database.Customer) Recipient {
+	return Recipient{CustomerID: c.ID, TelegramID: c.TelegramID, Language: c.Language, WebOnly: c.IsWebOnly}
+}
+
+// HasTelegram — у адресата настоящий чат с ботом.
+func (r Recipient) HasTelegram() bool {
+	return !r.WebOnly && r.TelegramID > 0 && !utils.IsSyntheticTelegramID(r.TelegramID)
+}
+
+// Channel доставляет сообщение одним способом.
+type Channel interface {
+	Send(ctx context.Context, to Recipient, msg Message) error
+}
+
+// Router выбирает канал адресата: Telegram, если есть чат с ботом, иначе email.
+type Router struct {
+	telegram Channel
+	email    Channel
+}
+
+// NewRouter — конструктор. email == nil — SMTP не настроен, web-only клиентам доставки нет.
+func NewRouter(telegram, email Channel) *Router {
+	return &Router{telegram: telegram, email: email}
+}
+
+// Kind — канал, которым уйдёт сообщение адресату (database.DeliveryChannel*); "" — доставить нечем.
+func (r *Router) Kind(to Recipient) string {
+	if to.HasTelegram() {
+		return database.DeliveryChannelTelegram
+	}
+	if r.email != nil && to.CustomerID > 0 {
+		return database.DeliveryChannelEmail
+	}
+	return ""
+}
+
+// Send отправляет сообщение по каналу адресата. Ошибки Telegram возвращаются как есть
+// (рассылка различает 429 по *bot.TooManyRequestsError).
+func (r *Router) Send(ctx context.Context, to Recipient, msg Message) error {
+	switch r.Kind(to) {
+	case database.DeliveryChannelTelegram:
+		return r.telegram.Send(ctx, to, msg)
+	case database.DeliveryChannelEmail:
+		return r.email.Send(ctx, to, msg)
+	default:
+		return ErrNoChannel
+	}
+}
@@ -0,0 +1,53 @@
+package channel
+
+import (
+	"testing"
+
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/utils"
+)
+
+func TestRouterKind(t *testing.T) {
+	withEmail := NewRouter(&Telegram{}, &Email{})
+	telegramOnly := NewRouter(&Telegram{}, nil)
+	tg := Recipient{CustomerID: 1, TelegramID: 100}
+	web := Recipient{CustomerID: 2, TelegramID: utils.SyntheticTelegramID(5), WebOnly: true}
+
+	if got := withEmail.Kind(tg); got != database.DeliveryChannelTelegram {
+		t.Fatalf("telegram customer: %q", got)
+	}
+	if got := withEmail.Kind(web); got != database.DeliveryChannelEmail {
+		t.Fatalf("web-only customer: %q", got)
+	}
+	if got := telegramOnly.Kind(web); got != "" {
+		t.Fatalf("web-only customer without email channel: %q", got)
+	}
+}
+
+func TestEmailBody(t *testing.T) {
+	html := emailBody(Message{Text: "<b>Hi</b>\nthere", ParseMode: models.ParseModeHTML})
+	if html != "<b>Hi</b><br>\nthere" {
+		t.Fatalf("html: %q", html)
+	}
+	plain := emailBody(Message{Text: "a < b\nc"})
+	if plain != "a &lt; b<br>\nc" {
+		t.Fatalf("plain: %q", plain)
+	}
+}
+
+func TestEmailLinks(t *testing.T) {
+	e := NewEmail(nil, nil, "https://shop.example.com/", nil)
+	links := e.links("en", [][]models.InlineKeyboardButton{
+		{{Text: "Renew", WebApp: &models.WebAppInfo{URL: "https://app.example.com/cabinet/tariffs?x=1"}}},
+		{{Text: "Menu", CallbackData: "start"}, {Text: "Support", URL: "https://t.me/support"}},
+	})
+	if len(links) != 2 || links[0].URL != "https://shop.example.com/cabinet/tariffs?x=1" || links[1].URL != "https://t.me/support" {
+		t.Fatalf("links: %+v", links)
+	}
+	links = e.links("en", [][]models.InlineKeyboardButton{{{Text: "Buy", CallbackData: "buy"}}})
+	if len(links) != 1 || links[0].URL != "https://shop.example.com/cabinet" || links[0].Label != "Open cabinet" {
+		t.Fatalf("fallback: %+v", links)
+	}
+}
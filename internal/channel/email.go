@@ -0,0 +1,107 @@
+package channel
+
+import (
+	"context"
+	"html/template"
+	"net/url"
+	"strings"
+
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/cabinet/mail"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/utils"
+)
+
+type emailContacts interface {
+	FindEmailContact(ctx context.Context, customerID int64) (*database.EmailContact, error)
+}
+
+// Email отправляет сообщение письмом на подтверждённый email аккаунта кабинета, привязанного к клиенту.
+// Кнопки со ссылкой и WebApp становятся ссылками в письме (WebApp — на страницу кабинета по PublicURL),
+// callback-кнопки пропускаются; если ссылок не осталось, в письме — кнопка «Открыть кабинет».
+type Email struct {
+	contacts  emailContacts
+	mailer    *mail.Mailer
+	publicURL string
+	secret    []byte // подпись ссылки отписки (mail.UnsubscribeToken)
+}
+
+func NewEmail(contacts emailContacts, mailer *mail.Mailer, publicURL string, secret []byte) *Email {
+	return &Email{contacts: contacts, mailer: mailer, publicURL: strings.TrimSuffix(strings.TrimSpace(publicURL), "/"), secret: secret}
+}
+
+func (e *Email) Send(ctx context.Context, to Recipient, msg Message) error {
+	contact, err := e.contacts.FindEmailContact(ctx, to.CustomerID)
+	if err != nil {
+		return err
+	}
+	if contact == nil {
+		return ErrNoChannel
+	}
+	if msg.Marketing && contact.MarketingOptOut {
+		return ErrUnsubscribed
+	}
+	data := mail.NotificationData{
+		Title: msg.Subject,
+		Body:  emailBody(msg),
+		Links: e.links(to.Language, msg.Keyboard),
+	}
+	if msg.Marketing {
+		data.UnsubscribeURL = e.publicURL + mail.UnsubscribePath + "?token=" + url.QueryEscape(mail.UnsubscribeToken(e.secret, contact.AccountID))
+	}
+	return e.mailer.SendNotification(ctx, contact.Email, to.Language, data)
+}
+
+// emailBody — HTML письма из текста Telegram: разметка HTML сохраняется, обычный текст экранируется,
+// переводы строк становятся <br>.
+func emailBody(msg Message) template.HTML {
+	body := msg.Text
+	if msg.ParseMode != models.ParseModeHTML {
+		body = utils.MessageEntitiesToHTML(msg.Text, msg.Entities)
+	}
+	return template.HTML(strings.ReplaceAll(body, "\n", "<br>\n"))
+}
+
+func (e *Email) links(lang string, keyboard [][]models.InlineKeyboardButton) []mail.NotificationLink {
+	var out []mail.NotificationLink
+	for _, row := range keyboard {
+		for _, btn := range row {
+			var link string
+			switch {
+			case btn.URL != "":
+				link = btn.URL
+			case btn.WebApp != nil && btn.WebApp.URL != "":
+				link = e.cabinetURL(btn.WebApp.URL)
+			}
+			if link != "" {
+				out = append(out, mail.NotificationLink{Label: btn.Text, URL: link})
+			}
+		}
+	}
+	if len(out) == 0 && e.publicURL != "" {
+		out = append(out, mail.NotificationLink{Label: openCabinetLabel(lang), URL: e.publicURL + "/cabinet"})
+	}
+	return out
+}
+
+// cabinetURL переносит путь WebApp-ссылки Mini App на PublicURL кабинета: в браузере Mini App не откроется.
+func (e *Email) cabinetURL(webAppURL string) string {
+	u, err := url.Parse(webAppURL)
+	if err != nil || e.publicURL == "" {
+		return webAppURL
+	}
+	base, err := url.Parse(e.publicURL)
+	if err != nil {
+		return webAppURL
+	}
+	u.Scheme, u.Host, u.User = base.Scheme, base.Host, base.User
+	return u.String()
+}
+
+func openCabinetLabel(lang string) string {
+	if lang == "en" {
+		return "Open cabinet"
+	}
+	return "Открыть кабинет"
+}
@@ -0,0 +1,54 @@
+package channel
+
+import (
+	"context"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// Telegram отправляет сообщение от бота в чат клиента.
+type Telegram struct {
+	bot *bot.Bot
+}
+
+func NewTelegram(b *bot.Bot) *Telegram {
+	return &Telegram{bot: b}
+}
+
+func (t *Telegram) Send(ctx context.Context, to Recipient, msg Message) error {
+	var markup models.ReplyMarkup
+	if len(msg.Keyboard) > 0 {
+		markup = models.InlineKeyboardMarkup{InlineKeyboard: msg.Keyboard}
+	}
+	if msg.Media != nil {
+		if msg.Media.AsPhoto {
+			_, err := t.bot.SendPhoto(ctx, &bot.SendPhotoParams{
+				ChatID:          to.TelegramID,
+				Photo:           &models.InputFileString{Data: msg.Media.FileID},
+				Caption:         msg.Text,
+				ParseMode:       msg.ParseMode,
+				CaptionEntities: msg.Entities,
+				ReplyMarkup:     markup,
+			})
+			return err
+		}
+		_, err := t.bot.SendDocument(ctx, &bot.SendDocumentParams{
+			ChatID:          to.TelegramID,
+			Document:        &models.InputFileString{Data: msg.Media.FileID},
+			Caption:         msg.Text,
+			ParseMode:       msg.ParseMode,
+			CaptionEntities: msg.Entities,
+			ReplyMarkup:     markup,
+		})
+		return err
+	}
+	_, err := t.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      to.TelegramID,
+		Text:        msg.Text,
+		ParseMode:   msg.ParseMode,
+		Entities:    msg.Entities,
+		ReplyMarkup: markup,
+	})
+	return err
+}
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -20,7 +21,7 @@ type config struct {
 	remnawaveUrl, remnawaveToken, remnawaveMode, remnawaveTag                    string
 	defaultLanguage                                                              string
 	databaseURL                                                                  string
-	cryptoPayURL, cryptoPayToken                                                 string
+	cryptoPayURL, cryptoPayToken, cryptoPayWebhookURL                            string
 	botURL                                                                       string
 	yookasaURL, yookasaShopId, yookasaSecretKey, yookasaEmail, yookasaWebhookURL string
 	plategaMerchantID, plategaSecret, plategaWebhookURL                          string
@@ -46,10 +47,16 @@ type config struct {
 	termsOfServiceURL                                                            string
 	greetingImage                                                                string
 	isYookasaEnabled                                                             bool
+	yookasaAutoRenewEnabled                                                      bool
+	yookasaAutoRenewDaysBefore                                                   int
+	yookasaAutoRenewMaxAttempts                                                  int
+	yookasaAutoRenewRetryHours                                                   int
 	isCryptoEnabled                                                              bool
 	isTelegramStarsEnabled                                                       bool
+	telegramStarsSubscriptionEnabled                                             bool
 	isMoynalogEnabled                                                            bool
 	moynalogReceiptYookasa, moynalogReceiptPlatega, moynalogReceiptCrypto        bool // MOYNALOG_RECEIPT_FOR
+	moynalogReceiptMaxAttempts                                                   int
 	adminTelegramId                                                              int64
 	forwardUserMessagesToAdmin                                                   bool
 	trialDays                                                                    int
@@ -66,6 +73,29 @@ type config struct {
 	trialHwidLimit                                                               int
 	paidHwidLimit                                                                int
 	hwidExtraDevicesEnabled                                                      bool
+	giftsEnabled                                                                 bool
+	giftCodeTTLDays                                                              int
+	balanceEnabled                                                               bool
+	balanceTopUpMin                                                              int
+	balanceTopUpMax                                                              int
+	balanceTopUpPresets                                                          []int
+	referralRewardToBalance                                                      bool
+	referralPartnerEnabled                                                       bool
+	referralPartnerDefaultPercent                                                int
+	referralPartnerHoldDays                                                      int
+	referralPartnerMinPayout                                                     int
+	purchaseReconcileAfterMinutes                                                int
+	purchaseExpireHours                                                          int
+	purchaseOutboxMaxAttempts                                                    int
+	subscriptionPauseEnabled                                                     bool
+	subscriptionPauseMinDays                                                     int
+	subscriptionPauseMaxDays                                                     int
+	subscriptionPauseLimit                                                       int
+	subscriptionPausePeriodDays                                                  int
+	trafficPacksEnabled                                                          bool
+	trafficNotifyEnabled                                                         bool
+	trafficNotifyThresholds                                                      []int
+	remnawaveWebhookSecret, remnawaveWebhookPath                                 string
 	miniApp                                                                      string
 	enableAutoPayment                                                            bool
 	healthCheckPort                                                              int
@@ -84,8 +114,12 @@ type config struct {
 	remnawaveHeaders                                                             map[string]string
 	trafficLimitResetStrategy                                                    string
 	salesMode                                                                    string
-	showLongTermSavingsPercent                                                   bool    // подписи кнопок периодов: (-N%) к 3/6/12 мес относительно цены 1 мес
-	rubPerStar                                                                   float64 // рублей за 1 Star; 0 = не задано (подсказка Stars в админке отключена)
+	showLongTermSavingsPercent                                                   bool                       // подписи кнопок периодов: (-N%) к 3/6/12 мес относительно цены 1 мес
+	rubPerStar                                                                   float64                    // рублей за 1 Star; 0 = не задано (подсказка Stars в админке отключена)
+	priceCurrencies                                                              []string                   // доп. валюты цен (PRICE_CURRENCIES), без RUB и Stars
+	currencyPrices                                                               map[string]map[int]float64 // classic: PRICE_<M>_<CUR>
+	currencyRates                                                                map[string]float64         // рублей за единицу валюты (CURRENCY_RATES)
+	languageCurrencies                                                           map[string]string          // язык клиента → валюта (LANGUAGE_CURRENCY)
 	loyaltyEnabled                                                               bool
 	loyaltyMaxTotalDiscountPercent                                               int   // потолок суммы лояльность+промо (1–100)
 	loyaltyXPMinPerPurchase                                                      int64 // минимум XP за оплату если сумма не дала XP
@@ -225,6 +259,143 @@ func HwidExtraDevicesEnabled() bool {
 	return conf.hwidExtraDevicesEnabled
 }
 
+// GiftsEnabled — покупка подписки в подарок (кнопка в боте, /start gift_<код>).
+func GiftsEnabled() bool {
+	return conf.giftsEnabled
+}
+
+// GiftCodeTTLDays — сколько дней подарочный код можно активировать после оплаты.
+func GiftCodeTTLDays() int {
+	return conf.giftCodeTTLDays
+}
+
+// BalanceEnabled — внутренний баланс: пополнение и оплата подписок с баланса.
+func BalanceEnabled() bool {
+	return conf.balanceEnabled
+}
+
+// BalanceTopUpMin / BalanceTopUpMax — допустимая сумма одного пополнения, ₽.
+func BalanceTopUpMin() int {
+	return conf.balanceTopUpMin
+}
+
+func BalanceTopUpMax() int {
+	return conf.balanceTopUpMax
+}
+
+// BalanceTopUpPresets — суммы-кнопки пополнения в боте, ₽.
+func BalanceTopUpPresets() []int {
+	return conf.balanceTopUpPresets
+}
+
+// ReferralRewardToBalance — реферальные бонусы зачисляются на баланс рублями (дни × цена дня месячной подписки) вместо дней.
+func ReferralRewardToBalance() bool {
+	return conf.balanceEnabled && conf.referralRewardToBalance
+}
+
+// ReferralPartnerEnabled — денежная партнёрская программа: процент с оплат приглашённых и выплаты.
+func ReferralPartnerEnabled() bool {
+	return conf.referralPartnerEnabled
+}
+
+// ReferralPartnerDefaultPercent — комиссия реферера без персональной ставки; 0 — только назначенные партнёры.
+func ReferralPartnerDefaultPercent() int {
+	return conf.referralPartnerDefaultPercent
+}
+
+// ReferralPartnerHoldDays — сколько дней начисление «заморожено» до доступности к выплате (окно возвратов).
+func ReferralPartnerHoldDays() int {
+	return conf.referralPartnerHoldDays
+}
+
+// ReferralPartnerMinPayout — минимальная сумма запроса на выплату, ₽.
+func ReferralPartnerMinPayout() int {
+	return conf.referralPartnerMinPayout
+}
+
+// PurchaseReconcileAfterMinutes — возраст new/pending-покупки, после которого сверка спрашивает статус у провайдера; 0 — сверка выключена.
+func PurchaseReconcileAfterMinutes() int {
+	return conf.purchaseReconcileAfterMinutes
+}
+
+// PurchaseExpireHours — через сколько часов неоплаченный счёт закрывается как просроченный.
+func PurchaseExpireHours() int {
+	return conf.purchaseExpireHours
+}
+
+// PurchaseOutboxMaxAttempts — неудачных попыток шага проведения оплаты, после которых он уходит в failed.
+func PurchaseOutboxMaxAttempts() int {
+	return conf.purchaseOutboxMaxAttempts
+}
+
+// SubscriptionPauseEnabled — клиенты могут сами заморозить оплаченную подписку (бот и кабинет).
+func SubscriptionPauseEnabled() bool {
+	return conf.subscriptionPauseEnabled
+}
+
+// SubscriptionPauseMinDays — минимальная длина паузы, дней.
+func SubscriptionPauseMinDays() int {
+	return conf.subscriptionPauseMinDays
+}
+
+// SubscriptionPauseMaxDays — максимальная длина паузы, дней (не меньше минимальной).
+func SubscriptionPauseMaxDays() int {
+	if conf.subscriptionPauseMaxDays < conf.subscriptionPauseMinDays {
+		return conf.subscriptionPauseMinDays
+	}
+	return conf.subscriptionPauseMaxDays
+}
+
+// SubscriptionPauseLimit — сколько пауз можно взять за SubscriptionPausePeriodDays; 0 — без ограничения.
+func SubscriptionPauseLimit() int {
+	return conf.subscriptionPauseLimit
+}
+
+// SubscriptionPausePeriodDays — окно, за которое считается лимит пауз, дней.
+func SubscriptionPausePeriodDays() int {
+	return conf.subscriptionPausePeriodDays
+}
+
+// TrafficPacksEnabled — продажа пакетов трафика тарифа (бот, кабинет, уведомление об исчерпании трафика).
+func TrafficPacksEnabled() bool {
+	return conf.trafficPacksEnabled
+}
+
+// TrafficNotifyEnabled — уведомления о расходе трафика по порогам TrafficNotifyThresholds.
+func TrafficNotifyEnabled() bool {
+	return conf.trafficNotifyEnabled
+}
+
+// TrafficNotifyThresholds — пороги расхода трафика в процентах от лимита, по возрастанию.
+func TrafficNotifyThresholds() []int {
+	return append([]int(nil), conf.trafficNotifyThresholds...)
+}
+
+// parseTrafficNotifyThresholds разбирает «80,95,100»: проценты 1–100, без повторов, результат по возрастанию.
+func parseTrafficNotifyThresholds(value string) ([]int, error) {
+	seen := make(map[int]bool)
+	var out []int
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		v, err := strconv.Atoi(part)
+		if err != nil || v < 1 || v > 100 {
+			return nil, fmt.Errorf("invalid threshold %q: must be 1–100", part)
+		}
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("at least one threshold required")
+	}
+	sort.Ints(out)
+	return out, nil
+}
+
 func TrialHwidLimit() int {
 	return conf.trialHwidLimit
 }
@@ -449,12 +620,27 @@ func RemnawaveToken() string {
 func RemnawaveMode() string {
 	return conf.remnawaveMode
 }
+
+// RemnawaveWebhookSecret — секрет подписи вебхуков панели (WEBHOOK_SECRET_HEADER в Remnawave); пусто = приёмник выключен.
+func RemnawaveWebhookSecret() string {
+	return conf.remnawaveWebhookSecret
+}
+
+// RemnawaveWebhookPath — путь приёмника вебхуков панели на health-check сервере.
+func RemnawaveWebhookPath() string {
+	return conf.remnawaveWebhookPath
+}
 func CryptoPayUrl() string {
 	return conf.cryptoPayURL
 }
 func CryptoPayToken() string {
 	return conf.cryptoPayToken
 }
+
+// GetCryptoPayWebHookURL — путь вебхука Crypto Pay (invoice_paid); пусто = только поллинг.
+func GetCryptoPayWebHookURL() string {
+	return conf.cryptoPayWebhookURL
+}
 func BotURL() string {
 	return conf.botURL
 }
@@ -482,10 +668,35 @@ func IsYookasaEnabled() bool {
 	return conf.isYookasaEnabled
 }
 
+// YookasaAutoRenewEnabled — сохранять способ оплаты ЮKassa и списывать продление автоматически.
+func YookasaAutoRenewEnabled() bool {
+	return conf.isYookasaEnabled && conf.yookasaAutoRenewEnabled
+}
+
+// YookasaAutoRenewDaysBefore — за сколько дней до expire_at пробовать списание.
+func YookasaAutoRenewDaysBefore() int {
+	return conf.yookasaAutoRenewDaysBefore
+}
+
+// YookasaAutoRenewMaxAttempts — попыток списания подряд, после которых автопродление выключается.
+func YookasaAutoRenewMaxAttempts() int {
+	return conf.yookasaAutoRenewMaxAttempts
+}
+
+// YookasaAutoRenewRetryHours — пауза между неудачными попытками списания.
+func YookasaAutoRenewRetryHours() int {
+	return conf.yookasaAutoRenewRetryHours
+}
+
 func IsTelegramStarsEnabled() bool {
 	return conf.isTelegramStarsEnabled
 }
 
+// TelegramStarsSubscriptionEnabled — предлагать оплату Stars с ежемесячным автопродлением (подписка Telegram Stars).
+func TelegramStarsSubscriptionEnabled() bool {
+	return conf.telegramStarsSubscriptionEnabled
+}
+
 func RequirePaidPurchaseForStars() bool {
 	return conf.requirePaidPurchaseForStars
 }
@@ -550,6 +761,11 @@ func MoynalogReceiptForCrypto() bool {
 	return conf.moynalogReceiptCrypto
 }
 
+// MoynalogReceiptMaxAttempts — неудачных попыток отправки (или аннулирования) чека, после которых он уходит в failed.
+func MoynalogReceiptMaxAttempts() int {
+	return conf.moynalogReceiptMaxAttempts
+}
+
 const bytesInGigabyte = 1073741824
 
 func mustEnv(key string) string {
@@ -650,6 +866,25 @@ func parseMoynalogReceiptFor(raw string, hasKey bool) {
 	}
 }
 
+// parseBalanceTopUpPresets — «100,300,500» → суммы в пределах [BALANCE_TOPUP_MIN, BALANCE_TOPUP_MAX]; пусто — набор по умолчанию.
+func parseBalanceTopUpPresets(raw string) []int {
+	if strings.TrimSpace(raw) == "" {
+		raw = "100,300,500,1000"
+	}
+	var out []int
+	for _, part := range strings.Split(raw, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || v < conf.balanceTopUpMin || v > conf.balanceTopUpMax {
+			if strings.TrimSpace(part) != "" {
+				slog.Warn("BALANCE_TOPUP_PRESETS: value ignored", "value", part)
+			}
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
 // Lifecycle notifications
 func LifecycleNotifyEnabled() bool {
 	return conf.lifecycleNotifyEnabled
@@ -784,6 +1019,7 @@ func InitConfig() {
 		conf.starsPrice3 = envIntDefault("STARS_PRICE_3", conf.price3)
 		conf.starsPrice6 = envIntDefault("STARS_PRICE_6", conf.price6)
 		conf.starsPrice12 = envIntDefault("STARS_PRICE_12", conf.price12)
+		conf.telegramStarsSubscriptionEnabled = envBool("TELEGRAM_STARS_SUBSCRIPTION_ENABLED")
 
 	}
 	conf.requirePaidPurchaseForStars = envBool("REQUIRE_PAID_PURCHASE_FOR_STARS")
@@ -805,12 +1041,19 @@ func InitConfig() {
 
 	conf.remnawaveToken = mustEnv("REMNAWAVE_TOKEN")
 
+	conf.remnawaveWebhookSecret = os.Getenv("REMNAWAVE_WEBHOOK_SECRET")
+	conf.remnawaveWebhookPath = envStringDefault("REMNAWAVE_WEBHOOK_PATH", "/remnawave/webhook")
+	if !strings.HasPrefix(conf.remnawaveWebhookPath, "/") {
+		panic("REMNAWAVE_WEBHOOK_PATH must start with '/'")
+	}
+
 	conf.databaseURL = mustEnv("DATABASE_URL")
 
 	conf.isCryptoEnabled = envBool("CRYPTO_PAY_ENABLED")
 	if conf.isCryptoEnabled {
 		conf.cryptoPayURL = mustEnv("CRYPTO_PAY_URL")
 		conf.cryptoPayToken = mustEnv("CRYPTO_PAY_TOKEN")
+		conf.cryptoPayWebhookURL = strings.TrimSpace(os.Getenv("CRYPTO_PAY_WEBHOOK_URL"))
 	}
 
 	conf.isYookasaEnabled = envBool("YOOKASA_ENABLED")
@@ -820,6 +1063,19 @@ func InitConfig() {
 		conf.yookasaSecretKey = mustEnv("YOOKASA_SECRET_KEY")
 		conf.yookasaEmail = mustEnv("YOOKASA_EMAIL")
 		conf.yookasaWebhookURL = strings.TrimSpace(os.Getenv("YOOKASA_WEBHOOK_URL"))
+		conf.yookasaAutoRenewEnabled = envBool("YOOKASA_AUTORENEW_ENABLED")
+		conf.yookasaAutoRenewDaysBefore = envIntDefault("YOOKASA_AUTORENEW_DAYS_BEFORE", 1)
+		if conf.yookasaAutoRenewDaysBefore < 0 {
+			conf.yookasaAutoRenewDaysBefore = 0
+		}
+		conf.yookasaAutoRenewMaxAttempts = envIntDefault("YOOKASA_AUTORENEW_MAX_ATTEMPTS", 3)
+		if conf.yookasaAutoRenewMaxAttempts < 1 {
+			conf.yookasaAutoRenewMaxAttempts = 1
+		}
+		conf.yookasaAutoRenewRetryHours = envIntDefault("YOOKASA_AUTORENEW_RETRY_HOURS", 12)
+		if conf.yookasaAutoRenewRetryHours < 1 {
+			conf.yookasaAutoRenewRetryHours = 1
+		}
 	}
 
 	if envBool("PLATEGA_ENABLED") {
@@ -853,6 +1109,76 @@ func InitConfig() {
 	conf.paidHwidLimit = envIntDefault("PAID_HWID_LIMIT", 0)
 	conf.hwidExtraDevicesEnabled = envBoolDefault("HWID_EXTRA_DEVICES_ENABLED", true)
 
+	conf.giftsEnabled = envBool("GIFTS_ENABLED")
+	conf.giftCodeTTLDays = envIntDefault("GIFT_CODE_TTL_DAYS", 90)
+	if conf.giftCodeTTLDays < 1 {
+		conf.giftCodeTTLDays = 1
+	}
+
+	conf.balanceEnabled = envBool("BALANCE_ENABLED")
+	conf.balanceTopUpMin = envIntDefault("BALANCE_TOPUP_MIN", 100)
+	if conf.balanceTopUpMin < 1 {
+		conf.balanceTopUpMin = 1
+	}
+	conf.balanceTopUpMax = envIntDefault("BALANCE_TOPUP_MAX", 50000)
+	if conf.balanceTopUpMax < conf.balanceTopUpMin {
+		conf.balanceTopUpMax = conf.balanceTopUpMin
+	}
+	conf.balanceTopUpPresets = parseBalanceTopUpPresets(os.Getenv("BALANCE_TOPUP_PRESETS"))
+	conf.referralRewardToBalance = envBool("REFERRAL_REWARD_TO_BALANCE")
+
+	conf.referralPartnerEnabled = envBool("REFERRAL_PARTNER_ENABLED")
+	conf.referralPartnerDefaultPercent = envIntDefault("REFERRAL_PARTNER_DEFAULT_PERCENT", 0)
+	if conf.referralPartnerDefaultPercent < 0 || conf.referralPartnerDefaultPercent > 100 {
+		panic("REFERRAL_PARTNER_DEFAULT_PERCENT must be between 0 and 100")
+	}
+	conf.referralPartnerHoldDays = envIntDefault("REFERRAL_PARTNER_HOLD_DAYS", 14)
+	if conf.referralPartnerHoldDays < 0 {
+		conf.referralPartnerHoldDays = 0
+	}
+	conf.referralPartnerMinPayout = envIntDefault("REFERRAL_PARTNER_MIN_PAYOUT", 1000)
+	if conf.referralPartnerMinPayout < 1 {
+		conf.referralPartnerMinPayout = 1
+	}
+
+	conf.purchaseReconcileAfterMinutes = envIntDefault("PURCHASE_RECONCILE_AFTER_MINUTES", 30)
+	if conf.purchaseReconcileAfterMinutes < 0 {
+		conf.purchaseReconcileAfterMinutes = 0
+	}
+	conf.purchaseExpireHours = envIntDefault("PURCHASE_EXPIRE_HOURS", 24)
+	if conf.purchaseExpireHours < 1 {
+		conf.purchaseExpireHours = 1
+	}
+	conf.purchaseOutboxMaxAttempts = envIntDefault("PURCHASE_OUTBOX_MAX_ATTEMPTS", 12)
+	if conf.purchaseOutboxMaxAttempts < 1 {
+		conf.purchaseOutboxMaxAttempts = 1
+	}
+
+	conf.subscriptionPauseEnabled = envBool("SUBSCRIPTION_PAUSE_ENABLED")
+	conf.subscriptionPauseMinDays = envIntDefault("SUBSCRIPTION_PAUSE_MIN_DAYS", 7)
+	if conf.subscriptionPauseMinDays < 1 {
+		conf.subscriptionPauseMinDays = 1
+	}
+	conf.subscriptionPauseMaxDays = envIntDefault("SUBSCRIPTION_PAUSE_MAX_DAYS", 30)
+	if conf.subscriptionPauseMaxDays < conf.subscriptionPauseMinDays {
+		conf.subscriptionPauseMaxDays = conf.subscriptionPauseMinDays
+	}
+	conf.subscriptionPauseLimit = envIntDefault("SUBSCRIPTION_PAUSE_LIMIT", 1)
+	if conf.subscriptionPauseLimit < 0 {
+		conf.subscriptionPauseLimit = 0
+	}
+	conf.subscriptionPausePeriodDays = envIntDefault("SUBSCRIPTION_PAUSE_PERIOD_DAYS", 365)
+	if conf.subscriptionPausePeriodDays < 1 {
+		conf.subscriptionPausePeriodDays = 1
+	}
+	conf.trafficPacksEnabled = envBool("TRAFFIC_PACKS_ENABLED")
+	conf.trafficNotifyEnabled = envBool("TRAFFIC_NOTIFY_ENABLED")
+	thresholds, err := parseTrafficNotifyThresholds(envStringDefault("TRAFFIC_NOTIFY_THRESHOLDS", "80,95,100"))
+	if err != nil {
+		panic(fmt.Sprintf("TRAFFIC_NOTIFY_THRESHOLDS: %v", err))
+	}
+	conf.trafficNotifyThresholds = thresholds
+
 	conf.serverStatusURL = os.Getenv("SERVER_STATUS_URL")
 	conf.supportURL = os.Getenv("SUPPORT_URL")
 	conf.supportBotAPIEnabled = envBoolDefault("SUPPORT_BOT_API", false)
@@ -1029,6 +1355,10 @@ func InitConfig() {
 		conf.moynalogPassword = mustEnv("MOYNALOG_PASSWORD")
 		rawReceiptFor, hasReceiptFor := os.LookupEnv("MOYNALOG_RECEIPT_FOR")
 		parseMoynalogReceiptFor(rawReceiptFor, hasReceiptFor)
+		conf.moynalogReceiptMaxAttempts = envIntDefault("MOYNALOG_RECEIPT_MAX_ATTEMPTS", 10)
+		if conf.moynalogReceiptMaxAttempts < 1 {
+			conf.moynalogReceiptMaxAttempts = 1
+		}
 	}
 
 	conf.telegramProxyURL = envStringDefault("TELEGRAM_PROXY_URL", "")
@@ -1052,6 +1382,8 @@ func InitConfig() {
 		return f
 	}()
 
+	loadCurrencies()
+
 	conf.loyaltyEnabled = envBoolDefault("LOYALTY_ENABLED", false)
 	conf.loyaltyMaxTotalDiscountPercent = envIntDefault("LOYALTY_MAX_TOTAL_DISCOUNT_PERCENT", 100)
 	if conf.loyaltyMaxTotalDiscountPercent < 1 {
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Коды валют, которые бот считает отдельно от ISO-валют из PRICE_CURRENCIES.
+const (
+	CurrencyRUB   = "RUB"
+	CurrencyStars = "STARS"
+)
+
+// NormalizeCurrency — код валюты в верхнем регистре; пусто и RUR — рубли, XTR — Telegram Stars.
+func NormalizeCurrency(code string) string {
+	c := strings.ToUpper(strings.TrimSpace(code))
+	switch c {
+	case "", "RUR":
+		return CurrencyRUB
+	case "XTR":
+		return CurrencyStars
+	default:
+		return c
+	}
+}
+
+// PriceCurrencies — дополнительные валюты цен (PRICE_CURRENCIES) в заданном порядке.
+func PriceCurrencies() []string {
+	return append([]string(nil), conf.priceCurrencies...)
+}
+
+// IsPriceCurrency — валюта входит в PRICE_CURRENCIES.
+func IsPriceCurrency(currency string) bool {
+	c := NormalizeCurrency(currency)
+	for _, v := range conf.priceCurrencies {
+		if v == c {
+			return true
+		}
+	}
+	return false
+}
+
+// CurrencyPrice — цена classic-подписки за month месяцев в валюте из PRICE_CURRENCIES (PRICE_<M>_<CUR>); 0 — не задана.
+func CurrencyPrice(month int, currency string) float64 {
+	return conf.currencyPrices[NormalizeCurrency(currency)][month]
+}
+
+// CurrencyRateRub — сколько рублей в единице валюты: RUB — 1, STARS — RUB_PER_STAR, остальные — CURRENCY_RATES.
+// 0 — курс не задан.
+func CurrencyRateRub(currency string) float64 {
+	switch c := NormalizeCurrency(currency); c {
+	case CurrencyRUB:
+		return 1
+	case CurrencyStars:
+		return conf.rubPerStar
+	default:
+		return conf.currencyRates[c]
+	}
+}
+
+// CurrencyForLanguage — валюта клиента по его языку (LANGUAGE_CURRENCY); пусто — не задана.
+func CurrencyForLanguage(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		lang = lang[:i]
+	}
+	return conf.languageCurrencies[lang]
+}
+
+// loadCurrencies читает PRICE_CURRENCIES, PRICE_<M>_<CUR>, CURRENCY_RATES и LANGUAGE_CURRENCY.
+func loadCurrencies() {
+	conf.priceCurrencies = parseCurrencyList(os.Getenv("PRICE_CURRENCIES"))
+	conf.currencyPrices = make(map[string]map[int]float64, len(conf.priceCurrencies))
+	for _, c := range conf.priceCurrencies {
+		prices := make(map[int]float64, 4)
+		for _, m := range []int{1, 3, 6, 12} {
+			key := fmt.Sprintf("PRICE_%d_%s", m, c)
+			if v := parseMoney(os.Getenv(key)); v > 0 {
+				prices[m] = v
+			} else if strings.TrimSpace(os.Getenv(key)) != "" {
+				slog.Warn("invalid currency price, ignored", "key", key)
+			}
+		}
+		conf.currencyPrices[c] = prices
+	}
+	conf.currencyRates = parseCurrencyRates(os.Getenv("CURRENCY_RATES"))
+	conf.languageCurrencies = parseLanguageCurrencies(os.Getenv("LANGUAGE_CURRENCY"))
+}
+
+// parseCurrencyList — «usd, EUR» → [USD EUR]; RUB, Stars и повторы пропускаются.
+func parseCurrencyList(raw string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		c := NormalizeCurrency(part)
+		if c == CurrencyRUB || c == CurrencyStars || seen[c] {
+			continue
+		}
+		seen[c] = true
+		out = append(out, c)
+	}
+	return out
+}
+
+// parseCurrencyRates — «USD:92.5,EUR:100» → рублей за единицу валюты.
+func parseCurrencyRates(raw string) map[string]float64 {
+	out := make(map[string]float64)
+	for _, pair := range strings.Split(raw, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) != 2 {
+			if strings.TrimSpace(pair) != "" {
+				slog.Warn("CURRENCY_RATES: value ignored", "value", pair)
+			}
+			continue
+		}
+		c := NormalizeCurrency(parts[0])
+		rate := parseMoney(parts[1])
+		if c == CurrencyRUB || c == CurrencyStars || rate <= 0 {
+			slog.Warn("CURRENCY_RATES: value ignored", "value", pair)
+			continue
+		}
+		out[c] = rate
+	}
+	return out
+}
+
+// parseLanguageCurrencies — «en:USD,de:EUR» → язык → валюта.
+func parseLanguageCurrencies(raw string) map[string]string {
+	out := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			if strings.TrimSpace(pair) != "" {
+				slog.Warn("LANGUAGE_CURRENCY: value ignored", "value", pair)
+			}
+			continue
+		}
+		out[strings.ToLower(strings.TrimSpace(parts[0]))] = NormalizeCurrency(parts[1])
+	}
+	return out
+}
+
+func parseMoney(raw string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil || v <= 0 {
+		return 0
+	}
+	return v
+}
@@ -0,0 +1,32 @@
+package config
+
+import "testing"
+
+func TestParseCurrencyList(t *testing.T) {
+	got := parseCurrencyList(" usd, EUR,rub,XTR,usd,")
+	if len(got) != 2 || got[0] != "USD" || got[1] != "EUR" {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestParseCurrencyRates(t *testing.T) {
+	got := parseCurrencyRates("usd:92.5, EUR:100,RUB:1,GBP:x,bad")
+	if len(got) != 2 || got["USD"] != 92.5 || got["EUR"] != 100 {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestCurrencyForLanguage(t *testing.T) {
+	orig := conf.languageCurrencies
+	t.Cleanup(func() { conf.languageCurrencies = orig })
+	conf.languageCurrencies = parseLanguageCurrencies("en:usd,De:EUR")
+	if c := CurrencyForLanguage("en-US"); c != "USD" {
+		t.Fatalf("en-US: got %q", c)
+	}
+	if c := CurrencyForLanguage("de"); c != "EUR" {
+		t.Fatalf("de: got %q", c)
+	}
+	if c := CurrencyForLanguage("ru"); c != "" {
+		t.Fatalf("ru: got %q", c)
+	}
+}
@@ -185,6 +185,37 @@ func RuntimeSettingsRegistry() []SettingField {
 			Current: func() string { return conf.trialRemnawaveTag },
 		},
 
+		// --- pause ---
+		{
+			Key: "SUBSCRIPTION_PAUSE_ENABLED", Group: "pause", Type: SettingBool, Instant: true,
+			Apply:  applyBoolField(func(v bool) { conf.subscriptionPauseEnabled = v }),
+			Current: func() string { return boolStr(conf.subscriptionPauseEnabled) },
+		},
+		{
+			Key: "SUBSCRIPTION_PAUSE_MIN_DAYS", Group: "pause", Type: SettingInt,
+			MinInt: intPtr(1),
+			Apply:  applyIntField(func(v int) error { conf.subscriptionPauseMinDays = v; return nil }),
+			Current: func() string { return strconv.Itoa(conf.subscriptionPauseMinDays) },
+		},
+		{
+			Key: "SUBSCRIPTION_PAUSE_MAX_DAYS", Group: "pause", Type: SettingInt,
+			MinInt: intPtr(1),
+			Apply:  applyIntField(func(v int) error { conf.subscriptionPauseMaxDays = v; return nil }),
+			Current: func() string { return strconv.Itoa(conf.subscriptionPauseMaxDays) },
+		},
+		{
+			Key: "SUBSCRIPTION_PAUSE_LIMIT", Group: "pause", Type: SettingInt,
+			MinInt: intPtr(0),
+			Apply:  applyIntField(func(v int) error { conf.subscriptionPauseLimit = v; return nil }),
+			Current: func() string { return strconv.Itoa(conf.subscriptionPauseLimit) },
+		},
+		{
+			Key: "SUBSCRIPTION_PAUSE_PERIOD_DAYS", Group: "pause", Type: SettingInt,
+			MinInt: intPtr(1),
+			Apply:  applyIntField(func(v int) error { conf.subscriptionPausePeriodDays = v; return nil }),
+			Current: func() string { return strconv.Itoa(conf.subscriptionPausePeriodDays) },
+		},
+
 		// --- hwid ---
 		{
 			Key: "HWID_EXTRA_DEVICES_ENABLED", Group: "hwid", Type: SettingBool, Instant: true,
@@ -319,6 +350,11 @@ func RuntimeSettingsRegistry() []SettingField {
 			Apply:      applyCabinetTariffPriceDisplay(),
 			Current:    cabinetTariffPriceDisplayCurrent(),
 		},
+		{
+			Key: "TRAFFIC_PACKS_ENABLED", Group: "tariffs", Type: SettingBool, Instant: true,
+			Apply:  applyBoolField(func(v bool) { conf.trafficPacksEnabled = v }),
+			Current: func() string { return boolStr(conf.trafficPacksEnabled) },
+		},
 
 		// --- lifecycle (без cron / master toggle) ---
 		{
@@ -382,6 +418,29 @@ func RuntimeSettingsRegistry() []SettingField {
 			Apply:  applyStringField(func(v string) { conf.lifecycleSupportContact = v }),
 			Current: func() string { return conf.lifecycleSupportContact },
 		},
+		{
+			Key: "TRAFFIC_NOTIFY_ENABLED", Group: "lifecycle", Type: SettingBool, Instant: true,
+			Apply:   applyBoolField(func(v bool) { conf.trafficNotifyEnabled = v }),
+			Current: func() string { return boolStr(conf.trafficNotifyEnabled) },
+		},
+		{
+			Key: "TRAFFIC_NOTIFY_THRESHOLDS", Group: "lifecycle", Type: SettingCSVInt,
+			Apply: func(value string) error {
+				thresholds, err := parseTrafficNotifyThresholds(value)
+				if err != nil {
+					return err
+				}
+				conf.trafficNotifyThresholds = thresholds
+				return nil
+			},
+			Current: func() string {
+				parts := make([]string, 0, len(conf.trafficNotifyThresholds))
+				for _, v := range conf.trafficNotifyThresholds {
+					parts = append(parts, strconv.Itoa(v))
+				}
+				return strings.Join(parts, ",")
+			},
+		},
 
 		// --- links ---
 		{Key: "CHANNEL_URL", Group: "links", Type: SettingURL, Apply: applyStringField(func(v string) { conf.channelURL = v }), Current: func() string { return conf.channelURL }},
@@ -0,0 +1,21 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTrafficNotifyThresholds(t *testing.T) {
+	got, err := parseTrafficNotifyThresholds(" 95, 80,100,80 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{80, 95, 100}) {
+		t.Fatalf("got %v", got)
+	}
+	for _, bad := range []string{"", "0", "101", "80,abc"} {
+		if _, err := parseTrafficNotifyThresholds(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
@@ -55,6 +55,11 @@ func (r InvoiceResponse) IsPaid() bool {
 	return r.Status == "paid"
 }
 
+// IsExpired — счёт просрочен (expires_in истёк), оплатить его уже нельзя.
+func (r InvoiceResponse) IsExpired() bool {
+	return r.Status == "expired"
+}
+
 type ResponseWrapper[T any] struct {
 	Ok     bool `json:"ok"`
 	Result T    `json:"result"`
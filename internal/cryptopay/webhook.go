@@ -0,0 +1,188 @@
+package cryptopay
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/database"
+)
+
+// SignatureHeader — HMAC-SHA256 тела запроса; ключ — SHA256 от API-токена приложения.
+const SignatureHeader = "crypto-pay-api-signature"
+
+const updateTypeInvoicePaid = "invoice_paid"
+
+type PurchaseProcessor interface {
+	ProcessPurchaseById(ctx context.Context, purchaseId int64) error
+}
+
+// PaidContextFunc обогащает ctx перед ProcessPurchaseById (username, мета для уведомлений).
+// Пакет payment импортирует cryptopay, поэтому сборку ctx передаёт вызывающий код.
+type PaidContextFunc func(ctx context.Context, invoice InvoiceResponse) context.Context
+
+type WebhookHandler struct {
+	token        string
+	purchaseRepo *database.PurchaseRepository
+	processor    PurchaseProcessor
+	paidContext  PaidContextFunc
+	// inFlight — счета, которые сейчас обрабатываются: параллельный повтор invoice_paid
+	// получает 503 и будет переотправлен, когда покупка уже paid.
+	inFlight sync.Map
+}
+
+func NewWebhookHandler(token string, purchaseRepo *database.PurchaseRepository, processor PurchaseProcessor, paidContext PaidContextFunc) *WebhookHandler {
+	return &WebhookHandler{
+		token:        token,
+		purchaseRepo: purchaseRepo,
+		processor:    processor,
+		paidContext:  paidContext,
+	}
+}
+
+type webhookUpdate struct {
+	UpdateID    int64           `json:"update_id"`
+	UpdateType  string          `json:"update_type"`
+	RequestDate string          `json:"request_date"`
+	Payload     InvoiceResponse `json:"payload"`
+}
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.token == "" {
+		http.Error(w, "cryptopay not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		slog.Error("cryptopay webhook: read body error", "error", err)
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if !VerifySignature(h.token, body, r.Header.Get(SignatureHeader)) {
+		slog.Warn("cryptopay webhook: invalid signature")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var upd webhookUpdate
+	if err := json.Unmarshal(body, &upd); err != nil {
+		slog.Error("cryptopay webhook: unmarshal error", "error", err, "payload", string(body))
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	slog.Info("cryptopay webhook received", "update_id", upd.UpdateID, "update_type", upd.UpdateType, "invoice_id", upd.Payload.InvoiceID)
+
+	if upd.UpdateType != updateTypeInvoicePaid || !upd.Payload.IsPaid() || upd.Payload.InvoiceID == nil {
+		slog.Debug("cryptopay webhook: ignored update", "update_type", upd.UpdateType, "status", upd.Payload.Status)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	invoiceID := *upd.Payload.InvoiceID
+
+	purchaseID, _, ok := ParseInvoicePayload(upd.Payload.Payload)
+	if !ok {
+		slog.Warn("cryptopay webhook: missing purchaseId in payload", "invoice_id", invoiceID, "payload", upd.Payload.Payload)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if _, busy := h.inFlight.LoadOrStore(invoiceID, struct{}{}); busy {
+		slog.Info("cryptopay webhook: invoice is being processed, asking for retry", "invoice_id", invoiceID)
+		http.Error(w, "processing", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.inFlight.Delete(invoiceID)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	defer cancel()
+
+	purchase, err := h.purchaseRepo.FindById(ctx, purchaseID)
+	if err != nil {
+		slog.Error("cryptopay webhook: find purchase failed", "purchase_id", purchaseID, "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if purchase == nil {
+		slog.Warn("cryptopay webhook: purchase not found", "purchase_id", purchaseID)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if purchase.InvoiceType != database.InvoiceTypeCrypto || purchase.CryptoInvoiceID == nil || *purchase.CryptoInvoiceID != invoiceID {
+		slog.Warn("cryptopay webhook: invoice does not match purchase", "purchase_id", purchaseID, "invoice_id", invoiceID)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if purchase.Status != database.PurchaseStatusPending && purchase.Status != database.PurchaseStatusNew {
+		// Повтор invoice_paid после успешной обработки — отвечаем 200, чтобы Crypto Pay перестал слать.
+		slog.Info("cryptopay webhook: purchase already finalized", "purchase_id", purchaseID, "status", purchase.Status)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	paidCtx := ctx
+	if h.paidContext != nil {
+		paidCtx = h.paidContext(ctx, upd.Payload)
+	}
+	if err := h.processor.ProcessPurchaseById(paidCtx, purchaseID); err != nil {
+		slog.Error("cryptopay webhook: process purchase failed", "purchase_id", purchaseID, "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	slog.Info("cryptopay webhook: invoice processed", "invoice_id", invoiceID, "purchase_id", purchaseID)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// VerifySignature сверяет заголовок crypto-pay-api-signature: hex(HMAC-SHA256(key=SHA256(token), body)).
+func VerifySignature(token string, body []byte, signature string) bool {
+	signature = strings.TrimSpace(signature)
+	if token == "" || signature == "" {
+		return false
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	secret := sha256.Sum256([]byte(token))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), got)
+}
+
+// ParseInvoicePayload разбирает payload счёта вида "purchaseId=123&username=user".
+func ParseInvoicePayload(payload string) (purchaseID int64, username string, ok bool) {
+	for _, part := range strings.Split(payload, "&") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "purchaseId":
+			id, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil || id <= 0 {
+				return 0, "", false
+			}
+			purchaseID = id
+		case "username":
+			username = kv[1]
+		}
+	}
+	return purchaseID, username, purchaseID > 0
+}
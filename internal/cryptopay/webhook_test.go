@@ -0,0 +1,43 @@
+package cryptopay
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestVerifySignature(t *testing.T) {
+	token := "1234:AAA"
+	body := []byte(`{"update_id":1,"update_type":"invoice_paid"}`)
+	secret := sha256.Sum256([]byte(token))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write(body)
+	sig := hex.EncodeToString(mac.Sum(nil))
+
+	if !VerifySignature(token, body, sig) {
+		t.Fatal("valid signature rejected")
+	}
+	if VerifySignature("other", body, sig) {
+		t.Fatal("signature with wrong token accepted")
+	}
+	if VerifySignature(token, append(body, ' '), sig) {
+		t.Fatal("signature for modified body accepted")
+	}
+	if VerifySignature(token, body, "") {
+		t.Fatal("empty signature accepted")
+	}
+}
+
+func TestParseInvoicePayload(t *testing.T) {
+	id, username, ok := ParseInvoicePayload("purchaseId=42&username=john")
+	if !ok || id != 42 || username != "john" {
+		t.Fatalf("got %d %q %v", id, username, ok)
+	}
+	if _, _, ok := ParseInvoicePayload("username=john"); ok {
+		t.Fatal("payload without purchaseId accepted")
+	}
+	if _, _, ok := ParseInvoicePayload("purchaseId=abc"); ok {
+		t.Fatal("non-numeric purchaseId accepted")
+	}
+}
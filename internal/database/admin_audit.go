@@ -0,0 +1,140 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// AdminAuditEntry — запись журнала действий сотрудника; Before/After — JSON-объекты или nil.
+type AdminAuditEntry struct {
+	ID              int64           `db:"id"`
+	CreatedAt       time.Time       `db:"created_at"`
+	Source          string          `db:"source"`
+	ActorTelegramID *int64          `db:"actor_telegram_id"`
+	ActorAccountID  *int64          `db:"actor_account_id"`
+	Action          string          `db:"action"`
+	CustomerID      *int64          `db:"customer_id"`
+	TargetType      string          `db:"target_type"`
+	TargetID        string          `db:"target_id"`
+	Before          json.RawMessage `db:"before_state"`
+	After           json.RawMessage `db:"after_state"`
+}
+
+// AdminAuditFilter — фильтр журнала; нулевые поля не ограничивают выборку.
+// Action с точкой на конце («user.») — префикс.
+type AdminAuditFilter struct {
+	CustomerID      int64
+	ActorTelegramID int64
+	Source          string
+	Action          string
+	TargetType      string
+	From            *time.Time
+	To              *time.Time
+	Limit           int
+	Offset          int
+}
+
+const adminAuditColumns = "id, created_at, source, actor_telegram_id, actor_account_id, action, customer_id, target_type, target_id, before_state, after_state"
+
+type AdminAuditRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAdminAuditRepository(pool *pgxpool.Pool) *AdminAuditRepository {
+	return &AdminAuditRepository{pool: pool}
+}
+
+// Insert добавляет запись в журнал.
+func (r *AdminAuditRepository) Insert(ctx context.Context, e AdminAuditEntry) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO admin_audit (source, actor_telegram_id, actor_account_id, action, customer_id, target_type, target_id, before_state, after_state)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		e.Source, e.ActorTelegramID, e.ActorAccountID, e.Action, e.CustomerID, e.TargetType, e.TargetID,
+		nullableJSON(e.Before), nullableJSON(e.After))
+	if err != nil {
+		return fmt.Errorf("failed to insert admin audit: %w", err)
+	}
+	return nil
+}
+
+// List — записи журнала по фильтру, новые первыми, и общее число подходящих записей.
+func (r *AdminAuditRepository) List(ctx context.Context, f AdminAuditFilter) ([]AdminAuditEntry, int64, error) {
+	where := sq.And{}
+	if f.CustomerID > 0 {
+		where = append(where, sq.Eq{"customer_id": f.CustomerID})
+	}
+	if f.ActorTelegramID > 0 {
+		where = append(where, sq.Eq{"actor_telegram_id": f.ActorTelegramID})
+	}
+	if f.Source != "" {
+		where = append(where, sq.Eq{"source": f.Source})
+	}
+	if f.Action != "" {
+		if strings.HasSuffix(f.Action, ".") {
+			where = append(where, sq.Like{"action": strings.ReplaceAll(f.Action, "_", `\_`) + "%"})
+		} else {
+			where = append(where, sq.Eq{"action": f.Action})
+		}
+	}
+	if f.TargetType != "" {
+		where = append(where, sq.Eq{"target_type": f.TargetType})
+	}
+	if f.From != nil {
+		where = append(where, sq.GtOrEq{"created_at": *f.From})
+	}
+	if f.To != nil {
+		where = append(where, sq.Lt{"created_at": *f.To})
+	}
+	if f.Limit <= 0 || f.Limit > 200 {
+		f.Limit = 50
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+
+	countQuery, countArgs, err := sq.Select("COUNT(*)").From("admin_audit").Where(where).PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to build admin audit count query: %w", err)
+	}
+	var total int64
+	if err := r.pool.QueryRow(ctx, countQuery, countArgs...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count admin audit: %w", err)
+	}
+
+	query, args, err := sq.Select(adminAuditColumns).From("admin_audit").Where(where).
+		OrderBy("created_at DESC", "id DESC").
+		Limit(uint64(f.Limit)).Offset(uint64(f.Offset)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to build admin audit query: %w", err)
+	}
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list admin audit: %w", err)
+	}
+	defer rows.Close()
+
+	var out []AdminAuditEntry
+	for rows.Next() {
+		var e AdminAuditEntry
+		if err := rows.Scan(&e.ID, &e.CreatedAt, &e.Source, &e.ActorTelegramID, &e.ActorAccountID, &e.Action,
+			&e.CustomerID, &e.TargetType, &e.TargetID, &e.Before, &e.After); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan admin audit: %w", err)
+		}
+		out = append(out, e)
+	}
+	return out, total, rows.Err()
+}
+
+func nullableJSON(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
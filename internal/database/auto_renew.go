@@ -0,0 +1,204 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// CustomerAutoRenew — сохранённый способ оплаты клиента и состояние автосписаний.
+type CustomerAutoRenew struct {
+	CustomerID         int64       `db:"customer_id"`
+	InvoiceType        InvoiceType `db:"invoice_type"`
+	PaymentMethodID    string      `db:"payment_method_id"`
+	PaymentMethodTitle *string     `db:"payment_method_title"`
+	Enabled            bool        `db:"enabled"`
+	Months             int         `db:"months"`
+	TariffID           *int64      `db:"tariff_id"`
+	FailedAttempts     int         `db:"failed_attempts"`
+	NextAttemptAt      *time.Time  `db:"next_attempt_at"`
+	LastError          *string     `db:"last_error"`
+	LastChargedAt      *time.Time  `db:"last_charged_at"`
+	CreatedAt          time.Time   `db:"created_at"`
+	UpdatedAt          time.Time   `db:"updated_at"`
+}
+
+// AutoRenewDue — автопродление вместе с датой окончания подписки клиента (выборка для cron).
+type AutoRenewDue struct {
+	CustomerAutoRenew
+	ExpireAt time.Time
+}
+
+const customerAutoRenewColumns = "customer_id, invoice_type, payment_method_id, payment_method_title, enabled, months, tariff_id, " +
+	"failed_attempts, next_attempt_at, last_error, last_charged_at, created_at, updated_at"
+
+func customerAutoRenewScanArgs(r *CustomerAutoRenew) []interface{} {
+	return []interface{}{
+		&r.CustomerID, &r.InvoiceType, &r.PaymentMethodID, &r.PaymentMethodTitle, &r.Enabled, &r.Months, &r.TariffID,
+		&r.FailedAttempts, &r.NextAttemptAt, &r.LastError, &r.LastChargedAt, &r.CreatedAt, &r.UpdatedAt,
+	}
+}
+
+type AutoRenewRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAutoRenewRepository(pool *pgxpool.Pool) *AutoRenewRepository {
+	return &AutoRenewRepository{pool: pool}
+}
+
+// SavePaymentMethod сохраняет (или заменяет) способ оплаты клиента и включает автопродление.
+// Счётчик неудач сбрасывается: новая карта — новая попытка.
+func (r *AutoRenewRepository) SavePaymentMethod(ctx context.Context, ar *CustomerAutoRenew) error {
+	query := `
+		INSERT INTO customer_auto_renew (customer_id, invoice_type, payment_method_id, payment_method_title, enabled, months, tariff_id)
+		VALUES ($1, $2, $3, $4, TRUE, $5, $6)
+		ON CONFLICT (customer_id) DO UPDATE SET
+			invoice_type = EXCLUDED.invoice_type,
+			payment_method_id = EXCLUDED.payment_method_id,
+			payment_method_title = EXCLUDED.payment_method_title,
+			enabled = TRUE,
+			months = EXCLUDED.months,
+			tariff_id = EXCLUDED.tariff_id,
+			failed_attempts = 0,
+			next_attempt_at = NULL,
+			last_error = NULL,
+			updated_at = NOW()`
+	_, err := r.pool.Exec(ctx, query, ar.CustomerID, ar.InvoiceType, ar.PaymentMethodID, ar.PaymentMethodTitle, ar.Months, ar.TariffID)
+	if err != nil {
+		return fmt.Errorf("failed to save auto renew payment method: %w", err)
+	}
+	return nil
+}
+
+func (r *AutoRenewRepository) FindByCustomerID(ctx context.Context, customerID int64) (*CustomerAutoRenew, error) {
+	query, args, err := sq.Select(customerAutoRenewColumns).
+		From("customer_auto_renew").
+		Where(sq.Eq{"customer_id": customerID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+	var ar CustomerAutoRenew
+	if err := r.pool.QueryRow(ctx, query, args...).Scan(customerAutoRenewScanArgs(&ar)...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query auto renew: %w", err)
+	}
+	return &ar, nil
+}
+
+// SetEnabled переключает автопродление. Включение сбрасывает счётчик неудач.
+// Возвращает false, если у клиента нет сохранённого способа оплаты.
+func (r *AutoRenewRepository) SetEnabled(ctx context.Context, customerID int64, enabled bool) (bool, error) {
+	b := sq.Update("customer_auto_renew").
+		Set("enabled", enabled).
+		Set("updated_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"customer_id": customerID}).
+		PlaceholderFormat(sq.Dollar)
+	if enabled {
+		b = b.Set("failed_attempts", 0).Set("next_attempt_at", nil).Set("last_error", nil)
+	}
+	query, args, err := b.ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build update query: %w", err)
+	}
+	tag, err := r.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to update auto renew: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Delete забывает сохранённый способ оплаты.
+func (r *AutoRenewRepository) Delete(ctx context.Context, customerID int64) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM customer_auto_renew WHERE customer_id = $1`, customerID); err != nil {
+		return fmt.Errorf("failed to delete auto renew: %w", err)
+	}
+	return nil
+}
+
+// FindDue — включённые автопродления, у которых подписка кончается в [expireFrom, expireTo]
+// и подошло время очередной попытки.
+func (r *AutoRenewRepository) FindDue(ctx context.Context, expireFrom, expireTo, now time.Time, limit int) ([]AutoRenewDue, error) {
+	query, args, err := sq.Select("ar.customer_id, ar.invoice_type, ar.payment_method_id, ar.payment_method_title, ar.enabled, ar.months, ar.tariff_id, "+
+		"ar.failed_attempts, ar.next_attempt_at, ar.last_error, ar.last_charged_at, ar.created_at, ar.updated_at", "c.expire_at").
+		From("customer_auto_renew ar").
+		Join("customer c ON c.id = ar.customer_id").
+		Where(sq.And{
+			sq.Eq{"ar.enabled": true},
+			sq.NotEq{"c.expire_at": nil},
+			sq.GtOrEq{"c.expire_at": expireFrom},
+			sq.LtOrEq{"c.expire_at": expireTo},
+			sq.Or{sq.Eq{"ar.next_attempt_at": nil}, sq.LtOrEq{"ar.next_attempt_at": now}},
+		}).
+		OrderBy("c.expire_at ASC").
+		Limit(uint64(limit)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due auto renewals: %w", err)
+	}
+	defer rows.Close()
+	var out []AutoRenewDue
+	for rows.Next() {
+		var d AutoRenewDue
+		if err := rows.Scan(append(customerAutoRenewScanArgs(&d.CustomerAutoRenew), &d.ExpireAt)...); err != nil {
+			return nil, fmt.Errorf("failed to scan auto renew: %w", err)
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating auto renew rows: %w", err)
+	}
+	return out, nil
+}
+
+// Claim атомарно откладывает следующую попытку до until. false — попытку уже забрал параллельный запуск.
+func (r *AutoRenewRepository) Claim(ctx context.Context, customerID int64, now, until time.Time) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE customer_auto_renew SET next_attempt_at = $3, updated_at = NOW()
+		WHERE customer_id = $1 AND enabled AND (next_attempt_at IS NULL OR next_attempt_at <= $2)`,
+		customerID, now, until)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim auto renew: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RecordSuccess сбрасывает счётчик неудач после успешного списания.
+func (r *AutoRenewRepository) RecordSuccess(ctx context.Context, customerID int64) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE customer_auto_renew SET failed_attempts = 0, next_attempt_at = NULL, last_error = NULL,
+			last_charged_at = NOW(), updated_at = NOW()
+		WHERE customer_id = $1`, customerID)
+	if err != nil {
+		return fmt.Errorf("failed to record auto renew success: %w", err)
+	}
+	return nil
+}
+
+// RecordFailure увеличивает счётчик неудач; disable=true выключает автопродление (попытки исчерпаны, карта отозвана).
+func (r *AutoRenewRepository) RecordFailure(ctx context.Context, customerID int64, errText string, nextAttemptAt *time.Time, disable bool) (int, error) {
+	var attempts int
+	err := r.pool.QueryRow(ctx, `
+		UPDATE customer_auto_renew SET failed_attempts = failed_attempts + 1, last_error = $2,
+			next_attempt_at = $3, enabled = enabled AND NOT $4, updated_at = NOW()
+		WHERE customer_id = $1
+		RETURNING failed_attempts`, customerID, errText, nextAttemptAt, disable).Scan(&attempts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record auto renew failure: %w", err)
+	}
+	return attempts, nil
+}
@@ -0,0 +1,174 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// BalanceTxType — вид операции по балансу клиента.
+type BalanceTxType string
+
+const (
+	BalanceTxTopUp    BalanceTxType = "topup"
+	BalanceTxPurchase BalanceTxType = "purchase"
+	BalanceTxRefund   BalanceTxType = "refund"
+	BalanceTxReferral BalanceTxType = "referral"
+	BalanceTxAdmin    BalanceTxType = "admin"
+)
+
+var (
+	// ErrBalanceInsufficient — списание увело бы баланс в минус.
+	ErrBalanceInsufficient = errors.New("insufficient balance")
+	// ErrBalanceTxDuplicate — операция по этой покупке (возврату) уже проведена.
+	ErrBalanceTxDuplicate = errors.New("balance operation already applied")
+)
+
+// BalanceTx — строка журнала customer_balance_tx. Amount со знаком: + зачисление, − списание.
+type BalanceTx struct {
+	ID              int64         `db:"id"`
+	CustomerID      int64         `db:"customer_id"`
+	Type            BalanceTxType `db:"type"`
+	Amount          float64       `db:"amount"`
+	BalanceAfter    float64       `db:"balance_after"`
+	PurchaseID      *int64        `db:"purchase_id"`
+	RefundID        *int64        `db:"refund_id"`
+	Comment         *string       `db:"comment"`
+	AdminTelegramID *int64        `db:"admin_telegram_id"`
+	AdminAccountID  *int64        `db:"admin_account_id"`
+	CreatedAt       time.Time     `db:"created_at"`
+}
+
+const balanceTxColumns = "id, customer_id, type, amount::float8, balance_after::float8, purchase_id, refund_id, comment, " +
+	"admin_telegram_id, admin_account_id, created_at"
+
+func balanceTxScanArgs(t *BalanceTx) []interface{} {
+	return []interface{}{
+		&t.ID, &t.CustomerID, &t.Type, &t.Amount, &t.BalanceAfter, &t.PurchaseID, &t.RefundID, &t.Comment,
+		&t.AdminTelegramID, &t.AdminAccountID, &t.CreatedAt,
+	}
+}
+
+type BalanceRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewBalanceRepository(pool *pgxpool.Pool) *BalanceRepository {
+	return &BalanceRepository{pool: pool}
+}
+
+// Get — текущий баланс клиента; 0, если операций ещё не было.
+func (r *BalanceRepository) Get(ctx context.Context, customerID int64) (float64, error) {
+	var balance float64
+	err := r.pool.QueryRow(ctx, `SELECT balance::float8 FROM customer_balance WHERE customer_id = $1`, customerID).Scan(&balance)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to query balance: %w", err)
+	}
+	return balance, nil
+}
+
+// Apply меняет баланс на t.Amount и пишет строку журнала в одной транзакции.
+// ErrBalanceInsufficient — списание больше остатка; ErrBalanceTxDuplicate — операция по покупке/возврату уже была.
+func (r *BalanceRepository) Apply(ctx context.Context, t *BalanceTx) (*BalanceTx, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `INSERT INTO customer_balance (customer_id) VALUES ($1) ON CONFLICT (customer_id) DO NOTHING`, t.CustomerID); err != nil {
+		return nil, fmt.Errorf("failed to init balance: %w", err)
+	}
+	var balanceAfter float64
+	err = tx.QueryRow(ctx, `
+		UPDATE customer_balance SET balance = balance + $2, updated_at = NOW()
+		WHERE customer_id = $1 AND balance + $2 >= 0
+		RETURNING balance::float8`, t.CustomerID, t.Amount).Scan(&balanceAfter)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrBalanceInsufficient
+		}
+		return nil, fmt.Errorf("failed to update balance: %w", err)
+	}
+
+	var out BalanceTx
+	err = tx.QueryRow(ctx, `
+		INSERT INTO customer_balance_tx (customer_id, type, amount, balance_after, purchase_id, refund_id, comment, admin_telegram_id, admin_account_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+balanceTxColumns,
+		t.CustomerID, t.Type, t.Amount, balanceAfter, t.PurchaseID, t.RefundID, t.Comment, t.AdminTelegramID, t.AdminAccountID).
+		Scan(balanceTxScanArgs(&out)...)
+	if err != nil {
+		if strings.Contains(err.Error(), "23505") {
+			return nil, ErrBalanceTxDuplicate
+		}
+		return nil, fmt.Errorf("failed to insert balance tx: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit balance tx: %w", err)
+	}
+	return &out, nil
+}
+
+// FindByPurchase — операция данного типа по покупке; nil, если её не было.
+func (r *BalanceRepository) FindByPurchase(ctx context.Context, purchaseID int64, txType BalanceTxType) (*BalanceTx, error) {
+	query, args, err := sq.Select(balanceTxColumns).
+		From("customer_balance_tx").
+		Where(sq.Eq{"purchase_id": purchaseID, "type": txType}).
+		OrderBy("id DESC").
+		Limit(1).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+	var t BalanceTx
+	if err := r.pool.QueryRow(ctx, query, args...).Scan(balanceTxScanArgs(&t)...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query balance tx: %w", err)
+	}
+	return &t, nil
+}
+
+// ListByCustomer — журнал операций клиента (новые сверху).
+func (r *BalanceRepository) ListByCustomer(ctx context.Context, customerID int64, limit, offset int) ([]BalanceTx, error) {
+	query, args, err := sq.Select(balanceTxColumns).
+		From("customer_balance_tx").
+		Where(sq.Eq{"customer_id": customerID}).
+		OrderBy("created_at DESC", "id DESC").
+		Limit(uint64(limit)).
+		Offset(uint64(offset)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query balance tx: %w", err)
+	}
+	defer rows.Close()
+	var out []BalanceTx
+	for rows.Next() {
+		var t BalanceTx
+		if err := rows.Scan(balanceTxScanArgs(&t)...); err != nil {
+			return nil, fmt.Errorf("failed to scan balance tx: %w", err)
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating balance tx rows: %w", err)
+	}
+	return out, nil
+}
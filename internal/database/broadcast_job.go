@@ -0,0 +1,358 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type BroadcastJobStatus string
+
+const (
+	BroadcastJobStatusScheduled BroadcastJobStatus = "scheduled"
+	BroadcastJobStatusRunning   BroadcastJobStatus = "running"
+	BroadcastJobStatusPaused    BroadcastJobStatus = "paused"
+	BroadcastJobStatusCanceled  BroadcastJobStatus = "canceled"
+	BroadcastJobStatusDone      BroadcastJobStatus = "done"
+)
+
+// ErrBroadcastJobState — текущий статус рассылки не допускает действие (пауза завершённой и т. п.).
+var ErrBroadcastJobState = errors.New("broadcast job state does not allow this action")
+
+// BroadcastJob — рассылка (broadcast_job). Entities и Buttons — JSON, их формат знает пакет broadcast.
+type BroadcastJob struct {
+	ID                  int64              `db:"id"`
+	Status              BroadcastJobStatus `db:"status"`
+	Source              string             `db:"source"`
+	CreatedByTelegramID *int64             `db:"created_by_telegram_id"`
+	CreatedByAccountID  *int64             `db:"created_by_account_id"`
+	NotifyChatID        *int64             `db:"notify_chat_id"`
+	Audience            string             `db:"audience"`
+	TariffID            *int64             `db:"tariff_id"`
+	SegmentID           *int64             `db:"segment_id"`
+	SegmentFilter       []byte             `db:"segment_filter"` // снимок SegmentFilter; задан — Audience = "segment"
+	MessageText         string             `db:"message_text"`
+	Entities            []byte             `db:"entities"`
+	MediaFileID         *string            `db:"media_file_id"`
+	MediaAsPhoto        bool               `db:"media_as_photo"`
+	Buttons             []byte             `db:"buttons"`
+	ScheduledAt         time.Time          `db:"scheduled_at"`
+	StartedAt           *time.Time         `db:"started_at"`
+	FinishedAt          *time.Time         `db:"finished_at"`
+	TotalCount          int                `db:"total_count"`
+	SentCount           int                `db:"sent_count"`
+	FailedCount         int                `db:"failed_count"`
+	CreatedAt           time.Time          `db:"created_at"`
+	UpdatedAt           time.Time          `db:"updated_at"`
+}
+
+// BroadcastDelivery — получатель рассылки, ожидающий отправки.
+type BroadcastDelivery struct {
+	JobID      int64
+	TelegramID int64
+	Language   string
+	Attempts   int
+	Channel    string // DeliveryChannelTelegram | DeliveryChannelEmail
+	CustomerID int64  // 0 — доставки, созданные до появления email-канала
+}
+
+const broadcastJobColumns = "id, status, source, created_by_telegram_id, created_by_account_id, notify_chat_id, audience, " +
+	"tariff_id, segment_id, segment_filter, message_text, entities, media_file_id, media_as_photo, buttons, scheduled_at, started_at, finished_at, " +
+	"total_count, sent_count, failed_count, created_at, updated_at"
+
+func broadcastJobScanArgs(j *BroadcastJob) []interface{} {
+	return []interface{}{
+		&j.ID, &j.Status, &j.Source, &j.CreatedByTelegramID, &j.CreatedByAccountID, &j.NotifyChatID, &j.Audience,
+		&j.TariffID, &j.SegmentID, &j.SegmentFilter, &j.MessageText, &j.Entities, &j.MediaFileID, &j.MediaAsPhoto, &j.Buttons, &j.ScheduledAt, &j.StartedAt, &j.FinishedAt,
+		&j.TotalCount, &j.SentCount, &j.FailedCount, &j.CreatedAt, &j.UpdatedAt,
+	}
+}
+
+type BroadcastJobRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewBroadcastJobRepository(pool *pgxpool.Pool) *BroadcastJobRepository {
+	return &BroadcastJobRepository{pool: pool}
+}
+
+func (r *BroadcastJobRepository) queryOne(ctx context.Context, q pgx.Row) (*BroadcastJob, error) {
+	var out BroadcastJob
+	if err := q.Scan(broadcastJobScanArgs(&out)...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Create сохраняет рассылку в статусе scheduled; воркер запустит её в ScheduledAt.
+func (r *BroadcastJobRepository) Create(ctx context.Context, j BroadcastJob) (*BroadcastJob, error) {
+	buttons := j.Buttons
+	if len(buttons) == 0 {
+		buttons = []byte("{}")
+	}
+	out, err := r.queryOne(ctx, r.pool.QueryRow(ctx, `
+		INSERT INTO broadcast_job (source, created_by_telegram_id, created_by_account_id, notify_chat_id, audience, tariff_id,
+			segment_id, segment_filter, message_text, entities, media_file_id, media_as_photo, buttons, scheduled_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8::jsonb, $9, $10::jsonb, $11, $12, $13::jsonb, $14)
+		RETURNING `+broadcastJobColumns,
+		j.Source, j.CreatedByTelegramID, j.CreatedByAccountID, j.NotifyChatID, j.Audience, j.TariffID,
+		j.SegmentID, nullableJSON(j.SegmentFilter), j.MessageText, nullableJSON(j.Entities), j.MediaFileID, j.MediaAsPhoto, string(buttons), j.ScheduledAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create broadcast job: %w", err)
+	}
+	return out, nil
+}
+
+func (r *BroadcastJobRepository) FindByID(ctx context.Context, id int64) (*BroadcastJob, error) {
+	out, err := r.queryOne(ctx, r.pool.QueryRow(ctx, `SELECT `+broadcastJobColumns+` FROM broadcast_job WHERE id = $1`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find broadcast job: %w", err)
+	}
+	return out, nil
+}
+
+// List — рассылки, новые первыми, и их общее число.
+func (r *BroadcastJobRepository) List(ctx context.Context, limit, offset int) ([]BroadcastJob, int64, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	var total int64
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM broadcast_job`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count broadcast jobs: %w", err)
+	}
+	query, args, err := sq.Select(broadcastJobColumns).From("broadcast_job").
+		OrderBy("created_at DESC", "id DESC").
+		Limit(uint64(limit)).Offset(uint64(offset)).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to build broadcast job query: %w", err)
+	}
+	out, err := r.list(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
+// ListByStatus — рассылки в статусе status в порядке создания.
+func (r *BroadcastJobRepository) ListByStatus(ctx context.Context, status BroadcastJobStatus) ([]BroadcastJob, error) {
+	return r.list(ctx, `SELECT `+broadcastJobColumns+` FROM broadcast_job WHERE status = $1 ORDER BY id`, status)
+}
+
+// FindDue — запланированные рассылки, время которых наступило.
+func (r *BroadcastJobRepository) FindDue(ctx context.Context, now time.Time) ([]BroadcastJob, error) {
+	return r.list(ctx, `SELECT `+broadcastJobColumns+` FROM broadcast_job
+		WHERE status = 'scheduled' AND scheduled_at <= $1 ORDER BY scheduled_at, id`, now)
+}
+
+func (r *BroadcastJobRepository) list(ctx context.Context, query string, args ...interface{}) ([]BroadcastJob, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query broadcast jobs: %w", err)
+	}
+	defer rows.Close()
+	var out []BroadcastJob
+	for rows.Next() {
+		var j BroadcastJob
+		if err := rows.Scan(broadcastJobScanArgs(&j)...); err != nil {
+			return nil, fmt.Errorf("failed to scan broadcast job: %w", err)
+		}
+		out = append(out, j)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating broadcast jobs: %w", err)
+	}
+	return out, nil
+}
+
+// Start переводит запланированную рассылку в running и сохраняет получателей одной транзакцией.
+// nil — рассылку уже запустили, поставили на паузу или отменили.
+func (r *BroadcastJobRepository) Start(ctx context.Context, id int64, recipients []BroadcastRecipient, now time.Time) (*BroadcastJob, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var locked int64
+	err = tx.QueryRow(ctx, `SELECT id FROM broadcast_job WHERE id = $1 AND status = 'scheduled' FOR UPDATE`, id).Scan(&locked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock broadcast job: %w", err)
+	}
+
+	ids := make([]int64, len(recipients))
+	langs := make([]string, len(recipients))
+	customerIDs := make([]int64, len(recipients))
+	channels := make([]string, len(recipients))
+	for i, rec := range recipients {
+		ids[i] = rec.TelegramID
+		langs[i] = rec.Language
+		customerIDs[i] = rec.CustomerID
+		channels[i] = DeliveryChannelTelegram
+		if rec.WebOnly {
+			channels[i] = DeliveryChannelEmail
+		}
+	}
+	if len(ids) > 0 {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO broadcast_delivery (job_id, telegram_id, language, next_attempt_at, customer_id, channel)
+			SELECT $1, t.telegram_id, t.language, $4, NULLIF(t.customer_id, 0), t.channel
+			FROM unnest($2::bigint[], $3::text[], $5::bigint[], $6::text[]) AS t (telegram_id, language, customer_id, channel)
+			ON CONFLICT (job_id, telegram_id) DO NOTHING`, id, ids, langs, now, customerIDs, channels); err != nil {
+			return nil, fmt.Errorf("failed to insert broadcast deliveries: %w", err)
+		}
+	}
+	out, err := r.queryOne(ctx, tx.QueryRow(ctx, `
+		UPDATE broadcast_job SET status = 'running', started_at = $2, updated_at = NOW(),
+			total_count = (SELECT COUNT(*) FROM broadcast_delivery WHERE job_id = $1)
+		WHERE id = $1
+		RETURNING `+broadcastJobColumns, id, now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to start broadcast job: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return out, nil
+}
+
+// DueDeliveries — до limit получателей рассылки, которым пора отправлять. Воркер один на процесс,
+// поэтому строки не блокируются: после сбоя неотмеченные получатели просто берутся снова.
+func (r *BroadcastJobRepository) DueDeliveries(ctx context.Context, jobID int64, now time.Time, limit int) ([]BroadcastDelivery, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT job_id, telegram_id, language, attempts, channel, COALESCE(customer_id, 0) FROM broadcast_delivery
+		WHERE job_id = $1 AND status = 'pending' AND next_attempt_at <= $2
+		ORDER BY next_attempt_at, telegram_id
+		LIMIT $3`, jobID, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query broadcast deliveries: %w", err)
+	}
+	defer rows.Close()
+	var out []BroadcastDelivery
+	for rows.Next() {
+		var d BroadcastDelivery
+		if err := rows.Scan(&d.JobID, &d.TelegramID, &d.Language, &d.Attempts, &d.Channel, &d.CustomerID); err != nil {
+			return nil, fmt.Errorf("failed to scan broadcast delivery: %w", err)
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating broadcast deliveries: %w", err)
+	}
+	return out, nil
+}
+
+// MarkSent — сообщение доставлено; счётчик рассылки увеличивается в том же запросе.
+func (r *BroadcastJobRepository) MarkSent(ctx context.Context, jobID, telegramID int64) error {
+	_, err := r.pool.Exec(ctx, `
+		WITH d AS (
+			UPDATE broadcast_delivery SET status = 'sent', attempts = attempts + 1, last_error = NULL, sent_at = NOW()
+			WHERE job_id = $1 AND telegram_id = $2 AND status = 'pending'
+			RETURNING job_id
+		)
+		UPDATE broadcast_job SET sent_count = sent_count + 1, updated_at = NOW()
+		WHERE id IN (SELECT job_id FROM d)`, jobID, telegramID)
+	if err != nil {
+		return fmt.Errorf("failed to mark broadcast delivery sent: %w", err)
+	}
+	return nil
+}
+
+// MarkFailed — доставка не удалась окончательно (бот заблокирован, чат не найден, исчерпаны повторы).
+func (r *BroadcastJobRepository) MarkFailed(ctx context.Context, jobID, telegramID int64, errText string) error {
+	_, err := r.pool.Exec(ctx, `
+		WITH d AS (
+			UPDATE broadcast_delivery SET status = 'failed', attempts = attempts + 1, last_error = $3
+			WHERE job_id = $1 AND telegram_id = $2 AND status = 'pending'
+			RETURNING job_id
+		)
+		UPDATE broadcast_job SET failed_count = failed_count + 1, updated_at = NOW()
+		WHERE id IN (SELECT job_id FROM d)`, jobID, telegramID, errText)
+	if err != nil {
+		return fmt.Errorf("failed to mark broadcast delivery failed: %w", err)
+	}
+	return nil
+}
+
+// Postpone переносит попытку на nextAttemptAt (Telegram ответил 429).
+func (r *BroadcastJobRepository) Postpone(ctx context.Context, jobID, telegramID int64, errText string, nextAttemptAt time.Time) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE broadcast_delivery SET attempts = attempts + 1, last_error = $3, next_attempt_at = $4
+		WHERE job_id = $1 AND telegram_id = $2 AND status = 'pending'`, jobID, telegramID, errText, nextAttemptAt)
+	if err != nil {
+		return fmt.Errorf("failed to postpone broadcast delivery: %w", err)
+	}
+	return nil
+}
+
+// Finish завершает рассылку, если у неё не осталось получателей в pending. nil — рассылка не завершена
+// (остались получатели или её уже поставили на паузу / отменили).
+func (r *BroadcastJobRepository) Finish(ctx context.Context, id int64) (*BroadcastJob, error) {
+	out, err := r.queryOne(ctx, r.pool.QueryRow(ctx, `
+		UPDATE broadcast_job SET status = 'done', finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'running'
+			AND NOT EXISTS (SELECT 1 FROM broadcast_delivery WHERE job_id = $1 AND status = 'pending')
+		RETURNING `+broadcastJobColumns, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to finish broadcast job: %w", err)
+	}
+	return out, nil
+}
+
+// Pause останавливает запланированную или идущую рассылку. nil, nil — рассылки нет.
+func (r *BroadcastJobRepository) Pause(ctx context.Context, id int64) (*BroadcastJob, error) {
+	return r.transition(ctx, id, `
+		UPDATE broadcast_job SET status = 'paused', updated_at = NOW()
+		WHERE id = $1 AND status IN ('scheduled', 'running')
+		RETURNING `+broadcastJobColumns)
+}
+
+// Resume возобновляет рассылку с паузы: ещё не запущенная снова ждёт scheduled_at, начатая продолжает отправку.
+func (r *BroadcastJobRepository) Resume(ctx context.Context, id int64) (*BroadcastJob, error) {
+	return r.transition(ctx, id, `
+		UPDATE broadcast_job SET status = CASE WHEN started_at IS NULL THEN 'scheduled' ELSE 'running' END, updated_at = NOW()
+		WHERE id = $1 AND status = 'paused'
+		RETURNING `+broadcastJobColumns)
+}
+
+// Cancel отменяет рассылку; неотправленные получатели закрываются как canceled.
+func (r *BroadcastJobRepository) Cancel(ctx context.Context, id int64) (*BroadcastJob, error) {
+	return r.transition(ctx, id, `
+		WITH d AS (
+			UPDATE broadcast_delivery SET status = 'canceled'
+			WHERE job_id = $1 AND status = 'pending'
+				AND EXISTS (SELECT 1 FROM broadcast_job WHERE id = $1 AND status IN ('scheduled', 'running', 'paused'))
+		)
+		UPDATE broadcast_job SET status = 'canceled', finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status IN ('scheduled', 'running', 'paused')
+		RETURNING `+broadcastJobColumns)
+}
+
+func (r *BroadcastJobRepository) transition(ctx context.Context, id int64, query string) (*BroadcastJob, error) {
+	out, err := r.queryOne(ctx, r.pool.QueryRow(ctx, query, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update broadcast job: %w", err)
+	}
+	if out != nil {
+		return out, nil
+	}
+	cur, err := r.FindByID(ctx, id)
+	if err != nil || cur == nil {
+		return nil, err
+	}
+	return nil, ErrBroadcastJobState
+}
@@ -24,7 +24,7 @@ func NewCustomerRepository(poll *pgxpool.Pool) *CustomerRepository {
 
 // customerSelectColumns порядок полей для SELECT (не использовать * — совместимость со схемой).
 // Порядок столбцов синхронизирован со всеми Scan-вызовами и с struct Customer.
-const customerSelectColumns = "id, telegram_id, expire_at, created_at, subscription_link, language, extra_hwid, extra_hwid_expires_at, current_tariff_id, subscription_period_start, subscription_period_months, loyalty_xp, telegram_username, is_web_only, legal_accepted_at"
+const customerSelectColumns = "id, telegram_id, expire_at, created_at, subscription_link, language, extra_hwid, extra_hwid_expires_at, current_tariff_id, subscription_period_start, subscription_period_months, loyalty_xp, telegram_username, is_web_only, legal_accepted_at, acquisition_source, trial_activated_at, paused_until"
 
 type Customer struct {
 	ID                       int64      `db:"id"`
@@ -46,6 +46,12 @@ type Customer struct {
 	IsWebOnly bool `db:"is_web_only"`
 	// LegalAcceptedAt — момент принятия политики/оферты в Telegram-боте (NULL = gate).
 	LegalAcceptedAt *time.Time `db:"legal_accepted_at"`
+	// AcquisitionSource — метка источника привлечения (src_<метка> / utm_*), пишется при первом контакте.
+	AcquisitionSource *string `db:"acquisition_source"`
+	// TrialActivatedAt — первая активация триала (кнопка или промокод).
+	TrialActivatedAt *time.Time `db:"trial_activated_at"`
+	// PausedUntil — подписка на паузе до этого момента (NULL — не на паузе), см. subscription_pause.
+	PausedUntil *time.Time `db:"paused_until"`
 }
 
 func scanCustomer(sc interface{ Scan(dest ...any) error }, c *Customer) error {
@@ -65,13 +71,19 @@ func scanCustomer(sc interface{ Scan(dest ...any) error }, c *Customer) error {
 		&c.TelegramUsername,
 		&c.IsWebOnly,
 		&c.LegalAcceptedAt,
+		&c.AcquisitionSource,
+		&c.TrialActivatedAt,
+		&c.PausedUntil,
 	)
 }
 
 // BroadcastRecipient is a Telegram user with language for localized broadcast keyboards.
+// WebOnly — клиент кабинета без Telegram: рассылка уходит ему на email.
 type BroadcastRecipient struct {
 	TelegramID int64
 	Language   string
+	CustomerID int64
+	WebOnly    bool
 }
 
 // Broadcast audience filters for GetBroadcastRecipients.
@@ -267,6 +279,21 @@ func (cr *CustomerRepository) SetLegalAcceptedAt(ctx context.Context, customerID
 	})
 }
 
+// SetAcquisitionSource записывает источник привлечения, если он ещё не задан (первый контакт выигрывает).
+func (cr *CustomerRepository) SetAcquisitionSource(ctx context.Context, customerID int64, source string, utm map[string]string) error {
+	var utmArg interface{}
+	if len(utm) > 0 {
+		utmArg = utm
+	}
+	_, err := cr.pool.Exec(ctx, `
+UPDATE customer SET acquisition_source = $2, acquisition_utm = $3
+WHERE id = $1 AND acquisition_source IS NULL`, customerID, source, utmArg)
+	if err != nil {
+		return fmt.Errorf("set acquisition source: %w", err)
+	}
+	return nil
+}
+
 // IncrementLoyaltyXP добавляет накопленный XP лояльности после успешной оплаты.
 func (cr *CustomerRepository) IncrementLoyaltyXP(ctx context.Context, customerID int64, delta int64) error {
 	if delta <= 0 {
@@ -282,6 +309,21 @@ func (cr *CustomerRepository) IncrementLoyaltyXP(ctx context.Context, customerID
 	return nil
 }
 
+// DecrementLoyaltyXP списывает XP лояльности (возврат оплаты); не уходит ниже нуля.
+func (cr *CustomerRepository) DecrementLoyaltyXP(ctx context.Context, customerID int64, delta int64) error {
+	if delta <= 0 {
+		return nil
+	}
+	res, err := cr.pool.Exec(ctx, `UPDATE customer SET loyalty_xp = GREATEST(loyalty_xp - $2, 0) WHERE id = $1`, customerID, delta)
+	if err != nil {
+		return fmt.Errorf("decrement loyalty_xp: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("customer not found: %d", customerID)
+	}
+	return nil
+}
+
 // DevCabinetResetTelegramToSynthetic вызывается только из dev-эндпоинта кабинета:
 // при реальном telegram_id у связанного customer подменяет его на SyntheticTelegramID(accountID),
 // обнуляет telegram_username и выставляет is_web_only (как у web-only клиента).
@@ -372,9 +414,12 @@ func (cr *CustomerRepository) UpdateFields(ctx context.Context, id int64, update
 		"subscription_period_start":  {},
 		"subscription_period_months": {},
 		"loyalty_xp":                 {},
+		"online_at":                  {},
 		"telegram_username":          {},
 		"is_web_only":                {},
 		"legal_accepted_at":          {},
+		"trial_activated_at":         {},
+		"paused_until":               {},
 	}
 
 	buildUpdate := sq.Update("customer").
@@ -734,13 +779,23 @@ func (cr *CustomerRepository) GetInactiveTelegramIds(ctx context.Context) ([]int
 
 // GetBroadcastRecipients returns telegram_id and language for mass broadcast (button labels per user).
 // tariffID ограничивает сегменты active_paid / inactive_paid по customer.current_tariff_id (режим tariffs).
-// Web-only клиенты кабинета исключаются (нет доставки в Telegram).
+// Web-only клиенты попадают в выборку, только если им можно отправить рассылку на email.
 func (cr *CustomerRepository) GetBroadcastRecipients(ctx context.Context, audience string, tariffID *int64) ([]BroadcastRecipient, error) {
-	now := time.Now()
-	buildSelect := sq.Select("telegram_id", "language").
+	cond, err := broadcastAudienceCondition(audience, tariffID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	buildSelect := sq.Select("telegram_id", "language", "id", "is_web_only").
 		From("customer").
 		PlaceholderFormat(sq.Dollar)
+	if cond != nil {
+		buildSelect = buildSelect.Where(cond)
+	}
+	return cr.queryBroadcastRecipients(ctx, buildSelect)
+}
 
+// broadcastAudienceCondition — условие WHERE для фиксированной аудитории рассылки; nil — без фильтра (all).
+func broadcastAudienceCondition(audience string, tariffID *int64, now time.Time) (sq.Sqlizer, error) {
 	activeVPN := sq.And{sq.NotEq{"expire_at": nil}, sq.Gt{"expire_at": now}}
 	inactiveVPN := sq.Or{sq.Eq{"expire_at": nil}, sq.LtOrEq{"expire_at": now}}
 	paidSubscription := sq.Expr(`EXISTS (SELECT 1 FROM purchase p WHERE p.customer_id = customer.id AND p.status = 'paid' AND p.month > 0)`)
@@ -748,32 +803,36 @@ func (cr *CustomerRepository) GetBroadcastRecipients(ctx context.Context, audien
 
 	switch audience {
 	case BroadcastAudienceAll:
-		// no extra filter
+		return nil, nil
 	case BroadcastAudienceActive, BroadcastAudienceActiveAll:
-		buildSelect = buildSelect.Where(activeVPN)
+		return activeVPN, nil
 	case BroadcastAudienceInactive, BroadcastAudienceInactiveAll:
-		buildSelect = buildSelect.Where(inactiveVPN)
+		return inactiveVPN, nil
 	case BroadcastAudienceActivePaid:
 		ap := sq.And{activeVPN, paidSubscription}
 		if tariffID != nil {
 			ap = sq.And{ap, sq.Eq{"current_tariff_id": *tariffID}}
 		}
-		buildSelect = buildSelect.Where(ap)
+		return ap, nil
 	case BroadcastAudienceActiveTrial:
-		buildSelect = buildSelect.Where(sq.And{activeVPN, noPaidSubscription})
+		return sq.And{activeVPN, noPaidSubscription}, nil
 	case BroadcastAudienceInactivePaid:
 		ip := sq.And{inactiveVPN, paidSubscription}
 		if tariffID != nil {
 			ip = sq.And{ip, sq.Eq{"current_tariff_id": *tariffID}}
 		}
-		buildSelect = buildSelect.Where(ip)
+		return ip, nil
 	case BroadcastAudienceInactiveTrial:
-		buildSelect = buildSelect.Where(sq.And{inactiveVPN, noPaidSubscription})
+		return sq.And{inactiveVPN, noPaidSubscription}, nil
 	default:
 		return nil, fmt.Errorf("unknown broadcast audience: %s", audience)
 	}
+}
 
-	buildSelect = buildSelect.Where(sq.Eq{"is_web_only": false})
+// queryBroadcastRecipients выполняет выборку telegram_id, language, id, is_web_only;
+// web-only клиенты без подтверждённого email или отписавшиеся от рассылок исключаются.
+func (cr *CustomerRepository) queryBroadcastRecipients(ctx context.Context, buildSelect sq.SelectBuilder) ([]BroadcastRecipient, error) {
+	buildSelect = buildSelect.Where(sq.Or{sq.Eq{"is_web_only": false}, sq.Expr(marketingEmailReachable)})
 
 	sqlStr, args, err := buildSelect.ToSql()
 	if err != nil {
@@ -790,7 +849,7 @@ func (cr *CustomerRepository) GetBroadcastRecipients(ctx context.Context, audien
 	for rows.Next() {
 		var r BroadcastRecipient
 		var lang string
-		err := rows.Scan(&r.TelegramID, &lang)
+		err := rows.Scan(&r.TelegramID, &lang, &r.CustomerID, &r.WebOnly)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan broadcast recipient: %w", err)
 		}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Канал доставки получателя рассылки (broadcast_delivery.channel).
+const (
+	DeliveryChannelTelegram = "telegram"
+	DeliveryChannelEmail    = "email"
+)
+
+// marketingEmailReachable — у клиента есть подтверждённый email привязанного аккаунта кабинета,
+// не отписанный от рассылок. Условие для выборок из customer.
+const marketingEmailReachable = `EXISTS (SELECT 1 FROM cabinet_account_customer_link l
+	JOIN cabinet_account a ON a.id = l.account_id
+	WHERE l.customer_id = customer.id AND l.link_status = 'linked' AND a.status = 'active'
+	  AND a.email IS NOT NULL AND a.email_verified_at IS NOT NULL AND a.marketing_email_opt_out_at IS NULL)`
+
+// EmailContact — подтверждённый email аккаунта кабинета, привязанного к клиенту.
+type EmailContact struct {
+	AccountID       int64
+	Email           string
+	MarketingOptOut bool // клиент отписался от рекламных писем
+}
+
+// FindEmailContact возвращает email клиента для уведомлений; nil, nil — подтверждённого email нет.
+func (cr *CustomerRepository) FindEmailContact(ctx context.Context, customerID int64) (*EmailContact, error) {
+	var c EmailContact
+	err := cr.pool.QueryRow(ctx, `
+		SELECT a.id, a.email, a.marketing_email_opt_out_at IS NOT NULL
+		FROM cabinet_account_customer_link l
+		JOIN cabinet_account a ON a.id = l.account_id
+		WHERE l.customer_id = $1 AND l.link_status = 'linked' AND a.status = 'active'
+		  AND a.email IS NOT NULL AND a.email_verified_at IS NOT NULL
+		LIMIT 1`, customerID).Scan(&c.AccountID, &c.Email, &c.MarketingOptOut)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find customer email: %w", err)
+	}
+	return &c, nil
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// CustomerNotification — уведомление в ленте кабинета; ReadAt == nil — не прочитано.
+type CustomerNotification struct {
+	ID         int64      `db:"id"`
+	CustomerID int64      `db:"customer_id"`
+	Kind       string     `db:"kind"`
+	Text       string     `db:"text"`
+	CreatedAt  time.Time  `db:"created_at"`
+	ReadAt     *time.Time `db:"read_at"`
+}
+
+type CustomerNotificationRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewCustomerNotificationRepository(pool *pgxpool.Pool) *CustomerNotificationRepository {
+	return &CustomerNotificationRepository{pool: pool}
+}
+
+// Create добавляет уведомление в ленту клиента.
+func (r *CustomerNotificationRepository) Create(ctx context.Context, customerID int64, kind, text string) error {
+	_, err := r.pool.Exec(ctx,
+		`INSERT INTO customer_notification (customer_id, kind, text) VALUES ($1, $2, $3)`, customerID, kind, text)
+	if err != nil {
+		return fmt.Errorf("failed to create customer notification: %w", err)
+	}
+	return nil
+}
+
+// ListByCustomer — последние limit уведомлений клиента, новые первыми.
+func (r *CustomerNotificationRepository) ListByCustomer(ctx context.Context, customerID int64, limit int) ([]CustomerNotification, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, customer_id, kind, text, created_at, read_at FROM customer_notification
+		WHERE customer_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2`, customerID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list customer notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var out []CustomerNotification
+	for rows.Next() {
+		var n CustomerNotification
+		if err := rows.Scan(&n.ID, &n.CustomerID, &n.Kind, &n.Text, &n.CreatedAt, &n.ReadAt); err != nil {
+			return nil, fmt.Errorf("failed to scan customer notification: %w", err)
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+// CountUnread — число непрочитанных уведомлений клиента.
+func (r *CustomerNotificationRepository) CountUnread(ctx context.Context, customerID int64) (int64, error) {
+	var n int64
+	err := r.pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM customer_notification WHERE customer_id = $1 AND read_at IS NULL`, customerID).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread customer notifications: %w", err)
+	}
+	return n, nil
+}
+
+// MarkAllRead помечает прочитанными все уведомления клиента.
+func (r *CustomerNotificationRepository) MarkAllRead(ctx context.Context, customerID int64) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE customer_notification SET read_at = NOW() WHERE customer_id = $1 AND read_at IS NULL`, customerID)
+	if err != nil {
+		return fmt.Errorf("failed to mark customer notifications read: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// CustomerTrafficPack — оплаченный пакет трафика, применённый в панели. ResetMarker — lastTrafficResetAt
+// пользователя Remnawave при покупке; RolledBackAt == nil — пакет ещё действует.
+type CustomerTrafficPack struct {
+	ID           int64      `db:"id"`
+	CustomerID   int64      `db:"customer_id"`
+	PurchaseID   int64      `db:"purchase_id"`
+	Bytes        int64      `db:"bytes"`
+	ResetMarker  *time.Time `db:"reset_marker"`
+	AppliedAt    time.Time  `db:"applied_at"`
+	RolledBackAt *time.Time `db:"rolled_back_at"`
+}
+
+const customerTrafficPackColumns = "id, customer_id, purchase_id, bytes, reset_marker, applied_at, rolled_back_at"
+
+type CustomerTrafficPackRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewCustomerTrafficPackRepository(pool *pgxpool.Pool) *CustomerTrafficPackRepository {
+	return &CustomerTrafficPackRepository{pool: pool}
+}
+
+func scanCustomerTrafficPack(sc interface{ Scan(dest ...any) error }, p *CustomerTrafficPack) error {
+	return sc.Scan(&p.ID, &p.CustomerID, &p.PurchaseID, &p.Bytes, &p.ResetMarker, &p.AppliedAt, &p.RolledBackAt)
+}
+
+// Create записывает применённый пакет. Пакет один на покупку: повтор возвращает уже записанный.
+func (r *CustomerTrafficPackRepository) Create(ctx context.Context, customerID, purchaseID, bytes int64, resetMarker *time.Time) (*CustomerTrafficPack, error) {
+	var p CustomerTrafficPack
+	err := scanCustomerTrafficPack(r.pool.QueryRow(ctx, `
+		INSERT INTO customer_traffic_pack (customer_id, purchase_id, bytes, reset_marker) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (purchase_id) DO UPDATE SET purchase_id = EXCLUDED.purchase_id
+		RETURNING `+customerTrafficPackColumns, customerID, purchaseID, bytes, resetMarker), &p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create customer traffic pack: %w", err)
+	}
+	return &p, nil
+}
+
+// FindByPurchaseID — пакет, применённый по покупке; nil — ещё не применён.
+func (r *CustomerTrafficPackRepository) FindByPurchaseID(ctx context.Context, purchaseID int64) (*CustomerTrafficPack, error) {
+	var p CustomerTrafficPack
+	err := scanCustomerTrafficPack(r.pool.QueryRow(ctx,
+		`SELECT `+customerTrafficPackColumns+` FROM customer_traffic_pack WHERE purchase_id = $1`, purchaseID), &p)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query customer traffic pack: %w", err)
+	}
+	return &p, nil
+}
+
+// ListActive — действующие пакеты клиента, старые первыми.
+func (r *CustomerTrafficPackRepository) ListActive(ctx context.Context, customerID int64) ([]CustomerTrafficPack, error) {
+	return r.query(ctx, `
+		SELECT `+customerTrafficPackColumns+` FROM customer_traffic_pack
+		WHERE customer_id = $1 AND rolled_back_at IS NULL
+		ORDER BY applied_at, id`, customerID)
+}
+
+// FindActiveBatch — действующие пакеты всех клиентов для крона отката, сгруппированные по клиенту.
+func (r *CustomerTrafficPackRepository) FindActiveBatch(ctx context.Context, afterID int64, limit int) ([]CustomerTrafficPack, error) {
+	return r.query(ctx, `
+		SELECT `+customerTrafficPackColumns+` FROM customer_traffic_pack
+		WHERE rolled_back_at IS NULL AND customer_id > $1
+		  AND customer_id IN (
+			SELECT DISTINCT customer_id FROM customer_traffic_pack
+			WHERE rolled_back_at IS NULL AND customer_id > $1
+			ORDER BY customer_id
+			LIMIT $2)
+		ORDER BY customer_id, id`, afterID, limit)
+}
+
+// MarkRolledBack закрывает пакет. false — его уже закрыл другой процесс (продление или повтор крона).
+func (r *CustomerTrafficPackRepository) MarkRolledBack(ctx context.Context, id int64, at time.Time) (bool, error) {
+	tag, err := r.pool.Exec(ctx,
+		`UPDATE customer_traffic_pack SET rolled_back_at = $2 WHERE id = $1 AND rolled_back_at IS NULL`, id, at)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark customer traffic pack rolled back: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Reopen снова делает пакеты действующими — откат, если лимит в панели не удалось уменьшить.
+func (r *CustomerTrafficPackRepository) Reopen(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if _, err := r.pool.Exec(ctx, `UPDATE customer_traffic_pack SET rolled_back_at = NULL WHERE id = ANY($1)`, ids); err != nil {
+		return fmt.Errorf("failed to reopen customer traffic packs: %w", err)
+	}
+	return nil
+}
+
+// CloseAllActive закрывает все действующие пакеты клиента (лимит уже сброшен продлением подписки).
+func (r *CustomerTrafficPackRepository) CloseAllActive(ctx context.Context, customerID int64, at time.Time) error {
+	if _, err := r.pool.Exec(ctx,
+		`UPDATE customer_traffic_pack SET rolled_back_at = $2 WHERE customer_id = $1 AND rolled_back_at IS NULL`, customerID, at); err != nil {
+		return fmt.Errorf("failed to close customer traffic packs: %w", err)
+	}
+	return nil
+}
+
+func (r *CustomerTrafficPackRepository) query(ctx context.Context, sql string, args ...any) ([]CustomerTrafficPack, error) {
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query customer traffic packs: %w", err)
+	}
+	defer rows.Close()
+	var out []CustomerTrafficPack
+	for rows.Next() {
+		var p CustomerTrafficPack
+		if err := scanCustomerTrafficPack(rows, &p); err != nil {
+			return nil, fmt.Errorf("failed to scan customer traffic pack: %w", err)
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating customer traffic pack rows: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,212 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+var (
+	// ErrFamilyInviteCodeTaken — сгенерированный код приглашения уже занят (коллизия, нужно сгенерировать заново).
+	ErrFamilyInviteCodeTaken = errors.New("family invite code already exists")
+	// ErrFamilyFull — в семье уже максимум участников для тарифа владельца.
+	ErrFamilyFull = errors.New("family is full")
+	// ErrFamilyMemberExists — клиент уже состоит в семье (участник может быть только в одной).
+	ErrFamilyMemberExists = errors.New("customer already belongs to a family")
+)
+
+// FamilyGroup — семья владельца семейного тарифа; invite_code — параметр ссылки /start fam_<код>.
+type FamilyGroup struct {
+	ID              int64     `db:"id"`
+	OwnerCustomerID int64     `db:"owner_customer_id"`
+	InviteCode      string    `db:"invite_code"`
+	CreatedAt       time.Time `db:"created_at"`
+}
+
+// FamilyMember — участник семьи; DeviceLimit — устройства, выделенные ему из пула владельца.
+type FamilyMember struct {
+	ID          int64     `db:"id"`
+	GroupID     int64     `db:"group_id"`
+	CustomerID  int64     `db:"customer_id"`
+	DeviceLimit int       `db:"device_limit"`
+	JoinedAt    time.Time `db:"joined_at"`
+}
+
+const (
+	familyGroupColumns  = "id, owner_customer_id, invite_code, created_at"
+	familyMemberColumns = "id, group_id, customer_id, device_limit, joined_at"
+)
+
+type FamilyRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewFamilyRepository(pool *pgxpool.Pool) *FamilyRepository {
+	return &FamilyRepository{pool: pool}
+}
+
+// CreateGroup создаёт семью владельца. ErrFamilyInviteCodeTaken — код занят; если семья уже есть, возвращает её.
+func (r *FamilyRepository) CreateGroup(ctx context.Context, ownerCustomerID int64, inviteCode string) (*FamilyGroup, error) {
+	var g FamilyGroup
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO family_group (owner_customer_id, invite_code) VALUES ($1, $2)
+		ON CONFLICT (owner_customer_id) DO NOTHING
+		RETURNING `+familyGroupColumns, ownerCustomerID, inviteCode).
+		Scan(&g.ID, &g.OwnerCustomerID, &g.InviteCode, &g.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			existing, ferr := r.FindGroupByOwner(ctx, ownerCustomerID)
+			if ferr != nil {
+				return nil, ferr
+			}
+			if existing == nil {
+				return nil, fmt.Errorf("family of customer %d vanished after conflict", ownerCustomerID)
+			}
+			return existing, nil
+		}
+		if strings.Contains(err.Error(), "23505") {
+			return nil, ErrFamilyInviteCodeTaken
+		}
+		return nil, fmt.Errorf("failed to create family: %w", err)
+	}
+	return &g, nil
+}
+
+func (r *FamilyRepository) findGroup(ctx context.Context, where sq.Sqlizer) (*FamilyGroup, error) {
+	query, args, err := sq.Select(familyGroupColumns).
+		From("family_group").
+		Where(where).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+	var g FamilyGroup
+	if err := r.pool.QueryRow(ctx, query, args...).Scan(&g.ID, &g.OwnerCustomerID, &g.InviteCode, &g.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query family: %w", err)
+	}
+	return &g, nil
+}
+
+func (r *FamilyRepository) FindGroupByID(ctx context.Context, id int64) (*FamilyGroup, error) {
+	return r.findGroup(ctx, sq.Eq{"id": id})
+}
+
+func (r *FamilyRepository) FindGroupByOwner(ctx context.Context, ownerCustomerID int64) (*FamilyGroup, error) {
+	return r.findGroup(ctx, sq.Eq{"owner_customer_id": ownerCustomerID})
+}
+
+func (r *FamilyRepository) FindGroupByInviteCode(ctx context.Context, code string) (*FamilyGroup, error) {
+	return r.findGroup(ctx, sq.Eq{"invite_code": code})
+}
+
+// UpdateInviteCode заменяет код приглашения: старая ссылка перестаёт работать.
+func (r *FamilyRepository) UpdateInviteCode(ctx context.Context, groupID int64, code string) error {
+	_, err := r.pool.Exec(ctx, `UPDATE family_group SET invite_code = $2 WHERE id = $1`, groupID, code)
+	if err != nil {
+		if strings.Contains(err.Error(), "23505") {
+			return ErrFamilyInviteCodeTaken
+		}
+		return fmt.Errorf("failed to update family invite code: %w", err)
+	}
+	return nil
+}
+
+// AddMember добавляет участника, если в семье меньше maxMembers человек (проверка под блокировкой семьи).
+// ErrFamilyFull — мест нет; ErrFamilyMemberExists — клиент уже в какой-то семье.
+func (r *FamilyRepository) AddMember(ctx context.Context, groupID, customerID int64, deviceLimit, maxMembers int) (*FamilyMember, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT id FROM family_group WHERE id = $1 FOR UPDATE`, groupID); err != nil {
+		return nil, fmt.Errorf("failed to lock family: %w", err)
+	}
+	var count int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM family_member WHERE group_id = $1`, groupID).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to count family members: %w", err)
+	}
+	if count >= maxMembers {
+		return nil, ErrFamilyFull
+	}
+	var m FamilyMember
+	err = tx.QueryRow(ctx, `
+		INSERT INTO family_member (group_id, customer_id, device_limit) VALUES ($1, $2, $3)
+		RETURNING `+familyMemberColumns, groupID, customerID, deviceLimit).
+		Scan(&m.ID, &m.GroupID, &m.CustomerID, &m.DeviceLimit, &m.JoinedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "23505") {
+			return nil, ErrFamilyMemberExists
+		}
+		return nil, fmt.Errorf("failed to add family member: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// FindMembership — участие клиента в семье; nil, если он не участник.
+func (r *FamilyRepository) FindMembership(ctx context.Context, customerID int64) (*FamilyMember, error) {
+	var m FamilyMember
+	err := r.pool.QueryRow(ctx, `SELECT `+familyMemberColumns+` FROM family_member WHERE customer_id = $1`, customerID).
+		Scan(&m.ID, &m.GroupID, &m.CustomerID, &m.DeviceLimit, &m.JoinedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query family membership: %w", err)
+	}
+	return &m, nil
+}
+
+// ListMembers — участники семьи в порядке вступления.
+func (r *FamilyRepository) ListMembers(ctx context.Context, groupID int64) ([]FamilyMember, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+familyMemberColumns+` FROM family_member WHERE group_id = $1 ORDER BY joined_at, id`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query family members: %w", err)
+	}
+	defer rows.Close()
+	var out []FamilyMember
+	for rows.Next() {
+		var m FamilyMember
+		if err := rows.Scan(&m.ID, &m.GroupID, &m.CustomerID, &m.DeviceLimit, &m.JoinedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan family member: %w", err)
+		}
+		out = append(out, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating family member rows: %w", err)
+	}
+	return out, nil
+}
+
+// RemoveMember исключает участника из семьи. false — его там уже нет.
+func (r *FamilyRepository) RemoveMember(ctx context.Context, groupID, customerID int64) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM family_member WHERE group_id = $1 AND customer_id = $2`, groupID, customerID)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove family member: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// SetMemberDeviceLimit меняет число устройств участника. false — участника нет в семье.
+func (r *FamilyRepository) SetMemberDeviceLimit(ctx context.Context, groupID, customerID int64, limit int) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `UPDATE family_member SET device_limit = $3 WHERE group_id = $1 AND customer_id = $2`,
+		groupID, customerID, limit)
+	if err != nil {
+		return false, fmt.Errorf("failed to update family member devices: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
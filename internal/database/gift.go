@@ -0,0 +1,220 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type GiftStatus string
+
+const (
+	GiftStatusActive   GiftStatus = "active"
+	GiftStatusRedeemed GiftStatus = "redeemed"
+	GiftStatusRevoked  GiftStatus = "revoked"
+	// GiftStatusExpired в БД не хранится: это active с истёкшим expires_at (см. EffectiveStatus).
+	GiftStatusExpired GiftStatus = "expired"
+)
+
+// ErrGiftCodeTaken — сгенерированный код уже занят (коллизия, нужно сгенерировать заново).
+var ErrGiftCodeTaken = errors.New("gift code already exists")
+
+type GiftSubscription struct {
+	ID                   int64      `db:"id"`
+	Code                 string     `db:"code"`
+	PurchaseID           int64      `db:"purchase_id"`
+	BuyerCustomerID      int64      `db:"buyer_customer_id"`
+	Months               int        `db:"months"`
+	TariffID             *int64     `db:"tariff_id"`
+	Status               GiftStatus `db:"status"`
+	ExpiresAt            time.Time  `db:"expires_at"`
+	RedeemedByCustomerID *int64     `db:"redeemed_by_customer_id"`
+	RedeemedAt           *time.Time `db:"redeemed_at"`
+	CreatedAt            time.Time  `db:"created_at"`
+}
+
+// EffectiveStatus — статус с учётом срока: неактивированный просроченный подарок — expired.
+func (g *GiftSubscription) EffectiveStatus(now time.Time) GiftStatus {
+	if g.Status == GiftStatusActive && !g.ExpiresAt.After(now) {
+		return GiftStatusExpired
+	}
+	return g.Status
+}
+
+const giftSubscriptionColumns = "id, code, purchase_id, buyer_customer_id, months, tariff_id, status, expires_at, " +
+	"redeemed_by_customer_id, redeemed_at, created_at"
+
+func giftSubscriptionScanArgs(g *GiftSubscription) []interface{} {
+	return []interface{}{
+		&g.ID, &g.Code, &g.PurchaseID, &g.BuyerCustomerID, &g.Months, &g.TariffID, &g.Status, &g.ExpiresAt,
+		&g.RedeemedByCustomerID, &g.RedeemedAt, &g.CreatedAt,
+	}
+}
+
+type GiftRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewGiftRepository(pool *pgxpool.Pool) *GiftRepository {
+	return &GiftRepository{pool: pool}
+}
+
+// Create сохраняет подарок. ErrGiftCodeTaken — код занят; повтор по той же покупке возвращает уже выпущенный подарок.
+func (r *GiftRepository) Create(ctx context.Context, g *GiftSubscription) (*GiftSubscription, error) {
+	query := `
+		INSERT INTO gift_subscription (code, purchase_id, buyer_customer_id, months, tariff_id, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (purchase_id) DO NOTHING
+		RETURNING ` + giftSubscriptionColumns
+	var out GiftSubscription
+	err := r.pool.QueryRow(ctx, query, g.Code, g.PurchaseID, g.BuyerCustomerID, g.Months, g.TariffID, GiftStatusActive, g.ExpiresAt).
+		Scan(giftSubscriptionScanArgs(&out)...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			existing, ferr := r.FindByPurchaseID(ctx, g.PurchaseID)
+			if ferr != nil {
+				return nil, ferr
+			}
+			if existing == nil {
+				return nil, fmt.Errorf("gift for purchase %d vanished after conflict", g.PurchaseID)
+			}
+			return existing, nil
+		}
+		if strings.Contains(err.Error(), "23505") {
+			return nil, ErrGiftCodeTaken
+		}
+		return nil, fmt.Errorf("failed to create gift: %w", err)
+	}
+	return &out, nil
+}
+
+func (r *GiftRepository) findOne(ctx context.Context, where sq.Sqlizer) (*GiftSubscription, error) {
+	query, args, err := sq.Select(giftSubscriptionColumns).
+		From("gift_subscription").
+		Where(where).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+	var g GiftSubscription
+	if err := r.pool.QueryRow(ctx, query, args...).Scan(giftSubscriptionScanArgs(&g)...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query gift: %w", err)
+	}
+	return &g, nil
+}
+
+func (r *GiftRepository) FindByCode(ctx context.Context, code string) (*GiftSubscription, error) {
+	return r.findOne(ctx, sq.Eq{"code": code})
+}
+
+func (r *GiftRepository) FindByPurchaseID(ctx context.Context, purchaseID int64) (*GiftSubscription, error) {
+	return r.findOne(ctx, sq.Eq{"purchase_id": purchaseID})
+}
+
+// ClaimRedeem атомарно помечает подарок активированным. false — код уже использован, отозван или просрочен.
+func (r *GiftRepository) ClaimRedeem(ctx context.Context, id, customerID int64, now time.Time) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE gift_subscription SET status = $3, redeemed_by_customer_id = $2, redeemed_at = $4
+		WHERE id = $1 AND status = $5 AND expires_at > $4`,
+		id, customerID, GiftStatusRedeemed, now, GiftStatusActive)
+	if err != nil {
+		return false, fmt.Errorf("failed to redeem gift: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ReleaseRedeem возвращает подарок в active, если начислить дни не удалось.
+func (r *GiftRepository) ReleaseRedeem(ctx context.Context, id int64) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE gift_subscription SET status = $2, redeemed_by_customer_id = NULL, redeemed_at = NULL
+		WHERE id = $1 AND status = $3`, id, GiftStatusActive, GiftStatusRedeemed)
+	if err != nil {
+		return fmt.Errorf("failed to release gift: %w", err)
+	}
+	return nil
+}
+
+// RevokeByPurchase отзывает неактивированный подарок (возврат денег покупателю). false — подарка нет или он уже активирован.
+func (r *GiftRepository) RevokeByPurchase(ctx context.Context, purchaseID int64) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `UPDATE gift_subscription SET status = $2 WHERE purchase_id = $1 AND status = $3`,
+		purchaseID, GiftStatusRevoked, GiftStatusActive)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke gift: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// HasRedeemed — клиент активировал хотя бы один подарок.
+func (r *GiftRepository) HasRedeemed(ctx context.Context, customerID int64) (bool, error) {
+	var exists bool
+	err := r.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM gift_subscription WHERE redeemed_by_customer_id = $1 AND status = $2)`,
+		customerID, GiftStatusRedeemed).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check redeemed gifts: %w", err)
+	}
+	return exists, nil
+}
+
+// ListRecent — последние подарки для админки; пустой status — все.
+func (r *GiftRepository) ListRecent(ctx context.Context, status GiftStatus, limit, offset int) ([]GiftSubscription, error) {
+	b := sq.Select(giftSubscriptionColumns).
+		From("gift_subscription").
+		OrderBy("created_at DESC", "id DESC").
+		Limit(uint64(limit)).
+		Offset(uint64(offset)).
+		PlaceholderFormat(sq.Dollar)
+	switch status {
+	case "":
+	case GiftStatusExpired:
+		b = b.Where(sq.And{sq.Eq{"status": GiftStatusActive}, sq.Expr("expires_at <= NOW()")})
+	case GiftStatusActive:
+		b = b.Where(sq.And{sq.Eq{"status": GiftStatusActive}, sq.Expr("expires_at > NOW()")})
+	default:
+		b = b.Where(sq.Eq{"status": status})
+	}
+	return r.list(ctx, b)
+}
+
+// ListByBuyer — подарки, купленные клиентом (новые сверху).
+func (r *GiftRepository) ListByBuyer(ctx context.Context, buyerCustomerID int64, limit int) ([]GiftSubscription, error) {
+	return r.list(ctx, sq.Select(giftSubscriptionColumns).
+		From("gift_subscription").
+		Where(sq.Eq{"buyer_customer_id": buyerCustomerID}).
+		OrderBy("created_at DESC", "id DESC").
+		Limit(uint64(limit)).
+		PlaceholderFormat(sq.Dollar))
+}
+
+func (r *GiftRepository) list(ctx context.Context, b sq.SelectBuilder) ([]GiftSubscription, error) {
+	query, args, err := b.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query gifts: %w", err)
+	}
+	defer rows.Close()
+	var out []GiftSubscription
+	for rows.Next() {
+		var g GiftSubscription
+		if err := rows.Scan(giftSubscriptionScanArgs(&g)...); err != nil {
+			return nil, fmt.Errorf("failed to scan gift: %w", err)
+		}
+		out = append(out, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating gift rows: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,266 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type MoynalogReceiptStatus string
+
+const (
+	MoynalogReceiptStatusPending       MoynalogReceiptStatus = "pending"
+	MoynalogReceiptStatusSent          MoynalogReceiptStatus = "sent"
+	MoynalogReceiptStatusFailed        MoynalogReceiptStatus = "failed"
+	MoynalogReceiptStatusCancelPending MoynalogReceiptStatus = "cancel_pending"
+	MoynalogReceiptStatusCanceled      MoynalogReceiptStatus = "canceled"
+	MoynalogReceiptStatusCancelFailed  MoynalogReceiptStatus = "cancel_failed"
+	// MoynalogReceiptStatusNeedsAttention — чек создан в «Мой налог», но UUID не удалось сохранить.
+	// Из очереди чек исключён, чтобы не создать доход повторно; разбирает админ.
+	MoynalogReceiptStatusNeedsAttention MoynalogReceiptStatus = "needs_attention"
+)
+
+// ErrMoynalogReceiptNotRetryable — чек не в failed/cancel_failed/needs_attention (уже отправлен, аннулирован или в очереди).
+var ErrMoynalogReceiptNotRetryable = errors.New("moynalog receipt is not in a failed state")
+
+// MoynalogReceipt — чек «Мой налог» по оплаченной покупке (moynalog_receipt).
+type MoynalogReceipt struct {
+	ID            int64                 `db:"id"`
+	PurchaseID    int64                 `db:"purchase_id"`
+	Amount        float64               `db:"amount"`
+	Description   string                `db:"description"`
+	OperationTime time.Time             `db:"operation_time"`
+	Status        MoynalogReceiptStatus `db:"status"`
+	ReceiptUUID   *string               `db:"receipt_uuid"`
+	Attempts      int                   `db:"attempts"`
+	LastError     *string               `db:"last_error"`
+	NextAttemptAt *time.Time            `db:"next_attempt_at"`
+	CreatedAt     time.Time             `db:"created_at"`
+	UpdatedAt     time.Time             `db:"updated_at"`
+	SentAt        *time.Time            `db:"sent_at"`
+	CanceledAt    *time.Time            `db:"canceled_at"`
+}
+
+const moynalogReceiptColumns = "id, purchase_id, amount::float8, description, operation_time, status, receipt_uuid, " +
+	"attempts, last_error, next_attempt_at, created_at, updated_at, sent_at, canceled_at"
+
+func moynalogReceiptScanArgs(r *MoynalogReceipt) []interface{} {
+	return []interface{}{
+		&r.ID, &r.PurchaseID, &r.Amount, &r.Description, &r.OperationTime, &r.Status, &r.ReceiptUUID,
+		&r.Attempts, &r.LastError, &r.NextAttemptAt, &r.CreatedAt, &r.UpdatedAt, &r.SentAt, &r.CanceledAt,
+	}
+}
+
+type MoynalogReceiptRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewMoynalogReceiptRepository(pool *pgxpool.Pool) *MoynalogReceiptRepository {
+	return &MoynalogReceiptRepository{pool: pool}
+}
+
+func (r *MoynalogReceiptRepository) queryOne(ctx context.Context, query string, args ...interface{}) (*MoynalogReceipt, error) {
+	var out MoynalogReceipt
+	if err := r.pool.QueryRow(ctx, query, args...).Scan(moynalogReceiptScanArgs(&out)...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Enqueue ставит чек в очередь с первой попыткой в now. created=false — чек по покупке уже есть (повторная обработка оплаты).
+func (r *MoynalogReceiptRepository) Enqueue(ctx context.Context, purchaseID int64, amount float64, description string, operationTime, now time.Time) (*MoynalogReceipt, bool, error) {
+	out, err := r.queryOne(ctx, `
+		INSERT INTO moynalog_receipt (purchase_id, amount, description, operation_time, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (purchase_id) DO NOTHING
+		RETURNING `+moynalogReceiptColumns, purchaseID, amount, description, operationTime, now)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to enqueue moynalog receipt: %w", err)
+	}
+	if out != nil {
+		return out, true, nil
+	}
+	existing, err := r.FindByPurchase(ctx, purchaseID)
+	return existing, false, err
+}
+
+func (r *MoynalogReceiptRepository) FindByID(ctx context.Context, id int64) (*MoynalogReceipt, error) {
+	out, err := r.queryOne(ctx, "SELECT "+moynalogReceiptColumns+" FROM moynalog_receipt WHERE id = $1", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find moynalog receipt: %w", err)
+	}
+	return out, nil
+}
+
+func (r *MoynalogReceiptRepository) FindByPurchase(ctx context.Context, purchaseID int64) (*MoynalogReceipt, error) {
+	out, err := r.queryOne(ctx, "SELECT "+moynalogReceiptColumns+" FROM moynalog_receipt WHERE purchase_id = $1", purchaseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find moynalog receipt by purchase: %w", err)
+	}
+	return out, nil
+}
+
+// FindDue — чеки, которые пора отправить или аннулировать.
+func (r *MoynalogReceiptRepository) FindDue(ctx context.Context, now time.Time, limit int) ([]MoynalogReceipt, error) {
+	return r.list(ctx, sq.Select(moynalogReceiptColumns).
+		From("moynalog_receipt").
+		Where(sq.And{
+			sq.Eq{"status": []MoynalogReceiptStatus{MoynalogReceiptStatusPending, MoynalogReceiptStatusCancelPending}},
+			sq.LtOrEq{"next_attempt_at": now},
+		}).
+		OrderBy("next_attempt_at ASC").
+		Limit(uint64(limit)))
+}
+
+// List — чеки в статусах statuses (пусто — все), последние изменённые первыми.
+func (r *MoynalogReceiptRepository) List(ctx context.Context, statuses []MoynalogReceiptStatus, limit, offset int) ([]MoynalogReceipt, error) {
+	b := sq.Select(moynalogReceiptColumns).
+		From("moynalog_receipt").
+		OrderBy("updated_at DESC", "id DESC").
+		Limit(uint64(limit)).
+		Offset(uint64(offset))
+	if len(statuses) > 0 {
+		b = b.Where(sq.Eq{"status": statuses})
+	}
+	return r.list(ctx, b)
+}
+
+func (r *MoynalogReceiptRepository) list(ctx context.Context, b sq.SelectBuilder) ([]MoynalogReceipt, error) {
+	query, args, err := b.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query moynalog receipts: %w", err)
+	}
+	defer rows.Close()
+	var out []MoynalogReceipt
+	for rows.Next() {
+		var rc MoynalogReceipt
+		if err := rows.Scan(moynalogReceiptScanArgs(&rc)...); err != nil {
+			return nil, fmt.Errorf("failed to scan moynalog receipt: %w", err)
+		}
+		out = append(out, rc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating moynalog receipt rows: %w", err)
+	}
+	return out, nil
+}
+
+// Claim атомарно откладывает следующую попытку до until. nil — чек уже забрал параллельный запуск
+// или он больше не ждёт обработки.
+func (r *MoynalogReceiptRepository) Claim(ctx context.Context, id int64, now, until time.Time) (*MoynalogReceipt, error) {
+	out, err := r.queryOne(ctx, `
+		UPDATE moynalog_receipt SET next_attempt_at = $3, updated_at = NOW()
+		WHERE id = $1 AND status IN ('pending', 'cancel_pending') AND next_attempt_at <= $2
+		RETURNING `+moynalogReceiptColumns, id, now, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim moynalog receipt: %w", err)
+	}
+	return out, nil
+}
+
+// MarkSent сохраняет UUID созданного чека. Если пока чек отправлялся, покупку вернули (status ушёл из pending),
+// чек сразу ставится на аннулирование.
+func (r *MoynalogReceiptRepository) MarkSent(ctx context.Context, id int64, receiptUUID string) (*MoynalogReceipt, error) {
+	out, err := r.queryOne(ctx, `
+		UPDATE moynalog_receipt SET receipt_uuid = $2, sent_at = NOW(), attempts = 0, last_error = NULL,
+			status = CASE WHEN status = 'pending' THEN 'sent' ELSE 'cancel_pending' END,
+			next_attempt_at = CASE WHEN status = 'pending' THEN NULL ELSE NOW() END,
+			canceled_at = NULL, updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+moynalogReceiptColumns, id, receiptUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark moynalog receipt sent: %w", err)
+	}
+	return out, nil
+}
+
+// MarkNeedsAttention сохраняет UUID созданного чека, когда MarkSent не прошёл: pending-чек уходит в needs_attention
+// и больше не отправляется, чек, который пока отправлялся успели вернуть, ставится на аннулирование.
+func (r *MoynalogReceiptRepository) MarkNeedsAttention(ctx context.Context, id int64, receiptUUID, errText string) (*MoynalogReceipt, error) {
+	out, err := r.queryOne(ctx, `
+		UPDATE moynalog_receipt SET receipt_uuid = $2, sent_at = NOW(), last_error = $3,
+			status = CASE WHEN status = 'pending' THEN 'needs_attention' ELSE 'cancel_pending' END,
+			next_attempt_at = CASE WHEN status = 'pending' THEN NULL ELSE NOW() END,
+			updated_at = NOW()
+		WHERE id = $1 AND status IN ('pending', 'cancel_pending')
+		RETURNING `+moynalogReceiptColumns, id, receiptUUID, errText)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark moynalog receipt needs attention: %w", err)
+	}
+	return out, nil
+}
+
+// MarkCanceled — чек аннулирован в «Мой налог».
+func (r *MoynalogReceiptRepository) MarkCanceled(ctx context.Context, id int64) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE moynalog_receipt SET status = 'canceled', canceled_at = NOW(), attempts = 0, last_error = NULL,
+			next_attempt_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND status = 'cancel_pending'`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark moynalog receipt canceled: %w", err)
+	}
+	return nil
+}
+
+// RecordFailure увеличивает счётчик неудач текущей операции. final=true переводит чек в failed / cancel_failed,
+// иначе следующая попытка — в nextAttemptAt.
+func (r *MoynalogReceiptRepository) RecordFailure(ctx context.Context, id int64, errText string, nextAttemptAt *time.Time, final bool) (*MoynalogReceipt, error) {
+	out, err := r.queryOne(ctx, `
+		UPDATE moynalog_receipt SET attempts = attempts + 1, last_error = $2,
+			next_attempt_at = CASE WHEN $4::boolean THEN NULL ELSE $3::timestamptz END,
+			status = CASE WHEN NOT $4::boolean THEN status WHEN status = 'pending' THEN 'failed' ELSE 'cancel_failed' END,
+			updated_at = NOW()
+		WHERE id = $1 AND status IN ('pending', 'cancel_pending')
+		RETURNING `+moynalogReceiptColumns, id, errText, nextAttemptAt, final)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record moynalog receipt failure: %w", err)
+	}
+	return out, nil
+}
+
+// RequestCancel ставит чек покупки на аннулирование. Ещё не отправленный чек закрывается сразу.
+// nil — чека нет или он уже аннулирован.
+func (r *MoynalogReceiptRepository) RequestCancel(ctx context.Context, purchaseID int64, now time.Time) (*MoynalogReceipt, error) {
+	out, err := r.queryOne(ctx, `
+		UPDATE moynalog_receipt SET attempts = 0, last_error = NULL, updated_at = NOW(),
+			status = CASE WHEN receipt_uuid IS NULL THEN 'canceled' ELSE 'cancel_pending' END,
+			canceled_at = CASE WHEN receipt_uuid IS NULL THEN NOW() END,
+			next_attempt_at = CASE WHEN receipt_uuid IS NULL THEN NULL ELSE $2::timestamptz END
+		WHERE purchase_id = $1 AND status IN ('pending', 'failed', 'sent', 'cancel_failed', 'needs_attention')
+		RETURNING `+moynalogReceiptColumns, purchaseID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request moynalog receipt cancel: %w", err)
+	}
+	return out, nil
+}
+
+// Retry возвращает failed / cancel_failed чек в очередь (попытка в now) с обнулённым счётчиком попыток.
+// needs_attention чек с сохранённым UUID закрывается как sent: доход в «Мой налог» уже создан.
+func (r *MoynalogReceiptRepository) Retry(ctx context.Context, id int64, now time.Time) (*MoynalogReceipt, error) {
+	out, err := r.queryOne(ctx, `
+		UPDATE moynalog_receipt SET attempts = 0, updated_at = NOW(),
+			next_attempt_at = CASE WHEN status = 'needs_attention' THEN NULL ELSE $2::timestamptz END,
+			last_error = CASE WHEN status = 'needs_attention' THEN NULL ELSE last_error END,
+			status = CASE WHEN status = 'failed' THEN 'pending' WHEN status = 'needs_attention' THEN 'sent' ELSE 'cancel_pending' END
+		WHERE id = $1 AND (status IN ('failed', 'cancel_failed') OR (status = 'needs_attention' AND receipt_uuid IS NOT NULL))
+		RETURNING `+moynalogReceiptColumns, id, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry moynalog receipt: %w", err)
+	}
+	if out == nil {
+		return nil, ErrMoynalogReceiptNotRetryable
+	}
+	return out, nil
+}
@@ -41,6 +41,17 @@ type PromoCode struct {
 	CreatedAt                  time.Time  `db:"created_at"`
 	DiscountMaxSubscriptionPaymentsPerCustomer int    `db:"discount_max_subscription_payments_per_customer"`
 	TariffID                   *int64     `db:"tariff_id"`
+	// BatchID — пакет одноразовых кодов (promo_batch), из которого сгенерирован код; nil — код создан вручную.
+	BatchID *int64 `db:"batch_id"`
+	// DiscountAmount — фиксированная скидка в ₽ (тип discount вместо DiscountPercent).
+	DiscountAmount *int `db:"discount_amount"`
+	// Ограничения скидки: тарифы, периоды (мес.), способы оплаты и минимальная сумма заказа в ₽; пусто — без ограничения.
+	DiscountTariffIDs    []int64  `db:"discount_tariff_ids"`
+	DiscountMonths       []int    `db:"discount_months"`
+	DiscountInvoiceTypes []string `db:"discount_invoice_types"`
+	DiscountMinAmount    *int     `db:"discount_min_amount"`
+	// SegmentID — промокод только для клиентов сохранённого сегмента (customer_segment); nil — для всех.
+	SegmentID *int64 `db:"segment_id"`
 }
 
 type PromoRedemption struct {
@@ -59,6 +70,8 @@ type PendingDiscount struct {
 	UntilFirstPurchase  bool       `db:"until_first_purchase"`
 	CreatedAt           time.Time  `db:"created_at"`
 	SubscriptionPaymentsRemaining int `db:"subscription_payments_remaining"`
+	// Amount — фиксированная скидка в ₽ (промокод с discount_amount); Percent при этом 0.
+	Amount int `db:"amount"`
 }
 
 type PromoRepository struct {
@@ -76,12 +89,14 @@ func (r *PromoRepository) Create(ctx context.Context, p *PromoCode) (int64, erro
 			"discount_percent", "discount_ttl_hours", "max_uses", "uses_count", "valid_until",
 			"active", "first_purchase_only", "require_customer_in_db", "allow_trial_without_payment",
 			"discount_max_subscription_payments_per_customer", "tariff_id",
+			"discount_amount", "discount_tariff_ids", "discount_months", "discount_invoice_types", "discount_min_amount", "segment_id",
 		).
 		Values(
 			p.Code, p.Type, p.SubscriptionDays, p.TrialDays, p.ExtraHwidDelta,
 			p.DiscountPercent, p.DiscountTTLHours, p.MaxUses, p.UsesCount, p.ValidUntil,
 			p.Active, p.FirstPurchaseOnly, p.RequireCustomerInDB, p.AllowTrialWithoutPayment,
 			p.DiscountMaxSubscriptionPaymentsPerCustomer, p.TariffID,
+			p.DiscountAmount, p.DiscountTariffIDs, p.DiscountMonths, p.DiscountInvoiceTypes, p.DiscountMinAmount, p.SegmentID,
 		).
 		Suffix("RETURNING id").
 		PlaceholderFormat(sq.Dollar)
@@ -91,7 +106,7 @@ func (r *PromoRepository) Create(ctx context.Context, p *PromoCode) (int64, erro
 	}
 	var id int64
 	err = r.pool.QueryRow(ctx, sqlStr, args...).Scan(&id)
-	return id, err
+	return id, promoSegmentError(err)
 }
 
 func (r *PromoRepository) FindByID(ctx context.Context, id int64) (*PromoCode, error) {
@@ -99,7 +114,8 @@ func (r *PromoRepository) FindByID(ctx context.Context, id int64) (*PromoCode, e
 		"id", "code", "type", "subscription_days", "trial_days", "extra_hwid_delta",
 		"discount_percent", "discount_ttl_hours", "max_uses", "uses_count", "valid_until",
 		"active", "first_purchase_only", "require_customer_in_db", "allow_trial_without_payment", "created_at",
-		"discount_max_subscription_payments_per_customer", "tariff_id",
+		"discount_max_subscription_payments_per_customer", "tariff_id", "batch_id",
+		"discount_amount", "discount_tariff_ids", "discount_months", "discount_invoice_types", "discount_min_amount", "segment_id",
 	).From("promo_code").Where(sq.Eq{"id": id}).PlaceholderFormat(sq.Dollar))
 }
 
@@ -108,7 +124,8 @@ func (r *PromoRepository) FindByCode(ctx context.Context, codeUpper string) (*Pr
 		"id", "code", "type", "subscription_days", "trial_days", "extra_hwid_delta",
 		"discount_percent", "discount_ttl_hours", "max_uses", "uses_count", "valid_until",
 		"active", "first_purchase_only", "require_customer_in_db", "allow_trial_without_payment", "created_at",
-		"discount_max_subscription_payments_per_customer", "tariff_id",
+		"discount_max_subscription_payments_per_customer", "tariff_id", "batch_id",
+		"discount_amount", "discount_tariff_ids", "discount_months", "discount_invoice_types", "discount_min_amount", "segment_id",
 	).From("promo_code").Where(sq.Eq{"code": codeUpper}).PlaceholderFormat(sq.Dollar))
 }
 
@@ -124,7 +141,8 @@ func (r *PromoRepository) scanOne(ctx context.Context, builder sq.SelectBuilder)
 		&p.ID, &p.Code, &p.Type, &p.SubscriptionDays, &p.TrialDays, &p.ExtraHwidDelta,
 		&p.DiscountPercent, &p.DiscountTTLHours, &p.MaxUses, &p.UsesCount, &p.ValidUntil,
 		&p.Active, &p.FirstPurchaseOnly, &p.RequireCustomerInDB, &p.AllowTrialWithoutPayment, &p.CreatedAt,
-		&p.DiscountMaxSubscriptionPaymentsPerCustomer, &tid,
+		&p.DiscountMaxSubscriptionPaymentsPerCustomer, &tid, &p.BatchID,
+		&p.DiscountAmount, &p.DiscountTariffIDs, &p.DiscountMonths, &p.DiscountInvoiceTypes, &p.DiscountMinAmount, &p.SegmentID,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -139,8 +157,9 @@ func (r *PromoRepository) scanOne(ctx context.Context, builder sq.SelectBuilder)
 	return &p, nil
 }
 
+// List — промокоды, созданные вручную (коды из пакетов — ListBatches), новые сверху.
 func (r *PromoRepository) List(ctx context.Context, offset, limit int) ([]PromoCode, int, error) {
-	countQ := sq.Select("COUNT(*)").From("promo_code").PlaceholderFormat(sq.Dollar)
+	countQ := sq.Select("COUNT(*)").From("promo_code").Where("batch_id IS NULL").PlaceholderFormat(sq.Dollar)
 	csql, cargs, _ := countQ.ToSql()
 	var total int
 	if err := r.pool.QueryRow(ctx, csql, cargs...).Scan(&total); err != nil {
@@ -150,8 +169,9 @@ func (r *PromoRepository) List(ctx context.Context, offset, limit int) ([]PromoC
 		"id", "code", "type", "subscription_days", "trial_days", "extra_hwid_delta",
 		"discount_percent", "discount_ttl_hours", "max_uses", "uses_count", "valid_until",
 		"active", "first_purchase_only", "require_customer_in_db", "allow_trial_without_payment", "created_at",
-		"discount_max_subscription_payments_per_customer", "tariff_id",
-	).From("promo_code").OrderBy("id DESC").Offset(uint64(offset)).Limit(uint64(limit)).PlaceholderFormat(sq.Dollar)
+		"discount_max_subscription_payments_per_customer", "tariff_id", "batch_id",
+		"discount_amount", "discount_tariff_ids", "discount_months", "discount_invoice_types", "discount_min_amount", "segment_id",
+	).From("promo_code").Where("batch_id IS NULL").OrderBy("id DESC").Offset(uint64(offset)).Limit(uint64(limit)).PlaceholderFormat(sq.Dollar)
 	sqlStr, args, err := builder.ToSql()
 	if err != nil {
 		return nil, 0, err
@@ -169,7 +189,8 @@ func (r *PromoRepository) List(ctx context.Context, offset, limit int) ([]PromoC
 			&p.ID, &p.Code, &p.Type, &p.SubscriptionDays, &p.TrialDays, &p.ExtraHwidDelta,
 			&p.DiscountPercent, &p.DiscountTTLHours, &p.MaxUses, &p.UsesCount, &p.ValidUntil,
 			&p.Active, &p.FirstPurchaseOnly, &p.RequireCustomerInDB, &p.AllowTrialWithoutPayment, &p.CreatedAt,
-			&p.DiscountMaxSubscriptionPaymentsPerCustomer, &tid,
+			&p.DiscountMaxSubscriptionPaymentsPerCustomer, &tid, &p.BatchID,
+			&p.DiscountAmount, &p.DiscountTariffIDs, &p.DiscountMonths, &p.DiscountInvoiceTypes, &p.DiscountMinAmount, &p.SegmentID,
 		); err != nil {
 			return nil, 0, err
 		}
@@ -182,8 +203,9 @@ func (r *PromoRepository) List(ctx context.Context, offset, limit int) ([]PromoC
 	return list, total, rows.Err()
 }
 
+// CountTotals — число промокодов, созданных вручную (без кодов из пакетов).
 func (r *PromoRepository) CountTotals(ctx context.Context) (total, active, inactive int, err error) {
-	err = r.pool.QueryRow(ctx, `SELECT COUNT(*)::int, COUNT(*) FILTER (WHERE active)::int, COUNT(*) FILTER (WHERE NOT active)::int FROM promo_code`).Scan(&total, &active, &inactive)
+	err = r.pool.QueryRow(ctx, `SELECT COUNT(*)::int, COUNT(*) FILTER (WHERE active)::int, COUNT(*) FILTER (WHERE NOT active)::int FROM promo_code WHERE batch_id IS NULL`).Scan(&total, &active, &inactive)
 	return
 }
 
@@ -262,7 +284,7 @@ func (r *PromoRepository) UpdateFields(ctx context.Context, id int64, fields map
 		return err
 	}
 	_, err = r.pool.Exec(ctx, sqlStr, args...)
-	return err
+	return promoSegmentError(err)
 }
 
 func (r *PromoRepository) Delete(ctx context.Context, id int64) error {
@@ -386,7 +408,8 @@ func (r *PromoRepository) FindByCodeForUpdate(ctx context.Context, tx pgx.Tx, co
 	q := `SELECT id, code, type, subscription_days, trial_days, extra_hwid_delta,
 		discount_percent, discount_ttl_hours, max_uses, uses_count, valid_until,
 		active, first_purchase_only, require_customer_in_db, allow_trial_without_payment, created_at,
-		discount_max_subscription_payments_per_customer, tariff_id
+		discount_max_subscription_payments_per_customer, tariff_id, batch_id,
+		discount_amount, discount_tariff_ids, discount_months, discount_invoice_types, discount_min_amount, segment_id
 		FROM promo_code WHERE code = $1 FOR UPDATE`
 	row := tx.QueryRow(ctx, q, codeUpper)
 	var p PromoCode
@@ -395,7 +418,8 @@ func (r *PromoRepository) FindByCodeForUpdate(ctx context.Context, tx pgx.Tx, co
 		&p.ID, &p.Code, &p.Type, &p.SubscriptionDays, &p.TrialDays, &p.ExtraHwidDelta,
 		&p.DiscountPercent, &p.DiscountTTLHours, &p.MaxUses, &p.UsesCount, &p.ValidUntil,
 		&p.Active, &p.FirstPurchaseOnly, &p.RequireCustomerInDB, &p.AllowTrialWithoutPayment, &p.CreatedAt,
-		&p.DiscountMaxSubscriptionPaymentsPerCustomer, &tid,
+		&p.DiscountMaxSubscriptionPaymentsPerCustomer, &tid, &p.BatchID,
+		&p.DiscountAmount, &p.DiscountTariffIDs, &p.DiscountMonths, &p.DiscountInvoiceTypes, &p.DiscountMinAmount, &p.SegmentID,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -415,7 +439,8 @@ func (r *PromoRepository) FindByIDForUpdate(ctx context.Context, tx pgx.Tx, id i
 	q := `SELECT id, code, type, subscription_days, trial_days, extra_hwid_delta,
 		discount_percent, discount_ttl_hours, max_uses, uses_count, valid_until,
 		active, first_purchase_only, require_customer_in_db, allow_trial_without_payment, created_at,
-		discount_max_subscription_payments_per_customer, tariff_id
+		discount_max_subscription_payments_per_customer, tariff_id, batch_id,
+		discount_amount, discount_tariff_ids, discount_months, discount_invoice_types, discount_min_amount, segment_id
 		FROM promo_code WHERE id = $1 FOR UPDATE`
 	row := tx.QueryRow(ctx, q, id)
 	var p PromoCode
@@ -424,7 +449,8 @@ func (r *PromoRepository) FindByIDForUpdate(ctx context.Context, tx pgx.Tx, id i
 		&p.ID, &p.Code, &p.Type, &p.SubscriptionDays, &p.TrialDays, &p.ExtraHwidDelta,
 		&p.DiscountPercent, &p.DiscountTTLHours, &p.MaxUses, &p.UsesCount, &p.ValidUntil,
 		&p.Active, &p.FirstPurchaseOnly, &p.RequireCustomerInDB, &p.AllowTrialWithoutPayment, &p.CreatedAt,
-		&p.DiscountMaxSubscriptionPaymentsPerCustomer, &tid,
+		&p.DiscountMaxSubscriptionPaymentsPerCustomer, &tid, &p.BatchID,
+		&p.DiscountAmount, &p.DiscountTariffIDs, &p.DiscountMonths, &p.DiscountInvoiceTypes, &p.DiscountMinAmount, &p.SegmentID,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -460,11 +486,11 @@ func (r *PromoRepository) DecrementUses(ctx context.Context, tx pgx.Tx, promoID
 }
 
 func (r *PromoRepository) GetPendingDiscountByCustomerID(ctx context.Context, customerID int64) (*PendingDiscount, error) {
-	q := `SELECT id, customer_id, promo_code_id, percent, expires_at, until_first_purchase, created_at, subscription_payments_remaining
+	q := `SELECT id, customer_id, promo_code_id, percent, expires_at, until_first_purchase, created_at, subscription_payments_remaining, amount
 		FROM customer_pending_discount WHERE customer_id = $1`
 	row := r.pool.QueryRow(ctx, q, customerID)
 	var d PendingDiscount
-	err := row.Scan(&d.ID, &d.CustomerID, &d.PromoCodeID, &d.Percent, &d.ExpiresAt, &d.UntilFirstPurchase, &d.CreatedAt, &d.SubscriptionPaymentsRemaining)
+	err := row.Scan(&d.ID, &d.CustomerID, &d.PromoCodeID, &d.Percent, &d.ExpiresAt, &d.UntilFirstPurchase, &d.CreatedAt, &d.SubscriptionPaymentsRemaining, &d.Amount)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -474,28 +500,30 @@ func (r *PromoRepository) GetPendingDiscountByCustomerID(ctx context.Context, cu
 	return &d, nil
 }
 
-func (r *PromoRepository) UpsertPendingDiscount(ctx context.Context, tx pgx.Tx, customerID, promoCodeID int64, percent int, expiresAt *time.Time, untilFirst bool, subscriptionPaymentsRemaining int) error {
+// UpsertPendingDiscount сохраняет скидку клиента: percent — процент, amount — фиксированная сумма в ₽ (обычно задано одно).
+func (r *PromoRepository) UpsertPendingDiscount(ctx context.Context, tx pgx.Tx, customerID, promoCodeID int64, percent, amount int, expiresAt *time.Time, untilFirst bool, subscriptionPaymentsRemaining int) error {
 	_, err := tx.Exec(ctx, `
-INSERT INTO customer_pending_discount (customer_id, promo_code_id, percent, expires_at, until_first_purchase, subscription_payments_remaining)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO customer_pending_discount (customer_id, promo_code_id, percent, expires_at, until_first_purchase, subscription_payments_remaining, amount)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 ON CONFLICT (customer_id) DO UPDATE SET
   promo_code_id = EXCLUDED.promo_code_id,
   percent = EXCLUDED.percent,
+  amount = EXCLUDED.amount,
   expires_at = EXCLUDED.expires_at,
   until_first_purchase = EXCLUDED.until_first_purchase,
   subscription_payments_remaining = EXCLUDED.subscription_payments_remaining,
   created_at = CURRENT_TIMESTAMP
-`, customerID, promoCodeID, percent, expiresAt, untilFirst, subscriptionPaymentsRemaining)
+`, customerID, promoCodeID, percent, expiresAt, untilFirst, subscriptionPaymentsRemaining, amount)
 	return err
 }
 
 // GetPendingDiscountByCustomerIDForUpdate блокирует строку pending discount в транзакции.
 func (r *PromoRepository) GetPendingDiscountByCustomerIDForUpdate(ctx context.Context, tx pgx.Tx, customerID int64) (*PendingDiscount, error) {
-	q := `SELECT id, customer_id, promo_code_id, percent, expires_at, until_first_purchase, created_at, subscription_payments_remaining
+	q := `SELECT id, customer_id, promo_code_id, percent, expires_at, until_first_purchase, created_at, subscription_payments_remaining, amount
 		FROM customer_pending_discount WHERE customer_id = $1 FOR UPDATE`
 	row := tx.QueryRow(ctx, q, customerID)
 	var d PendingDiscount
-	err := row.Scan(&d.ID, &d.CustomerID, &d.PromoCodeID, &d.Percent, &d.ExpiresAt, &d.UntilFirstPurchase, &d.CreatedAt, &d.SubscriptionPaymentsRemaining)
+	err := row.Scan(&d.ID, &d.CustomerID, &d.PromoCodeID, &d.Percent, &d.ExpiresAt, &d.UntilFirstPurchase, &d.CreatedAt, &d.SubscriptionPaymentsRemaining, &d.Amount)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -0,0 +1,219 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// ErrPromoBatchCodesExhausted — не удалось подобрать нужное число уникальных кодов (слишком короткий код или префикс).
+var ErrPromoBatchCodesExhausted = errors.New("failed to generate unique promo codes for batch")
+
+// promoBatchInsertAttempts — сколько раз добираем коды, отброшенные из-за совпадения с уже существующими.
+const promoBatchInsertAttempts = 10
+
+// PromoBatch — пакет одноразовых промокодов одной кампании.
+type PromoBatch struct {
+	ID            int64      `db:"id"`
+	Campaign      string     `db:"campaign"`
+	Prefix        string     `db:"prefix"`
+	CodeCount     int        `db:"code_count"`
+	DeactivatedAt *time.Time `db:"deactivated_at"`
+	CreatedAt     time.Time  `db:"created_at"`
+}
+
+// PromoBatchStats — пакет и состояние его кодов.
+type PromoBatchStats struct {
+	PromoBatch
+	Codes         int
+	ActiveCodes   int
+	RedeemedCodes int
+}
+
+// PromoBatchCode — строка выгрузки пакета: код и его первая активация.
+type PromoBatchCode struct {
+	Code               string
+	Active             bool
+	UsesCount          int
+	RedeemedAt         *time.Time
+	RedeemedTelegramID *int64
+}
+
+const promoBatchStatsSelect = `
+SELECT b.id, b.campaign, b.prefix, b.code_count, b.deactivated_at, b.created_at,
+  COUNT(pc.id)::int,
+  COUNT(pc.id) FILTER (WHERE pc.active)::int,
+  COUNT(pc.id) FILTER (WHERE pc.uses_count > 0)::int
+FROM promo_batch b
+LEFT JOIN promo_code pc ON pc.batch_id = b.id`
+
+func scanPromoBatchStats(row pgx.Row) (*PromoBatchStats, error) {
+	var s PromoBatchStats
+	err := row.Scan(&s.ID, &s.Campaign, &s.Prefix, &s.CodeCount, &s.DeactivatedAt, &s.CreatedAt,
+		&s.Codes, &s.ActiveCodes, &s.RedeemedCodes)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// CreateBatch создаёт пакет и count кодов по шаблону tmpl (Code, MaxUses и UsesCount шаблона игнорируются:
+// каждый код одноразовый). gen выдаёт очередной код-кандидат; совпавшие с существующими отбрасываются и добираются.
+func (r *PromoRepository) CreateBatch(ctx context.Context, b *PromoBatch, tmpl *PromoCode, gen func() (string, error)) (*PromoBatch, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	out := *b
+	err = tx.QueryRow(ctx, `
+		INSERT INTO promo_batch (campaign, prefix, code_count) VALUES ($1, $2, $3)
+		RETURNING id, created_at`,
+		b.Campaign, b.Prefix, b.CodeCount).Scan(&out.ID, &out.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create promo batch: %w", err)
+	}
+
+	inserted := 0
+	for attempt := 0; inserted < b.CodeCount; attempt++ {
+		if attempt == promoBatchInsertAttempts {
+			return nil, ErrPromoBatchCodesExhausted
+		}
+		need := b.CodeCount - inserted
+		codes := make([]string, 0, need)
+		seen := make(map[string]bool, need)
+		for len(codes) < need {
+			code, err := gen()
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate promo code: %w", err)
+			}
+			if !seen[code] {
+				seen[code] = true
+				codes = append(codes, code)
+			}
+		}
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO promo_code (
+				code, type, subscription_days, trial_days, extra_hwid_delta,
+				discount_percent, discount_ttl_hours, max_uses, uses_count, valid_until,
+				active, first_purchase_only, require_customer_in_db, allow_trial_without_payment,
+				discount_max_subscription_payments_per_customer, tariff_id, batch_id,
+				discount_amount, discount_tariff_ids, discount_months, discount_invoice_types, discount_min_amount, segment_id)
+			SELECT c, $2, $3, $4, $5, $6, $7, 1, 0, $8, TRUE, $9, $10, $11, $12, $13, $14, $15, $16::bigint[], $17::int[], $18::text[], $19, $20
+			FROM unnest($1::text[]) AS c
+			ON CONFLICT (code) DO NOTHING`,
+			codes, tmpl.Type, tmpl.SubscriptionDays, tmpl.TrialDays, tmpl.ExtraHwidDelta,
+			tmpl.DiscountPercent, tmpl.DiscountTTLHours, tmpl.ValidUntil,
+			tmpl.FirstPurchaseOnly, tmpl.RequireCustomerInDB, tmpl.AllowTrialWithoutPayment,
+			tmpl.DiscountMaxSubscriptionPaymentsPerCustomer, tmpl.TariffID, out.ID,
+			tmpl.DiscountAmount, tmpl.DiscountTariffIDs, tmpl.DiscountMonths, tmpl.DiscountInvoiceTypes, tmpl.DiscountMinAmount, tmpl.SegmentID)
+		if err != nil {
+			if errors.Is(promoSegmentError(err), ErrSegmentNotFound) {
+				return nil, ErrSegmentNotFound
+			}
+			return nil, fmt.Errorf("failed to insert batch promo codes: %w", err)
+		}
+		inserted += int(tag.RowsAffected())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit promo batch: %w", err)
+	}
+	return &out, nil
+}
+
+// ListBatches — пакеты (campaign != "" — только этой кампании), новые сверху, и общее число.
+func (r *PromoRepository) ListBatches(ctx context.Context, campaign string, offset, limit int) ([]PromoBatchStats, int, error) {
+	var total int
+	if err := r.pool.QueryRow(ctx,
+		`SELECT COUNT(*)::int FROM promo_batch WHERE $1 = '' OR campaign = $1`, campaign,
+	).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count promo batches: %w", err)
+	}
+	rows, err := r.pool.Query(ctx, promoBatchStatsSelect+`
+		WHERE $1 = '' OR b.campaign = $1
+		GROUP BY b.id
+		ORDER BY b.id DESC
+		LIMIT $2 OFFSET $3`, campaign, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query promo batches: %w", err)
+	}
+	defer rows.Close()
+	var out []PromoBatchStats
+	for rows.Next() {
+		s, err := scanPromoBatchStats(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan promo batch: %w", err)
+		}
+		out = append(out, *s)
+	}
+	return out, total, rows.Err()
+}
+
+// FindBatch — пакет со статистикой; nil, если не найден.
+func (r *PromoRepository) FindBatch(ctx context.Context, id int64) (*PromoBatchStats, error) {
+	s, err := scanPromoBatchStats(r.pool.QueryRow(ctx, promoBatchStatsSelect+`
+		WHERE b.id = $1
+		GROUP BY b.id`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query promo batch: %w", err)
+	}
+	return s, nil
+}
+
+// DeactivateBatch выключает все коды пакета; возвращает число выключенных кодов.
+func (r *PromoRepository) DeactivateBatch(ctx context.Context, id int64) (int64, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	tag, err := tx.Exec(ctx, `UPDATE promo_code SET active = FALSE WHERE batch_id = $1 AND active`, id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to deactivate batch promo codes: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE promo_batch SET deactivated_at = COALESCE(deactivated_at, NOW()) WHERE id = $1`, id); err != nil {
+		return 0, fmt.Errorf("failed to mark promo batch deactivated: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit promo batch deactivation: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ListBatchCodes — все коды пакета в порядке создания с первой активацией (для выгрузки в CSV).
+func (r *PromoRepository) ListBatchCodes(ctx context.Context, id int64) ([]PromoBatchCode, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT pc.code, pc.active, pc.uses_count, pr.used_at, c.telegram_id
+		FROM promo_code pc
+		LEFT JOIN LATERAL (
+			SELECT used_at, customer_id FROM promo_redemption
+			WHERE promo_code_id = pc.id
+			ORDER BY used_at
+			LIMIT 1
+		) pr ON TRUE
+		LEFT JOIN customer c ON c.id = pr.customer_id
+		WHERE pc.batch_id = $1
+		ORDER BY pc.id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query batch promo codes: %w", err)
+	}
+	defer rows.Close()
+	var out []PromoBatchCode
+	for rows.Next() {
+		var c PromoBatchCode
+		if err := rows.Scan(&c.Code, &c.Active, &c.UsesCount, &c.RedeemedAt, &c.RedeemedTelegramID); err != nil {
+			return nil, fmt.Errorf("failed to scan batch promo code: %w", err)
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
@@ -25,6 +26,8 @@ const (
 	InvoiceTypePlategaAcquiring InvoiceType = "plt_acq"
 	InvoiceTypePlategaWorldwide InvoiceType = "plt_ww"
 	InvoiceTypePlategaCrypto    InvoiceType = "plt_crypto"
+	// InvoiceTypeBalance — оплата с внутреннего баланса клиента (customer_balance), проводится сразу.
+	InvoiceTypeBalance InvoiceType = "balance"
 )
 
 // PlategaInvoiceTypes перечисляет все invoice_type Platega (поллинг, статистика).
@@ -61,8 +64,16 @@ const (
 	PurchaseStatusPending PurchaseStatus = "pending"
 	PurchaseStatusPaid    PurchaseStatus = "paid"
 	PurchaseStatusCancel  PurchaseStatus = "cancel"
+	// Возвраты (см. purchase_refund): частичный — часть суммы вернули, эффекты откатили пропорционально.
+	PurchaseStatusRefunded          PurchaseStatus = "refunded"
+	PurchaseStatusPartiallyRefunded PurchaseStatus = "partially_refunded"
 )
 
+// IsRefunded — true для полного и частичного возврата.
+func (s PurchaseStatus) IsRefunded() bool {
+	return s == PurchaseStatusRefunded || s == PurchaseStatusPartiallyRefunded
+}
+
 // PurchaseKind вид строки покупки (тарифы / доплата и т.д.).
 type PurchaseKind string
 
@@ -70,6 +81,12 @@ const (
 	PurchaseKindSubscription  PurchaseKind = "subscription"
 	PurchaseKindTariffUpgrade PurchaseKind = "tariff_upgrade"
 	PurchaseKindExtraHwid     PurchaseKind = "extra_hwid"
+	// PurchaseKindGift — подписка в подарок: покупатель получает код (gift_subscription), дни — тот, кто его активирует.
+	PurchaseKindGift PurchaseKind = "gift"
+	// PurchaseKindBalanceTopUp — пополнение баланса: сумма зачисляется в customer_balance, дни не добавляются.
+	PurchaseKindBalanceTopUp PurchaseKind = "balance_topup"
+	// PurchaseKindTrafficPack — пакет трафика: лимит в панели растёт на traffic_gb до ближайшего сброса.
+	PurchaseKindTrafficPack PurchaseKind = "traffic_pack"
 )
 
 type Purchase struct {
@@ -95,8 +112,22 @@ type Purchase struct {
 	TariffID               *int64         `db:"tariff_id"`
 	PurchaseKind           PurchaseKind   `db:"purchase_kind"`
 	IsEarlyDowngrade       bool           `db:"is_early_downgrade"`
+	TelegramChargeID       *string        `db:"telegram_charge_id"`
+	RefundedAmount         float64        `db:"refunded_amount"`
+	RefundedAt             *time.Time     `db:"refunded_at"`
+	// IsAutoRenew — покупка создана автосписанием с сохранённого способа оплаты (customer_auto_renew).
+	IsAutoRenew bool `db:"is_auto_renew"`
+	// ReconciledAt — когда сверка последний раз спрашивала статус у провайдера (см. FindStaleUnpaid).
+	ReconciledAt *time.Time `db:"reconciled_at"`
+	// StarsSubscriptionID — первая оплата или продление подписки Telegram Stars (stars_subscription).
+	StarsSubscriptionID *int64 `db:"stars_subscription_id"`
+	// TrafficGB — объём пакета трафика (purchase_kind traffic_pack), ГБ.
+	TrafficGB int `db:"traffic_gb"`
 }
 
+// ErrPurchaseTelegramChargeTaken — покупка с таким telegram_charge_id уже есть (повторная доставка оплаты Stars).
+var ErrPurchaseTelegramChargeTaken = errors.New("purchase with this telegram charge id already exists")
+
 type PurchaseRepository struct {
 	pool *pgxpool.Pool
 }
@@ -108,7 +139,7 @@ func NewPurchaseRepository(pool *pgxpool.Pool) *PurchaseRepository {
 }
 
 // purchaseScanArgs returns pointers for scanning a full purchase row (column order must match SELECT * from purchase).
-// Порядок колонок в PostgreSQL — порядок CREATE + ALTER ADD (см. миграции 000001, 000005 extra_hwid, 000007 promo, 000008 tariff, 000032 platega).
+// Порядок колонок в PostgreSQL — порядок CREATE + ALTER ADD (см. миграции 000001, 000005 extra_hwid, 000007 promo, 000008 tariff, 000032 platega, 000041 refund, 000043 auto renew, 000048 reconcile, 000050 stars subscription, 000057 traffic pack).
 func purchaseScanArgs(p *Purchase) []interface{} {
 	return []interface{}{
 		&p.ID, &p.Amount, &p.CustomerID, &p.CreatedAt, &p.Month,
@@ -118,6 +149,11 @@ func purchaseScanArgs(p *Purchase) []interface{} {
 		&p.PromoCodeID, &p.DiscountPercentApplied,
 		&p.TariffID, &p.PurchaseKind, &p.IsEarlyDowngrade,
 		&p.PlategaID, &p.PlategaURL,
+		&p.TelegramChargeID, &p.RefundedAmount, &p.RefundedAt,
+		&p.IsAutoRenew,
+		&p.ReconciledAt,
+		&p.StarsSubscriptionID,
+		&p.TrafficGB,
 	}
 }
 
@@ -126,8 +162,8 @@ func (cr *PurchaseRepository) Create(ctx context.Context, purchase *Purchase) (i
 		purchase.PurchaseKind = PurchaseKindSubscription
 	}
 	buildInsert := sq.Insert("purchase").
-		Columns("amount", "customer_id", "month", "currency", "expire_at", "status", "invoice_type", "crypto_invoice_id", "crypto_invoice_url", "yookasa_url", "yookasa_id", "platega_id", "platega_url", "extra_hwid", "promo_code_id", "discount_percent_applied", "tariff_id", "purchase_kind", "is_early_downgrade").
-		Values(purchase.Amount, purchase.CustomerID, purchase.Month, purchase.Currency, purchase.ExpireAt, purchase.Status, purchase.InvoiceType, purchase.CryptoInvoiceID, purchase.CryptoInvoiceLink, purchase.YookasaURL, purchase.YookasaID, purchase.PlategaID, purchase.PlategaURL, purchase.ExtraHwid, purchase.PromoCodeID, purchase.DiscountPercentApplied, purchase.TariffID, purchase.PurchaseKind, purchase.IsEarlyDowngrade).
+		Columns("amount", "customer_id", "month", "currency", "expire_at", "status", "invoice_type", "crypto_invoice_id", "crypto_invoice_url", "yookasa_url", "yookasa_id", "platega_id", "platega_url", "extra_hwid", "promo_code_id", "discount_percent_applied", "tariff_id", "purchase_kind", "is_early_downgrade", "is_auto_renew", "telegram_charge_id", "stars_subscription_id", "traffic_gb").
+		Values(purchase.Amount, purchase.CustomerID, purchase.Month, purchase.Currency, purchase.ExpireAt, purchase.Status, purchase.InvoiceType, purchase.CryptoInvoiceID, purchase.CryptoInvoiceLink, purchase.YookasaURL, purchase.YookasaID, purchase.PlategaID, purchase.PlategaURL, purchase.ExtraHwid, purchase.PromoCodeID, purchase.DiscountPercentApplied, purchase.TariffID, purchase.PurchaseKind, purchase.IsEarlyDowngrade, purchase.IsAutoRenew, purchase.TelegramChargeID, purchase.StarsSubscriptionID, purchase.TrafficGB).
 		Suffix("RETURNING id").
 		PlaceholderFormat(sq.Dollar)
 
@@ -139,6 +175,9 @@ func (cr *PurchaseRepository) Create(ctx context.Context, purchase *Purchase) (i
 	var id int64
 	err = cr.pool.QueryRow(ctx, sql, args...).Scan(&id)
 	if err != nil {
+		if strings.Contains(err.Error(), "23505") {
+			return 0, ErrPurchaseTelegramChargeTaken
+		}
 		return 0, err
 	}
 
@@ -250,6 +289,66 @@ func (p *PurchaseRepository) UpdateFields(ctx context.Context, id int64, updates
 	return nil
 }
 
+// FindStaleUnpaid — new/pending-покупки провайдеров invoiceTypes, созданные раньше before и не сверявшиеся
+// с тех пор (reconciled_at пусто или раньше before). Несверенные и давно сверенные — первыми.
+func (pr *PurchaseRepository) FindStaleUnpaid(ctx context.Context, invoiceTypes []InvoiceType, before time.Time, limit int) ([]Purchase, error) {
+	if len(invoiceTypes) == 0 {
+		return nil, nil
+	}
+	query, args, err := sq.Select("*").
+		From("purchase").
+		Where(sq.Eq{
+			"status":       []PurchaseStatus{PurchaseStatusNew, PurchaseStatusPending},
+			"invoice_type": invoiceTypes,
+		}).
+		Where(sq.Lt{"created_at": before}).
+		Where(sq.Or{sq.Eq{"reconciled_at": nil}, sq.Lt{"reconciled_at": before}}).
+		OrderBy("reconciled_at NULLS FIRST", "created_at").
+		Limit(uint64(limit)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build query: %w", err)
+	}
+	rows, err := pr.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stale purchases: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Purchase
+	for rows.Next() {
+		var p Purchase
+		if err := rows.Scan(purchaseScanArgs(&p)...); err != nil {
+			return nil, fmt.Errorf("failed to scan purchase: %w", err)
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return out, nil
+}
+
+// MarkReconciled отмечает, что статус покупок только что сверен с провайдером.
+func (pr *PurchaseRepository) MarkReconciled(ctx context.Context, ids []int64, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	query, args, err := sq.Update("purchase").
+		Set("reconciled_at", at).
+		Where(sq.Eq{"id": ids}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf("build query: %w", err)
+	}
+	if _, err := pr.pool.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to mark purchases reconciled: %w", err)
+	}
+	return nil
+}
+
 func (pr *PurchaseRepository) MarkAsPaid(ctx context.Context, purchaseID int64) error {
 	currentTime := time.Now()
 
@@ -354,7 +453,7 @@ func (pr *PurchaseRepository) FindPaidByCustomer(ctx context.Context, customerID
 		From("purchase").
 		Where(sq.And{
 			sq.Eq{"customer_id": customerID},
-			sq.Eq{"status": PurchaseStatusPaid},
+			sq.Eq{"status": []PurchaseStatus{PurchaseStatusPaid, PurchaseStatusPartiallyRefunded}},
 		}).
 		OrderBy("paid_at DESC").
 		Limit(uint64(limit)).
@@ -423,7 +522,7 @@ func (pr *PurchaseRepository) ListAllPaidForLoyaltyBackfill(ctx context.Context)
 }
 
 // SumPaidSpendBreakdown возвращает по успешным оплатам клиента: счёт и сумму в рублях (RUB/RUR/пустая валюта,
-// без строк Telegram Stars) и отдельно счёт и сумму amount по Stars (invoice_type telegram или валюта STARS/XTR).
+// без строк Telegram Stars и пополнений баланса) и отдельно счёт и сумму amount по Stars (invoice_type telegram или валюта STARS/XTR).
 func (pr *PurchaseRepository) SumPaidSpendBreakdown(ctx context.Context, customerID int64) (
 	rubCount int64, rubSum float64, starsCount int64, starsSum float64, err error,
 ) {
@@ -435,14 +534,14 @@ SELECT
   ) AND (
     UPPER(TRIM(COALESCE(p.currency, ''))) IN ('RUB', 'RUR', '')
     OR COALESCE(p.currency, '') = ''
-  )),
+  ) AND p.purchase_kind IS DISTINCT FROM 'balance_topup'),
   COALESCE(SUM(p.amount) FILTER (WHERE p.status = 'paid' AND NOT (
     p.invoice_type = 'telegram'
     OR UPPER(TRIM(COALESCE(p.currency, ''))) IN ('STARS', 'XTR')
   ) AND (
     UPPER(TRIM(COALESCE(p.currency, ''))) IN ('RUB', 'RUR', '')
     OR COALESCE(p.currency, '') = ''
-  )), 0),
+  ) AND p.purchase_kind IS DISTINCT FROM 'balance_topup'), 0),
   COUNT(*) FILTER (WHERE p.status = 'paid' AND (
     p.invoice_type = 'telegram'
     OR UPPER(TRIM(COALESCE(p.currency, ''))) IN ('STARS', 'XTR')
@@ -466,7 +565,7 @@ func (pr *PurchaseRepository) CountPaidByCustomer(ctx context.Context, customerI
 		From("purchase").
 		Where(sq.And{
 			sq.Eq{"customer_id": customerID},
-			sq.Eq{"status": PurchaseStatusPaid},
+			sq.Eq{"status": []PurchaseStatus{PurchaseStatusPaid, PurchaseStatusPartiallyRefunded}},
 		}).
 		PlaceholderFormat(sq.Dollar)
 
@@ -489,6 +588,7 @@ func (pr *PurchaseRepository) CountPaidSubscriptionsByCustomer(ctx context.Conte
 			sq.Eq{"customer_id": customerID},
 			sq.Eq{"status": PurchaseStatusPaid},
 			sq.Gt{"month": 0},
+			sq.NotEq{"purchase_kind": PurchaseKindGift},
 		}).
 		PlaceholderFormat(sq.Dollar)
 
@@ -513,6 +613,7 @@ func (pr *PurchaseRepository) HasPaidSubscription(ctx context.Context, customerI
 			sq.Eq{"customer_id": customerID},
 			sq.Eq{"status": PurchaseStatusPaid},
 			sq.Gt{"month": 0},
+			sq.NotEq{"purchase_kind": PurchaseKindGift},
 		}).
 		Limit(1).
 		PlaceholderFormat(sq.Dollar)
@@ -533,6 +634,26 @@ func (pr *PurchaseRepository) HasPaidSubscription(ctx context.Context, customerI
 	return true, nil
 }
 
+// FindByTelegramChargeID — покупка по telegram_payment_charge_id оплаты Stars; nil, если такой нет.
+func (pr *PurchaseRepository) FindByTelegramChargeID(ctx context.Context, chargeID string) (*Purchase, error) {
+	query, args, err := sq.Select("*").
+		From("purchase").
+		Where(sq.Eq{"telegram_charge_id": chargeID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build query: %w", err)
+	}
+	p := &Purchase{}
+	if err := pr.pool.QueryRow(ctx, query, args...).Scan(purchaseScanArgs(p)...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find purchase by telegram charge id: %w", err)
+	}
+	return p, nil
+}
+
 func (pr *PurchaseRepository) FindByCustomerIDAndInvoiceTypeLast(
 	ctx context.Context,
 	customerID int64,
@@ -565,3 +686,28 @@ func (pr *PurchaseRepository) FindByCustomerIDAndInvoiceTypeLast(
 
 	return p, nil
 }
+
+// HasPendingAutoRenew — у клиента есть незавершённое автосписание (ЮKassa ещё не ответила окончательно).
+func (pr *PurchaseRepository) HasPendingAutoRenew(ctx context.Context, customerID int64) (bool, error) {
+	query, args, err := sq.Select("1").
+		From("purchase").
+		Where(sq.Eq{
+			"customer_id":   customerID,
+			"is_auto_renew": true,
+			"status":        []PurchaseStatus{PurchaseStatusNew, PurchaseStatusPending},
+		}).
+		Limit(1).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return false, fmt.Errorf("build query: %w", err)
+	}
+	var one int
+	if err := pr.pool.QueryRow(ctx, query, args...).Scan(&one); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("query pending auto renew: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,315 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type PurchaseOutboxStatus string
+
+const (
+	PurchaseOutboxStatusPending  PurchaseOutboxStatus = "pending"
+	PurchaseOutboxStatusDone     PurchaseOutboxStatus = "done"
+	PurchaseOutboxStatusFailed   PurchaseOutboxStatus = "failed"
+	PurchaseOutboxStatusCanceled PurchaseOutboxStatus = "canceled"
+)
+
+// PurchaseOutboxKind — побочный эффект оплаты. Apply выполняется первым, остальные ждут его завершения.
+type PurchaseOutboxKind string
+
+const (
+	PurchaseOutboxKindApply              PurchaseOutboxKind = "apply"
+	PurchaseOutboxKindMoynalogReceipt    PurchaseOutboxKind = "moynalog_receipt"
+	PurchaseOutboxKindNotifyUser         PurchaseOutboxKind = "notify_user"
+	PurchaseOutboxKindReferralBonus      PurchaseOutboxKind = "referral_bonus"
+	PurchaseOutboxKindPromo              PurchaseOutboxKind = "promo"
+	PurchaseOutboxKindLoyaltyXP          PurchaseOutboxKind = "loyalty_xp"
+	PurchaseOutboxKindReferralCommission PurchaseOutboxKind = "referral_commission"
+	PurchaseOutboxKindAutoRenewMethod    PurchaseOutboxKind = "auto_renew_method"
+	PurchaseOutboxKindNotifyAdmin        PurchaseOutboxKind = "notify_admin"
+	PurchaseOutboxKindFamilySync         PurchaseOutboxKind = "family_sync"
+)
+
+// ErrPurchaseOutboxNotRetryable — у покупки нет шагов в failed.
+var ErrPurchaseOutboxNotRetryable = errors.New("purchase outbox has no failed tasks")
+
+// PurchaseOutboxTask — шаг проведения оплаченной покупки (purchase_outbox).
+type PurchaseOutboxTask struct {
+	ID             int64                `db:"id"`
+	PurchaseID     int64                `db:"purchase_id"`
+	Step           int                  `db:"step"`
+	Kind           PurchaseOutboxKind   `db:"kind"`
+	IdempotencyKey string               `db:"idempotency_key"`
+	Payload        []byte               `db:"payload"`
+	Status         PurchaseOutboxStatus `db:"status"`
+	Attempts       int                  `db:"attempts"`
+	LastError      *string              `db:"last_error"`
+	NextAttemptAt  *time.Time           `db:"next_attempt_at"`
+	CreatedAt      time.Time            `db:"created_at"`
+	UpdatedAt      time.Time            `db:"updated_at"`
+	DoneAt         *time.Time           `db:"done_at"`
+}
+
+// PurchaseOutboxIdempotencyKey — ключ шага: один шаг каждого вида на покупку.
+func PurchaseOutboxIdempotencyKey(purchaseID int64, kind PurchaseOutboxKind) string {
+	return fmt.Sprintf("purchase:%d:%s", purchaseID, kind)
+}
+
+const purchaseOutboxColumns = "id, purchase_id, step, kind, idempotency_key, payload, status, attempts, last_error, " +
+	"next_attempt_at, created_at, updated_at, done_at"
+
+func purchaseOutboxScanArgs(t *PurchaseOutboxTask) []interface{} {
+	return []interface{}{
+		&t.ID, &t.PurchaseID, &t.Step, &t.Kind, &t.IdempotencyKey, &t.Payload, &t.Status, &t.Attempts, &t.LastError,
+		&t.NextAttemptAt, &t.CreatedAt, &t.UpdatedAt, &t.DoneAt,
+	}
+}
+
+type PurchaseOutboxRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPurchaseOutboxRepository(pool *pgxpool.Pool) *PurchaseOutboxRepository {
+	return &PurchaseOutboxRepository{pool: pool}
+}
+
+func (r *PurchaseOutboxRepository) queryOne(ctx context.Context, query string, args ...interface{}) (*PurchaseOutboxTask, error) {
+	var out PurchaseOutboxTask
+	if err := r.pool.QueryRow(ctx, query, args...).Scan(purchaseOutboxScanArgs(&out)...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &out, nil
+}
+
+// MarkPaid переводит покупку в paid и ставит шаги tasks в очередь одной транзакцией (первая попытка — в paidAt).
+// false — покупка уже оплачена или возвращена: её провёл параллельный вебхук / поллер.
+func (r *PurchaseOutboxRepository) MarkPaid(ctx context.Context, purchaseID int64, paidAt time.Time, tasks []PurchaseOutboxTask) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var id int64
+	err = tx.QueryRow(ctx, `
+		UPDATE purchase SET status = $2, paid_at = $3
+		WHERE id = $1 AND status NOT IN ($2, $4, $5)
+		RETURNING id`,
+		purchaseID, PurchaseStatusPaid, paidAt, PurchaseStatusRefunded, PurchaseStatusPartiallyRefunded).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to mark purchase paid: %w", err)
+	}
+
+	for _, t := range tasks {
+		payload := t.Payload
+		if len(payload) == 0 {
+			payload = []byte("{}")
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO purchase_outbox (purchase_id, step, kind, idempotency_key, payload, next_attempt_at)
+			VALUES ($1, $2, $3, $4, $5::jsonb, $6)
+			ON CONFLICT (idempotency_key) DO NOTHING`,
+			purchaseID, t.Step, t.Kind, PurchaseOutboxIdempotencyKey(purchaseID, t.Kind), string(payload), paidAt); err != nil {
+			return false, fmt.Errorf("failed to insert purchase outbox task: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// ListByPurchase — шаги покупки в порядке выполнения.
+func (r *PurchaseOutboxRepository) ListByPurchase(ctx context.Context, purchaseID int64) ([]PurchaseOutboxTask, error) {
+	return r.list(ctx, sq.Select(purchaseOutboxColumns).
+		From("purchase_outbox").
+		Where(sq.Eq{"purchase_id": purchaseID}).
+		OrderBy("step ASC", "id ASC"))
+}
+
+// FindDuePurchaseIDs — покупки, у которых есть шаг, готовый к попытке. Шаги после apply не считаются,
+// пока apply не выполнен.
+func (r *PurchaseOutboxRepository) FindDuePurchaseIDs(ctx context.Context, now time.Time, limit int) ([]int64, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT o.purchase_id
+		FROM purchase_outbox o
+		WHERE o.status = 'pending' AND o.next_attempt_at <= $1
+		  AND (o.kind = $2 OR NOT EXISTS (
+			SELECT 1 FROM purchase_outbox a
+			WHERE a.purchase_id = o.purchase_id AND a.kind = $2 AND a.status <> 'done'))
+		GROUP BY o.purchase_id
+		ORDER BY MIN(o.next_attempt_at)
+		LIMIT $3`, now, PurchaseOutboxKindApply, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due purchase outbox: %w", err)
+	}
+	defer rows.Close()
+	var out []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan purchase outbox purchase id: %w", err)
+		}
+		out = append(out, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating purchase outbox rows: %w", err)
+	}
+	return out, nil
+}
+
+// List — шаги в статусах statuses (пусто — все), последние изменённые первыми.
+func (r *PurchaseOutboxRepository) List(ctx context.Context, statuses []PurchaseOutboxStatus, limit, offset int) ([]PurchaseOutboxTask, error) {
+	b := sq.Select(purchaseOutboxColumns).
+		From("purchase_outbox").
+		OrderBy("updated_at DESC", "id DESC").
+		Limit(uint64(limit)).
+		Offset(uint64(offset))
+	if len(statuses) > 0 {
+		b = b.Where(sq.Eq{"status": statuses})
+	}
+	return r.list(ctx, b)
+}
+
+func (r *PurchaseOutboxRepository) list(ctx context.Context, b sq.SelectBuilder) ([]PurchaseOutboxTask, error) {
+	query, args, err := b.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query purchase outbox: %w", err)
+	}
+	defer rows.Close()
+	var out []PurchaseOutboxTask
+	for rows.Next() {
+		var t PurchaseOutboxTask
+		if err := rows.Scan(purchaseOutboxScanArgs(&t)...); err != nil {
+			return nil, fmt.Errorf("failed to scan purchase outbox task: %w", err)
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating purchase outbox rows: %w", err)
+	}
+	return out, nil
+}
+
+// Claim атомарно откладывает следующую попытку до until. nil — шаг уже забрал параллельный запуск
+// или он больше не ждёт выполнения.
+func (r *PurchaseOutboxRepository) Claim(ctx context.Context, id int64, now, until time.Time) (*PurchaseOutboxTask, error) {
+	out, err := r.queryOne(ctx, `
+		UPDATE purchase_outbox SET next_attempt_at = $3, updated_at = NOW()
+		WHERE id = $1 AND status = 'pending' AND next_attempt_at <= $2
+		RETURNING `+purchaseOutboxColumns, id, now, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim purchase outbox task: %w", err)
+	}
+	return out, nil
+}
+
+// SavePayload сохраняет промежуточное состояние шага (уже выполненные части), не меняя статус.
+func (r *PurchaseOutboxRepository) SavePayload(ctx context.Context, id int64, payload []byte) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE purchase_outbox SET payload = $2::jsonb, updated_at = NOW()
+		WHERE id = $1 AND status = 'pending'`, id, string(payload))
+	if err != nil {
+		return fmt.Errorf("failed to save purchase outbox payload: %w", err)
+	}
+	return nil
+}
+
+// MarkDone — шаг выполнен; payload — итоговое состояние.
+func (r *PurchaseOutboxRepository) MarkDone(ctx context.Context, id int64, payload []byte) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE purchase_outbox SET status = 'done', payload = $2::jsonb, last_error = NULL, next_attempt_at = NULL,
+			done_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'pending'`, id, string(payload))
+	if err != nil {
+		return fmt.Errorf("failed to mark purchase outbox task done: %w", err)
+	}
+	return nil
+}
+
+// CompleteLoyaltyXP начисляет клиенту XP и закрывает шаг одной транзакцией: повтор после сбоя
+// не начислит XP второй раз. false — шаг уже закрыт.
+func (r *PurchaseOutboxRepository) CompleteLoyaltyXP(ctx context.Context, id, customerID, gain int64) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	res, err := tx.Exec(ctx, `
+		UPDATE purchase_outbox SET status = 'done', last_error = NULL, next_attempt_at = NULL, done_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'pending'`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark purchase outbox task done: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return false, nil
+	}
+	res, err = tx.Exec(ctx, `UPDATE customer SET loyalty_xp = loyalty_xp + $2 WHERE id = $1`, customerID, gain)
+	if err != nil {
+		return false, fmt.Errorf("increment loyalty_xp: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return false, fmt.Errorf("customer not found: %d", customerID)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// RecordFailure увеличивает счётчик неудач. final=true переводит шаг в failed, иначе следующая попытка — в nextAttemptAt.
+func (r *PurchaseOutboxRepository) RecordFailure(ctx context.Context, id int64, errText string, nextAttemptAt *time.Time, final bool) (*PurchaseOutboxTask, error) {
+	out, err := r.queryOne(ctx, `
+		UPDATE purchase_outbox SET attempts = attempts + 1, last_error = $2,
+			next_attempt_at = CASE WHEN $4::boolean THEN NULL ELSE $3::timestamptz END,
+			status = CASE WHEN $4::boolean THEN 'failed' ELSE status END,
+			updated_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+		RETURNING `+purchaseOutboxColumns, id, errText, nextAttemptAt, final)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record purchase outbox failure: %w", err)
+	}
+	return out, nil
+}
+
+// CancelPending закрывает невыполненные шаги покупки (покупку вернули до их выполнения).
+func (r *PurchaseOutboxRepository) CancelPending(ctx context.Context, purchaseID int64) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE purchase_outbox SET status = 'canceled', next_attempt_at = NULL, updated_at = NOW()
+		WHERE purchase_id = $1 AND status IN ('pending', 'failed')`, purchaseID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel purchase outbox tasks: %w", err)
+	}
+	return nil
+}
+
+// Retry возвращает failed-шаги покупки в очередь (попытка в now) с обнулённым счётчиком попыток.
+func (r *PurchaseOutboxRepository) Retry(ctx context.Context, purchaseID int64, now time.Time) error {
+	res, err := r.pool.Exec(ctx, `
+		UPDATE purchase_outbox SET status = 'pending', attempts = 0, next_attempt_at = $2, updated_at = NOW()
+		WHERE purchase_id = $1 AND status = 'failed'`, purchaseID, now)
+	if err != nil {
+		return fmt.Errorf("failed to retry purchase outbox tasks: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return ErrPurchaseOutboxNotRetryable
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type RefundStatus string
+
+const (
+	RefundStatusPending   RefundStatus = "pending"
+	RefundStatusSucceeded RefundStatus = "succeeded"
+	// RefundStatusManual — у провайдера нет API возврата: эффекты откатили, деньги админ возвращает сам.
+	RefundStatusManual RefundStatus = "manual"
+	RefundStatusFailed RefundStatus = "failed"
+)
+
+// RefundSource — откуда инициирован возврат.
+type RefundSource string
+
+const (
+	RefundSourceBot     RefundSource = "bot"
+	RefundSourceCabinet RefundSource = "cabinet"
+)
+
+// ErrRefundInProgress — по покупке уже есть незавершённый возврат (uq_purchase_refund_pending).
+var ErrRefundInProgress = errors.New("refund already in progress")
+
+// refundAmountEpsilon — допуск при сравнении сумм DECIMAL(20,8), пришедших как float64.
+const refundAmountEpsilon = 0.005
+
+type PurchaseRefund struct {
+	ID               int64        `db:"id"`
+	PurchaseID       int64        `db:"purchase_id"`
+	CustomerID       int64        `db:"customer_id"`
+	Amount           float64      `db:"amount"`
+	Currency         string       `db:"currency"`
+	Status           RefundStatus `db:"status"`
+	ProviderRefundID *string      `db:"provider_refund_id"`
+	Reason           *string      `db:"reason"`
+	Source           RefundSource `db:"source"`
+	AdminTelegramID  *int64       `db:"admin_telegram_id"`
+	AdminAccountID   *int64       `db:"admin_account_id"`
+	DaysRevoked      int          `db:"days_revoked"`
+	HwidRevoked      int          `db:"hwid_revoked"`
+	XPRevoked        int64        `db:"xp_revoked"`
+	PromoRestored    bool         `db:"promo_restored"`
+	Error            *string      `db:"error"`
+	CreatedAt        time.Time    `db:"created_at"`
+	CompletedAt      *time.Time   `db:"completed_at"`
+}
+
+const purchaseRefundColumns = "id, purchase_id, customer_id, amount, COALESCE(currency, ''), status, provider_refund_id, reason, source, " +
+	"admin_telegram_id, admin_account_id, days_revoked, hwid_revoked, xp_revoked, promo_restored, error, created_at, completed_at"
+
+func purchaseRefundScanArgs(r *PurchaseRefund) []interface{} {
+	return []interface{}{
+		&r.ID, &r.PurchaseID, &r.CustomerID, &r.Amount, &r.Currency, &r.Status, &r.ProviderRefundID, &r.Reason, &r.Source,
+		&r.AdminTelegramID, &r.AdminAccountID, &r.DaysRevoked, &r.HwidRevoked, &r.XPRevoked, &r.PromoRestored, &r.Error, &r.CreatedAt, &r.CompletedAt,
+	}
+}
+
+type PurchaseRefundRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPurchaseRefundRepository(pool *pgxpool.Pool) *PurchaseRefundRepository {
+	return &PurchaseRefundRepository{pool: pool}
+}
+
+// CreatePending вставляет строку возврата в статусе pending. Второй pending по той же покупке → ErrRefundInProgress.
+func (r *PurchaseRefundRepository) CreatePending(ctx context.Context, refund *PurchaseRefund) (int64, error) {
+	query := sq.Insert("purchase_refund").
+		Columns("purchase_id", "customer_id", "amount", "currency", "status", "reason", "source", "admin_telegram_id", "admin_account_id").
+		Values(refund.PurchaseID, refund.CustomerID, refund.Amount, refund.Currency, RefundStatusPending, refund.Reason, refund.Source, refund.AdminTelegramID, refund.AdminAccountID).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar)
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return 0, fmt.Errorf("failed to build insert refund query: %w", err)
+	}
+	var id int64
+	if err := r.pool.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
+		if strings.Contains(err.Error(), "23505") {
+			return 0, ErrRefundInProgress
+		}
+		return 0, fmt.Errorf("failed to insert refund: %w", err)
+	}
+	return id, nil
+}
+
+// UpdateFields обновляет произвольные поля строки возврата.
+func (r *PurchaseRefundRepository) UpdateFields(ctx context.Context, id int64, updates map[string]interface{}) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	buildUpdate := sq.Update("purchase_refund").
+		PlaceholderFormat(sq.Dollar).
+		Where(sq.Eq{"id": id})
+	for field, value := range updates {
+		buildUpdate = buildUpdate.Set(field, value)
+	}
+	sql, args, err := buildUpdate.ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build update refund query: %w", err)
+	}
+	res, err := r.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update refund: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return fmt.Errorf("no refund found with id: %d", id)
+	}
+	return nil
+}
+
+// MarkFailed закрывает pending-строку с ошибкой провайдера.
+func (r *PurchaseRefundRepository) MarkFailed(ctx context.Context, id int64, cause error) error {
+	msg := ""
+	if cause != nil {
+		msg = cause.Error()
+	}
+	return r.UpdateFields(ctx, id, map[string]interface{}{
+		"status":       RefundStatusFailed,
+		"error":        msg,
+		"completed_at": time.Now().UTC(),
+	})
+}
+
+func (r *PurchaseRefundRepository) FindByID(ctx context.Context, id int64) (*PurchaseRefund, error) {
+	var out PurchaseRefund
+	err := r.pool.QueryRow(ctx, "SELECT "+purchaseRefundColumns+" FROM purchase_refund WHERE id = $1", id).Scan(purchaseRefundScanArgs(&out)...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query refund: %w", err)
+	}
+	return &out, nil
+}
+
+// ListByPurchase — все попытки возврата по покупке (новые сверху).
+func (r *PurchaseRefundRepository) ListByPurchase(ctx context.Context, purchaseID int64) ([]PurchaseRefund, error) {
+	return r.list(ctx, "SELECT "+purchaseRefundColumns+" FROM purchase_refund WHERE purchase_id = $1 ORDER BY created_at DESC", purchaseID)
+}
+
+// ListRecent — последние возвраты по всем клиентам (админка кабинета).
+func (r *PurchaseRefundRepository) ListRecent(ctx context.Context, limit, offset int) ([]PurchaseRefund, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	return r.list(ctx, "SELECT "+purchaseRefundColumns+" FROM purchase_refund ORDER BY created_at DESC LIMIT $1 OFFSET $2", limit, offset)
+}
+
+func (r *PurchaseRefundRepository) list(ctx context.Context, q string, args ...interface{}) ([]PurchaseRefund, error) {
+	rows, err := r.pool.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query refunds: %w", err)
+	}
+	defer rows.Close()
+
+	var out []PurchaseRefund
+	for rows.Next() {
+		var item PurchaseRefund
+		if err := rows.Scan(purchaseRefundScanArgs(&item)...); err != nil {
+			return nil, fmt.Errorf("failed to scan refund: %w", err)
+		}
+		out = append(out, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating refunds: %w", err)
+	}
+	return out, nil
+}
+
+// RefundStatusAfter — итоговый статус покупки после возврата суммы refundedTotal из amount.
+func RefundStatusAfter(amount, refundedTotal float64) PurchaseStatus {
+	if refundedTotal+refundAmountEpsilon >= amount {
+		return PurchaseStatusRefunded
+	}
+	return PurchaseStatusPartiallyRefunded
+}
+
+// RefundableRemainder — сколько ещё можно вернуть по покупке.
+func RefundableRemainder(p *Purchase) float64 {
+	if p == nil {
+		return 0
+	}
+	rest := p.Amount - p.RefundedAmount
+	if rest < refundAmountEpsilon {
+		return 0
+	}
+	return rest
+}
+
+// ApplyRefundToPurchase атомарно увеличивает refunded_amount и выставляет refunded / partially_refunded.
+// Условие в WHERE не даёт вернуть больше оплаченного, даже если два возврата завершились одновременно.
+func (pr *PurchaseRepository) ApplyRefundToPurchase(ctx context.Context, purchaseID int64, amount float64) (PurchaseStatus, error) {
+	var status PurchaseStatus
+	err := pr.pool.QueryRow(ctx, `
+UPDATE purchase
+SET refunded_amount = refunded_amount + $2,
+    refunded_at = NOW(),
+    status = CASE WHEN refunded_amount + $2 + $3 >= amount THEN $4 ELSE $5 END
+WHERE id = $1
+  AND status IN ($6, $5)
+  AND refunded_amount + $2 <= amount + $3
+RETURNING status`,
+		purchaseID, amount, refundAmountEpsilon,
+		PurchaseStatusRefunded, PurchaseStatusPartiallyRefunded, PurchaseStatusPaid,
+	).Scan(&status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("purchase %d is not refundable for amount %.2f", purchaseID, amount)
+		}
+		return "", fmt.Errorf("failed to apply refund to purchase: %w", err)
+	}
+	return status, nil
+}
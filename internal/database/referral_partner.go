@@ -0,0 +1,403 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// CommissionStatus — статус начисления партнёру.
+type CommissionStatus string
+
+const (
+	CommissionStatusAccrued  CommissionStatus = "accrued"
+	CommissionStatusReversed CommissionStatus = "reversed"
+)
+
+// PayoutMethod — куда выплатить заработанное партнёром.
+type PayoutMethod string
+
+const (
+	// PayoutMethodBalance — зачисление на внутренний баланс (customer_balance).
+	PayoutMethodBalance PayoutMethod = "balance"
+	// PayoutMethodManual — админ переводит деньги сам по реквизитам из details.
+	PayoutMethodManual PayoutMethod = "manual"
+)
+
+// PayoutStatus — статус запроса на выплату.
+type PayoutStatus string
+
+const (
+	PayoutStatusPending  PayoutStatus = "pending"
+	PayoutStatusPaid     PayoutStatus = "paid"
+	PayoutStatusRejected PayoutStatus = "rejected"
+)
+
+var (
+	// ErrPayoutPendingExists — у партнёра уже есть необработанный запрос на выплату.
+	ErrPayoutPendingExists = errors.New("payout request already pending")
+	// ErrPayoutStatusConflict — запрос уже обработан (или обрабатывается параллельно).
+	ErrPayoutStatusConflict = errors.New("payout status changed concurrently")
+)
+
+// ReferralPartner — персональная ставка реферера (referral_partner).
+type ReferralPartner struct {
+	CustomerID        int64     `db:"customer_id"`
+	CommissionPercent float64   `db:"commission_percent"`
+	Enabled           bool      `db:"enabled"`
+	Note              *string   `db:"note"`
+	CreatedAt         time.Time `db:"created_at"`
+	UpdatedAt         time.Time `db:"updated_at"`
+}
+
+// ReferralCommission — начисление партнёру за оплату реферала.
+type ReferralCommission struct {
+	ID                int64            `db:"id"`
+	PartnerCustomerID int64            `db:"partner_customer_id"`
+	RefereeCustomerID int64            `db:"referee_customer_id"`
+	PurchaseID        int64            `db:"purchase_id"`
+	PurchaseAmount    float64          `db:"purchase_amount"`
+	CommissionPercent float64          `db:"commission_percent"`
+	Amount            float64          `db:"amount"`
+	ReversedAmount    float64          `db:"reversed_amount"`
+	Status            CommissionStatus `db:"status"`
+	AvailableAt       time.Time        `db:"available_at"`
+	CreatedAt         time.Time        `db:"created_at"`
+	ReversedAt        *time.Time       `db:"reversed_at"`
+}
+
+// Net — начисление за вычетом сторно.
+func (c *ReferralCommission) Net() float64 {
+	return c.Amount - c.ReversedAmount
+}
+
+// ReferralPayout — запрос партнёра на выплату.
+type ReferralPayout struct {
+	ID                int64        `db:"id"`
+	PartnerCustomerID int64        `db:"partner_customer_id"`
+	Amount            float64      `db:"amount"`
+	Method            PayoutMethod `db:"method"`
+	Details           *string      `db:"details"`
+	Status            PayoutStatus `db:"status"`
+	AdminComment      *string      `db:"admin_comment"`
+	AdminTelegramID   *int64       `db:"admin_telegram_id"`
+	AdminAccountID    *int64       `db:"admin_account_id"`
+	CreatedAt         time.Time    `db:"created_at"`
+	ProcessedAt       *time.Time   `db:"processed_at"`
+}
+
+// PartnerSummary — суммы партнёра в рублях. Earned уже за вычетом сторно.
+type PartnerSummary struct {
+	Earned        float64
+	OnHold        float64
+	PayoutPending float64
+	PaidOut       float64
+	Commissions   int
+}
+
+// Available — сколько можно запросить к выплате. Отрицательное значение — сторно после выплаты,
+// покрывается будущими начислениями.
+func (s PartnerSummary) Available() float64 {
+	return s.Earned - s.OnHold - s.PayoutPending - s.PaidOut
+}
+
+const referralPartnerColumns = "customer_id, commission_percent::float8, enabled, note, created_at, updated_at"
+
+const referralCommissionColumns = "id, partner_customer_id, referee_customer_id, purchase_id, purchase_amount::float8, " +
+	"commission_percent::float8, amount::float8, reversed_amount::float8, status, available_at, created_at, reversed_at"
+
+const referralPayoutColumns = "id, partner_customer_id, amount::float8, method, details, status, admin_comment, " +
+	"admin_telegram_id, admin_account_id, created_at, processed_at"
+
+func referralPartnerScanArgs(p *ReferralPartner) []interface{} {
+	return []interface{}{&p.CustomerID, &p.CommissionPercent, &p.Enabled, &p.Note, &p.CreatedAt, &p.UpdatedAt}
+}
+
+func referralCommissionScanArgs(c *ReferralCommission) []interface{} {
+	return []interface{}{
+		&c.ID, &c.PartnerCustomerID, &c.RefereeCustomerID, &c.PurchaseID, &c.PurchaseAmount,
+		&c.CommissionPercent, &c.Amount, &c.ReversedAmount, &c.Status, &c.AvailableAt, &c.CreatedAt, &c.ReversedAt,
+	}
+}
+
+func referralPayoutScanArgs(p *ReferralPayout) []interface{} {
+	return []interface{}{
+		&p.ID, &p.PartnerCustomerID, &p.Amount, &p.Method, &p.Details, &p.Status, &p.AdminComment,
+		&p.AdminTelegramID, &p.AdminAccountID, &p.CreatedAt, &p.ProcessedAt,
+	}
+}
+
+type PartnerRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPartnerRepository(pool *pgxpool.Pool) *PartnerRepository {
+	return &PartnerRepository{pool: pool}
+}
+
+// GetPartner — персональная ставка; nil, если её не назначали.
+func (r *PartnerRepository) GetPartner(ctx context.Context, customerID int64) (*ReferralPartner, error) {
+	var p ReferralPartner
+	err := r.pool.QueryRow(ctx, `SELECT `+referralPartnerColumns+` FROM referral_partner WHERE customer_id = $1`, customerID).
+		Scan(referralPartnerScanArgs(&p)...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query referral partner: %w", err)
+	}
+	return &p, nil
+}
+
+// UpsertPartner создаёт или обновляет персональную ставку.
+func (r *PartnerRepository) UpsertPartner(ctx context.Context, p *ReferralPartner) (*ReferralPartner, error) {
+	var out ReferralPartner
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO referral_partner (customer_id, commission_percent, enabled, note)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (customer_id) DO UPDATE
+		SET commission_percent = EXCLUDED.commission_percent, enabled = EXCLUDED.enabled, note = EXCLUDED.note, updated_at = NOW()
+		RETURNING `+referralPartnerColumns,
+		p.CustomerID, p.CommissionPercent, p.Enabled, p.Note).Scan(referralPartnerScanArgs(&out)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert referral partner: %w", err)
+	}
+	return &out, nil
+}
+
+// ListPartners — партнёры с персональной ставкой (новые сверху).
+func (r *PartnerRepository) ListPartners(ctx context.Context, limit, offset int) ([]ReferralPartner, error) {
+	query, args, err := sq.Select(referralPartnerColumns).
+		From("referral_partner").
+		OrderBy("created_at DESC").
+		Limit(uint64(limit)).
+		Offset(uint64(offset)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query referral partners: %w", err)
+	}
+	defer rows.Close()
+	var out []ReferralPartner
+	for rows.Next() {
+		var p ReferralPartner
+		if err := rows.Scan(referralPartnerScanArgs(&p)...); err != nil {
+			return nil, fmt.Errorf("failed to scan referral partner: %w", err)
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating referral partner rows: %w", err)
+	}
+	return out, nil
+}
+
+// CreateCommission записывает начисление. created=false — по этой покупке начисление уже есть
+// (повтор вебхука или поллера), возвращается существующая строка.
+func (r *PartnerRepository) CreateCommission(ctx context.Context, c *ReferralCommission) (out *ReferralCommission, created bool, err error) {
+	var row ReferralCommission
+	err = r.pool.QueryRow(ctx, `
+		INSERT INTO referral_commission (partner_customer_id, referee_customer_id, purchase_id, purchase_amount, commission_percent, amount, available_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (purchase_id) DO NOTHING
+		RETURNING `+referralCommissionColumns,
+		c.PartnerCustomerID, c.RefereeCustomerID, c.PurchaseID, c.PurchaseAmount, c.CommissionPercent, c.Amount, c.AvailableAt).
+		Scan(referralCommissionScanArgs(&row)...)
+	if err == nil {
+		return &row, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, fmt.Errorf("failed to insert referral commission: %w", err)
+	}
+	existing, err := r.FindCommissionByPurchase(ctx, c.PurchaseID)
+	return existing, false, err
+}
+
+// FindCommissionByPurchase — начисление по покупке; nil, если его не было.
+func (r *PartnerRepository) FindCommissionByPurchase(ctx context.Context, purchaseID int64) (*ReferralCommission, error) {
+	var c ReferralCommission
+	err := r.pool.QueryRow(ctx, `SELECT `+referralCommissionColumns+` FROM referral_commission WHERE purchase_id = $1`, purchaseID).
+		Scan(referralCommissionScanArgs(&c)...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query referral commission: %w", err)
+	}
+	return &c, nil
+}
+
+// ReverseCommission доводит сторно по покупке до reversedTotal (накопительно, не больше начисления).
+// Повторный вызов с той же суммой ничего не меняет. nil — начисления по покупке не было.
+func (r *PartnerRepository) ReverseCommission(ctx context.Context, purchaseID int64, reversedTotal float64) (*ReferralCommission, error) {
+	var c ReferralCommission
+	err := r.pool.QueryRow(ctx, `
+		UPDATE referral_commission
+		SET reversed_amount = LEAST(amount, GREATEST(reversed_amount, $2)),
+		    status = CASE WHEN LEAST(amount, GREATEST(reversed_amount, $2)) >= amount THEN 'reversed' ELSE status END,
+		    reversed_at = CASE WHEN $2 > reversed_amount THEN NOW() ELSE reversed_at END
+		WHERE purchase_id = $1
+		RETURNING `+referralCommissionColumns, purchaseID, reversedTotal).
+		Scan(referralCommissionScanArgs(&c)...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to reverse referral commission: %w", err)
+	}
+	return &c, nil
+}
+
+// ListCommissions — начисления партнёра (новые сверху).
+func (r *PartnerRepository) ListCommissions(ctx context.Context, partnerCustomerID int64, limit, offset int) ([]ReferralCommission, error) {
+	query, args, err := sq.Select(referralCommissionColumns).
+		From("referral_commission").
+		Where(sq.Eq{"partner_customer_id": partnerCustomerID}).
+		OrderBy("created_at DESC", "id DESC").
+		Limit(uint64(limit)).
+		Offset(uint64(offset)).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query referral commissions: %w", err)
+	}
+	defer rows.Close()
+	var out []ReferralCommission
+	for rows.Next() {
+		var c ReferralCommission
+		if err := rows.Scan(referralCommissionScanArgs(&c)...); err != nil {
+			return nil, fmt.Errorf("failed to scan referral commission: %w", err)
+		}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating referral commission rows: %w", err)
+	}
+	return out, nil
+}
+
+// Summary — заработано, в холде, в ожидании выплаты и выплачено.
+func (r *PartnerRepository) Summary(ctx context.Context, partnerCustomerID int64) (PartnerSummary, error) {
+	var s PartnerSummary
+	err := r.pool.QueryRow(ctx, `
+		SELECT
+			COALESCE((SELECT SUM(amount - reversed_amount) FROM referral_commission WHERE partner_customer_id = $1), 0)::float8,
+			COALESCE((SELECT SUM(amount - reversed_amount) FROM referral_commission WHERE partner_customer_id = $1 AND available_at > NOW()), 0)::float8,
+			COALESCE((SELECT SUM(amount) FROM referral_payout WHERE partner_customer_id = $1 AND status = 'pending'), 0)::float8,
+			COALESCE((SELECT SUM(amount) FROM referral_payout WHERE partner_customer_id = $1 AND status = 'paid'), 0)::float8,
+			(SELECT COUNT(*) FROM referral_commission WHERE partner_customer_id = $1 AND status = 'accrued')`,
+		partnerCustomerID).Scan(&s.Earned, &s.OnHold, &s.PayoutPending, &s.PaidOut, &s.Commissions)
+	if err != nil {
+		return PartnerSummary{}, fmt.Errorf("failed to query partner summary: %w", err)
+	}
+	return s, nil
+}
+
+// CreatePayout записывает запрос на выплату. ErrPayoutPendingExists — предыдущий запрос ещё не обработан.
+func (r *PartnerRepository) CreatePayout(ctx context.Context, p *ReferralPayout) (*ReferralPayout, error) {
+	var out ReferralPayout
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO referral_payout (partner_customer_id, amount, method, details)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+referralPayoutColumns,
+		p.PartnerCustomerID, p.Amount, p.Method, p.Details).Scan(referralPayoutScanArgs(&out)...)
+	if err != nil {
+		if strings.Contains(err.Error(), "23505") {
+			return nil, ErrPayoutPendingExists
+		}
+		return nil, fmt.Errorf("failed to insert referral payout: %w", err)
+	}
+	return &out, nil
+}
+
+// FindPayout — запрос на выплату по id; nil, если не найден.
+func (r *PartnerRepository) FindPayout(ctx context.Context, id int64) (*ReferralPayout, error) {
+	var p ReferralPayout
+	err := r.pool.QueryRow(ctx, `SELECT `+referralPayoutColumns+` FROM referral_payout WHERE id = $1`, id).
+		Scan(referralPayoutScanArgs(&p)...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query referral payout: %w", err)
+	}
+	return &p, nil
+}
+
+// ListPayouts — запросы на выплату; status пустой — все, partnerCustomerID 0 — все партнёры.
+// pending отдаются по очереди (старые сверху), остальные — новые сверху.
+func (r *PartnerRepository) ListPayouts(ctx context.Context, status PayoutStatus, partnerCustomerID int64, limit, offset int) ([]ReferralPayout, error) {
+	q := sq.Select(referralPayoutColumns).
+		From("referral_payout").
+		Limit(uint64(limit)).
+		Offset(uint64(offset)).
+		PlaceholderFormat(sq.Dollar)
+	if status != "" {
+		q = q.Where(sq.Eq{"status": status})
+	}
+	if partnerCustomerID > 0 {
+		q = q.Where(sq.Eq{"partner_customer_id": partnerCustomerID})
+	}
+	if status == PayoutStatusPending {
+		q = q.OrderBy("created_at ASC", "id ASC")
+	} else {
+		q = q.OrderBy("created_at DESC", "id DESC")
+	}
+	query, args, err := q.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query referral payouts: %w", err)
+	}
+	defer rows.Close()
+	var out []ReferralPayout
+	for rows.Next() {
+		var p ReferralPayout
+		if err := rows.Scan(referralPayoutScanArgs(&p)...); err != nil {
+			return nil, fmt.Errorf("failed to scan referral payout: %w", err)
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating referral payout rows: %w", err)
+	}
+	return out, nil
+}
+
+// TransitionPayout переводит запрос из from в to. ErrPayoutStatusConflict — статус уже не from.
+func (r *PartnerRepository) TransitionPayout(ctx context.Context, id int64, from, to PayoutStatus, adminComment *string, adminTelegramID, adminAccountID *int64) (*ReferralPayout, error) {
+	var out ReferralPayout
+	err := r.pool.QueryRow(ctx, `
+		UPDATE referral_payout
+		SET status = $3,
+		    admin_comment = COALESCE($4, admin_comment),
+		    admin_telegram_id = COALESCE($5, admin_telegram_id),
+		    admin_account_id = COALESCE($6, admin_account_id),
+		    processed_at = CASE WHEN $3 = 'pending' THEN NULL ELSE NOW() END
+		WHERE id = $1 AND status = $2
+		RETURNING `+referralPayoutColumns,
+		id, from, to, adminComment, adminTelegramID, adminAccountID).Scan(referralPayoutScanArgs(&out)...)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrPayoutStatusConflict
+		}
+		return nil, fmt.Errorf("failed to update referral payout: %w", err)
+	}
+	return &out, nil
+}
@@ -0,0 +1,417 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"remnawave-tg-shop-bot/internal/acquisition"
+)
+
+// Канал связи с клиентом в фильтре сегмента.
+const (
+	SegmentChannelTelegram = "telegram" // есть чат с ботом
+	SegmentChannelWeb      = "web"      // web-only клиент кабинета
+)
+
+// BroadcastAudienceSegment — audience рассылки по фильтру сегмента (broadcast_job.segment_filter).
+const BroadcastAudienceSegment = "segment"
+
+const segmentMaxDays = 3650
+
+var (
+	// ErrSegmentInvalid — фильтр сегмента не прошёл проверку (текст ошибки можно показать админу).
+	ErrSegmentInvalid = errors.New("invalid segment")
+	// ErrSegmentNameTaken — сегмент с таким именем уже есть.
+	ErrSegmentNameTaken = errors.New("segment name already exists")
+	// ErrSegmentInUse — на сегмент ссылаются промокоды, удалить нельзя.
+	ErrSegmentInUse = errors.New("segment is used by promo codes")
+	// ErrSegmentNotFound — промокод ссылается на несуществующий сегмент.
+	ErrSegmentNotFound = errors.New("segment not found")
+)
+
+// promoSegmentError — ErrSegmentNotFound, если запись промокода нарушила внешний ключ на customer_segment.
+func promoSegmentError(err error) error {
+	if err != nil && strings.Contains(err.Error(), "promo_code_segment_id_fkey") {
+		return ErrSegmentNotFound
+	}
+	return err
+}
+
+// SegmentFilter — набор условий сегмента; условия объединяются через AND, пустое поле не фильтрует.
+// Списки внутри одного условия — OR (например, любой из языков).
+type SegmentFilter struct {
+	Audience            string   `json:"audience,omitempty"` // BroadcastAudience*
+	Languages           []string `json:"languages,omitempty"`
+	TariffIDs           []int64  `json:"tariff_ids,omitempty"`
+	ExpiresWithinDays   *int     `json:"expires_within_days,omitempty"`    // подписка активна и истекает в ближайшие N дней
+	ExpiredMoreThanDays *int     `json:"expired_more_than_days,omitempty"` // подписка истекла больше N дней назад
+	LoyaltyTierIDs      []int64  `json:"loyalty_tier_ids,omitempty"`
+	PaidPurchasesMin    *int     `json:"paid_purchases_min,omitempty"`
+	PaidPurchasesMax    *int     `json:"paid_purchases_max,omitempty"`
+	ReferralsMin        *int     `json:"referrals_min,omitempty"`
+	ReferralsMax        *int     `json:"referrals_max,omitempty"`
+	AcquisitionSources  []string `json:"acquisition_sources,omitempty"`
+	OnlineWithinDays    *int     `json:"online_within_days,omitempty"`     // подключался к VPN за последние N дней
+	OfflineMoreThanDays *int     `json:"offline_more_than_days,omitempty"` // не подключался N дней или ни разу
+	Channel             string   `json:"channel,omitempty"`                // SegmentChannel*; пусто — любой
+}
+
+func segmentInvalidf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrSegmentInvalid, fmt.Sprintf(format, args...))
+}
+
+// Normalize убирает пробелы и пустые значения в списках. Источники приводятся к виду,
+// в котором хранится customer.acquisition_source (acquisition.Normalize).
+func (f *SegmentFilter) Normalize() {
+	f.Audience = strings.TrimSpace(f.Audience)
+	f.Channel = strings.ToLower(strings.TrimSpace(f.Channel))
+	f.Languages = normalizeSegmentStrings(f.Languages, true)
+	sources := make([]string, 0, len(f.AcquisitionSources))
+	for _, s := range f.AcquisitionSources {
+		sources = append(sources, acquisition.Normalize(s))
+	}
+	f.AcquisitionSources = normalizeSegmentStrings(sources, false)
+}
+
+func normalizeSegmentStrings(in []string, lower bool) []string {
+	var out []string
+	for _, s := range in {
+		s = strings.TrimSpace(s)
+		if lower {
+			s = strings.ToLower(s)
+		}
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Validate проверяет фильтр; ошибки оборачивают ErrSegmentInvalid.
+func (f *SegmentFilter) Validate() error {
+	switch f.Audience {
+	case "", BroadcastAudienceAll, BroadcastAudienceActive, BroadcastAudienceActiveAll, BroadcastAudienceActivePaid,
+		BroadcastAudienceActiveTrial, BroadcastAudienceInactive, BroadcastAudienceInactiveAll,
+		BroadcastAudienceInactivePaid, BroadcastAudienceInactiveTrial:
+	default:
+		return segmentInvalidf("unknown audience %q", f.Audience)
+	}
+	switch f.Channel {
+	case "", SegmentChannelTelegram, SegmentChannelWeb:
+	default:
+		return segmentInvalidf("unknown channel %q", f.Channel)
+	}
+	days := []struct {
+		name string
+		v    *int
+	}{
+		{"expires_within_days", f.ExpiresWithinDays},
+		{"expired_more_than_days", f.ExpiredMoreThanDays},
+		{"online_within_days", f.OnlineWithinDays},
+		{"offline_more_than_days", f.OfflineMoreThanDays},
+	}
+	for _, d := range days {
+		if d.v != nil && (*d.v < 0 || *d.v > segmentMaxDays) {
+			return segmentInvalidf("%s must be between 0 and %d", d.name, segmentMaxDays)
+		}
+	}
+	ranges := []struct {
+		name     string
+		min, max *int
+	}{
+		{"paid_purchases", f.PaidPurchasesMin, f.PaidPurchasesMax},
+		{"referrals", f.ReferralsMin, f.ReferralsMax},
+	}
+	for _, r := range ranges {
+		if (r.min != nil && *r.min < 0) || (r.max != nil && *r.max < 0) {
+			return segmentInvalidf("%s must not be negative", r.name)
+		}
+		if r.min != nil && r.max != nil && *r.min > *r.max {
+			return segmentInvalidf("%s_min is greater than %s_max", r.name, r.name)
+		}
+	}
+	return nil
+}
+
+// Condition — условие WHERE по таблице customer; nil — фильтр пустой (все клиенты).
+func (f *SegmentFilter) Condition(now time.Time) (sq.Sqlizer, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	var and sq.And
+	if f.Audience != "" {
+		cond, err := broadcastAudienceCondition(f.Audience, nil, now)
+		if err != nil {
+			return nil, err
+		}
+		if cond != nil {
+			and = append(and, cond)
+		}
+	}
+	if len(f.Languages) > 0 {
+		and = append(and, sq.Expr(`COALESCE(NULLIF(customer.language, ''), 'en') = ANY(?)`, f.Languages))
+	}
+	if len(f.TariffIDs) > 0 {
+		and = append(and, sq.Expr(`customer.current_tariff_id = ANY(?)`, f.TariffIDs))
+	}
+	if f.ExpiresWithinDays != nil {
+		and = append(and, sq.Gt{"customer.expire_at": now}, sq.LtOrEq{"customer.expire_at": now.AddDate(0, 0, *f.ExpiresWithinDays)})
+	}
+	if f.ExpiredMoreThanDays != nil {
+		and = append(and, sq.LtOrEq{"customer.expire_at": now.AddDate(0, 0, -*f.ExpiredMoreThanDays)})
+	}
+	if len(f.LoyaltyTierIDs) > 0 {
+		and = append(and, sq.Expr(`(SELECT lt.id FROM loyalty_tier lt WHERE lt.xp_min <= customer.loyalty_xp
+			ORDER BY lt.xp_min DESC, lt.sort_order DESC LIMIT 1) = ANY(?)`, f.LoyaltyTierIDs))
+	}
+	const paidCount = `(SELECT COUNT(*) FROM purchase p WHERE p.customer_id = customer.id AND p.status = 'paid')`
+	if f.PaidPurchasesMin != nil {
+		and = append(and, sq.Expr(paidCount+` >= ?`, *f.PaidPurchasesMin))
+	}
+	if f.PaidPurchasesMax != nil {
+		and = append(and, sq.Expr(paidCount+` <= ?`, *f.PaidPurchasesMax))
+	}
+	const referralCount = `(SELECT COUNT(*) FROM referral r WHERE r.referrer_id = customer.telegram_id)`
+	if f.ReferralsMin != nil {
+		and = append(and, sq.Expr(referralCount+` >= ?`, *f.ReferralsMin))
+	}
+	if f.ReferralsMax != nil {
+		and = append(and, sq.Expr(referralCount+` <= ?`, *f.ReferralsMax))
+	}
+	if len(f.AcquisitionSources) > 0 {
+		and = append(and, sq.Expr(`customer.acquisition_source = ANY(?)`, f.AcquisitionSources))
+	}
+	if f.OnlineWithinDays != nil {
+		and = append(and, sq.GtOrEq{"customer.online_at": now.AddDate(0, 0, -*f.OnlineWithinDays)})
+	}
+	if f.OfflineMoreThanDays != nil {
+		and = append(and, sq.Or{sq.Eq{"customer.online_at": nil}, sq.Lt{"customer.online_at": now.AddDate(0, 0, -*f.OfflineMoreThanDays)}})
+	}
+	switch f.Channel {
+	case SegmentChannelTelegram:
+		and = append(and, sq.Eq{"customer.is_web_only": false})
+	case SegmentChannelWeb:
+		and = append(and, sq.Eq{"customer.is_web_only": true})
+	}
+	if len(and) == 0 {
+		return nil, nil
+	}
+	return and, nil
+}
+
+// SegmentCount — размер сегмента: все клиенты и те, кому можно написать в Telegram.
+type SegmentCount struct {
+	Total    int `json:"total"`
+	Telegram int `json:"telegram"`
+	Email    int `json:"email"` // web-only клиенты, которым рассылка уйдёт на email
+}
+
+// CountSegment считает клиентов сегмента.
+func (cr *CustomerRepository) CountSegment(ctx context.Context, f SegmentFilter) (SegmentCount, error) {
+	var out SegmentCount
+	cond, err := f.Condition(time.Now())
+	if err != nil {
+		return out, err
+	}
+	buildSelect := sq.Select("COUNT(*)", "COUNT(*) FILTER (WHERE NOT customer.is_web_only)",
+		"COUNT(*) FILTER (WHERE customer.is_web_only AND "+marketingEmailReachable+")").
+		From("customer").
+		PlaceholderFormat(sq.Dollar)
+	if cond != nil {
+		buildSelect = buildSelect.Where(cond)
+	}
+	sqlStr, args, err := buildSelect.ToSql()
+	if err != nil {
+		return out, fmt.Errorf("failed to build segment count query: %w", err)
+	}
+	if err := cr.pool.QueryRow(ctx, sqlStr, args...).Scan(&out.Total, &out.Telegram, &out.Email); err != nil {
+		return out, fmt.Errorf("failed to count segment: %w", err)
+	}
+	return out, nil
+}
+
+// GetSegmentRecipients — получатели рассылки по сегменту (web-only клиенты — как в GetBroadcastRecipients).
+func (cr *CustomerRepository) GetSegmentRecipients(ctx context.Context, f SegmentFilter) ([]BroadcastRecipient, error) {
+	cond, err := f.Condition(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	buildSelect := sq.Select("telegram_id", "language", "id", "is_web_only").
+		From("customer").
+		PlaceholderFormat(sq.Dollar)
+	if cond != nil {
+		buildSelect = buildSelect.Where(cond)
+	}
+	return cr.queryBroadcastRecipients(ctx, buildSelect)
+}
+
+// InSegment — входит ли клиент в сохранённый сегмент сейчас. Нет сегмента — false.
+func (cr *CustomerRepository) InSegment(ctx context.Context, customerID, segmentID int64) (bool, error) {
+	var raw []byte
+	err := cr.pool.QueryRow(ctx, `SELECT filter FROM customer_segment WHERE id = $1`, segmentID).Scan(&raw)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load segment: %w", err)
+	}
+	var f SegmentFilter
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return false, fmt.Errorf("failed to decode segment filter: %w", err)
+	}
+	cond, err := f.Condition(time.Now())
+	if err != nil {
+		return false, err
+	}
+	buildSelect := sq.Select("1").From("customer").Where(sq.Eq{"customer.id": customerID}).PlaceholderFormat(sq.Dollar)
+	if cond != nil {
+		buildSelect = buildSelect.Where(cond)
+	}
+	sqlStr, args, err := buildSelect.Prefix("SELECT EXISTS (").Suffix(")").ToSql()
+	if err != nil {
+		return false, fmt.Errorf("failed to build segment membership query: %w", err)
+	}
+	var ok bool
+	if err := cr.pool.QueryRow(ctx, sqlStr, args...).Scan(&ok); err != nil {
+		return false, fmt.Errorf("failed to check segment membership: %w", err)
+	}
+	return ok, nil
+}
+
+// UpdateOnlineAt сохраняет последнее подключение к VPN (onlineAt панели) по telegram_id.
+func (cr *CustomerRepository) UpdateOnlineAt(ctx context.Context, onlineAt map[int64]time.Time) error {
+	if len(onlineAt) == 0 {
+		return nil
+	}
+	ids := make([]int64, 0, len(onlineAt))
+	times := make([]time.Time, 0, len(onlineAt))
+	for id, t := range onlineAt {
+		ids = append(ids, id)
+		times = append(times, t)
+	}
+	_, err := cr.pool.Exec(ctx, `
+		UPDATE customer c SET online_at = v.online_at
+		FROM unnest($1::bigint[], $2::timestamptz[]) AS v(telegram_id, online_at)
+		WHERE c.telegram_id = v.telegram_id AND c.online_at IS DISTINCT FROM v.online_at`, ids, times)
+	if err != nil {
+		return fmt.Errorf("failed to update online_at: %w", err)
+	}
+	return nil
+}
+
+// CustomerSegment — сохранённый сегмент (customer_segment).
+type CustomerSegment struct {
+	ID        int64         `db:"id"`
+	Name      string        `db:"name"`
+	Filter    SegmentFilter `db:"filter"`
+	CreatedAt time.Time     `db:"created_at"`
+	UpdatedAt time.Time     `db:"updated_at"`
+}
+
+type CustomerSegmentRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewCustomerSegmentRepository(pool *pgxpool.Pool) *CustomerSegmentRepository {
+	return &CustomerSegmentRepository{pool: pool}
+}
+
+const customerSegmentColumns = "id, name, filter, created_at, updated_at"
+
+func scanCustomerSegment(row pgx.Row) (*CustomerSegment, error) {
+	var s CustomerSegment
+	var raw []byte
+	if err := row.Scan(&s.ID, &s.Name, &raw, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &s.Filter); err != nil {
+		return nil, fmt.Errorf("failed to decode segment filter: %w", err)
+	}
+	return &s, nil
+}
+
+func (r *CustomerSegmentRepository) List(ctx context.Context) ([]CustomerSegment, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+customerSegmentColumns+` FROM customer_segment ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list segments: %w", err)
+	}
+	defer rows.Close()
+	var out []CustomerSegment
+	for rows.Next() {
+		s, err := scanCustomerSegment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan segment: %w", err)
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+func (r *CustomerSegmentRepository) FindByID(ctx context.Context, id int64) (*CustomerSegment, error) {
+	s, err := scanCustomerSegment(r.pool.QueryRow(ctx, `SELECT `+customerSegmentColumns+` FROM customer_segment WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find segment: %w", err)
+	}
+	return s, nil
+}
+
+func (r *CustomerSegmentRepository) Create(ctx context.Context, name string, f SegmentFilter) (*CustomerSegment, error) {
+	raw, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	s, err := scanCustomerSegment(r.pool.QueryRow(ctx, `
+		INSERT INTO customer_segment (name, filter) VALUES ($1, $2::jsonb)
+		RETURNING `+customerSegmentColumns, name, string(raw)))
+	if err != nil {
+		if strings.Contains(err.Error(), "23505") {
+			return nil, ErrSegmentNameTaken
+		}
+		return nil, fmt.Errorf("failed to create segment: %w", err)
+	}
+	return s, nil
+}
+
+// Update меняет имя и фильтр; нет сегмента — nil, nil.
+func (r *CustomerSegmentRepository) Update(ctx context.Context, id int64, name string, f SegmentFilter) (*CustomerSegment, error) {
+	raw, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	s, err := scanCustomerSegment(r.pool.QueryRow(ctx, `
+		UPDATE customer_segment SET name = $2, filter = $3::jsonb, updated_at = NOW() WHERE id = $1
+		RETURNING `+customerSegmentColumns, id, name, string(raw)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		if strings.Contains(err.Error(), "23505") {
+			return nil, ErrSegmentNameTaken
+		}
+		return nil, fmt.Errorf("failed to update segment: %w", err)
+	}
+	return s, nil
+}
+
+// Delete удаляет сегмент; false — сегмента не было. Сегмент промокодов — ErrSegmentInUse.
+func (r *CustomerSegmentRepository) Delete(ctx context.Context, id int64) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM customer_segment WHERE id = $1`, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "23503") {
+			return false, ErrSegmentInUse
+		}
+		return false, fmt.Errorf("failed to delete segment: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestSegmentFilterValidate(t *testing.T) {
+	cases := []struct {
+		name string
+		f    SegmentFilter
+		ok   bool
+	}{
+		{"empty", SegmentFilter{}, true},
+		{"audience", SegmentFilter{Audience: BroadcastAudienceActivePaid}, true},
+		{"unknown audience", SegmentFilter{Audience: "vip"}, false},
+		{"unknown channel", SegmentFilter{Channel: "sms"}, false},
+		{"negative days", SegmentFilter{ExpiresWithinDays: intPtr(-1)}, false},
+		{"min above max", SegmentFilter{ReferralsMin: intPtr(3), ReferralsMax: intPtr(1)}, false},
+		{"range", SegmentFilter{PaidPurchasesMin: intPtr(1), PaidPurchasesMax: intPtr(1)}, true},
+	}
+	for _, c := range cases {
+		err := c.f.Validate()
+		if c.ok && err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+		}
+		if !c.ok && !errors.Is(err, ErrSegmentInvalid) {
+			t.Errorf("%s: want ErrSegmentInvalid, got %v", c.name, err)
+		}
+	}
+}
+
+func TestSegmentFilterNormalizeAcquisitionSources(t *testing.T) {
+	f := SegmentFilter{AcquisitionSources: []string{" TikTok ", "tiktok oct", "", "!!!"}}
+	f.Normalize()
+	want := []string{"tiktok", "tiktok_oct"}
+	if len(f.AcquisitionSources) != len(want) {
+		t.Fatalf("got %q want %q", f.AcquisitionSources, want)
+	}
+	for i := range want {
+		if f.AcquisitionSources[i] != want[i] {
+			t.Fatalf("got %q want %q", f.AcquisitionSources, want)
+		}
+	}
+}
+
+func TestSegmentFilterCondition(t *testing.T) {
+	empty := SegmentFilter{}
+	cond, err := empty.Condition(time.Now())
+	if err != nil || cond != nil {
+		t.Fatalf("empty filter: cond=%v err=%v", cond, err)
+	}
+
+	f := SegmentFilter{
+		Languages:           []string{"ru"},
+		LoyaltyTierIDs:      []int64{2},
+		ReferralsMin:        intPtr(1),
+		OfflineMoreThanDays: intPtr(30),
+		Channel:             SegmentChannelTelegram,
+	}
+	cond, err = f.Condition(time.Now())
+	if err != nil {
+		t.Fatalf("Condition() returned error: %v", err)
+	}
+	sqlStr, args, err := sq.Select("1").From("customer").Where(cond).PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		t.Fatalf("ToSql() returned error: %v", err)
+	}
+	for _, want := range []string{"FROM loyalty_tier", "FROM referral r", "customer.online_at IS NULL", "customer.is_web_only = $"} {
+		if !strings.Contains(sqlStr, want) {
+			t.Errorf("expected SQL to contain %q, got: %s", want, sqlStr)
+		}
+	}
+	if len(args) != 5 {
+		t.Errorf("unexpected args count %d: %v", len(args), args)
+	}
+}
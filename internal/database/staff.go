@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// StaffMember — сотрудник админки; Permissions — явный список прав (см. пакет staff).
+type StaffMember struct {
+	TelegramID  int64     `db:"telegram_id"`
+	Role        string    `db:"role"`
+	Permissions []string  `db:"permissions"`
+	Note        string    `db:"note"`
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+const staffColumns = "telegram_id, role, permissions, note, created_at, updated_at"
+
+type StaffRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewStaffRepository(pool *pgxpool.Pool) *StaffRepository {
+	return &StaffRepository{pool: pool}
+}
+
+func scanStaffMember(sc interface{ Scan(dest ...any) error }, m *StaffMember) error {
+	return sc.Scan(&m.TelegramID, &m.Role, &m.Permissions, &m.Note, &m.CreatedAt, &m.UpdatedAt)
+}
+
+// List — все сотрудники по дате добавления.
+func (r *StaffRepository) List(ctx context.Context) ([]StaffMember, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+staffColumns+` FROM staff ORDER BY created_at, telegram_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list staff: %w", err)
+	}
+	defer rows.Close()
+
+	var out []StaffMember
+	for rows.Next() {
+		var m StaffMember
+		if err := scanStaffMember(rows, &m); err != nil {
+			return nil, fmt.Errorf("failed to scan staff: %w", err)
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// FindByTelegramID — сотрудник по Telegram ID; nil — не найден.
+func (r *StaffRepository) FindByTelegramID(ctx context.Context, telegramID int64) (*StaffMember, error) {
+	var m StaffMember
+	err := scanStaffMember(r.pool.QueryRow(ctx, `SELECT `+staffColumns+` FROM staff WHERE telegram_id = $1`, telegramID), &m)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find staff: %w", err)
+	}
+	return &m, nil
+}
+
+// Upsert добавляет сотрудника или меняет роль, права и заметку существующего.
+func (r *StaffRepository) Upsert(ctx context.Context, m StaffMember) (*StaffMember, error) {
+	if m.Permissions == nil {
+		m.Permissions = []string{}
+	}
+	var out StaffMember
+	err := scanStaffMember(r.pool.QueryRow(ctx, `
+		INSERT INTO staff (telegram_id, role, permissions, note) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (telegram_id) DO UPDATE
+		SET role = EXCLUDED.role, permissions = EXCLUDED.permissions, note = EXCLUDED.note, updated_at = NOW()
+		RETURNING `+staffColumns, m.TelegramID, m.Role, m.Permissions, m.Note), &out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert staff: %w", err)
+	}
+	return &out, nil
+}
+
+// Delete удаляет сотрудника; false — такого не было.
+func (r *StaffRepository) Delete(ctx context.Context, telegramID int64) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM staff WHERE telegram_id = $1`, telegramID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete staff: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,196 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type StarsSubscriptionStatus string
+
+const (
+	StarsSubscriptionStatusActive StarsSubscriptionStatus = "active"
+	// StarsSubscriptionStatusCanceled — продление отменено в боте, оплаченный период ещё идёт.
+	StarsSubscriptionStatusCanceled StarsSubscriptionStatus = "canceled"
+	// StarsSubscriptionStatusExpired — очередное списание не пришло (отмена в Telegram, не хватило звёзд).
+	StarsSubscriptionStatusExpired StarsSubscriptionStatus = "expired"
+)
+
+// StarsSubscription — подписка Telegram Stars с ежемесячным списанием, начатая покупкой PurchaseID.
+type StarsSubscription struct {
+	ID         int64 `db:"id"`
+	CustomerID int64 `db:"customer_id"`
+	PurchaseID int64 `db:"purchase_id"`
+	// TelegramChargeID — charge id первой оплаты: им подписку отменяют и возобновляют (editUserStarSubscription).
+	TelegramChargeID string                  `db:"telegram_charge_id"`
+	Amount           int                     `db:"amount"`
+	Months           int                     `db:"months"`
+	TariffID         *int64                  `db:"tariff_id"`
+	ExtraHwid        int                     `db:"extra_hwid"`
+	Status           StarsSubscriptionStatus `db:"status"`
+	// ExpiresAt — subscription_expiration_date последней оплаты: до этого момента подписка оплачена.
+	ExpiresAt     time.Time  `db:"expires_at"`
+	Renewals      int        `db:"renewals"`
+	LastRenewedAt *time.Time `db:"last_renewed_at"`
+	CanceledAt    *time.Time `db:"canceled_at"`
+	CreatedAt     time.Time  `db:"created_at"`
+	UpdatedAt     time.Time  `db:"updated_at"`
+}
+
+const starsSubscriptionColumns = "id, customer_id, purchase_id, telegram_charge_id, amount, months, tariff_id, extra_hwid, " +
+	"status, expires_at, renewals, last_renewed_at, canceled_at, created_at, updated_at"
+
+func starsSubscriptionScanArgs(s *StarsSubscription) []interface{} {
+	return []interface{}{
+		&s.ID, &s.CustomerID, &s.PurchaseID, &s.TelegramChargeID, &s.Amount, &s.Months, &s.TariffID, &s.ExtraHwid,
+		&s.Status, &s.ExpiresAt, &s.Renewals, &s.LastRenewedAt, &s.CanceledAt, &s.CreatedAt, &s.UpdatedAt,
+	}
+}
+
+type StarsSubscriptionRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewStarsSubscriptionRepository(pool *pgxpool.Pool) *StarsSubscriptionRepository {
+	return &StarsSubscriptionRepository{pool: pool}
+}
+
+// Create сохраняет подписку и привязывает к ней первую покупку. Повтор по той же покупке возвращает уже созданную.
+func (r *StarsSubscriptionRepository) Create(ctx context.Context, sub *StarsSubscription) (*StarsSubscription, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	var out StarsSubscription
+	err = tx.QueryRow(ctx, `
+		INSERT INTO stars_subscription (customer_id, purchase_id, telegram_charge_id, amount, months, tariff_id, extra_hwid, status, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (purchase_id) DO NOTHING
+		RETURNING `+starsSubscriptionColumns,
+		sub.CustomerID, sub.PurchaseID, sub.TelegramChargeID, sub.Amount, sub.Months, sub.TariffID, sub.ExtraHwid,
+		StarsSubscriptionStatusActive, sub.ExpiresAt).
+		Scan(starsSubscriptionScanArgs(&out)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return r.FindByPurchaseID(ctx, sub.PurchaseID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stars subscription: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE purchase SET stars_subscription_id = $2 WHERE id = $1`, out.PurchaseID, out.ID); err != nil {
+		return nil, fmt.Errorf("failed to link purchase to stars subscription: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit stars subscription: %w", err)
+	}
+	return &out, nil
+}
+
+func (r *StarsSubscriptionRepository) findOne(ctx context.Context, where sq.Sqlizer) (*StarsSubscription, error) {
+	query, args, err := sq.Select(starsSubscriptionColumns).
+		From("stars_subscription").
+		Where(where).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+	var s StarsSubscription
+	if err := r.pool.QueryRow(ctx, query, args...).Scan(starsSubscriptionScanArgs(&s)...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query stars subscription: %w", err)
+	}
+	return &s, nil
+}
+
+func (r *StarsSubscriptionRepository) FindByID(ctx context.Context, id int64) (*StarsSubscription, error) {
+	return r.findOne(ctx, sq.Eq{"id": id})
+}
+
+func (r *StarsSubscriptionRepository) FindByPurchaseID(ctx context.Context, purchaseID int64) (*StarsSubscription, error) {
+	return r.findOne(ctx, sq.Eq{"purchase_id": purchaseID})
+}
+
+// ListByCustomer — подписки клиента: сначала действующие, затем по дате создания (новые сверху).
+func (r *StarsSubscriptionRepository) ListByCustomer(ctx context.Context, customerID int64) ([]StarsSubscription, error) {
+	query, args, err := sq.Select(starsSubscriptionColumns).
+		From("stars_subscription").
+		Where(sq.Eq{"customer_id": customerID}).
+		OrderBy("status = 'expired'", "created_at DESC", "id DESC").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stars subscriptions: %w", err)
+	}
+	defer rows.Close()
+	var out []StarsSubscription
+	for rows.Next() {
+		var s StarsSubscription
+		if err := rows.Scan(starsSubscriptionScanArgs(&s)...); err != nil {
+			return nil, fmt.Errorf("failed to scan stars subscription: %w", err)
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stars subscriptions: %w", err)
+	}
+	return out, nil
+}
+
+// RecordRenewal учитывает очередное списание: срок сдвигается на expiresAt, подписка, считавшаяся
+// истёкшей, снова активна (Telegram мог списать с опозданием).
+func (r *StarsSubscriptionRepository) RecordRenewal(ctx context.Context, id int64, expiresAt, renewedAt time.Time) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE stars_subscription SET
+			renewals = renewals + 1,
+			last_renewed_at = $3,
+			expires_at = GREATEST(expires_at, $2),
+			status = CASE WHEN status = $4 THEN $5 ELSE status END,
+			updated_at = NOW()
+		WHERE id = $1`,
+		id, expiresAt, renewedAt, StarsSubscriptionStatusExpired, StarsSubscriptionStatusActive)
+	if err != nil {
+		return fmt.Errorf("failed to record stars subscription renewal: %w", err)
+	}
+	return nil
+}
+
+// SetCanceled отменяет (canceled=true) или возобновляет продление. Истёкшую подписку не трогает; false — строк не изменено.
+func (r *StarsSubscriptionRepository) SetCanceled(ctx context.Context, id int64, canceled bool, now time.Time) (bool, error) {
+	status, from, canceledAt := StarsSubscriptionStatusCanceled, StarsSubscriptionStatusActive, &now
+	if !canceled {
+		status, from, canceledAt = StarsSubscriptionStatusActive, StarsSubscriptionStatusCanceled, nil
+	}
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE stars_subscription SET status = $2, canceled_at = $3, updated_at = NOW()
+		WHERE id = $1 AND status = $4`,
+		id, status, canceledAt, from)
+	if err != nil {
+		return false, fmt.Errorf("failed to update stars subscription: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ExpireLapsed помечает истёкшими подписки, оплаченный срок которых закончился раньше before.
+func (r *StarsSubscriptionRepository) ExpireLapsed(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE stars_subscription SET status = $2, updated_at = NOW()
+		WHERE status IN ($3, $4) AND expires_at < $1`,
+		before, StarsSubscriptionStatusExpired, StarsSubscriptionStatusActive, StarsSubscriptionStatusCanceled)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire stars subscriptions: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
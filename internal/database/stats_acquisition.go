@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/config"
+)
+
+// AdminAcquisitionSourceStat — воронка одного источника привлечения: клиенты, пришедшие за период
+// (created_at), сколько из них взяли триал, оплатили и сколько принесли за всё время.
+type AdminAcquisitionSourceStat struct {
+	// Source — метка (customer.acquisition_source); "" — клиенты без метки.
+	Source            string
+	Starts            int64
+	Trials            int64
+	Paid              int64
+	RevenueByCurrency map[string]float64
+	RevenueRub        float64
+}
+
+// FetchAcquisitionFunnel — воронка «старт → триал → первая оплата → выручка» по источникам для клиентов,
+// пришедших в [from, to); nil — без границы. Выручка — все оплаты когорты, кроме пополнений баланса.
+// Источники отсортированы по числу стартов.
+func (s *StatsRepository) FetchAcquisitionFunnel(ctx context.Context, from, to *time.Time) ([]AdminAcquisitionSourceStat, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT COALESCE(c.acquisition_source, ''),
+       COUNT(*),
+       COUNT(*) FILTER (WHERE c.trial_activated_at IS NOT NULL),
+       COUNT(*) FILTER (WHERE EXISTS (
+         SELECT 1 FROM purchase p
+         WHERE p.customer_id = c.id AND p.status = 'paid' AND p.purchase_kind IS DISTINCT FROM 'balance_topup'
+       ))
+FROM customer c
+WHERE ($1::timestamptz IS NULL OR c.created_at >= $1) AND ($2::timestamptz IS NULL OR c.created_at < $2)
+GROUP BY 1`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("stats acquisition funnel: %w", err)
+	}
+	bySource := make(map[string]*AdminAcquisitionSourceStat)
+	for rows.Next() {
+		st := &AdminAcquisitionSourceStat{RevenueByCurrency: map[string]float64{}}
+		if err := rows.Scan(&st.Source, &st.Starts, &st.Trials, &st.Paid); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("stats acquisition funnel scan: %w", err)
+		}
+		bySource[st.Source] = st
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("stats acquisition funnel: %w", err)
+	}
+
+	rows, err = s.pool.Query(ctx, `
+SELECT COALESCE(c.acquisition_source, ''), `+sqlNormalizedCurrency+`, COALESCE(SUM(p.amount), 0)::float8
+FROM purchase p
+JOIN customer c ON c.id = p.customer_id
+WHERE p.status = 'paid' AND p.purchase_kind IS DISTINCT FROM 'balance_topup'
+  AND ($1::timestamptz IS NULL OR c.created_at >= $1) AND ($2::timestamptz IS NULL OR c.created_at < $2)
+GROUP BY 1, 2`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("stats acquisition revenue: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var src, cur string
+		var sum float64
+		if err := rows.Scan(&src, &cur, &sum); err != nil {
+			return nil, fmt.Errorf("stats acquisition revenue scan: %w", err)
+		}
+		if st := bySource[src]; st != nil {
+			st.RevenueByCurrency[cur] = sum
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("stats acquisition revenue: %w", err)
+	}
+
+	out := make([]AdminAcquisitionSourceStat, 0, len(bySource))
+	for _, st := range bySource {
+		st.RevenueRub = NormalizeRevenueRub(st.RevenueByCurrency, config.CurrencyRateRub)
+		out = append(out, *st)
+	}
+	sortAcquisitionSources(out)
+	return out, nil
+}
+
+// sortAcquisitionSources — по убыванию стартов, при равенстве — по метке.
+func sortAcquisitionSources(list []AdminAcquisitionSourceStat) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Starts != list[j].Starts {
+			return list[i].Starts > list[j].Starts
+		}
+		return list[i].Source < list[j].Source
+	})
+}
@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -21,7 +22,12 @@ func NewStatsRepository(pool *pgxpool.Pool) *StatsRepository {
 
 const sqlSubPurchase = `p.status = 'paid' AND p.month > 0 AND p.purchase_kind IN ('subscription', 'tariff_upgrade')`
 
-const sqlRubCurrency = `(UPPER(TRIM(COALESCE(p.currency, ''))) IN ('RUB', 'RUR', '') OR COALESCE(p.currency, '') = '')`
+// sqlRubCurrency — рублёвая выручка. Пополнения баланса не считаются: выручка учитывается, когда баланс тратится
+// (покупка с invoice_type balance), иначе одни и те же деньги попали бы в отчёт дважды.
+const sqlRubCurrency = `((UPPER(TRIM(COALESCE(p.currency, ''))) IN ('RUB', 'RUR', '') OR COALESCE(p.currency, '') = '') AND p.purchase_kind IS DISTINCT FROM 'balance_topup')`
+
+// sqlNormalizedCurrency — код валюты покупки как в config.NormalizeCurrency (пусто / RUR → RUB, XTR → STARS).
+const sqlNormalizedCurrency = `CASE UPPER(TRIM(COALESCE(p.currency, ''))) WHEN '' THEN 'RUB' WHEN 'RUR' THEN 'RUB' WHEN 'XTR' THEN 'STARS' ELSE UPPER(TRIM(p.currency)) END`
 
 // AdminTopReferrer строка топа рефереров (дни начислений рефереру добиваются в handler через ReferralRepository).
 type AdminTopReferrer struct {
@@ -95,6 +101,14 @@ type AdminStatsSnapshot struct {
 	UniquePayersYear      int64
 	PaymentRubByInvoice   map[string]float64
 
+	// RevenueMonthByCurrency / RevenueAllTimeByCurrency — выручка в валюте оплаты (RUB, STARS, USD, …), без пополнений баланса.
+	RevenueMonthByCurrency   map[string]float64
+	RevenueAllTimeByCurrency map[string]float64
+	// RevenueMonthNormalizedRub / RevenueAllTimeNormalizedRub — то же, пересчитанное в рубли по курсам
+	// (RUB_PER_STAR, CURRENCY_RATES); валюты без курса не входят.
+	RevenueMonthNormalizedRub   float64
+	RevenueAllTimeNormalizedRub float64
+
 	DistinctReferrers int64
 	ActiveReferrers   int64
 	RefBonusDaysAll       int64
@@ -325,6 +339,15 @@ GROUP BY p.invoice_type`, sqlRubCurrency))
 		return nil, err
 	}
 
+	if out.RevenueMonthByCurrency, err = s.revenueByCurrency(ctx, &monthStart, &monthEnd); err != nil {
+		return nil, fmt.Errorf("stats revenue by currency month: %w", err)
+	}
+	if out.RevenueAllTimeByCurrency, err = s.revenueByCurrency(ctx, nil, nil); err != nil {
+		return nil, fmt.Errorf("stats revenue by currency all time: %w", err)
+	}
+	out.RevenueMonthNormalizedRub = NormalizeRevenueRub(out.RevenueMonthByCurrency, config.CurrencyRateRub)
+	out.RevenueAllTimeNormalizedRub = NormalizeRevenueRub(out.RevenueAllTimeByCurrency, config.CurrencyRateRub)
+
 	if err := s.pool.QueryRow(ctx, `SELECT COUNT(DISTINCT referrer_id) FROM referral`).Scan(&out.DistinctReferrers); err != nil {
 		return nil, fmt.Errorf("stats distinct referrers: %w", err)
 	}
@@ -371,6 +394,42 @@ WHERE EXISTS (
 	return out, nil
 }
 
+// revenueByCurrency — оплаченные суммы по валютам за [from, to); nil — за всё время.
+func (s *StatsRepository) revenueByCurrency(ctx context.Context, from, to *time.Time) (map[string]float64, error) {
+	rows, err := s.pool.Query(ctx, `
+SELECT `+sqlNormalizedCurrency+`, COALESCE(SUM(p.amount), 0)::float8
+FROM purchase p
+WHERE p.status = 'paid' AND p.paid_at IS NOT NULL AND p.purchase_kind IS DISTINCT FROM 'balance_topup'
+  AND ($1::timestamptz IS NULL OR p.paid_at >= $1) AND ($2::timestamptz IS NULL OR p.paid_at < $2)
+GROUP BY 1`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[string]float64)
+	for rows.Next() {
+		var cur string
+		var sum float64
+		if err := rows.Scan(&cur, &sum); err != nil {
+			return nil, err
+		}
+		out[cur] = sum
+	}
+	return out, rows.Err()
+}
+
+// NormalizeRevenueRub складывает выручку по валютам в рублях; rate — рублей за единицу валюты, 0 — курс неизвестен
+// (такая валюта пропускается).
+func NormalizeRevenueRub(byCurrency map[string]float64, rate func(string) float64) float64 {
+	var total float64
+	for cur, sum := range byCurrency {
+		if r := rate(cur); r > 0 {
+			total += sum * r
+		}
+	}
+	return math.Round(total*100) / 100
+}
+
 func (s *StatsRepository) referralBonusDaysRange(ctx context.Context, from, to time.Time) (int64, error) {
 	if config.ReferralMode() == "progressive" {
 		return s.sumProgressiveReferrerDays(ctx, from, to)
@@ -0,0 +1,16 @@
+package database
+
+import "testing"
+
+func TestNormalizeRevenueRub(t *testing.T) {
+	rates := map[string]float64{"RUB": 1, "STARS": 1.5, "USD": 90}
+	by := map[string]float64{"RUB": 1000, "STARS": 200, "USD": 10.5, "EUR": 50}
+	got := NormalizeRevenueRub(by, func(c string) float64 { return rates[c] })
+	// EUR без курса не учитывается.
+	if want := 1000 + 300 + 945.0; got != want {
+		t.Fatalf("NormalizeRevenueRub = %v, want %v", got, want)
+	}
+	if got := NormalizeRevenueRub(nil, func(string) float64 { return 1 }); got != 0 {
+		t.Fatalf("empty = %v, want 0", got)
+	}
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// ErrSubscriptionPauseActive — у клиента уже есть активная пауза (уникальный индекс по customer_id).
+var ErrSubscriptionPauseActive = errors.New("subscription is already paused")
+
+// SubscriptionPause — пауза подписки. RemainingSeconds — остаток срока на момент паузы;
+// ResumedAt == nil — пауза активна.
+type SubscriptionPause struct {
+	ID               int64      `db:"id"`
+	CustomerID       int64      `db:"customer_id"`
+	PausedAt         time.Time  `db:"paused_at"`
+	ResumeAt         time.Time  `db:"resume_at"`
+	RemainingSeconds int64      `db:"remaining_seconds"`
+	ResumedAt        *time.Time `db:"resumed_at"`
+}
+
+// Remaining — остаток подписки, замороженный паузой.
+func (p *SubscriptionPause) Remaining() time.Duration {
+	return time.Duration(p.RemainingSeconds) * time.Second
+}
+
+const subscriptionPauseColumns = "id, customer_id, paused_at, resume_at, remaining_seconds, resumed_at"
+
+type SubscriptionPauseRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewSubscriptionPauseRepository(pool *pgxpool.Pool) *SubscriptionPauseRepository {
+	return &SubscriptionPauseRepository{pool: pool}
+}
+
+func scanSubscriptionPause(sc interface{ Scan(dest ...any) error }, p *SubscriptionPause) error {
+	return sc.Scan(&p.ID, &p.CustomerID, &p.PausedAt, &p.ResumeAt, &p.RemainingSeconds, &p.ResumedAt)
+}
+
+// Create записывает новую паузу. ErrSubscriptionPauseActive — активная пауза уже есть.
+func (r *SubscriptionPauseRepository) Create(ctx context.Context, customerID int64, resumeAt time.Time, remaining time.Duration) (*SubscriptionPause, error) {
+	var p SubscriptionPause
+	err := scanSubscriptionPause(r.pool.QueryRow(ctx, `
+		INSERT INTO subscription_pause (customer_id, resume_at, remaining_seconds) VALUES ($1, $2, $3)
+		RETURNING `+subscriptionPauseColumns, customerID, resumeAt, int64(remaining/time.Second)), &p)
+	if err != nil {
+		if strings.Contains(err.Error(), "23505") {
+			return nil, ErrSubscriptionPauseActive
+		}
+		return nil, fmt.Errorf("failed to create subscription pause: %w", err)
+	}
+	return &p, nil
+}
+
+// FindActive — активная пауза клиента; nil, если подписка не на паузе.
+func (r *SubscriptionPauseRepository) FindActive(ctx context.Context, customerID int64) (*SubscriptionPause, error) {
+	var p SubscriptionPause
+	err := scanSubscriptionPause(r.pool.QueryRow(ctx,
+		`SELECT `+subscriptionPauseColumns+` FROM subscription_pause WHERE customer_id = $1 AND resumed_at IS NULL`, customerID), &p)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query subscription pause: %w", err)
+	}
+	return &p, nil
+}
+
+// CountSince — сколько пауз клиент начал начиная с since (для лимита за период).
+func (r *SubscriptionPauseRepository) CountSince(ctx context.Context, customerID int64, since time.Time) (int, error) {
+	var n int
+	err := r.pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM subscription_pause WHERE customer_id = $1 AND paused_at >= $2`, customerID, since).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count subscription pauses: %w", err)
+	}
+	return n, nil
+}
+
+// FindDue — активные паузы, у которых наступил resume_at, самые старые первыми.
+func (r *SubscriptionPauseRepository) FindDue(ctx context.Context, now time.Time, limit int) ([]SubscriptionPause, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+subscriptionPauseColumns+` FROM subscription_pause
+		WHERE resumed_at IS NULL AND resume_at <= $1
+		ORDER BY resume_at, id
+		LIMIT $2`, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due subscription pauses: %w", err)
+	}
+	defer rows.Close()
+	var out []SubscriptionPause
+	for rows.Next() {
+		var p SubscriptionPause
+		if err := scanSubscriptionPause(rows, &p); err != nil {
+			return nil, fmt.Errorf("failed to scan subscription pause: %w", err)
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subscription pause rows: %w", err)
+	}
+	return out, nil
+}
+
+// MarkResumed закрывает паузу. false — её уже закрыл другой процесс (крон или сам клиент).
+func (r *SubscriptionPauseRepository) MarkResumed(ctx context.Context, id int64, at time.Time) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `UPDATE subscription_pause SET resumed_at = $2 WHERE id = $1 AND resumed_at IS NULL`, id, at)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark subscription pause resumed: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Reopen снова делает паузу активной — откат, если панель не удалось включить.
+func (r *SubscriptionPauseRepository) Reopen(ctx context.Context, id int64) error {
+	if _, err := r.pool.Exec(ctx, `UPDATE subscription_pause SET resumed_at = NULL WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to reopen subscription pause: %w", err)
+	}
+	return nil
+}
+
+// Delete удаляет паузу — откат, если панель не удалось отключить.
+func (r *SubscriptionPauseRepository) Delete(ctx context.Context, id int64) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM subscription_pause WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete subscription pause: %w", err)
+	}
+	return nil
+}
@@ -29,6 +29,8 @@ type Tariff struct {
 	TierLevel                  *int       `db:"tier_level"`
 	Description                *string    `db:"description"`
 	DescriptionDetail          *string    `db:"description_detail"`
+	// FamilyMaxMembers — сколько участников владелец может пригласить в семью; 0 — тариф не семейный.
+	FamilyMaxMembers           int        `db:"family_max_members"`
 }
 
 // TariffPrice цена тарифа за период (месяцы).
@@ -57,7 +59,7 @@ func scanTariff(row rowScanner) (*Tariff, error) {
 		&t.ID, &t.Slug, &t.Name, &t.SortOrder, &t.IsActive,
 		&t.DeviceLimit, &t.TrafficLimitBytes, &t.TrafficLimitResetStrategy,
 		&t.ActiveInternalSquadUUIDs, &t.ExternalSquadUUID, &t.RemnawaveTag, &t.TierLevel,
-		&t.Description, &t.DescriptionDetail,
+		&t.Description, &t.DescriptionDetail, &t.FamilyMaxMembers,
 	)
 	if err != nil {
 		return nil, err
@@ -71,7 +73,7 @@ func (r *TariffRepository) ListActive(ctx context.Context) ([]Tariff, error) {
 		"id", "slug", "name", "sort_order", "is_active",
 		"device_limit", "traffic_limit_bytes", "traffic_limit_reset_strategy",
 		"active_internal_squad_uuids", "external_squad_uuid", "remnawave_tag", "tier_level",
-		"description", "description_detail",
+		"description", "description_detail", "family_max_members",
 	).From("tariff").Where(sq.Eq{"is_active": true}).OrderBy("sort_order ASC", "id ASC").PlaceholderFormat(sq.Dollar)
 	sqlStr, args, err := q.ToSql()
 	if err != nil {
@@ -102,7 +104,7 @@ func (r *TariffRepository) GetByID(ctx context.Context, id int64) (*Tariff, erro
 		"id", "slug", "name", "sort_order", "is_active",
 		"device_limit", "traffic_limit_bytes", "traffic_limit_reset_strategy",
 		"active_internal_squad_uuids", "external_squad_uuid", "remnawave_tag", "tier_level",
-		"description", "description_detail",
+		"description", "description_detail", "family_max_members",
 	).From("tariff").Where(sq.Eq{"id": id}).PlaceholderFormat(sq.Dollar)
 	sqlStr, args, err := q.ToSql()
 	if err != nil {
@@ -194,7 +196,7 @@ func (r *TariffRepository) ListAll(ctx context.Context) ([]Tariff, error) {
 		"id", "slug", "name", "sort_order", "is_active",
 		"device_limit", "traffic_limit_bytes", "traffic_limit_reset_strategy",
 		"active_internal_squad_uuids", "external_squad_uuid", "remnawave_tag", "tier_level",
-		"description", "description_detail",
+		"description", "description_detail", "family_max_members",
 	).From("tariff").OrderBy("sort_order ASC", "id ASC").PlaceholderFormat(sq.Dollar)
 	sqlStr, args, err := q.ToSql()
 	if err != nil {
@@ -288,13 +290,13 @@ func (r *TariffRepository) CreateWithPrices(ctx context.Context, t *Tariff, rub
 	defer tx.Rollback(ctx)
 
 	q := `INSERT INTO tariff (slug, name, sort_order, is_active, device_limit, traffic_limit_bytes,
-		traffic_limit_reset_strategy, active_internal_squad_uuids, external_squad_uuid, remnawave_tag, tier_level, description, description_detail)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13) RETURNING id`
+		traffic_limit_reset_strategy, active_internal_squad_uuids, external_squad_uuid, remnawave_tag, tier_level, description, description_detail, family_max_members)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14) RETURNING id`
 	var id int64
 	err = tx.QueryRow(ctx, q,
 		t.Slug, t.Name, t.SortOrder, t.IsActive, t.DeviceLimit, t.TrafficLimitBytes,
 		t.TrafficLimitResetStrategy, t.ActiveInternalSquadUUIDs, t.ExternalSquadUUID, t.RemnawaveTag, t.TierLevel,
-		t.Description, t.DescriptionDetail,
+		t.Description, t.DescriptionDetail, t.FamilyMaxMembers,
 	).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("insert tariff: %w", err)
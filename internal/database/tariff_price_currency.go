@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// TariffCurrencyPrice цена тарифа за период в дополнительной валюте (USD, EUR, …).
+type TariffCurrencyPrice struct {
+	TariffID int64   `db:"tariff_id"`
+	Months   int     `db:"months"`
+	Currency string  `db:"currency"`
+	Amount   float64 `db:"amount"`
+}
+
+// ListCurrencyPrices — валютные цены тарифа по месяцам и коду валюты.
+func (r *TariffRepository) ListCurrencyPrices(ctx context.Context, tariffID int64) ([]TariffCurrencyPrice, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT tariff_id, months, currency, amount::float8
+		FROM tariff_price_currency
+		WHERE tariff_id = $1
+		ORDER BY months ASC, currency ASC`, tariffID)
+	if err != nil {
+		return nil, fmt.Errorf("list tariff currency prices: %w", err)
+	}
+	defer rows.Close()
+	var out []TariffCurrencyPrice
+	for rows.Next() {
+		var p TariffCurrencyPrice
+		if err := rows.Scan(&p.TariffID, &p.Months, &p.Currency, &p.Amount); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// GetCurrencyPrice возвращает цену (tariff_id, months) в валюте currency; ok=false — цена не задана.
+func (r *TariffRepository) GetCurrencyPrice(ctx context.Context, tariffID int64, months int, currency string) (amount float64, ok bool, err error) {
+	err = r.pool.QueryRow(ctx, `
+		SELECT amount::float8 FROM tariff_price_currency
+		WHERE tariff_id = $1 AND months = $2 AND currency = $3`,
+		tariffID, months, currency).Scan(&amount)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("get tariff currency price: %w", err)
+	}
+	return amount, true, nil
+}
+
+// ReplaceCurrencyPrices заменяет все валютные цены тарифа; строки с amount <= 0 пропускаются.
+func (r *TariffRepository) ReplaceCurrencyPrices(ctx context.Context, tariffID int64, prices []TariffCurrencyPrice) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `DELETE FROM tariff_price_currency WHERE tariff_id = $1`, tariffID); err != nil {
+		return fmt.Errorf("delete tariff currency prices: %w", err)
+	}
+	for _, p := range prices {
+		if p.Amount <= 0 {
+			continue
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO tariff_price_currency (tariff_id, months, currency, amount) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (tariff_id, months, currency) DO UPDATE SET amount = EXCLUDED.amount, updated_at = NOW()`,
+			tariffID, p.Months, p.Currency, p.Amount,
+		); err != nil {
+			return fmt.Errorf("insert tariff currency price: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// TariffTrafficPack — пакет трафика тарифа: +TrafficGB к лимиту до ближайшего сброса.
+// PriceStars == 0 — цена в Stars считается по RUB_PER_STAR.
+type TariffTrafficPack struct {
+	TariffID   int64 `db:"tariff_id"`
+	TrafficGB  int   `db:"traffic_gb"`
+	PriceRub   int   `db:"price_rub"`
+	PriceStars int   `db:"price_stars"`
+}
+
+// ListTrafficPacks — пакеты трафика тарифа от меньшего к большему.
+func (r *TariffRepository) ListTrafficPacks(ctx context.Context, tariffID int64) ([]TariffTrafficPack, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT tariff_id, traffic_gb, price_rub, price_stars
+		FROM tariff_traffic_pack
+		WHERE tariff_id = $1
+		ORDER BY traffic_gb ASC`, tariffID)
+	if err != nil {
+		return nil, fmt.Errorf("list tariff traffic packs: %w", err)
+	}
+	defer rows.Close()
+	var out []TariffTrafficPack
+	for rows.Next() {
+		var p TariffTrafficPack
+		if err := rows.Scan(&p.TariffID, &p.TrafficGB, &p.PriceRub, &p.PriceStars); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// GetTrafficPack — пакет тарифа на trafficGB; nil — такого пакета нет.
+func (r *TariffRepository) GetTrafficPack(ctx context.Context, tariffID int64, trafficGB int) (*TariffTrafficPack, error) {
+	var p TariffTrafficPack
+	err := r.pool.QueryRow(ctx, `
+		SELECT tariff_id, traffic_gb, price_rub, price_stars
+		FROM tariff_traffic_pack
+		WHERE tariff_id = $1 AND traffic_gb = $2`, tariffID, trafficGB).
+		Scan(&p.TariffID, &p.TrafficGB, &p.PriceRub, &p.PriceStars)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get tariff traffic pack: %w", err)
+	}
+	return &p, nil
+}
+
+// ReplaceTrafficPacks заменяет все пакеты трафика тарифа; строки с traffic_gb или price_rub <= 0 пропускаются.
+func (r *TariffRepository) ReplaceTrafficPacks(ctx context.Context, tariffID int64, packs []TariffTrafficPack) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `DELETE FROM tariff_traffic_pack WHERE tariff_id = $1`, tariffID); err != nil {
+		return fmt.Errorf("delete tariff traffic packs: %w", err)
+	}
+	for _, p := range packs {
+		if p.TrafficGB <= 0 || p.PriceRub <= 0 {
+			continue
+		}
+		stars := p.PriceStars
+		if stars < 0 {
+			stars = 0
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO tariff_traffic_pack (tariff_id, traffic_gb, price_rub, price_stars) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (tariff_id, traffic_gb) DO UPDATE SET price_rub = EXCLUDED.price_rub, price_stars = EXCLUDED.price_stars`,
+			tariffID, p.TrafficGB, p.PriceRub, stars,
+		); err != nil {
+			return fmt.Errorf("insert tariff traffic pack: %w", err)
+		}
+	}
+	return tx.Commit(ctx)
+}
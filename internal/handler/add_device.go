@@ -14,6 +14,7 @@ import (
 
 	"remnawave-tg-shop-bot/internal/config"
 	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/promo"
 	"remnawave-tg-shop-bot/internal/remnawave"
 	"remnawave-tg-shop-bot/internal/translation"
 	"remnawave-tg-shop-bot/utils"
@@ -282,9 +283,12 @@ func (h Handler) AddDevicePaymentCallbackHandler(ctx context.Context, b *bot.Bot
 
 	invoiceType := database.InvoiceType(params["invoiceType"])
 	amt := amount
-	meta := h.checkoutPromoMeta(ctx, customer, invoiceType, &amt)
+	meta := h.checkoutPromoMeta(ctx, customer, promo.NewCheckoutOrder(invoiceType, nil, 0, amt), &amt)
 	ctxWithUsername := context.WithValue(ctx, remnawave.CtxKeyUsername, update.CallbackQuery.From.Username)
 	paymentURL, purchaseId, err := h.paymentService.CreateHwidPurchase(ctxWithUsername, float64(amt), delta, customer, invoiceType, meta)
+	if h.handleBalanceCheckout(ctx, b, update, langCode, invoiceType, err, fmt.Sprintf("%s?target=%d", CallbackAddDevicePayment, target)) {
+		return
+	}
 	if err != nil {
 		slog.Error("Error creating hwid payment", "error", err)
 		h.notifyPaymentProviderUnavailable(ctx, b, update, langCode, fmt.Sprintf("%s?target=%d", CallbackAddDevicePayment, target))
@@ -373,53 +377,11 @@ func (h Handler) showDeviceChangeConfirm(ctx context.Context, b *bot.Bot, callba
 }
 
 func (h Handler) showDevicePaymentMethods(ctx context.Context, b *bot.Bot, callbackMessage *models.Message, langCode string, target, amount int) {
-	var keyboard [][]models.InlineKeyboardButton
-
-	if config.IsCryptoPayEnabled() {
-		keyboard = append(keyboard, []models.InlineKeyboardButton{
-			h.translation.WithButton(langCode, "crypto_button", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s?target=%d&invoiceType=%s", CallbackAddDevicePayment, target, database.InvoiceTypeCrypto)}),
-		})
-	}
-
-	if config.IsYookasaEnabled() {
-		keyboard = append(keyboard, []models.InlineKeyboardButton{
-			h.translation.WithButton(langCode, "card_button", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s?target=%d&invoiceType=%s", CallbackAddDevicePayment, target, database.InvoiceTypeYookasa)}),
-		})
-	}
-
-	keyboard = h.appendPlategaAddDevicePaymentRows(keyboard, langCode, target)
-
-	if config.IsTelegramStarsEnabled() {
-		shouldShowStarsButton := true
-		if config.RequirePaidPurchaseForStars() {
-			customer, err := h.customerRepository.FindByTelegramId(ctx, callbackMessage.Chat.ID)
-			if err != nil {
-				slog.Error("Error finding customer for stars check", "error", err)
-				shouldShowStarsButton = false
-			} else if customer != nil {
-				hasPaid, err := h.purchaseRepository.HasPaidSubscription(ctx, customer.ID)
-				if err != nil {
-					slog.Error("Error checking paid purchase", "error", err)
-					shouldShowStarsButton = false
-				} else if !hasPaid {
-					shouldShowStarsButton = false
-				}
-			} else {
-				shouldShowStarsButton = false
-			}
-		}
-		if shouldShowStarsButton {
-			keyboard = append(keyboard, []models.InlineKeyboardButton{
-				h.translation.WithButton(langCode, "stars_button", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s?target=%d&invoiceType=%s", CallbackAddDevicePayment, target, database.InvoiceTypeTelegram)}),
-			})
-		}
-	}
-
-	if config.GetTributeWebHookUrl() != "" {
-		keyboard = append(keyboard, []models.InlineKeyboardButton{
-			h.translation.WithButton(langCode, "tribute_button", models.InlineKeyboardButton{URL: config.GetTributePaymentUrl()}),
-		})
-	}
+	keyboard := h.paymentMethodRows(langCode, func(t database.InvoiceType) string {
+		return fmt.Sprintf("%s?target=%d&invoiceType=%s", CallbackAddDevicePayment, target, t)
+	}, func() bool {
+		return h.starsAllowedForChat(ctx, callbackMessage.Chat.ID)
+	})
 
 	keyboard = append(keyboard, []models.InlineKeyboardButton{
 		h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s?target=%d", CallbackAddDeviceConfirm, target)}),
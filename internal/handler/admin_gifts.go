@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/staff"
+)
+
+const adminGiftsListSize = 20
+
+// AdminGiftsHandler — последние выпущенные подарочные коды со статусами (только просмотр).
+func (h Handler) AdminGiftsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermPayments) {
+		return
+	}
+	msg := update.CallbackQuery.Message.Message
+	if msg == nil {
+		return
+	}
+	lang := update.CallbackQuery.From.LanguageCode
+	gifts, err := h.paymentService.ListGifts(ctx, "", adminGiftsListSize, 0)
+	if err != nil {
+		slog.Error("admin gifts: list", "error", err)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(h.translation.GetText(lang, "admin_gifts_title"))
+	sb.WriteString("\n\n")
+	if len(gifts) == 0 {
+		sb.WriteString(h.translation.GetText(lang, "admin_gifts_empty"))
+	}
+	now := time.Now().UTC()
+	for i := range gifts {
+		g := &gifts[i]
+		status := g.EffectiveStatus(now)
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "admin_gifts_line"),
+			g.Code, g.Months, h.translation.GetText(lang, "admin_gift_status_"+string(status)), g.BuyerCustomerID, g.CreatedAt.Format("02.01.2006")))
+		if status == database.GiftStatusRedeemed && g.RedeemedByCustomerID != nil {
+			sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "admin_gifts_redeemed_by"), *g.RedeemedByCustomerID))
+		}
+		sb.WriteString("\n")
+	}
+
+	kb := [][]models.InlineKeyboardButton{
+		{h.translation.WithButton(lang, "back_button", models.InlineKeyboardButton{CallbackData: CallbackAdminPanel})},
+	}
+	_, err = editCallbackOriginToHTMLText(ctx, b, msg, sb.String(), models.ParseModeHTML, models.InlineKeyboardMarkup{InlineKeyboard: kb}, nil)
+	logEditError("admin gifts edit", err)
+}
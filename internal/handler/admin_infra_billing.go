@@ -11,8 +11,9 @@ import (
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 
-	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/audit"
 	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/internal/staff"
 )
 
 // sendInfraWizardPrompt — текстовый шаг мастера с кнопкой «Назад» (callback очищает мастер и возвращает экран).
@@ -35,7 +36,7 @@ func (h Handler) sendInfraWizardPrompt(ctx context.Context, b *bot.Bot, chatID i
 
 // AdminInfraWizBackRouter — префикс ifb* (отмена текстового мастера, возврат к экрану).
 func (h Handler) AdminInfraWizBackRouter(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermInfra) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -202,7 +203,7 @@ func infraHistoryCallback(page int) string {
 
 // AdminInfraRootHandler — корень «Инфра-биллинг».
 func (h Handler) AdminInfraRootHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermInfra) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -230,7 +231,7 @@ func (h Handler) AdminInfraRootHandler(ctx context.Context, b *bot.Bot, update *
 
 // AdminInfraNodesHandler — список нод и дат оплаты.
 func (h Handler) AdminInfraNodesHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermInfra) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -283,7 +284,7 @@ func (h Handler) adminInfraNotifyKeyboard(lang string, st struct {
 
 // AdminInfraNotifyHandler — тумблеры напоминаний за N дней.
 func (h Handler) AdminInfraNotifyHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermInfra) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -312,7 +313,7 @@ func (h Handler) AdminInfraNotifyHandler(ctx context.Context, b *bot.Bot, update
 
 // AdminInfraToggleHandler — callback ibt1 / ibt3 / ibt7 / ibt14.
 func (h Handler) AdminInfraToggleHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermInfra) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -345,6 +346,11 @@ func (h Handler) AdminInfraToggleHandler(ctx context.Context, b *bot.Bot, update
 		slog.Error("admin infra toggle set", "error", err)
 		return
 	}
+	audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+		Action: audit.ActionInfraUpdate, TargetType: "infra_settings",
+		Before: map[string]any{fmt.Sprintf("notify_before_%d", days): cur},
+		After:  map[string]any{fmt.Sprintf("notify_before_%d", days): !cur},
+	})
 	st2, err := h.infraBillingRepository.GetSettings(ctx)
 	if err != nil {
 		slog.Error("admin infra toggle get2", "error", err)
@@ -370,7 +376,7 @@ func (h Handler) AdminInfraToggleHandler(ctx context.Context, b *bot.Bot, update
 
 // AdminInfraHistoryHandler — история оплат (пагинация по 10).
 func (h Handler) AdminInfraHistoryHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermInfra) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -388,7 +394,7 @@ func (h Handler) AdminInfraHistoryHandler(ctx context.Context, b *bot.Bot, updat
 
 // AdminInfraProvidersHandler — список провайдеров.
 func (h Handler) AdminInfraProvidersHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermInfra) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -16,8 +16,9 @@ import (
 	"github.com/go-telegram/bot/models"
 	"github.com/google/uuid"
 
-	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/audit"
 	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/internal/staff"
 )
 
 // Callback-префиксы CRUD (не пересекаются с ib_*): ifn* — ноды, ifp* — провайдеры, ifh* — история.
@@ -260,7 +261,7 @@ func (h Handler) renderAdminInfraNodesScreen(ctx context.Context, b *bot.Bot, ch
 
 // AdminInfraNodeCRUDRouter — префикс ifn* (ноды).
 func (h Handler) AdminInfraNodeCRUDRouter(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermInfra) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -426,6 +427,9 @@ func (h Handler) AdminInfraNodeCRUDRouter(ctx context.Context, b *bot.Bot, updat
 			h.editAdminInfraAPIError(ctx, b, msg, lang, err)
 			return
 		}
+		audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+			Action: audit.ActionInfraDelete, TargetType: "infra_node", TargetID: bu.String(),
+		})
 		if err := h.renderAdminInfraNodesScreen(ctx, b, msg.Chat.ID, msg.ID, lang); err != nil {
 			logEditError("infra node delete refresh", err)
 		}
@@ -434,7 +438,7 @@ func (h Handler) AdminInfraNodeCRUDRouter(ctx context.Context, b *bot.Bot, updat
 
 // AdminInfraProviderCRUDRouter — префикс ifp*.
 func (h Handler) AdminInfraProviderCRUDRouter(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermInfra) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -519,6 +523,9 @@ func (h Handler) AdminInfraProviderCRUDRouter(ctx context.Context, b *bot.Bot, u
 			h.editAdminInfraAPIError(ctx, b, msg, lang, err)
 			return
 		}
+		audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+			Action: audit.ActionInfraDelete, TargetType: "infra_provider", TargetID: pu.String(),
+		})
 		_ = h.renderAdminInfraProvidersScreen(ctx, b, msg.Chat.ID, msg.ID, lang)
 
 	case strings.HasPrefix(data, cbInfraProvDeleteNo):
@@ -624,7 +631,7 @@ func (h Handler) AdminInfraProviderCRUDRouter(ctx context.Context, b *bot.Bot, u
 
 // AdminInfraHistoryCRUDRouter — префикс ifh*.
 func (h Handler) AdminInfraHistoryCRUDRouter(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermInfra) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -714,6 +721,9 @@ func (h Handler) AdminInfraHistoryCRUDRouter(ctx context.Context, b *bot.Bot, up
 			h.editAdminInfraAPIError(ctx, b, msg, lang, err)
 			return
 		}
+		audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+			Action: audit.ActionInfraDelete, TargetType: "infra_history", TargetID: ru.String(),
+		})
 		if err := h.renderInfraHistoryPage(ctx, b, msg, lang, page); err != nil {
 			logEditError("infra hist after delete", err)
 		}
@@ -859,7 +869,7 @@ func (h Handler) AdminInfraBillingTextHandler(ctx context.Context, b *bot.Bot, u
 		return
 	}
 	adminID := update.Message.From.ID
-	if adminID != config.GetAdminTelegramId() {
+	if !staff.Can(adminID, staff.PermInfra) {
 		return
 	}
 	st, ok := infraWizGet(adminID)
@@ -877,15 +887,19 @@ func (h Handler) AdminInfraBillingTextHandler(ctx context.Context, b *bot.Bot, u
 			_, _ = b.SendMessage(ctx, &bot.SendMessageParams{ChatID: adminID, Text: h.translation.GetText(lang, "admin_infra_wiz_bad_date")})
 			return
 		}
-		_, err = h.remnawaveClient.PatchInfraBillingNodes(ctx, remnawave.UpdateInfraBillingNodeRequest{
+		req := remnawave.UpdateInfraBillingNodeRequest{
 			UUIDs:         []uuid.UUID{st.BillingUUID},
 			NextBillingAt: t,
-		})
+		}
+		_, err = h.remnawaveClient.PatchInfraBillingNodes(ctx, req)
 		infraWizClear(adminID)
 		if err != nil {
 			_, _ = b.SendMessage(ctx, &bot.SendMessageParams{ChatID: adminID, Text: h.translation.GetText(lang, "admin_infra_api_error")})
 			return
 		}
+		audit.Record(ctx, audit.Bot(adminID), audit.Entry{
+			Action: audit.ActionInfraUpdate, TargetType: "infra_node", TargetID: st.BillingUUID.String(), After: req,
+		})
 		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: adminID,
 			Text:   h.translation.GetText(lang, "admin_infra_wiz_done"),
@@ -905,16 +919,20 @@ func (h Handler) AdminInfraBillingTextHandler(ctx context.Context, b *bot.Bot, u
 			infraWizClear(adminID)
 			return
 		}
-		_, err = h.remnawaveClient.CreateInfraBillingNode(ctx, remnawave.CreateInfraBillingNodeRequest{
+		req := remnawave.CreateInfraBillingNodeRequest{
 			ProviderUUID:  st.ProviderUUID,
 			NodeUUID:      nu,
 			NextBillingAt: &t,
-		})
+		}
+		_, err = h.remnawaveClient.CreateInfraBillingNode(ctx, req)
 		infraWizClear(adminID)
 		if err != nil {
 			_, _ = b.SendMessage(ctx, &bot.SendMessageParams{ChatID: adminID, Text: h.translation.GetText(lang, "admin_infra_api_error")})
 			return
 		}
+		audit.Record(ctx, audit.Bot(adminID), audit.Entry{
+			Action: audit.ActionInfraCreate, TargetType: "infra_node", TargetID: nu.String(), After: req,
+		})
 		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: adminID,
 			Text:   h.translation.GetText(lang, "admin_infra_wiz_node_created"),
@@ -948,16 +966,20 @@ func (h Handler) AdminInfraBillingTextHandler(ctx context.Context, b *bot.Bot, u
 			_, _ = b.SendMessage(ctx, &bot.SendMessageParams{ChatID: adminID, Text: h.translation.GetText(lang, "admin_infra_wiz_bad_date")})
 			return
 		}
-		_, err = h.remnawaveClient.CreateInfraBillingHistory(ctx, remnawave.CreateInfraBillingHistoryRequest{
+		req := remnawave.CreateInfraBillingHistoryRequest{
 			ProviderUUID: st.ProviderUUID,
 			Amount:       st.HistDraftAmount,
 			BilledAt:     t,
-		})
+		}
+		_, err = h.remnawaveClient.CreateInfraBillingHistory(ctx, req)
 		infraWizClear(adminID)
 		if err != nil {
 			_, _ = b.SendMessage(ctx, &bot.SendMessageParams{ChatID: adminID, Text: h.translation.GetText(lang, "admin_infra_api_error")})
 			return
 		}
+		audit.Record(ctx, audit.Bot(adminID), audit.Entry{
+			Action: audit.ActionInfraCreate, TargetType: "infra_history", After: req,
+		})
 		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: adminID,
 			Text:   h.translation.GetText(lang, "admin_infra_wiz_hist_created"),
@@ -998,6 +1020,9 @@ func (h Handler) AdminInfraBillingTextHandler(ctx context.Context, b *bot.Bot, u
 			_, _ = b.SendMessage(ctx, &bot.SendMessageParams{ChatID: adminID, Text: h.translation.GetText(lang, "admin_infra_api_error")})
 			return
 		}
+		audit.Record(ctx, audit.Bot(adminID), audit.Entry{
+			Action: audit.ActionInfraCreate, TargetType: "infra_provider", After: req,
+		})
 		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: adminID,
 			Text:   h.translation.GetText(lang, "admin_infra_wiz_prov_created"),
@@ -1012,15 +1037,17 @@ func (h Handler) AdminInfraBillingTextHandler(ctx context.Context, b *bot.Bot, u
 			return
 		}
 		pu := st.ProvEditUUID
-		_, err := h.remnawaveClient.PatchInfraProvider(ctx, remnawave.UpdateInfraProviderRequest{
+		req := remnawave.UpdateInfraProviderRequest{
 			UUID: pu,
 			Name: &text,
-		})
+		}
+		_, err := h.remnawaveClient.PatchInfraProvider(ctx, req)
 		infraWizClear(adminID)
 		if err != nil {
 			_, _ = b.SendMessage(ctx, &bot.SendMessageParams{ChatID: adminID, Text: h.translation.GetText(lang, "admin_infra_api_error")})
 			return
 		}
+		h.recordInfraProviderUpdate(ctx, adminID, req)
 		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:      adminID,
 			Text:        h.translation.GetText(lang, "admin_infra_wiz_done"),
@@ -1033,15 +1060,17 @@ func (h Handler) AdminInfraBillingTextHandler(ctx context.Context, b *bot.Bot, u
 			fav = &text
 		}
 		pu := st.ProvEditUUID
-		_, err := h.remnawaveClient.PatchInfraProvider(ctx, remnawave.UpdateInfraProviderRequest{
+		req := remnawave.UpdateInfraProviderRequest{
 			UUID:        pu,
 			FaviconLink: fav,
-		})
+		}
+		_, err := h.remnawaveClient.PatchInfraProvider(ctx, req)
 		infraWizClear(adminID)
 		if err != nil {
 			_, _ = b.SendMessage(ctx, &bot.SendMessageParams{ChatID: adminID, Text: h.translation.GetText(lang, "admin_infra_api_error")})
 			return
 		}
+		h.recordInfraProviderUpdate(ctx, adminID, req)
 		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:      adminID,
 			Text:        h.translation.GetText(lang, "admin_infra_wiz_done"),
@@ -1054,15 +1083,17 @@ func (h Handler) AdminInfraBillingTextHandler(ctx context.Context, b *bot.Bot, u
 			login = &text
 		}
 		pu := st.ProvEditUUID
-		_, err := h.remnawaveClient.PatchInfraProvider(ctx, remnawave.UpdateInfraProviderRequest{
+		req := remnawave.UpdateInfraProviderRequest{
 			UUID:     pu,
 			LoginURL: login,
-		})
+		}
+		_, err := h.remnawaveClient.PatchInfraProvider(ctx, req)
 		infraWizClear(adminID)
 		if err != nil {
 			_, _ = b.SendMessage(ctx, &bot.SendMessageParams{ChatID: adminID, Text: h.translation.GetText(lang, "admin_infra_api_error")})
 			return
 		}
+		h.recordInfraProviderUpdate(ctx, adminID, req)
 		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:      adminID,
 			Text:        h.translation.GetText(lang, "admin_infra_wiz_done"),
@@ -1071,6 +1102,13 @@ func (h Handler) AdminInfraBillingTextHandler(ctx context.Context, b *bot.Bot, u
 	}
 }
 
+// recordInfraProviderUpdate пишет в журнал правку провайдера из мастера.
+func (h Handler) recordInfraProviderUpdate(ctx context.Context, adminID int64, req remnawave.UpdateInfraProviderRequest) {
+	audit.Record(ctx, audit.Bot(adminID), audit.Entry{
+		Action: audit.ActionInfraUpdate, TargetType: "infra_provider", TargetID: req.UUID.String(), After: req,
+	})
+}
+
 func (h Handler) renderAdminInfraProvidersScreen(ctx context.Context, b *bot.Bot, chatID int64, msgID int, lang string) error {
 	body, err := h.remnawaveClient.GetInfraBillingProviders(ctx)
 	if err != nil {
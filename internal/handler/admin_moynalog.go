@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/audit"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/staff"
+)
+
+const adminMoynalogReceiptsListSize = 10
+
+var adminMoynalogFailedStatuses = []database.MoynalogReceiptStatus{
+	database.MoynalogReceiptStatusFailed,
+	database.MoynalogReceiptStatusCancelFailed,
+	database.MoynalogReceiptStatusNeedsAttention,
+}
+
+// AdminMoynalogReceiptsHandler — чеки «Мой налог», по которым исчерпаны попытки отправки или аннулирования
+// или не сохранился UUID созданного чека.
+func (h Handler) AdminMoynalogReceiptsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermPayments) {
+		return
+	}
+	msg := update.CallbackQuery.Message.Message
+	if msg == nil {
+		return
+	}
+	h.renderAdminMoynalogReceipts(ctx, b, msg, update.CallbackQuery.From.LanguageCode)
+}
+
+func (h Handler) renderAdminMoynalogReceipts(ctx context.Context, b *bot.Bot, msg *models.Message, lang string) {
+	receipts, err := h.paymentService.ListMoynalogReceipts(ctx, adminMoynalogFailedStatuses, adminMoynalogReceiptsListSize, 0)
+	if err != nil {
+		slog.Error("admin moynalog receipts: list", "error", err)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(h.translation.GetText(lang, "admin_moynalog_receipts_title"))
+	sb.WriteString("\n\n")
+	if len(receipts) == 0 {
+		sb.WriteString(h.translation.GetText(lang, "admin_moynalog_receipts_empty"))
+	}
+	var kb [][]models.InlineKeyboardButton
+	for i := range receipts {
+		rc := &receipts[i]
+		lastErr := ""
+		if rc.LastError != nil {
+			lastErr = *rc.LastError
+			if len([]rune(lastErr)) > 120 {
+				lastErr = string([]rune(lastErr)[:120]) + "…"
+			}
+		}
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "admin_moynalog_receipts_line"),
+			rc.ID,
+			h.translation.GetText(lang, "admin_moynalog_status_"+string(rc.Status)),
+			rc.PurchaseID,
+			rc.Amount,
+			rc.UpdatedAt.Format("02.01.2006 15:04"),
+			html.EscapeString(lastErr),
+		))
+		sb.WriteString("\n\n")
+		kb = append(kb, []models.InlineKeyboardButton{
+			{Text: fmt.Sprintf("🔁 #%d", rc.ID), CallbackData: fmt.Sprintf("%s%d", CallbackAdminMoynalogRetryPrefix, rc.ID)},
+		})
+	}
+	kb = append(kb, []models.InlineKeyboardButton{
+		h.translation.WithButton(lang, "back_button", models.InlineKeyboardButton{CallbackData: CallbackAdminPanel}),
+	})
+	_, err = editCallbackOriginToHTMLText(ctx, b, msg, sb.String(), models.ParseModeHTML, models.InlineKeyboardMarkup{InlineKeyboard: kb}, nil)
+	logEditError("admin moynalog receipts edit", err)
+}
+
+// AdminMoynalogRetryHandler — «повторить сейчас»: одна попытка сразу, при неудаче чек остаётся в очереди с повторами.
+func (h Handler) AdminMoynalogRetryHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermPayments) {
+		return
+	}
+	cb := update.CallbackQuery
+	id, ok := parsePurchaseIDFromPrefix(cb.Data, CallbackAdminMoynalogRetryPrefix)
+	if !ok {
+		return
+	}
+	msg := cb.Message.Message
+	if msg == nil {
+		return
+	}
+	lang := cb.From.LanguageCode
+	rc, err := h.paymentService.RetryMoynalogReceipt(ctx, id)
+	var text string
+	switch {
+	case errors.Is(err, database.ErrMoynalogReceiptNotRetryable):
+		text = h.translation.GetText(lang, "admin_moynalog_retry_not_failed")
+	case err != nil:
+		slog.Error("admin moynalog retry", "error", err, "receipt_id", id)
+		text = h.translation.GetText(lang, "admin_user_action_error")
+	case rc == nil:
+		text = h.translation.GetText(lang, "admin_user_action_error")
+	default:
+		audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+			Action: audit.ActionMoynalogRetry, TargetType: "moynalog_receipt", TargetID: strconv.FormatInt(id, 10),
+			After: map[string]any{"status": rc.Status, "purchase_id": rc.PurchaseID},
+		})
+		text = fmt.Sprintf(h.translation.GetText(lang, "admin_moynalog_retry_result"), rc.ID,
+			h.translation.GetText(lang, "admin_moynalog_status_"+string(rc.Status)))
+	}
+	_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: cb.ID,
+		Text:            text,
+		ShowAlert:       true,
+	})
+	h.renderAdminMoynalogReceipts(ctx, b, msg, lang)
+}
@@ -8,40 +8,76 @@ import (
 	"github.com/go-telegram/bot/models"
 
 	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/staff"
 )
 
-// RenderAdminPanel edits an existing message to the admin root menu (broadcast, sync, promos).
-func (h Handler) RenderAdminPanel(ctx context.Context, b *bot.Bot, msg *models.Message, lang string) error {
+// RenderAdminPanel edits an existing message to the admin root menu; sections are filtered by the staff member's permissions.
+func (h Handler) RenderAdminPanel(ctx context.Context, b *bot.Bot, msg *models.Message, userID int64, lang string) error {
 	if msg == nil {
 		return nil
 	}
-	kb := [][]models.InlineKeyboardButton{
-		{
-			h.translation.WithButton(lang, "admin_users_submenu_button", models.InlineKeyboardButton{CallbackData: CallbackAdminUsersRoot}),
-			h.translation.WithButton(lang, "admin_tariffs", models.InlineKeyboardButton{CallbackData: CallbackAdminTariffs}),
-		},
+	can := func(perm staff.Permission) bool { return staff.Can(userID, perm) }
+	var kb [][]models.InlineKeyboardButton
+	appendRow := func(row []models.InlineKeyboardButton) {
+		if len(row) > 0 {
+			kb = append(kb, row)
+		}
 	}
-	if config.LoyaltyEnabled() {
-		kb = append(kb, []models.InlineKeyboardButton{
-			h.translation.WithButton(lang, "admin_promos", models.InlineKeyboardButton{CallbackData: CallbackAdminPromo}),
-			h.translation.WithButton(lang, "admin_loyalty", models.InlineKeyboardButton{CallbackData: CallbackAdminLoyaltyRoot}),
-		})
-	} else {
-		kb = append(kb, []models.InlineKeyboardButton{
-			h.translation.WithButton(lang, "admin_promos", models.InlineKeyboardButton{CallbackData: CallbackAdminPromo}),
+
+	var row []models.InlineKeyboardButton
+	if can(staff.PermUsersRead) {
+		row = append(row, h.translation.WithButton(lang, "admin_users_submenu_button", models.InlineKeyboardButton{CallbackData: CallbackAdminUsersRoot}))
+	}
+	if can(staff.PermTariffs) {
+		row = append(row, h.translation.WithButton(lang, "admin_tariffs", models.InlineKeyboardButton{CallbackData: CallbackAdminTariffs}))
+	}
+	appendRow(row)
+
+	row = nil
+	if can(staff.PermPromos) {
+		row = append(row, h.translation.WithButton(lang, "admin_promos", models.InlineKeyboardButton{CallbackData: CallbackAdminPromo}))
+		if config.LoyaltyEnabled() {
+			row = append(row, h.translation.WithButton(lang, "admin_loyalty", models.InlineKeyboardButton{CallbackData: CallbackAdminLoyaltyRoot}))
+		}
+	}
+	appendRow(row)
+
+	if can(staff.PermBroadcasts) {
+		appendRow([]models.InlineKeyboardButton{
+			h.translation.WithButton(lang, "admin_broadcast", models.InlineKeyboardButton{CallbackData: CallbackAdminBroadcast}),
 		})
 	}
+
+	row = nil
+	if can(staff.PermStats) {
+		row = append(row, h.translation.WithButton(lang, "admin_stats", models.InlineKeyboardButton{CallbackData: CallbackAdminStatsRoot}))
+	}
+	if can(staff.PermInfra) {
+		row = append(row, h.translation.WithButton(lang, "admin_infra_billing", models.InlineKeyboardButton{CallbackData: CallbackAdminInfraRoot}))
+	}
+	appendRow(row)
+
+	row = nil
+	if can(staff.PermInfra) {
+		row = append(row, h.translation.WithButton(lang, "admin_sync", models.InlineKeyboardButton{CallbackData: CallbackAdminSync}))
+	}
+	if config.GiftsEnabled() && can(staff.PermPayments) {
+		row = append(row, h.translation.WithButton(lang, "admin_gifts", models.InlineKeyboardButton{CallbackData: CallbackAdminGifts}))
+	}
+	appendRow(row)
+
+	row = nil
+	if can(staff.PermPayments) {
+		if config.ReferralPartnerEnabled() {
+			row = append(row, h.translation.WithButton(lang, "admin_partner_payouts_button", models.InlineKeyboardButton{CallbackData: CallbackAdminPartnerPayouts}))
+		}
+		if config.IsMoynalogEnabled() {
+			row = append(row, h.translation.WithButton(lang, "admin_moynalog_receipts_button", models.InlineKeyboardButton{CallbackData: CallbackAdminMoynalogReceipts}))
+		}
+	}
+	appendRow(row)
+
 	kb = append(kb,
-		[]models.InlineKeyboardButton{
-			h.translation.WithButton(lang, "admin_broadcast", models.InlineKeyboardButton{CallbackData: CallbackAdminBroadcast}),
-		},
-		[]models.InlineKeyboardButton{
-			h.translation.WithButton(lang, "admin_stats", models.InlineKeyboardButton{CallbackData: CallbackAdminStatsRoot}),
-			h.translation.WithButton(lang, "admin_infra_billing", models.InlineKeyboardButton{CallbackData: CallbackAdminInfraRoot}),
-		},
-		[]models.InlineKeyboardButton{
-			h.translation.WithButton(lang, "admin_sync", models.InlineKeyboardButton{CallbackData: CallbackAdminSync}),
-		},
 		[]models.InlineKeyboardButton{
 			h.translation.WithButton(lang, "back_button", models.InlineKeyboardButton{CallbackData: CallbackStart}),
 		},
@@ -52,13 +88,13 @@ func (h Handler) RenderAdminPanel(ctx context.Context, b *bot.Bot, msg *models.M
 
 // AdminPanelHandler shows admin menu (broadcast, sync, promos).
 func (h Handler) AdminPanelHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.IsStaff(update.CallbackQuery.From.ID) {
 		return
 	}
 	cb := update.CallbackQuery
 	lang := cb.From.LanguageCode
 	msg := cb.Message.Message
-	if err := h.RenderAdminPanel(ctx, b, msg, lang); err != nil {
+	if err := h.RenderAdminPanel(ctx, b, msg, cb.From.ID, lang); err != nil {
 		slog.Error("admin panel edit", "error", err)
 	}
 	_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: cb.ID})
@@ -66,7 +102,7 @@ func (h Handler) AdminPanelHandler(ctx context.Context, b *bot.Bot, update *mode
 
 // AdminBroadcastShortcutHandler shows broadcast audience selection by editing the current message (same flow as /broadcast).
 func (h Handler) AdminBroadcastShortcutHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermBroadcasts) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -87,7 +123,7 @@ func (h Handler) AdminBroadcastShortcutHandler(ctx context.Context, b *bot.Bot,
 
 // AdminSyncShortcutHandler runs Remnawave sync (same as /sync).
 func (h Handler) AdminSyncShortcutHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermInfra) {
 		return
 	}
 	h.syncService.Sync()
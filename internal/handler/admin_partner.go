@@ -0,0 +1,250 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/audit"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/payment"
+	"remnawave-tg-shop-bot/internal/staff"
+)
+
+// Партнёрская программа в админке: admin_payouts — очередь запросов на выплату,
+// ppa{payoutId} / ppr{payoutId} — выплатить / отклонить, apt{customerId} — ставка реферера,
+// apu{customerId}_{percent} — назначить ставку (0 — отключить начисления).
+
+const adminPartnerPayoutsListSize = 10
+
+var adminPartnerPercentPresets = [][]int{{0, 5, 10, 15, 20}, {25, 30, 40, 50}}
+
+// AdminPartnerPayoutsHandler — необработанные запросы на выплату, старые первыми.
+func (h Handler) AdminPartnerPayoutsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermPayments) {
+		return
+	}
+	msg := update.CallbackQuery.Message.Message
+	if msg == nil {
+		return
+	}
+	h.renderAdminPartnerPayouts(ctx, b, msg, update.CallbackQuery.From.LanguageCode)
+}
+
+func (h Handler) renderAdminPartnerPayouts(ctx context.Context, b *bot.Bot, msg *models.Message, lang string) {
+	payouts, err := h.paymentService.PartnerPayouts(ctx, database.PayoutStatusPending, 0, adminPartnerPayoutsListSize, 0)
+	if err != nil {
+		slog.Error("admin partner payouts: list", "error", err)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(h.translation.GetText(lang, "admin_partner_payouts_title"))
+	sb.WriteString("\n\n")
+	if len(payouts) == 0 {
+		sb.WriteString(h.translation.GetText(lang, "admin_partner_payouts_empty"))
+	}
+	var kb [][]models.InlineKeyboardButton
+	for i := range payouts {
+		p := &payouts[i]
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "admin_partner_payouts_line"),
+			p.ID,
+			payment.FormatBalanceRub(p.Amount),
+			h.translation.GetText(lang, "admin_partner_payout_method_"+string(p.Method)),
+			p.PartnerCustomerID,
+			p.CreatedAt.Format("02.01.2006 15:04"),
+		))
+		if p.Details != nil && *p.Details != "" {
+			sb.WriteString("\n")
+			sb.WriteString(html.EscapeString(*p.Details))
+		}
+		sb.WriteString("\n\n")
+		kb = append(kb, []models.InlineKeyboardButton{
+			{Text: fmt.Sprintf("✅ #%d", p.ID), CallbackData: fmt.Sprintf("%s%d", CallbackAdminPayoutApprovePrefix, p.ID)},
+			{Text: fmt.Sprintf("❌ #%d", p.ID), CallbackData: fmt.Sprintf("%s%d", CallbackAdminPayoutRejectPrefix, p.ID)},
+		})
+	}
+	kb = append(kb, []models.InlineKeyboardButton{
+		h.translation.WithButton(lang, "back_button", models.InlineKeyboardButton{CallbackData: CallbackAdminPanel}),
+	})
+	_, err = editCallbackOriginToHTMLText(ctx, b, msg, sb.String(), models.ParseModeHTML, models.InlineKeyboardMarkup{InlineKeyboard: kb}, nil)
+	logEditError("admin partner payouts edit", err)
+}
+
+// AdminPayoutApproveHandler отмечает выплату проведённой (для method=balance — зачисляет на баланс).
+func (h Handler) AdminPayoutApproveHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	h.adminProcessPayout(ctx, b, update, CallbackAdminPayoutApprovePrefix, true)
+}
+
+// AdminPayoutRejectHandler отклоняет запрос: сумма снова доступна партнёру.
+func (h Handler) AdminPayoutRejectHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	h.adminProcessPayout(ctx, b, update, CallbackAdminPayoutRejectPrefix, false)
+}
+
+func (h Handler) adminProcessPayout(ctx context.Context, b *bot.Bot, update *models.Update, prefix string, approve bool) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermPayments) {
+		return
+	}
+	cb := update.CallbackQuery
+	id, ok := parsePurchaseIDFromPrefix(cb.Data, prefix)
+	if !ok {
+		return
+	}
+	msg := cb.Message.Message
+	if msg == nil {
+		return
+	}
+	lang := cb.From.LanguageCode
+	var (
+		p   *database.ReferralPayout
+		err error
+	)
+	action := audit.ActionPartnerPayoutApprove
+	if approve {
+		p, err = h.paymentService.ApprovePartnerPayout(ctx, id, "", cb.From.ID, 0)
+	} else {
+		action = audit.ActionPartnerPayoutReject
+		p, err = h.paymentService.RejectPartnerPayout(ctx, id, "", cb.From.ID, 0)
+	}
+	if err != nil {
+		key := "admin_user_action_error"
+		if errors.Is(err, database.ErrPayoutStatusConflict) || errors.Is(err, payment.ErrPayoutNotFound) {
+			key = "admin_partner_payout_conflict"
+		} else {
+			slog.Error("admin partner payout process", "error", err, "payout_id", id, "approve", approve)
+		}
+		_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: cb.ID,
+			Text:            h.translation.GetText(lang, key),
+			ShowAlert:       true,
+		})
+		h.renderAdminPartnerPayouts(ctx, b, msg, lang)
+		return
+	}
+	if p != nil {
+		audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+			Action: action, CustomerID: p.PartnerCustomerID,
+			TargetType: "partner_payout", TargetID: strconv.FormatInt(id, 10),
+			After: map[string]any{"status": p.Status, "amount": p.Amount, "method": p.Method},
+		})
+	}
+	key := "admin_partner_payout_rejected"
+	if approve {
+		key = "admin_partner_payout_approved"
+	}
+	_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: cb.ID,
+		Text:            fmt.Sprintf(h.translation.GetText(lang, key), id),
+	})
+	h.renderAdminPartnerPayouts(ctx, b, msg, lang)
+}
+
+// AdminUserPartnerMenuHandler — текущая ставка реферера и пресеты для назначения.
+func (h Handler) AdminUserPartnerMenuHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermPayments) {
+		return
+	}
+	cb := update.CallbackQuery
+	id, ok := parseCustomerIDFromPrefix(cb.Data, CallbackAdminUserPartnerMenuPrefix)
+	if !ok {
+		return
+	}
+	msg := cb.Message.Message
+	if msg == nil {
+		return
+	}
+	cust, err := h.customerRepository.FindById(ctx, id)
+	if err != nil || cust == nil {
+		return
+	}
+	h.renderAdminUserPartnerMenu(ctx, b, msg, cb.From.LanguageCode, cust.ID)
+}
+
+func (h Handler) renderAdminUserPartnerMenu(ctx context.Context, b *bot.Bot, msg *models.Message, lang string, customerID int64) {
+	personal, percent, err := h.paymentService.PartnerSettings(ctx, customerID)
+	if err != nil {
+		slog.Error("admin partner settings", "error", err)
+		return
+	}
+	source := h.translation.GetText(lang, "admin_user_partner_rate_default")
+	if personal != nil {
+		source = h.translation.GetText(lang, "admin_user_partner_rate_personal")
+	}
+	text := fmt.Sprintf(h.translation.GetText(lang, "admin_user_partner_rate_text"), payment.FormatPartnerPercent(percent), source)
+
+	var kb [][]models.InlineKeyboardButton
+	for _, presets := range adminPartnerPercentPresets {
+		var row []models.InlineKeyboardButton
+		for _, n := range presets {
+			row = append(row, models.InlineKeyboardButton{
+				Text:         strconv.Itoa(n) + "%",
+				CallbackData: fmt.Sprintf("%s%d_%d", CallbackAdminUserPartnerSetPrefix, customerID, n),
+			})
+		}
+		kb = append(kb, row)
+	}
+	kb = append(kb, []models.InlineKeyboardButton{
+		h.translation.WithButton(lang, "back_button", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s%d", CallbackAdminUserReferralsPrefix, customerID)}),
+	})
+	_, err = editCallbackOriginToHTMLText(ctx, b, msg, text, models.ParseModeHTML, models.InlineKeyboardMarkup{InlineKeyboard: kb}, nil)
+	logEditError("admin partner rate menu", err)
+}
+
+// AdminUserPartnerSetHandler назначает персональную ставку из пресета.
+func (h Handler) AdminUserPartnerSetHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermPayments) {
+		return
+	}
+	cb := update.CallbackQuery
+	if !strings.HasPrefix(cb.Data, CallbackAdminUserPartnerSetPrefix) {
+		return
+	}
+	rest := strings.TrimPrefix(cb.Data, CallbackAdminUserPartnerSetPrefix)
+	lastUnderscore := strings.LastIndex(rest, "_")
+	if lastUnderscore <= 0 || lastUnderscore >= len(rest)-1 {
+		return
+	}
+	cid, err := strconv.ParseInt(rest[:lastUnderscore], 10, 64)
+	if err != nil || cid <= 0 {
+		return
+	}
+	percent, err := strconv.Atoi(rest[lastUnderscore+1:])
+	if err != nil || percent < 0 || percent > 100 {
+		return
+	}
+	msg := cb.Message.Message
+	if msg == nil {
+		return
+	}
+	lang := cb.From.LanguageCode
+	cust, err := h.customerRepository.FindById(ctx, cid)
+	if err != nil || cust == nil {
+		return
+	}
+	p, err := h.paymentService.SetPartnerRate(ctx, cust.ID, float64(percent), percent > 0, "")
+	if err != nil {
+		slog.Error("admin partner rate set", "error", err)
+		_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: cb.ID,
+			Text:            h.translation.GetText(lang, "admin_user_action_error"),
+			ShowAlert:       true,
+		})
+		return
+	}
+	audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+		Action: audit.ActionPartnerUpdate, CustomerID: cust.ID,
+		After: map[string]any{"commission_percent": p.CommissionPercent, "enabled": p.Enabled},
+	})
+	_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: cb.ID,
+		Text:            h.translation.GetText(lang, "admin_user_partner_rate_saved"),
+	})
+	h.renderAdminUserPartnerMenu(ctx, b, msg, lang, cust.ID)
+}
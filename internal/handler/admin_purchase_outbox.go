@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/audit"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/staff"
+)
+
+// AdminPurchaseOutboxRetryHandler — «повторить» из уведомления об остановившемся проведении покупки:
+// failed-шаги возвращаются в очередь и сразу выполняются ещё раз.
+func (h Handler) AdminPurchaseOutboxRetryHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermPayments) {
+		return
+	}
+	cb := update.CallbackQuery
+	purchaseID, ok := parsePurchaseIDFromPrefix(cb.Data, CallbackAdminPurchaseOutboxRetryPrefix)
+	if !ok {
+		return
+	}
+	lang := cb.From.LanguageCode
+	err := h.paymentService.RetryPurchaseOutbox(ctx, purchaseID)
+	var text string
+	switch {
+	case errors.Is(err, database.ErrPurchaseOutboxNotRetryable):
+		text = h.translation.GetText(lang, "admin_purchase_outbox_retry_not_failed")
+	case err != nil:
+		slog.Error("admin purchase outbox retry", "error", err, "purchase_id", purchaseID)
+		text = h.translation.GetText(lang, "admin_user_action_error")
+	default:
+		audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+			Action: audit.ActionPurchaseOutboxRetry, TargetType: "purchase", TargetID: strconv.FormatInt(purchaseID, 10),
+		})
+		text = fmt.Sprintf(h.translation.GetText(lang, "admin_purchase_outbox_retry_result"), purchaseID)
+	}
+	_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: cb.ID,
+		Text:            text,
+		ShowAlert:       true,
+	})
+}
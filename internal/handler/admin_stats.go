@@ -14,6 +14,7 @@ import (
 	"github.com/go-telegram/bot/models"
 
 	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/staff"
 )
 
 func (h Handler) adminStatsKeyboard(lang, backCallback string) [][]models.InlineKeyboardButton {
@@ -39,6 +40,7 @@ func (h Handler) adminStatsRootKeyboard(lang string) [][]models.InlineKeyboardBu
 		},
 		{
 			h.translation.WithButton(lang, "admin_stats_btn_fortune", models.InlineKeyboardButton{CallbackData: CallbackAdminStatsFortune}),
+			h.translation.WithButton(lang, "admin_stats_btn_sources", models.InlineKeyboardButton{CallbackData: CallbackAdminStatsSources}),
 		},
 		{
 			h.translation.WithButton(lang, "admin_stats_btn_summary", models.InlineKeyboardButton{CallbackData: CallbackAdminStatsSummary}),
@@ -76,7 +78,7 @@ func growthPct(cur, prev int64) string {
 
 // AdminStatsRootHandler корень меню «Статистика».
 func (h Handler) AdminStatsRootHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermStats) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -94,7 +96,7 @@ func (h Handler) AdminStatsRootHandler(ctx context.Context, b *bot.Bot, update *
 
 // AdminStatsUsersHandler экран «Пользователи».
 func (h Handler) AdminStatsUsersHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermStats) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -149,7 +151,7 @@ func (h Handler) AdminStatsUsersHandler(ctx context.Context, b *bot.Bot, update
 
 // AdminStatsSubsHandler экран «Подписки».
 func (h Handler) AdminStatsSubsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermStats) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -210,7 +212,7 @@ func rubStr(v float64) string {
 
 // AdminStatsRevenueHandler экран «Доходы».
 func (h Handler) AdminStatsRevenueHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermStats) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -229,6 +231,7 @@ func (h Handler) AdminStatsRevenueHandler(ctx context.Context, b *bot.Bot, updat
 		fmt.Sprintf(h.translation.GetText(lang, "admin_stats_rev_line_rub"), rubStr(snap.RevenueMonthRub)),
 		fmt.Sprintf(h.translation.GetText(lang, "admin_stats_rev_from_subs"), rubStr(snap.RevenueSubsMonthRub)),
 	}
+	lines = append(lines, h.revenueCurrencyLines(lang, snap.RevenueMonthByCurrency, snap.RevenueMonthNormalizedRub)...)
 	if config.SalesMode() == "tariffs" && len(snap.TariffBreakdown) > 0 {
 		lines = append(lines, h.translation.GetText(lang, "admin_stats_rev_tariffs_split_header"))
 		for _, t := range snap.TariffBreakdown {
@@ -254,6 +257,7 @@ func (h Handler) AdminStatsRevenueHandler(ctx context.Context, b *bot.Bot, updat
 		h.translation.GetText(lang, "admin_stats_rev_all_header"),
 		fmt.Sprintf(h.translation.GetText(lang, "admin_stats_rev_line_rub"), rubStr(snap.RevenueAllTimeRub)),
 	)
+	lines = append(lines, h.revenueCurrencyLines(lang, snap.RevenueAllTimeByCurrency, snap.RevenueAllTimeNormalizedRub)...)
 	if config.SalesMode() == "tariffs" && len(snap.TariffBreakdown) > 0 {
 		lines = append(lines, h.translation.GetText(lang, "admin_stats_rev_tariffs_split_header"))
 		for _, t := range snap.TariffBreakdown {
@@ -292,7 +296,7 @@ func (h Handler) AdminStatsRevenueHandler(ctx context.Context, b *bot.Bot, updat
 
 // AdminStatsRefHandler экран «Реферальная статистика».
 func (h Handler) AdminStatsRefHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermStats) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -344,7 +348,7 @@ func (h Handler) AdminStatsRefHandler(ctx context.Context, b *bot.Bot, update *m
 
 // AdminStatsSummaryHandler общая сводка.
 func (h Handler) AdminStatsSummaryHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermStats) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -450,7 +454,7 @@ func fortuneDetailDiscountLine(by map[string]int64, dash string) string {
 
 // AdminStatsFortuneHandler экран «Колесо фортуны» (лог fortune_spins).
 func (h Handler) AdminStatsFortuneHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermStats) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -510,3 +514,69 @@ func (h Handler) AdminStatsFortuneHandler(ctx context.Context, b *bot.Bot, updat
 		slog.Error("admin stats fortune edit", "error", err)
 	}
 }
+
+// Экран «Источники»: клиенты, пришедшие за последние adminStatsSourcesDays дней, не больше adminStatsSourcesLimit строк.
+const (
+	adminStatsSourcesDays  = 30
+	adminStatsSourcesLimit = 15
+)
+
+// AdminStatsSourcesHandler экран «Источники привлечения»: воронка по меткам src_ / utm_*.
+func (h Handler) AdminStatsSourcesHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermStats) {
+		return
+	}
+	cb := update.CallbackQuery
+	lang := cb.From.LanguageCode
+	msg := cb.Message.Message
+	if msg == nil || h.statsRepository == nil {
+		return
+	}
+	now := time.Now().UTC()
+	from := now.AddDate(0, 0, -adminStatsSourcesDays)
+	list, err := h.statsRepository.FetchAcquisitionFunnel(ctx, &from, nil)
+	if err != nil {
+		slog.Error("admin stats sources fetch", "error", err)
+		return
+	}
+	lines := []string{fmt.Sprintf(h.translation.GetText(lang, "admin_stats_sources_title"), adminStatsSourcesDays), ""}
+	if len(list) == 0 {
+		lines = append(lines, h.translation.GetText(lang, "admin_stats_sources_empty"))
+	}
+	for i, st := range list {
+		if i == adminStatsSourcesLimit {
+			lines = append(lines, fmt.Sprintf(h.translation.GetText(lang, "admin_stats_sources_more"), len(list)-i))
+			break
+		}
+		label := html.EscapeString(st.Source)
+		if st.Source == "" {
+			label = h.translation.GetText(lang, "admin_stats_sources_untagged")
+		}
+		lines = append(lines, fmt.Sprintf(h.translation.GetText(lang, "admin_stats_sources_line"),
+			label, st.Starts, st.Trials, pctStr(st.Trials, st.Starts), st.Paid, pctStr(st.Paid, st.Starts), rubStr(st.RevenueRub)))
+	}
+	text := strings.Join(lines, "\n") + "\n\n" + h.formatStatsUpdated(lang, now)
+	_, err = editCallbackOriginToHTMLText(ctx, b, msg, text, models.ParseModeHTML, models.InlineKeyboardMarkup{InlineKeyboard: h.adminStatsKeyboard(lang, CallbackAdminStatsSources)}, nil)
+	if err != nil {
+		slog.Error("admin stats sources edit", "error", err)
+	}
+}
+
+// revenueCurrencyLines — выручка по валютам и итог в рублях по курсам; пусто, если платили только в рублях.
+func (h Handler) revenueCurrencyLines(lang string, byCurrency map[string]float64, normalizedRub float64) []string {
+	keys := make([]string, 0, len(byCurrency))
+	for k := range byCurrency {
+		if k != config.CurrencyRUB {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+	lines := []string{h.translation.GetText(lang, "admin_stats_rev_currencies_header")}
+	for _, k := range keys {
+		lines = append(lines, fmt.Sprintf(h.translation.GetText(lang, "admin_stats_rev_currency_line"), k, rubStr(byCurrency[k])))
+	}
+	return append(lines, fmt.Sprintf(h.translation.GetText(lang, "admin_stats_rev_normalized_rub"), rubStr(normalizedRub)))
+}
@@ -10,9 +10,10 @@ import (
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
-	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/audit"
 	"remnawave-tg-shop-bot/internal/database"
 	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/internal/staff"
 )
 
 // Инлайн-календарь для выбора даты окончания подписки (PATCH expireAt в Remnawave).
@@ -242,7 +243,8 @@ func expireAtEndOfDayUTC(t time.Time) time.Time {
 	return time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 59, 0, time.UTC)
 }
 
-func (h Handler) adminApplyPanelExpireAt(ctx context.Context, cust *database.Customer, exp time.Time) (*remnawave.User, error) {
+// adminApplyPanelExpireAt ставит дату окончания в панели и БД; adminID — сотрудник для журнала действий.
+func (h Handler) adminApplyPanelExpireAt(ctx context.Context, adminID int64, cust *database.Customer, exp time.Time) (*remnawave.User, error) {
 	rwUser, err := h.adminFindRWUserByCustomer(ctx, cust)
 	if err != nil || rwUser == nil {
 		return nil, fmt.Errorf("no rw user")
@@ -262,6 +264,10 @@ func (h Handler) adminApplyPanelExpireAt(ctx context.Context, cust *database.Cus
 	}); err != nil {
 		return nil, err
 	}
+	audit.Record(ctx, audit.Bot(adminID), audit.Entry{
+		Action: audit.ActionUserExpireSet, CustomerID: cust.ID,
+		Before: audit.UserState(rwUser), After: audit.UserState(out),
+	})
 	return out, nil
 }
 
@@ -308,7 +314,7 @@ func parseCalPick(data string) (cid int64, yyyymmdd int, ok bool) {
 }
 
 func (h Handler) AdminUserCalOpenHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -341,7 +347,7 @@ func (h Handler) AdminUserCalOpenHandler(ctx context.Context, b *bot.Bot, update
 }
 
 func (h Handler) AdminUserCalNavHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -374,7 +380,7 @@ func (h Handler) AdminUserCalNavHandler(ctx context.Context, b *bot.Bot, update
 }
 
 func (h Handler) AdminUserCalPickHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -400,7 +406,7 @@ func (h Handler) AdminUserCalPickHandler(ctx context.Context, b *bot.Bot, update
 	now := time.Now().UTC()
 	exp := time.Date(y, time.Month(mo), d, now.Hour(), now.Minute(), now.Second(), 0, time.UTC)
 
-	if _, err := h.adminApplyPanelExpireAt(ctx, cust, exp); err != nil {
+	if _, err := h.adminApplyPanelExpireAt(ctx, cb.From.ID, cust, exp); err != nil {
 		slog.Error("admin cal patch expire", "error", err)
 		alertKey := "admin_user_action_error"
 		if err.Error() == "no rw user" {
@@ -427,7 +433,7 @@ func (h Handler) AdminUserCalPickHandler(ctx context.Context, b *bot.Bot, update
 }
 
 func (h Handler) AdminUserCalBlankHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -460,7 +466,7 @@ func (h Handler) AdminUserCalBlankHandler(ctx context.Context, b *bot.Bot, updat
 }
 
 func (h Handler) AdminUserCalManualHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -495,7 +501,7 @@ func (h Handler) AdminUserExpireDateTextHandler(ctx context.Context, b *bot.Bot,
 		return
 	}
 	adminID := update.Message.From.ID
-	if adminID != config.GetAdminTelegramId() || update.Message.ReplyToMessage != nil {
+	if !staff.Can(adminID, staff.PermUsersWrite) || update.Message.ReplyToMessage != nil {
 		return
 	}
 	cid, ok := adminExpireDateCustomer(adminID)
@@ -522,7 +528,7 @@ func (h Handler) AdminUserExpireDateTextHandler(ctx context.Context, b *bot.Bot,
 		})
 		return
 	}
-	if _, err := h.adminApplyPanelExpireAt(ctx, cust, exp); err != nil {
+	if _, err := h.adminApplyPanelExpireAt(ctx, adminID, cust, exp); err != nil {
 		slog.Error("admin cal manual patch expire", "error", err)
 		alertKey := "admin_user_action_error"
 		if err.Error() == "no rw user" {
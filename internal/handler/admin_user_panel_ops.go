@@ -12,10 +12,12 @@ import (
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"github.com/google/uuid"
+	"remnawave-tg-shop-bot/internal/audit"
 	"remnawave-tg-shop-bot/internal/config"
 	"remnawave-tg-shop-bot/internal/database"
 	"remnawave-tg-shop-bot/internal/payment"
 	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/internal/staff"
 )
 
 var adminPanelStrategies = []string{"DAY", "WEEK", "MONTH", "MONTH_ROLLING", "NO_RESET"}
@@ -72,7 +74,7 @@ func (h Handler) adminSyncCustomerAfterPatch(ctx context.Context, custID int64,
 // --- Корень расширенных настроек Remnawave ------------------------------------
 
 func (h Handler) AdminUserPanelMenuHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -161,7 +163,7 @@ func (h Handler) renderAdminSquadMenu(ctx context.Context, b *bot.Bot, msg *mode
 }
 
 func (h Handler) AdminUserSquadMenuHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -181,7 +183,7 @@ func (h Handler) AdminUserSquadMenuHandler(ctx context.Context, b *bot.Bot, upda
 }
 
 func (h Handler) AdminUserSquadPickHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -262,6 +264,10 @@ func (h Handler) adminUserSquadToggle(ctx context.Context, b *bot.Bot, cb *model
 	if err := h.adminSyncCustomerAfterPatch(ctx, cust.ID, out); err != nil {
 		slog.Error("admin squad sync db", "error", err)
 	}
+	audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+		Action: audit.ActionUserSquads, CustomerID: cust.ID,
+		Before: audit.UserState(rw), After: audit.UserState(out),
+	})
 	lang := cb.From.LanguageCode
 	if err := h.renderAdminSquadMenu(ctx, b, msg, cid, lang); err != nil {
 		slog.Error("admin squad refresh menu", "error", err)
@@ -287,7 +293,7 @@ func (h Handler) adminRwUserByCustomerID(ctx context.Context, cid int64) (*datab
 // Стратегия --------------------------------------------------------------------
 
 func (h Handler) AdminUserStrategyMenuHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -340,7 +346,7 @@ func (h Handler) AdminUserStrategyMenuHandler(ctx context.Context, b *bot.Bot, u
 }
 
 func (h Handler) AdminUserStrategySetHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -391,6 +397,10 @@ func (h Handler) adminUserStrategyApply(ctx context.Context, b *bot.Bot, cb *mod
 	if err := h.adminSyncCustomerAfterPatch(ctx, cust.ID, out); err != nil {
 		slog.Error("admin strategy sync", "error", err)
 	}
+	audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+		Action: audit.ActionUserStrategy, CustomerID: cust.ID,
+		Before: audit.UserState(rw), After: audit.UserState(out),
+	})
 	cust, _ = h.customerRepository.FindById(ctx, cid)
 	lang := cb.From.LanguageCode
 	text, markup := h.adminUserManageContent(ctx, b, lang, cust)
@@ -407,7 +417,7 @@ func (h Handler) adminUserStrategyApply(ctx context.Context, b *bot.Bot, cb *mod
 // Лимит трафика ----------------------------------------------------------------
 
 func (h Handler) AdminUserTrafficMenuHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -467,7 +477,7 @@ func (h Handler) AdminUserTrafficMenuHandler(ctx context.Context, b *bot.Bot, up
 }
 
 func (h Handler) AdminUserTrafficSetHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -519,6 +529,10 @@ func (h Handler) adminUserTrafficApply(ctx context.Context, b *bot.Bot, cb *mode
 	if err := h.adminSyncCustomerAfterPatch(ctx, cust.ID, out); err != nil {
 		slog.Error("admin traffic sync", "error", err)
 	}
+	audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+		Action: audit.ActionUserTrafficLimit, CustomerID: cust.ID,
+		Before: audit.UserState(rw), After: audit.UserState(out),
+	})
 	cust, _ = h.customerRepository.FindById(ctx, cid)
 	lang := cb.From.LanguageCode
 	text, markup := h.adminUserManageContent(ctx, b, lang, cust)
@@ -535,7 +549,7 @@ func (h Handler) adminUserTrafficApply(ctx context.Context, b *bot.Bot, cb *mode
 // Отключить --------------------------------------------------------------------
 
 func (h Handler) AdminUserDisableAskHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -567,7 +581,7 @@ func (h Handler) AdminUserDisableAskHandler(ctx context.Context, b *bot.Bot, upd
 }
 
 func (h Handler) AdminUserDisableConfirmHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -605,6 +619,10 @@ func (h Handler) AdminUserDisableConfirmHandler(ctx context.Context, b *bot.Bot,
 	if err := h.adminSyncCustomerAfterPatch(ctx, cust.ID, out); err != nil {
 		slog.Error("admin disable sync", "error", err)
 	}
+	audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+		Action: audit.ActionUserDisable, CustomerID: cust.ID,
+		Before: audit.UserState(rw), After: audit.UserState(out),
+	})
 	cust, _ = h.customerRepository.FindById(ctx, cid)
 	lang := cb.From.LanguageCode
 	text, markup := h.adminUserManageContent(ctx, b, lang, cust)
@@ -619,7 +637,7 @@ func (h Handler) AdminUserDisableConfirmHandler(ctx context.Context, b *bot.Bot,
 }
 
 func (h Handler) AdminUserEnableAskHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -651,7 +669,7 @@ func (h Handler) AdminUserEnableAskHandler(ctx context.Context, b *bot.Bot, upda
 }
 
 func (h Handler) AdminUserEnableConfirmHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -689,6 +707,10 @@ func (h Handler) AdminUserEnableConfirmHandler(ctx context.Context, b *bot.Bot,
 	if err := h.adminSyncCustomerAfterPatch(ctx, cust.ID, out); err != nil {
 		slog.Error("admin enable sync", "error", err)
 	}
+	audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+		Action: audit.ActionUserEnable, CustomerID: cust.ID,
+		Before: audit.UserState(rw), After: audit.UserState(out),
+	})
 	cust, _ = h.customerRepository.FindById(ctx, cid)
 	lang := cb.From.LanguageCode
 	text, markup := h.adminUserManageContent(ctx, b, lang, cust)
@@ -705,7 +727,7 @@ func (h Handler) AdminUserEnableConfirmHandler(ctx context.Context, b *bot.Bot,
 // Удалить ----------------------------------------------------------------------
 
 func (h Handler) AdminUserDeleteAskHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -737,7 +759,7 @@ func (h Handler) AdminUserDeleteAskHandler(ctx context.Context, b *bot.Bot, upda
 }
 
 func (h Handler) AdminUserDeleteConfirmHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -779,6 +801,9 @@ func (h Handler) AdminUserDeleteConfirmHandler(ctx context.Context, b *bot.Bot,
 		})
 		return
 	}
+	audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+		Action: audit.ActionUserDelete, CustomerID: cust.ID, Before: audit.CustomerState(cust),
+	})
 	lang := cb.From.LanguageCode
 	text := h.translation.GetText(lang, "admin_user_delete_done")
 	kb := [][]models.InlineKeyboardButton{
@@ -796,7 +821,7 @@ func (h Handler) AdminUserDeleteConfirmHandler(ctx context.Context, b *bot.Bot,
 }
 
 func (h Handler) AdminUserTrafficCustomAskHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -833,7 +858,7 @@ func (h Handler) AdminUserTrafficLimitTextHandler(ctx context.Context, b *bot.Bo
 		return
 	}
 	adminID := update.Message.From.ID
-	if adminID != config.GetAdminTelegramId() || update.Message.ReplyToMessage != nil {
+	if !staff.Can(adminID, staff.PermUsersWrite) || update.Message.ReplyToMessage != nil {
 		return
 	}
 	cid, ok := adminTrafficLimitCustomer(adminID)
@@ -885,6 +910,10 @@ func (h Handler) AdminUserTrafficLimitTextHandler(ctx context.Context, b *bot.Bo
 	if err := h.adminSyncCustomerAfterPatch(ctx, cust.ID, out); err != nil {
 		slog.Error("admin traffic custom sync", "error", err)
 	}
+	audit.Record(ctx, audit.Bot(adminID), audit.Entry{
+		Action: audit.ActionUserTrafficLimit, CustomerID: cust.ID,
+		Before: audit.UserState(rw), After: audit.UserState(out),
+	})
 	adminTrafficLimitClear(adminID)
 	cust, _ = h.customerRepository.FindById(ctx, cid)
 	text, markup := h.adminUserManageContent(ctx, b, lang, cust)
@@ -978,6 +1007,10 @@ func (h Handler) adminUserExtraHwidApplyDelta(ctx context.Context, b *bot.Bot, c
 		_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: cb.ID, Text: h.translation.GetText(lang, "admin_user_action_error"), ShowAlert: true})
 		return
 	}
+	audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+		Action: audit.ActionUserExtraHwid, CustomerID: cust.ID,
+		Before: map[string]any{"extra_hwid": cust.ExtraHwid}, After: map[string]any{"extra_hwid": newExtra},
+	})
 	if err := h.adminRefreshSubscriptionMessage(ctx, b, msg, lang, custID); err != nil {
 		slog.Error("admin extra hwid refresh", "error", err)
 	}
@@ -985,7 +1018,7 @@ func (h Handler) adminUserExtraHwidApplyDelta(ctx context.Context, b *bot.Bot, c
 }
 
 func (h Handler) AdminUserExtraHwidDecHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -997,7 +1030,7 @@ func (h Handler) AdminUserExtraHwidDecHandler(ctx context.Context, b *bot.Bot, u
 }
 
 func (h Handler) AdminUserExtraHwidIncHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1009,7 +1042,7 @@ func (h Handler) AdminUserExtraHwidIncHandler(ctx context.Context, b *bot.Bot, u
 }
 
 func (h Handler) AdminUserTariffMenuHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) || h.tariffRepository == nil {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) || h.tariffRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1062,7 +1095,7 @@ func (h Handler) AdminUserTariffMenuHandler(ctx context.Context, b *bot.Bot, upd
 }
 
 func (h Handler) AdminUserTariffPickHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) || h.tariffRepository == nil {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) || h.tariffRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1085,6 +1118,7 @@ func (h Handler) AdminUserTariffPickHandler(ctx context.Context, b *bot.Bot, upd
 	if err != nil || cust == nil {
 		return
 	}
+	before := audit.CustomerState(cust)
 	profile := payment.BuildRemnawaveTariffProfile(tariff)
 	out, err := h.remnawaveClient.CreateOrUpdateUserWithTariffProfile(ctx, cust.ID, cust.TelegramID, 0, profile)
 	if err != nil {
@@ -1116,6 +1150,9 @@ func (h Handler) AdminUserTariffPickHandler(ctx context.Context, b *bot.Bot, upd
 			}
 		}
 	}
+	audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+		Action: audit.ActionUserTariff, CustomerID: cid, Before: before, After: audit.CustomerState(cust),
+	})
 	if err := h.adminRefreshSubscriptionMessage(ctx, b, msg, lang, cid); err != nil {
 		slog.Error("admin tariff refresh", "error", err)
 	}
@@ -1123,7 +1160,7 @@ func (h Handler) AdminUserTariffPickHandler(ctx context.Context, b *bot.Bot, upd
 }
 
 func (h Handler) AdminUserDescriptionAskHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1159,7 +1196,7 @@ func (h Handler) AdminUserDescriptionAskHandler(ctx context.Context, b *bot.Bot,
 }
 
 func (h Handler) AdminUserDescriptionClearHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1189,6 +1226,10 @@ func (h Handler) AdminUserDescriptionClearHandler(ctx context.Context, b *bot.Bo
 		_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: cb.ID, Text: h.translation.GetText(lang, "admin_user_action_error"), ShowAlert: true})
 		return
 	}
+	audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+		Action: audit.ActionUserDescription, CustomerID: cid,
+		Before: map[string]any{"description": rw.Description}, After: map[string]any{"description": empty},
+	})
 	if err := h.adminRefreshSubscriptionMessage(ctx, b, msg, lang, cid); err != nil {
 		slog.Error("admin desc clear refresh", "error", err)
 	}
@@ -1200,7 +1241,7 @@ func (h Handler) AdminUserDescriptionTextHandler(ctx context.Context, b *bot.Bot
 		return
 	}
 	adminID := update.Message.From.ID
-	if adminID != config.GetAdminTelegramId() || update.Message.ReplyToMessage != nil {
+	if !staff.Can(adminID, staff.PermUsersWrite) || update.Message.ReplyToMessage != nil {
 		return
 	}
 	cid, ok := adminUserDescriptionCustomer(adminID)
@@ -1244,6 +1285,10 @@ func (h Handler) AdminUserDescriptionTextHandler(ctx context.Context, b *bot.Bot
 		return
 	}
 	adminUserDescriptionClear(adminID)
+	audit.Record(ctx, audit.Bot(adminID), audit.Entry{
+		Action: audit.ActionUserDescription, CustomerID: cid,
+		Before: map[string]any{"description": rw.Description}, After: map[string]any{"description": desc},
+	})
 	cust, err := h.customerRepository.FindById(ctx, cid)
 	if err != nil || cust == nil {
 		return
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"remnawave-tg-shop-bot/internal/audit"
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/payment"
+	"remnawave-tg-shop-bot/internal/staff"
+)
+
+// Возврат оплаты: rfq{purchaseId} — подтверждение, rfc{purchaseId} — выполнить (полный остаток),
+// rfb{purchaseId} — зачислить остаток на внутренний баланс клиента вместо возврата через провайдера.
+// Частичный возврат — только из кабинета (POST /cabinet/api/admin/purchases/{id}/refund с amount).
+
+func parsePurchaseIDFromPrefix(data, prefix string) (int64, bool) {
+	if !strings.HasPrefix(data, prefix) {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(data, prefix), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+func (h Handler) adminRefundBackButton(lang string, customerID int64) models.InlineKeyboardButton {
+	return h.translation.WithButton(lang, "back_button", models.InlineKeyboardButton{
+		CallbackData: fmt.Sprintf("%s%dp%d", CallbackAdminUserPaymentsPrefix, customerID, 1),
+	})
+}
+
+func (h Handler) AdminUserRefundAskHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermPayments) {
+		return
+	}
+	cb := update.CallbackQuery
+	pid, ok := parsePurchaseIDFromPrefix(cb.Data, CallbackAdminRefundAskPrefix)
+	if !ok {
+		return
+	}
+	msg := cb.Message.Message
+	if msg == nil {
+		return
+	}
+	lang := cb.From.LanguageCode
+	p, err := h.purchaseRepository.FindById(ctx, pid)
+	if err != nil || p == nil {
+		_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: cb.ID,
+			Text:            h.translation.GetText(lang, "admin_user_action_error"),
+			ShowAlert:       true,
+		})
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "admin_refund_confirm_text"),
+		p.ID,
+		formatAmount(database.RefundableRemainder(p), p.Currency),
+		purchaseInvoiceLabel(lang, p.InvoiceType),
+	))
+	if !h.paymentService.RefundSupported(p.InvoiceType) {
+		sb.WriteString("\n\n")
+		sb.WriteString(h.translation.GetText(lang, "admin_refund_manual_warning"))
+	}
+	kb := [][]models.InlineKeyboardButton{
+		{
+			h.translation.WithButton(lang, "admin_refund_confirm_yes", models.InlineKeyboardButton{
+				CallbackData: fmt.Sprintf("%s%d", CallbackAdminRefundConfirmPrefix, p.ID),
+			}),
+			h.adminRefundBackButton(lang, p.CustomerID),
+		},
+	}
+	if config.BalanceEnabled() && p.PurchaseKind != database.PurchaseKindBalanceTopUp && p.InvoiceType != database.InvoiceTypeTelegram {
+		kb = append(kb, []models.InlineKeyboardButton{
+			h.translation.WithButton(lang, "admin_refund_to_balance", models.InlineKeyboardButton{
+				CallbackData: fmt.Sprintf("%s%d", CallbackAdminRefundBalancePrefix, p.ID),
+			}),
+		})
+	}
+	_, err = editCallbackOriginToHTMLText(ctx, b, msg, sb.String(), models.ParseModeHTML, models.InlineKeyboardMarkup{InlineKeyboard: kb}, nil)
+	if err != nil {
+		slog.Error("admin refund ask", "error", err)
+	}
+	_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: cb.ID})
+}
+
+func (h Handler) AdminUserRefundConfirmHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermPayments) {
+		return
+	}
+	cb := update.CallbackQuery
+	toBalance := false
+	pid, ok := parsePurchaseIDFromPrefix(cb.Data, CallbackAdminRefundConfirmPrefix)
+	if !ok {
+		pid, ok = parsePurchaseIDFromPrefix(cb.Data, CallbackAdminRefundBalancePrefix)
+		toBalance = true
+	}
+	if !ok {
+		return
+	}
+	msg := cb.Message.Message
+	if msg == nil {
+		return
+	}
+	lang := cb.From.LanguageCode
+	res, err := h.paymentService.RefundPurchase(ctx, payment.RefundRequest{
+		PurchaseID:      pid,
+		Source:          database.RefundSourceBot,
+		AdminTelegramID: cb.From.ID,
+		ToBalance:       toBalance,
+	})
+	if err != nil {
+		slog.Error("admin refund", "error", err, "purchase_id", pid)
+		_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+			CallbackQueryID: cb.ID,
+			Text:            h.translation.GetText(lang, adminRefundErrorKey(err)),
+			ShowAlert:       true,
+		})
+		return
+	}
+
+	r := res.Refund
+	audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+		Action: audit.ActionUserRefund, CustomerID: r.CustomerID,
+		TargetType: "purchase", TargetID: strconv.FormatInt(pid, 10),
+		After: map[string]any{
+			"amount": r.Amount, "currency": r.Currency, "to_balance": toBalance,
+			"days_revoked": r.DaysRevoked, "hwid_revoked": r.HwidRevoked, "xp_revoked": r.XPRevoked,
+			"provider_refunded": res.ProviderRefunded,
+		},
+	})
+	text := fmt.Sprintf(h.translation.GetText(lang, "admin_refund_done_text"),
+		res.Purchase.ID,
+		formatAmount(r.Amount, res.Purchase.Currency),
+		r.DaysRevoked, r.HwidRevoked, r.XPRevoked,
+	)
+	if r.PromoRestored {
+		text += "\n" + h.translation.GetText(lang, "admin_refund_done_promo")
+	}
+	if toBalance {
+		text += "\n" + h.translation.GetText(lang, "admin_refund_done_balance")
+	} else if !res.ProviderRefunded {
+		text += "\n\n" + h.translation.GetText(lang, "admin_refund_manual_warning")
+	}
+	kb := [][]models.InlineKeyboardButton{{h.adminRefundBackButton(lang, res.Purchase.CustomerID)}}
+	_, err = editCallbackOriginToHTMLText(ctx, b, msg, text, models.ParseModeHTML, models.InlineKeyboardMarkup{InlineKeyboard: kb}, nil)
+	if err != nil {
+		slog.Error("admin refund done edit", "error", err)
+	}
+	_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: cb.ID})
+}
+
+func adminRefundErrorKey(err error) string {
+	switch {
+	case errors.Is(err, payment.ErrRefundNotAllowed), errors.Is(err, payment.ErrRefundPurchaseNotFound):
+		return "admin_refund_err_not_allowed"
+	case errors.Is(err, payment.ErrRefundPartialUnsupported):
+		return "admin_refund_err_partial"
+	case errors.Is(err, database.ErrRefundInProgress):
+		return "admin_refund_err_in_progress"
+	case errors.Is(err, payment.ErrRefundProviderFailed):
+		return "admin_refund_err_provider"
+	case errors.Is(err, payment.ErrBalanceTopUpSpent):
+		return "admin_refund_err_topup_spent"
+	case errors.Is(err, payment.ErrRefundToBalanceUnsupported):
+		return "admin_refund_err_to_balance"
+	default:
+		return "admin_user_action_error"
+	}
+}
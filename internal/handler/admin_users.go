@@ -12,9 +12,12 @@ import (
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"remnawave-tg-shop-bot/internal/audit"
 	"remnawave-tg-shop-bot/internal/config"
 	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/payment"
 	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/internal/staff"
 	"remnawave-tg-shop-bot/internal/translation"
 	"remnawave-tg-shop-bot/utils"
 )
@@ -205,14 +208,15 @@ func adminUserDescriptionCustomer(adminID int64) (int64, bool) {
 	return cid, ok
 }
 
-func isAdmin(cb *models.CallbackQuery) bool {
-	return cb != nil && cb.From.ID == config.GetAdminTelegramId()
+// isAdmin — callback от сотрудника с правом perm (ADMIN_TELEGRAM_ID проходит всегда).
+func isAdmin(cb *models.CallbackQuery, perm staff.Permission) bool {
+	return cb != nil && staff.Can(cb.From.ID, perm)
 }
 
 // --- Подменю «Юзеры и подписки» -------------------------------------------------
 
 func (h Handler) AdminUsersSubmenuHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersRead) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -237,7 +241,7 @@ func (h Handler) AdminUsersSubmenuHandler(ctx context.Context, b *bot.Bot, updat
 // --- Корень «Пользователи» ------------------------------------------------------
 
 func (h Handler) AdminUsersRootHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersRead) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -411,7 +415,7 @@ func adminUsersPagePickerMarkup(lang string, h Handler, mode string, chunk, retu
 
 // AdminUsersListPagePickerOpenHandler — сетка выбора страницы по нажатию «N/M» в списке клиентов.
 func (h Handler) AdminUsersListPagePickerOpenHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersRead) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -474,7 +478,7 @@ func (h Handler) AdminUsersListPagePickerOpenHandler(ctx context.Context, b *bot
 
 // AdminUsersListPagePickerJumpHandler — переход на выбранную страницу из сетки.
 func (h Handler) AdminUsersListPagePickerJumpHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersRead) {
 		return
 	}
 	data := update.CallbackQuery.Data
@@ -498,7 +502,7 @@ func (h Handler) AdminUsersListPagePickerJumpHandler(ctx context.Context, b *bot
 }
 
 func (h Handler) adminUsersListPage(ctx context.Context, b *bot.Bot, update *models.Update, scope database.CustomerListScope, page int, titleKeyOverride string, listParentBack string) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersRead) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -740,7 +744,7 @@ func adminUserListButtonLabel(h Handler, lang string, c *database.Customer, now
 // --- Статистика в разделе пользователей -----------------------------------------
 
 func (h Handler) AdminUsersStatsSectionHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersRead) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -785,7 +789,7 @@ func (h Handler) AdminUsersStatsSectionHandler(ctx context.Context, b *bot.Bot,
 // --- Поиск по Telegram ID -------------------------------------------------------
 
 func (h Handler) AdminUsersSearchHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersRead) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -815,7 +819,7 @@ func (h Handler) AdminUsersSearchMessageHandler(ctx context.Context, b *bot.Bot,
 		return
 	}
 	adminID := update.Message.From.ID
-	if adminID != config.GetAdminTelegramId() || update.Message.ReplyToMessage != nil {
+	if !staff.Can(adminID, staff.PermUsersRead) || update.Message.ReplyToMessage != nil {
 		return
 	}
 	if !AdminUsersSearchWaiting(adminID) {
@@ -1008,7 +1012,7 @@ func parseCustomerIDFromPrefix(data, prefix string) (int64, bool) {
 }
 
 func (h Handler) AdminUserManageHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersRead) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1036,7 +1040,7 @@ func (h Handler) AdminUserManageHandler(ctx context.Context, b *bot.Bot, update
 
 // AdminPaymentsNotifyOpenUserHandler — callback «К пользователю» из уведомления об оплате в группе: новое сообщение с той же карточкой, что в разделе «Пользователи».
 func (h Handler) AdminPaymentsNotifyOpenUserHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersRead) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1163,6 +1167,7 @@ func (h Handler) adminUserCardBodyHTML(ctx context.Context, b *bot.Bot, lang str
 			sb.WriteString("\n")
 		}
 	}
+	sb.WriteString(h.adminStarsSubscriptionsHTML(ctx, lang, cust))
 	sb.WriteString("\n")
 
 	if rw == nil {
@@ -1341,7 +1346,7 @@ func (h Handler) adminUserSubscriptionSubmenuKeyboard(ctx context.Context, lang
 }
 
 func (h Handler) AdminUserSubscriptionHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersRead) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1386,7 +1391,7 @@ func parseAxeExtend(data string) (days int, customerID int64, ok bool) {
 }
 
 func (h Handler) AdminUserExtendHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1411,7 +1416,12 @@ func (h Handler) AdminUserExtendHandler(ctx context.Context, b *bot.Bot, update
 		})
 		return
 	}
+	before := cust
 	cust, _ = h.customerRepository.FindById(ctx, cid)
+	audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+		Action: audit.ActionUserExtend, CustomerID: cid,
+		Before: audit.CustomerState(before), After: audit.CustomerState(cust),
+	})
 	lang := cb.From.LanguageCode
 	text, markup := h.adminUserManageContent(ctx, b, lang, cust)
 	_, err = editCallbackOriginToHTMLText(ctx, b, msg, text, models.ParseModeHTML, markup, nil)
@@ -1443,7 +1453,7 @@ func (h Handler) adminExtendCustomerDays(ctx context.Context, cust *database.Cus
 }
 
 func (h Handler) AdminUserResetTrafficAskHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1482,7 +1492,7 @@ func (h Handler) AdminUserResetTrafficAskHandler(ctx context.Context, b *bot.Bot
 }
 
 func (h Handler) AdminUserResetTrafficConfirmHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1516,6 +1526,11 @@ func (h Handler) AdminUserResetTrafficConfirmHandler(ctx context.Context, b *bot
 		})
 		return
 	}
+	audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+		Action: audit.ActionUserTrafficReset, CustomerID: cust.ID,
+		Before: map[string]any{"used_traffic_bytes": u.UserTraffic.UsedTrafficBytes},
+		After:  map[string]any{"used_traffic_bytes": 0},
+	})
 	lang := cb.From.LanguageCode
 	text, markup := h.adminUserManageContent(ctx, b, lang, cust)
 	_, err = editCallbackOriginToHTMLText(ctx, b, msg, text, models.ParseModeHTML, markup, nil)
@@ -1529,7 +1544,7 @@ func (h Handler) AdminUserResetTrafficConfirmHandler(ctx context.Context, b *bot
 }
 
 func (h Handler) AdminUserHwPresetMenuHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1571,7 +1586,7 @@ func (h Handler) AdminUserHwPresetMenuHandler(ctx context.Context, b *bot.Bot, u
 }
 
 func (h Handler) AdminUserHwPresetSetHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1599,7 +1614,8 @@ func (h Handler) AdminUserHwPresetSetHandler(ctx context.Context, b *bot.Bot, up
 	if err != nil || cust == nil {
 		return
 	}
-	if _, err := h.remnawaveClient.UpdateUserDeviceLimitByCustomer(ctx, cust.ID, cust.TelegramID, limit); err != nil {
+	out, err := h.remnawaveClient.UpdateUserDeviceLimitByCustomer(ctx, cust.ID, cust.TelegramID, limit)
+	if err != nil {
 		slog.Error("admin hw preset set", "error", err)
 		_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 			CallbackQueryID: cb.ID,
@@ -1608,6 +1624,9 @@ func (h Handler) AdminUserHwPresetSetHandler(ctx context.Context, b *bot.Bot, up
 		})
 		return
 	}
+	audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+		Action: audit.ActionUserHwidLimit, CustomerID: cust.ID, After: map[string]any{"hwid_device_limit": out.HwidDeviceLimit},
+	})
 	lang := cb.From.LanguageCode
 	text, markup := h.adminUserManageContent(ctx, b, lang, cust)
 	_, err = editCallbackOriginToHTMLText(ctx, b, msg, text, models.ParseModeHTML, markup, nil)
@@ -1661,7 +1680,7 @@ func (h Handler) adminUserDevicesMessageAndKeyboard(ctx context.Context, lang st
 }
 
 func (h Handler) AdminUserDevicesHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersRead) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1695,7 +1714,7 @@ func (h Handler) AdminUserDevicesHandler(ctx context.Context, b *bot.Bot, update
 }
 
 func (h Handler) AdminUserDevDelHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1744,6 +1763,9 @@ func (h Handler) AdminUserDevDelHandler(ctx context.Context, b *bot.Bot, update
 		})
 		return
 	}
+	audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+		Action: audit.ActionUserDeviceDelete, CustomerID: cust.ID, Before: map[string]any{"hwid": devices[idx].Hwid},
+	})
 	msg := cb.Message.Message
 	if msg == nil {
 		return
@@ -1772,7 +1794,7 @@ func (h Handler) AdminUserDevDelHandler(ctx context.Context, b *bot.Bot, update
 // --- Рефералы / статистика / оплаты ---------------------------------------------
 
 func (h Handler) AdminUserReferralsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersRead) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1820,11 +1842,24 @@ func (h Handler) AdminUserReferralsHandler(ctx context.Context, b *bot.Bot, upda
 		stats.Active,
 		earned,
 	))
-	kb := [][]models.InlineKeyboardButton{
-		{
-			h.translation.WithButton(lang, "back_button", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s%d", CallbackAdminUserManagePrefix, cust.ID)}),
-		},
+	var kb [][]models.InlineKeyboardButton
+	if config.ReferralPartnerEnabled() {
+		if dash, err := h.paymentService.PartnerDashboard(ctx, cust.ID); err == nil {
+			sb.WriteString("\n")
+			sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "admin_user_partner_line"),
+				payment.FormatPartnerPercent(dash.Percent),
+				payment.FormatBalanceRub(dash.Summary.Earned),
+				payment.FormatBalanceRub(dash.Available()),
+				payment.FormatBalanceRub(dash.Summary.PaidOut),
+			))
+		}
+		kb = append(kb, []models.InlineKeyboardButton{
+			h.translation.WithButton(lang, "admin_user_partner_rate_btn", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s%d", CallbackAdminUserPartnerMenuPrefix, cust.ID)}),
+		})
 	}
+	kb = append(kb, []models.InlineKeyboardButton{
+		h.translation.WithButton(lang, "back_button", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s%d", CallbackAdminUserManagePrefix, cust.ID)}),
+	})
 	_, err = editCallbackOriginToHTMLText(ctx, b, msg, sb.String(), models.ParseModeHTML, models.InlineKeyboardMarkup{InlineKeyboard: kb}, nil)
 	if err != nil {
 		slog.Error("admin user ref edit", "error", err)
@@ -1832,7 +1867,7 @@ func (h Handler) AdminUserReferralsHandler(ctx context.Context, b *bot.Bot, upda
 }
 
 func (h Handler) AdminUserSpendHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersRead) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1888,7 +1923,7 @@ func parseAupPayments(data string) (customerID int64, page int, ok bool) {
 }
 
 func (h Handler) AdminUserPaymentsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersRead) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1922,16 +1957,34 @@ func (h Handler) AdminUserPaymentsHandler(ctx context.Context, b *bot.Bot, updat
 		return
 	}
 	text := h.buildPurchaseHistoryText(ctx, lang, purchases, page, totalPages)
-	kb := h.adminUserPurchaseHistoryMarkup(lang, cust.ID, page, totalPages)
+	kb := h.adminUserPurchaseHistoryMarkup(lang, cust.ID, page, totalPages, purchases)
 	_, err = editCallbackOriginToHTMLText(ctx, b, msg, text, models.ParseModeHTML, models.InlineKeyboardMarkup{InlineKeyboard: kb}, nil)
 	if err != nil {
 		slog.Error("admin user payments edit", "error", err)
 	}
 }
 
-func (h Handler) adminUserPurchaseHistoryMarkup(lang string, customerID int64, page, totalPages int) [][]models.InlineKeyboardButton {
+func (h Handler) adminUserPurchaseHistoryMarkup(lang string, customerID int64, page, totalPages int, purchases []database.Purchase) [][]models.InlineKeyboardButton {
 	tm := translation.GetInstance()
 	var rows [][]models.InlineKeyboardButton
+	// Возврат: по кнопке на каждую оплату страницы (rfq + purchase id), по две в ряд.
+	var refundRow []models.InlineKeyboardButton
+	for _, p := range purchases {
+		if p.Status != database.PurchaseStatusPaid && p.Status != database.PurchaseStatusPartiallyRefunded {
+			continue
+		}
+		refundRow = append(refundRow, models.InlineKeyboardButton{
+			Text:         fmt.Sprintf(tm.GetText(lang, "admin_refund_btn"), p.ID),
+			CallbackData: fmt.Sprintf("%s%d", CallbackAdminRefundAskPrefix, p.ID),
+		})
+		if len(refundRow) == 2 {
+			rows = append(rows, refundRow)
+			refundRow = nil
+		}
+	}
+	if len(refundRow) > 0 {
+		rows = append(rows, refundRow)
+	}
 	var nav []models.InlineKeyboardButton
 	if page > 1 {
 		nav = append(nav, models.InlineKeyboardButton{
@@ -1957,7 +2010,7 @@ func (h Handler) adminUserPurchaseHistoryMarkup(lang string, customerID int64, p
 }
 
 func (h Handler) AdminUserMsgHintHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersWrite) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1996,7 +2049,7 @@ func (h Handler) AdminUserDMMessageHandler(ctx context.Context, b *bot.Bot, upda
 		return
 	}
 	adminID := update.Message.From.ID
-	if adminID != config.GetAdminTelegramId() {
+	if !staff.Can(adminID, staff.PermUsersWrite) {
 		return
 	}
 	targetTG, ok := adminUsersDMRecipient(adminID)
@@ -2038,6 +2091,14 @@ func (h Handler) AdminUserDMMessageHandler(ctx context.Context, b *bot.Bot, upda
 		})
 		return
 	}
+	entry := audit.Entry{
+		Action: audit.ActionUserMessage, TargetType: "telegram", TargetID: strconv.FormatInt(targetTG, 10),
+		After: map[string]any{"text": txt},
+	}
+	if cust, _ := h.customerRepository.FindByTelegramId(ctx, targetTG); cust != nil {
+		entry.CustomerID = cust.ID
+	}
+	audit.Record(ctx, audit.Bot(adminID), entry)
 	_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:      update.Message.Chat.ID,
 		Text:        h.translation.GetText(langDM, "admin_user_send_dm_ok"),
@@ -2053,7 +2114,7 @@ func (h Handler) AdminUserDMMessageHandler(ctx context.Context, b *bot.Bot, upda
 // --- Ветка «Подписки» ------------------------------------------------------------
 
 func (h Handler) AdminSubsRootHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersRead) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -2116,7 +2177,7 @@ func (h Handler) AdminSubsStatsJumpHandler(ctx context.Context, b *bot.Bot, upda
 // --- Рефералы (короткий экран) ----------------------------------------------------
 
 func (h Handler) AdminRefRootHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery) {
+	if update.CallbackQuery == nil || !isAdmin(update.CallbackQuery, staff.PermUsersRead) {
 		return
 	}
 	cb := update.CallbackQuery
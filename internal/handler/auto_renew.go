@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/payment"
+	"remnawave-tg-shop-bot/utils"
+)
+
+// AutoRenewCallbackHandler — экран «Автопродление» (auto_renew) и действия auto_renew_on / _off / _del.
+func (h Handler) AutoRenewCallbackHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || update.CallbackQuery.Message.Message == nil {
+		return
+	}
+	langCode := update.CallbackQuery.From.LanguageCode
+	customer, err := h.customerRepository.FindByTelegramId(ctx, update.CallbackQuery.From.ID)
+	if err != nil {
+		slog.Error("Error finding customer", "error", err)
+		return
+	}
+	if customer == nil {
+		slog.Error("customer not exist", "telegramId", utils.MaskHalfInt64(update.CallbackQuery.From.ID))
+		return
+	}
+
+	var actionErr error
+	switch update.CallbackQuery.Data {
+	case CallbackAutoRenewOn:
+		actionErr = h.paymentService.SetAutoRenew(ctx, customer.ID, true)
+	case CallbackAutoRenewOff:
+		actionErr = h.paymentService.SetAutoRenew(ctx, customer.ID, false)
+	case CallbackAutoRenewForget:
+		actionErr = h.paymentService.ForgetAutoRenewMethod(ctx, customer.ID)
+	}
+	if actionErr != nil && !errors.Is(actionErr, payment.ErrAutoRenewNoMethod) && !errors.Is(actionErr, payment.ErrAutoRenewUnavailable) {
+		slog.Error("auto renew action", "error", actionErr, "customer_id", utils.MaskHalfInt64(customer.ID))
+	}
+
+	st, err := h.paymentService.AutoRenewStatus(ctx, customer.ID)
+	if err != nil {
+		slog.Error("auto renew status", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+		return
+	}
+
+	_, err = editCallbackOriginToHTMLText(ctx, b, update.CallbackQuery.Message.Message, h.buildAutoRenewText(langCode, st), models.ParseModeHTML, models.InlineKeyboardMarkup{
+		InlineKeyboard: h.buildAutoRenewMarkup(langCode, st),
+	}, nil)
+	logEditError("Error sending auto renew message", err)
+}
+
+func (h Handler) buildAutoRenewText(langCode string, st payment.AutoRenewStatus) string {
+	var sb strings.Builder
+	sb.WriteString(h.translation.GetText(langCode, "auto_renew_title"))
+	sb.WriteString("\n\n")
+	if !st.HasMethod {
+		sb.WriteString(h.translation.GetText(langCode, "auto_renew_no_method"))
+		return sb.String()
+	}
+	title := st.MethodTitle
+	if title == "" {
+		title = h.translation.GetText(langCode, "auto_renew_method_unknown")
+	}
+	sb.WriteString(fmt.Sprintf(h.translation.GetText(langCode, "auto_renew_method_line"), escapeHTML(title)))
+	sb.WriteString("\n")
+	if st.Enabled && st.Available {
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(langCode, "auto_renew_status_on"), config.YookasaAutoRenewDaysBefore()))
+	} else {
+		sb.WriteString(h.translation.GetText(langCode, "auto_renew_status_off"))
+	}
+	if st.Enabled && st.FailedAttempts > 0 && st.NextAttemptAt != nil {
+		sb.WriteString("\n\n")
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(langCode, "auto_renew_retry_line"), st.FailedAttempts, st.NextAttemptAt.Local().Format("02.01.2006 15:04")))
+	}
+	return sb.String()
+}
+
+func (h Handler) buildAutoRenewMarkup(langCode string, st payment.AutoRenewStatus) [][]models.InlineKeyboardButton {
+	var kb [][]models.InlineKeyboardButton
+	if st.HasMethod {
+		if st.Enabled {
+			kb = append(kb, []models.InlineKeyboardButton{
+				h.translation.WithButton(langCode, "auto_renew_off_button", models.InlineKeyboardButton{CallbackData: CallbackAutoRenewOff}),
+			})
+		} else if st.Available {
+			kb = append(kb, []models.InlineKeyboardButton{
+				h.translation.WithButton(langCode, "auto_renew_on_button", models.InlineKeyboardButton{CallbackData: CallbackAutoRenewOn}),
+			})
+		}
+		kb = append(kb, []models.InlineKeyboardButton{
+			h.translation.WithButton(langCode, "auto_renew_forget_button", models.InlineKeyboardButton{CallbackData: CallbackAutoRenewForget}),
+		})
+	} else {
+		kb = append(kb, []models.InlineKeyboardButton{
+			h.translation.WithButton(langCode, "buy_button", models.InlineKeyboardButton{CallbackData: CallbackBuy}),
+		})
+	}
+	kb = append(kb, []models.InlineKeyboardButton{
+		h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackConnect}),
+	})
+	return kb
+}
@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/payment"
+	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/utils"
+)
+
+const balanceHistoryLimit = 10
+
+// BalanceCallbackHandler — экран «Баланс»: остаток, последние операции, кнопка пополнения.
+func (h Handler) BalanceCallbackHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || update.CallbackQuery.Message.Message == nil {
+		return
+	}
+	langCode := update.CallbackQuery.From.LanguageCode
+	if !config.BalanceEnabled() {
+		return
+	}
+	customer, err := h.customerRepository.FindByTelegramId(ctx, update.CallbackQuery.From.ID)
+	if err != nil {
+		slog.Error("Error finding customer", "error", err)
+		return
+	}
+	if customer == nil {
+		slog.Error("customer not exist", "telegramId", utils.MaskHalfInt64(update.CallbackQuery.From.ID))
+		return
+	}
+	balance, err := h.paymentService.Balance(ctx, customer.ID)
+	if err != nil {
+		slog.Error("balance: get", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+		return
+	}
+	history, err := h.paymentService.BalanceHistory(ctx, customer.ID, balanceHistoryLimit, 0)
+	if err != nil {
+		slog.Error("balance: history", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(h.translation.GetText(langCode, "balance_title"), payment.FormatBalanceRub(balance)))
+	sb.WriteString("\n\n")
+	if len(history) == 0 {
+		sb.WriteString(h.translation.GetText(langCode, "balance_history_empty"))
+	} else {
+		sb.WriteString(h.translation.GetText(langCode, "balance_history_title"))
+		for _, t := range history {
+			sign := ""
+			if t.Amount > 0 {
+				sign = "+"
+			}
+			sb.WriteString(fmt.Sprintf("\n%s · <b>%s%s</b> · %s",
+				t.CreatedAt.Local().Format("02.01 15:04"), sign, payment.FormatBalanceRub(t.Amount), h.balanceTxLabel(langCode, t.Type)))
+		}
+	}
+
+	kb := [][]models.InlineKeyboardButton{
+		{h.translation.WithButton(langCode, "balance_topup_button", models.InlineKeyboardButton{CallbackData: CallbackBalanceTopUp})},
+		{h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackStart})},
+	}
+	_, err = editCallbackOriginToHTMLText(ctx, b, update.CallbackQuery.Message.Message, sb.String(), models.ParseModeHTML, models.InlineKeyboardMarkup{
+		InlineKeyboard: kb,
+	}, nil)
+	logEditError("Error sending balance message", err)
+}
+
+func (h Handler) balanceTxLabel(langCode string, t database.BalanceTxType) string {
+	switch t {
+	case database.BalanceTxTopUp:
+		return h.translation.GetText(langCode, "balance_tx_topup")
+	case database.BalanceTxPurchase:
+		return h.translation.GetText(langCode, "balance_tx_purchase")
+	case database.BalanceTxRefund:
+		return h.translation.GetText(langCode, "balance_tx_refund")
+	case database.BalanceTxReferral:
+		return h.translation.GetText(langCode, "balance_tx_referral")
+	case database.BalanceTxAdmin:
+		return h.translation.GetText(langCode, "balance_tx_admin")
+	default:
+		return string(t)
+	}
+}
+
+// BalanceTopUpCallbackHandler — пополнение: wallet_topup — выбор суммы, wallet_topup?amount= — выбор способа оплаты.
+func (h Handler) BalanceTopUpCallbackHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || update.CallbackQuery.Message.Message == nil {
+		return
+	}
+	langCode := update.CallbackQuery.From.LanguageCode
+	if !config.BalanceEnabled() {
+		return
+	}
+	q := parseCallbackData(update.CallbackQuery.Data)
+	amount := parseIntSafe(q["amount"])
+
+	if amount <= 0 {
+		var rows [][]models.InlineKeyboardButton
+		var row []models.InlineKeyboardButton
+		for _, v := range config.BalanceTopUpPresets() {
+			row = append(row, models.InlineKeyboardButton{
+				Text:         payment.FormatBalanceRub(float64(v)),
+				CallbackData: fmt.Sprintf("%s?amount=%d", CallbackBalanceTopUp, v),
+			})
+			if len(row) == 2 {
+				rows = append(rows, row)
+				row = nil
+			}
+		}
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+		rows = append(rows, []models.InlineKeyboardButton{
+			h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackBalance}),
+		})
+		text := fmt.Sprintf(h.translation.GetText(langCode, "balance_topup_choose_amount"),
+			payment.FormatBalanceRub(float64(config.BalanceTopUpMin())), payment.FormatBalanceRub(float64(config.BalanceTopUpMax())))
+		_, err := editCallbackOriginToHTMLText(ctx, b, update.CallbackQuery.Message.Message, text, models.ParseModeHTML, models.InlineKeyboardMarkup{
+			InlineKeyboard: rows,
+		}, nil)
+		logEditError("Error sending balance top-up amounts", err)
+		return
+	}
+
+	var keyboard [][]models.InlineKeyboardButton
+	for _, p := range h.paymentService.Providers().Enabled() {
+		info := p.Info()
+		// Внешняя ссылка (Tribute) не создаёт покупку в боте, Stars — не рубли.
+		if info.ExternalURL != "" || !payment.BalanceTopUpMethodAllowed(info.InvoiceType) {
+			continue
+		}
+		keyboard = append(keyboard, []models.InlineKeyboardButton{
+			h.translation.WithButton(langCode, info.ButtonKey, models.InlineKeyboardButton{
+				CallbackData: fmt.Sprintf("%s?amount=%d&invoiceType=%s", CallbackBalanceTopUpPay, amount, info.InvoiceType),
+			}),
+		})
+	}
+	keyboard = append(keyboard, []models.InlineKeyboardButton{
+		h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackBalanceTopUp}),
+	})
+	text := fmt.Sprintf(h.translation.GetText(langCode, "balance_topup_choose_method"), payment.FormatBalanceRub(float64(amount)))
+	_, err := editCallbackOriginToHTMLText(ctx, b, update.CallbackQuery.Message.Message, text, models.ParseModeHTML, models.InlineKeyboardMarkup{
+		InlineKeyboard: keyboard,
+	}, nil)
+	logEditError("Error sending balance top-up methods", err)
+}
+
+// BalanceTopUpPayCallbackHandler выставляет счёт на пополнение; зачисление — после оплаты в ProcessPurchaseById.
+func (h Handler) BalanceTopUpPayCallbackHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || update.CallbackQuery.Message.Message == nil {
+		return
+	}
+	callback := update.CallbackQuery.Message.Message
+	langCode := update.CallbackQuery.From.LanguageCode
+	q := parseCallbackData(update.CallbackQuery.Data)
+	amount := parseIntSafe(q["amount"])
+	invoiceType := database.InvoiceType(q["invoiceType"])
+	back := fmt.Sprintf("%s?amount=%d", CallbackBalanceTopUp, amount)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	customer, err := h.customerRepository.FindByTelegramId(ctx, callback.Chat.ID)
+	if err != nil {
+		slog.Error("Error finding customer", "error", err)
+		return
+	}
+	if customer == nil {
+		slog.Error("customer not exist", "chatID", utils.MaskHalfInt64(callback.Chat.ID))
+		return
+	}
+
+	ctxWithUsername := context.WithValue(ctx, remnawave.CtxKeyUsername, update.CallbackQuery.From.Username)
+	paymentURL, purchaseId, err := h.paymentService.CreateBalanceTopUp(ctxWithUsername, customer, invoiceType, amount)
+	if err != nil {
+		slog.Error("balance: create top-up", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+		h.notifyPaymentProviderUnavailable(ctx, b, update, langCode, back)
+		return
+	}
+
+	text := fmt.Sprintf(h.translation.GetText(langCode, "balance_topup_pay_text"), payment.FormatBalanceRub(float64(amount)))
+	message, err := editCallbackOriginToHTMLText(ctx, b, callback, text, models.ParseModeHTML, models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				h.translation.WithButton(langCode, "pay_button", models.InlineKeyboardButton{URL: paymentURL}),
+				h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: back}),
+			},
+		},
+	}, nil)
+	if err != nil {
+		logEditError("Error sending balance top-up payment message", err)
+		return
+	}
+	h.cache.Set(purchaseId, message.ID)
+}
+
+// handleBalanceCheckout — итог оплаты с баланса вместо кнопки «Оплатить»: покупка уже проведена
+// или денег не хватило. false — способ оплаты не баланс, вызывающий показывает счёт сам.
+func (h Handler) handleBalanceCheckout(ctx context.Context, b *bot.Bot, update *models.Update, langCode string, invoiceType database.InvoiceType, createErr error, back string) bool {
+	if invoiceType != database.InvoiceTypeBalance {
+		return false
+	}
+	if createErr != nil {
+		if errors.Is(createErr, database.ErrBalanceInsufficient) {
+			h.notifyBalanceInsufficient(ctx, b, update, langCode, back)
+		} else {
+			h.notifyPaymentProviderUnavailable(ctx, b, update, langCode, back)
+		}
+		return true
+	}
+	text := h.translation.GetText(langCode, "balance_paid")
+	if customer, err := h.customerRepository.FindByTelegramId(ctx, update.CallbackQuery.From.ID); err == nil && customer != nil {
+		if balance, err := h.paymentService.Balance(ctx, customer.ID); err == nil {
+			text += "\n" + fmt.Sprintf(h.translation.GetText(langCode, "balance_left"), payment.FormatBalanceRub(balance))
+		}
+	}
+	markup := models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackStart})},
+		},
+	}
+	err := SendOrEditAfterInlineCallback(ctx, b, update, text, models.ParseModeHTML, markup, nil)
+	logEditError("Error sending paid from balance message", err)
+	return true
+}
+
+func (h Handler) notifyBalanceInsufficient(ctx context.Context, b *bot.Bot, update *models.Update, langCode, back string) {
+	markup := models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{h.translation.WithButton(langCode, "balance_topup_button", models.InlineKeyboardButton{CallbackData: CallbackBalanceTopUp})},
+			{h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: back})},
+		},
+	}
+	err := SendOrEditAfterInlineCallback(ctx, b, update, h.translation.GetText(langCode, "balance_insufficient"), models.ParseModeHTML, markup, nil)
+	logEditError("Error sending balance insufficient message", err)
+}
@@ -7,14 +7,17 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unicode/utf8"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 
+	"remnawave-tg-shop-bot/internal/audit"
 	"remnawave-tg-shop-bot/internal/broadcast"
 	"remnawave-tg-shop-bot/internal/config"
 	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/staff"
 )
 
 // BroadcastType определяет тип рассылки
@@ -45,6 +48,8 @@ type BroadcastState struct {
 	pendingButtons       map[int64]BroadcastRecipientButtons
 	waitingForInput      map[int64]bool
 	waitingForButtonPick map[int64]bool
+	// Ждём дату отложенного запуска (кнопка «Запланировать» на экране подтверждения).
+	waitingForSchedule map[int64]bool
 	selectedType              map[int64]BroadcastType
 	// Фильтр тарифа для платных сегментов в SALES_MODE=tariffs; nil — все платники сегмента; не в map — то же после classic.
 	selectedTariffID map[int64]*int64
@@ -61,6 +66,7 @@ func newBroadcastState() *BroadcastState {
 		pendingButtons:       make(map[int64]BroadcastRecipientButtons),
 		waitingForInput:      make(map[int64]bool),
 		waitingForButtonPick: make(map[int64]bool),
+		waitingForSchedule:   make(map[int64]bool),
 		selectedType:               make(map[int64]BroadcastType),
 		selectedTariffID:             make(map[int64]*int64),
 		broadcastOpenedFromAdmin:   make(map[int64]bool),
@@ -77,6 +83,7 @@ func clearBroadcastState(adminID int64) {
 	delete(broadcastState.pendingButtons, adminID)
 	delete(broadcastState.waitingForInput, adminID)
 	delete(broadcastState.waitingForButtonPick, adminID)
+	delete(broadcastState.waitingForSchedule, adminID)
 	delete(broadcastState.selectedType, adminID)
 	delete(broadcastState.selectedTariffID, adminID)
 	delete(broadcastState.broadcastOpenedFromAdmin, adminID)
@@ -92,6 +99,7 @@ func resetBroadcastDraft(adminID int64) {
 	delete(broadcastState.pendingMedia, adminID)
 	delete(broadcastState.pendingButtons, adminID)
 	delete(broadcastState.waitingForButtonPick, adminID)
+	delete(broadcastState.waitingForSchedule, adminID)
 	delete(broadcastState.promptMessageID, adminID)
 	delete(broadcastState.pendingPreviewMsgID, adminID)
 	delete(broadcastState.selectedTariffID, adminID)
@@ -228,6 +236,7 @@ func (h Handler) BroadcastAudienceKeyboard(lang string, withBackToAdmin bool) []
 		{h.translation.WithButton(lang, "broadcast_audience_all", models.InlineKeyboardButton{CallbackData: CallbackBroadcastAll})},
 		{h.translation.WithButton(lang, "broadcast_audience_active", models.InlineKeyboardButton{CallbackData: CallbackBroadcastActive})},
 		{h.translation.WithButton(lang, "broadcast_audience_inactive", models.InlineKeyboardButton{CallbackData: CallbackBroadcastInactive})},
+		{h.translation.WithButton(lang, "broadcast_jobs_button", models.InlineKeyboardButton{CallbackData: CallbackBroadcastJobs})},
 	}
 	if withBackToAdmin {
 		rows = append(rows, []models.InlineKeyboardButton{
@@ -257,8 +266,8 @@ func (h Handler) BroadcastCommandHandler(ctx context.Context, b *bot.Bot, update
 		return
 	}
 
-	adminID := config.GetAdminTelegramId()
-	if update.Message.From.ID != adminID {
+	adminID := update.Message.From.ID
+	if !staff.Can(adminID, staff.PermBroadcasts) {
 		return
 	}
 
@@ -324,8 +333,8 @@ func (h Handler) BroadcastActiveMenuHandler(ctx context.Context, b *bot.Bot, upd
 	if update.CallbackQuery == nil {
 		return
 	}
-	adminID := config.GetAdminTelegramId()
-	if update.CallbackQuery.From.ID != adminID {
+	adminID := update.CallbackQuery.From.ID
+	if !staff.Can(adminID, staff.PermBroadcasts) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -345,8 +354,8 @@ func (h Handler) BroadcastInactiveMenuHandler(ctx context.Context, b *bot.Bot, u
 	if update.CallbackQuery == nil {
 		return
 	}
-	adminID := config.GetAdminTelegramId()
-	if update.CallbackQuery.From.ID != adminID {
+	adminID := update.CallbackQuery.From.ID
+	if !staff.Can(adminID, staff.PermBroadcasts) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -366,8 +375,8 @@ func (h Handler) BroadcastBackToAudienceHandler(ctx context.Context, b *bot.Bot,
 	if update.CallbackQuery == nil {
 		return
 	}
-	adminID := config.GetAdminTelegramId()
-	if update.CallbackQuery.From.ID != adminID {
+	adminID := update.CallbackQuery.From.ID
+	if !staff.Can(adminID, staff.PermBroadcasts) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -403,8 +412,8 @@ func segmentPickBroadcastType(cbData string) BroadcastType {
 
 // broadcastBeginDraftPrompt — аудитория выбрана, запрашиваем текст/медиа черновика.
 func (h Handler) broadcastBeginDraftPrompt(ctx context.Context, b *bot.Bot, cb *models.CallbackQuery, broadcastType BroadcastType, tariffFilter *int64) {
-	adminID := config.GetAdminTelegramId()
-	if cb.From.ID != adminID {
+	adminID := cb.From.ID
+	if !staff.Can(adminID, staff.PermBroadcasts) {
 		return
 	}
 	callbackMessage := cb.Message.Message
@@ -449,8 +458,8 @@ func (h Handler) BroadcastPaidTariffCallbacksHandler(ctx context.Context, b *bot
 		return
 	}
 	cb := update.CallbackQuery
-	adminID := config.GetAdminTelegramId()
-	if cb.From.ID != adminID || cb.Message.Message == nil {
+	adminID := cb.From.ID
+	if !staff.Can(adminID, staff.PermBroadcasts) || cb.Message.Message == nil {
 		return
 	}
 	data := cb.Data
@@ -508,8 +517,8 @@ func (h Handler) BroadcastSegmentPickHandler(ctx context.Context, b *bot.Bot, up
 	if update.CallbackQuery == nil {
 		return
 	}
-	adminID := config.GetAdminTelegramId()
-	if update.CallbackQuery.From.ID != adminID {
+	adminID := update.CallbackQuery.From.ID
+	if !staff.Can(adminID, staff.PermBroadcasts) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -596,8 +605,8 @@ func (h Handler) BroadcastTypeSelectHandler(ctx context.Context, b *bot.Bot, upd
 		return
 	}
 
-	adminID := config.GetAdminTelegramId()
-	if update.CallbackQuery.From.ID != adminID {
+	adminID := update.CallbackQuery.From.ID
+	if !staff.Can(adminID, staff.PermBroadcasts) {
 		return
 	}
 
@@ -614,8 +623,8 @@ func (h Handler) BroadcastMessageHandler(ctx context.Context, b *bot.Bot, update
 		return
 	}
 
-	adminID := config.GetAdminTelegramId()
-	if update.Message.From.ID != adminID {
+	adminID := update.Message.From.ID
+	if !staff.Can(adminID, staff.PermBroadcasts) {
 		return
 	}
 
@@ -700,8 +709,8 @@ func (h Handler) BroadcastButtonToggleHandler(ctx context.Context, b *bot.Bot, u
 		return
 	}
 	cb := update.CallbackQuery
-	adminID := config.GetAdminTelegramId()
-	if cb.From.ID != adminID || cb.Message.Message == nil {
+	adminID := cb.From.ID
+	if !staff.Can(adminID, staff.PermBroadcasts) || cb.Message.Message == nil {
 		return
 	}
 
@@ -746,8 +755,8 @@ func (h Handler) BroadcastButtonsNextHandler(ctx context.Context, b *bot.Bot, up
 		return
 	}
 	cb := update.CallbackQuery
-	adminID := config.GetAdminTelegramId()
-	if cb.From.ID != adminID || cb.Message.Message == nil {
+	adminID := cb.From.ID
+	if !staff.Can(adminID, staff.PermBroadcasts) || cb.Message.Message == nil {
 		return
 	}
 
@@ -844,6 +853,9 @@ func (h Handler) BroadcastButtonsNextHandler(ctx context.Context, b *bot.Bot, up
 				{Text: h.translation.GetText(lang, "broadcast_confirm_yes"), CallbackData: CallbackBroadcastConfirm},
 				{Text: h.translation.GetText(lang, "broadcast_confirm_no"), CallbackData: CallbackBroadcastCancel},
 			},
+			{
+				{Text: h.translation.GetText(lang, "broadcast_confirm_schedule"), CallbackData: CallbackBroadcastSchedule},
+			},
 		},
 	}
 
@@ -858,65 +870,41 @@ func (h Handler) BroadcastButtonsNextHandler(ctx context.Context, b *bot.Bot, up
 	}
 }
 
-// BroadcastConfirmHandler обрабатывает подтверждение рассылки
+// BroadcastConfirmHandler обрабатывает подтверждение рассылки: ставит её в очередь на отправку сейчас.
 func (h Handler) BroadcastConfirmHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	if update.CallbackQuery == nil {
 		return
 	}
 
-	adminID := config.GetAdminTelegramId()
-	if update.CallbackQuery.From.ID != adminID {
+	adminID := update.CallbackQuery.From.ID
+	if !staff.Can(adminID, staff.PermBroadcasts) {
 		return
 	}
 
-	broadcastState.mu.Lock()
-	messageText, exists := broadcastState.pendingText[adminID]
-	broadcastType, typeExists := broadcastState.selectedType[adminID]
-	entities := broadcastState.pendingEntities[adminID]
-	flags := broadcastState.pendingButtons[adminID]
-	previewID := broadcastState.pendingPreviewMsgID[adminID]
-	var draftMedia *broadcastDraftMedia
-	if m, ok := broadcastState.pendingMedia[adminID]; ok {
-		cp := *m
-		draftMedia = &cp
-	}
-	if !exists || !typeExists {
-		broadcastState.mu.Unlock()
-		lang := update.CallbackQuery.From.LanguageCode
+	lang := update.CallbackQuery.From.LanguageCode
+	draft, ok := takeBroadcastDraft(adminID)
+	if !ok {
 		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: adminID,
 			Text:   h.translation.GetText(lang, "broadcast_session_expired"),
 		})
 		return
 	}
-	entCopy := append([]models.MessageEntity(nil), entities...)
-	flagsCopy := flags
-	var tariffFilter *int64
-	if ptr, ok := broadcastState.selectedTariffID[adminID]; ok && ptr != nil {
-		tariffFilter = ptr
-	}
-	broadcastState.mu.Unlock()
-	clearBroadcastState(adminID)
 
-	callbackMessage := update.CallbackQuery.Message.Message
-	if previewID != 0 {
+	if draft.previewMsgID != 0 {
 		_, _ = b.DeleteMessage(ctx, &bot.DeleteMessageParams{
 			ChatID:    adminID,
-			MessageID: previewID,
+			MessageID: draft.previewMsgID,
+		})
+	}
+	if callbackMessage := update.CallbackQuery.Message.Message; callbackMessage != nil {
+		_, _ = b.DeleteMessage(ctx, &bot.DeleteMessageParams{
+			ChatID:    callbackMessage.Chat.ID,
+			MessageID: callbackMessage.ID,
 		})
 	}
-	_, _ = b.DeleteMessage(ctx, &bot.DeleteMessageParams{
-		ChatID:    callbackMessage.Chat.ID,
-		MessageID: callbackMessage.ID,
-	})
-
-	lang := update.CallbackQuery.From.LanguageCode
-	_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID: adminID,
-		Text:   h.translation.GetText(lang, "broadcast_started_wait"),
-	})
 
-	go h.sendBroadcast(ctx, b, adminID, messageText, entCopy, draftMedia, flagsCopy, broadcastType, tariffFilter)
+	h.enqueueBroadcast(ctx, b, adminID, lang, draft, time.Time{})
 }
 
 // BroadcastCancelHandler обрабатывает отмену рассылки
@@ -925,8 +913,8 @@ func (h Handler) BroadcastCancelHandler(ctx context.Context, b *bot.Bot, update
 		return
 	}
 
-	adminID := config.GetAdminTelegramId()
-	if update.CallbackQuery.From.ID != adminID {
+	adminID := update.CallbackQuery.From.ID
+	if !staff.Can(adminID, staff.PermBroadcasts) {
 		return
 	}
 
@@ -970,26 +958,92 @@ func (h Handler) BroadcastCancelHandler(ctx context.Context, b *bot.Bot, update
 
 // BroadcastBackToAdminHandler возвращает из экрана выбора аудитории рассылки в админ-меню.
 func (h Handler) BroadcastBackToAdminHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.IsStaff(update.CallbackQuery.From.ID) {
 		return
 	}
 	cb := update.CallbackQuery
 	clearBroadcastState(cb.From.ID)
-	if err := h.RenderAdminPanel(ctx, b, cb.Message.Message, cb.From.LanguageCode); err != nil {
+	if err := h.RenderAdminPanel(ctx, b, cb.Message.Message, cb.From.ID, cb.From.LanguageCode); err != nil {
 		slog.Error("broadcast back to admin", "error", err)
 	}
 }
 
-// sendBroadcast отправляет сообщение пользователям пачками в зависимости от типа рассылки.
-func (h Handler) sendBroadcast(ctx context.Context, b *bot.Bot, adminID int64, messageText string, entities []models.MessageEntity, media *broadcastDraftMedia, flags BroadcastRecipientButtons, broadcastType BroadcastType, tariffFilter *int64) {
-	audience := broadcastTypeToAudience(broadcastType)
-	var tf *int64
-	if (broadcastType == BroadcastTypeActivePaid || broadcastType == BroadcastTypeInactivePaid) && tariffFilter != nil {
-		tf = tariffFilter
+// broadcastDraft — черновик рассылки, забранный из BroadcastState.
+type broadcastDraft struct {
+	text          string
+	entities      []models.MessageEntity
+	media         *broadcastDraftMedia
+	buttons       BroadcastRecipientButtons
+	broadcastType BroadcastType
+	tariffID      *int64
+	previewMsgID  int
+	promptMsgID   int
+}
+
+// takeBroadcastDraft забирает черновик и очищает состояние админа; false — черновика нет (сессия истекла).
+func takeBroadcastDraft(adminID int64) (broadcastDraft, bool) {
+	broadcastState.mu.Lock()
+	text, exists := broadcastState.pendingText[adminID]
+	broadcastType, typeExists := broadcastState.selectedType[adminID]
+	if !exists || !typeExists {
+		broadcastState.mu.Unlock()
+		return broadcastDraft{}, false
+	}
+	d := broadcastDraft{
+		text:          text,
+		entities:      append([]models.MessageEntity(nil), broadcastState.pendingEntities[adminID]...),
+		buttons:       broadcastState.pendingButtons[adminID],
+		broadcastType: broadcastType,
+		previewMsgID:  broadcastState.pendingPreviewMsgID[adminID],
+		promptMsgID:   broadcastState.promptMessageID[adminID],
+	}
+	if m, ok := broadcastState.pendingMedia[adminID]; ok {
+		cp := *m
+		d.media = &cp
+	}
+	if ptr, ok := broadcastState.selectedTariffID[adminID]; ok && ptr != nil {
+		d.tariffID = ptr
 	}
+	broadcastState.mu.Unlock()
+	clearBroadcastState(adminID)
+	return d, true
+}
+
+// enqueueBroadcast ставит рассылку в очередь (scheduledAt нулевое — сразу) и показывает её карточку.
+func (h Handler) enqueueBroadcast(ctx context.Context, b *bot.Bot, adminID int64, lang string, d broadcastDraft, scheduledAt time.Time) {
 	if h.broadcastSender == nil {
 		slog.Error("broadcast sender not configured")
 		return
 	}
-	h.broadcastSender.Send(ctx, b, adminID, audience, tf, messageText, entities, media, flags)
+	var tf *int64
+	if (d.broadcastType == BroadcastTypeActivePaid || d.broadcastType == BroadcastTypeInactivePaid) && d.tariffID != nil {
+		tf = d.tariffID
+	}
+	job, err := h.broadcastSender.Enqueue(ctx, broadcast.Draft{
+		Source:              string(audit.SourceBot),
+		CreatedByTelegramID: adminID,
+		NotifyChatID:        adminID,
+		Audience:            broadcastTypeToAudience(d.broadcastType),
+		TariffID:            tf,
+		Text:                d.text,
+		Entities:            d.entities,
+		Media:               d.media,
+		Buttons:             d.buttons,
+		ScheduledAt:         scheduledAt,
+	})
+	if err != nil {
+		slog.Error("broadcast enqueue", "error", err)
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: adminID,
+			Text:   h.translation.GetText(lang, "broadcast_enqueue_error"),
+		})
+		return
+	}
+
+	audit.Record(ctx, audit.Bot(adminID), audit.Entry{
+		Action: audit.ActionBroadcastSend, TargetType: "broadcast", TargetID: strconv.FormatInt(job.ID, 10),
+		After: map[string]any{"audience": job.Audience, "tariff_id": tf, "text": d.text,
+			"scheduled_at": job.ScheduledAt.UTC().Format(time.RFC3339)},
+	})
+	h.sendBroadcastJobCard(ctx, b, adminID, lang, job)
 }
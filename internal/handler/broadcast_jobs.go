@@ -0,0 +1,275 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/audit"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/staff"
+)
+
+const broadcastJobsListLimit = 10
+
+// BroadcastAwaitingSchedule — админ нажал «Запланировать» и бот ждёт дату запуска.
+func BroadcastAwaitingSchedule(adminID int64) bool {
+	broadcastState.mu.Lock()
+	defer broadcastState.mu.Unlock()
+	return broadcastState.waitingForSchedule[adminID]
+}
+
+// BroadcastScheduleHandler — «Запланировать» на экране подтверждения: просит дату и время (МСК).
+func (h Handler) BroadcastScheduleHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil {
+		return
+	}
+	cb := update.CallbackQuery
+	adminID := cb.From.ID
+	if !staff.Can(adminID, staff.PermBroadcasts) || cb.Message.Message == nil {
+		return
+	}
+	lang := cb.From.LanguageCode
+
+	broadcastState.mu.Lock()
+	_, hasText := broadcastState.pendingText[adminID]
+	_, hasType := broadcastState.selectedType[adminID]
+	if hasText && hasType {
+		broadcastState.waitingForSchedule[adminID] = true
+		broadcastState.promptMessageID[adminID] = cb.Message.Message.ID
+	}
+	broadcastState.mu.Unlock()
+	if !hasText || !hasType {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: adminID,
+			Text:   h.translation.GetText(lang, "broadcast_session_expired"),
+		})
+		return
+	}
+
+	example := time.Now().In(adminInfraTZ()).Add(time.Hour).Truncate(time.Hour).Format("02.01.2006 15:04")
+	_, err := editCallbackOriginToHTMLText(ctx, b, cb.Message.Message,
+		fmt.Sprintf(h.translation.GetText(lang, "broadcast_schedule_prompt"), example), models.ParseModeHTML,
+		models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
+			{{Text: h.translation.GetText(lang, "broadcast_confirm_no"), CallbackData: CallbackBroadcastCancel}},
+		}}, nil)
+	if err != nil {
+		slog.Error("broadcast schedule prompt", "error", err)
+	}
+}
+
+// BroadcastScheduleInputHandler — дата запуска отложенной рассылки («ДД.ММ.ГГГГ ЧЧ:ММ», МСК).
+func (h Handler) BroadcastScheduleInputHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.Message == nil || update.Message.From == nil {
+		return
+	}
+	adminID := update.Message.From.ID
+	if !staff.Can(adminID, staff.PermBroadcasts) || !BroadcastAwaitingSchedule(adminID) {
+		return
+	}
+	lang := update.Message.From.LanguageCode
+
+	at, err := parseInfraAdminDateInput(update.Message.Text)
+	if err != nil {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:    adminID,
+			ParseMode: models.ParseModeHTML,
+			Text:      h.translation.GetText(lang, "broadcast_schedule_invalid"),
+		})
+		return
+	}
+	if !at.After(time.Now()) {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: adminID,
+			Text:   h.translation.GetText(lang, "broadcast_schedule_past"),
+		})
+		return
+	}
+
+	draft, ok := takeBroadcastDraft(adminID)
+	if !ok {
+		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: adminID,
+			Text:   h.translation.GetText(lang, "broadcast_session_expired"),
+		})
+		return
+	}
+	for _, id := range []int{draft.previewMsgID, draft.promptMsgID} {
+		if id != 0 {
+			_, _ = b.DeleteMessage(ctx, &bot.DeleteMessageParams{ChatID: adminID, MessageID: id})
+		}
+	}
+	h.enqueueBroadcast(ctx, b, adminID, lang, draft, at)
+}
+
+func (h Handler) broadcastJobStatusText(lang string, status database.BroadcastJobStatus) string {
+	return h.translation.GetText(lang, "broadcast_job_status_"+string(status))
+}
+
+// broadcastJobCard — текст и кнопки карточки рассылки: прогресс и управление.
+func (h Handler) broadcastJobCard(ctx context.Context, lang string, job *database.BroadcastJob) (string, models.InlineKeyboardMarkup) {
+	loc := adminInfraTZ()
+	audience := h.translation.GetText(lang, "broadcast_summary_segment")
+	if job.Audience != database.BroadcastAudienceSegment {
+		audience = h.broadcastAudienceSummaryLine(ctx, lang, BroadcastType(job.Audience), job.TariffID)
+	}
+	text := fmt.Sprintf(h.translation.GetText(lang, "broadcast_job_card"),
+		job.ID,
+		h.broadcastJobStatusText(lang, job.Status),
+		audience,
+		job.ScheduledAt.In(loc).Format("02.01.2006 15:04"),
+		job.SentCount, job.TotalCount, job.FailedCount,
+	)
+	if job.FinishedAt != nil {
+		text += fmt.Sprintf(h.translation.GetText(lang, "broadcast_job_finished_at"), job.FinishedAt.In(loc).Format("02.01.2006 15:04"))
+	}
+
+	id := strconv.FormatInt(job.ID, 10)
+	var rows [][]models.InlineKeyboardButton
+	control := []models.InlineKeyboardButton{
+		{Text: h.translation.GetText(lang, "broadcast_job_refresh"), CallbackData: CallbackBroadcastJobViewPrefix + id},
+	}
+	switch job.Status {
+	case database.BroadcastJobStatusScheduled, database.BroadcastJobStatusRunning:
+		control = append(control, models.InlineKeyboardButton{Text: h.translation.GetText(lang, "broadcast_job_pause"), CallbackData: CallbackBroadcastJobPausePrefix + id})
+	case database.BroadcastJobStatusPaused:
+		control = append(control, models.InlineKeyboardButton{Text: h.translation.GetText(lang, "broadcast_job_resume"), CallbackData: CallbackBroadcastJobResumePrefix + id})
+	}
+	rows = append(rows, control)
+	switch job.Status {
+	case database.BroadcastJobStatusScheduled, database.BroadcastJobStatusRunning, database.BroadcastJobStatusPaused:
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: h.translation.GetText(lang, "broadcast_job_cancel"), CallbackData: CallbackBroadcastJobCancelPrefix + id},
+		})
+	}
+	rows = append(rows, []models.InlineKeyboardButton{
+		{Text: h.translation.GetText(lang, "broadcast_jobs_button"), CallbackData: CallbackBroadcastJobs},
+	})
+	return text, models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
+
+func (h Handler) sendBroadcastJobCard(ctx context.Context, b *bot.Bot, chatID int64, lang string, job *database.BroadcastJob) {
+	text, markup := h.broadcastJobCard(ctx, lang, job)
+	_, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatID,
+		ParseMode:   models.ParseModeHTML,
+		Text:        text,
+		ReplyMarkup: markup,
+	})
+	if err != nil {
+		slog.Error("broadcast job card", "job_id", job.ID, "error", err)
+	}
+}
+
+// BroadcastJobCallbackHandler — список рассылок и карточка с паузой / продолжением / отменой (префикс bcj_).
+func (h Handler) BroadcastJobCallbackHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || h.broadcastSender == nil {
+		return
+	}
+	cb := update.CallbackQuery
+	adminID := cb.From.ID
+	msg := cb.Message.Message
+	if !staff.Can(adminID, staff.PermBroadcasts) || msg == nil {
+		return
+	}
+	lang := cb.From.LanguageCode
+
+	if cb.Data == CallbackBroadcastJobs {
+		h.renderBroadcastJobs(ctx, b, msg, lang)
+		return
+	}
+
+	var prefix string
+	for _, p := range []string{CallbackBroadcastJobViewPrefix, CallbackBroadcastJobPausePrefix, CallbackBroadcastJobResumePrefix, CallbackBroadcastJobCancelPrefix} {
+		if strings.HasPrefix(cb.Data, p) {
+			prefix = p
+			break
+		}
+	}
+	if prefix == "" {
+		return
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(cb.Data, prefix), 10, 64)
+	if err != nil || id <= 0 {
+		return
+	}
+
+	var (
+		job    *database.BroadcastJob
+		action string
+	)
+	switch prefix {
+	case CallbackBroadcastJobViewPrefix:
+		job, err = h.broadcastSender.Get(ctx, id)
+	case CallbackBroadcastJobPausePrefix:
+		job, err = h.broadcastSender.Pause(ctx, id)
+		action = audit.ActionBroadcastPause
+	case CallbackBroadcastJobResumePrefix:
+		job, err = h.broadcastSender.Resume(ctx, id)
+		action = audit.ActionBroadcastResume
+	case CallbackBroadcastJobCancelPrefix:
+		job, err = h.broadcastSender.Cancel(ctx, id)
+		action = audit.ActionBroadcastCancel
+	}
+	if errors.Is(err, database.ErrBroadcastJobState) {
+		// Статус уже сменился (рассылка завершилась) — показываем актуальную карточку.
+		action = ""
+		job, err = h.broadcastSender.Get(ctx, id)
+	}
+	if err != nil {
+		slog.Error("broadcast job callback", "job_id", id, "data", cb.Data, "error", err)
+		return
+	}
+	if job == nil {
+		_, _ = editCallbackOriginToHTMLText(ctx, b, msg, h.translation.GetText(lang, "broadcast_job_not_found"), models.ParseModeHTML,
+			models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
+				{{Text: h.translation.GetText(lang, "broadcast_jobs_button"), CallbackData: CallbackBroadcastJobs}},
+			}}, nil)
+		return
+	}
+	if action != "" {
+		audit.Record(ctx, audit.Bot(adminID), audit.Entry{
+			Action: action, TargetType: "broadcast", TargetID: strconv.FormatInt(job.ID, 10),
+			After: map[string]any{"status": job.Status},
+		})
+	}
+
+	text, markup := h.broadcastJobCard(ctx, lang, job)
+	if _, err := editCallbackOriginToHTMLText(ctx, b, msg, text, models.ParseModeHTML, markup, nil); err != nil {
+		slog.Error("broadcast job card edit", "job_id", job.ID, "error", err)
+	}
+}
+
+// renderBroadcastJobs — последние рассылки кнопками, новые первыми.
+func (h Handler) renderBroadcastJobs(ctx context.Context, b *bot.Bot, msg *models.Message, lang string) {
+	jobs, _, err := h.broadcastSender.List(ctx, broadcastJobsListLimit, 0)
+	if err != nil {
+		slog.Error("broadcast jobs list", "error", err)
+		return
+	}
+	text := h.translation.GetText(lang, "broadcast_jobs_title")
+	if len(jobs) == 0 {
+		text = h.translation.GetText(lang, "broadcast_jobs_empty")
+	}
+	var rows [][]models.InlineKeyboardButton
+	for i := range jobs {
+		j := &jobs[i]
+		label := fmt.Sprintf("#%d · %s · %d/%d", j.ID, h.broadcastJobStatusText(lang, j.Status), j.SentCount, j.TotalCount)
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: label, CallbackData: CallbackBroadcastJobViewPrefix + strconv.FormatInt(j.ID, 10)},
+		})
+	}
+	rows = append(rows, []models.InlineKeyboardButton{
+		h.translation.WithButton(lang, "back_button", models.InlineKeyboardButton{CallbackData: CallbackBroadcastBackAudience}),
+	})
+	if _, err := editCallbackOriginToHTMLText(ctx, b, msg, text, models.ParseModeHTML, models.InlineKeyboardMarkup{InlineKeyboard: rows}, nil); err != nil {
+		slog.Error("broadcast jobs list edit", "error", err)
+	}
+}
@@ -23,6 +23,51 @@ const (
 	CallbackAddDevicePayment  = "add_device_payment"
 	CallbackRenewExtraHwid    = "renew_extra_hwid"
 	CallbackPurchaseHistory   = "purchase_history"
+	// Автопродление (ЮKassa): экран и действия; регистрируется префиксом CallbackAutoRenew.
+	CallbackAutoRenew       = "auto_renew"
+	CallbackAutoRenewOn     = "auto_renew_on"
+	CallbackAutoRenewOff    = "auto_renew_off"
+	CallbackAutoRenewForget = "auto_renew_del"
+	// Подписки Telegram Stars: экран (stars_sub), отмена и возобновление продления (+ id подписки);
+	// регистрируется префиксом CallbackStarsSubscriptions.
+	CallbackStarsSubscriptions            = "stars_sub"
+	CallbackStarsSubscriptionCancelPrefix = "stars_sub_c"
+	CallbackStarsSubscriptionResumePrefix = "stars_sub_r"
+	// Подарочные подписки: покупка (gift_menu?tid=&month=), счёт и активация по коду (gift_redeem?c=).
+	CallbackGift       = "gift_menu"
+	CallbackGiftPay    = "gift_pay"
+	CallbackGiftRedeem = "gift_redeem"
+	// Внутренний баланс: экран (точное совпадение), выбор суммы/способа (wallet_topup?amount=) и счёт (wallet_pay).
+	CallbackBalance         = "wallet"
+	CallbackBalanceTopUp    = "wallet_topup"
+	CallbackBalanceTopUpPay = "wallet_pay"
+	// Партнёрская программа: кабинет партнёра (точное совпадение) и запрос выплаты (partner_po?m=balance|manual).
+	CallbackPartner       = "partner"
+	CallbackPartnerPayout = "partner_po"
+	// Семейный тариф: раздел (точное совпадение) и действия с общим префиксом CallbackFamilyAction —
+	// участник (fam_mem?c=), его устройства (fam_dev?c=&n=), исключение, новая ссылка, вступление (fam_join?c=) и выход.
+	CallbackFamily          = "family"
+	CallbackFamilyAction    = "fam_"
+	CallbackFamilyMember    = "fam_mem"
+	CallbackFamilyDevices   = "fam_dev"
+	CallbackFamilyRemoveAsk = "fam_rma"
+	CallbackFamilyRemove    = "fam_rmv"
+	CallbackFamilyInvite    = "fam_inv"
+	CallbackFamilyJoin      = "fam_join"
+	CallbackFamilyLeaveAsk  = "fam_lva"
+	CallbackFamilyLeave     = "fam_lvy"
+	// Пауза подписки: экран (точное совпадение) и действия с префиксом CallbackPauseAction —
+	// подтверждение (pause_ask?d=), постановка на паузу (pause_go?d=) и возобновление.
+	CallbackPause       = "pause"
+	CallbackPauseAction = "pause_"
+	CallbackPauseAsk    = "pause_ask"
+	CallbackPauseStart  = "pause_go"
+	CallbackPauseResume = "pause_res"
+	// Пакеты трафика: экран (точное совпадение), выбор способа оплаты (tpk_buy?g=) и счёт (tpk_pay?g=&invoiceType=).
+	CallbackTrafficPacks      = "tpk"
+	CallbackTrafficPackAction = "tpk_"
+	CallbackTrafficPackBuy    = "tpk_buy"
+	CallbackTrafficPackPay    = "tpk_pay"
 	CallbackBroadcastConfirm  = "broadcast_confirm"
 	CallbackBroadcastCancel   = "broadcast_cancel"
 	CallbackBroadcastAll           = "broadcast_all"
@@ -48,12 +93,31 @@ const (
 	CallbackBroadcastToggleVPN   = "bc_t_vpn"
 	CallbackBroadcastToggleBuy   = "bc_t_buy"
 	CallbackBroadcastButtonsNext = "bc_next"
+	CallbackBroadcastSchedule    = "bc_sched"
+	// Рассылки-задания: bcj_l — список, bcj_<действие>_<id> — карточка, пауза, продолжение, отмена.
+	CallbackBroadcastJobs            = "bcj_l"
+	CallbackBroadcastJobPrefix       = "bcj_"
+	CallbackBroadcastJobViewPrefix   = "bcj_v_"
+	CallbackBroadcastJobPausePrefix  = "bcj_p_"
+	CallbackBroadcastJobResumePrefix = "bcj_r_"
+	CallbackBroadcastJobCancelPrefix = "bcj_c_"
 
 	CallbackAdminPanel   = "admin_panel"
 	CallbackAdminBroadcast = "admin_bc"
 	CallbackAdminSync    = "admin_sync"
 	CallbackAdminPromo   = "admin_promo"
 	CallbackAdminTariffs = "admin_tariffs"
+	CallbackAdminGifts   = "admin_gifts"
+	// Очередь выплат партнёрам: список (точное совпадение), ppa{id} — выплачено, ppr{id} — отклонить.
+	CallbackAdminPartnerPayouts      = "admin_payouts"
+	CallbackAdminPayoutApprovePrefix = "ppa"
+	CallbackAdminPayoutRejectPrefix  = "ppr"
+	// Чеки «Мой налог» с ошибкой: список (точное совпадение), mnr{id} — повторить сейчас.
+	CallbackAdminMoynalogReceipts    = "admin_receipts"
+	CallbackAdminMoynalogRetryPrefix = "mnr"
+	// Проведение оплаты остановилось (шаг outbox в failed): pxr{purchaseId} — повторить.
+	// Должен совпадать с кнопкой в payment.notifyAdminOutboxFailure.
+	CallbackAdminPurchaseOutboxRetryPrefix = "pxr"
 
 	// Админ: пользователи и подписки (Bedolaga-style; короткие callback).
 	CallbackAdminUsersSubmenu      = "au_sm"
@@ -121,6 +185,16 @@ const (
 	CallbackAdminUserDescAskPrefix      = "uds"
 	CallbackAdminUserDescClearPrefix    = "udc"
 
+	// Возврат оплаты из истории платежей клиента: rfq подтверждение, rfc выполнить (префикс + id покупки).
+	CallbackAdminRefundAskPrefix     = "rfq"
+	CallbackAdminRefundConfirmPrefix = "rfc"
+	// rfb{purchaseId} — вернуть остаток на внутренний баланс клиента.
+	CallbackAdminRefundBalancePrefix = "rfb"
+
+	// Ставка партнёра из карточки рефералов клиента: apt{customerId} — меню, apu{customerId}_{percent} — сохранить.
+	CallbackAdminUserPartnerMenuPrefix = "apt"
+	CallbackAdminUserPartnerSetPrefix  = "apu"
+
 	CallbackAdminSubsRoot       = "sbr"
 	CallbackAdminSubsListPrefix = "sbl"
 	CallbackAdminSubsExpiring   = "sbe"
@@ -152,6 +226,7 @@ const (
 	CallbackAdminStatsRef     = "as_f"
 	CallbackAdminStatsSummary = "as_m"
 	CallbackAdminStatsFortune = "as_w"
+	CallbackAdminStatsSources = "as_src"
 
 	CallbackAdminInfraRoot   = "ib_r"
 	CallbackAdminInfraNodes  = "ib_n"
@@ -160,7 +235,7 @@ const (
 	CallbackAdminInfraProv   = "ib_p"
 	CallbackAdminInfraToggle = "ibt"
 	CallbackTariffNew    = "tf_new"
-	// Префиксы callback: tf_v?, tf_t?, tf_d?, tf_y?, tf_s?, tf_q?, tf_sc?, tf_sa?, tf_nm?, tf_tt?, tf_td?, tf_tl?, tf_ep?, tf_ds?, tf_ca?, tf_wc?
+	// Префиксы callback: tf_v?, tf_t?, tf_d?, tf_y?, tf_s?, tf_q?, tf_sc?, tf_sa?, tf_nm?, tf_tt?, tf_td?, tf_tl?, tf_ep?, tf_ds?, tf_fm?, tf_ca?, tf_wc?
 
 	CallbackPromoRoot     = "promo_root"
 	CallbackPromoList     = "promo_list"
@@ -183,6 +258,12 @@ const (
 	CallbackPromoEditSubsTariff    = "promo_xts"
 	CallbackPromoEditSubsTariffSet = "promo_xta"
 	CallbackPromoEditDiscPay       = "promo_edp"
+	// Пакеты одноразовых кодов: список, карточка, CSV, выключение (вопрос / подтверждение).
+	CallbackPromoBatchList   = "promo_bl"
+	CallbackPromoBatchCard   = "promo_bk"
+	CallbackPromoBatchExport = "promo_bx"
+	CallbackPromoBatchOffAsk = "promo_bo"
+	CallbackPromoBatchOffYes = "promo_bz"
 	CallbackEnterPromo         = "enter_promo"
 
 	CallbackLegalAccept  = "legal_accept"
@@ -37,7 +37,7 @@ func (h Handler) ConnectCommandHandler(ctx context.Context, b *bot.Bot, update *
 
 	langCode := update.Message.From.LanguageCode
 
-	markup := h.buildConnectInlineMarkup(langCode, customer)
+	markup := h.buildConnectInlineMarkup(ctx, langCode, customer)
 
 	isDisabled := true
 	displayName := buildDisplayName(update.Message.From.FirstName, update.Message.From.LastName, update.Message.From.Username)
@@ -77,7 +77,7 @@ func (h Handler) ConnectCallbackHandler(ctx context.Context, b *bot.Bot, update
 
 	langCode := update.CallbackQuery.From.LanguageCode
 
-	markup := h.buildConnectInlineMarkup(langCode, customer)
+	markup := h.buildConnectInlineMarkup(ctx, langCode, customer)
 
 	isDisabled := true
 	displayName := buildDisplayName(update.CallbackQuery.From.FirstName, update.CallbackQuery.From.LastName, update.CallbackQuery.From.Username)
@@ -89,11 +89,13 @@ func (h Handler) ConnectCallbackHandler(ctx context.Context, b *bot.Bot, update
 	logEditError("Error sending connect message", err)
 }
 
-// buildConnectInlineMarkup — порядок клавиатуры «Мой VPN»: подключить VPN / купить → управление устройствами (только при активной подписке)
+// buildConnectInlineMarkup — порядок клавиатуры «Мой VPN»: подключить VPN / купить → управление устройствами и автопродление
+// (только при активной подписке; автопродление — при YOOKASA_AUTORENEW_ENABLED; пакеты трафика — при TRAFFIC_PACKS_ENABLED
+// и пакетах у тарифа) → «Семья» (владелец семейного тарифа или участник)
 // → статус серверов (SERVER_STATUS_URL) и лояльность (LOYALTY_ENABLED) в одном ряду → история и рефералы → назад.
 // Кнопка «Подключить VPN»: при включённом кабинете WebApp на MiniAppEntryURL; иначе MINI_APP_URL или ссылка подписки.
 // Отдельные кнопки опускаются, если URL не задан или функция выключена.
-func (h Handler) buildConnectInlineMarkup(langCode string, customer *database.Customer) [][]models.InlineKeyboardButton {
+func (h Handler) buildConnectInlineMarkup(ctx context.Context, langCode string, customer *database.Customer) [][]models.InlineKeyboardButton {
 	if cabinetTelegramMinimalismActive() {
 		kb := h.buildCabinetMinimalismCoreRows(langCode, customer)
 		kb = append(kb, []models.InlineKeyboardButton{
@@ -111,11 +113,40 @@ func (h Handler) buildConnectInlineMarkup(langCode string, customer *database.Cu
 		markup = append(markup, []models.InlineKeyboardButton{
 			h.translation.WithButton(langCode, "manage_devices_button", models.InlineKeyboardButton{CallbackData: CallbackManageDevices}),
 		})
+		if config.YookasaAutoRenewEnabled() {
+			markup = append(markup, []models.InlineKeyboardButton{
+				h.translation.WithButton(langCode, "auto_renew_button", models.InlineKeyboardButton{CallbackData: CallbackAutoRenew}),
+			})
+		}
+		if config.IsTelegramStarsEnabled() && config.TelegramStarsSubscriptionEnabled() {
+			markup = append(markup, []models.InlineKeyboardButton{
+				h.translation.WithButton(langCode, "stars_subscriptions_button", models.InlineKeyboardButton{CallbackData: CallbackStarsSubscriptions}),
+			})
+		}
+		if customer.PausedUntil != nil && customer.PausedUntil.After(now) {
+			markup = append(markup, []models.InlineKeyboardButton{
+				h.translation.WithButton(langCode, "pause_resume_button", models.InlineKeyboardButton{CallbackData: CallbackPause}),
+			})
+		} else if config.SubscriptionPauseEnabled() {
+			markup = append(markup, []models.InlineKeyboardButton{
+				h.translation.WithButton(langCode, "pause_button", models.InlineKeyboardButton{CallbackData: CallbackPause}),
+			})
+		}
+		if h.paymentService.HasTrafficPacks(ctx, customer) {
+			markup = append(markup, []models.InlineKeyboardButton{
+				h.translation.WithButton(langCode, "traffic_packs_button", models.InlineKeyboardButton{CallbackData: CallbackTrafficPacks}),
+			})
+		}
 	} else {
 		markup = append(markup, []models.InlineKeyboardButton{
 			h.translation.WithButton(langCode, "buy_button", models.InlineKeyboardButton{CallbackData: CallbackBuy}),
 		})
 	}
+	if h.paymentService.HasFamilyAccess(ctx, customer) {
+		markup = append(markup, []models.InlineKeyboardButton{
+			h.translation.WithButton(langCode, "family_button", models.InlineKeyboardButton{CallbackData: CallbackFamily}),
+		})
+	}
 
 	var statusLoyalty []models.InlineKeyboardButton
 	if config.ServerStatusURL() != "" {
@@ -159,14 +190,14 @@ func (h Handler) buildConnectText(ctx context.Context, customer *database.Custom
 	isActive := customer.ExpireAt != nil && now.Before(*customer.ExpireAt)
 	if !isActive {
 		if h.promoService != nil {
-			pct, untilFirst, expAt, ok, errPD := h.promoService.PendingDiscountForConnectUI(ctx, customer.ID)
+			pct, amount, untilFirst, expAt, ok, errPD := h.promoService.PendingDiscountForConnectUI(ctx, customer.ID)
 			if errPD != nil {
 				slog.Error("pending discount connect ui", "error", errPD)
-			} else if ok && pct > 0 {
+			} else if ok && (pct > 0 || amount > 0) {
 				var sb strings.Builder
 				sb.WriteString(tm.GetText(langCode, "no_subscription"))
 				sb.WriteString("\n\n")
-				sb.WriteString(fmt.Sprintf(tm.GetText(langCode, "vpn_pending_discount_line"), pct))
+				sb.WriteString(pendingDiscountLine(langCode, pct, amount))
 				sb.WriteString("\n")
 				if untilFirst {
 					sb.WriteString(tm.GetText(langCode, "vpn_pending_discount_until_first"))
@@ -190,7 +221,9 @@ func (h Handler) buildConnectText(ctx context.Context, customer *database.Custom
 
 	info.WriteString(fmt.Sprintf(tm.GetText(langCode, "vpn_username"), escapeHTML(name)))
 	info.WriteString("\n")
-	if isActive {
+	if customer.PausedUntil != nil && customer.PausedUntil.After(now) {
+		info.WriteString(fmt.Sprintf(tm.GetText(langCode, "vpn_status_paused"), customer.PausedUntil.Format("02.01.2006")))
+	} else if isActive {
 		info.WriteString(tm.GetText(langCode, "vpn_status_active"))
 	} else {
 		info.WriteString(tm.GetText(langCode, "vpn_status_inactive"))
@@ -277,12 +310,12 @@ func (h Handler) buildConnectText(ctx context.Context, customer *database.Custom
 	}
 
 	if h.promoService != nil {
-		pct, untilFirst, expAt, ok, errPD := h.promoService.PendingDiscountForConnectUI(ctx, customer.ID)
+		pct, amount, untilFirst, expAt, ok, errPD := h.promoService.PendingDiscountForConnectUI(ctx, customer.ID)
 		if errPD != nil {
 			slog.Error("pending discount connect ui", "error", errPD)
-		} else if ok && pct > 0 {
+		} else if ok && (pct > 0 || amount > 0) {
 			info.WriteString("\n\n")
-			info.WriteString(fmt.Sprintf(tm.GetText(langCode, "vpn_pending_discount_line"), pct))
+			info.WriteString(pendingDiscountLine(langCode, pct, amount))
 			info.WriteString("\n")
 			if untilFirst {
 				info.WriteString(tm.GetText(langCode, "vpn_pending_discount_until_first"))
@@ -316,6 +349,15 @@ func buildDisplayName(firstName, lastName, username string) string {
 	return strings.TrimSpace(strings.TrimPrefix(username, "@"))
 }
 
+// pendingDiscountLine — строка об активной скидке промокода: процент или фиксированная сумма в ₽.
+func pendingDiscountLine(lang string, pct, amount int) string {
+	tm := translation.GetInstance()
+	if amount > 0 {
+		return fmt.Sprintf(tm.GetText(lang, "vpn_pending_discount_amount_line"), amount)
+	}
+	return fmt.Sprintf(tm.GetText(lang, "vpn_pending_discount_line"), pct)
+}
+
 func formatDiscountTimeLeft(lang string, expiresAt, now time.Time) string {
 	if !expiresAt.After(now) {
 		return ""
@@ -0,0 +1,349 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/payment"
+	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/utils"
+)
+
+// FamilyCallbackHandler — раздел «Семья»: у владельца семейного тарифа — участники, пул устройств и ссылка-приглашение,
+// у участника — чья это семья и выход из неё.
+func (h Handler) FamilyCallbackHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || update.CallbackQuery.Message.Message == nil {
+		return
+	}
+	customer := h.callbackCustomer(ctx, update)
+	if customer == nil {
+		return
+	}
+	h.renderFamily(ctx, b, update, update.CallbackQuery.From.LanguageCode, customer, "")
+}
+
+// FamilyActionCallbackHandler — действия раздела «Семья» (префикс fam_): карточка участника (fam_mem?c=),
+// его устройства (fam_dev?c=&n=), исключение (fam_rma / fam_rmv), новая ссылка (fam_inv),
+// вступление по коду (fam_join?c=) и выход (fam_lva / fam_lvy).
+func (h Handler) FamilyActionCallbackHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || update.CallbackQuery.Message.Message == nil {
+		return
+	}
+	langCode := update.CallbackQuery.From.LanguageCode
+	customer := h.callbackCustomer(ctx, update)
+	if customer == nil {
+		return
+	}
+	action, _, _ := strings.Cut(update.CallbackQuery.Data, "?")
+	q := parseCallbackData(update.CallbackQuery.Data)
+	memberID := parseInt64Safe(q["c"])
+
+	switch action {
+	case CallbackFamilyMember:
+		h.renderFamilyMember(ctx, b, update, langCode, customer, memberID, "")
+	case CallbackFamilyDevices:
+		err := h.paymentService.SetFamilyMemberDevices(ctx, customer, memberID, parseIntSafe(q["n"]))
+		banner := ""
+		if err != nil {
+			banner = h.familyErrorText(langCode, err)
+		}
+		h.renderFamilyMember(ctx, b, update, langCode, customer, memberID, banner)
+	case CallbackFamilyRemoveAsk:
+		overview, err := h.paymentService.Family(ctx, customer)
+		if err != nil {
+			h.renderFamily(ctx, b, update, langCode, customer, h.familyErrorText(langCode, err))
+			return
+		}
+		view := findFamilyMember(overview, memberID)
+		if view == nil {
+			h.renderFamily(ctx, b, update, langCode, customer, h.familyErrorText(langCode, payment.ErrFamilyMemberNotFound))
+			return
+		}
+		_, err = editCallbackOriginToHTMLText(ctx, b, update.CallbackQuery.Message.Message,
+			fmt.Sprintf(h.translation.GetText(langCode, "family_remove_confirm"), payment.FamilyMemberLabel(view.Customer)),
+			models.ParseModeHTML, models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{{
+				h.translation.WithButton(langCode, "family_remove_yes_button", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s?c=%d", CallbackFamilyRemove, memberID)}),
+				h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s?c=%d", CallbackFamilyMember, memberID)}),
+			}}}, nil)
+		logEditError("Error sending family remove confirm", err)
+	case CallbackFamilyRemove:
+		banner := h.translation.GetText(langCode, "family_removed_done")
+		if err := h.paymentService.RemoveFamilyMember(ctx, customer, memberID); err != nil {
+			banner = h.familyErrorText(langCode, err)
+		}
+		h.renderFamily(ctx, b, update, langCode, customer, banner)
+	case CallbackFamilyInvite:
+		banner := h.translation.GetText(langCode, "family_invite_regenerated")
+		if _, err := h.paymentService.RegenerateFamilyInvite(ctx, customer); err != nil {
+			banner = h.familyErrorText(langCode, err)
+		}
+		h.renderFamily(ctx, b, update, langCode, customer, banner)
+	case CallbackFamilyJoin:
+		h.joinFamily(ctx, b, update, langCode, customer, q["c"])
+	case CallbackFamilyLeaveAsk:
+		_, err := editCallbackOriginToHTMLText(ctx, b, update.CallbackQuery.Message.Message, h.translation.GetText(langCode, "family_leave_confirm"),
+			models.ParseModeHTML, models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{{
+				h.translation.WithButton(langCode, "family_leave_yes_button", models.InlineKeyboardButton{CallbackData: CallbackFamilyLeave}),
+				h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackFamily}),
+			}}}, nil)
+		logEditError("Error sending family leave confirm", err)
+	case CallbackFamilyLeave:
+		text := h.translation.GetText(langCode, "family_left_done")
+		if err := h.paymentService.LeaveFamily(ctx, customer); err != nil {
+			text = h.familyErrorText(langCode, err)
+		}
+		_, err := editCallbackOriginToHTMLText(ctx, b, update.CallbackQuery.Message.Message, text, models.ParseModeHTML, models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{{
+				h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackStart}),
+			}},
+		}, nil)
+		logEditError("Error sending family leave message", err)
+	}
+}
+
+func (h Handler) callbackCustomer(ctx context.Context, update *models.Update) *database.Customer {
+	customer, err := h.customerRepository.FindByTelegramId(ctx, update.CallbackQuery.From.ID)
+	if err != nil {
+		slog.Error("Error finding customer", "error", err)
+		return nil
+	}
+	if customer == nil {
+		slog.Error("customer not exist", "telegramId", utils.MaskHalfInt64(update.CallbackQuery.From.ID))
+	}
+	return customer
+}
+
+func (h Handler) renderFamily(ctx context.Context, b *bot.Bot, update *models.Update, langCode string, customer *database.Customer, banner string) {
+	var sb strings.Builder
+	if banner != "" {
+		sb.WriteString(banner)
+		sb.WriteString("\n\n")
+	}
+	var kb [][]models.InlineKeyboardButton
+
+	membership, err := h.paymentService.Membership(ctx, customer)
+	if err != nil {
+		slog.Error("family: membership", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+		return
+	}
+	if membership != nil {
+		owner := "—"
+		if membership.Owner != nil {
+			owner = payment.FamilyMemberLabel(membership.Owner)
+		}
+		expire := "—"
+		if customer.ExpireAt != nil {
+			expire = customer.ExpireAt.Format("02.01.2006")
+		}
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(langCode, "family_member_text"), owner, membership.Member.DeviceLimit, expire))
+		kb = append(kb, []models.InlineKeyboardButton{
+			h.translation.WithButton(langCode, "family_leave_button", models.InlineKeyboardButton{CallbackData: CallbackFamilyLeaveAsk}),
+		})
+	} else {
+		overview, err := h.paymentService.Family(ctx, customer)
+		switch {
+		case errors.Is(err, payment.ErrFamilyNotAvailable), errors.Is(err, payment.ErrFamilyDisabled):
+			sb.WriteString(h.translation.GetText(langCode, "family_not_available"))
+		case err != nil:
+			slog.Error("family: overview", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+			return
+		default:
+			h.writeFamilyOverview(&sb, langCode, overview)
+			for _, m := range overview.Members {
+				if m.Customer == nil {
+					continue
+				}
+				kb = append(kb, []models.InlineKeyboardButton{{
+					Text:         fmt.Sprintf("👤 %s · %d", payment.FamilyMemberLabel(m.Customer), m.DeviceLimit),
+					CallbackData: fmt.Sprintf("%s?c=%d", CallbackFamilyMember, m.CustomerID),
+				}})
+			}
+			if overview.InviteLink != "" && overview.FreeSlots() > 0 {
+				share := "https://t.me/share/url?url=" + url.QueryEscape(overview.InviteLink) + "&text=" + url.QueryEscape(h.translation.GetText(langCode, "family_share_text"))
+				kb = append(kb, []models.InlineKeyboardButton{
+					h.translation.WithButton(langCode, "family_share_button", models.InlineKeyboardButton{URL: share}),
+				})
+			}
+			kb = append(kb, []models.InlineKeyboardButton{
+				h.translation.WithButton(langCode, "family_invite_regenerate_button", models.InlineKeyboardButton{CallbackData: CallbackFamilyInvite}),
+			})
+		}
+	}
+	kb = append(kb, []models.InlineKeyboardButton{
+		h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackConnect}),
+	})
+	_, err = editCallbackOriginToHTMLText(ctx, b, update.CallbackQuery.Message.Message, sb.String(), models.ParseModeHTML, models.InlineKeyboardMarkup{
+		InlineKeyboard: kb,
+	}, nil)
+	logEditError("Error sending family message", err)
+}
+
+func (h Handler) writeFamilyOverview(sb *strings.Builder, langCode string, o *payment.FamilyOverview) {
+	sb.WriteString(fmt.Sprintf(h.translation.GetText(langCode, "family_owner_text"),
+		displayTariffName(o.Tariff), o.ExpireAt.Format("02.01.2006"), o.DevicePool, o.OwnerDevices, len(o.Members), o.MaxMembers()))
+	sb.WriteString("\n\n")
+	if len(o.Members) == 0 {
+		sb.WriteString(h.translation.GetText(langCode, "family_owner_no_members"))
+	}
+	for _, m := range o.Members {
+		label := fmt.Sprintf("ID %d", m.CustomerID)
+		if m.Customer != nil {
+			label = payment.FamilyMemberLabel(m.Customer)
+		}
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(langCode, "family_owner_member_line"), label, m.DeviceLimit))
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+	if o.FreeSlots() == 0 {
+		sb.WriteString(h.translation.GetText(langCode, "family_owner_full"))
+		return
+	}
+	invite := o.InviteLink
+	if invite == "" {
+		invite = payment.FamilyDeepLinkPrefix + o.Group.InviteCode
+	}
+	sb.WriteString(fmt.Sprintf(h.translation.GetText(langCode, "family_owner_invite"), invite))
+}
+
+// renderFamilyMember — карточка участника для владельца: устройства ± и исключение.
+func (h Handler) renderFamilyMember(ctx context.Context, b *bot.Bot, update *models.Update, langCode string, owner *database.Customer, memberID int64, banner string) {
+	overview, err := h.paymentService.Family(ctx, owner)
+	if err != nil {
+		h.renderFamily(ctx, b, update, langCode, owner, h.familyErrorText(langCode, err))
+		return
+	}
+	view := findFamilyMember(overview, memberID)
+	if view == nil {
+		h.renderFamily(ctx, b, update, langCode, owner, h.familyErrorText(langCode, payment.ErrFamilyMemberNotFound))
+		return
+	}
+	text := fmt.Sprintf(h.translation.GetText(langCode, "family_member_card"),
+		payment.FamilyMemberLabel(view.Customer), view.DeviceLimit, overview.OwnerDevices)
+	if banner != "" {
+		text = banner + "\n\n" + text
+	}
+	var devices []models.InlineKeyboardButton
+	if view.DeviceLimit > 1 {
+		devices = append(devices, models.InlineKeyboardButton{
+			Text:         "➖",
+			CallbackData: fmt.Sprintf("%s?c=%d&n=%d", CallbackFamilyDevices, memberID, view.DeviceLimit-1),
+		})
+	}
+	if overview.OwnerDevices > 1 {
+		devices = append(devices, models.InlineKeyboardButton{
+			Text:         "➕",
+			CallbackData: fmt.Sprintf("%s?c=%d&n=%d", CallbackFamilyDevices, memberID, view.DeviceLimit+1),
+		})
+	}
+	var kb [][]models.InlineKeyboardButton
+	if len(devices) > 0 {
+		kb = append(kb, devices)
+	}
+	kb = append(kb,
+		[]models.InlineKeyboardButton{
+			h.translation.WithButton(langCode, "family_remove_button", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s?c=%d", CallbackFamilyRemoveAsk, memberID)}),
+		},
+		[]models.InlineKeyboardButton{
+			h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackFamily}),
+		},
+	)
+	_, err = editCallbackOriginToHTMLText(ctx, b, update.CallbackQuery.Message.Message, text, models.ParseModeHTML, models.InlineKeyboardMarkup{
+		InlineKeyboard: kb,
+	}, nil)
+	logEditError("Error sending family member message", err)
+}
+
+func findFamilyMember(o *payment.FamilyOverview, customerID int64) *payment.FamilyMemberView {
+	for i := range o.Members {
+		if o.Members[i].CustomerID == customerID && o.Members[i].Customer != nil {
+			return &o.Members[i]
+		}
+	}
+	return nil
+}
+
+func (h Handler) joinFamily(ctx context.Context, b *bot.Bot, update *models.Update, langCode string, customer *database.Customer, code string) {
+	ctx = context.WithValue(ctx, remnawave.CtxKeyUsername, update.CallbackQuery.From.Username)
+	membership, err := h.paymentService.JoinFamily(ctx, code, customer)
+	var text string
+	var kb [][]models.InlineKeyboardButton
+	if err == nil {
+		text = fmt.Sprintf(h.translation.GetText(langCode, "family_join_success"), payment.FamilyMemberLabel(membership.Owner), membership.Member.DeviceLimit)
+		kb = append(kb, h.resolveConnectButton(langCode))
+	} else {
+		text = h.familyErrorText(langCode, err)
+	}
+	kb = append(kb, []models.InlineKeyboardButton{
+		h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackStart}),
+	})
+	_, err = editCallbackOriginToHTMLText(ctx, b, update.CallbackQuery.Message.Message, text, models.ParseModeHTML, models.InlineKeyboardMarkup{
+		InlineKeyboard: kb,
+	}, nil)
+	logEditError("Error sending family join message", err)
+}
+
+// familyErrorText — текст ошибки семейного раздела; неожиданные ошибки логируются.
+func (h Handler) familyErrorText(langCode string, err error) string {
+	switch {
+	case errors.Is(err, payment.ErrFamilyInviteNotFound), errors.Is(err, payment.ErrFamilyDisabled):
+		return h.translation.GetText(langCode, "family_invite_not_found")
+	case errors.Is(err, payment.ErrFamilyNotAvailable):
+		return h.translation.GetText(langCode, "family_not_available")
+	case errors.Is(err, payment.ErrFamilyOwnInvite):
+		return h.translation.GetText(langCode, "family_join_own")
+	case errors.Is(err, payment.ErrFamilyHasSubscription):
+		return h.translation.GetText(langCode, "family_join_has_subscription")
+	case errors.Is(err, payment.ErrFamilyAlreadyMember):
+		return h.translation.GetText(langCode, "family_join_already_member")
+	case errors.Is(err, payment.ErrFamilyFull):
+		return h.translation.GetText(langCode, "family_full")
+	case errors.Is(err, payment.ErrFamilyDevicePool):
+		return h.translation.GetText(langCode, "family_device_pool_exhausted")
+	case errors.Is(err, payment.ErrFamilyMemberNotFound):
+		return h.translation.GetText(langCode, "family_member_not_found")
+	default:
+		slog.Error("family: action failed", "error", err)
+		return h.translation.GetText(langCode, "family_action_failed")
+	}
+}
+
+// sendFamilyPreview — ответ на /start fam_<код>: чья семья, тариф и кнопка вступления.
+func (h Handler) sendFamilyPreview(ctx context.Context, b *bot.Bot, chatID int64, langCode, code string) {
+	owner, tariff, err := h.paymentService.FamilyInvite(ctx, code)
+	params := &bot.SendMessageParams{
+		ChatID:    chatID,
+		ParseMode: models.ParseModeHTML,
+	}
+	switch {
+	case err == nil:
+		params.Text = fmt.Sprintf(h.translation.GetText(langCode, "family_invite_preview"),
+			payment.FamilyMemberLabel(owner), displayTariffName(tariff), owner.ExpireAt.Format("02.01.2006"))
+		params.ReplyMarkup = models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{{
+			h.translation.WithButton(langCode, "family_join_button", models.InlineKeyboardButton{
+				CallbackData: fmt.Sprintf("%s?c=%s", CallbackFamilyJoin, payment.NormalizeFamilyInviteCode(code)),
+			}),
+		}}}
+	default:
+		params.Text = h.familyErrorText(langCode, err)
+	}
+	if _, err := b.SendMessage(ctx, params); err != nil {
+		slog.Error("family: send preview", "error", err)
+	}
+}
+
+// startFamilyCode — код приглашения из «/start fam_<код>»; пусто, если параметр другой.
+func startFamilyCode(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) < 2 || !strings.HasPrefix(fields[1], payment.FamilyDeepLinkPrefix) {
+		return ""
+	}
+	return payment.NormalizeFamilyInviteCode(fields[1])
+}
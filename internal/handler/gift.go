@@ -0,0 +1,301 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/payment"
+	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/utils"
+)
+
+// GiftCallbackHandler — покупка подарка: тариф (режим tariffs) → период → способ оплаты.
+// Шаги кодируются в gift_menu?tid=&month=; оплата — CallbackGiftPay.
+func (h Handler) GiftCallbackHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || update.CallbackQuery.Message.Message == nil {
+		return
+	}
+	langCode := update.CallbackQuery.From.LanguageCode
+	if !config.GiftsEnabled() {
+		return
+	}
+	q := parseCallbackData(update.CallbackQuery.Data)
+	tid := parseInt64Safe(q["tid"])
+	month := parseIntSafe(q["month"])
+	tariffsMode := config.SalesMode() == "tariffs" && h.tariffRepository != nil
+
+	if tariffsMode && tid <= 0 {
+		tariffs, err := h.tariffRepository.ListActive(ctx)
+		if err != nil {
+			slog.Error("gift: list tariffs", "error", err)
+			return
+		}
+		if len(tariffs) == 1 {
+			h.renderGiftMonths(ctx, b, update, langCode, tariffs[0].ID, CallbackStart)
+			return
+		}
+		var rows [][]models.InlineKeyboardButton
+		for _, t := range tariffs {
+			label := t.Slug
+			if t.Name != nil && strings.TrimSpace(*t.Name) != "" {
+				label = strings.TrimSpace(*t.Name)
+			}
+			rows = append(rows, []models.InlineKeyboardButton{
+				{Text: label, CallbackData: fmt.Sprintf("%s?tid=%d", CallbackGift, t.ID)},
+			})
+		}
+		rows = append(rows, []models.InlineKeyboardButton{
+			h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackStart}),
+		})
+		_, err = editCallbackOriginToHTMLText(ctx, b, update.CallbackQuery.Message.Message, h.translation.GetText(langCode, "gift_choose_tariff"), models.ParseModeHTML, models.InlineKeyboardMarkup{
+			InlineKeyboard: rows,
+		}, nil)
+		logEditError("Error sending gift tariffs message", err)
+		return
+	}
+
+	if month <= 0 {
+		back := CallbackStart
+		if tariffsMode {
+			back = CallbackGift
+		}
+		h.renderGiftMonths(ctx, b, update, langCode, tid, back)
+		return
+	}
+
+	back := CallbackGift
+	if tariffsMode {
+		back = fmt.Sprintf("%s?tid=%d", CallbackGift, tid)
+	}
+	var keyboard [][]models.InlineKeyboardButton
+	for _, p := range h.paymentService.Providers().Enabled() {
+		info := p.Info()
+		// Внешняя оплата (Tribute) не создаёт покупку в боте — подарок по ней не выпустить.
+		if info.ExternalURL != "" {
+			continue
+		}
+		cb := fmt.Sprintf("%s?month=%d&invoiceType=%s", CallbackGiftPay, month, info.InvoiceType)
+		if tariffsMode {
+			cb = fmt.Sprintf("%s?tid=%d&month=%d&invoiceType=%s", CallbackGiftPay, tid, month, info.InvoiceType)
+		}
+		keyboard = append(keyboard, []models.InlineKeyboardButton{
+			h.translation.WithButton(langCode, info.ButtonKey, models.InlineKeyboardButton{CallbackData: cb}),
+		})
+	}
+	keyboard = append(keyboard, []models.InlineKeyboardButton{
+		h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: back}),
+	})
+	text := fmt.Sprintf(h.translation.GetText(langCode, "gift_choose_method"), month, config.GiftCodeTTLDays())
+	_, err := editCallbackOriginToHTMLText(ctx, b, update.CallbackQuery.Message.Message, text, models.ParseModeHTML, models.InlineKeyboardMarkup{
+		InlineKeyboard: keyboard,
+	}, nil)
+	logEditError("Error sending gift methods message", err)
+}
+
+// renderGiftMonths — кнопки периодов подарка: цены тарифа из БД или classic PRICE_*.
+func (h Handler) renderGiftMonths(ctx context.Context, b *bot.Bot, update *models.Update, langCode string, tid int64, backCallback string) {
+	type option struct{ months, amount int }
+	var options []option
+	if tid > 0 {
+		prices, err := h.tariffRepository.ListPricesForTariff(ctx, tid)
+		if err != nil {
+			slog.Error("gift: list tariff prices", "error", err)
+			return
+		}
+		for _, p := range prices {
+			if p.AmountRub > 0 {
+				options = append(options, option{p.Months, p.AmountRub})
+			}
+		}
+	} else {
+		for _, m := range []int{1, 3, 6, 12} {
+			if a := config.Price(m); a > 0 {
+				options = append(options, option{m, a})
+			}
+		}
+	}
+	price1Rub := 0
+	for _, o := range options {
+		if o.months == 1 {
+			price1Rub = o.amount
+		}
+	}
+
+	var priceButtons []models.InlineKeyboardButton
+	for _, o := range options {
+		cb := fmt.Sprintf("%s?month=%d", CallbackGift, o.months)
+		if tid > 0 {
+			cb = fmt.Sprintf("%s?tid=%d&month=%d", CallbackGift, tid, o.months)
+		}
+		priceButtons = append(priceButtons, h.monthPriceButton(langCode, monthButtonKey(o.months), o.amount, o.months, price1Rub, models.InlineKeyboardButton{CallbackData: cb}))
+	}
+	keyboard := [][]models.InlineKeyboardButton{}
+	if len(priceButtons) == 4 {
+		keyboard = append(keyboard, priceButtons[:2])
+		keyboard = append(keyboard, priceButtons[2:])
+	} else if len(priceButtons) > 0 {
+		keyboard = append(keyboard, priceButtons)
+	}
+	keyboard = append(keyboard, []models.InlineKeyboardButton{
+		h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: backCallback}),
+	})
+	err := SendOrEditAfterInlineCallback(ctx, b, update, h.translation.GetText(langCode, "gift_choose_period"), models.ParseModeHTML, models.InlineKeyboardMarkup{
+		InlineKeyboard: keyboard,
+	}, nil)
+	logEditError("Error sending gift months message", err)
+}
+
+// GiftPayCallbackHandler выставляет счёт на подарок; сумму считает PaymentService.GiftPrice.
+func (h Handler) GiftPayCallbackHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || update.CallbackQuery.Message.Message == nil {
+		return
+	}
+	callback := update.CallbackQuery.Message.Message
+	langCode := update.CallbackQuery.From.LanguageCode
+	q := parseCallbackData(update.CallbackQuery.Data)
+	month := parseIntSafe(q["month"])
+	invoiceType := database.InvoiceType(q["invoiceType"])
+	var tariffID *int64
+	if tid := parseInt64Safe(q["tid"]); tid > 0 {
+		tariffID = &tid
+	}
+	back := fmt.Sprintf("%s?month=%d", CallbackGift, month)
+	if tariffID != nil {
+		back = fmt.Sprintf("%s?tid=%d&month=%d", CallbackGift, *tariffID, month)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	customer, err := h.customerRepository.FindByTelegramId(ctx, callback.Chat.ID)
+	if err != nil {
+		slog.Error("Error finding customer", "error", err)
+		return
+	}
+	if customer == nil {
+		slog.Error("customer not exist", "chatID", utils.MaskHalfInt64(callback.Chat.ID))
+		return
+	}
+	if invoiceType == database.InvoiceTypeTelegram && !h.starsAllowedForChat(ctx, callback.Chat.ID) {
+		return
+	}
+
+	ctxWithUsername := context.WithValue(ctx, remnawave.CtxKeyUsername, update.CallbackQuery.From.Username)
+	paymentURL, purchaseId, err := h.paymentService.CreateGiftPurchase(ctxWithUsername, customer, invoiceType, month, tariffID)
+	if h.handleBalanceCheckout(ctx, b, update, langCode, invoiceType, err, back) {
+		return
+	}
+	if err != nil {
+		slog.Error("gift: create purchase", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+		h.notifyPaymentProviderUnavailable(ctx, b, update, langCode, back)
+		return
+	}
+
+	message, err := editCallbackOriginToHTMLText(ctx, b, callback, fmt.Sprintf(h.translation.GetText(langCode, "gift_pay_text"), month), models.ParseModeHTML, models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				h.translation.WithButton(langCode, "pay_button", models.InlineKeyboardButton{URL: paymentURL}),
+				h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: back}),
+			},
+		},
+	}, nil)
+	if err != nil {
+		logEditError("Error sending gift payment message", err)
+		return
+	}
+	h.cache.Set(purchaseId, message.ID)
+}
+
+// sendGiftPreview — ответ на /start gift_<код>: что за подарок и кнопка активации.
+func (h Handler) sendGiftPreview(ctx context.Context, b *bot.Bot, chatID int64, langCode, code string) {
+	gift, err := h.paymentService.FindGift(ctx, code)
+	if err != nil && !errors.Is(err, payment.ErrGiftsDisabled) {
+		slog.Error("gift: find by code", "error", err)
+		return
+	}
+	var text string
+	var kb [][]models.InlineKeyboardButton
+	switch {
+	case gift == nil:
+		text = h.translation.GetText(langCode, "gift_not_found")
+	default:
+		switch gift.EffectiveStatus(time.Now().UTC()) {
+		case database.GiftStatusActive:
+			text = fmt.Sprintf(h.translation.GetText(langCode, "gift_preview"), gift.Months, gift.ExpiresAt.Format("02.01.2006"))
+			kb = append(kb, []models.InlineKeyboardButton{
+				h.translation.WithButton(langCode, "gift_redeem_button", models.InlineKeyboardButton{
+					CallbackData: fmt.Sprintf("%s?c=%s", CallbackGiftRedeem, gift.Code),
+				}),
+			})
+		case database.GiftStatusRedeemed:
+			text = h.translation.GetText(langCode, "gift_already_redeemed")
+		default:
+			text = h.translation.GetText(langCode, "gift_expired")
+		}
+	}
+	params := &bot.SendMessageParams{
+		ChatID:    chatID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	}
+	if len(kb) > 0 {
+		params.ReplyMarkup = models.InlineKeyboardMarkup{InlineKeyboard: kb}
+	}
+	if _, err := b.SendMessage(ctx, params); err != nil {
+		slog.Error("gift: send preview", "error", err)
+	}
+}
+
+// GiftRedeemCallbackHandler активирует подарок (gift_redeem?c=<код>) на текущего пользователя.
+func (h Handler) GiftRedeemCallbackHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || update.CallbackQuery.Message.Message == nil {
+		return
+	}
+	langCode := update.CallbackQuery.From.LanguageCode
+	code := parseCallbackData(update.CallbackQuery.Data)["c"]
+	customer, err := h.customerRepository.FindByTelegramId(ctx, update.CallbackQuery.From.ID)
+	if err != nil {
+		slog.Error("Error finding customer", "error", err)
+		return
+	}
+	if customer == nil {
+		slog.Error("customer not exist", "telegramId", utils.MaskHalfInt64(update.CallbackQuery.From.ID))
+		return
+	}
+
+	ctx = context.WithValue(ctx, remnawave.CtxKeyUsername, update.CallbackQuery.From.Username)
+	gift, err := h.paymentService.RedeemGift(ctx, code, customer)
+	var text string
+	var kb [][]models.InlineKeyboardButton
+	switch {
+	case err == nil:
+		text = fmt.Sprintf(h.translation.GetText(langCode, "gift_redeem_success"), gift.Months)
+		kb = append(kb, h.resolveConnectButton(langCode))
+	case errors.Is(err, payment.ErrGiftNotFound), errors.Is(err, payment.ErrGiftsDisabled):
+		text = h.translation.GetText(langCode, "gift_not_found")
+	case errors.Is(err, payment.ErrGiftAlreadyRedeemed):
+		text = h.translation.GetText(langCode, "gift_already_redeemed")
+	case errors.Is(err, payment.ErrGiftExpired):
+		text = h.translation.GetText(langCode, "gift_expired")
+	case errors.Is(err, payment.ErrGiftTariffConflict):
+		text = h.translation.GetText(langCode, "gift_tariff_conflict")
+	default:
+		slog.Error("gift: redeem", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+		text = h.translation.GetText(langCode, "gift_redeem_failed")
+	}
+	kb = append(kb, []models.InlineKeyboardButton{
+		h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackStart}),
+	})
+	_, err = editCallbackOriginToHTMLText(ctx, b, update.CallbackQuery.Message.Message, text, models.ParseModeHTML, models.InlineKeyboardMarkup{
+		InlineKeyboard: kb,
+	}, nil)
+	logEditError("Error sending gift redeem message", err)
+}
@@ -60,6 +60,7 @@ func NewHandler(
 	statsRepository *database.StatsRepository,
 	infraBillingRepository *database.InfraBillingRepository,
 	loyaltyTierRepository *database.LoyaltyTierRepository,
+	broadcastSender *broadcast.Sender,
 ) *Handler {
 	return &Handler{
 		syncService:            syncService,
@@ -78,7 +79,7 @@ func NewHandler(
 		statsRepository:        statsRepository,
 		infraBillingRepository: infraBillingRepository,
 		loyaltyTierRepository:  loyaltyTierRepository,
-		broadcastSender:        broadcast.NewSender(customerRepository, translation),
+		broadcastSender:        broadcastSender,
 	}
 }
 
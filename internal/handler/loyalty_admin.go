@@ -12,8 +12,11 @@ import (
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 
+	"remnawave-tg-shop-bot/internal/audit"
 	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
 	"remnawave-tg-shop-bot/internal/loyalty"
+	"remnawave-tg-shop-bot/internal/staff"
 )
 
 type adminLoyaltyEditPending struct {
@@ -69,7 +72,7 @@ func callbackLoyaltyTierCard(id int64) string {
 
 // AdminLoyaltyRootHandler — корень раздела лояльности в админке.
 func (h Handler) AdminLoyaltyRootHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || !config.LoyaltyEnabled() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || !config.LoyaltyEnabled() {
 		return
 	}
 	cb := update.CallbackQuery
@@ -110,7 +113,7 @@ func (h Handler) AdminLoyaltyRootHandler(ctx context.Context, b *bot.Bot, update
 
 // AdminLoyaltyStatsHandler — распределение клиентов по текущим уровням (интервалы xp_min).
 func (h Handler) AdminLoyaltyStatsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || !config.LoyaltyEnabled() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || !config.LoyaltyEnabled() {
 		return
 	}
 	cb := update.CallbackQuery
@@ -162,7 +165,7 @@ func (h Handler) AdminLoyaltyStatsHandler(ctx context.Context, b *bot.Bot, updat
 
 // AdminLoyaltyRulesHandler — экран «Правила XP» (чтение из env).
 func (h Handler) AdminLoyaltyRulesHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || !config.LoyaltyEnabled() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || !config.LoyaltyEnabled() {
 		return
 	}
 	cb := update.CallbackQuery
@@ -241,7 +244,7 @@ func truncateAdminBtn(s string) string {
 
 // AdminLoyaltyLevelsHandler — список уровней.
 func (h Handler) AdminLoyaltyLevelsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || !config.LoyaltyEnabled() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || !config.LoyaltyEnabled() {
 		return
 	}
 	cb := update.CallbackQuery
@@ -259,7 +262,7 @@ func (h Handler) AdminLoyaltyLevelsHandler(ctx context.Context, b *bot.Bot, upda
 
 // AdminLoyaltyTierCardHandler — карточка уровня.
 func (h Handler) AdminLoyaltyTierCardHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || !config.LoyaltyEnabled() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || !config.LoyaltyEnabled() {
 		return
 	}
 	cb := update.CallbackQuery
@@ -332,7 +335,7 @@ func (h Handler) AdminLoyaltyEditDnAskHandler(ctx context.Context, b *bot.Bot, u
 }
 
 func (h Handler) adminLoyaltyEditAsk(ctx context.Context, b *bot.Bot, update *models.Update, field string) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -370,7 +373,7 @@ func (h Handler) adminLoyaltyEditAsk(ctx context.Context, b *bot.Bot, update *mo
 }
 
 func (h Handler) AdminLoyaltyDelAskHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -396,8 +399,17 @@ func (h Handler) AdminLoyaltyDelAskHandler(ctx context.Context, b *bot.Bot, upda
 	_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: cb.ID})
 }
 
+// recordLoyaltyTierUpdate пишет в журнал изменение уровня лояльности относительно before.
+func (h Handler) recordLoyaltyTierUpdate(ctx context.Context, adminID int64, before *database.LoyaltyTier) {
+	after, _ := h.loyaltyTierRepository.GetByID(ctx, before.ID)
+	audit.Record(ctx, audit.Bot(adminID), audit.Entry{
+		Action: audit.ActionLoyaltyTierUpdate, TargetType: "loyalty_tier", TargetID: strconv.FormatInt(before.ID, 10),
+		Before: audit.Row(before), After: audit.Row(after),
+	})
+}
+
 func (h Handler) AdminLoyaltyDelYesHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -405,6 +417,7 @@ func (h Handler) AdminLoyaltyDelYesHandler(ctx context.Context, b *bot.Bot, upda
 	lang := cb.From.LanguageCode
 	msg := cb.Message.Message
 	if id > 0 {
+		before, _ := h.loyaltyTierRepository.GetByID(ctx, id)
 		if err := h.loyaltyTierRepository.Delete(ctx, id); err != nil {
 			slog.Error("admin loyalty delete", "error", err)
 			_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
@@ -414,6 +427,10 @@ func (h Handler) AdminLoyaltyDelYesHandler(ctx context.Context, b *bot.Bot, upda
 			})
 			return
 		}
+		audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+			Action: audit.ActionLoyaltyTierDelete, TargetType: "loyalty_tier", TargetID: strconv.FormatInt(id, 10),
+			Before: audit.Row(before),
+		})
 	}
 	if err := h.adminLoyaltyLevelsEdit(ctx, b, msg.Chat.ID, int64(msg.ID), lang); err != nil {
 		slog.Error("admin loyalty levels after del", "error", err)
@@ -422,7 +439,7 @@ func (h Handler) AdminLoyaltyDelYesHandler(ctx context.Context, b *bot.Bot, upda
 }
 
 func (h Handler) AdminLoyaltyNewHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || !config.LoyaltyEnabled() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || !config.LoyaltyEnabled() {
 		return
 	}
 	cb := update.CallbackQuery
@@ -450,7 +467,7 @@ func (h Handler) AdminLoyaltyNewHandler(ctx context.Context, b *bot.Bot, update
 }
 
 func (h Handler) AdminLoyaltyRecalcAskHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || !config.LoyaltyEnabled() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || !config.LoyaltyEnabled() {
 		return
 	}
 	cb := update.CallbackQuery
@@ -475,7 +492,7 @@ func (h Handler) AdminLoyaltyRecalcAskHandler(ctx context.Context, b *bot.Bot, u
 }
 
 func (h Handler) AdminLoyaltyRecalcRunHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || !config.LoyaltyEnabled() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || !config.LoyaltyEnabled() {
 		return
 	}
 	cb := update.CallbackQuery
@@ -502,6 +519,9 @@ func (h Handler) AdminLoyaltyRecalcRunHandler(ctx context.Context, b *bot.Bot, u
 		})
 		return
 	}
+	audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+		Action: audit.ActionLoyaltyRecalc, After: map[string]any{"purchases": len(purchases), "customers": len(sums)},
+	})
 	doneText := fmt.Sprintf(h.translation.GetText(lang, "admin_loyalty_recalc_done"),
 		len(purchases), len(sums))
 	kb := [][]models.InlineKeyboardButton{
@@ -522,7 +542,7 @@ func (h Handler) AdminLoyaltyRecalcRunHandler(ctx context.Context, b *bot.Bot, u
 
 // AdminLoyaltyTextHandler — ввод чисел для редактирования и нового уровня.
 func (h Handler) AdminLoyaltyTextHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.Message == nil || update.Message.From.ID != config.GetAdminTelegramId() || !config.LoyaltyEnabled() {
+	if update.Message == nil || !staff.Can(update.Message.From.ID, staff.PermPromos) || !config.LoyaltyEnabled() {
 		return
 	}
 	adminID := update.Message.From.ID
@@ -553,6 +573,7 @@ func (h Handler) AdminLoyaltyTextHandler(ctx context.Context, b *bot.Bot, update
 				slog.Error("admin loyalty update dn", "error", err)
 				return
 			}
+			h.recordLoyaltyTierUpdate(ctx, adminID, tier)
 			adminLoyaltyClear(adminID)
 			msg, err := b.SendMessage(ctx, &bot.SendMessageParams{
 				ChatID:    update.Message.Chat.ID,
@@ -595,6 +616,7 @@ func (h Handler) AdminLoyaltyTextHandler(ctx context.Context, b *bot.Bot, update
 				return
 			}
 		}
+		h.recordLoyaltyTierUpdate(ctx, adminID, tier)
 		adminLoyaltyClear(adminID)
 		msg, err := b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID:    update.Message.Chat.ID,
@@ -650,11 +672,17 @@ func (h Handler) AdminLoyaltyTextHandler(ctx context.Context, b *bot.Bot, update
 				return
 			}
 			nextOrder := mx + 1
-			if _, err := h.loyaltyTierRepository.Insert(ctx, nextOrder, xpMin, pct, nil); err != nil {
+			tierID, err := h.loyaltyTierRepository.Insert(ctx, nextOrder, xpMin, pct, nil)
+			if err != nil {
 				slog.Error("admin loyalty insert", "error", err)
 				adminLoyaltyClear(adminID)
 				return
 			}
+			created, _ := h.loyaltyTierRepository.GetByID(ctx, tierID)
+			audit.Record(ctx, audit.Bot(adminID), audit.Entry{
+				Action: audit.ActionLoyaltyTierCreate, TargetType: "loyalty_tier", TargetID: strconv.FormatInt(tierID, 10),
+				After: audit.Row(created),
+			})
 			adminLoyaltyClear(adminID)
 			msg, err := b.SendMessage(ctx, &bot.SendMessageParams{
 				ChatID:    update.Message.Chat.ID,
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/payment"
+	"remnawave-tg-shop-bot/utils"
+)
+
+// partnerDashboardFor — сводка партнёра или nil, если программа выключена или у клиента нулевая ставка.
+func (h Handler) partnerDashboardFor(ctx context.Context, customer *database.Customer) *payment.PartnerDashboard {
+	if !config.ReferralPartnerEnabled() || customer == nil {
+		return nil
+	}
+	dash, err := h.paymentService.PartnerDashboard(ctx, customer.ID)
+	if err != nil {
+		if !errors.Is(err, payment.ErrPartnerNotPartner) && !errors.Is(err, payment.ErrPartnerDisabled) {
+			slog.Error("partner: dashboard", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+		}
+		return nil
+	}
+	return dash
+}
+
+// PartnerCallbackHandler — кабинет партнёра: ставка, заработано, в холде, к выплате, выплачено.
+func (h Handler) PartnerCallbackHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || update.CallbackQuery.Message.Message == nil {
+		return
+	}
+	langCode := update.CallbackQuery.From.LanguageCode
+	customer, err := h.customerRepository.FindByTelegramId(ctx, update.CallbackQuery.From.ID)
+	if err != nil {
+		slog.Error("Error finding customer", "error", err)
+		return
+	}
+	dash := h.partnerDashboardFor(ctx, customer)
+	if dash == nil {
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(h.translation.GetText(langCode, "partner_title"),
+		payment.FormatPartnerPercent(dash.Percent),
+		payment.FormatBalanceRub(dash.Summary.Earned),
+		payment.FormatBalanceRub(dash.Summary.OnHold),
+		payment.FormatBalanceRub(dash.Available()),
+		payment.FormatBalanceRub(dash.Summary.PayoutPending),
+		payment.FormatBalanceRub(dash.Summary.PaidOut),
+	))
+	sb.WriteString("\n\n")
+	sb.WriteString(fmt.Sprintf(h.translation.GetText(langCode, "partner_terms"), dash.HoldDays, payment.FormatBalanceRub(float64(dash.MinPayout))))
+
+	var kb [][]models.InlineKeyboardButton
+	if dash.CanRequestPayout() {
+		kb = append(kb, []models.InlineKeyboardButton{
+			h.translation.WithButton(langCode, "partner_payout_button", models.InlineKeyboardButton{CallbackData: CallbackPartnerPayout}),
+		})
+	}
+	kb = append(kb, []models.InlineKeyboardButton{
+		h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackReferral}),
+	})
+	_, err = editCallbackOriginToHTMLText(ctx, b, update.CallbackQuery.Message.Message, sb.String(), models.ParseModeHTML, models.InlineKeyboardMarkup{
+		InlineKeyboard: kb,
+	}, nil)
+	logEditError("Error sending partner message", err)
+}
+
+// PartnerPayoutCallbackHandler — запрос выплаты всей доступной суммы: partner_po — выбор способа,
+// partner_po?m=balance|manual — создать запрос в очередь админа.
+func (h Handler) PartnerPayoutCallbackHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || update.CallbackQuery.Message.Message == nil {
+		return
+	}
+	langCode := update.CallbackQuery.From.LanguageCode
+	customer, err := h.customerRepository.FindByTelegramId(ctx, update.CallbackQuery.From.ID)
+	if err != nil {
+		slog.Error("Error finding customer", "error", err)
+		return
+	}
+	dash := h.partnerDashboardFor(ctx, customer)
+	if dash == nil {
+		return
+	}
+	back := []models.InlineKeyboardButton{
+		h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackPartner}),
+	}
+	method := database.PayoutMethod(parseCallbackData(update.CallbackQuery.Data)["m"])
+
+	if method == "" {
+		var kb [][]models.InlineKeyboardButton
+		for _, m := range []database.PayoutMethod{database.PayoutMethodBalance, database.PayoutMethodManual} {
+			if !payment.PayoutMethodAvailable(m) {
+				continue
+			}
+			kb = append(kb, []models.InlineKeyboardButton{
+				h.translation.WithButton(langCode, "partner_payout_method_"+string(m), models.InlineKeyboardButton{
+					CallbackData: fmt.Sprintf("%s?m=%s", CallbackPartnerPayout, m),
+				}),
+			})
+		}
+		kb = append(kb, back)
+		text := fmt.Sprintf(h.translation.GetText(langCode, "partner_payout_choose"), payment.FormatBalanceRub(dash.Available()))
+		_, err = editCallbackOriginToHTMLText(ctx, b, update.CallbackQuery.Message.Message, text, models.ParseModeHTML, models.InlineKeyboardMarkup{
+			InlineKeyboard: kb,
+		}, nil)
+		logEditError("Error sending partner payout methods", err)
+		return
+	}
+
+	var text string
+	payout, err := h.paymentService.RequestPartnerPayout(ctx, customer, 0, method, "")
+	switch {
+	case err == nil:
+		text = fmt.Sprintf(h.translation.GetText(langCode, "partner_payout_requested"), payment.FormatBalanceRub(payout.Amount))
+	case errors.Is(err, database.ErrPayoutPendingExists):
+		text = h.translation.GetText(langCode, "partner_payout_already_pending")
+	case errors.Is(err, payment.ErrPayoutAmount):
+		text = fmt.Sprintf(h.translation.GetText(langCode, "partner_payout_too_small"), payment.FormatBalanceRub(float64(dash.MinPayout)))
+	default:
+		slog.Error("partner: request payout", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+		text = h.translation.GetText(langCode, "partner_payout_failed")
+	}
+	_, err = editCallbackOriginToHTMLText(ctx, b, update.CallbackQuery.Message.Message, text, models.ParseModeHTML, models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{back},
+	}, nil)
+	logEditError("Error sending partner payout result", err)
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/payment"
+	"remnawave-tg-shop-bot/utils"
+)
+
+// PauseCallbackHandler — экран паузы подписки: активная пауза и кнопка возобновления
+// либо выбор длительности новой паузы в пределах настроек.
+func (h Handler) PauseCallbackHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || update.CallbackQuery.Message.Message == nil {
+		return
+	}
+	customer := h.callbackCustomer(ctx, update)
+	if customer == nil {
+		return
+	}
+	h.renderPause(ctx, b, update, update.CallbackQuery.From.LanguageCode, customer, "")
+}
+
+// PauseActionCallbackHandler — действия паузы (префикс pause_): подтверждение (pause_ask?d=),
+// постановка на паузу (pause_go?d=) и досрочное возобновление (pause_res).
+func (h Handler) PauseActionCallbackHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || update.CallbackQuery.Message.Message == nil {
+		return
+	}
+	langCode := update.CallbackQuery.From.LanguageCode
+	customer := h.callbackCustomer(ctx, update)
+	if customer == nil {
+		return
+	}
+	action, _, _ := strings.Cut(update.CallbackQuery.Data, "?")
+	days := parseIntSafe(parseCallbackData(update.CallbackQuery.Data)["d"])
+
+	switch action {
+	case CallbackPauseAsk:
+		_, err := editCallbackOriginToHTMLText(ctx, b, update.CallbackQuery.Message.Message,
+			fmt.Sprintf(h.translation.GetText(langCode, "pause_confirm"), days),
+			models.ParseModeHTML, models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{{
+				h.translation.WithButton(langCode, "pause_confirm_button", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s?d=%d", CallbackPauseStart, days)}),
+				h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackPause}),
+			}}}, nil)
+		logEditError("Error sending pause confirm", err)
+	case CallbackPauseStart:
+		banner := ""
+		if pause, err := h.paymentService.PauseSubscription(ctx, customer, days); err != nil {
+			banner = h.pauseErrorText(langCode, err)
+		} else {
+			banner = fmt.Sprintf(h.translation.GetText(langCode, "pause_started"), pause.ResumeAt.Format("02.01.2006"))
+		}
+		h.renderPause(ctx, b, update, langCode, customer, banner)
+	case CallbackPauseResume:
+		banner := h.translation.GetText(langCode, "pause_resumed")
+		if err := h.paymentService.ResumeSubscription(ctx, customer); err != nil {
+			banner = h.pauseErrorText(langCode, err)
+		}
+		h.renderPause(ctx, b, update, langCode, customer, banner)
+	}
+}
+
+func (h Handler) renderPause(ctx context.Context, b *bot.Bot, update *models.Update, langCode string, customer *database.Customer, banner string) {
+	st, err := h.paymentService.PauseStatus(ctx, customer)
+	if err != nil {
+		slog.Error("pause: status", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+		return
+	}
+	var sb strings.Builder
+	if banner != "" {
+		sb.WriteString(banner)
+		sb.WriteString("\n\n")
+	}
+	var kb [][]models.InlineKeyboardButton
+
+	switch {
+	case st.Active != nil:
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(langCode, "pause_active"),
+			st.Active.ResumeAt.Format("02.01.2006"), daysLeft(st.Active.ResumeAt.Add(st.Active.Remaining()), st.Active.ResumeAt)))
+		kb = append(kb, []models.InlineKeyboardButton{
+			h.translation.WithButton(langCode, "pause_resume_button", models.InlineKeyboardButton{CallbackData: CallbackPauseResume}),
+		})
+	case st.CanPause():
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(langCode, "pause_intro"), st.MinDays, st.MaxDays))
+		if st.Limit > 0 {
+			sb.WriteString("\n")
+			sb.WriteString(fmt.Sprintf(h.translation.GetText(langCode, "pause_limit_line"), st.Limit-st.Used, st.Limit, st.PeriodDays))
+		}
+		var row []models.InlineKeyboardButton
+		for _, d := range payment.PauseDayOptions(st.MinDays, st.MaxDays) {
+			row = append(row, models.InlineKeyboardButton{
+				Text:         fmt.Sprintf(h.translation.GetText(langCode, "pause_days_button"), d),
+				CallbackData: fmt.Sprintf("%s?d=%d", CallbackPauseAsk, d),
+			})
+			if len(row) == 3 {
+				kb = append(kb, row)
+				row = nil
+			}
+		}
+		if len(row) > 0 {
+			kb = append(kb, row)
+		}
+	case banner == "":
+		sb.WriteString(h.pauseErrorText(langCode, st.Reason))
+	}
+	kb = append(kb, []models.InlineKeyboardButton{
+		h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackConnect}),
+	})
+	_, err = editCallbackOriginToHTMLText(ctx, b, update.CallbackQuery.Message.Message, sb.String(), models.ParseModeHTML,
+		models.InlineKeyboardMarkup{InlineKeyboard: kb}, nil)
+	logEditError("Error sending pause screen", err)
+}
+
+func (h Handler) pauseErrorText(langCode string, err error) string {
+	switch {
+	case errors.Is(err, payment.ErrPauseDisabled):
+		return h.translation.GetText(langCode, "pause_disabled")
+	case errors.Is(err, payment.ErrPauseNoSubscription):
+		return h.translation.GetText(langCode, "pause_no_subscription")
+	case errors.Is(err, payment.ErrPauseTooLittleLeft):
+		return h.translation.GetText(langCode, "pause_too_little_left")
+	case errors.Is(err, payment.ErrPauseFamily):
+		return h.translation.GetText(langCode, "pause_family")
+	case errors.Is(err, payment.ErrPauseLimit):
+		return h.translation.GetText(langCode, "pause_limit_reached")
+	case errors.Is(err, payment.ErrPauseDays):
+		return h.translation.GetText(langCode, "pause_days_invalid")
+	case errors.Is(err, payment.ErrPauseAlreadyPaused):
+		return h.translation.GetText(langCode, "pause_already_paused")
+	case errors.Is(err, payment.ErrPauseNotActive):
+		return h.translation.GetText(langCode, "pause_not_active")
+	default:
+		slog.Error("pause: action failed", "error", err)
+		return h.translation.GetText(langCode, "pause_action_failed")
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"remnawave-tg-shop-bot/internal/database"
 	"remnawave-tg-shop-bot/internal/loyalty"
 	"remnawave-tg-shop-bot/internal/payment"
+	"remnawave-tg-shop-bot/internal/promo"
 	"remnawave-tg-shop-bot/internal/remnawave"
 )
 
@@ -297,52 +298,13 @@ func (h Handler) SellCallbackHandler(ctx context.Context, b *bot.Bot, update *mo
 		extraCount = parseIntSafe(extraChoice)
 	}
 
-	if config.IsCryptoPayEnabled() {
-		keyboard = append(keyboard, []models.InlineKeyboardButton{
-			h.translation.WithButton(langCode, "crypto_button", models.InlineKeyboardButton{CallbackData: paymentCallbackQuery(tidStr, month, string(database.InvoiceTypeCrypto), amount, extraCount)}),
-		})
-	}
-
-	if config.IsYookasaEnabled() {
-		keyboard = append(keyboard, []models.InlineKeyboardButton{
-			h.translation.WithButton(langCode, "card_button", models.InlineKeyboardButton{CallbackData: paymentCallbackQuery(tidStr, month, string(database.InvoiceTypeYookasa), amount, extraCount)}),
-		})
-	}
-
-	keyboard = h.appendPlategaPaymentRows(keyboard, langCode, tidStr, month, amount, extraCount)
-
-	if config.IsTelegramStarsEnabled() {
-		shouldShowStarsButton := true
-
-		if config.RequirePaidPurchaseForStars() {
-			customer, err := h.customerRepository.FindByTelegramId(ctx, callback.Chat.ID)
-			if err != nil {
-				slog.Error("Error finding customer for stars check", err)
-				shouldShowStarsButton = false
-			} else if customer != nil {
-				paidPurchase, err := h.purchaseRepository.FindSuccessfulPaidPurchaseByCustomer(ctx, customer.ID)
-				if err != nil {
-					slog.Error("Error checking paid purchase", err)
-					shouldShowStarsButton = false
-				} else if paidPurchase == nil {
-					shouldShowStarsButton = false
-				}
-			} else {
-				shouldShowStarsButton = false
-			}
-		}
-
-		if shouldShowStarsButton {
-			keyboard = append(keyboard, []models.InlineKeyboardButton{
-				h.translation.WithButton(langCode, "stars_button", models.InlineKeyboardButton{CallbackData: paymentCallbackQuery(tidStr, month, string(database.InvoiceTypeTelegram), amount, extraCount)}),
-			})
-		}
-	}
-
-	if config.GetTributeWebHookUrl() != "" {
-		keyboard = append(keyboard, []models.InlineKeyboardButton{
-			h.translation.WithButton(langCode, "tribute_button", models.InlineKeyboardButton{URL: config.GetTributePaymentUrl()}),
-		})
+	keyboard = append(keyboard, h.paymentMethodRows(langCode, func(t database.InvoiceType) string {
+		return paymentCallbackQuery(tidStr, month, string(t), amount, extraCount)
+	}, func() bool {
+		return h.starsAllowedForPaidPurchase(ctx, callback.Chat.ID)
+	})...)
+	if row := h.starsSubscriptionPaymentRow(ctx, langCode, customer, tidStr, month, amount, extraCount); row != nil {
+		keyboard = append(keyboard, row)
 	}
 
 	backSell := CallbackBuy
@@ -404,6 +366,7 @@ func (h Handler) PaymentCallbackHandler(ctx context.Context, b *bot.Bot, update
 	}
 
 	invoiceType := database.InvoiceType(callbackQuery["invoiceType"])
+	starsSubscription := invoiceType == database.InvoiceTypeTelegram && callbackQuery["sub"] == "1"
 	extra := parseIntSafe(callbackQuery["extra"])
 	if !config.HwidExtraDevicesEnabled() && extra > 0 {
 		extra = 0
@@ -463,34 +426,51 @@ func (h Handler) PaymentCallbackHandler(ctx context.Context, b *bot.Bot, update
 		}
 	}
 
+	// Валюта счёта: валюта языка клиента, если провайдер её принимает и для неё задана цена, иначе рубли / Stars.
+	currency := h.paymentService.CheckoutCurrency(ctx, invoiceType, customer, "", tariffID, month, extra)
+	amount := float64(price)
+
 	// Скидка от pending-промокода на полную сумму счёта за выбранный период (в т.ч. апгрейд/даунгрейд тарифа).
-	meta := h.checkoutPromoMeta(ctx, customer, invoiceType, &price)
+	// Подписка Stars — по полной цене: Telegram списывает сумму первого счёта каждый период.
+	var meta *payment.PromoMeta
+	order := promo.NewCheckoutOrder(invoiceType, tariffID, month, price)
+	if payment.IsForeignCurrency(currency) {
+		amount, _, err = payment.SubscriptionPriceIn(ctx, h.tariffRepository, tariffID, month, extra, currency)
+		if err != nil {
+			slog.Error("currency price for payment", "error", err, "currency", currency, "month", month)
+			return
+		}
+		meta = h.checkoutPromoMetaAmount(ctx, customer, order.InCurrency(amount), &amount)
+	} else if !starsSubscription {
+		meta = h.checkoutPromoMeta(ctx, customer, order, &price)
+		amount = float64(price)
+	}
 
-	ctxWithUsername := context.WithValue(ctx, remnawave.CtxKeyUsername, update.CallbackQuery.From.Username)
+	ctxWithUsername := payment.WithInvoiceCurrency(context.WithValue(ctx, remnawave.CtxKeyUsername, update.CallbackQuery.From.Username), currency)
+	if starsSubscription {
+		ctxWithUsername = payment.WithStarsSubscription(ctxWithUsername)
+	}
 	var paymentURL string
 	var purchaseId int64
 	if extra > 0 {
-		paymentURL, purchaseId, err = h.paymentService.CreatePurchaseWithExtra(ctxWithUsername, float64(price), month, extra, customer, invoiceType, meta, tariffID, tariffExtras)
+		paymentURL, purchaseId, err = h.paymentService.CreatePurchaseWithExtra(ctxWithUsername, amount, month, extra, customer, invoiceType, meta, tariffID, tariffExtras)
 	} else {
-		paymentURL, purchaseId, err = h.paymentService.CreatePurchase(ctxWithUsername, float64(price), month, customer, invoiceType, meta, tariffID, tariffExtras)
+		paymentURL, purchaseId, err = h.paymentService.CreatePurchase(ctxWithUsername, amount, month, customer, invoiceType, meta, tariffID, tariffExtras)
+	}
+	langCode := update.CallbackQuery.From.LanguageCode
+	backSell := fmt.Sprintf("%s?month=%d&amount=%d&extra=%d", CallbackSell, month, price, extra)
+	if tidStr != "" {
+		backSell = fmt.Sprintf("%s?tid=%s&month=%d&amount=%d&extra=%d", CallbackSell, tidStr, month, price, extra)
+	}
+	if h.handleBalanceCheckout(ctx, b, update, langCode, invoiceType, err, backSell) {
+		return
 	}
 	if err != nil {
 		slog.Error("Error creating payment", err)
-		langCode := update.CallbackQuery.From.LanguageCode
-		backSell := fmt.Sprintf("%s?month=%d&amount=%d&extra=%d", CallbackSell, month, price, extra)
-		if tidStr != "" {
-			backSell = fmt.Sprintf("%s?tid=%s&month=%d&amount=%d&extra=%d", CallbackSell, tidStr, month, price, extra)
-		}
 		h.notifyPaymentProviderUnavailable(ctx, b, update, langCode, backSell)
 		return
 	}
 
-	langCode := update.CallbackQuery.From.LanguageCode
-
-	backSell := fmt.Sprintf("%s?month=%d&amount=%d&extra=%d", CallbackSell, month, price, extra)
-	if tidStr != "" {
-		backSell = fmt.Sprintf("%s?tid=%s&month=%d&amount=%d&extra=%d", CallbackSell, tidStr, month, price, extra)
-	}
 	replyMarkup := models.InlineKeyboardMarkup{
 		InlineKeyboard: [][]models.InlineKeyboardButton{
 			{
@@ -523,7 +503,7 @@ func (h Handler) PaymentCallbackHandler(ctx context.Context, b *bot.Bot, update
 				}
 			}
 		}
-		textMsg, sumErr := h.buildTariffCheckoutSummaryHTML(ctx, langCode, customer, tid, month, price, invoiceStars, switchBonusDays, extra, switchTariffKind)
+		textMsg, sumErr := h.buildTariffCheckoutSummaryHTML(ctx, langCode, customer, tid, month, amount, currency, switchBonusDays, extra, switchTariffKind)
 		if sumErr != nil {
 			slog.Error("build tariff payment link", "error", sumErr)
 			textMsg = h.translation.GetText(langCode, "payment_tariff_screen_fallback")
@@ -564,13 +544,16 @@ func (h Handler) SuccessPaymentHandler(ctx context.Context, b *bot.Bot, update *
 
 	ctxWithUsername := context.WithValue(ctx, remnawave.CtxKeyUsername, username)
 	sp := update.Message.SuccessfulPayment
-	ctxPaid := payment.WithStarsNotifyMeta(ctxWithUsername, payment.StarsNotifyMeta{
-		TelegramPaymentChargeID: sp.TelegramPaymentChargeID,
-		ProviderPaymentChargeID: sp.ProviderPaymentChargeID,
-		TotalAmount:             sp.TotalAmount,
-		Currency:                sp.Currency,
+	// Списания подписки Stars приходят с payload первого счёта; ProcessStarsPayment заводит покупку продления.
+	err = h.paymentService.ProcessStarsPayment(ctxWithUsername, int64(purchaseId), payment.StarsNotifyMeta{
+		TelegramPaymentChargeID:    sp.TelegramPaymentChargeID,
+		ProviderPaymentChargeID:    sp.ProviderPaymentChargeID,
+		TotalAmount:                sp.TotalAmount,
+		Currency:                   sp.Currency,
+		IsRecurring:                sp.IsRecurring,
+		IsFirstRecurring:           sp.IsFirstRecurring,
+		SubscriptionExpirationDate: sp.SubscriptionExpirationDate,
 	})
-	err = h.paymentService.ProcessPurchaseById(ctxPaid, int64(purchaseId))
 	if err != nil {
 		slog.Error("Error processing purchase", err)
 	}
@@ -598,96 +581,72 @@ func parseInt64Safe(value string) int64 {
 	return parsed
 }
 
-// paymentCallbackQuery — данные callback для оплаты; для тарифов цена берётся из БД, amount не передаётся.
-func plategaInvoiceEnabled(it database.InvoiceType) bool {
-	if !config.IsPlategaEnabled() {
-		return false
-	}
-	switch it {
-	case database.InvoiceTypePlategaSBP:
-		return config.IsPlategaSBPEnabled()
-	case database.InvoiceTypePlategaCards:
-		return config.IsPlategaCardsEnabled()
-	case database.InvoiceTypePlategaAcquiring:
-		return config.IsPlategaAcquiringEnabled()
-	case database.InvoiceTypePlategaWorldwide:
-		return config.IsPlategaWorldwideEnabled()
-	case database.InvoiceTypePlategaCrypto:
-		return config.IsPlategaCryptoEnabled()
-	default:
-		return false
-	}
-}
-
-func (h Handler) appendPlategaPaymentRows(keyboard [][]models.InlineKeyboardButton, langCode, tidStr, month, amount string, extraCount int) [][]models.InlineKeyboardButton {
-	type row struct {
-		inv database.InvoiceType
-		key string
-	}
-	for _, r := range []row{
-		{database.InvoiceTypePlategaSBP, "platega_sbp_button"},
-		{database.InvoiceTypePlategaCards, "platega_cards_button"},
-		{database.InvoiceTypePlategaAcquiring, "platega_acquiring_button"},
-		{database.InvoiceTypePlategaWorldwide, "platega_worldwide_button"},
-		{database.InvoiceTypePlategaCrypto, "platega_crypto_button"},
-	} {
-		if plategaInvoiceEnabled(r.inv) {
-			keyboard = append(keyboard, []models.InlineKeyboardButton{
-				h.translation.WithButton(langCode, r.key, models.InlineKeyboardButton{
-					CallbackData: paymentCallbackQuery(tidStr, month, string(r.inv), amount, extraCount),
-				}),
-			})
+// paymentMethodRows — по кнопке на каждый включённый способ оплаты из реестра провайдеров.
+// callbackFor строит callback_data для invoice_type; starsAllowed — проверка REQUIRE_PAID_PURCHASE_FOR_STARS
+// (вызывается лениво, только если Stars включены).
+func (h Handler) paymentMethodRows(langCode string, callbackFor func(database.InvoiceType) string, starsAllowed func() bool) [][]models.InlineKeyboardButton {
+	var keyboard [][]models.InlineKeyboardButton
+	for _, p := range h.paymentService.Providers().Enabled() {
+		info := p.Info()
+		btn := models.InlineKeyboardButton{}
+		switch {
+		case info.ExternalURL != "":
+			btn.URL = info.ExternalURL
+		case info.InvoiceType == database.InvoiceTypeTelegram && starsAllowed != nil && !starsAllowed():
+			continue
+		default:
+			btn.CallbackData = callbackFor(info.InvoiceType)
 		}
+		keyboard = append(keyboard, []models.InlineKeyboardButton{
+			h.translation.WithButton(langCode, info.ButtonKey, btn),
+		})
 	}
 	return keyboard
 }
 
-func (h Handler) appendPlategaAddDevicePaymentRows(keyboard [][]models.InlineKeyboardButton, langCode string, target int) [][]models.InlineKeyboardButton {
-	type row struct {
-		inv database.InvoiceType
-		key string
-	}
-	for _, r := range []row{
-		{database.InvoiceTypePlategaSBP, "platega_sbp_button"},
-		{database.InvoiceTypePlategaCards, "platega_cards_button"},
-		{database.InvoiceTypePlategaAcquiring, "platega_acquiring_button"},
-		{database.InvoiceTypePlategaWorldwide, "platega_worldwide_button"},
-		{database.InvoiceTypePlategaCrypto, "platega_crypto_button"},
-	} {
-		if plategaInvoiceEnabled(r.inv) {
-			keyboard = append(keyboard, []models.InlineKeyboardButton{
-				h.translation.WithButton(langCode, r.key, models.InlineKeyboardButton{
-					CallbackData: fmt.Sprintf("%s?target=%d&invoiceType=%s", CallbackAddDevicePayment, target, r.inv),
-				}),
-			})
-		}
+// starsAllowedForPaidPurchase — вариант для экрана покупки подписки: любая успешная оплата.
+func (h Handler) starsAllowedForPaidPurchase(ctx context.Context, chatID int64) bool {
+	if !config.RequirePaidPurchaseForStars() {
+		return true
 	}
-	return keyboard
+	customer, err := h.customerRepository.FindByTelegramId(ctx, chatID)
+	if err != nil {
+		slog.Error("Error finding customer for stars check", "error", err)
+		return false
+	}
+	if customer == nil {
+		return false
+	}
+	paidPurchase, err := h.purchaseRepository.FindSuccessfulPaidPurchaseByCustomer(ctx, customer.ID)
+	if err != nil {
+		slog.Error("Error checking paid purchase", "error", err)
+		return false
+	}
+	return paidPurchase != nil
 }
 
-func (h Handler) appendPlategaRenewExtraRows(keyboard [][]models.InlineKeyboardButton, langCode string, extra, months int) [][]models.InlineKeyboardButton {
-	type row struct {
-		inv database.InvoiceType
-		key string
-	}
-	for _, r := range []row{
-		{database.InvoiceTypePlategaSBP, "platega_sbp_button"},
-		{database.InvoiceTypePlategaCards, "platega_cards_button"},
-		{database.InvoiceTypePlategaAcquiring, "platega_acquiring_button"},
-		{database.InvoiceTypePlategaWorldwide, "platega_worldwide_button"},
-		{database.InvoiceTypePlategaCrypto, "platega_crypto_button"},
-	} {
-		if plategaInvoiceEnabled(r.inv) {
-			keyboard = append(keyboard, []models.InlineKeyboardButton{
-				h.translation.WithButton(langCode, r.key, models.InlineKeyboardButton{
-					CallbackData: fmt.Sprintf("%s?extra=%d&months=%d&invoiceType=%s", CallbackRenewExtraHwid, extra, months, r.inv),
-				}),
-			})
-		}
+// starsAllowedForChat — при REQUIRE_PAID_PURCHASE_FOR_STARS кнопка Stars только у тех, у кого была оплаченная подписка.
+func (h Handler) starsAllowedForChat(ctx context.Context, chatID int64) bool {
+	if !config.RequirePaidPurchaseForStars() {
+		return true
 	}
-	return keyboard
+	customer, err := h.customerRepository.FindByTelegramId(ctx, chatID)
+	if err != nil {
+		slog.Error("Error finding customer for stars check", "error", err)
+		return false
+	}
+	if customer == nil {
+		return false
+	}
+	hasPaid, err := h.purchaseRepository.HasPaidSubscription(ctx, customer.ID)
+	if err != nil {
+		slog.Error("Error checking paid purchase", "error", err)
+		return false
+	}
+	return hasPaid
 }
 
+// paymentCallbackQuery — данные callback для оплаты; для тарифов цена берётся из БД, amount не передаётся.
 func paymentCallbackQuery(tidStr, month, invoiceType, amount string, extra int) string {
 	if tidStr != "" {
 		return fmt.Sprintf("%s?tid=%s&month=%s&invoiceType=%s&extra=%d", CallbackPayment, tidStr, month, invoiceType, extra)
@@ -752,28 +711,30 @@ func (h Handler) appendPendingDiscountToPricingHTML(ctx context.Context, lang st
 		}
 	}
 
-	promoPct := 0
-	promoOK := false
+	promoPct, promoAmount := 0, 0
 	if h.promoService != nil && customer != nil {
-		pct, _, _, ok, err := h.promoService.PendingDiscountForConnectUI(ctx, customer.ID)
+		pct, amount, _, _, ok, err := h.promoService.PendingDiscountForConnectUI(ctx, customer.ID)
 		if err != nil {
 			slog.Error("pricing screen pending discount", "error", err)
-		} else if ok && pct > 0 {
-			promoPct = pct
-			promoOK = true
+		} else if ok {
+			promoPct, promoAmount = pct, amount
 		}
 	}
 
 	var blocks []string
 	switch {
-	case loyaltyPct > 0 && promoOK:
+	case loyaltyPct > 0 && promoPct > 0:
 		total := loyalty.CombinedDiscountPercent(loyaltyPct, promoPct, capPct)
 		blocks = append(blocks, fmt.Sprintf(h.translation.GetText(lang, "buy_screen_loyalty_promo_combo_note"), loyaltyPct, promoPct, total))
 	case loyaltyPct > 0:
 		blocks = append(blocks, fmt.Sprintf(h.translation.GetText(lang, "buy_screen_loyalty_discount_note"), loyaltyPct))
-	case promoOK:
+	case promoPct > 0:
 		blocks = append(blocks, fmt.Sprintf(h.translation.GetText(lang, "buy_screen_pending_discount_note"), promoPct))
-	default:
+	}
+	if promoAmount > 0 {
+		blocks = append(blocks, fmt.Sprintf(h.translation.GetText(lang, "buy_screen_pending_discount_amount_note"), promoAmount))
+	}
+	if len(blocks) == 0 {
 		return base
 	}
 
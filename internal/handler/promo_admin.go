@@ -3,6 +3,7 @@ package handler
 import (
 	"context"
 	"fmt"
+	"html"
 	"strconv"
 	"strings"
 	"sync"
@@ -13,9 +14,11 @@ import (
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 
+	"remnawave-tg-shop-bot/internal/audit"
 	"remnawave-tg-shop-bot/internal/config"
 	"remnawave-tg-shop-bot/internal/database"
 	"remnawave-tg-shop-bot/internal/promo"
+	"remnawave-tg-shop-bot/internal/staff"
 )
 
 const promoPageSize = 5
@@ -27,6 +30,20 @@ type adminPromoEditState struct {
 	PromptMsgID int
 }
 
+// updatePromoAudited — UpdateFields с записью изменившихся полей в журнал действий.
+func (h Handler) updatePromoAudited(ctx context.Context, adminID, id int64, fields map[string]interface{}) error {
+	before, _ := h.promoRepository.FindByID(ctx, id)
+	if err := h.promoRepository.UpdateFields(ctx, id, fields); err != nil {
+		return err
+	}
+	after, _ := h.promoRepository.FindByID(ctx, id)
+	audit.Record(ctx, audit.Bot(adminID), audit.Entry{
+		Action: audit.ActionPromoUpdate, TargetType: "promo", TargetID: strconv.FormatInt(id, 10),
+		Before: audit.Row(before), After: audit.Row(after),
+	})
+	return nil
+}
+
 var adminPromoEdit = struct {
 	mu      sync.Mutex
 	pending map[int64]*adminPromoEditState
@@ -197,11 +214,25 @@ func (h Handler) buildPromoEditMenuText(ctx context.Context, p *database.PromoCo
 	return sb.String()
 }
 
+// promoDiscountValue — размер скидки промокода: «15%» или «150 ₽»; "" — не скидочный промокод.
+func promoDiscountValue(p *database.PromoCode) string {
+	if p.Type != database.PromoTypeDiscount {
+		return ""
+	}
+	if p.DiscountAmount != nil {
+		return fmt.Sprintf("%d ₽", *p.DiscountAmount)
+	}
+	if p.DiscountPercent != nil {
+		return fmt.Sprintf("%d%%", *p.DiscountPercent)
+	}
+	return ""
+}
+
 func (h Handler) formatDiscountExtraLine(p *database.PromoCode, lang string) string {
-	if p.Type != database.PromoTypeDiscount || p.DiscountPercent == nil {
+	pct := promoDiscountValue(p)
+	if pct == "" {
 		return ""
 	}
-	pct := *p.DiscountPercent
 	var prefix string
 	if p.DiscountMaxSubscriptionPaymentsPerCustomer > 1 {
 		prefix = fmt.Sprintf(h.translation.GetText(lang, "promo_line_disc_pay_n_short"), p.DiscountMaxSubscriptionPaymentsPerCustomer) + "\n"
@@ -222,10 +253,10 @@ func (h Handler) formatDiscountExtraLine(p *database.PromoCode, lang string) str
 }
 
 func (h Handler) formatCardDiscountLine(p *database.PromoCode, lang string) string {
-	if p.Type != database.PromoTypeDiscount || p.DiscountPercent == nil {
+	pct := promoDiscountValue(p)
+	if pct == "" {
 		return ""
 	}
-	pct := *p.DiscountPercent
 	var prefix string
 	if p.DiscountMaxSubscriptionPaymentsPerCustomer > 1 {
 		prefix = fmt.Sprintf(h.translation.GetText(lang, "promo_card_disc_sub_pay_n"), p.DiscountMaxSubscriptionPaymentsPerCustomer) + "\n"
@@ -245,6 +276,49 @@ func (h Handler) formatCardDiscountLine(p *database.PromoCode, lang string) stri
 	return prefix + fmt.Sprintf(h.translation.GetText(lang, "promo_card_discount_hours"), pct, hh)
 }
 
+// formatCardDiscountRestrictions — строки карточки об ограничениях скидки (тарифы, периоды, способы оплаты, мин. сумма).
+func (h Handler) formatCardDiscountRestrictions(ctx context.Context, p *database.PromoCode, lang string) string {
+	var sb strings.Builder
+	if len(p.DiscountTariffIDs) > 0 {
+		names := make([]string, 0, len(p.DiscountTariffIDs))
+		for _, id := range p.DiscountTariffIDs {
+			name := "#" + strconv.FormatInt(id, 10)
+			if h.tariffRepository != nil {
+				if tf, err := h.tariffRepository.GetByID(ctx, id); err == nil && tf != nil {
+					name = tf.Slug
+					if tf.Name != nil && strings.TrimSpace(*tf.Name) != "" {
+						name = strings.TrimSpace(*tf.Name)
+					}
+				}
+			}
+			names = append(names, html.EscapeString(name))
+		}
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "promo_card_disc_tariffs"), strings.Join(names, ", ")))
+		sb.WriteString("\n")
+	}
+	if len(p.DiscountMonths) > 0 {
+		months := make([]string, 0, len(p.DiscountMonths))
+		for _, m := range p.DiscountMonths {
+			months = append(months, strconv.Itoa(m))
+		}
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "promo_card_disc_months"), strings.Join(months, ", ")))
+		sb.WriteString("\n")
+	}
+	if len(p.DiscountInvoiceTypes) > 0 {
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "promo_card_disc_providers"), html.EscapeString(strings.Join(p.DiscountInvoiceTypes, ", "))))
+		sb.WriteString("\n")
+	}
+	if p.DiscountMinAmount != nil && *p.DiscountMinAmount > 0 {
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "promo_card_disc_min_amount"), *p.DiscountMinAmount))
+		sb.WriteString("\n")
+	}
+	if p.SegmentID != nil {
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "promo_card_segment"), *p.SegmentID))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
 func (h Handler) promoLineSummary(p *database.PromoCode, lang string) string {
 	active := "❌"
 	if p.Active {
@@ -293,7 +367,7 @@ func (h Handler) promoLineSummary(p *database.PromoCode, lang string) string {
 
 // AdminPromoOpenHandler opens promo management root (from admin panel).
 func (h Handler) AdminPromoOpenHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
 		return
 	}
 	h.renderPromoRoot(ctx, b, update.CallbackQuery)
@@ -301,7 +375,7 @@ func (h Handler) AdminPromoOpenHandler(ctx context.Context, b *bot.Bot, update *
 
 // PromoRootHandler callback promo_root
 func (h Handler) PromoRootHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
 		return
 	}
 	adminPromoReset(update.CallbackQuery.From.ID)
@@ -323,6 +397,7 @@ func (h Handler) renderPromoRoot(ctx context.Context, b *bot.Bot, cb *models.Cal
 			h.translation.WithButton(lang, "promo_admin_create", models.InlineKeyboardButton{CallbackData: CallbackPromoNew}),
 		},
 		{h.translation.WithButton(lang, "promo_admin_stats_all", models.InlineKeyboardButton{CallbackData: CallbackPromoStatsAll})},
+		{h.translation.WithButton(lang, "promo_admin_batches", models.InlineKeyboardButton{CallbackData: CallbackPromoBatchList + "?p=0"})},
 		{h.translation.WithButton(lang, "back_button", models.InlineKeyboardButton{CallbackData: CallbackAdminPanel})},
 	}
 	_, err = b.EditMessageText(ctx, &bot.EditMessageTextParams{
@@ -390,7 +465,7 @@ func (h Handler) renderPromoListPage(ctx context.Context, b *bot.Bot, chatID int
 
 // PromoListHandler prefix promo_list?p=
 func (h Handler) PromoListHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
@@ -406,7 +481,7 @@ func (h Handler) PromoListHandler(ctx context.Context, b *bot.Bot, update *model
 
 // PromoCardHandler promo_card?id=
 func (h Handler) PromoCardHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
@@ -469,6 +544,7 @@ func (h Handler) buildPromoCardText(ctx context.Context, p *database.PromoCode,
 	case database.PromoTypeDiscount:
 		sb.WriteString(h.formatCardDiscountLine(p, lang))
 		sb.WriteString("\n")
+		sb.WriteString(h.formatCardDiscountRestrictions(ctx, p, lang))
 	}
 	sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "promo_card_until_line"), until))
 	sb.WriteString("\n")
@@ -513,7 +589,7 @@ func (h Handler) showPromoCard(ctx context.Context, b *bot.Bot, cb *models.Callb
 
 // PromoToggleHandler
 func (h Handler) PromoToggleHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
@@ -523,7 +599,7 @@ func (h Handler) PromoToggleHandler(ctx context.Context, b *bot.Bot, update *mod
 	if err != nil || p == nil {
 		return
 	}
-	_ = h.promoRepository.UpdateFields(ctx, id, map[string]interface{}{"active": !p.Active})
+	_ = h.updatePromoAudited(ctx, cb.From.ID, id, map[string]interface{}{"active": !p.Active})
 	p, _ = h.promoRepository.FindByID(ctx, id)
 	if p == nil {
 		return
@@ -544,7 +620,7 @@ func (h Handler) PromoToggleHandler(ctx context.Context, b *bot.Bot, update *mod
 
 // PromoFirstPurchaseToggle
 func (h Handler) PromoFirstPurchaseToggle(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
@@ -554,7 +630,7 @@ func (h Handler) PromoFirstPurchaseToggle(ctx context.Context, b *bot.Bot, updat
 	if err != nil || p == nil {
 		return
 	}
-	_ = h.promoRepository.UpdateFields(ctx, id, map[string]interface{}{"first_purchase_only": !p.FirstPurchaseOnly})
+	_ = h.updatePromoAudited(ctx, cb.From.ID, id, map[string]interface{}{"first_purchase_only": !p.FirstPurchaseOnly})
 	p, _ = h.promoRepository.FindByID(ctx, id)
 	if p == nil {
 		return
@@ -575,7 +651,7 @@ func (h Handler) PromoFirstPurchaseToggle(ctx context.Context, b *bot.Bot, updat
 
 // PromoStatHandler
 func (h Handler) PromoStatHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
@@ -624,7 +700,7 @@ func (h Handler) PromoStatHandler(ctx context.Context, b *bot.Bot, update *model
 
 // PromoStatsAllHandler
 func (h Handler) PromoStatsAllHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
@@ -653,7 +729,7 @@ func (h Handler) PromoStatsAllHandler(ctx context.Context, b *bot.Bot, update *m
 
 // PromoDeleteAskHandler
 func (h Handler) PromoDeleteAskHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
@@ -687,16 +763,20 @@ func (h Handler) PromoDeleteAskHandler(ctx context.Context, b *bot.Bot, update *
 
 // PromoDeleteYesHandler
 func (h Handler) PromoDeleteYesHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
 	lang := cb.From.LanguageCode
 	id, _ := strconv.ParseInt(parseCallbackData(cb.Data)["id"], 10, 64)
+	before, _ := h.promoRepository.FindByID(ctx, id)
 	if err := h.promoRepository.Delete(ctx, id); err != nil {
 		slog.Error("promo delete", "error", err)
 		return
 	}
+	audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+		Action: audit.ActionPromoDelete, TargetType: "promo", TargetID: strconv.FormatInt(id, 10), Before: audit.Row(before),
+	})
 	_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 		CallbackQueryID: cb.ID,
 		Text:            h.translation.GetText(lang, "promo_deleted_alert"),
@@ -713,7 +793,7 @@ func (h Handler) PromoDeleteYesHandler(ctx context.Context, b *bot.Bot, update *
 
 // PromoNewMenuHandler — choose type
 func (h Handler) PromoNewMenuHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
@@ -752,7 +832,7 @@ func (h Handler) PromoNewMenuHandler(ctx context.Context, b *bot.Bot, update *mo
 
 // PromoNewTypeHandler sets type and asks for code via next message
 func (h Handler) PromoNewTypeHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
@@ -841,7 +921,7 @@ func (h Handler) renderPromoDiscountKind(ctx context.Context, b *bot.Bot, cb *mo
 
 // PromoDiscountKindHandler — одноразовая или многоразовая скидка, затем экран ввода кода.
 func (h Handler) PromoDiscountKindHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
@@ -960,7 +1040,7 @@ func (h Handler) renderPromoSubDaysScope(ctx context.Context, b *bot.Bot, cb *mo
 
 // PromoSubDaysScopeHandler — область действия бонусных дней (все тарифы или один).
 func (h Handler) PromoSubDaysScopeHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1020,8 +1100,8 @@ func (h Handler) AdminPromoTextHandler(ctx context.Context, b *bot.Bot, update *
 	if update.Message == nil || h.promoRepository == nil {
 		return
 	}
-	adminID := config.GetAdminTelegramId()
-	if update.Message.From.ID != adminID || update.Message.ReplyToMessage != nil {
+	adminID := update.Message.From.ID
+	if !staff.Can(adminID, staff.PermPromos) || update.Message.ReplyToMessage != nil {
 		return
 	}
 	if AdminPromoEditWaiting(adminID) {
@@ -1242,13 +1322,17 @@ func (h Handler) finalizePromoCreate(ctx context.Context, b *bot.Bot, adminID in
 			pc.DiscountMaxSubscriptionPaymentsPerCustomer = d.DiscountMaxSubPayments
 		}
 	}
-	_, err := h.promoRepository.Create(ctx, pc)
+	promoID, err := h.promoRepository.Create(ctx, pc)
 	if err != nil {
 		slog.Error("promo create", "error", err)
 		_, _ = b.SendMessage(ctx, &bot.SendMessageParams{ChatID: adminID, Text: h.translation.GetText(lang, "promo_create_err")})
 		adminPromoReset(adminID)
 		return
 	}
+	pc.ID = promoID
+	audit.Record(ctx, audit.Bot(adminID), audit.Entry{
+		Action: audit.ActionPromoCreate, TargetType: "promo", TargetID: strconv.FormatInt(promoID, 10), After: audit.Row(pc),
+	})
 	summary := h.formatPromoSuccessSummary(ctx, lang, d, validUntil, maxUses)
 	kb := models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
 		{h.translation.WithButton(lang, "promo_to_root", models.InlineKeyboardButton{CallbackData: CallbackPromoList + "?p=0"})},
@@ -1348,7 +1432,7 @@ func (h Handler) handlePromoEditText(ctx context.Context, b *bot.Bot, update *mo
 			_, _ = b.SendMessage(ctx, &bot.SendMessageParams{ChatID: adminID, Text: h.translation.GetText(lang, "promo_bad_number")})
 			return
 		}
-		if err := h.promoRepository.UpdateFields(ctx, st.PromoID, map[string]interface{}{
+		if err := h.updatePromoAudited(ctx, adminID, st.PromoID, map[string]interface{}{
 			"discount_max_subscription_payments_per_customer": n,
 		}); err != nil {
 			slog.Error("promo edit discount_max_subscription_payments_per_customer", "error", err)
@@ -1375,7 +1459,7 @@ func (h Handler) handlePromoEditText(ctx context.Context, b *bot.Bot, update *mo
 		} else {
 			vu = nil
 		}
-		if err := h.promoRepository.UpdateFields(ctx, st.PromoID, map[string]interface{}{"valid_until": vu}); err != nil {
+		if err := h.updatePromoAudited(ctx, adminID, st.PromoID, map[string]interface{}{"valid_until": vu}); err != nil {
 			slog.Error("promo edit valid_until", "error", err)
 			return
 		}
@@ -1399,7 +1483,7 @@ func (h Handler) handlePromoEditText(ctx context.Context, b *bot.Bot, update *mo
 		} else {
 			mu = nil
 		}
-		if err := h.promoRepository.UpdateFields(ctx, st.PromoID, map[string]interface{}{"max_uses": mu}); err != nil {
+		if err := h.updatePromoAudited(ctx, adminID, st.PromoID, map[string]interface{}{"max_uses": mu}); err != nil {
 			slog.Error("promo edit max_uses", "error", err)
 			return
 		}
@@ -1417,7 +1501,7 @@ func (h Handler) handlePromoEditText(ctx context.Context, b *bot.Bot, update *mo
 			_, _ = b.SendMessage(ctx, &bot.SendMessageParams{ChatID: adminID, Text: h.translation.GetText(lang, "promo_bad_number")})
 			return
 		}
-		if err := h.promoRepository.UpdateFields(ctx, st.PromoID, map[string]interface{}{"subscription_days": n}); err != nil {
+		if err := h.updatePromoAudited(ctx, adminID, st.PromoID, map[string]interface{}{"subscription_days": n}); err != nil {
 			slog.Error("promo edit subscription_days", "error", err)
 			return
 		}
@@ -1435,7 +1519,7 @@ func (h Handler) handlePromoEditText(ctx context.Context, b *bot.Bot, update *mo
 			_, _ = b.SendMessage(ctx, &bot.SendMessageParams{ChatID: adminID, Text: h.translation.GetText(lang, "promo_bad_number")})
 			return
 		}
-		if err := h.promoRepository.UpdateFields(ctx, st.PromoID, map[string]interface{}{"trial_days": n}); err != nil {
+		if err := h.updatePromoAudited(ctx, adminID, st.PromoID, map[string]interface{}{"trial_days": n}); err != nil {
 			slog.Error("promo edit trial_days", "error", err)
 			return
 		}
@@ -1452,7 +1536,7 @@ func (h Handler) handlePromoEditText(ctx context.Context, b *bot.Bot, update *mo
 
 // PromoEditMenuHandler — параметры промокода для правки.
 func (h Handler) PromoEditMenuHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1539,7 +1623,7 @@ func (h Handler) promoEditShowPrompt(ctx context.Context, b *bot.Bot, cb *models
 
 // PromoEditAskValidHandler — ввод срока действия (дней от сегодня, 0 = бессрочно).
 func (h Handler) PromoEditAskValidHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1551,7 +1635,7 @@ func (h Handler) PromoEditAskValidHandler(ctx context.Context, b *bot.Bot, updat
 
 // PromoEditAskMaxHandler — количество использований (0 = безлимит).
 func (h Handler) PromoEditAskMaxHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1563,7 +1647,7 @@ func (h Handler) PromoEditAskMaxHandler(ctx context.Context, b *bot.Bot, update
 
 // PromoEditAskSubDaysHandler — дни подписки (тип «дни подписки»).
 func (h Handler) PromoEditAskSubDaysHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1575,7 +1659,7 @@ func (h Handler) PromoEditAskSubDaysHandler(ctx context.Context, b *bot.Bot, upd
 
 // PromoEditAskTrialDaysHandler — дни триала.
 func (h Handler) PromoEditAskTrialDaysHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1587,7 +1671,7 @@ func (h Handler) PromoEditAskTrialDaysHandler(ctx context.Context, b *bot.Bot, u
 
 // PromoEditSubsTariffMenuHandler — смена тарифа для промо «дни подписки» (режим тарифов).
 func (h Handler) PromoEditSubsTariffMenuHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil || h.tariffRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil || h.tariffRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1609,7 +1693,7 @@ func (h Handler) PromoEditSubsTariffMenuHandler(ctx context.Context, b *bot.Bot,
 
 // PromoEditSubsTariffApplyHandler — сохранить область тарифа для промо «дни подписки».
 func (h Handler) PromoEditSubsTariffApplyHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1641,7 +1725,7 @@ func (h Handler) PromoEditSubsTariffApplyHandler(ctx context.Context, b *bot.Bot
 		_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: cb.ID})
 		return
 	}
-	if err := h.promoRepository.UpdateFields(ctx, id, map[string]interface{}{"tariff_id": tariffVal}); err != nil {
+	if err := h.updatePromoAudited(ctx, cb.From.ID, id, map[string]interface{}{"tariff_id": tariffVal}); err != nil {
 		slog.Error("promo edit tariff_id", "error", err)
 		_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: cb.ID})
 		return
@@ -1665,7 +1749,7 @@ func (h Handler) PromoEditSubsTariffApplyHandler(ctx context.Context, b *bot.Bot
 
 // PromoEditAskDiscPayHandler — лимит оплат подписки со скидкой на пользователя (многоразовая скидка).
 func (h Handler) PromoEditAskDiscPayHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() || h.promoRepository == nil {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
 		return
 	}
 	cb := update.CallbackQuery
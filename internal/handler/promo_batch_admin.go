@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/audit"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/promo"
+	"remnawave-tg-shop-bot/internal/staff"
+)
+
+// Пакеты одноразовых кодов создаются в кабинете (POST /cabinet/api/admin/promo-batches); в боте — список,
+// статистика, выгрузка CSV и выключение всего пакета.
+
+func promoBatchCallback(prefix string, id int64) string {
+	return prefix + "?id=" + strconv.FormatInt(id, 10)
+}
+
+func (h Handler) promoBatchLine(lang string, s *database.PromoBatchStats) string {
+	status := h.translation.GetText(lang, "promo_status_label_active")
+	if s.DeactivatedAt != nil {
+		status = h.translation.GetText(lang, "promo_status_label_inactive")
+	}
+	return fmt.Sprintf(h.translation.GetText(lang, "promo_batch_line"),
+		s.ID, html.EscapeString(s.Campaign), s.RedeemedCodes, s.Codes, status)
+}
+
+// PromoBatchListHandler promo_bl?p=
+func (h Handler) PromoBatchListHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
+		return
+	}
+	cb := update.CallbackQuery
+	msg := cb.Message.Message
+	if msg == nil {
+		return
+	}
+	lang := cb.From.LanguageCode
+	page := parseIntSafe(parseCallbackData(cb.Data)["p"])
+	list, total, err := h.promoRepository.ListBatches(ctx, "", page*promoPageSize, promoPageSize)
+	if err != nil {
+		slog.Error("promo batches list", "error", err)
+		return
+	}
+	pages := (total + promoPageSize - 1) / promoPageSize
+	if pages == 0 {
+		pages = 1
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "promo_batch_list_title"), page+1, pages))
+	sb.WriteString("\n\n")
+	if len(list) == 0 {
+		sb.WriteString(h.translation.GetText(lang, "promo_batch_list_empty"))
+	}
+	var rows [][]models.InlineKeyboardButton
+	for i := range list {
+		sb.WriteString(h.promoBatchLine(lang, &list[i]))
+		sb.WriteString("\n")
+		rows = append(rows, []models.InlineKeyboardButton{{
+			Text:         fmt.Sprintf("#%d %s", list[i].ID, list[i].Campaign),
+			CallbackData: promoBatchCallback(CallbackPromoBatchCard, list[i].ID),
+		}})
+	}
+	var navRow []models.InlineKeyboardButton
+	if page > 0 {
+		navRow = append(navRow, models.InlineKeyboardButton{Text: "«", CallbackData: CallbackPromoBatchList + "?p=" + strconv.Itoa(page-1)})
+	}
+	if (page+1)*promoPageSize < total {
+		navRow = append(navRow, models.InlineKeyboardButton{Text: "»", CallbackData: CallbackPromoBatchList + "?p=" + strconv.Itoa(page+1)})
+	}
+	if len(navRow) > 0 {
+		rows = append(rows, navRow)
+	}
+	rows = append(rows, []models.InlineKeyboardButton{
+		h.translation.WithButton(lang, "back_button", models.InlineKeyboardButton{CallbackData: CallbackPromoRoot}),
+	})
+	_, err = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      msg.Chat.ID,
+		MessageID:   msg.ID,
+		ParseMode:   models.ParseModeHTML,
+		Text:        sb.String(),
+		ReplyMarkup: models.InlineKeyboardMarkup{InlineKeyboard: rows},
+	})
+	if err != nil {
+		slog.Error("promo batches list edit", "error", err)
+	}
+	_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: cb.ID})
+}
+
+// PromoBatchCardHandler promo_bk?id= — статистика пакета.
+func (h Handler) PromoBatchCardHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
+		return
+	}
+	cb := update.CallbackQuery
+	id, _ := strconv.ParseInt(parseCallbackData(cb.Data)["id"], 10, 64)
+	s, err := h.promoRepository.FindBatch(ctx, id)
+	if err != nil || s == nil {
+		return
+	}
+	h.showPromoBatchCard(ctx, b, cb, s)
+	_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: cb.ID})
+}
+
+func (h Handler) showPromoBatchCard(ctx context.Context, b *bot.Bot, cb *models.CallbackQuery, s *database.PromoBatchStats) {
+	msg := cb.Message.Message
+	if msg == nil {
+		return
+	}
+	lang := cb.From.LanguageCode
+	status := h.translation.GetText(lang, "promo_status_label_active")
+	if s.DeactivatedAt != nil {
+		status = fmt.Sprintf(h.translation.GetText(lang, "promo_batch_deactivated_at"), s.DeactivatedAt.Local().Format("02.01.2006 15:04"))
+	}
+	redeemedPct := 0.0
+	if s.Codes > 0 {
+		redeemedPct = float64(s.RedeemedCodes) * 100 / float64(s.Codes)
+	}
+	text := fmt.Sprintf(h.translation.GetText(lang, "promo_batch_card"),
+		s.ID, html.EscapeString(s.Campaign), html.EscapeString(s.Prefix), s.CreatedAt.Local().Format("02.01.2006 15:04"),
+		s.Codes, s.ActiveCodes, s.RedeemedCodes, redeemedPct, status)
+	kb := [][]models.InlineKeyboardButton{
+		{h.translation.WithButton(lang, "promo_batch_export_button", models.InlineKeyboardButton{CallbackData: promoBatchCallback(CallbackPromoBatchExport, s.ID)})},
+	}
+	if s.ActiveCodes > 0 {
+		kb = append(kb, []models.InlineKeyboardButton{
+			h.translation.WithButton(lang, "promo_batch_deactivate_button", models.InlineKeyboardButton{CallbackData: promoBatchCallback(CallbackPromoBatchOffAsk, s.ID)}),
+		})
+	}
+	kb = append(kb, []models.InlineKeyboardButton{
+		h.translation.WithButton(lang, "back_button", models.InlineKeyboardButton{CallbackData: CallbackPromoBatchList + "?p=0"}),
+	})
+	_, err := b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      msg.Chat.ID,
+		MessageID:   msg.ID,
+		ParseMode:   models.ParseModeHTML,
+		Text:        text,
+		ReplyMarkup: models.InlineKeyboardMarkup{InlineKeyboard: kb},
+	})
+	if err != nil {
+		slog.Error("promo batch card edit", "error", err)
+	}
+}
+
+// PromoBatchExportHandler promo_bx?id= — присылает коды пакета CSV-файлом.
+func (h Handler) PromoBatchExportHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
+		return
+	}
+	cb := update.CallbackQuery
+	lang := cb.From.LanguageCode
+	id, _ := strconv.ParseInt(parseCallbackData(cb.Data)["id"], 10, 64)
+	s, err := h.promoRepository.FindBatch(ctx, id)
+	if err != nil || s == nil {
+		return
+	}
+	codes, err := h.promoRepository.ListBatchCodes(ctx, id)
+	if err != nil {
+		slog.Error("promo batch export", "batch_id", id, "error", err)
+		return
+	}
+	var buf bytes.Buffer
+	if err := promo.WriteBatchCSV(&buf, codes); err != nil {
+		slog.Error("promo batch export csv", "batch_id", id, "error", err)
+		return
+	}
+	_, err = b.SendDocument(ctx, &bot.SendDocumentParams{
+		ChatID:    cb.From.ID,
+		Document:  &models.InputFileUpload{Filename: fmt.Sprintf("promo-batch-%d.csv", id), Data: &buf},
+		Caption:   fmt.Sprintf(h.translation.GetText(lang, "promo_batch_export_caption"), html.EscapeString(s.Campaign), len(codes)),
+		ParseMode: models.ParseModeHTML,
+	})
+	if err != nil {
+		slog.Error("promo batch export send", "batch_id", id, "error", err)
+	}
+	_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: cb.ID})
+}
+
+// PromoBatchDeactivateAskHandler promo_bo?id= — подтверждение выключения пакета.
+func (h Handler) PromoBatchDeactivateAskHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
+		return
+	}
+	cb := update.CallbackQuery
+	msg := cb.Message.Message
+	if msg == nil {
+		return
+	}
+	lang := cb.From.LanguageCode
+	id, _ := strconv.ParseInt(parseCallbackData(cb.Data)["id"], 10, 64)
+	s, err := h.promoRepository.FindBatch(ctx, id)
+	if err != nil || s == nil {
+		return
+	}
+	_, err = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:    msg.Chat.ID,
+		MessageID: msg.ID,
+		ParseMode: models.ParseModeHTML,
+		Text:      fmt.Sprintf(h.translation.GetText(lang, "promo_batch_deactivate_confirm"), html.EscapeString(s.Campaign), s.ID, s.ActiveCodes),
+		ReplyMarkup: models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
+			{h.translation.WithButton(lang, "promo_batch_deactivate_yes", models.InlineKeyboardButton{CallbackData: promoBatchCallback(CallbackPromoBatchOffYes, id)})},
+			{h.translation.WithButton(lang, "promo_delete_cancel", models.InlineKeyboardButton{CallbackData: promoBatchCallback(CallbackPromoBatchCard, id)})},
+		}},
+	})
+	if err != nil {
+		slog.Error("promo batch deactivate ask", "error", err)
+	}
+	_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: cb.ID})
+}
+
+// PromoBatchDeactivateYesHandler promo_bz?id= — выключает все коды пакета.
+func (h Handler) PromoBatchDeactivateYesHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermPromos) || h.promoRepository == nil {
+		return
+	}
+	cb := update.CallbackQuery
+	lang := cb.From.LanguageCode
+	id, _ := strconv.ParseInt(parseCallbackData(cb.Data)["id"], 10, 64)
+	n, err := h.promoRepository.DeactivateBatch(ctx, id)
+	if err != nil {
+		slog.Error("promo batch deactivate", "batch_id", id, "error", err)
+		return
+	}
+	audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+		Action: audit.ActionPromoBatchDeactivate, TargetType: "promo_batch", TargetID: strconv.FormatInt(id, 10),
+		After: map[string]any{"deactivated": n},
+	})
+	_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: cb.ID,
+		Text:            fmt.Sprintf(h.translation.GetText(lang, "promo_batch_deactivated_alert"), n),
+		ShowAlert:       true,
+	})
+	s, err := h.promoRepository.FindBatch(ctx, id)
+	if err != nil || s == nil {
+		return
+	}
+	h.showPromoBatchCard(ctx, b, cb, s)
+}
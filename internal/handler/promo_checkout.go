@@ -8,19 +8,41 @@ import (
 	"remnawave-tg-shop-bot/internal/database"
 	"remnawave-tg-shop-bot/internal/loyalty"
 	"remnawave-tg-shop-bot/internal/payment"
+	"remnawave-tg-shop-bot/internal/promo"
 )
 
 // checkoutPromoMeta применяет скидки лояльности и pending-промокода к сумме счёта (Tribute исключён, как и промо).
 // Мутирует *price — итог к оплате. Возвращает PromoMeta только если участвовал промокод (для записи в purchase).
-func (h Handler) checkoutPromoMeta(ctx context.Context, customer *database.Customer, invoiceType database.InvoiceType, price *int) *payment.PromoMeta {
+// order — заказ до скидок (promo.NewCheckoutOrder): по нему проверяются ограничения промокода.
+func (h Handler) checkoutPromoMeta(ctx context.Context, customer *database.Customer, order promo.CheckoutOrder, price *int) *payment.PromoMeta {
 	if customer == nil || price == nil {
 		return nil
 	}
-	if invoiceType == database.InvoiceTypeTribute {
+	loyaltyPct, disc := h.checkoutDiscounts(ctx, customer, order)
+	promoPct, fixed := appliedPromoDiscount(disc)
+	*price = loyalty.ApplyCombinedDiscount(*price, loyaltyPct, promoPct, fixed, config.LoyaltyMaxTotalDiscountPercent())
+	return payment.PromoMetaFor(disc)
+}
+
+// checkoutPromoMetaAmount — checkoutPromoMeta для счёта в валюте из PRICE_CURRENCIES (сумма с центами);
+// order — заказ в этой валюте (CheckoutOrder.InCurrency).
+func (h Handler) checkoutPromoMetaAmount(ctx context.Context, customer *database.Customer, order promo.CheckoutOrder, amount *float64) *payment.PromoMeta {
+	if customer == nil || amount == nil {
 		return nil
 	}
+	loyaltyPct, disc := h.checkoutDiscounts(ctx, customer, order)
+	promoPct, fixed := appliedPromoDiscount(disc)
+	*amount = loyalty.ApplyCombinedDiscountAmount(*amount, loyaltyPct, promoPct, fixed, config.LoyaltyMaxTotalDiscountPercent())
+	return payment.PromoMetaFor(disc)
+}
+
+// checkoutDiscounts — процент скидки лояльности и pending-промокод для заказа; Tribute — без скидок.
+// Промокод возвращается и тогда, когда он к заказу не подходит (CheckoutDiscount.Reject).
+func (h Handler) checkoutDiscounts(ctx context.Context, customer *database.Customer, order promo.CheckoutOrder) (loyaltyPct int, disc *promo.CheckoutDiscount) {
+	if order.InvoiceType == database.InvoiceTypeTribute {
+		return 0, nil
+	}
 
-	loyaltyPct := 0
 	if config.LoyaltyEnabled() && h.loyaltyTierRepository != nil {
 		pct, err := h.loyaltyTierRepository.DiscountPercentForXP(ctx, customer.LoyaltyXP)
 		if err != nil {
@@ -30,21 +52,21 @@ func (h Handler) checkoutPromoMeta(ctx context.Context, customer *database.Custo
 		}
 	}
 
-	promoPct := 0
-	var promoMeta *payment.PromoMeta
 	if h.promoService != nil {
-		pct, pid, err := h.promoService.PendingDiscountForPayment(ctx, customer.ID)
+		d, err := h.promoService.PendingDiscountForCheckout(ctx, customer.ID, order)
 		if err != nil {
 			slog.Error("pending promo for checkout", "error", err)
-		} else if pct > 0 && pid != 0 {
-			promoPct = pct
-			pc := pct
-			p := pid
-			promoMeta = &payment.PromoMeta{PromoCodeID: &p, DiscountPercentApplied: &pc}
+		} else {
+			disc = d
 		}
 	}
+	return loyaltyPct, disc
+}
 
-	cap := config.LoyaltyMaxTotalDiscountPercent()
-	*price = loyalty.ApplyCombinedPercentDiscount(*price, loyaltyPct, promoPct, cap)
-	return promoMeta
+// appliedPromoDiscount — процент и фиксированная скидка (в единицах счёта) промокода, если он применяется к заказу.
+func appliedPromoDiscount(d *promo.CheckoutDiscount) (pct int, fixed float64) {
+	if !d.Applied() {
+		return 0, 0
+	}
+	return d.Percent, d.Fixed
 }
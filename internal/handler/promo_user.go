@@ -121,7 +121,11 @@ func (h Handler) UserPromoMessageHandler(ctx context.Context, b *bot.Bot, update
 	var text string
 	switch res.Type {
 	case promo.ResultTypeDiscount:
-		text = fmt.Sprintf(h.translation.GetText(lang, "promo_apply_ok_discount"), res.DiscountPercent)
+		if res.DiscountAmount > 0 {
+			text = fmt.Sprintf(h.translation.GetText(lang, "promo_apply_ok_discount_amount"), res.DiscountAmount)
+		} else {
+			text = fmt.Sprintf(h.translation.GetText(lang, "promo_apply_ok_discount"), res.DiscountPercent)
+		}
 	case promo.ResultTypeTrial:
 		if res.TrialSkippedActiveSub {
 			text = h.translation.GetText(lang, "promo_apply_ok_trial_has_sub")
@@ -127,7 +127,11 @@ func (h Handler) buildPurchaseHistoryText(ctx context.Context, langCode string,
 				sb.WriteString("\n")
 			}
 		}
-		if p.ExtraHwid > 0 && p.Month > 0 {
+		if p.PurchaseKind == database.PurchaseKindBalanceTopUp {
+			sb.WriteString(tm.GetText(langCode, "purchase_history_balance_topup"))
+		} else if p.PurchaseKind == database.PurchaseKindTrafficPack {
+			sb.WriteString(fmt.Sprintf(tm.GetText(langCode, "purchase_history_traffic_pack"), p.TrafficGB))
+		} else if p.ExtraHwid > 0 && p.Month > 0 {
 			sb.WriteString(fmt.Sprintf(tm.GetText(langCode, "purchase_history_subscription_combo"), formatMonthLabel(langCode, p.Month), p.ExtraHwid))
 		} else if p.ExtraHwid > 0 {
 			sb.WriteString(fmt.Sprintf(tm.GetText(langCode, "purchase_history_subscription_hwid"), p.ExtraHwid))
@@ -193,6 +197,8 @@ func purchaseInvoiceLabel(langCode string, invoiceType database.InvoiceType) str
 		return tm.GetText(langCode, "purchase_history_method_platega_card")
 	case database.InvoiceTypePlategaCrypto:
 		return tm.GetText(langCode, "purchase_history_method_platega_crypto")
+	case database.InvoiceTypeBalance:
+		return tm.GetText(langCode, "purchase_history_method_balance")
 	default:
 		return tm.GetText(langCode, "purchase_history_method_unknown")
 	}
@@ -244,6 +250,8 @@ func purchaseMethodEmoji(invoiceType database.InvoiceType) string {
 		return "⭐️"
 	case database.InvoiceTypeCrypto:
 		return "₿"
+	case database.InvoiceTypeBalance:
+		return "👛"
 	default:
 		return "💰"
 	}
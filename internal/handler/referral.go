@@ -44,18 +44,24 @@ func (h Handler) ReferralCallbackHandler(ctx context.Context, b *bot.Bot, update
 		stats.EarnedTotal,
 		stats.EarnedLastMonth,
 	)
-	callbackMessage := update.CallbackQuery.Message.Message
-	_, err = editCallbackOriginToHTMLText(ctx, b, callbackMessage, text, models.ParseModeHTML, models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
+	kb := [][]models.InlineKeyboardButton{
 		{
 			h.translation.WithButton(langCode, "share_referral_button", models.InlineKeyboardButton{URL: refLink}),
 		},
 		{
 			h.translation.WithButton(langCode, "referral_list_button", models.InlineKeyboardButton{CallbackData: CallbackReferralList}),
 		},
-		{
-			h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackConnect}),
-		},
-	}}, nil)
+	}
+	if h.partnerDashboardFor(ctx, customer) != nil {
+		kb = append(kb, []models.InlineKeyboardButton{
+			h.translation.WithButton(langCode, "partner_button", models.InlineKeyboardButton{CallbackData: CallbackPartner}),
+		})
+	}
+	kb = append(kb, []models.InlineKeyboardButton{
+		h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackConnect}),
+	})
+	callbackMessage := update.CallbackQuery.Message.Message
+	_, err = editCallbackOriginToHTMLText(ctx, b, callbackMessage, text, models.ParseModeHTML, models.InlineKeyboardMarkup{InlineKeyboard: kb}, nil)
 	logEditError("Error sending referral message", err)
 }
 
@@ -12,6 +12,7 @@ import (
 
 	"remnawave-tg-shop-bot/internal/config"
 	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/promo"
 	"remnawave-tg-shop-bot/internal/remnawave"
 	"remnawave-tg-shop-bot/utils"
 )
@@ -58,9 +59,12 @@ func (h Handler) RenewExtraHwidCallbackHandler(ctx context.Context, b *bot.Bot,
 
 	invoiceType := database.InvoiceType(params["invoiceType"])
 	amt := amount
-	meta := h.checkoutPromoMeta(ctx, customer, invoiceType, &amt)
+	meta := h.checkoutPromoMeta(ctx, customer, promo.NewCheckoutOrder(invoiceType, nil, months, amt), &amt)
 	ctxWithUsername := context.WithValue(ctx, remnawave.CtxKeyUsername, update.CallbackQuery.From.Username)
 	paymentURL, purchaseId, err := h.paymentService.CreateHwidPurchase(ctxWithUsername, float64(amt), extra, customer, invoiceType, meta)
+	if h.handleBalanceCheckout(ctx, b, update, langCode, invoiceType, err, fmt.Sprintf("%s?extra=%d&months=%d", CallbackRenewExtraHwid, extra, months)) {
+		return
+	}
 	if err != nil {
 		slog.Error("Error creating renew hwid payment", "error", err)
 		h.notifyPaymentProviderUnavailable(ctx, b, update, langCode, fmt.Sprintf("%s?extra=%d&months=%d", CallbackRenewExtraHwid, extra, months))
@@ -87,53 +91,11 @@ func (h Handler) RenewExtraHwidCallbackHandler(ctx context.Context, b *bot.Bot,
 }
 
 func (h Handler) showRenewPaymentMethods(ctx context.Context, b *bot.Bot, callbackMessage *models.Message, langCode string, extra, months, amount int) {
-	var keyboard [][]models.InlineKeyboardButton
-
-	if config.IsCryptoPayEnabled() {
-		keyboard = append(keyboard, []models.InlineKeyboardButton{
-			h.translation.WithButton(langCode, "crypto_button", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s?extra=%d&months=%d&invoiceType=%s", CallbackRenewExtraHwid, extra, months, database.InvoiceTypeCrypto)}),
-		})
-	}
-
-	if config.IsYookasaEnabled() {
-		keyboard = append(keyboard, []models.InlineKeyboardButton{
-			h.translation.WithButton(langCode, "card_button", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s?extra=%d&months=%d&invoiceType=%s", CallbackRenewExtraHwid, extra, months, database.InvoiceTypeYookasa)}),
-		})
-	}
-
-	keyboard = h.appendPlategaRenewExtraRows(keyboard, langCode, extra, months)
-
-	if config.IsTelegramStarsEnabled() {
-		shouldShowStarsButton := true
-		if config.RequirePaidPurchaseForStars() {
-			customer, err := h.customerRepository.FindByTelegramId(ctx, callbackMessage.Chat.ID)
-			if err != nil {
-				slog.Error("Error finding customer for stars check", "error", err)
-				shouldShowStarsButton = false
-			} else if customer != nil {
-				hasPaid, err := h.purchaseRepository.HasPaidSubscription(ctx, customer.ID)
-				if err != nil {
-					slog.Error("Error checking paid purchase", "error", err)
-					shouldShowStarsButton = false
-				} else if !hasPaid {
-					shouldShowStarsButton = false
-				}
-			} else {
-				shouldShowStarsButton = false
-			}
-		}
-		if shouldShowStarsButton {
-			keyboard = append(keyboard, []models.InlineKeyboardButton{
-				h.translation.WithButton(langCode, "stars_button", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s?extra=%d&months=%d&invoiceType=%s", CallbackRenewExtraHwid, extra, months, database.InvoiceTypeTelegram)}),
-			})
-		}
-	}
-
-	if config.GetTributeWebHookUrl() != "" {
-		keyboard = append(keyboard, []models.InlineKeyboardButton{
-			h.translation.WithButton(langCode, "tribute_button", models.InlineKeyboardButton{URL: config.GetTributePaymentUrl()}),
-		})
-	}
+	keyboard := h.paymentMethodRows(langCode, func(t database.InvoiceType) string {
+		return fmt.Sprintf("%s?extra=%d&months=%d&invoiceType=%s", CallbackRenewExtraHwid, extra, months, t)
+	}, func() bool {
+		return h.starsAllowedForChat(ctx, callbackMessage.Chat.ID)
+	})
 
 	keyboard = append(keyboard, []models.InlineKeyboardButton{
 		h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackConnect}),
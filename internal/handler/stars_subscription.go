@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/payment"
+	"remnawave-tg-shop-bot/utils"
+)
+
+// starsSubscriptionPaymentRow — кнопка «Stars с автопродлением» на экране способов оплаты; nil, если подписка
+// для этой покупки недоступна (не 1 месяц, Stars скрыты, смена тарифа с бонусными днями).
+func (h Handler) starsSubscriptionPaymentRow(ctx context.Context, langCode string, customer *database.Customer, tidStr, month, amount string, extra int) []models.InlineKeyboardButton {
+	monthInt := parseIntSafe(month)
+	if !h.paymentService.StarsSubscriptionOffered(monthInt) || !h.starsAllowedForPaidPurchase(ctx, customer.TelegramID) {
+		return nil
+	}
+	if tidStr != "" && config.SalesMode() == "tariffs" && h.tariffRepository != nil {
+		_, _, pk, early, err := payment.ResolveTariffPurchase(ctx, h.tariffRepository, customer, parseInt64Safe(tidStr), monthInt, true)
+		if err != nil || pk != database.PurchaseKindSubscription || early {
+			return nil
+		}
+	}
+	cb := paymentCallbackQuery(tidStr, month, string(database.InvoiceTypeTelegram), amount, extra) + "&sub=1"
+	return []models.InlineKeyboardButton{
+		h.translation.WithButton(langCode, "stars_subscription_pay_button", models.InlineKeyboardButton{CallbackData: cb}),
+	}
+}
+
+// StarsSubscriptionsCallbackHandler — экран «Подписки Stars» (stars_sub) и отмена / возобновление продления
+// (stars_sub_c<id> / stars_sub_r<id>).
+func (h Handler) StarsSubscriptionsCallbackHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || update.CallbackQuery.Message.Message == nil {
+		return
+	}
+	langCode := update.CallbackQuery.From.LanguageCode
+	customer, err := h.customerRepository.FindByTelegramId(ctx, update.CallbackQuery.From.ID)
+	if err != nil {
+		slog.Error("Error finding customer", "error", err)
+		return
+	}
+	if customer == nil {
+		slog.Error("customer not exist", "telegramId", utils.MaskHalfInt64(update.CallbackQuery.From.ID))
+		return
+	}
+
+	data := update.CallbackQuery.Data
+	if id, ok := parsePurchaseIDFromPrefix(data, CallbackStarsSubscriptionCancelPrefix); ok {
+		h.toggleStarsSubscription(ctx, customer, id, true)
+	} else if id, ok := parsePurchaseIDFromPrefix(data, CallbackStarsSubscriptionResumePrefix); ok {
+		h.toggleStarsSubscription(ctx, customer, id, false)
+	}
+
+	subs, err := h.paymentService.StarsSubscriptions(ctx, customer.ID)
+	if err != nil {
+		slog.Error("stars subscriptions list", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+		return
+	}
+	_, err = editCallbackOriginToHTMLText(ctx, b, update.CallbackQuery.Message.Message, h.buildStarsSubscriptionsText(langCode, subs), models.ParseModeHTML, models.InlineKeyboardMarkup{
+		InlineKeyboard: h.buildStarsSubscriptionsMarkup(langCode, subs),
+	}, nil)
+	logEditError("Error sending stars subscriptions message", err)
+}
+
+func (h Handler) toggleStarsSubscription(ctx context.Context, customer *database.Customer, id int64, cancel bool) {
+	_, err := h.paymentService.SetStarsSubscriptionCanceled(ctx, customer, id, cancel)
+	if err != nil && !errors.Is(err, payment.ErrStarsSubscriptionNotFound) && !errors.Is(err, payment.ErrStarsSubscriptionExpired) {
+		slog.Error("stars subscription toggle", "error", err, "subscription_id", id, "cancel", cancel)
+	}
+}
+
+// starsSubscriptionStatusText — состояние продления одной подписки (экран клиента и карточка в админке).
+func (h Handler) starsSubscriptionStatusText(langCode string, sub *database.StarsSubscription) string {
+	date := sub.ExpiresAt.Local().Format("02.01.2006")
+	switch sub.Status {
+	case database.StarsSubscriptionStatusActive:
+		return fmt.Sprintf(h.translation.GetText(langCode, "stars_subscription_status_active"), date)
+	case database.StarsSubscriptionStatusCanceled:
+		return fmt.Sprintf(h.translation.GetText(langCode, "stars_subscription_status_canceled"), date)
+	default:
+		return fmt.Sprintf(h.translation.GetText(langCode, "stars_subscription_status_expired"), date)
+	}
+}
+
+func (h Handler) buildStarsSubscriptionsText(langCode string, subs []database.StarsSubscription) string {
+	var sb strings.Builder
+	sb.WriteString(h.translation.GetText(langCode, "stars_subscriptions_title"))
+	sb.WriteString("\n\n")
+	if len(subs) == 0 {
+		sb.WriteString(h.translation.GetText(langCode, "stars_subscriptions_empty"))
+		return sb.String()
+	}
+	for i := range subs {
+		sub := &subs[i]
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(langCode, "stars_subscription_line"), sub.ID, sub.Amount, h.starsSubscriptionStatusText(langCode, sub)))
+		if sub.Renewals > 0 {
+			sb.WriteString("\n")
+			sb.WriteString(fmt.Sprintf(h.translation.GetText(langCode, "stars_subscription_renewals_line"), sub.Renewals))
+		}
+	}
+	return sb.String()
+}
+
+func (h Handler) buildStarsSubscriptionsMarkup(langCode string, subs []database.StarsSubscription) [][]models.InlineKeyboardButton {
+	var kb [][]models.InlineKeyboardButton
+	now := time.Now()
+	for i := range subs {
+		sub := &subs[i]
+		switch {
+		case sub.Status == database.StarsSubscriptionStatusActive:
+			kb = append(kb, []models.InlineKeyboardButton{{
+				Text:         fmt.Sprintf(h.translation.GetText(langCode, "stars_subscription_cancel_button"), sub.ID),
+				CallbackData: CallbackStarsSubscriptionCancelPrefix + strconv.FormatInt(sub.ID, 10),
+			}})
+		case sub.Status == database.StarsSubscriptionStatusCanceled && sub.ExpiresAt.After(now):
+			kb = append(kb, []models.InlineKeyboardButton{{
+				Text:         fmt.Sprintf(h.translation.GetText(langCode, "stars_subscription_resume_button"), sub.ID),
+				CallbackData: CallbackStarsSubscriptionResumePrefix + strconv.FormatInt(sub.ID, 10),
+			}})
+		}
+	}
+	if len(subs) == 0 {
+		kb = append(kb, []models.InlineKeyboardButton{
+			h.translation.WithButton(langCode, "buy_button", models.InlineKeyboardButton{CallbackData: CallbackBuy}),
+		})
+	}
+	kb = append(kb, []models.InlineKeyboardButton{
+		h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackConnect}),
+	})
+	return kb
+}
+
+// adminStarsSubscriptionsHTML — строки о подписках Stars для карточки клиента в админке (пусто, если подписок нет).
+func (h Handler) adminStarsSubscriptionsHTML(ctx context.Context, lang string, cust *database.Customer) string {
+	subs, err := h.paymentService.StarsSubscriptions(ctx, cust.ID)
+	if err != nil {
+		slog.Error("admin user card: stars subscriptions", "error", err, "customer_id", utils.MaskHalfInt64(cust.ID))
+		return ""
+	}
+	var sb strings.Builder
+	for i := range subs {
+		sub := &subs[i]
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "admin_user_card_stars_subscription"),
+			sub.ID, sub.Amount, h.starsSubscriptionStatusText(lang, sub), sub.Renewals))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -14,8 +14,11 @@ import (
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 
+	"remnawave-tg-shop-bot/internal/acquisition"
 	"remnawave-tg-shop-bot/internal/config"
 	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/payment"
+	"remnawave-tg-shop-bot/internal/staff"
 	"remnawave-tg-shop-bot/utils"
 )
 
@@ -39,6 +42,13 @@ func (h Handler) StartCommandHandler(ctx context.Context, b *bot.Bot, update *mo
 			return
 		}
 
+		// /start src_<метка> — источник привлечения (рекламная ссылка), только при первом контакте.
+		if tag := acquisition.FromStartText(update.Message.Text); !tag.Empty() {
+			if err := h.customerRepository.SetAcquisitionSource(ctx, existingCustomer.ID, tag.Source, tag.UTM); err != nil {
+				slog.Error("error saving acquisition source", "error", err)
+			}
+		}
+
 		if strings.Contains(update.Message.Text, "ref_") {
 			arg := strings.Split(update.Message.Text, " ")[1]
 			if strings.HasPrefix(arg, "ref_") {
@@ -94,8 +104,19 @@ func (h Handler) StartCommandHandler(ctx context.Context, b *bot.Bot, update *mo
 		return
 	}
 
+	// /start gift_<код> и fam_<код>: после меню (или экрана согласия) — карточка подарка или приглашения в семью;
+	// активация и вступление проходят legal-middleware.
+	giftCode := startGiftCode(update.Message.Text)
+	familyCode := startFamilyCode(update.Message.Text)
+
 	if CustomerNeedsLegalGate(existingCustomer) {
 		h.sendLegalGateMessage(ctx, b, update.Message.Chat.ID, langCode, false)
+		if giftCode != "" {
+			h.sendGiftPreview(ctx, b, update.Message.Chat.ID, langCode, giftCode)
+		}
+		if familyCode != "" {
+			h.sendFamilyPreview(ctx, b, update.Message.Chat.ID, langCode, familyCode)
+		}
 		return
 	}
 
@@ -103,6 +124,21 @@ func (h Handler) StartCommandHandler(ctx context.Context, b *bot.Bot, update *mo
 	inlineKeyboard := h.buildStartKeyboard(existingCustomer, langCode)
 	err = h.sendStartMenu(ctx, b, update.Message.Chat.ID, langCode, inlineKeyboard, existingCustomer, displayName)
 	logEditError("Error sending /start message", err)
+	if giftCode != "" {
+		h.sendGiftPreview(ctx, b, update.Message.Chat.ID, langCode, giftCode)
+	}
+	if familyCode != "" {
+		h.sendFamilyPreview(ctx, b, update.Message.Chat.ID, langCode, familyCode)
+	}
+}
+
+// startGiftCode — код подарка из «/start gift_<код>»; пусто, если параметр другой.
+func startGiftCode(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) < 2 || !strings.HasPrefix(fields[1], payment.GiftDeepLinkPrefix) {
+		return ""
+	}
+	return payment.NormalizeGiftCode(fields[1])
 }
 
 func (h Handler) StartCallbackHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
@@ -320,7 +356,7 @@ func (h Handler) HelpCallbackHandler(ctx context.Context, b *bot.Bot, update *mo
 func (h Handler) buildStartKeyboard(existingCustomer *database.Customer, langCode string) [][]models.InlineKeyboardButton {
 	if cabinetTelegramMinimalismActive() {
 		kb := h.buildCabinetMinimalismCoreRows(langCode, existingCustomer)
-		if existingCustomer != nil && existingCustomer.TelegramID == config.GetAdminTelegramId() {
+		if existingCustomer != nil && staff.IsStaff(existingCustomer.TelegramID) {
 			kb = append(kb, h.adminStartKeyboardRow(langCode))
 		}
 		return kb
@@ -340,6 +376,18 @@ func (h Handler) buildStartKeyboard(existingCustomer *database.Customer, langCod
 		h.translation.WithButton(langCode, "buy_button", models.InlineKeyboardButton{CallbackData: CallbackBuy}),
 	})
 
+	if config.GiftsEnabled() {
+		inlineKeyboard = append(inlineKeyboard, []models.InlineKeyboardButton{
+			h.translation.WithButton(langCode, "gift_button", models.InlineKeyboardButton{CallbackData: CallbackGift}),
+		})
+	}
+
+	if config.BalanceEnabled() {
+		inlineKeyboard = append(inlineKeyboard, []models.InlineKeyboardButton{
+			h.translation.WithButton(langCode, "balance_button", models.InlineKeyboardButton{CallbackData: CallbackBalance}),
+		})
+	}
+
 	// 3. Подключиться (если есть подписка)
 	if existingCustomer.SubscriptionLink != nil {
 		inlineKeyboard = append(inlineKeyboard, h.resolveConnectButton(langCode))
@@ -365,7 +413,7 @@ func (h Handler) buildStartKeyboard(existingCustomer *database.Customer, langCod
 		h.translation.WithButton(langCode, "help_button", models.InlineKeyboardButton{CallbackData: "help"}),
 	})
 
-	if existingCustomer.TelegramID == config.GetAdminTelegramId() {
+	if staff.IsStaff(existingCustomer.TelegramID) {
 		inlineKeyboard = append(inlineKeyboard, h.adminStartKeyboardRow(langCode))
 	}
 
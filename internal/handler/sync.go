@@ -6,10 +6,13 @@ import (
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"log/slog"
+
+	"remnawave-tg-shop-bot/internal/audit"
 )
 
 func (h Handler) SyncUsersCommandHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	h.syncService.Sync()
+	audit.Record(ctx, audit.Bot(update.Message.From.ID), audit.Entry{Action: audit.ActionSyncRun})
 	_, err := b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID: update.Message.Chat.ID,
 		Text:   "Users synced",
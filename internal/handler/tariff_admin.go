@@ -15,8 +15,11 @@ import (
 	"github.com/go-telegram/bot/models"
 	"github.com/google/uuid"
 
+	"remnawave-tg-shop-bot/internal/audit"
 	"remnawave-tg-shop-bot/internal/config"
 	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/staff"
+	"remnawave-tg-shop-bot/utils"
 )
 
 const (
@@ -36,6 +39,8 @@ const (
 	tariffCallbackCancel = "tf_ca"
 	tariffCallbackWizCan = "tf_wc"
 	tariffCallbackDs     = "tf_ds"
+	tariffCallbackFm     = "tf_fm"
+	tariffCallbackTp     = "tf_tp"
 )
 
 type tariffWizardDraft struct {
@@ -262,7 +267,7 @@ func (h Handler) editAdminTariffsMessage(ctx context.Context, b *bot.Bot, chatID
 
 // AdminTariffsHandler — корень раздела тарифов.
 func (h Handler) AdminTariffsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermTariffs) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -278,7 +283,7 @@ func (h Handler) AdminTariffsHandler(ctx context.Context, b *bot.Bot, update *mo
 
 // AdminTariffNewHandler — старт мастера создания.
 func (h Handler) AdminTariffNewHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermTariffs) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -308,7 +313,7 @@ func (h Handler) AdminTariffNewHandler(ctx context.Context, b *bot.Bot, update *
 
 // AdminTariffCallbackRouter — callback с префиксом tf_ (кроме tf_new — отдельная регистрация).
 func (h Handler) AdminTariffCallbackRouter(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermTariffs) {
 		return
 	}
 	data := update.CallbackQuery.Data
@@ -333,7 +338,7 @@ func (h Handler) AdminTariffCallbackRouter(ctx context.Context, b *bot.Bot, upda
 		h.AdminTariffSquadClearHandler(ctx, b, update)
 	case tariffCallbackAll:
 		h.AdminTariffSquadAllHandler(ctx, b, update)
-	case tariffCallbackNm, tariffCallbackTT, tariffCallbackTD, tariffCallbackTL, tariffCallbackEp, tariffCallbackDs:
+	case tariffCallbackNm, tariffCallbackTT, tariffCallbackTD, tariffCallbackTL, tariffCallbackEp, tariffCallbackDs, tariffCallbackFm, tariffCallbackTp:
 		h.AdminTariffEditAskHandler(ctx, b, update)
 	case tariffCallbackCancel:
 		h.AdminTariffEditCancelHandler(ctx, b, update)
@@ -346,7 +351,7 @@ func (h Handler) AdminTariffCallbackRouter(ctx context.Context, b *bot.Bot, upda
 
 // AdminTariffTextHandler — ввод в мастере и при редактировании.
 func (h Handler) AdminTariffTextHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.Message == nil || update.Message.From.ID != config.GetAdminTelegramId() {
+	if update.Message == nil || !staff.Can(update.Message.From.ID, staff.PermTariffs) {
 		return
 	}
 	adminID := update.Message.From.ID
@@ -494,7 +499,7 @@ func (h Handler) AdminTariffTextHandler(ctx context.Context, b *bot.Bot, update
 			if len(text) < 1 || len(text) > 200 {
 				return
 			}
-			_ = h.tariffRepository.UpdateTariff(ctx, editID, map[string]interface{}{"name": text})
+			_ = h.updateTariffAudited(ctx, adminID, editID, map[string]interface{}{"name": text})
 			tid := editID
 			adminTariffReset(adminID)
 			h.sendAdminTariffFullCard(ctx, b, adminID, lang, tid, h.translation.GetText(lang, "tariff_edit_saved_name"))
@@ -510,7 +515,7 @@ func (h Handler) AdminTariffTextHandler(ctx context.Context, b *bot.Bot, update
 			} else {
 				tb = gb * bytesInGB()
 			}
-			_ = h.tariffRepository.UpdateTariff(ctx, editID, map[string]interface{}{"traffic_limit_bytes": tb})
+			_ = h.updateTariffAudited(ctx, adminID, editID, map[string]interface{}{"traffic_limit_bytes": tb})
 			tid := editID
 			adminTariffReset(adminID)
 			h.sendAdminTariffFullCard(ctx, b, adminID, lang, tid, h.translation.GetText(lang, "tariff_edit_saved_traffic"))
@@ -520,7 +525,7 @@ func (h Handler) AdminTariffTextHandler(ctx context.Context, b *bot.Bot, update
 			if err != nil || dev < 1 {
 				return
 			}
-			_ = h.tariffRepository.UpdateTariff(ctx, editID, map[string]interface{}{"device_limit": dev})
+			_ = h.updateTariffAudited(ctx, adminID, editID, map[string]interface{}{"device_limit": dev})
 			tid := editID
 			adminTariffReset(adminID)
 			h.sendAdminTariffFullCard(ctx, b, adminID, lang, tid, h.translation.GetText(lang, "tariff_edit_saved_devices"))
@@ -530,11 +535,21 @@ func (h Handler) AdminTariffTextHandler(ctx context.Context, b *bot.Bot, update
 			if err != nil || tier < 1 || tier > 10 {
 				return
 			}
-			_ = h.tariffRepository.UpdateTariff(ctx, editID, map[string]interface{}{"tier_level": tier, "sort_order": tier})
+			_ = h.updateTariffAudited(ctx, adminID, editID, map[string]interface{}{"tier_level": tier, "sort_order": tier})
 			tid := editID
 			adminTariffReset(adminID)
 			h.sendAdminTariffFullCard(ctx, b, adminID, lang, tid, h.translation.GetText(lang, "tariff_edit_saved_tier"))
 			return
+		case "family":
+			n, err := strconv.Atoi(text)
+			if err != nil || n < 0 || n > 20 {
+				return
+			}
+			_ = h.updateTariffAudited(ctx, adminID, editID, map[string]interface{}{"family_max_members": n})
+			tid := editID
+			adminTariffReset(adminID)
+			h.sendAdminTariffFullCard(ctx, b, adminID, lang, tid, h.translation.GetText(lang, "tariff_edit_saved_family"))
+			return
 		case "prices":
 			parts := strings.SplitN(text, "|", 2)
 			if len(parts) != 2 {
@@ -560,26 +575,44 @@ func (h Handler) AdminTariffTextHandler(ctx context.Context, b *bot.Bot, update
 					return
 				}
 			}
+			before := h.tariffAuditState(ctx, editID)
 			if err := h.tariffRepository.ReplaceAllPrices(ctx, editID, rub, stars); err != nil {
 				slog.Error("replace prices", "error", err)
 				return
 			}
+			h.recordTariffUpdate(ctx, adminID, editID, before)
 			tid := editID
 			adminTariffReset(adminID)
 			h.sendAdminTariffFullCard(ctx, b, adminID, lang, tid, h.translation.GetText(lang, "tariff_edit_saved_prices"))
 			return
+		case "packs":
+			packs, err := parseTrafficPacksInput(text)
+			if err != nil {
+				_, _ = b.SendMessage(ctx, &bot.SendMessageParams{ChatID: adminID, ParseMode: models.ParseModeHTML, Text: h.translation.GetText(lang, "tariff_err_traffic_packs")})
+				return
+			}
+			before := h.tariffAuditState(ctx, editID)
+			if err := h.tariffRepository.ReplaceTrafficPacks(ctx, editID, packs); err != nil {
+				slog.Error("replace traffic packs", "error", err)
+				return
+			}
+			h.recordTariffUpdate(ctx, adminID, editID, before)
+			tid := editID
+			adminTariffReset(adminID)
+			h.sendAdminTariffFullCard(ctx, b, adminID, lang, tid, h.translation.GetText(lang, "tariff_edit_saved_traffic_packs"))
+			return
 		case "description":
 			low := strings.ToLower(text)
 			if low == "-" || low == "—" {
-				_ = h.tariffRepository.UpdateTariff(ctx, editID, map[string]interface{}{"description": nil})
+				_ = h.updateTariffAudited(ctx, adminID, editID, map[string]interface{}{"description": nil})
 			} else {
-				if utf16UnitsLen(msgText) > 1024 {
+				if utils.UTF16UnitsLen(msgText) > 1024 {
 					_, _ = b.SendMessage(ctx, &bot.SendMessageParams{ChatID: adminID, Text: h.translation.GetText(lang, "tariff_err_description_len")})
 					return
 				}
 				ents := append([]models.MessageEntity(nil), update.Message.Entities...)
-				toStore := strings.TrimSpace(messageEntitiesToHTML(msgText, ents))
-				_ = h.tariffRepository.UpdateTariff(ctx, editID, map[string]interface{}{"description": toStore})
+				toStore := strings.TrimSpace(utils.MessageEntitiesToHTML(msgText, ents))
+				_ = h.updateTariffAudited(ctx, adminID, editID, map[string]interface{}{"description": toStore})
 			}
 			tid := editID
 			adminTariffReset(adminID)
@@ -589,6 +622,57 @@ func (h Handler) AdminTariffTextHandler(ctx context.Context, b *bot.Bot, update
 	}
 }
 
+// parseTrafficPacksInput — пакеты трафика построчно: «ГБ РУБ [STARS]»; «-» — убрать все пакеты.
+func parseTrafficPacksInput(s string) ([]database.TariffTrafficPack, error) {
+	s = strings.TrimSpace(s)
+	if s == "-" || s == "—" {
+		return nil, nil
+	}
+	var out []database.TariffTrafficPack
+	seen := map[int]bool{}
+	for _, line := range strings.Split(s, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("bad")
+		}
+		gb, err1 := strconv.Atoi(fields[0])
+		rub, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil || gb <= 0 || rub <= 0 || seen[gb] {
+			return nil, fmt.Errorf("bad")
+		}
+		p := database.TariffTrafficPack{TrafficGB: gb, PriceRub: rub}
+		if len(fields) == 3 {
+			stars, err := strconv.Atoi(fields[2])
+			if err != nil || stars < 0 {
+				return nil, fmt.Errorf("bad")
+			}
+			p.PriceStars = stars
+		}
+		seen[gb] = true
+		out = append(out, p)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("bad")
+	}
+	return out, nil
+}
+
+// formatTrafficPacksAdmin — пакеты в формате ввода, чтобы админ мог скопировать и поправить.
+func formatTrafficPacksAdmin(packs []database.TariffTrafficPack) string {
+	lines := make([]string, 0, len(packs))
+	for _, p := range packs {
+		line := fmt.Sprintf("%d %d", p.TrafficGB, p.PriceRub)
+		if p.PriceStars > 0 {
+			line += fmt.Sprintf(" %d", p.PriceStars)
+		}
+		lines = append(lines, line)
+	}
+	return "<code>" + strings.Join(lines, "\n") + "</code>"
+}
+
 func parseFourStars(s string) ([4]*int, error) {
 	s = strings.TrimSpace(s)
 	s = strings.ReplaceAll(s, " ", "")
@@ -658,12 +742,56 @@ func (h Handler) finalizeTariffWizard(ctx context.Context, adminID int64, d *tar
 	if err != nil {
 		return 0, err
 	}
+	audit.Record(ctx, audit.Bot(adminID), audit.Entry{
+		Action: audit.ActionTariffCreate, TargetType: "tariff", TargetID: strconv.FormatInt(id, 10),
+		After: h.tariffAuditState(ctx, id),
+	})
 	return id, nil
 }
 
+// tariffAuditState — тариф с ценами и пакетами трафика для журнала действий; nil, если тарифа нет.
+func (h Handler) tariffAuditState(ctx context.Context, id int64) map[string]any {
+	t, err := h.tariffRepository.GetByID(ctx, id)
+	if err != nil || t == nil {
+		return nil
+	}
+	st := audit.Row(t)
+	prices, _ := h.tariffRepository.ListPricesForTariff(ctx, id)
+	rows := make([]map[string]any, 0, len(prices))
+	for i := range prices {
+		rows = append(rows, audit.Row(&prices[i]))
+	}
+	st["prices"] = rows
+	packs, _ := h.tariffRepository.ListTrafficPacks(ctx, id)
+	rows = make([]map[string]any, 0, len(packs))
+	for i := range packs {
+		rows = append(rows, audit.Row(&packs[i]))
+	}
+	st["traffic_packs"] = rows
+	return st
+}
+
+// recordTariffUpdate пишет в журнал изменение тарифа относительно состояния before.
+func (h Handler) recordTariffUpdate(ctx context.Context, adminID, id int64, before map[string]any) {
+	audit.Record(ctx, audit.Bot(adminID), audit.Entry{
+		Action: audit.ActionTariffUpdate, TargetType: "tariff", TargetID: strconv.FormatInt(id, 10),
+		Before: before, After: h.tariffAuditState(ctx, id),
+	})
+}
+
+// updateTariffAudited — UpdateTariff с записью изменившихся полей в журнал действий.
+func (h Handler) updateTariffAudited(ctx context.Context, adminID, id int64, fields map[string]interface{}) error {
+	before := h.tariffAuditState(ctx, id)
+	if err := h.tariffRepository.UpdateTariff(ctx, id, fields); err != nil {
+		return err
+	}
+	h.recordTariffUpdate(ctx, adminID, id, before)
+	return nil
+}
+
 // AdminTariffViewHandler — карточка тарифа (prefix tf_v?i=).
 func (h Handler) AdminTariffViewHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermTariffs) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -685,8 +813,9 @@ func (h Handler) AdminTariffViewHandler(ctx context.Context, b *bot.Bot, update
 		return
 	}
 	prices, _ := h.tariffRepository.ListPricesForTariff(ctx, id)
+	packs, _ := h.tariffRepository.ListTrafficPacks(ctx, id)
 	nPur, _ := h.tariffRepository.CountPurchasesForTariff(ctx, id)
-	text := h.formatTariffCard(lang, t, prices, nPur)
+	text := h.formatTariffCard(lang, t, prices, packs, nPur)
 	kb := h.tariffAdminCardKeyboard(lang, id, t)
 	_, err = b.EditMessageText(ctx, &bot.EditMessageTextParams{
 		ChatID:      msg.Chat.ID,
@@ -715,8 +844,14 @@ func (h Handler) tariffAdminCardKeyboard(lang string, id int64, t *database.Tari
 			h.translation.WithButton(lang, "tariff_btn_devices", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s?i=%d", tariffCallbackTD, id)}),
 			h.translation.WithButton(lang, "tariff_btn_traffic", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s?i=%d", tariffCallbackTT, id)}),
 		},
-		{h.translation.WithButton(lang, "tariff_btn_tier", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s?i=%d", tariffCallbackTL, id)})},
-		{h.translation.WithButton(lang, "tariff_btn_prices", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s?i=%d", tariffCallbackEp, id)})},
+		{
+			h.translation.WithButton(lang, "tariff_btn_tier", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s?i=%d", tariffCallbackTL, id)}),
+			h.translation.WithButton(lang, "tariff_btn_family", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s?i=%d", tariffCallbackFm, id)}),
+		},
+		{
+			h.translation.WithButton(lang, "tariff_btn_prices", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s?i=%d", tariffCallbackEp, id)}),
+			h.translation.WithButton(lang, "tariff_btn_traffic_packs", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s?i=%d", tariffCallbackTp, id)}),
+		},
 		{h.translation.WithButton(lang, "tariff_btn_servers", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s?i=%d", tariffCallbackSrv, id)})},
 		{
 			h.translation.WithButton(lang, activeLabel, models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s?i=%d", tariffCallbackToggle, id)}),
@@ -732,8 +867,9 @@ func (h Handler) sendAdminTariffFullCard(ctx context.Context, b *bot.Bot, adminI
 		return
 	}
 	prices, _ := h.tariffRepository.ListPricesForTariff(ctx, tid)
+	packs, _ := h.tariffRepository.ListTrafficPacks(ctx, tid)
 	nPur, _ := h.tariffRepository.CountPurchasesForTariff(ctx, tid)
-	body := h.formatTariffCard(lang, t, prices, nPur)
+	body := h.formatTariffCard(lang, t, prices, packs, nPur)
 	text := body
 	if strings.TrimSpace(topBanner) != "" {
 		text = topBanner + "\n\n" + body
@@ -747,7 +883,7 @@ func (h Handler) sendAdminTariffFullCard(ctx context.Context, b *bot.Bot, adminI
 	})
 }
 
-func (h Handler) formatTariffCard(lang string, t *database.Tariff, prices []database.TariffPrice, nPurch int64) string {
+func (h Handler) formatTariffCard(lang string, t *database.Tariff, prices []database.TariffPrice, packs []database.TariffTrafficPack, nPurch int64) string {
 	dim := config.DaysInMonth()
 	var sb strings.Builder
 	name := escapeHTML(displayTariffName(t))
@@ -760,6 +896,9 @@ func (h Handler) formatTariffCard(lang string, t *database.Tariff, prices []data
 	if t.TierLevel != nil {
 		sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "tariff_admin_card_tier"), *t.TierLevel))
 	}
+	if t.FamilyMaxMembers > 0 {
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "tariff_admin_card_family"), t.FamilyMaxMembers))
+	}
 	sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "tariff_admin_card_sort"), t.SortOrder))
 	sb.WriteString(h.translation.GetText(lang, "tariff_admin_card_params_header"))
 	var trafficVal string
@@ -781,6 +920,9 @@ func (h Handler) formatTariffCard(lang string, t *database.Tariff, prices []data
 		sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "tariff_price_line"), days, p.Months, p.AmountRub, starPart))
 		sb.WriteString("\n")
 	}
+	if len(packs) > 0 {
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "tariff_admin_card_traffic_packs"), formatTrafficPacksAdmin(packs)))
+	}
 	sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "tariff_admin_card_purchases"), nPurch))
 	sq := strings.TrimSpace(t.ActiveInternalSquadUUIDs)
 	if sq == "" {
@@ -794,9 +936,9 @@ func (h Handler) formatTariffCard(lang string, t *database.Tariff, prices []data
 	return sb.String()
 }
 
-// AdminTariffEditAskHandler — префиксы tf_nm, tf_tt, tf_td, tf_tl, tf_ep, tf_ds.
+// AdminTariffEditAskHandler — префиксы tf_nm, tf_tt, tf_td, tf_tl, tf_ep, tf_ds, tf_fm, tf_tp.
 func (h Handler) AdminTariffEditAskHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermTariffs) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -835,6 +977,20 @@ func (h Handler) AdminTariffEditAskHandler(ctx context.Context, b *bot.Bot, upda
 	case tariffCallbackTL:
 		field = "tier"
 		prompt = fmt.Sprintf(h.translation.GetText(lang, "tariff_edit_prompt_tier"), dn)
+	case tariffCallbackFm:
+		field = "family"
+		prompt = fmt.Sprintf(h.translation.GetText(lang, "tariff_edit_prompt_family"), dn, t.FamilyMaxMembers)
+	case tariffCallbackTp:
+		field = "packs"
+		packs, err := h.tariffRepository.ListTrafficPacks(ctx, id)
+		if err != nil {
+			slog.Error("tariff traffic packs", "error", err)
+		}
+		cur := h.translation.GetText(lang, "tariff_edit_description_none")
+		if len(packs) > 0 {
+			cur = formatTrafficPacksAdmin(packs)
+		}
+		prompt = fmt.Sprintf(h.translation.GetText(lang, "tariff_edit_prompt_traffic_packs"), dn, cur)
 	case tariffCallbackEp:
 		field = "prices"
 		prompt = fmt.Sprintf(h.translation.GetText(lang, "tariff_edit_prompt_prices"), dn)
@@ -874,7 +1030,7 @@ func (h Handler) AdminTariffEditAskHandler(ctx context.Context, b *bot.Bot, upda
 
 // AdminTariffEditCancelHandler — отмена ввода при редактировании поля (возврат к карточке).
 func (h Handler) AdminTariffEditCancelHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermTariffs) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -890,7 +1046,7 @@ func (h Handler) AdminTariffEditCancelHandler(ctx context.Context, b *bot.Bot, u
 
 // AdminTariffWizardCancelHandler — отмена мастера создания (сообщение с шагом заменяется на текст + к списку).
 func (h Handler) AdminTariffWizardCancelHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermTariffs) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -916,7 +1072,7 @@ func (h Handler) AdminTariffWizardCancelHandler(ctx context.Context, b *bot.Bot,
 
 // AdminTariffToggleHandler — tf_t?i=.
 func (h Handler) AdminTariffToggleHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermTariffs) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -926,13 +1082,13 @@ func (h Handler) AdminTariffToggleHandler(ctx context.Context, b *bot.Bot, updat
 		_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: cb.ID})
 		return
 	}
-	_ = h.tariffRepository.UpdateTariff(ctx, id, map[string]interface{}{"is_active": !t.IsActive})
+	_ = h.updateTariffAudited(ctx, cb.From.ID, id, map[string]interface{}{"is_active": !t.IsActive})
 	h.AdminTariffViewHandler(ctx, b, update)
 }
 
 // AdminTariffDeleteAskHandler — tf_d?
 func (h Handler) AdminTariffDeleteAskHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermTariffs) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -958,18 +1114,22 @@ func (h Handler) AdminTariffDeleteAskHandler(ctx context.Context, b *bot.Bot, up
 
 // AdminTariffDeleteYesHandler — tf_y?
 func (h Handler) AdminTariffDeleteYesHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermTariffs) {
 		return
 	}
 	cb := update.CallbackQuery
 	lang := cb.From.LanguageCode
 	id, _ := strconv.ParseInt(parseCallbackData(cb.Data)["i"], 10, 64)
 	msg := cb.Message.Message
+	before := h.tariffAuditState(ctx, id)
 	if err := h.tariffRepository.DeleteTariff(ctx, id); err != nil {
 		slog.Error("tariff delete", "error", err)
 		_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{CallbackQueryID: cb.ID, Text: h.translation.GetText(lang, "tariff_err_delete"), ShowAlert: true})
 		return
 	}
+	audit.Record(ctx, audit.Bot(cb.From.ID), audit.Entry{
+		Action: audit.ActionTariffDelete, TargetType: "tariff", TargetID: strconv.FormatInt(id, 10), Before: before,
+	})
 	_, _ = b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
 		CallbackQueryID: cb.ID,
 		Text:            h.translation.GetText(lang, "tariff_deleted_ok"),
@@ -983,7 +1143,7 @@ func (h Handler) AdminTariffDeleteYesHandler(ctx context.Context, b *bot.Bot, up
 
 // AdminTariffServersHandler — список squads.
 func (h Handler) AdminTariffServersHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermTariffs) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1058,7 +1218,7 @@ func (h Handler) wizardCancelKeyboard(lang string) [][]models.InlineKeyboardButt
 
 // AdminTariffSquadToggleHandler — tf_q?
 func (h Handler) AdminTariffSquadToggleHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermTariffs) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1088,23 +1248,23 @@ func (h Handler) AdminTariffSquadToggleHandler(ctx context.Context, b *bot.Bot,
 		parts = append(parts, x.String())
 	}
 	newStr := strings.Join(parts, ",")
-	_ = h.tariffRepository.UpdateTariff(ctx, id, map[string]interface{}{"active_internal_squad_uuids": newStr})
+	_ = h.updateTariffAudited(ctx, cb.From.ID, id, map[string]interface{}{"active_internal_squad_uuids": newStr})
 	h.AdminTariffServersHandler(ctx, b, update)
 }
 
 // AdminTariffSquadClearHandler — tf_sc?
 func (h Handler) AdminTariffSquadClearHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermTariffs) {
 		return
 	}
 	id, _ := strconv.ParseInt(parseCallbackData(update.CallbackQuery.Data)["i"], 10, 64)
-	_ = h.tariffRepository.UpdateTariff(ctx, id, map[string]interface{}{"active_internal_squad_uuids": ""})
+	_ = h.updateTariffAudited(ctx, update.CallbackQuery.From.ID, id, map[string]interface{}{"active_internal_squad_uuids": ""})
 	h.AdminTariffServersHandler(ctx, b, update)
 }
 
 // AdminTariffSquadAllHandler — tf_sa?
 func (h Handler) AdminTariffSquadAllHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
-	if update.CallbackQuery == nil || update.CallbackQuery.From.ID != config.GetAdminTelegramId() {
+	if update.CallbackQuery == nil || !staff.Can(update.CallbackQuery.From.ID, staff.PermTariffs) {
 		return
 	}
 	cb := update.CallbackQuery
@@ -1117,6 +1277,6 @@ func (h Handler) AdminTariffSquadAllHandler(ctx context.Context, b *bot.Bot, upd
 	for _, s := range squads {
 		parts = append(parts, s.UUID.String())
 	}
-	_ = h.tariffRepository.UpdateTariff(ctx, id, map[string]interface{}{"active_internal_squad_uuids": strings.Join(parts, ",")})
+	_ = h.updateTariffAudited(ctx, cb.From.ID, id, map[string]interface{}{"active_internal_squad_uuids": strings.Join(parts, ",")})
 	h.AdminTariffServersHandler(ctx, b, update)
 }
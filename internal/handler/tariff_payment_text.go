@@ -7,6 +7,7 @@ import (
 
 	"remnawave-tg-shop-bot/internal/config"
 	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/payment"
 )
 
 func (h Handler) tariffMinPriceLabel(ctx context.Context, lang string, tariffID int64) string {
@@ -97,7 +98,7 @@ func (h Handler) buildTariffPaymentMethodsHTML(lang string) string {
 }
 
 // switchTariffKind: "" | "upgrade" | "downgrade" — для строк бонуса при смене тарифа (только если upgradeBonusDays > 0).
-func (h Handler) buildTariffCheckoutSummaryHTML(ctx context.Context, lang string, customer *database.Customer, tariffID int64, month, payAmount int, invoiceStars bool, upgradeBonusDays int, extraHwid int, switchTariffKind string) (string, error) {
+func (h Handler) buildTariffCheckoutSummaryHTML(ctx context.Context, lang string, customer *database.Customer, tariffID int64, month int, payAmount float64, currency string, upgradeBonusDays int, extraHwid int, switchTariffKind string) (string, error) {
 	t, err := h.tariffRepository.GetByID(ctx, tariffID)
 	if err != nil || t == nil {
 		return "", err
@@ -137,10 +138,13 @@ func (h Handler) buildTariffCheckoutSummaryHTML(ctx context.Context, lang string
 		sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "payment_tariff_checkout_total_days_line"), totalSubDays))
 		sb.WriteString("\n")
 	}
-	if invoiceStars {
-		sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "payment_tariff_checkout_amount_stars"), payAmount))
-	} else {
-		sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "payment_tariff_checkout_amount_rub"), payAmount))
+	switch {
+	case currency == config.CurrencyStars:
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "payment_tariff_checkout_amount_stars"), int(payAmount)))
+	case payment.IsForeignCurrency(currency):
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "payment_tariff_checkout_amount_currency"), rubStr(payAmount), currency))
+	default:
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(lang, "payment_tariff_checkout_amount_rub"), int(payAmount)))
 	}
 	if config.LoyaltyEnabled() && h.loyaltyTierRepository != nil && customer != nil {
 		if pct, err := h.loyaltyTierRepository.DiscountPercentForXP(ctx, customer.LoyaltyXP); err == nil && pct > 0 {
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/payment"
+	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/utils"
+)
+
+// TrafficPacksCallbackHandler — экран пакетов трафика: расход за период и кнопки пакетов тарифа клиента.
+func (h Handler) TrafficPacksCallbackHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || update.CallbackQuery.Message.Message == nil {
+		return
+	}
+	customer := h.callbackCustomer(ctx, update)
+	if customer == nil {
+		return
+	}
+	h.renderTrafficPacks(ctx, b, update, update.CallbackQuery.From.LanguageCode, customer)
+}
+
+// TrafficPackActionCallbackHandler — покупка пакета (префикс tpk_): выбор способа оплаты (tpk_buy?g=)
+// и счёт (tpk_pay?g=&invoiceType=). Цену считает PaymentService по пакету тарифа, не по callback.
+func (h Handler) TrafficPackActionCallbackHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	if update.CallbackQuery == nil || update.CallbackQuery.Message.Message == nil {
+		return
+	}
+	callback := update.CallbackQuery.Message.Message
+	langCode := update.CallbackQuery.From.LanguageCode
+	customer := h.callbackCustomer(ctx, update)
+	if customer == nil {
+		return
+	}
+	action, _, _ := strings.Cut(update.CallbackQuery.Data, "?")
+	q := parseCallbackData(update.CallbackQuery.Data)
+	gb := parseIntSafe(q["g"])
+
+	switch action {
+	case CallbackTrafficPackBuy:
+		var keyboard [][]models.InlineKeyboardButton
+		for _, p := range h.paymentService.Providers().Enabled() {
+			info := p.Info()
+			// Внешняя оплата (Tribute) не создаёт покупку в боте — пакет по ней не применить.
+			if info.ExternalURL != "" {
+				continue
+			}
+			keyboard = append(keyboard, []models.InlineKeyboardButton{
+				h.translation.WithButton(langCode, info.ButtonKey, models.InlineKeyboardButton{
+					CallbackData: fmt.Sprintf("%s?g=%d&invoiceType=%s", CallbackTrafficPackPay, gb, info.InvoiceType),
+				}),
+			})
+		}
+		keyboard = append(keyboard, []models.InlineKeyboardButton{
+			h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackTrafficPacks}),
+		})
+		_, err := editCallbackOriginToHTMLText(ctx, b, callback, fmt.Sprintf(h.translation.GetText(langCode, "traffic_pack_choose_method"), gb),
+			models.ParseModeHTML, models.InlineKeyboardMarkup{InlineKeyboard: keyboard}, nil)
+		logEditError("Error sending traffic pack methods message", err)
+	case CallbackTrafficPackPay:
+		invoiceType := database.InvoiceType(q["invoiceType"])
+		back := fmt.Sprintf("%s?g=%d", CallbackTrafficPackBuy, gb)
+		if invoiceType == database.InvoiceTypeTelegram && !h.starsAllowedForChat(ctx, callback.Chat.ID) {
+			return
+		}
+		ctxWithUsername := context.WithValue(ctx, remnawave.CtxKeyUsername, update.CallbackQuery.From.Username)
+		paymentURL, purchaseId, err := h.paymentService.CreateTrafficPackPurchase(ctxWithUsername, customer, invoiceType, gb)
+		if h.handleBalanceCheckout(ctx, b, update, langCode, invoiceType, err, back) {
+			return
+		}
+		if err != nil {
+			if text, ok := h.trafficPackErrorText(langCode, err); ok {
+				_, editErr := editCallbackOriginToHTMLText(ctx, b, callback, text, models.ParseModeHTML, models.InlineKeyboardMarkup{
+					InlineKeyboard: [][]models.InlineKeyboardButton{{
+						h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackConnect}),
+					}},
+				}, nil)
+				logEditError("Error sending traffic pack error message", editErr)
+				return
+			}
+			slog.Error("traffic pack: create purchase", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+			h.notifyPaymentProviderUnavailable(ctx, b, update, langCode, back)
+			return
+		}
+		message, err := editCallbackOriginToHTMLText(ctx, b, callback, fmt.Sprintf(h.translation.GetText(langCode, "traffic_pack_pay_text"), gb),
+			models.ParseModeHTML, models.InlineKeyboardMarkup{
+				InlineKeyboard: [][]models.InlineKeyboardButton{{
+					h.translation.WithButton(langCode, "pay_button", models.InlineKeyboardButton{URL: paymentURL}),
+					h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: back}),
+				}},
+			}, nil)
+		if err != nil {
+			logEditError("Error sending traffic pack payment message", err)
+			return
+		}
+		h.cache.Set(purchaseId, message.ID)
+	}
+}
+
+func (h Handler) renderTrafficPacks(ctx context.Context, b *bot.Bot, update *models.Update, langCode string, customer *database.Customer) {
+	kb := [][]models.InlineKeyboardButton{}
+	var text string
+	offer, err := h.paymentService.TrafficPackOffer(ctx, customer)
+	if err != nil {
+		text, _ = h.trafficPackErrorText(langCode, err)
+		if text == "" {
+			slog.Error("traffic pack: offer", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+			text = h.translation.GetText(langCode, "traffic_packs_failed")
+		}
+	} else {
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf(h.translation.GetText(langCode, "traffic_packs_intro"),
+			formatGigabytes(float64(offer.UsedBytes)), formatGigabytes(float64(offer.LimitBytes))))
+		if offer.ExtraBytes > 0 {
+			sb.WriteString("\n")
+			sb.WriteString(fmt.Sprintf(h.translation.GetText(langCode, "traffic_packs_extra_line"), formatGigabytes(float64(offer.ExtraBytes))))
+		}
+		if offer.Exhausted() {
+			sb.WriteString("\n\n")
+			sb.WriteString(h.translation.GetText(langCode, "traffic_packs_exhausted_line"))
+		}
+		text = sb.String()
+		for _, p := range offer.Packs {
+			kb = append(kb, []models.InlineKeyboardButton{{
+				Text:         fmt.Sprintf(h.translation.GetText(langCode, "traffic_pack_button"), p.TrafficGB, p.PriceRub),
+				CallbackData: fmt.Sprintf("%s?g=%d", CallbackTrafficPackBuy, p.TrafficGB),
+			}})
+		}
+	}
+	kb = append(kb, []models.InlineKeyboardButton{
+		h.translation.WithButton(langCode, "back_button", models.InlineKeyboardButton{CallbackData: CallbackConnect}),
+	})
+	_, err = editCallbackOriginToHTMLText(ctx, b, update.CallbackQuery.Message.Message, text, models.ParseModeHTML,
+		models.InlineKeyboardMarkup{InlineKeyboard: kb}, nil)
+	logEditError("Error sending traffic packs screen", err)
+}
+
+// trafficPackErrorText — текст для ожидаемых отказов; false — ошибка неожиданная (панель, БД).
+func (h Handler) trafficPackErrorText(langCode string, err error) (string, bool) {
+	switch {
+	case errors.Is(err, payment.ErrTrafficPacksDisabled):
+		return h.translation.GetText(langCode, "traffic_packs_disabled"), true
+	case errors.Is(err, payment.ErrTrafficPackNoSubscription):
+		return h.translation.GetText(langCode, "traffic_packs_no_subscription"), true
+	case errors.Is(err, payment.ErrTrafficPackUnlimited):
+		return h.translation.GetText(langCode, "traffic_packs_unlimited"), true
+	case errors.Is(err, payment.ErrTrafficPackNotFound):
+		return h.translation.GetText(langCode, "traffic_packs_not_found"), true
+	default:
+		return "", false
+	}
+}
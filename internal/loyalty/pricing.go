@@ -1,6 +1,8 @@
 package loyalty
 
 import (
+	"math"
+
 	"remnawave-tg-shop-bot/internal/config"
 	"remnawave-tg-shop-bot/internal/database"
 	"remnawave-tg-shop-bot/internal/promo"
@@ -30,11 +32,53 @@ func ApplyCombinedPercentDiscount(base int, loyaltyPct, promoPct, cap int) int {
 	return promo.ApplyPercentDiscountInt(base, total)
 }
 
+// ApplyCombinedPercentDiscountAmount — то же для суммы в валюте с центами (см. promo.ApplyPercentDiscount).
+func ApplyCombinedPercentDiscountAmount(base float64, loyaltyPct, promoPct, cap int) float64 {
+	total := CombinedDiscountPercent(loyaltyPct, promoPct, cap)
+	return promo.ApplyPercentDiscount(base, total)
+}
+
+// ApplyCombinedDiscount — ApplyCombinedPercentDiscount, затем фиксированная скидка промокода fixed (в единицах base).
+// Общая скидка не превышает cap% от base; минимум 1.
+func ApplyCombinedDiscount(base int, loyaltyPct, promoPct int, fixed float64, cap int) int {
+	out := ApplyCombinedPercentDiscount(base, loyaltyPct, promoPct, cap)
+	if fixed <= 0 {
+		return out
+	}
+	floor := promo.ApplyPercentDiscountInt(base, CombinedDiscountPercent(100, 0, cap))
+	out -= int(math.Round(fixed))
+	if out < floor {
+		out = floor
+	}
+	if out < 1 {
+		return 1
+	}
+	return out
+}
+
+// ApplyCombinedDiscountAmount — ApplyCombinedDiscount для суммы в валюте с центами; минимум 0.01.
+func ApplyCombinedDiscountAmount(base float64, loyaltyPct, promoPct int, fixed float64, cap int) float64 {
+	out := ApplyCombinedPercentDiscountAmount(base, loyaltyPct, promoPct, cap)
+	if fixed <= 0 {
+		return out
+	}
+	floor := promo.ApplyPercentDiscount(base, CombinedDiscountPercent(100, 0, cap))
+	out = math.Round((out-fixed)*100) / 100
+	if out < floor {
+		out = floor
+	}
+	if out < 0.01 {
+		return 0.01
+	}
+	return out
+}
+
 // XPRubEquivalentForPurchase начисление XP по строке purchase: ₽ или Stars×RUB_PER_STAR, затем минимум LOYALTY_XP_MIN_PER_PURCHASE. Стоимость доп. HWID уже в purchase.amount.
 func XPRubEquivalentForPurchase(p *database.Purchase) int64 {
 	cfg := XPConfig{
-		RubPerStar: config.RubPerStar(),
-		MinPerPaid: config.LoyaltyXPMinPerPaidPurchase(),
+		RubPerStar:   config.RubPerStar(),
+		MinPerPaid:   config.LoyaltyXPMinPerPaidPurchase(),
+		CurrencyRate: config.CurrencyRateRub,
 	}
 	return TotalXPForPurchase(p, cfg)
 }
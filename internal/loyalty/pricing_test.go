@@ -13,3 +13,29 @@ func TestCombinedDiscountPercent(t *testing.T) {
 		t.Fatalf("negative loyalty: got %d want 5", got)
 	}
 }
+
+func TestApplyCombinedDiscount_fixedAmount(t *testing.T) {
+	if got := ApplyCombinedDiscount(1000, 0, 0, 150, 100); got != 850 {
+		t.Fatalf("fixed only: got %d want 850", got)
+	}
+	// 10% лояльности, затем −150 ₽.
+	if got := ApplyCombinedDiscount(1000, 10, 0, 150, 100); got != 750 {
+		t.Fatalf("loyalty + fixed: got %d want 750", got)
+	}
+	// Потолок 50%: не дешевле 500.
+	if got := ApplyCombinedDiscount(1000, 40, 0, 300, 50); got != 500 {
+		t.Fatalf("cap: got %d want 500", got)
+	}
+	if got := ApplyCombinedDiscount(100, 0, 0, 500, 100); got != 1 {
+		t.Fatalf("min: got %d want 1", got)
+	}
+}
+
+func TestApplyCombinedDiscountAmount_fixedAmount(t *testing.T) {
+	if got := ApplyCombinedDiscountAmount(9.99, 0, 0, 1.5, 100); got != 8.49 {
+		t.Fatalf("got %v want 8.49", got)
+	}
+	if got := ApplyCombinedDiscountAmount(10, 0, 0, 20, 100); got != 0.01 {
+		t.Fatalf("min: got %v want 0.01", got)
+	}
+}
@@ -2,6 +2,7 @@ package loyalty
 
 import (
 	"math"
+	"strings"
 
 	"remnawave-tg-shop-bot/internal/database"
 )
@@ -10,27 +11,36 @@ import (
 type XPConfig struct {
 	RubPerStar float64 // ₽ за 1 Star; 0 — без конвертации Stars→₽ (для Stars задайте RUB_PER_STAR)
 	MinPerPaid int64   // минимум XP за успешную оплату, если сумма не дала XP
+	// CurrencyRate — ₽ за единицу валюты счёта (USD, EUR, …); nil или 0 — сумма в этой валюте XP не даёт.
+	CurrencyRate func(currency string) float64
 }
 
-func primaryXPFromAmount(p *database.Purchase, rubPerStar float64) int64 {
+func primaryXPFromAmount(p *database.Purchase, c XPConfig) int64 {
 	if p == nil {
 		return 0
 	}
 	if p.InvoiceType == database.InvoiceTypeTelegram {
-		if rubPerStar <= 0 {
+		if c.RubPerStar <= 0 {
 			return 0
 		}
-		return int64(math.Round(p.Amount * rubPerStar))
+		return int64(math.Round(p.Amount * c.RubPerStar))
+	}
+	if cur := strings.ToUpper(strings.TrimSpace(p.Currency)); cur != "" && cur != "RUB" && cur != "RUR" {
+		if c.CurrencyRate == nil {
+			return 0
+		}
+		return int64(math.Round(p.Amount * c.CurrencyRate(cur)))
 	}
 	return int64(math.Round(p.Amount))
 }
 
-// TotalXPForPurchase полная формула XP по строке purchase: сумма в ₽ или Stars×RUB_PER_STAR (доп. HWID уже в amount) → при нуле минимум LOYALTY_XP_MIN_PER_PURCHASE.
+// TotalXPForPurchase полная формула XP по строке purchase: сумма в ₽, Stars×RUB_PER_STAR или валюта×CURRENCY_RATES (доп. HWID уже в amount) → при нуле минимум LOYALTY_XP_MIN_PER_PURCHASE.
+// Пополнение баланса XP не даёт: XP начисляется, когда деньги с баланса тратятся на покупку.
 func TotalXPForPurchase(p *database.Purchase, c XPConfig) int64 {
-	if p == nil {
+	if p == nil || p.PurchaseKind == database.PurchaseKindBalanceTopUp {
 		return 0
 	}
-	primary := primaryXPFromAmount(p, c.RubPerStar)
+	primary := primaryXPFromAmount(p, c)
 	if primary <= 0 {
 		primary = c.MinPerPaid
 	}
@@ -64,3 +64,31 @@ func TestTotalXPForPurchase_ExtraHwidIncludedInAmount(t *testing.T) {
 		t.Fatalf("got %d want 100", got)
 	}
 }
+
+func TestTotalXPForPurchase_BalanceTopUpGivesNoXP(t *testing.T) {
+	p := &database.Purchase{
+		Amount:       500,
+		InvoiceType:  database.InvoiceTypeYookasa,
+		PurchaseKind: database.PurchaseKindBalanceTopUp,
+	}
+	got := TotalXPForPurchase(p, XPConfig{MinPerPaid: 10})
+	if got != 0 {
+		t.Fatalf("got %d want 0", got)
+	}
+}
+
+func TestTotalXPForPurchase_ForeignCurrencyByRate(t *testing.T) {
+	p := &database.Purchase{Amount: 2.5, Currency: "usd", InvoiceType: database.InvoiceTypeCrypto}
+	rate := func(c string) float64 {
+		if c == "USD" {
+			return 90
+		}
+		return 0
+	}
+	if got := TotalXPForPurchase(p, XPConfig{CurrencyRate: rate}); got != 225 {
+		t.Fatalf("got %d want 225", got)
+	}
+	if got := TotalXPForPurchase(p, XPConfig{MinPerPaid: 5}); got != 5 {
+		t.Fatalf("no rate: got %d want 5", got)
+	}
+}
@@ -225,16 +225,11 @@ func isRetryableError(err error) bool {
 	return errors.Is(err, ErrRetryable) || errors.Is(err, ErrAuth)
 }
 
-// CreateIncome создает чек о доходе
-func (c *Client) CreateIncome(ctx context.Context, amount float64, description string) error {
-	if err := c.ensureAuthenticated(ctx); err != nil {
-		return fmt.Errorf("authentication failed: %w", err)
-	}
-
+// CreateIncome создает чек о доходе и возвращает его UUID в «Мой налог».
+// operationTime — момент оплаты: при повторной отправке из очереди чек датируется им, а не временем запроса.
+func (c *Client) CreateIncome(ctx context.Context, amount float64, description string, operationTime time.Time) (string, error) {
 	incomeURL := fmt.Sprintf("%s/income", c.baseURL)
 
-	now := time.Now()
-
 	service := Service{
 		Name:     description,
 		Amount:   amount,
@@ -249,8 +244,8 @@ func (c *Client) CreateIncome(ctx context.Context, amount float64, description s
 	}
 
 	incomeRequest := CreateIncomeRequest{
-		OperationTime:                   now,
-		RequestTime:                     now,
+		OperationTime:                   operationTime,
+		RequestTime:                     time.Now(),
 		Services:                        []Service{service},
 		TotalAmount:                     fmt.Sprintf("%.2f", amount),
 		Client:                          client,
@@ -260,7 +255,49 @@ func (c *Client) CreateIncome(ctx context.Context, amount float64, description s
 
 	reqBody, err := json.Marshal(incomeRequest)
 	if err != nil {
-		return fmt.Errorf("failed to marshal income request: %w", err)
+		return "", fmt.Errorf("failed to marshal income request: %w", err)
+	}
+
+	var incomeResp CreateIncomeResponse
+	if err := c.doWithRetry(ctx, "create income", incomeURL, reqBody, &incomeResp); err != nil {
+		return "", err
+	}
+
+	receiptUUID := incomeResp.ApprovedReceiptUUID
+	if receiptUUID == "" {
+		receiptUUID = incomeResp.ID
+	}
+	slog.Info("Income receipt created successfully", "id", receiptUUID, "amount", amount)
+	return receiptUUID, nil
+}
+
+// CancelIncome аннулирует ранее созданный чек (например, при возврате средств).
+func (c *Client) CancelIncome(ctx context.Context, receiptUUID, comment string) error {
+	cancelURL := fmt.Sprintf("%s/cancel", c.baseURL)
+
+	now := time.Now()
+	reqBody, err := json.Marshal(CancelIncomeRequest{
+		OperationTime: now,
+		RequestTime:   now,
+		Comment:       comment,
+		ReceiptUUID:   receiptUUID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cancel request: %w", err)
+	}
+
+	if err := c.doWithRetry(ctx, "cancel income", cancelURL, reqBody, nil); err != nil {
+		return err
+	}
+	slog.Info("Income receipt canceled successfully", "id", receiptUUID)
+	return nil
+}
+
+// doWithRetry отправляет POST с авторизацией: несколько быстрых повторов при сетевых/5xx ошибках
+// и переаутентификация при 401/403. out == nil — тело ответа не разбирается.
+func (c *Client) doWithRetry(ctx context.Context, op, requestURL string, reqBody []byte, out interface{}) error {
+	if err := c.ensureAuthenticated(ctx); err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
 	}
 
 	const (
@@ -282,13 +319,13 @@ func (c *Client) CreateIncome(ctx context.Context, amount float64, description s
 		default:
 		}
 
-		err := c.sendIncomeRequest(ctx, incomeURL, reqBody, amount)
+		err := c.sendRequest(ctx, requestURL, reqBody, out)
 		if err == nil {
 			return nil
 		}
 
 		lastErr = err
-		slog.Warn("Failed to create income", "attempt", attempt, "maxRetries", maxRetries, "error", err)
+		slog.Warn("Moynalog request failed", "op", op, "attempt", attempt, "maxRetries", maxRetries, "error", err)
 
 		// Если это ошибка авторизации, очищаем токен и пытаемся переавторизоваться
 		if errors.Is(err, ErrAuth) {
@@ -325,17 +362,17 @@ func (c *Client) CreateIncome(ctx context.Context, amount float64, description s
 		}
 	}
 
-	return fmt.Errorf("failed to create income after %d attempts: %w", maxRetries, lastErr)
+	return fmt.Errorf("failed to %s after %d attempts: %w", op, maxRetries, lastErr)
 }
 
-// sendIncomeRequest отправляет запрос на создание чека
-func (c *Client) sendIncomeRequest(ctx context.Context, incomeURL string, reqBody []byte, amount float64) error {
+// sendRequest отправляет один авторизованный POST-запрос
+func (c *Client) sendRequest(ctx context.Context, requestURL string, reqBody []byte, out interface{}) error {
 	token := c.token.Load()
 	if token == nil {
 		return fmt.Errorf("%w: token is empty", ErrAuth)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", incomeURL, bytes.NewBuffer(reqBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", requestURL, bytes.NewBuffer(reqBody))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -377,11 +414,9 @@ func (c *Client) sendIncomeRequest(ctx context.Context, incomeURL string, reqBod
 		return fmt.Errorf("%w: status %d: %s", ErrClient, resp.StatusCode, b)
 	}
 
-	var incomeResp CreateIncomeResponse
-	if err := json.NewDecoder(resp.Body).Decode(&incomeResp); err != nil {
-		return err
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
 	}
-
-	slog.Info("Income receipt created successfully", "id", incomeResp.ID, "amount", amount)
-	return nil
+	return json.NewDecoder(resp.Body).Decode(out)
 }
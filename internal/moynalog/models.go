@@ -57,12 +57,22 @@ type IncomeClient struct {
 
 // CreateIncomeResponse - ответ на запрос создания дохода
 type CreateIncomeResponse struct {
-	ID            string       `json:"id"`
-	OperationTime time.Time    `json:"operationTime"`
-	RequestTime   time.Time    `json:"requestTime"`
-	Services      []Service    `json:"services"`
-	TotalAmount   string       `json:"totalAmount"`
-	Client        IncomeClient `json:"client"`
-	PaymentType   string       `json:"paymentType"`
-	Status        string       `json:"status"`
+	ApprovedReceiptUUID string       `json:"approvedReceiptUuid"`
+	ID                  string       `json:"id"`
+	OperationTime       time.Time    `json:"operationTime"`
+	RequestTime         time.Time    `json:"requestTime"`
+	Services            []Service    `json:"services"`
+	TotalAmount         string       `json:"totalAmount"`
+	Client              IncomeClient `json:"client"`
+	PaymentType         string       `json:"paymentType"`
+	Status              string       `json:"status"`
+}
+
+// CancelIncomeRequest - структура для запроса аннулирования чека
+type CancelIncomeRequest struct {
+	OperationTime time.Time `json:"operationTime"`
+	RequestTime   time.Time `json:"requestTime"`
+	Comment       string    `json:"comment"`
+	ReceiptUUID   string    `json:"receiptUuid"`
+	PartnerCode   *string   `json:"partnerCode"`
 }
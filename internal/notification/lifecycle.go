@@ -6,9 +6,9 @@ import (
 	"log/slog"
 	"time"
 
-	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 
+	"remnawave-tg-shop-bot/internal/channel"
 	"remnawave-tg-shop-bot/internal/config"
 	"remnawave-tg-shop-bot/internal/database"
 	"remnawave-tg-shop-bot/internal/handler"
@@ -24,7 +24,7 @@ type LifecycleService struct {
 	promoRepo      *database.PromoRepository
 	promoService   *promo.Service
 	remnawaveClient *remnawave.Client
-	bot            *bot.Bot
+	channels       *channel.Router
 	tm             *translation.Manager
 	lifecycleRepo  *LifecycleRepository
 }
@@ -35,7 +35,7 @@ func NewLifecycleService(
 	promoRepo *database.PromoRepository,
 	promoService *promo.Service,
 	remnawaveClient *remnawave.Client,
-	bot *bot.Bot,
+	channels *channel.Router,
 	tm *translation.Manager,
 	lifecycleRepo *LifecycleRepository,
 ) *LifecycleService {
@@ -45,7 +45,7 @@ func NewLifecycleService(
 		promoRepo:      promoRepo,
 		promoService:   promoService,
 		remnawaveClient: remnawaveClient,
-		bot:            bot,
+		channels:       channels,
 		tm:             tm,
 		lifecycleRepo:  lifecycleRepo,
 	}
@@ -76,6 +76,17 @@ func (s *LifecycleService) ProcessLifecycleNotifications() error {
 	return nil
 }
 
+// OnFirstConnected — клиент подключился (вебхук панели user.first_connected): напоминания «не подключился»
+// ему больше не нужны, помечаем их отправленными, не дожидаясь cron.
+func (s *LifecycleService) OnFirstConnected(ctx context.Context, customerID int64) error {
+	for _, kind := range []string{"no_connect_paid", "no_connect_trial"} {
+		if err := s.lifecycleRepo.MarkNotifySent(ctx, customerID, kind, "once"); err != nil {
+			return fmt.Errorf("mark %s: %w", kind, err)
+		}
+	}
+	return nil
+}
+
 func (s *LifecycleService) processNoConnectPaid(ctx context.Context) error {
 	delayHours := config.LifecycleNoConnectDelayHours()
 	maxAgeHours := config.LifecycleNoConnectMaxAgeHours()
@@ -102,7 +113,8 @@ func (s *LifecycleService) sendNoConnectPaidNotify(ctx context.Context, customer
 		return fmt.Errorf("find customer: %w", err)
 	}
 
-	if customer.IsWebOnly || utils.IsSyntheticTelegramID(customer.TelegramID) {
+	to := channel.RecipientOf(customer)
+	if s.channels.Kind(to) == "" {
 		return nil
 	}
 
@@ -118,13 +130,12 @@ func (s *LifecycleService) sendNoConnectPaidNotify(ctx context.Context, customer
 
 	support := s.buildSupportContact()
 	text := fmt.Sprintf(s.tm.GetText(customer.Language, "lifecycle_no_connect_paid"), support)
-	keyboard := s.buildNoConnectKeyboard(customer.Language)
 
-	_, err = s.bot.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:      customer.TelegramID,
-		Text:        text,
-		ParseMode:   models.ParseModeHTML,
-		ReplyMarkup: keyboard,
+	err = s.channels.Send(ctx, to, channel.Message{
+		Subject:   s.tm.GetText(customer.Language, "email_subject_lifecycle_no_connect"),
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+		Keyboard:  s.buildNoConnectKeyboard(customer.Language).InlineKeyboard,
 	})
 	if err != nil {
 		return fmt.Errorf("send message: %w", err)
@@ -164,7 +175,8 @@ func (s *LifecycleService) sendNoConnectTrialNotify(ctx context.Context, custome
 		return fmt.Errorf("find customer: %w", err)
 	}
 
-	if customer.IsWebOnly || utils.IsSyntheticTelegramID(customer.TelegramID) {
+	to := channel.RecipientOf(customer)
+	if s.channels.Kind(to) == "" {
 		return nil
 	}
 
@@ -178,13 +190,12 @@ func (s *LifecycleService) sendNoConnectTrialNotify(ctx context.Context, custome
 
 	support := s.buildSupportContact()
 	text := fmt.Sprintf(s.tm.GetText(customer.Language, "lifecycle_no_connect_trial"), support)
-	keyboard := s.buildNoConnectKeyboard(customer.Language)
 
-	_, err = s.bot.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:      customer.TelegramID,
-		Text:        text,
-		ParseMode:   models.ParseModeHTML,
-		ReplyMarkup: keyboard,
+	err = s.channels.Send(ctx, to, channel.Message{
+		Subject:   s.tm.GetText(customer.Language, "email_subject_lifecycle_no_connect"),
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+		Keyboard:  s.buildNoConnectKeyboard(customer.Language).InlineKeyboard,
 	})
 	if err != nil {
 		return fmt.Errorf("send message: %w", err)
@@ -218,6 +229,12 @@ func (s *LifecycleService) processWinback(ctx context.Context) error {
 }
 
 func (s *LifecycleService) sendWinbackNotify(ctx context.Context, candidate WinbackCandidate) error {
+	to := channel.Recipient{CustomerID: candidate.CustomerID, TelegramID: candidate.TelegramID, Language: candidate.Language, WebOnly: candidate.WebOnly}
+	// Скидку выдаём, только если о ней есть как сообщить.
+	if s.channels.Kind(to) == "" {
+		return nil
+	}
+
 	// Получаем системный promo_code __lifecycle_winback__
 	lifecyclePromo, err := s.promoRepo.FindByCode(ctx, "__LIFECYCLE_WINBACK__")
 	if err != nil || lifecyclePromo == nil {
@@ -250,13 +267,12 @@ func (s *LifecycleService) sendWinbackNotify(ctx context.Context, candidate Winb
 		timeLeft,
 	)
 
-	keyboard := s.buildWinbackKeyboard(candidate.Language, finalDiscount.Percent)
-
-	_, err = s.bot.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:      candidate.TelegramID,
-		Text:        text,
-		ParseMode:   models.ParseModeHTML,
-		ReplyMarkup: keyboard,
+	err = s.channels.Send(ctx, to, channel.Message{
+		Subject:   s.tm.GetText(candidate.Language, "email_subject_lifecycle_winback"),
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+		Keyboard:  s.buildWinbackKeyboard(candidate.Language, finalDiscount.Percent).InlineKeyboard,
+		Marketing: true,
 	})
 	if err != nil {
 		return fmt.Errorf("send message: %w", err)
@@ -7,6 +7,23 @@ import (
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
+// webOnlyHasEmail — web-only клиенту можно написать на email привязанного аккаунта кабинета.
+const webOnlyHasEmail = `EXISTS (
+				SELECT 1 FROM cabinet_account_customer_link l
+				JOIN cabinet_account a ON a.id = l.account_id
+				WHERE l.customer_id = c.id AND l.link_status = 'linked' AND a.status = 'active'
+				  AND a.email IS NOT NULL AND a.email_verified_at IS NOT NULL
+			)`
+
+// webOnlyHasMarketingEmail — то же, но клиент не отписался от рекламных писем (winback — предложение со скидкой).
+const webOnlyHasMarketingEmail = `EXISTS (
+				SELECT 1 FROM cabinet_account_customer_link l
+				JOIN cabinet_account a ON a.id = l.account_id
+				WHERE l.customer_id = c.id AND l.link_status = 'linked' AND a.status = 'active'
+				  AND a.email IS NOT NULL AND a.email_verified_at IS NOT NULL
+				  AND a.marketing_email_opt_out_at IS NULL
+			)`
+
 type LifecycleRepository struct {
 	pool *pgxpool.Pool
 }
@@ -58,7 +75,7 @@ func (r *LifecycleRepository) FindNoConnectPaidCandidates(ctx context.Context, d
 			c.expire_at > NOW()
 			AND fp.first_paid_at <= NOW() - INTERVAL '1 hour' * $1
 			AND fp.first_paid_at >= NOW() - INTERVAL '1 hour' * $2
-			AND NOT c.is_web_only
+			AND (NOT c.is_web_only OR ` + webOnlyHasEmail + `)
 			AND c.telegram_id > 0
 			AND NOT EXISTS (
 				SELECT 1 FROM customer_lifecycle_notify_sent ln
@@ -104,7 +121,7 @@ func (r *LifecycleRepository) FindNoConnectTrialCandidates(ctx context.Context,
 			AND c.subscription_period_start <= NOW() - INTERVAL '1 hour' * $1
 			AND c.subscription_period_start >= NOW() - INTERVAL '1 hour' * $2
 			AND COALESCE(pc.cnt, 0) = 0
-			AND NOT c.is_web_only
+			AND (NOT c.is_web_only OR ` + webOnlyHasEmail + `)
 			AND c.telegram_id > 0
 			AND NOT EXISTS (
 				SELECT 1 FROM customer_lifecycle_notify_sent ln
@@ -140,12 +157,13 @@ func (r *LifecycleRepository) FindWinbackCandidates(ctx context.Context, daysAft
 				c.telegram_id,
 				c.language,
 				c.expire_at,
+				c.is_web_only,
 				DATE_PART('day', NOW() - c.expire_at)::int as days_expired
 			FROM customer c
 			WHERE 
 				c.expire_at <= NOW()
 				AND c.subscription_link IS NOT NULL
-				AND NOT c.is_web_only
+				AND (NOT c.is_web_only OR ` + webOnlyHasMarketingEmail + `)
 				AND c.telegram_id > 0
 				AND DATE_PART('day', NOW() - c.expire_at)::int = $1
 		),
@@ -166,7 +184,8 @@ func (r *LifecycleRepository) FindWinbackCandidates(ctx context.Context, daysAft
 			te.id,
 			te.telegram_id,
 			te.language,
-			te.expire_at
+			te.expire_at,
+			te.is_web_only
 		FROM target_expires te
 		INNER JOIN has_paid hp ON hp.customer_id = te.id
 		WHERE NOT EXISTS (
@@ -189,7 +208,7 @@ func (r *LifecycleRepository) FindWinbackCandidates(ctx context.Context, daysAft
 	var result []WinbackCandidate
 	for rows.Next() {
 		var c WinbackCandidate
-		if err := rows.Scan(&c.CustomerID, &c.TelegramID, &c.Language, &c.ExpireAt); err != nil {
+		if err := rows.Scan(&c.CustomerID, &c.TelegramID, &c.Language, &c.ExpireAt, &c.WebOnly); err != nil {
 			return nil, err
 		}
 		result = append(result, c)
@@ -202,4 +221,5 @@ type WinbackCandidate struct {
 	TelegramID int64
 	Language   string
 	ExpireAt   time.Time
+	WebOnly    bool
 }
@@ -0,0 +1,284 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/google/uuid"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/handler"
+	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/internal/translation"
+	"remnawave-tg-shop-bot/utils"
+)
+
+// kind уведомлений по событиям панели в customer_lifecycle_notify_sent и customer_notification.
+// Исчерпание трафика делит kind с TrafficService, чтобы вебхук и cron не дублировали друг друга.
+const (
+	notifyKindPanelExpired        = "panel_expired"
+	notifyKindPanelDisabled       = "panel_disabled"
+	notifyKindPanelFirstConnected = "panel_first_connected"
+	notifyKindPanelDeviceAdded    = "panel_device_added"
+)
+
+// PanelEventService обрабатывает вебхуки Remnawave: сверяет клиента с панелью и уведомляет его —
+// в Telegram или, для клиентов кабинета без чата, в ленту кабинета.
+type PanelEventService struct {
+	customerRepo     *database.CustomerRepository
+	notificationRepo *database.CustomerNotificationRepository
+	remnawaveClient  *remnawave.Client
+	trafficService   *TrafficService
+	lifecycleService *LifecycleService
+	bot              *bot.Bot
+	tm               *translation.Manager
+	lifecycleRepo    *LifecycleRepository
+}
+
+func NewPanelEventService(
+	customerRepo *database.CustomerRepository,
+	notificationRepo *database.CustomerNotificationRepository,
+	remnawaveClient *remnawave.Client,
+	trafficService *TrafficService,
+	lifecycleService *LifecycleService,
+	bot *bot.Bot,
+	tm *translation.Manager,
+	lifecycleRepo *LifecycleRepository,
+) *PanelEventService {
+	return &PanelEventService{
+		customerRepo:     customerRepo,
+		notificationRepo: notificationRepo,
+		remnawaveClient:  remnawaveClient,
+		trafficService:   trafficService,
+		lifecycleService: lifecycleService,
+		bot:              bot,
+		tm:               tm,
+		lifecycleRepo:    lifecycleRepo,
+	}
+}
+
+// WebhookHandler — приёмник вебхуков панели: подпись X-Remnawave-Signature, затем HandleEvent.
+func (s *PanelEventService) WebhookHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), time.Second*60)
+		defer cancel()
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			slog.Error("remnawave webhook: read body error", "error", err)
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if !remnawave.VerifyWebhookSignature(body, r.Header.Get(remnawave.WebhookSignatureHeader), config.RemnawaveWebhookSecret()) {
+			slog.Warn("remnawave webhook: bad signature")
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		ev, err := remnawave.ParseWebhookEvent(body)
+		if err != nil {
+			slog.Error("remnawave webhook: unmarshal error", "error", err)
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		if err := s.HandleEvent(ctx, ev); err != nil {
+			slog.Error("remnawave webhook: handle event error", "event", ev.Event, "error", err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// HandleEvent применяет событие панели. Данные события могут устареть (панель шлёт их асинхронно),
+// поэтому пользователь перечитывается из панели и решения принимаются по актуальной карточке.
+func (s *PanelEventService) HandleEvent(ctx context.Context, ev *remnawave.WebhookEvent) error {
+	switch ev.Event {
+	case remnawave.WebhookEventUserExpired, remnawave.WebhookEventUserTrafficReached, remnawave.WebhookEventUserLimited,
+		remnawave.WebhookEventUserDisabled, remnawave.WebhookEventUserFirstConnected, remnawave.WebhookEventHwidDeviceAdded:
+	default:
+		slog.Debug("remnawave webhook: event ignored", "event", ev.Event)
+		return nil
+	}
+
+	evUser, err := ev.User()
+	if err != nil {
+		return fmt.Errorf("parse user: %w", err)
+	}
+	customer, err := remnawave.CustomerFromAdminSearchUser(ctx, s.customerRepo, *evUser)
+	if err != nil {
+		return fmt.Errorf("find customer: %w", err)
+	}
+	if customer == nil {
+		slog.Warn("remnawave webhook: customer not found", "event", ev.Event, "username", evUser.Username)
+		return nil
+	}
+	user := evUser
+	if evUser.UUID != uuid.Nil {
+		user, err = s.remnawaveClient.GetUserByUUID(ctx, evUser.UUID)
+		if err != nil {
+			return fmt.Errorf("get panel user: %w", err)
+		}
+	}
+	if err := s.syncCustomer(ctx, customer, user); err != nil {
+		return err
+	}
+
+	lang := customer.Language
+	switch ev.Event {
+	case remnawave.WebhookEventUserExpired:
+		// Клиент мог продлить подписку, пока вебхук был в пути.
+		if user.ExpireAt.After(time.Now()) {
+			return nil
+		}
+		return s.deliver(ctx, customer, notifyKindPanelExpired, user.ExpireAt.UTC().Format(time.RFC3339),
+			s.tm.GetText(lang, "panel_user_expired"),
+			[][]models.InlineKeyboardButton{{handler.SubscriptionExpiringRenewInlineButton(lang, s.tm)}})
+
+	case remnawave.WebhookEventUserTrafficReached, remnawave.WebhookEventUserLimited:
+		if user.TrafficLimitBytes <= 0 || int64(user.UserTraffic.UsedTrafficBytes) < user.TrafficLimitBytes {
+			return nil
+		}
+		if s.trafficService != nil {
+			handled, err := s.trafficService.NotifyExhausted(ctx, customer, user)
+			if err != nil || handled {
+				return err
+			}
+		}
+		return s.deliver(ctx, customer, notifyKindTrafficExhausted, trafficExhaustedRef(user),
+			s.tm.GetText(lang, "panel_traffic_reached"),
+			[][]models.InlineKeyboardButton{{s.tm.WithButton(lang, "buy_button", models.InlineKeyboardButton{CallbackData: handler.CallbackBuy})}})
+
+	case remnawave.WebhookEventUserDisabled:
+		// Пауза отключает пользователя в панели сама — о ней клиент уже знает.
+		if customer.PausedUntil != nil || !strings.EqualFold(user.Status, "DISABLED") {
+			return nil
+		}
+		return s.deliver(ctx, customer, notifyKindPanelDisabled, ev.Timestamp.UTC().Format(time.RFC3339),
+			s.tm.GetText(lang, "panel_user_disabled"), nil)
+
+	case remnawave.WebhookEventUserFirstConnected:
+		if s.lifecycleService != nil {
+			if err := s.lifecycleService.OnFirstConnected(ctx, customer.ID); err != nil {
+				return err
+			}
+		}
+		return s.deliver(ctx, customer, notifyKindPanelFirstConnected, "once",
+			s.tm.GetText(lang, "panel_user_first_connected"), nil)
+
+	case remnawave.WebhookEventHwidDeviceAdded:
+		device, err := ev.Device()
+		if err != nil {
+			return fmt.Errorf("parse device: %w", err)
+		}
+		if device == nil || device.Hwid == "" {
+			return nil
+		}
+		return s.deliver(ctx, customer, notifyKindPanelDeviceAdded, device.Hwid+":"+device.CreatedAt.UTC().Format(time.RFC3339),
+			fmt.Sprintf(s.tm.GetText(lang, "panel_device_added"), html.EscapeString(deviceTitle(device))),
+			[][]models.InlineKeyboardButton{{s.tm.WithButton(lang, "manage_devices_button", models.InlineKeyboardButton{CallbackData: handler.CallbackManageDevices})}})
+	}
+	return nil
+}
+
+// syncCustomer переносит срок и ссылку подписки из панели, как это делает синхронизация.
+func (s *PanelEventService) syncCustomer(ctx context.Context, customer *database.Customer, user *remnawave.User) error {
+	if user.UserTraffic.OnlineAt != nil && !user.UserTraffic.OnlineAt.IsZero() {
+		if err := s.customerRepo.UpdateFields(ctx, customer.ID, map[string]interface{}{"online_at": *user.UserTraffic.OnlineAt}); err != nil {
+			return fmt.Errorf("update online_at: %w", err)
+		}
+	}
+	updates := map[string]interface{}{}
+	if !user.ExpireAt.IsZero() && (customer.ExpireAt == nil || !customer.ExpireAt.Equal(user.ExpireAt)) {
+		updates["expire_at"] = user.ExpireAt
+	}
+	if user.SubscriptionUrl != "" && (customer.SubscriptionLink == nil || *customer.SubscriptionLink != user.SubscriptionUrl) {
+		updates["subscription_link"] = user.SubscriptionUrl
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	if err := s.customerRepo.UpdateFields(ctx, customer.ID, updates); err != nil {
+		return fmt.Errorf("update customer: %w", err)
+	}
+	expireAt, link := user.ExpireAt, user.SubscriptionUrl
+	customer.ExpireAt, customer.SubscriptionLink = &expireAt, &link
+	return nil
+}
+
+// deliver отправляет уведомление один раз на (kind, ref): в Telegram или в ленту кабинета для клиентов без чата.
+func (s *PanelEventService) deliver(ctx context.Context, customer *database.Customer, kind, ref, text string, keyboard [][]models.InlineKeyboardButton) error {
+	sent, err := s.lifecycleRepo.WasNotifySent(ctx, customer.ID, kind, ref)
+	if err != nil {
+		return fmt.Errorf("check sent: %w", err)
+	}
+	if sent {
+		return nil
+	}
+
+	if customer.IsWebOnly || utils.IsSyntheticTelegramID(customer.TelegramID) {
+		if err := s.notificationRepo.Create(ctx, customer.ID, kind, plainText(text)); err != nil {
+			return err
+		}
+	} else {
+		params := &bot.SendMessageParams{
+			ChatID:    customer.TelegramID,
+			Text:      text,
+			ParseMode: models.ParseModeHTML,
+		}
+		if len(keyboard) > 0 {
+			params.ReplyMarkup = models.InlineKeyboardMarkup{InlineKeyboard: keyboard}
+		}
+		if _, err := s.bot.SendMessage(ctx, params); err != nil {
+			// Клиент заблокировал бота — повтор вебхука не поможет.
+			if errors.Is(err, bot.ErrorForbidden) {
+				slog.Warn("remnawave webhook: bot blocked", "customer_id", utils.MaskHalfInt64(customer.ID), "kind", kind)
+				return nil
+			}
+			return fmt.Errorf("send message: %w", err)
+		}
+	}
+
+	if err := s.lifecycleRepo.MarkNotifySent(ctx, customer.ID, kind, ref); err != nil {
+		slog.Error("remnawave webhook: mark sent failed", "customer_id", utils.MaskHalfInt64(customer.ID), "error", err)
+	}
+	slog.Info("remnawave webhook: notify delivered", "customer_id", utils.MaskHalfInt64(customer.ID), "kind", kind)
+	return nil
+}
+
+func deviceTitle(d *remnawave.Device) string {
+	var parts []string
+	if d.DeviceModel != nil && strings.TrimSpace(*d.DeviceModel) != "" {
+		parts = append(parts, strings.TrimSpace(*d.DeviceModel))
+	}
+	if d.Platform != nil && strings.TrimSpace(*d.Platform) != "" {
+		parts = append(parts, strings.TrimSpace(*d.Platform))
+	}
+	if len(parts) == 0 {
+		return d.Hwid
+	}
+	return strings.Join(parts, ", ")
+}
+
+var htmlTagRe = regexp.MustCompile(`<[^>]+>`)
+
+// plainText — текст Telegram-уведомления без HTML-разметки для ленты кабинета.
+func plainText(s string) string {
+	return html.UnescapeString(htmlTagRe.ReplaceAllString(s, ""))
+}
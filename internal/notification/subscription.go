@@ -2,16 +2,16 @@ package notification
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"remnawave-tg-shop-bot/internal/channel"
 	"remnawave-tg-shop-bot/internal/database"
 	"remnawave-tg-shop-bot/internal/handler"
 	"remnawave-tg-shop-bot/internal/payment"
 	"remnawave-tg-shop-bot/internal/translation"
-	"remnawave-tg-shop-bot/utils"
 	"time"
 
-	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 )
 
@@ -32,7 +32,7 @@ type SubscriptionService struct {
 	customerRepository customerRepository
 	purchaseRepository tributeRepository
 	paymentService     paymentProcessor
-	telegramBot        *bot.Bot
+	channels           *channel.Router
 	tm                 *translation.Manager
 	notify             func(context.Context, database.Customer) error
 }
@@ -40,9 +40,9 @@ type SubscriptionService struct {
 func NewSubscriptionService(customerRepository customerRepository,
 	purchaseRepository tributeRepository,
 	paymentService paymentProcessor,
-	telegramBot *bot.Bot,
+	channels *channel.Router,
 	tm *translation.Manager) *SubscriptionService {
-	svc := &SubscriptionService{customerRepository: customerRepository, purchaseRepository: purchaseRepository, paymentService: paymentService, telegramBot: telegramBot, tm: tm}
+	svc := &SubscriptionService{customerRepository: customerRepository, purchaseRepository: purchaseRepository, paymentService: paymentService, channels: channels, tm: tm}
 	svc.notify = svc.sendNotification
 	return svc
 }
@@ -80,6 +80,10 @@ func (s *SubscriptionService) ProcessSubscriptionExpiration() error {
 	tributesProcessed := make(map[int64]bool, len(*latestActiveTributes))
 
 	for _, customer := range *customers {
+		// На паузе срок заморожен: напоминать о продлении и списывать Tribute рано.
+		if customer.PausedUntil != nil && customer.PausedUntil.After(now) {
+			continue
+		}
 		daysUntilExpiration := s.getDaysUntilExpiration(now, *customer.ExpireAt)
 
 		if p, ok := customerIdTributes[customer.ID]; ok {
@@ -148,8 +152,11 @@ func (s *SubscriptionService) getDaysUntilExpiration(now time.Time, expireAt tim
 	return int(duration.Hours() / 24)
 }
 
+// sendNotification напоминает об окончании подписки: в Telegram или письмом web-only клиенту.
+// Клиенту без канала доставки (web-only без подтверждённого email) напоминание не отправляется.
 func (s *SubscriptionService) sendNotification(ctx context.Context, customer database.Customer) error {
-	if customer.IsWebOnly || utils.IsSyntheticTelegramID(customer.TelegramID) {
+	to := channel.RecipientOf(&customer)
+	if s.channels.Kind(to) == "" {
 		return nil
 	}
 	expireDate := customer.ExpireAt.Format("02.01.2006")
@@ -159,16 +166,16 @@ func (s *SubscriptionService) sendNotification(ctx context.Context, customer dat
 		expireDate,
 	)
 
-	_, err := s.telegramBot.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:    customer.TelegramID,
+	err := s.channels.Send(ctx, to, channel.Message{
+		Subject:   s.tm.GetText(customer.Language, "email_subject_subscription_expiring"),
 		Text:      messageText,
 		ParseMode: models.ParseModeHTML,
-		ReplyMarkup: models.InlineKeyboardMarkup{
-			InlineKeyboard: [][]models.InlineKeyboardButton{
-				{handler.SubscriptionExpiringRenewInlineButton(customer.Language, s.tm)},
-			},
+		Keyboard: [][]models.InlineKeyboardButton{
+			{handler.SubscriptionExpiringRenewInlineButton(customer.Language, s.tm)},
 		},
 	})
-
+	if errors.Is(err, channel.ErrNoChannel) {
+		return nil
+	}
 	return err
 }
@@ -162,3 +162,35 @@ func TestSubscriptionService_ProcessSubscriptionExpiration_SkipsAutoRenewWhenLas
 		t.Fatalf("expected purchase repository to query by customer id %d, got %#v", customers[0].ID, pRepo.receivedIDs)
 	}
 }
+
+func TestSubscriptionService_ProcessSubscriptionExpiration_SkipsPausedCustomers(t *testing.T) {
+	expireAt := time.Now().Add(24 * time.Hour)
+	pausedUntil := time.Now().Add(5 * 24 * time.Hour)
+	customers := []database.Customer{
+		{ID: 11, ExpireAt: &expireAt, PausedUntil: &pausedUntil},
+		{ID: 12, ExpireAt: &expireAt},
+	}
+	tributes := []database.Purchase{{CustomerID: 11, Amount: 15, Month: 1}}
+
+	cRepo := &customerRepoMock{customers: &customers}
+	pRepo := &purchaseRepoMock{tributes: &tributes}
+	payMock := &paymentServiceMock{}
+	var notified []int64
+
+	svc := NewSubscriptionService(cRepo, pRepo, payMock, nil, nil)
+	svc.notify = func(ctx context.Context, customer database.Customer) error {
+		notified = append(notified, customer.ID)
+		return nil
+	}
+
+	if err := svc.ProcessSubscriptionExpiration(); err != nil {
+		t.Fatalf("ProcessSubscriptionExpiration returned error: %v", err)
+	}
+
+	if payMock.createCalls != 0 {
+		t.Fatalf("expected no tribute purchase for paused customer, got %d", payMock.createCalls)
+	}
+	if len(notified) != 1 || notified[0] != 12 {
+		t.Fatalf("expected only the active customer to be notified, got %#v", notified)
+	}
+}
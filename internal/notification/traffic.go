@@ -0,0 +1,192 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/handler"
+	"remnawave-tg-shop-bot/internal/payment"
+	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/internal/translation"
+	"remnawave-tg-shop-bot/utils"
+)
+
+// notifyKindTrafficExhausted — kind в customer_lifecycle_notify_sent; ссылка — дата последнего сброса и лимит,
+// поэтому после сброса или докупленного пакета уведомление придёт снова.
+const notifyKindTrafficExhausted = "traffic_exhausted"
+
+type TrafficService struct {
+	customerRepo    *database.CustomerRepository
+	paymentService  *payment.PaymentService
+	remnawaveClient *remnawave.Client
+	bot             *bot.Bot
+	tm              *translation.Manager
+	lifecycleRepo   *LifecycleRepository
+}
+
+func NewTrafficService(
+	customerRepo *database.CustomerRepository,
+	paymentService *payment.PaymentService,
+	remnawaveClient *remnawave.Client,
+	bot *bot.Bot,
+	tm *translation.Manager,
+	lifecycleRepo *LifecycleRepository,
+) *TrafficService {
+	return &TrafficService{
+		customerRepo:    customerRepo,
+		paymentService:  paymentService,
+		remnawaveClient: remnawaveClient,
+		bot:             bot,
+		tm:              tm,
+		lifecycleRepo:   lifecycleRepo,
+	}
+}
+
+// ProcessTrafficNotifications — уведомления о трафике по одной выгрузке пользователей панели:
+// сначала «трафик закончился» с пакетами, затем пороги расхода.
+func (s *TrafficService) ProcessTrafficNotifications(ctx context.Context) error {
+	packs := config.TrafficPacksEnabled() && config.SalesMode() == "tariffs"
+	if !packs && !config.TrafficNotifyEnabled() {
+		return nil
+	}
+	users, err := s.remnawaveClient.GetUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("get remnawave users: %w", err)
+	}
+	if packs {
+		if err := s.processTrafficExhausted(ctx, users); err != nil {
+			slog.Error("traffic: exhausted notifications failed", "error", err)
+		}
+	}
+	if config.TrafficNotifyEnabled() {
+		if err := s.processTrafficThresholds(ctx, users); err != nil {
+			slog.Error("traffic: threshold notifications failed", "error", err)
+		}
+	}
+	return nil
+}
+
+// processTrafficExhausted — уведомление «трафик закончился» с кнопками пакетов тарифа.
+// Один раз на пару (сброс трафика, лимит) — см. notifyKindTrafficExhausted.
+func (s *TrafficService) processTrafficExhausted(ctx context.Context, users []remnawave.User) error {
+	customers, byTelegramID, err := s.limitedCustomers(ctx, users, func(u remnawave.User) bool {
+		return int64(u.UserTraffic.UsedTrafficBytes) >= u.TrafficLimitBytes
+	})
+	if err != nil {
+		return err
+	}
+	if len(customers) == 0 {
+		return nil
+	}
+	slog.Info("traffic: exhausted candidates", "count", len(customers))
+
+	for i := range customers {
+		customer := &customers[i]
+		user := byTelegramID[customer.TelegramID]
+		if _, err := s.sendTrafficExhaustedNotify(ctx, customer, trafficExhaustedRef(&user)); err != nil {
+			slog.Error("traffic: send exhausted notify failed", "customer_id", utils.MaskHalfInt64(customer.ID), "error", err)
+		}
+	}
+	return nil
+}
+
+// limitedCustomers — клиенты с Telegram-чатом для пользователей панели с лимитом трафика, подходящих под match.
+func (s *TrafficService) limitedCustomers(ctx context.Context, users []remnawave.User, match func(u remnawave.User) bool) ([]database.Customer, map[int64]remnawave.User, error) {
+	byTelegramID := make(map[int64]remnawave.User)
+	var telegramIDs []int64
+	for _, u := range users {
+		if u.TelegramID == nil || u.TrafficLimitBytes <= 0 || !match(u) {
+			continue
+		}
+		if _, ok := byTelegramID[*u.TelegramID]; !ok {
+			telegramIDs = append(telegramIDs, *u.TelegramID)
+		}
+		byTelegramID[*u.TelegramID] = u
+	}
+	if len(telegramIDs) == 0 {
+		return nil, byTelegramID, nil
+	}
+	customers, err := s.customerRepo.FindByTelegramIds(ctx, telegramIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("find customers: %w", err)
+	}
+	out := customers[:0]
+	for _, c := range customers {
+		if c.IsWebOnly || utils.IsSyntheticTelegramID(c.TelegramID) {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out, byTelegramID, nil
+}
+
+// NotifyExhausted — то же уведомление для одного клиента (вебхук панели). false — пакеты клиенту недоступны,
+// вызывающий сам решает, чем их заменить; true — уведомление отправлено сейчас или раньше.
+func (s *TrafficService) NotifyExhausted(ctx context.Context, customer *database.Customer, user *remnawave.User) (bool, error) {
+	if !config.TrafficPacksEnabled() || config.SalesMode() != "tariffs" {
+		return false, nil
+	}
+	if customer.IsWebOnly || utils.IsSyntheticTelegramID(customer.TelegramID) {
+		return false, nil
+	}
+	return s.sendTrafficExhaustedNotify(ctx, customer, trafficExhaustedRef(user))
+}
+
+func trafficExhaustedRef(user *remnawave.User) string {
+	reset := "none"
+	if user.LastTrafficResetAt != nil {
+		reset = user.LastTrafficResetAt.UTC().Format("2006-01-02T15:04:05Z")
+	}
+	return fmt.Sprintf("%s:%d", reset, user.TrafficLimitBytes)
+}
+
+func (s *TrafficService) sendTrafficExhaustedNotify(ctx context.Context, customer *database.Customer, ref string) (bool, error) {
+	sent, err := s.lifecycleRepo.WasNotifySent(ctx, customer.ID, notifyKindTrafficExhausted, ref)
+	if err != nil {
+		return false, fmt.Errorf("check sent: %w", err)
+	}
+	if sent {
+		return true, nil
+	}
+	offer, err := s.paymentService.TrafficPackOffer(ctx, customer)
+	if err != nil {
+		if errors.Is(err, payment.ErrTrafficPacksDisabled) || errors.Is(err, payment.ErrTrafficPackNoSubscription) ||
+			errors.Is(err, payment.ErrTrafficPackUnlimited) || errors.Is(err, payment.ErrTrafficPackNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("traffic pack offer: %w", err)
+	}
+	if !offer.Exhausted() {
+		return true, nil
+	}
+
+	var keyboard [][]models.InlineKeyboardButton
+	for _, p := range offer.Packs {
+		keyboard = append(keyboard, []models.InlineKeyboardButton{{
+			Text:         fmt.Sprintf(s.tm.GetText(customer.Language, "traffic_pack_button"), p.TrafficGB, p.PriceRub),
+			CallbackData: fmt.Sprintf("%s?g=%d", handler.CallbackTrafficPackBuy, p.TrafficGB),
+		}})
+	}
+	_, err = s.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      customer.TelegramID,
+		Text:        s.tm.GetText(customer.Language, "traffic_exhausted_notify"),
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: models.InlineKeyboardMarkup{InlineKeyboard: keyboard},
+	})
+	if err != nil {
+		return false, fmt.Errorf("send message: %w", err)
+	}
+
+	if err := s.lifecycleRepo.MarkNotifySent(ctx, customer.ID, notifyKindTrafficExhausted, ref); err != nil {
+		slog.Error("traffic: mark sent failed", "customer_id", utils.MaskHalfInt64(customer.ID), "error", err)
+	}
+	slog.Info("traffic: exhausted notify sent", "customer_id", utils.MaskHalfInt64(customer.ID))
+	return true, nil
+}
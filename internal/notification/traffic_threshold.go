@@ -0,0 +1,131 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/handler"
+	"remnawave-tg-shop-bot/internal/payment"
+	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/utils"
+)
+
+// notifyKindTrafficThreshold — kind порогов расхода трафика; ссылка — период трафика (как у исчерпания) и порог,
+// поэтому каждый порог приходит один раз до следующего сброса или смены лимита.
+const notifyKindTrafficThreshold = "traffic_threshold"
+
+// processTrafficThresholds — уведомление о наибольшем достигнутом пороге TRAFFIC_NOTIFY_THRESHOLDS.
+// Если трафик вырос сразу через несколько порогов, младшие пропускаются.
+func (s *TrafficService) processTrafficThresholds(ctx context.Context, users []remnawave.User) error {
+	thresholds := config.TrafficNotifyThresholds()
+	customers, byTelegramID, err := s.limitedCustomers(ctx, users, func(u remnawave.User) bool {
+		return reachedTrafficThreshold(u.UserTraffic.UsedTrafficBytes, u.TrafficLimitBytes, thresholds) > 0
+	})
+	if err != nil {
+		return err
+	}
+	if len(customers) == 0 {
+		return nil
+	}
+	slog.Info("traffic: threshold candidates", "count", len(customers))
+
+	for i := range customers {
+		customer := &customers[i]
+		user := byTelegramID[customer.TelegramID]
+		threshold := reachedTrafficThreshold(user.UserTraffic.UsedTrafficBytes, user.TrafficLimitBytes, thresholds)
+		if err := s.sendTrafficThresholdNotify(ctx, customer, &user, threshold); err != nil {
+			slog.Error("traffic: send threshold notify failed", "customer_id", utils.MaskHalfInt64(customer.ID), "error", err)
+		}
+	}
+	return nil
+}
+
+// reachedTrafficThreshold — наибольший порог (в процентах), который достиг расход; 0 — ни одного.
+func reachedTrafficThreshold(usedBytes float64, limitBytes int64, thresholds []int) int {
+	if limitBytes <= 0 || usedBytes <= 0 {
+		return 0
+	}
+	reached := 0
+	for _, t := range thresholds {
+		if usedBytes*100 >= float64(limitBytes)*float64(t) && t > reached {
+			reached = t
+		}
+	}
+	return reached
+}
+
+func (s *TrafficService) sendTrafficThresholdNotify(ctx context.Context, customer *database.Customer, user *remnawave.User, threshold int) error {
+	periodRef := trafficExhaustedRef(user)
+	ref := fmt.Sprintf("%s:%d", periodRef, threshold)
+	sent, err := s.lifecycleRepo.WasNotifySent(ctx, customer.ID, notifyKindTrafficThreshold, ref)
+	if err != nil {
+		return fmt.Errorf("check sent: %w", err)
+	}
+	if sent {
+		return nil
+	}
+	// На 100% клиент уже мог получить «трафик закончился» с пакетами — второе сообщение не нужно.
+	if threshold >= 100 {
+		exhaustedSent, err := s.lifecycleRepo.WasNotifySent(ctx, customer.ID, notifyKindTrafficExhausted, periodRef)
+		if err != nil {
+			return fmt.Errorf("check exhausted sent: %w", err)
+		}
+		if exhaustedSent {
+			return s.lifecycleRepo.MarkNotifySent(ctx, customer.ID, notifyKindTrafficThreshold, ref)
+		}
+	}
+
+	lang := customer.Language
+	var keyboard [][]models.InlineKeyboardButton
+	if offer, err := s.paymentService.TrafficPackOffer(ctx, customer); err == nil && len(offer.Packs) > 0 {
+		keyboard = append(keyboard, []models.InlineKeyboardButton{
+			s.tm.WithButton(lang, "traffic_packs_button", models.InlineKeyboardButton{CallbackData: handler.CallbackTrafficPacks}),
+		})
+	} else if err != nil && !errors.Is(err, payment.ErrTrafficPacksDisabled) && !errors.Is(err, payment.ErrTrafficPackNoSubscription) &&
+		!errors.Is(err, payment.ErrTrafficPackUnlimited) && !errors.Is(err, payment.ErrTrafficPackNotFound) {
+		slog.Warn("traffic: threshold pack offer failed", "customer_id", utils.MaskHalfInt64(customer.ID), "error", err)
+	}
+	upgradeKey := "buy_button"
+	if config.SalesMode() == "tariffs" {
+		upgradeKey = "traffic_upgrade_button"
+	}
+	keyboard = append(keyboard, []models.InlineKeyboardButton{
+		s.tm.WithButton(lang, upgradeKey, models.InlineKeyboardButton{CallbackData: handler.CallbackBuy}),
+	})
+
+	used, limit := formatTrafficGB(user.UserTraffic.UsedTrafficBytes), formatTrafficGB(float64(user.TrafficLimitBytes))
+	text := fmt.Sprintf(s.tm.GetText(lang, "traffic_threshold_notify"), threshold, used, limit)
+	if threshold >= 100 {
+		text = fmt.Sprintf(s.tm.GetText(lang, "traffic_threshold_exhausted_notify"), used, limit)
+	}
+	_, err = s.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      customer.TelegramID,
+		Text:        text,
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: models.InlineKeyboardMarkup{InlineKeyboard: keyboard},
+	})
+	if err != nil {
+		return fmt.Errorf("send message: %w", err)
+	}
+
+	if err := s.lifecycleRepo.MarkNotifySent(ctx, customer.ID, notifyKindTrafficThreshold, ref); err != nil {
+		slog.Error("traffic: mark threshold sent failed", "customer_id", utils.MaskHalfInt64(customer.ID), "error", err)
+	}
+	slog.Info("traffic: threshold notify sent", "customer_id", utils.MaskHalfInt64(customer.ID), "threshold", threshold)
+	return nil
+}
+
+func formatTrafficGB(bytes float64) string {
+	if bytes <= 0 {
+		return "0.0"
+	}
+	const bytesInGigabyte = 1073741824
+	return fmt.Sprintf("%.1f", bytes/bytesInGigabyte)
+}
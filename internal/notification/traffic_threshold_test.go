@@ -0,0 +1,25 @@
+package notification
+
+import "testing"
+
+func TestReachedTrafficThreshold(t *testing.T) {
+	const gb = 1 << 30
+	thresholds := []int{80, 95, 100}
+	cases := []struct {
+		used  float64
+		limit int64
+		want  int
+	}{
+		{used: 0, limit: 10 * gb, want: 0},
+		{used: 7.9 * gb, limit: 10 * gb, want: 0},
+		{used: 8 * gb, limit: 10 * gb, want: 80},
+		{used: 9.6 * gb, limit: 10 * gb, want: 95},
+		{used: 12 * gb, limit: 10 * gb, want: 100},
+		{used: 5 * gb, limit: 0, want: 0},
+	}
+	for _, c := range cases {
+		if got := reachedTrafficThreshold(c.used, c.limit, thresholds); got != c.want {
+			t.Fatalf("used=%v limit=%d: got %d, want %d", c.used, c.limit, got, c.want)
+		}
+	}
+}
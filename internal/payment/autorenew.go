@@ -0,0 +1,409 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/promo"
+	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/internal/yookasa"
+	"remnawave-tg-shop-bot/utils"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/google/uuid"
+)
+
+// ErrAutoRenewUnavailable — автопродление выключено в env (YOOKASA_AUTORENEW_ENABLED).
+var ErrAutoRenewUnavailable = errors.New("auto renew is not available")
+
+// ErrAutoRenewNoMethod — у клиента нет сохранённого способа оплаты: сначала нужна обычная оплата картой.
+var ErrAutoRenewNoMethod = errors.New("auto renew: no saved payment method")
+
+// autoRenewBatchLimit — сколько автопродлений обрабатывается за один запуск cron.
+const autoRenewBatchLimit = 100
+
+const (
+	// Сохранение id созданного платежа повторяется сразу: без него поллер и сверка не свяжут покупку со списанием.
+	autoRenewSaveAttempts   = 3
+	autoRenewSaveRetryDelay = 2 * time.Second
+	// autoRenewLookupWindow — в каком окне после создания покупки сверка ищет её платёж в ЮKassa.
+	autoRenewLookupWindow = time.Hour
+)
+
+// AutoRenewStatus — состояние автопродления клиента для бота и кабинета.
+type AutoRenewStatus struct {
+	Available      bool
+	HasMethod      bool
+	Enabled        bool
+	MethodTitle    string
+	FailedAttempts int
+	LastError      string
+	NextAttemptAt  *time.Time
+	LastChargedAt  *time.Time
+}
+
+func (s PaymentService) autoRenewAvailable() bool {
+	return config.YookasaAutoRenewEnabled() && s.autoRenewRepository != nil && s.yookasaClient != nil
+}
+
+func (s PaymentService) AutoRenewStatus(ctx context.Context, customerID int64) (AutoRenewStatus, error) {
+	st := AutoRenewStatus{Available: s.autoRenewAvailable()}
+	if s.autoRenewRepository == nil {
+		return st, nil
+	}
+	ar, err := s.autoRenewRepository.FindByCustomerID(ctx, customerID)
+	if err != nil || ar == nil {
+		return st, err
+	}
+	st.HasMethod = true
+	st.Enabled = ar.Enabled
+	if ar.PaymentMethodTitle != nil {
+		st.MethodTitle = *ar.PaymentMethodTitle
+	}
+	st.FailedAttempts = ar.FailedAttempts
+	if ar.LastError != nil {
+		st.LastError = *ar.LastError
+	}
+	st.NextAttemptAt = ar.NextAttemptAt
+	st.LastChargedAt = ar.LastChargedAt
+	return st, nil
+}
+
+// SetAutoRenew включает/выключает автопродление клиента.
+func (s PaymentService) SetAutoRenew(ctx context.Context, customerID int64, enabled bool) error {
+	if enabled && !s.autoRenewAvailable() {
+		return ErrAutoRenewUnavailable
+	}
+	if s.autoRenewRepository == nil {
+		return ErrAutoRenewNoMethod
+	}
+	ok, err := s.autoRenewRepository.SetEnabled(ctx, customerID, enabled)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrAutoRenewNoMethod
+	}
+	slog.Info("auto renew toggled", "customer_id", utils.MaskHalfInt64(customerID), "enabled", enabled)
+	return nil
+}
+
+// ForgetAutoRenewMethod удаляет сохранённый способ оплаты (отвязка карты).
+func (s PaymentService) ForgetAutoRenewMethod(ctx context.Context, customerID int64) error {
+	if s.autoRenewRepository == nil {
+		return nil
+	}
+	return s.autoRenewRepository.Delete(ctx, customerID)
+}
+
+// withSavePaymentMethod просит ЮKassa сохранить способ оплаты, если это оплата подписки и автопродление включено.
+func (s PaymentService) withSavePaymentMethod(ctx context.Context, months int) context.Context {
+	if months <= 0 || !s.autoRenewAvailable() {
+		return ctx
+	}
+	return context.WithValue(ctx, yookasa.CtxKeySavePaymentMethod, true)
+}
+
+// rememberAutoRenewMethod вызывается после оплаты: сохраняет способ из ctx (вебхук/поллер ЮKassa);
+// для автосписания — сбрасывает счётчик неудач и сообщает клиенту о списании.
+func (s PaymentService) rememberAutoRenewMethod(ctx context.Context, purchase *database.Purchase, customer *database.Customer) {
+	if s.autoRenewRepository == nil || purchase.InvoiceType != database.InvoiceTypeYookasa {
+		return
+	}
+	if purchase.IsAutoRenew {
+		if err := s.autoRenewRepository.RecordSuccess(ctx, customer.ID); err != nil {
+			slog.Error("auto renew: record success", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+		}
+		s.notifyAutoRenew(ctx, customer, fmt.Sprintf(s.translation.GetText(customer.Language, "auto_renew_charged"), int(purchase.Amount)), false)
+		return
+	}
+	m, ok := yookasa.PaidPaymentMethodFromCtx(ctx)
+	if !ok || purchase.Month <= 0 || !config.YookasaAutoRenewEnabled() {
+		return
+	}
+	ar := &database.CustomerAutoRenew{
+		CustomerID:      customer.ID,
+		InvoiceType:     database.InvoiceTypeYookasa,
+		PaymentMethodID: m.ID.String(),
+		Months:          purchase.Month,
+		TariffID:        purchase.TariffID,
+	}
+	if m.Title != "" {
+		title := m.Title
+		ar.PaymentMethodTitle = &title
+	}
+	if err := s.autoRenewRepository.SavePaymentMethod(ctx, ar); err != nil {
+		slog.Error("auto renew: save payment method", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+		return
+	}
+	slog.Info("auto renew: payment method saved", "customer_id", utils.MaskHalfInt64(customer.ID), "months", purchase.Month)
+}
+
+// autoRenewWindow — подписки, истекающие в [from, to], пора продлевать. После expire_at попытки
+// продолжаются, пока не исчерпан лимит (maxAttempts × retryHours).
+func autoRenewWindow(now time.Time, daysBefore, maxAttempts, retryHours int) (from, to time.Time) {
+	return now.Add(-time.Duration(maxAttempts*retryHours) * time.Hour), now.AddDate(0, 0, daysBefore)
+}
+
+// ProcessAutoRenewals списывает продление с сохранённых способов оплаты (cron в main).
+func (s PaymentService) ProcessAutoRenewals(ctx context.Context) {
+	if !s.autoRenewAvailable() {
+		return
+	}
+	now := time.Now().UTC()
+	from, to := autoRenewWindow(now, config.YookasaAutoRenewDaysBefore(), config.YookasaAutoRenewMaxAttempts(), config.YookasaAutoRenewRetryHours())
+	due, err := s.autoRenewRepository.FindDue(ctx, from, to, now, autoRenewBatchLimit)
+	if err != nil {
+		slog.Error("auto renew: find due", "error", err)
+		return
+	}
+	for i := range due {
+		s.chargeAutoRenew(ctx, &due[i], now)
+	}
+}
+
+func (s PaymentService) chargeAutoRenew(ctx context.Context, due *database.AutoRenewDue, now time.Time) {
+	customerID := due.CustomerID
+	retryAt := now.Add(time.Duration(config.YookasaAutoRenewRetryHours()) * time.Hour)
+	claimed, err := s.autoRenewRepository.Claim(ctx, customerID, now, retryAt)
+	if err != nil {
+		slog.Error("auto renew: claim", "error", err, "customer_id", utils.MaskHalfInt64(customerID))
+		return
+	}
+	if !claimed {
+		return
+	}
+	pending, err := s.purchaseRepository.HasPendingAutoRenew(ctx, customerID)
+	if err != nil {
+		slog.Error("auto renew: check pending", "error", err, "customer_id", utils.MaskHalfInt64(customerID))
+		return
+	}
+	if pending {
+		slog.Info("auto renew: previous charge still pending", "customer_id", utils.MaskHalfInt64(customerID))
+		return
+	}
+
+	customer, err := s.customerRepository.FindById(ctx, customerID)
+	if err != nil {
+		slog.Error("auto renew: load customer", "error", err, "customer_id", utils.MaskHalfInt64(customerID))
+		return
+	}
+	if customer == nil {
+		_ = s.autoRenewRepository.Delete(ctx, customerID)
+		return
+	}
+	// На паузе не списываем: срок уже сдвинут, попытка повторится после возобновления.
+	if customer.PausedUntil != nil && customer.PausedUntil.After(now) {
+		slog.Info("auto renew: subscription paused", "customer_id", utils.MaskHalfInt64(customerID))
+		return
+	}
+
+	methodID, err := uuid.Parse(due.PaymentMethodID)
+	if err != nil {
+		s.recordAutoRenewFailure(ctx, customer, 0, retryAt, "invalid saved payment method", true)
+		return
+	}
+	amount, tariffID, err := s.autoRenewAmount(ctx, customer, due)
+	if err != nil {
+		s.recordAutoRenewFailure(ctx, customer, 0, retryAt, err.Error(), true)
+		return
+	}
+
+	pur := &database.Purchase{
+		InvoiceType: database.InvoiceTypeYookasa,
+		Status:      database.PurchaseStatusNew,
+		Amount:      float64(amount),
+		Currency:    "RUB",
+		CustomerID:  customer.ID,
+		Month:       due.Months,
+		TariffID:    tariffID,
+		IsAutoRenew: true,
+	}
+	purchaseID, err := s.purchaseRepository.Create(ctx, pur)
+	if err != nil {
+		slog.Error("auto renew: create purchase", "error", err, "customer_id", utils.MaskHalfInt64(customerID))
+		return
+	}
+
+	ctx = s.ctxWithTelegramUsernameIfMissing(ctx, customer)
+	desc := buildRubReceiptDescription(due.Months, 0, nil, database.InvoiceTypeYookasa)
+	invoice, err := s.yookasaClient.CreateRecurringPayment(ctx, amount, desc, customer.ID, purchaseID, methodID)
+	if err != nil {
+		slog.Error("auto renew: create payment", "error", err, "purchase_id", utils.MaskHalfInt64(purchaseID))
+		if cerr := s.markPurchaseCanceled(ctx, purchaseID); cerr != nil {
+			slog.Error("auto renew: cancel purchase", "error", cerr, "purchase_id", utils.MaskHalfInt64(purchaseID))
+		}
+		s.recordAutoRenewFailure(ctx, customer, amount, retryAt, "provider error", false)
+		return
+	}
+	if err := s.saveAutoRenewPayment(ctx, purchaseID, invoice.ID); err != nil {
+		// Покупка остаётся в new без id платежа: сверка найдёт платёж по purchaseId в metadata (findAutoRenewPayment).
+		slog.Error("auto renew: save payment id", "error", err, "purchase_id", utils.MaskHalfInt64(purchaseID), "yookasa_id", invoice.ID)
+		return
+	}
+
+	switch {
+	case invoice.Paid:
+		username := ""
+		if invoice.Metadata != nil {
+			username = invoice.Metadata["username"]
+		}
+		paidCtx := context.WithValue(ctx, remnawave.CtxKeyUsername, username)
+		if err := s.ProcessPurchaseById(paidCtx, purchaseID); err != nil {
+			// Деньги списаны — довести покупку должен повтор вебхука/поллера, не новое списание.
+			slog.Error("auto renew: process purchase", "error", err, "purchase_id", utils.MaskHalfInt64(purchaseID))
+			return
+		}
+		slog.Info("auto renew: charged", "customer_id", utils.MaskHalfInt64(customerID), "purchase_id", utils.MaskHalfInt64(purchaseID), "amount", amount)
+	case invoice.IsCancelled():
+		if err := s.markPurchaseCanceled(ctx, purchaseID); err != nil {
+			slog.Error("auto renew: cancel purchase", "error", err, "purchase_id", utils.MaskHalfInt64(purchaseID))
+		}
+		reason := "canceled"
+		if invoice.CancellationDetails != nil && invoice.CancellationDetails.Reason != "" {
+			reason = invoice.CancellationDetails.Reason
+		}
+		s.recordAutoRenewFailure(ctx, customer, amount, retryAt, reason, invoice.CancellationDetails.PermissionRevoked())
+	default:
+		// pending: итог придёт вебхуком или поллером ЮKassa, повторно не списываем (HasPendingAutoRenew).
+		slog.Info("auto renew: payment pending", "purchase_id", utils.MaskHalfInt64(purchaseID), "status", invoice.Status)
+	}
+}
+
+// saveAutoRenewPayment записывает id платежа в покупку, повторяя запись при ошибке БД.
+func (s PaymentService) saveAutoRenewPayment(ctx context.Context, purchaseID int64, paymentID uuid.UUID) error {
+	var err error
+	for attempt := 1; attempt <= autoRenewSaveAttempts; attempt++ {
+		if err = s.purchaseRepository.UpdateFields(ctx, purchaseID, map[string]interface{}{
+			"yookasa_id": paymentID,
+			"status":     database.PurchaseStatusPending,
+		}); err == nil {
+			return nil
+		}
+		slog.Warn("auto renew: update purchase", "error", err, "purchase_id", utils.MaskHalfInt64(purchaseID), "attempt", attempt)
+		if attempt == autoRenewSaveAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(autoRenewSaveRetryDelay):
+		}
+	}
+	return err
+}
+
+// findAutoRenewPayment — платёж автосписания, id которого не сохранился в покупку (new без yookasa_id).
+// Ищется по purchaseId в metadata; найденный id записывается в покупку. nil — платёж не создавался.
+func (s PaymentService) findAutoRenewPayment(ctx context.Context, pur *database.Purchase) (*yookasa.Payment, error) {
+	invoice, err := s.yookasaClient.FindPaymentByPurchase(ctx, pur.ID, pur.CreatedAt.Add(-time.Minute), pur.CreatedAt.Add(autoRenewLookupWindow))
+	if err != nil || invoice == nil {
+		return nil, err
+	}
+	slog.Warn("auto renew: found payment missing from purchase", "purchase_id", utils.MaskHalfInt64(pur.ID), "yookasa_id", invoice.ID, "status", invoice.Status)
+	if err := s.purchaseRepository.UpdateFields(ctx, pur.ID, map[string]interface{}{
+		"yookasa_id": invoice.ID,
+		"status":     database.PurchaseStatusPending,
+	}); err != nil {
+		slog.Error("auto renew: save found payment id", "error", err, "purchase_id", utils.MaskHalfInt64(pur.ID))
+	} else {
+		pur.YookasaID, pur.Status = &invoice.ID, database.PurchaseStatusPending
+	}
+	return invoice, nil
+}
+
+// autoRenewAmount — цена продления на тот же срок: текущий тариф клиента (SALES_MODE=tariffs) или PRICE_N,
+// со скидкой лояльности. Разовые промо-скидки к автосписанию не применяются.
+func (s PaymentService) autoRenewAmount(ctx context.Context, customer *database.Customer, due *database.AutoRenewDue) (int, *int64, error) {
+	var amount int
+	var tariffID *int64
+	if config.SalesMode() == "tariffs" && s.tariffRepository != nil {
+		tariffID = due.TariffID
+		if customer.CurrentTariffID != nil && *customer.CurrentTariffID > 0 {
+			tariffID = customer.CurrentTariffID
+		}
+		if tariffID == nil {
+			return 0, nil, fmt.Errorf("no tariff to renew")
+		}
+		tariff, err := s.tariffRepository.GetByID(ctx, *tariffID)
+		if err != nil {
+			return 0, nil, err
+		}
+		if tariff == nil || !tariff.IsActive {
+			return 0, nil, fmt.Errorf("tariff %d is not available", *tariffID)
+		}
+		tp, err := s.tariffRepository.GetPrice(ctx, *tariffID, due.Months)
+		if err != nil {
+			return 0, nil, err
+		}
+		if tp == nil {
+			return 0, nil, fmt.Errorf("no price for tariff %d months %d", *tariffID, due.Months)
+		}
+		amount = tp.AmountRub
+	} else {
+		amount = config.Price(due.Months)
+	}
+	if config.LoyaltyEnabled() && s.loyaltyTierRepository != nil {
+		pct, err := s.loyaltyTierRepository.DiscountPercentForXP(ctx, customer.LoyaltyXP)
+		if err != nil {
+			slog.Error("auto renew: loyalty discount", "error", err)
+		} else if pct > 0 {
+			amount = promo.ApplyPercentDiscountInt(amount, pct)
+		}
+	}
+	if amount <= 0 {
+		return 0, nil, fmt.Errorf("invalid renewal amount")
+	}
+	return amount, tariffID, nil
+}
+
+// recordAutoRenewFailure — dunning: считаем неудачу, после MAX_ATTEMPTS (или отзыва разрешения) выключаем автопродление.
+func (s PaymentService) recordAutoRenewFailure(ctx context.Context, customer *database.Customer, amount int, retryAt time.Time, reason string, permanent bool) {
+	maxAttempts := config.YookasaAutoRenewMaxAttempts()
+	cur, err := s.autoRenewRepository.FindByCustomerID(ctx, customer.ID)
+	if err != nil || cur == nil {
+		slog.Error("auto renew: load state", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+		return
+	}
+	disable := permanent || cur.FailedAttempts+1 >= maxAttempts
+	attempts, err := s.autoRenewRepository.RecordFailure(ctx, customer.ID, reason, &retryAt, disable)
+	if err != nil {
+		slog.Error("auto renew: record failure", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+		return
+	}
+	slog.Warn("auto renew: charge failed", "customer_id", utils.MaskHalfInt64(customer.ID), "reason", reason, "attempts", attempts, "disabled", disable)
+
+	lang := customer.Language
+	var text string
+	if disable {
+		text = s.translation.GetText(lang, "auto_renew_disabled_after_failures")
+	} else {
+		text = fmt.Sprintf(s.translation.GetText(lang, "auto_renew_charge_failed"), amount, config.YookasaAutoRenewRetryHours(), maxAttempts-attempts)
+	}
+	s.notifyAutoRenew(ctx, customer, text, true)
+}
+
+func (s PaymentService) notifyAutoRenew(ctx context.Context, customer *database.Customer, text string, withRenewButton bool) {
+	if skipTelegramCustomerDM(customer) {
+		return
+	}
+	params := &bot.SendMessageParams{
+		ChatID:    customer.TelegramID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	}
+	if withRenewButton {
+		params.ReplyMarkup = models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
+			{s.translation.WithButton(customer.Language, "renew_subscription_button", models.InlineKeyboardButton{CallbackData: "buy"})},
+		}}
+	}
+	if _, err := s.telegramBot.SendMessage(ctx, params); err != nil {
+		slog.Error("auto renew: notify customer", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+	}
+}
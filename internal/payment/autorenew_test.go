@@ -0,0 +1,22 @@
+package payment
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAutoRenewWindow(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	from, to := autoRenewWindow(now, 1, 3, 12)
+	if want := now.Add(-36 * time.Hour); !from.Equal(want) {
+		t.Fatalf("from = %v, want %v", from, want)
+	}
+	if want := now.AddDate(0, 0, 1); !to.Equal(want) {
+		t.Fatalf("to = %v, want %v", to, want)
+	}
+
+	from, to = autoRenewWindow(now, 0, 1, 1)
+	if !to.Equal(now) || !from.Equal(now.Add(-time.Hour)) {
+		t.Fatalf("unexpected window for days_before=0: [%v, %v]", from, to)
+	}
+}
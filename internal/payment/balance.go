@@ -0,0 +1,347 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/utils"
+)
+
+var (
+	ErrBalanceDisabled    = errors.New("customer balance is disabled")
+	ErrBalanceTopUpAmount = errors.New("top-up amount is out of range")
+	// ErrBalanceTopUpMethod — пополнять баланс можно только рублёвыми способами со счётом (не Stars, не Tribute, не сам баланс).
+	ErrBalanceTopUpMethod = errors.New("payment method is not available for top-up")
+	// ErrBalanceTopUpSpent — пополнение уже потрачено, вернуть его провайдером нельзя.
+	ErrBalanceTopUpSpent = errors.New("top-up has already been spent")
+	ErrBalanceAdjustZero = errors.New("adjustment amount must not be zero")
+)
+
+// Balance — текущий баланс клиента в рублях.
+func (s PaymentService) Balance(ctx context.Context, customerID int64) (float64, error) {
+	if s.balanceRepository == nil {
+		return 0, ErrBalanceDisabled
+	}
+	return s.balanceRepository.Get(ctx, customerID)
+}
+
+// BalanceHistory — журнал операций по балансу (новые сверху).
+func (s PaymentService) BalanceHistory(ctx context.Context, customerID int64, limit, offset int) ([]database.BalanceTx, error) {
+	if s.balanceRepository == nil {
+		return nil, ErrBalanceDisabled
+	}
+	return s.balanceRepository.ListByCustomer(ctx, customerID, limit, offset)
+}
+
+// BalanceTopUpMethodAllowed — можно ли пополнить баланс этим способом оплаты.
+func BalanceTopUpMethodAllowed(t database.InvoiceType) bool {
+	switch t {
+	case database.InvoiceTypeBalance, database.InvoiceTypeTelegram, database.InvoiceTypeTribute:
+		return false
+	}
+	return true
+}
+
+// CreateBalanceTopUp выставляет счёт на пополнение баланса через обычного провайдера.
+// Зачисление — в ProcessPurchaseById после оплаты (creditBalanceTopUp).
+func (s PaymentService) CreateBalanceTopUp(ctx context.Context, customer *database.Customer, invoiceType database.InvoiceType, amount int) (url string, purchaseId int64, err error) {
+	if !config.BalanceEnabled() || s.balanceRepository == nil {
+		return "", 0, ErrBalanceDisabled
+	}
+	if amount < config.BalanceTopUpMin() || amount > config.BalanceTopUpMax() {
+		return "", 0, ErrBalanceTopUpAmount
+	}
+	if !BalanceTopUpMethodAllowed(invoiceType) {
+		return "", 0, ErrBalanceTopUpMethod
+	}
+	return s.createInvoice(ctx, invoiceType, InvoiceParams{
+		Amount:   float64(amount),
+		Customer: customer,
+		Extras:   &TariffPurchaseExtras{Kind: database.PurchaseKindBalanceTopUp},
+	})
+}
+
+// creditBalanceTopUp зачисляет оплаченное пополнение (шаг apply outbox) и возвращает баланс после зачисления.
+// Повтор после сбоя упрётся в уникальный индекс (purchase_id, type) и не задвоит сумму.
+func (s PaymentService) creditBalanceTopUp(ctx context.Context, purchase *database.Purchase) (float64, error) {
+	pid := purchase.ID
+	tx, err := s.balanceRepository.Apply(ctx, &database.BalanceTx{
+		CustomerID: purchase.CustomerID,
+		Type:       database.BalanceTxTopUp,
+		Amount:     purchase.Amount,
+		PurchaseID: &pid,
+	})
+	if err != nil && !errors.Is(err, database.ErrBalanceTxDuplicate) {
+		return 0, err
+	}
+	slog.Info("balance top-up processed", "purchase_id", utils.MaskHalfInt64(purchase.ID), "customer_id", utils.MaskHalfInt64(purchase.CustomerID), "amount", purchase.Amount)
+	if tx != nil {
+		return tx.BalanceAfter, nil
+	}
+	return s.balanceRepository.Get(ctx, purchase.CustomerID)
+}
+
+// createBalanceInvoice — покупка с внутреннего баланса: списание и проведение сразу, без внешнего счёта.
+// Покупка создаётся в new и переходит в pending только после списания — поллер не увидит строку без денег.
+func (s PaymentService) createBalanceInvoice(ctx context.Context, in InvoiceParams) (string, int64, error) {
+	if s.balanceRepository == nil {
+		return "", 0, ErrBalanceDisabled
+	}
+	if in.Extras != nil && in.Extras.Kind == database.PurchaseKindBalanceTopUp {
+		return "", 0, ErrBalanceTopUpMethod
+	}
+	pur := &database.Purchase{
+		InvoiceType: database.InvoiceTypeBalance,
+		Status:      database.PurchaseStatusNew,
+		Amount:      in.Amount,
+		Currency:    "RUB",
+		CustomerID:  in.Customer.ID,
+		Month:       in.Months,
+		ExtraHwid:   in.ExtraHwid,
+		TariffID:    in.TariffID,
+	}
+	applyTariffPurchaseExtras(pur, in.Extras)
+	ensurePurchaseKindExtraHwidOnly(pur)
+	if in.Promo != nil {
+		pur.PromoCodeID = in.Promo.PromoCodeID
+		pur.DiscountPercentApplied = in.Promo.DiscountPercentApplied
+	}
+	purchaseId, err := s.purchaseRepository.Create(ctx, pur)
+	if err != nil {
+		slog.Error("Error creating purchase", "error", err)
+		return "", 0, err
+	}
+
+	_, err = s.balanceRepository.Apply(ctx, &database.BalanceTx{
+		CustomerID: in.Customer.ID,
+		Type:       database.BalanceTxPurchase,
+		Amount:     -in.Amount,
+		PurchaseID: &purchaseId,
+	})
+	if err != nil {
+		if cerr := s.markPurchaseCanceled(ctx, purchaseId); cerr != nil {
+			slog.Error("balance: cancel unpaid purchase", "error", cerr, "purchase_id", utils.MaskHalfInt64(purchaseId))
+		}
+		return "", 0, err
+	}
+	if err := s.purchaseRepository.UpdateFields(ctx, purchaseId, map[string]interface{}{
+		"status": database.PurchaseStatusPending,
+	}); err != nil {
+		slog.Error("Error updating purchase", "error", err)
+		return "", 0, err
+	}
+
+	// Ошибка проведения не откатывает списание: оплаченную покупку доводит outbox, не проведённую — поллер.
+	if err := s.ProcessPurchaseById(ctx, purchaseId); err != nil {
+		slog.Error("balance: process purchase", "error", err, "purchase_id", utils.MaskHalfInt64(purchaseId))
+	}
+	return "", purchaseId, nil
+}
+
+// refundToBalance зачисляет возврат на баланс клиента вместо возврата через провайдера.
+func (s PaymentService) refundToBalance(ctx context.Context, c *database.Customer, refund *database.PurchaseRefund) (string, error) {
+	if s.balanceRepository == nil {
+		return "", ErrBalanceDisabled
+	}
+	refundID := refund.ID
+	purchaseID := refund.PurchaseID
+	tx, err := s.balanceRepository.Apply(ctx, &database.BalanceTx{
+		CustomerID: c.ID,
+		Type:       database.BalanceTxRefund,
+		Amount:     refund.Amount,
+		PurchaseID: &purchaseID,
+		RefundID:   &refundID,
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("balance-%d", tx.ID), nil
+}
+
+// debitTopUpForRefund списывает с баланса возвращаемую часть пополнения до вызова провайдера.
+func (s PaymentService) debitTopUpForRefund(ctx context.Context, c *database.Customer, refund *database.PurchaseRefund) error {
+	if s.balanceRepository == nil {
+		return ErrBalanceDisabled
+	}
+	refundID := refund.ID
+	purchaseID := refund.PurchaseID
+	_, err := s.balanceRepository.Apply(ctx, &database.BalanceTx{
+		CustomerID: c.ID,
+		Type:       database.BalanceTxRefund,
+		Amount:     -refund.Amount,
+		PurchaseID: &purchaseID,
+		RefundID:   &refundID,
+	})
+	if errors.Is(err, database.ErrBalanceInsufficient) {
+		return ErrBalanceTopUpSpent
+	}
+	return err
+}
+
+// restoreTopUpAfterFailedRefund возвращает списанное, если провайдер не провёл возврат пополнения.
+func (s PaymentService) restoreTopUpAfterFailedRefund(ctx context.Context, c *database.Customer, refund *database.PurchaseRefund) {
+	purchaseID := refund.PurchaseID
+	comment := fmt.Sprintf("refund #%d failed at provider", refund.ID)
+	if _, err := s.balanceRepository.Apply(ctx, &database.BalanceTx{
+		CustomerID: c.ID,
+		Type:       database.BalanceTxRefund,
+		Amount:     refund.Amount,
+		PurchaseID: &purchaseID,
+		Comment:    &comment,
+	}); err != nil {
+		slog.Error("balance: restore top-up after failed refund", "error", err, "refund_id", refund.ID, "customer_id", utils.MaskHalfInt64(c.ID))
+	}
+}
+
+// AdjustBalance — ручное начисление (amount > 0) или списание (amount < 0) администратором.
+func (s PaymentService) AdjustBalance(ctx context.Context, customerID int64, amount float64, comment string, adminTelegramID, adminAccountID int64) (*database.BalanceTx, error) {
+	if !config.BalanceEnabled() || s.balanceRepository == nil {
+		return nil, ErrBalanceDisabled
+	}
+	amount = math.Round(amount*100) / 100
+	if amount == 0 {
+		return nil, ErrBalanceAdjustZero
+	}
+	t := &database.BalanceTx{
+		CustomerID: customerID,
+		Type:       database.BalanceTxAdmin,
+		Amount:     amount,
+	}
+	if c := strings.TrimSpace(comment); c != "" {
+		t.Comment = &c
+	}
+	if adminTelegramID != 0 {
+		t.AdminTelegramID = &adminTelegramID
+	}
+	if adminAccountID != 0 {
+		t.AdminAccountID = &adminAccountID
+	}
+	out, err := s.balanceRepository.Apply(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("balance adjusted by admin", "customer_id", utils.MaskHalfInt64(customerID), "amount", amount)
+	return out, nil
+}
+
+// referralRewardRub — денежный эквивалент реферального бонуса: дни по цене месяца (PRICE_1).
+func referralRewardRub(days, price1, daysInMonth int) float64 {
+	if days <= 0 || price1 <= 0 || daysInMonth <= 0 {
+		return 0
+	}
+	return math.Round(float64(days)*float64(price1)/float64(daysInMonth)*100) / 100
+}
+
+// grantReferralBalance зачисляет реферальный бонус деньгами (REFERRAL_REWARD_TO_BALANCE).
+func (s PaymentService) grantReferralBalance(ctx context.Context, customer *database.Customer, days int) error {
+	if s.balanceRepository == nil {
+		return ErrBalanceDisabled
+	}
+	amount := referralRewardRub(days, config.Price1(), config.DaysInMonth())
+	if amount <= 0 {
+		return nil
+	}
+	comment := fmt.Sprintf("referral bonus %d days", days)
+	_, err := s.balanceRepository.Apply(ctx, &database.BalanceTx{
+		CustomerID: customer.ID,
+		Type:       database.BalanceTxReferral,
+		Amount:     amount,
+		Comment:    &comment,
+	})
+	return err
+}
+
+// referralBonusText — текст уведомления о бонусе: дни или сумма на балансе.
+func (s PaymentService) referralBonusText(lang, daysKey string, days int) string {
+	if config.ReferralRewardToBalance() {
+		return fmt.Sprintf(s.translation.GetText(lang, daysKey+"_balance"),
+			FormatBalanceRub(referralRewardRub(days, config.Price1(), config.DaysInMonth())))
+	}
+	return fmt.Sprintf(s.translation.GetText(lang, daysKey), days)
+}
+
+// FormatBalanceRub — сумма в рублях для экранов баланса: без копеек, если они нулевые.
+func FormatBalanceRub(v float64) string {
+	if math.Abs(v-math.Round(v)) < 0.005 {
+		return fmt.Sprintf("%d ₽", int64(math.Round(v)))
+	}
+	return fmt.Sprintf("%.2f ₽", v)
+}
+
+func (s PaymentService) notifyBalanceToppedUp(ctx context.Context, c *database.Customer, amount, balance float64) {
+	if s.telegramBot == nil || skipTelegramCustomerDM(c) {
+		return
+	}
+	lang := c.Language
+	_, err := s.telegramBot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    c.TelegramID,
+		Text:      fmt.Sprintf(s.translation.GetText(lang, "balance_topped_up"), FormatBalanceRub(amount), FormatBalanceRub(balance)),
+		ParseMode: models.ParseModeHTML,
+		ReplyMarkup: models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
+			{s.translation.WithButton(lang, "buy_button", models.InlineKeyboardButton{CallbackData: "buy"})},
+			{s.translation.WithButton(lang, "back_button", models.InlineKeyboardButton{CallbackData: "start"})},
+		}},
+	})
+	if err != nil {
+		slog.Error("balance: notify top-up", "error", err, "customer_id", utils.MaskHalfInt64(c.ID))
+	}
+}
+
+// --- Баланс как способ оплаты -------------------------------------------------
+
+// balanceProvider — оплата с внутреннего баланса. Счёт проводится в CreateInvoice,
+// поллер подхватывает только покупки, у которых списание прошло, а проведение упало.
+type balanceProvider struct{ s *PaymentService }
+
+func (p *balanceProvider) Info() ProviderInfo {
+	return ProviderInfo{
+		InvoiceType: database.InvoiceTypeBalance,
+		CheckoutKey: "balance",
+		ButtonKey:   "balance_pay_button",
+		Pollable:    true,
+		Refundable:  true,
+	}
+}
+
+func (p *balanceProvider) Enabled() bool {
+	return config.BalanceEnabled() && p.s.balanceRepository != nil
+}
+
+func (p *balanceProvider) CreateInvoice(ctx context.Context, in InvoiceParams) (string, int64, error) {
+	return p.s.createBalanceInvoice(ctx, in)
+}
+
+func (p *balanceProvider) CheckStatus(ctx context.Context, pur *database.Purchase) (StatusCheck, error) {
+	debit, err := p.s.balanceRepository.FindByPurchase(ctx, pur.ID, database.BalanceTxPurchase)
+	if err != nil {
+		return StatusCheck{}, err
+	}
+	if debit == nil {
+		return StatusCheck{Status: InvoiceStatusCanceled}, nil
+	}
+	return StatusCheck{Status: InvoiceStatusPaid}, nil
+}
+
+func (p *balanceProvider) Cancel(ctx context.Context, pur *database.Purchase) error {
+	return p.s.markPurchaseCanceled(ctx, pur.ID)
+}
+
+func (p *balanceProvider) Refund(ctx context.Context, _ *database.Purchase, c *database.Customer, refund *database.PurchaseRefund) (string, bool, error) {
+	id, err := p.s.refundToBalance(ctx, c, refund)
+	if err != nil {
+		return "", false, err
+	}
+	return id, true, nil
+}
+
+func (p *balanceProvider) Webhook() (string, http.Handler) { return "", nil }
@@ -0,0 +1,54 @@
+package payment
+
+import (
+	"testing"
+
+	"remnawave-tg-shop-bot/internal/database"
+)
+
+func TestReferralRewardRub(t *testing.T) {
+	cases := []struct {
+		days, price1, daysInMonth int
+		want                      float64
+	}{
+		{7, 300, 30, 70},
+		{3, 199, 30, 19.9},
+		{1, 100, 30, 3.33},
+		{0, 300, 30, 0},
+		{7, 0, 30, 0},
+		{7, 300, 0, 0},
+	}
+	for _, c := range cases {
+		if got := referralRewardRub(c.days, c.price1, c.daysInMonth); got != c.want {
+			t.Errorf("referralRewardRub(%d, %d, %d) = %v, want %v", c.days, c.price1, c.daysInMonth, got, c.want)
+		}
+	}
+}
+
+func TestFormatBalanceRub(t *testing.T) {
+	cases := map[float64]string{
+		0:      "0 ₽",
+		500:    "500 ₽",
+		19.9:   "19.90 ₽",
+		-150:   "-150 ₽",
+		3.3333: "3.33 ₽",
+	}
+	for in, want := range cases {
+		if got := FormatBalanceRub(in); got != want {
+			t.Errorf("FormatBalanceRub(%v) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBalanceTopUpMethodAllowed(t *testing.T) {
+	for _, it := range []database.InvoiceType{database.InvoiceTypeBalance, database.InvoiceTypeTelegram, database.InvoiceTypeTribute} {
+		if BalanceTopUpMethodAllowed(it) {
+			t.Errorf("%s must not top up balance", it)
+		}
+	}
+	for _, it := range []database.InvoiceType{database.InvoiceTypeYookasa, database.InvoiceTypeCrypto, database.InvoiceTypePlategaSBP} {
+		if !BalanceTopUpMethodAllowed(it) {
+			t.Errorf("%s must top up balance", it)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+)
+
+// ErrCurrencyNotAccepted — провайдер не выставляет счета в запрошенной валюте.
+var ErrCurrencyNotAccepted = errors.New("currency is not accepted by payment provider")
+
+type invoiceCurrencyCtxKey struct{}
+
+// WithInvoiceCurrency задаёт валюту счёта (сумма в CreatePurchase* уже в этой валюте). Без неё — валюта провайдера по умолчанию.
+func WithInvoiceCurrency(ctx context.Context, currency string) context.Context {
+	return context.WithValue(ctx, invoiceCurrencyCtxKey{}, config.NormalizeCurrency(currency))
+}
+
+// AcceptedCurrencies — валюты счёта провайдера; первая — по умолчанию.
+func (i ProviderInfo) AcceptedCurrencies() []string {
+	if len(i.Currencies) == 0 {
+		return []string{config.CurrencyRUB}
+	}
+	return i.Currencies
+}
+
+// AcceptsCurrency — провайдер выставляет счета в currency.
+func (i ProviderInfo) AcceptsCurrency(currency string) bool {
+	c := config.NormalizeCurrency(currency)
+	for _, v := range i.AcceptedCurrencies() {
+		if v == c {
+			return true
+		}
+	}
+	return false
+}
+
+// invoiceCurrency — валюта нового счёта: из WithInvoiceCurrency, если провайдер её принимает, иначе по умолчанию.
+func invoiceCurrency(ctx context.Context, info ProviderInfo) (string, error) {
+	c, ok := ctx.Value(invoiceCurrencyCtxKey{}).(string)
+	if !ok || c == "" {
+		return info.AcceptedCurrencies()[0], nil
+	}
+	if !info.AcceptsCurrency(c) {
+		return "", fmt.Errorf("%w: %s via %s", ErrCurrencyNotAccepted, c, info.InvoiceType)
+	}
+	return c, nil
+}
+
+// IsForeignCurrency — валюта из PRICE_CURRENCIES: цены в ней задаются отдельно от рублей и Stars.
+func IsForeignCurrency(currency string) bool {
+	c := config.NormalizeCurrency(currency)
+	return c != config.CurrencyRUB && c != config.CurrencyStars
+}
+
+// PickCurrency выбирает валюту счёта: preferred (выбор клиента или валюта его языка), если провайдер её
+// принимает и в ней есть цена, иначе первая принимаемая провайдером валюта с ценой. Рубли и Stars считаются
+// оценёнными всегда: их цены проверяет прежний расчёт.
+func PickCurrency(info ProviderInfo, preferred string, priced func(currency string) bool) string {
+	accepted := info.AcceptedCurrencies()
+	candidates := accepted
+	if preferred != "" && info.AcceptsCurrency(preferred) {
+		candidates = append([]string{config.NormalizeCurrency(preferred)}, accepted...)
+	}
+	for _, c := range candidates {
+		if !IsForeignCurrency(c) || (config.IsPriceCurrency(c) && priced(c)) {
+			return c
+		}
+	}
+	return accepted[0]
+}
+
+// RoundMoney округляет сумму до копеек / центов.
+func RoundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// formatInvoiceAmount — сумма для API провайдера: без дробной части у целых сумм, иначе до сотых.
+func formatInvoiceAmount(v float64) string {
+	return strconv.FormatFloat(RoundMoney(v), 'f', -1, 64)
+}
+
+// HwidAddPriceIn — цена одного доп. устройства за месяц в валюте из PRICE_CURRENCIES: HWID_ADD_PRICE по курсу
+// CURRENCY_RATES. 0 — курс не задан.
+func HwidAddPriceIn(currency string) float64 {
+	rate := config.CurrencyRateRub(currency)
+	if rate <= 0 {
+		return 0
+	}
+	return RoundMoney(float64(config.HwidAddPrice()) / rate)
+}
+
+// SubscriptionPriceIn — полная цена подписки за months месяцев в валюте из PRICE_CURRENCIES, без скидок:
+// цена тарифа (tariffID != nil) или PRICE_<M>_<CUR> плюс продление extraHwid доп. устройств.
+// ok=false — цены в этой валюте нет (или нет курса для доп. устройств).
+func SubscriptionPriceIn(ctx context.Context, tr *database.TariffRepository, tariffID *int64, months, extraHwid int, currency string) (amount float64, ok bool, err error) {
+	c := config.NormalizeCurrency(currency)
+	if tariffID != nil {
+		if tr == nil {
+			return 0, false, nil
+		}
+		amount, ok, err = tr.GetCurrencyPrice(ctx, *tariffID, months, c)
+		if err != nil || !ok {
+			return 0, false, err
+		}
+	} else {
+		amount = config.CurrencyPrice(months, c)
+		if amount <= 0 {
+			return 0, false, nil
+		}
+	}
+	if extraHwid > 0 {
+		perDevice := HwidAddPriceIn(c)
+		if perDevice <= 0 {
+			return 0, false, nil
+		}
+		amount += perDevice * float64(extraHwid*months)
+	}
+	return RoundMoney(amount), true, nil
+}
+
+// CheckoutCurrency — валюта счёта подписки у провайдера invoiceType: requested (явный выбор в кабинете)
+// или валюта языка клиента (LANGUAGE_CURRENCY), если в ней есть цена; иначе валюта провайдера по умолчанию.
+func (s PaymentService) CheckoutCurrency(ctx context.Context, invoiceType database.InvoiceType, customer *database.Customer, requested string, tariffID *int64, months, extraHwid int) string {
+	p, err := s.providerFor(invoiceType)
+	if err != nil {
+		return config.CurrencyRUB
+	}
+	preferred := requested
+	if preferred == "" && customer != nil {
+		preferred = config.CurrencyForLanguage(customer.Language)
+	}
+	return PickCurrency(p.Info(), preferred, func(c string) bool {
+		_, ok, err := SubscriptionPriceIn(ctx, s.tariffRepository, tariffID, months, extraHwid, c)
+		if err != nil {
+			slog.Error("checkout currency price", "error", err, "currency", c)
+		}
+		return ok
+	})
+}
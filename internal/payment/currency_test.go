@@ -0,0 +1,45 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestInvoiceCurrency(t *testing.T) {
+	info := ProviderInfo{InvoiceType: "crypto", Currencies: []string{"RUB", "USD"}}
+
+	if c, err := invoiceCurrency(context.Background(), info); err != nil || c != "RUB" {
+		t.Fatalf("default = %q, %v; want RUB", c, err)
+	}
+	if c, err := invoiceCurrency(WithInvoiceCurrency(context.Background(), "usd"), info); err != nil || c != "USD" {
+		t.Fatalf("usd = %q, %v; want USD", c, err)
+	}
+	if _, err := invoiceCurrency(WithInvoiceCurrency(context.Background(), "EUR"), info); !errors.Is(err, ErrCurrencyNotAccepted) {
+		t.Fatalf("eur err = %v, want ErrCurrencyNotAccepted", err)
+	}
+	if c, err := invoiceCurrency(WithInvoiceCurrency(context.Background(), "RUR"), ProviderInfo{}); err != nil || c != "RUB" {
+		t.Fatalf("rub-only provider = %q, %v; want RUB", c, err)
+	}
+}
+
+func TestPickCurrency_withoutForeignPricesFallsBackToDefault(t *testing.T) {
+	info := ProviderInfo{Currencies: []string{"RUB", "USD"}}
+	priced := func(string) bool { return true }
+
+	// USD не в PRICE_CURRENCIES — цены в нём нет, остаются рубли.
+	if c := PickCurrency(info, "USD", priced); c != "RUB" {
+		t.Fatalf("PickCurrency(USD) = %q, want RUB", c)
+	}
+	if c := PickCurrency(ProviderInfo{Currencies: []string{"STARS"}}, "RUB", priced); c != "STARS" {
+		t.Fatalf("stars provider = %q, want STARS", c)
+	}
+}
+
+func TestFormatInvoiceAmount(t *testing.T) {
+	for in, want := range map[float64]string{100: "100", 9.9: "9.9", 12.345: "12.35", 0.1 + 0.2: "0.3"} {
+		if got := formatInvoiceAmount(in); got != want {
+			t.Errorf("formatInvoiceAmount(%v) = %q, want %q", in, got, want)
+		}
+	}
+}
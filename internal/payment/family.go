@@ -0,0 +1,663 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/utils"
+)
+
+var (
+	ErrFamilyDisabled        = errors.New("family plans are disabled")
+	ErrFamilyNotAvailable    = errors.New("current subscription is not a family plan")
+	ErrFamilyInviteNotFound  = errors.New("family invite not found")
+	ErrFamilyOwnInvite       = errors.New("customer is the family owner")
+	ErrFamilyHasSubscription = errors.New("customer has an active paid subscription")
+	ErrFamilyMemberNotFound  = errors.New("family member not found")
+	ErrFamilyDevicePool      = errors.New("not enough devices in the family pool")
+	ErrFamilyFull            = database.ErrFamilyFull
+	ErrFamilyAlreadyMember   = database.ErrFamilyMemberExists
+
+	errFamilyInviteCodeGenExhausted = errors.New("failed to generate unique family invite code")
+)
+
+// FamilyDeepLinkPrefix — параметр /start для вступления в семью: t.me/<бот>?start=fam_<код>.
+const FamilyDeepLinkPrefix = "fam_"
+
+// familyInviteCodeAttempts — сколько раз перевыпустить код приглашения при коллизии.
+const familyInviteCodeAttempts = 5
+
+// NormalizeFamilyInviteCode приводит код из ссылки или ручного ввода к виду в БД.
+func NormalizeFamilyInviteCode(code string) string {
+	code = strings.TrimSpace(code)
+	code = strings.TrimPrefix(code, FamilyDeepLinkPrefix)
+	return strings.ToUpper(code)
+}
+
+// FamilyInviteLink — ссылка-приглашение в семью; пусто, если адрес бота ещё не известен.
+func FamilyInviteLink(code string) string {
+	u := strings.TrimRight(strings.TrimSpace(config.BotURL()), "/")
+	if u == "" {
+		return ""
+	}
+	return u + "?start=" + FamilyDeepLinkPrefix + code
+}
+
+// FamilyMemberView — участник семьи с его клиентом (nil, если клиент удалён).
+type FamilyMemberView struct {
+	database.FamilyMember
+	Customer *database.Customer
+}
+
+// FamilyOverview — семья владельца: тариф, общий срок, пул устройств и участники.
+type FamilyOverview struct {
+	Group        *database.FamilyGroup
+	Tariff       *database.Tariff
+	ExpireAt     time.Time
+	DevicePool   int
+	OwnerDevices int
+	Members      []FamilyMemberView
+	InviteLink   string
+}
+
+// MaxMembers — сколько участников можно пригласить на тарифе владельца.
+func (o *FamilyOverview) MaxMembers() int {
+	return o.Tariff.FamilyMaxMembers
+}
+
+// FreeSlots — сколько ещё участников можно пригласить.
+func (o *FamilyOverview) FreeSlots() int {
+	if n := o.Tariff.FamilyMaxMembers - len(o.Members); n > 0 {
+		return n
+	}
+	return 0
+}
+
+// FamilyMembership — семья, в которой клиент состоит участником.
+type FamilyMembership struct {
+	Member database.FamilyMember
+	Owner  *database.Customer
+}
+
+// familyDevicePool — общий лимит устройств семьи: база тарифа владельца и его доп. устройства, не выше HWID_MAX_DEVICES.
+func familyDevicePool(base, extra, maxDevices int) int {
+	if base < 1 {
+		base = 1
+	}
+	if extra < 0 {
+		extra = 0
+	}
+	pool := base + extra
+	if maxDevices > 0 && pool > maxDevices {
+		pool = maxDevices
+	}
+	return pool
+}
+
+// familyOwnerDevices — устройства владельца: пул минус выделенные участникам, но не меньше одного.
+func familyOwnerDevices(pool int, members []database.FamilyMember) int {
+	left := pool
+	for _, m := range members {
+		left -= m.DeviceLimit
+	}
+	if left < 1 {
+		return 1
+	}
+	return left
+}
+
+// rebalanceFamilyDevices урезает лимиты участников, если пул стал меньше выделенного (истекли доп. устройства,
+// сменился тариф): сначала у вступивших последними, у каждого остаётся хотя бы одно устройство.
+// Возвращает индексы изменённых участников.
+func rebalanceFamilyDevices(pool int, members []database.FamilyMember) []int {
+	over := 1 - pool
+	for _, m := range members {
+		over += m.DeviceLimit
+	}
+	var changed []int
+	for i := len(members) - 1; i >= 0 && over > 0; i-- {
+		cut := members[i].DeviceLimit - 1
+		if cut > over {
+			cut = over
+		}
+		if cut <= 0 {
+			continue
+		}
+		members[i].DeviceLimit -= cut
+		over -= cut
+		changed = append(changed, i)
+	}
+	return changed
+}
+
+// familyTariffFor — семейный тариф активной подписки клиента; nil, если подписка не семейная или истекла.
+func (s PaymentService) familyTariffFor(ctx context.Context, owner *database.Customer) (*database.Tariff, error) {
+	if config.SalesMode() != "tariffs" || s.tariffRepository == nil || owner == nil {
+		return nil, nil
+	}
+	if owner.CurrentTariffID == nil || *owner.CurrentTariffID <= 0 || owner.ExpireAt == nil || !owner.ExpireAt.After(time.Now()) {
+		return nil, nil
+	}
+	tariff, err := s.tariffRepository.GetByID(ctx, *owner.CurrentTariffID)
+	if err != nil {
+		return nil, err
+	}
+	if tariff == nil || tariff.FamilyMaxMembers <= 0 {
+		return nil, nil
+	}
+	return tariff, nil
+}
+
+func (s PaymentService) familyPoolFor(owner *database.Customer, tariff *database.Tariff) int {
+	extra := 0
+	if owner.ExtraHwid > 0 && owner.ExtraHwidExpiresAt != nil && owner.ExtraHwidExpiresAt.After(time.Now()) {
+		extra = owner.ExtraHwid
+	}
+	return familyDevicePool(tariff.DeviceLimit, extra, config.HwidMaxDevices())
+}
+
+// HasFamilyAccess — показывать ли клиенту раздел «Семья»: он владелец семейного тарифа или участник чужой семьи.
+func (s PaymentService) HasFamilyAccess(ctx context.Context, customer *database.Customer) bool {
+	if s.familyRepository == nil || customer == nil {
+		return false
+	}
+	m, err := s.familyRepository.FindMembership(ctx, customer.ID)
+	if err != nil {
+		slog.Error("family: find membership", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+		return false
+	}
+	if m != nil {
+		return true
+	}
+	tariff, err := s.familyTariffFor(ctx, customer)
+	if err != nil {
+		slog.Error("family: load owner tariff", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+		return false
+	}
+	return tariff != nil
+}
+
+// Family — семья владельца (создаётся при первом обращении). ErrFamilyNotAvailable — подписка не на семейном тарифе.
+func (s PaymentService) Family(ctx context.Context, owner *database.Customer) (*FamilyOverview, error) {
+	if s.familyRepository == nil {
+		return nil, ErrFamilyDisabled
+	}
+	tariff, err := s.familyTariffFor(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if tariff == nil {
+		return nil, ErrFamilyNotAvailable
+	}
+	group, err := s.familyRepository.FindGroupByOwner(ctx, owner.ID)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		if group, err = s.createFamilyGroup(ctx, owner.ID); err != nil {
+			return nil, err
+		}
+	}
+	members, err := s.familyRepository.ListMembers(ctx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+	pool := s.familyPoolFor(owner, tariff)
+	out := &FamilyOverview{
+		Group:        group,
+		Tariff:       tariff,
+		ExpireAt:     *owner.ExpireAt,
+		DevicePool:   pool,
+		OwnerDevices: familyOwnerDevices(pool, members),
+		InviteLink:   FamilyInviteLink(group.InviteCode),
+	}
+	for _, m := range members {
+		c, err := s.customerRepository.FindById(ctx, m.CustomerID)
+		if err != nil {
+			return nil, err
+		}
+		out.Members = append(out.Members, FamilyMemberView{FamilyMember: m, Customer: c})
+	}
+	return out, nil
+}
+
+func (s PaymentService) createFamilyGroup(ctx context.Context, ownerID int64) (*database.FamilyGroup, error) {
+	for i := 0; i < familyInviteCodeAttempts; i++ {
+		code, err := generateShareCode()
+		if err != nil {
+			return nil, fmt.Errorf("generate family invite code: %w", err)
+		}
+		group, err := s.familyRepository.CreateGroup(ctx, ownerID, code)
+		if errors.Is(err, database.ErrFamilyInviteCodeTaken) {
+			continue
+		}
+		return group, err
+	}
+	return nil, errFamilyInviteCodeGenExhausted
+}
+
+// RegenerateFamilyInvite выпускает новый код приглашения; старая ссылка перестаёт работать.
+func (s PaymentService) RegenerateFamilyInvite(ctx context.Context, owner *database.Customer) (*FamilyOverview, error) {
+	overview, err := s.Family(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < familyInviteCodeAttempts; i++ {
+		code, err := generateShareCode()
+		if err != nil {
+			return nil, fmt.Errorf("generate family invite code: %w", err)
+		}
+		err = s.familyRepository.UpdateInviteCode(ctx, overview.Group.ID, code)
+		if errors.Is(err, database.ErrFamilyInviteCodeTaken) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		overview.Group.InviteCode = code
+		overview.InviteLink = FamilyInviteLink(code)
+		return overview, nil
+	}
+	return nil, errFamilyInviteCodeGenExhausted
+}
+
+// FamilyInvite — владелец и тариф семьи по коду приглашения (для экрана подтверждения).
+// ErrFamilyInviteNotFound — кода нет, подписка владельца больше не семейная или на паузе.
+func (s PaymentService) FamilyInvite(ctx context.Context, code string) (*database.Customer, *database.Tariff, error) {
+	if s.familyRepository == nil {
+		return nil, nil, ErrFamilyDisabled
+	}
+	code = NormalizeFamilyInviteCode(code)
+	if code == "" {
+		return nil, nil, ErrFamilyInviteNotFound
+	}
+	group, err := s.familyRepository.FindGroupByInviteCode(ctx, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	if group == nil {
+		return nil, nil, ErrFamilyInviteNotFound
+	}
+	owner, err := s.customerRepository.FindById(ctx, group.OwnerCustomerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	tariff, err := s.familyTariffFor(ctx, owner)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tariff == nil {
+		return nil, nil, ErrFamilyInviteNotFound
+	}
+	// Подписка владельца на паузе — участник получил бы активный доступ раньше владельца.
+	if owner.PausedUntil != nil && owner.PausedUntil.After(time.Now()) {
+		return nil, nil, ErrFamilyInviteNotFound
+	}
+	return owner, tariff, nil
+}
+
+// JoinFamily добавляет клиента в семью по коду приглашения: одно устройство из пула владельца,
+// свой пользователь Remnawave с профилем тарифа и сроком владельца.
+func (s PaymentService) JoinFamily(ctx context.Context, code string, member *database.Customer) (*FamilyMembership, error) {
+	owner, tariff, err := s.FamilyInvite(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if owner.ID == member.ID {
+		return nil, ErrFamilyOwnInvite
+	}
+	// Свои оплаченные дни участника перезаписались бы сроком владельца.
+	if member.CurrentTariffID != nil && *member.CurrentTariffID > 0 && member.ExpireAt != nil && member.ExpireAt.After(time.Now()) {
+		return nil, ErrFamilyHasSubscription
+	}
+	overview, err := s.Family(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if overview.OwnerDevices-1 < 1 {
+		return nil, ErrFamilyDevicePool
+	}
+	m, err := s.familyRepository.AddMember(ctx, overview.Group.ID, member.ID, 1, tariff.FamilyMaxMembers)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.provisionFamilyMember(ctx, tariff, overview.ExpireAt, member, m.DeviceLimit); err != nil {
+		if _, rerr := s.familyRepository.RemoveMember(ctx, m.GroupID, member.ID); rerr != nil {
+			slog.Error("family: rollback member after failed provisioning", "error", rerr, "customer_id", utils.MaskHalfInt64(member.ID))
+		}
+		return nil, err
+	}
+	s.applyFamilyOwnerDevices(ctx, owner, overview.DevicePool, append(membersOf(overview), *m))
+	slog.Info("family member joined", "owner_id", utils.MaskHalfInt64(owner.ID), "customer_id", utils.MaskHalfInt64(member.ID))
+	s.notifyFamily(ctx, owner, "family_member_joined_notice", FamilyMemberLabel(member))
+	return &FamilyMembership{Member: *m, Owner: owner}, nil
+}
+
+// Membership — семья, в которой клиент участник; nil, если не состоит.
+func (s PaymentService) Membership(ctx context.Context, member *database.Customer) (*FamilyMembership, error) {
+	if s.familyRepository == nil {
+		return nil, nil
+	}
+	m, err := s.familyRepository.FindMembership(ctx, member.ID)
+	if err != nil || m == nil {
+		return nil, err
+	}
+	group, err := s.familyRepository.FindGroupByID(ctx, m.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, nil
+	}
+	owner, err := s.customerRepository.FindById(ctx, group.OwnerCustomerID)
+	if err != nil {
+		return nil, err
+	}
+	return &FamilyMembership{Member: *m, Owner: owner}, nil
+}
+
+// LeaveFamily — участник сам выходит из семьи: подписка в панели заканчивается, устройства возвращаются владельцу.
+func (s PaymentService) LeaveFamily(ctx context.Context, member *database.Customer) error {
+	ms, err := s.Membership(ctx, member)
+	if err != nil {
+		return err
+	}
+	if ms == nil {
+		return ErrFamilyMemberNotFound
+	}
+	if err := s.detachFamilyMember(ctx, ms.Member.GroupID, member); err != nil {
+		return err
+	}
+	if ms.Owner != nil {
+		s.refreshFamilyOwnerDevices(ctx, ms.Owner)
+		s.notifyFamily(ctx, ms.Owner, "family_member_left_notice", FamilyMemberLabel(member))
+	}
+	return nil
+}
+
+// RemoveFamilyMember — владелец исключает участника.
+func (s PaymentService) RemoveFamilyMember(ctx context.Context, owner *database.Customer, memberCustomerID int64) error {
+	overview, member, err := s.familyMemberOf(ctx, owner, memberCustomerID)
+	if err != nil {
+		return err
+	}
+	if err := s.detachFamilyMember(ctx, overview.Group.ID, member); err != nil {
+		return err
+	}
+	s.applyFamilyOwnerDevices(ctx, owner, overview.DevicePool, withoutMember(membersOf(overview), memberCustomerID))
+	s.notifyFamily(ctx, member, "family_removed_notice")
+	return nil
+}
+
+// SetFamilyMemberDevices перераспределяет пул: участнику limit устройств, владельцу — остаток (не меньше одного).
+func (s PaymentService) SetFamilyMemberDevices(ctx context.Context, owner *database.Customer, memberCustomerID int64, limit int) error {
+	if limit < 1 {
+		return ErrFamilyDevicePool
+	}
+	overview, member, err := s.familyMemberOf(ctx, owner, memberCustomerID)
+	if err != nil {
+		return err
+	}
+	members := membersOf(overview)
+	for i := range members {
+		if members[i].CustomerID == memberCustomerID {
+			members[i].DeviceLimit = limit
+		}
+	}
+	used := 0
+	for _, m := range members {
+		used += m.DeviceLimit
+	}
+	if overview.DevicePool-used < 1 {
+		return ErrFamilyDevicePool
+	}
+	user, err := s.remnawaveClient.FindUserForCustomer(ctx, member.ID, member.TelegramID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return remnawave.ErrUserNotFound
+	}
+	if _, err := s.remnawaveClient.PatchUser(ctx, &remnawave.UpdateUserRequest{UUID: &user.UUID, HwidDeviceLimit: &limit}); err != nil {
+		return err
+	}
+	if _, err := s.familyRepository.SetMemberDeviceLimit(ctx, overview.Group.ID, memberCustomerID, limit); err != nil {
+		return err
+	}
+	s.applyFamilyOwnerDevices(ctx, owner, overview.DevicePool, members)
+	return nil
+}
+
+func (s PaymentService) familyMemberOf(ctx context.Context, owner *database.Customer, memberCustomerID int64) (*FamilyOverview, *database.Customer, error) {
+	overview, err := s.Family(ctx, owner)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, m := range overview.Members {
+		if m.CustomerID == memberCustomerID && m.Customer != nil {
+			return overview, m.Customer, nil
+		}
+	}
+	return nil, nil, ErrFamilyMemberNotFound
+}
+
+// provisionFamilyMember создаёт или обновляет пользователя Remnawave участника: профиль тарифа владельца,
+// его срок и выделенные устройства. Срок и лимит выставляются PatchUser точно — новый пользователь
+// создаётся на целое число дней.
+func (s PaymentService) provisionFamilyMember(ctx context.Context, tariff *database.Tariff, expireAt time.Time, member *database.Customer, deviceLimit int) error {
+	profile := BuildRemnawaveTariffProfile(tariff)
+	profile.BaseDeviceLimit = deviceLimit
+	rwCtx := s.ctxWithTelegramUsernameIfMissing(s.withRemnawavePanelUsername(ctx, member), member)
+	user, err := s.remnawaveClient.CreateOrUpdateUserWithTariffProfileUntil(rwCtx, member.ID, member.TelegramID, profile, expireAt)
+	if err != nil {
+		return err
+	}
+	user, err = s.remnawaveClient.PatchUser(ctx, &remnawave.UpdateUserRequest{
+		UUID:            &user.UUID,
+		Status:          "ACTIVE",
+		ExpireAt:        &expireAt,
+		HwidDeviceLimit: &deviceLimit,
+	})
+	if err != nil {
+		return err
+	}
+	return s.customerRepository.UpdateFields(ctx, member.ID, map[string]interface{}{
+		"subscription_link": user.SubscriptionUrl,
+		"expire_at":         user.ExpireAt,
+	})
+}
+
+// detachFamilyMember исключает участника и завершает его подписку в панели (срок — текущий момент).
+func (s PaymentService) detachFamilyMember(ctx context.Context, groupID int64, member *database.Customer) error {
+	removed, err := s.familyRepository.RemoveMember(ctx, groupID, member.ID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrFamilyMemberNotFound
+	}
+	now := time.Now().UTC()
+	user, err := s.remnawaveClient.FindUserForCustomer(ctx, member.ID, member.TelegramID)
+	if err != nil {
+		return err
+	}
+	if user != nil {
+		if _, err := s.remnawaveClient.PatchUser(ctx, &remnawave.UpdateUserRequest{UUID: &user.UUID, ExpireAt: &now}); err != nil {
+			return err
+		}
+	}
+	slog.Info("family member removed", "group_id", groupID, "customer_id", utils.MaskHalfInt64(member.ID))
+	return s.customerRepository.UpdateFields(ctx, member.ID, map[string]interface{}{"expire_at": now})
+}
+
+// applyFamilyOwnerDevices выставляет владельцу остаток пула. Ошибка не прерывает операцию с участником:
+// лимит владельца пересчитается при следующем изменении или продлении.
+func (s PaymentService) applyFamilyOwnerDevices(ctx context.Context, owner *database.Customer, pool int, members []database.FamilyMember) {
+	limit := familyOwnerDevices(pool, members)
+	if _, err := s.remnawaveClient.UpdateUserDeviceLimitByCustomer(ctx, owner.ID, owner.TelegramID, limit); err != nil {
+		slog.Error("family: update owner device limit", "error", err, "customer_id", utils.MaskHalfInt64(owner.ID))
+	}
+}
+
+func (s PaymentService) refreshFamilyOwnerDevices(ctx context.Context, owner *database.Customer) {
+	overview, err := s.Family(ctx, owner)
+	if err != nil {
+		if !errors.Is(err, ErrFamilyNotAvailable) {
+			slog.Error("family: load owner family", "error", err, "customer_id", utils.MaskHalfInt64(owner.ID))
+		}
+		return
+	}
+	s.applyFamilyOwnerDevices(ctx, owner, overview.DevicePool, membersOf(overview))
+}
+
+// syncFamilyAfterPurchase — шаг outbox после оплаты подписки или устройств: покупатель-участник чужой семьи
+// выходит из неё (у него теперь своя подписка), у покупателя-владельца участники получают новый срок и профиль.
+func (s PaymentService) syncFamilyAfterPurchase(ctx context.Context, purchase *database.Purchase) error {
+	if s.familyRepository == nil {
+		return nil
+	}
+	if purchase.Month > 0 {
+		m, err := s.familyRepository.FindMembership(ctx, purchase.CustomerID)
+		if err != nil {
+			return err
+		}
+		if m != nil {
+			if _, err := s.familyRepository.RemoveMember(ctx, m.GroupID, purchase.CustomerID); err != nil {
+				return err
+			}
+			slog.Info("family member left after own purchase", "customer_id", utils.MaskHalfInt64(purchase.CustomerID))
+			if group, err := s.familyRepository.FindGroupByID(ctx, m.GroupID); err == nil && group != nil {
+				if owner, err := s.customerRepository.FindById(ctx, group.OwnerCustomerID); err == nil && owner != nil {
+					s.refreshFamilyOwnerDevices(ctx, owner)
+				}
+			}
+		}
+	}
+	return s.SyncFamily(ctx, purchase.CustomerID)
+}
+
+// SyncFamily переносит на участников срок, профиль тарифа и лимиты устройств владельца (PatchUser).
+// Если подписка владельца больше не семейная, семья распускается: подписки участников заканчиваются.
+func (s PaymentService) SyncFamily(ctx context.Context, ownerID int64) error {
+	if s.familyRepository == nil {
+		return nil
+	}
+	group, err := s.familyRepository.FindGroupByOwner(ctx, ownerID)
+	if err != nil || group == nil {
+		return err
+	}
+	members, err := s.familyRepository.ListMembers(ctx, group.ID)
+	if err != nil || len(members) == 0 {
+		return err
+	}
+	owner, err := s.customerRepository.FindById(ctx, ownerID)
+	if err != nil {
+		return err
+	}
+	if owner == nil {
+		return fmt.Errorf("customer %s not found", utils.MaskHalfInt64(ownerID))
+	}
+	tariff, err := s.familyTariffFor(ctx, owner)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	keep := func(err error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if tariff == nil {
+		if owner.ExpireAt == nil || !owner.ExpireAt.After(time.Now()) {
+			// Подписка просто истекла: у участников тот же срок, продление владельца их вернёт.
+			return nil
+		}
+		for _, m := range members {
+			member, err := s.customerRepository.FindById(ctx, m.CustomerID)
+			if err != nil || member == nil {
+				keep(err)
+				continue
+			}
+			if err := s.detachFamilyMember(ctx, group.ID, member); err != nil {
+				keep(err)
+				continue
+			}
+			s.notifyFamily(ctx, member, "family_disbanded_notice")
+		}
+		slog.Info("family disbanded: owner tariff is not a family plan", "owner_id", utils.MaskHalfInt64(ownerID))
+		return firstErr
+	}
+
+	pool := s.familyPoolFor(owner, tariff)
+	for _, i := range rebalanceFamilyDevices(pool, members) {
+		_, err := s.familyRepository.SetMemberDeviceLimit(ctx, group.ID, members[i].CustomerID, members[i].DeviceLimit)
+		keep(err)
+	}
+	for _, m := range members {
+		member, err := s.customerRepository.FindById(ctx, m.CustomerID)
+		if err != nil || member == nil {
+			keep(err)
+			continue
+		}
+		keep(s.provisionFamilyMember(ctx, tariff, *owner.ExpireAt, member, m.DeviceLimit))
+	}
+	s.applyFamilyOwnerDevices(ctx, owner, pool, members)
+	return firstErr
+}
+
+func membersOf(o *FamilyOverview) []database.FamilyMember {
+	out := make([]database.FamilyMember, 0, len(o.Members))
+	for _, m := range o.Members {
+		out = append(out, m.FamilyMember)
+	}
+	return out
+}
+
+func withoutMember(members []database.FamilyMember, customerID int64) []database.FamilyMember {
+	out := members[:0]
+	for _, m := range members {
+		if m.CustomerID != customerID {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// FamilyMemberLabel — как показать участника семьи: @username или ID клиента.
+func FamilyMemberLabel(c *database.Customer) string {
+	if c.TelegramUsername != nil {
+		if u := strings.TrimPrefix(strings.TrimSpace(*c.TelegramUsername), "@"); u != "" {
+			return "@" + u
+		}
+	}
+	return fmt.Sprintf("ID %d", c.ID)
+}
+
+func (s PaymentService) notifyFamily(ctx context.Context, to *database.Customer, key string, args ...interface{}) {
+	if skipTelegramCustomerDM(to) || s.telegramBot == nil {
+		return
+	}
+	text := s.translation.GetText(to.Language, key)
+	if len(args) > 0 {
+		text = fmt.Sprintf(text, args...)
+	}
+	_, err := s.telegramBot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    to.TelegramID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	})
+	if err != nil {
+		slog.Error("family: notify", "error", err, "key", key, "customer_id", utils.MaskHalfInt64(to.ID))
+	}
+}
@@ -0,0 +1,67 @@
+package payment
+
+import (
+	"testing"
+
+	"remnawave-tg-shop-bot/internal/database"
+)
+
+func TestFamilyDevicePool(t *testing.T) {
+	tests := []struct {
+		base, extra, max, want int
+	}{
+		{5, 0, 0, 5},
+		{5, 2, 0, 7},
+		{5, 2, 6, 6},
+		{0, 0, 0, 1},
+		{3, -1, 0, 3},
+	}
+	for _, tt := range tests {
+		if got := familyDevicePool(tt.base, tt.extra, tt.max); got != tt.want {
+			t.Errorf("familyDevicePool(%d, %d, %d) = %d, want %d", tt.base, tt.extra, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestFamilyOwnerDevices(t *testing.T) {
+	members := []database.FamilyMember{{DeviceLimit: 2}, {DeviceLimit: 1}}
+	if got := familyOwnerDevices(6, members); got != 3 {
+		t.Fatalf("owner devices = %d, want 3", got)
+	}
+	if got := familyOwnerDevices(3, members); got != 1 {
+		t.Fatalf("owner devices = %d, want at least 1", got)
+	}
+	if got := familyOwnerDevices(4, nil); got != 4 {
+		t.Fatalf("owner devices without members = %d, want 4", got)
+	}
+}
+
+func TestRebalanceFamilyDevices(t *testing.T) {
+	members := []database.FamilyMember{{CustomerID: 1, DeviceLimit: 3}, {CustomerID: 2, DeviceLimit: 2}, {CustomerID: 3, DeviceLimit: 2}}
+	// Пул уменьшился с 10 до 5: владельцу 1, участникам 4 — режем с последних вступивших.
+	changed := rebalanceFamilyDevices(5, members)
+	if len(changed) != 3 || changed[0] != 2 || changed[1] != 1 || changed[2] != 0 {
+		t.Fatalf("changed = %v, want [2 1 0]", changed)
+	}
+	want := []int{2, 1, 1}
+	for i, m := range members {
+		if m.DeviceLimit != want[i] {
+			t.Fatalf("member %d limit = %d, want %d", i, m.DeviceLimit, want[i])
+		}
+	}
+	if got := familyOwnerDevices(5, members); got != 1 {
+		t.Fatalf("owner devices = %d, want 1", got)
+	}
+
+	fits := []database.FamilyMember{{DeviceLimit: 2}}
+	if changed := rebalanceFamilyDevices(5, fits); len(changed) != 0 || fits[0].DeviceLimit != 2 {
+		t.Fatalf("pool fits, nothing should change: %v %v", changed, fits)
+	}
+
+	// Даже при пуле меньше числа участников у каждого остаётся одно устройство.
+	tight := []database.FamilyMember{{DeviceLimit: 2}, {DeviceLimit: 2}}
+	rebalanceFamilyDevices(1, tight)
+	if tight[0].DeviceLimit != 1 || tight[1].DeviceLimit != 1 {
+		t.Fatalf("tight pool = %v, want both 1", tight)
+	}
+}
@@ -0,0 +1,296 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/utils"
+)
+
+var (
+	ErrGiftsDisabled        = errors.New("gift subscriptions are disabled")
+	ErrGiftInvalidPeriod    = errors.New("gift period is not for sale")
+	ErrGiftNotFound         = errors.New("gift not found")
+	ErrGiftAlreadyRedeemed  = errors.New("gift already redeemed")
+	ErrGiftExpired          = errors.New("gift expired or revoked")
+	ErrGiftTariffConflict   = errors.New("recipient has an active subscription on another tariff")
+	errGiftCodeGenExhausted = errors.New("failed to generate unique gift code")
+)
+
+// GiftDeepLinkPrefix — параметр /start для активации подарка: t.me/<бот>?start=gift_<код>.
+const GiftDeepLinkPrefix = "gift_"
+
+// giftCodeAttempts — сколько раз перевыпустить код подарка при коллизии.
+const giftCodeAttempts = 5
+
+// NormalizeGiftCode приводит код из ссылки или ручного ввода к виду в БД.
+func NormalizeGiftCode(code string) string {
+	code = strings.TrimSpace(code)
+	code = strings.TrimPrefix(code, GiftDeepLinkPrefix)
+	return strings.ToUpper(code)
+}
+
+// GiftDeepLink — ссылка на активацию подарка; пусто, если адрес бота ещё не известен.
+func GiftDeepLink(code string) string {
+	u := strings.TrimRight(strings.TrimSpace(config.BotURL()), "/")
+	if u == "" {
+		return ""
+	}
+	return u + "?start=" + GiftDeepLinkPrefix + code
+}
+
+// GiftPrice — цена подарка: полная цена периода (тарифа) без персональных скидок покупателя.
+func (s PaymentService) GiftPrice(ctx context.Context, tariffID *int64, months int, invoiceType database.InvoiceType) (int, error) {
+	invoiceStars := invoiceType == database.InvoiceTypeTelegram
+	if tariffID != nil && *tariffID > 0 {
+		if s.tariffRepository == nil {
+			return 0, ErrGiftInvalidPeriod
+		}
+		tp, err := s.tariffRepository.GetPrice(ctx, *tariffID, months)
+		if err != nil {
+			return 0, err
+		}
+		if tp == nil || tp.AmountRub <= 0 {
+			return 0, ErrGiftInvalidPeriod
+		}
+		return pickTariffAmount(tp, invoiceStars)
+	}
+	switch months {
+	case 1, 3, 6, 12:
+	default:
+		return 0, ErrGiftInvalidPeriod
+	}
+	price := config.Price(months)
+	if invoiceStars {
+		price = config.StarsPrice(months)
+	}
+	if price <= 0 {
+		return 0, ErrGiftInvalidPeriod
+	}
+	return price, nil
+}
+
+// CreateGiftPurchase выставляет счёт на подарок. Сумма считается здесь, а не берётся из callback.
+func (s PaymentService) CreateGiftPurchase(ctx context.Context, customer *database.Customer, invoiceType database.InvoiceType, months int, tariffID *int64) (url string, purchaseId int64, err error) {
+	if !config.GiftsEnabled() || s.giftRepository == nil {
+		return "", 0, ErrGiftsDisabled
+	}
+	if config.SalesMode() != "tariffs" {
+		tariffID = nil
+	} else if tariffID == nil || *tariffID <= 0 {
+		return "", 0, ErrGiftInvalidPeriod
+	}
+	price, err := s.GiftPrice(ctx, tariffID, months, invoiceType)
+	if err != nil {
+		return "", 0, err
+	}
+	return s.createInvoice(ctx, invoiceType, InvoiceParams{
+		Amount:   float64(price),
+		Months:   months,
+		Customer: customer,
+		TariffID: tariffID,
+		Extras:   &TariffPurchaseExtras{Kind: database.PurchaseKindGift},
+	})
+}
+
+// issueGift выпускает код подарка по оплаченной покупке (шаг apply outbox). Подарок один на покупку:
+// повтор шага после сбоя возвращает уже выпущенный код.
+func (s PaymentService) issueGift(ctx context.Context, purchase *database.Purchase) (*database.GiftSubscription, error) {
+	if existing, err := s.giftRepository.FindByPurchaseID(ctx, purchase.ID); err != nil || existing != nil {
+		return existing, err
+	}
+	expiresAt := time.Now().UTC().AddDate(0, 0, config.GiftCodeTTLDays())
+	for i := 0; i < giftCodeAttempts; i++ {
+		code, err := generateShareCode()
+		if err != nil {
+			return nil, fmt.Errorf("generate gift code: %w", err)
+		}
+		gift, err := s.giftRepository.Create(ctx, &database.GiftSubscription{
+			Code:            code,
+			PurchaseID:      purchase.ID,
+			BuyerCustomerID: purchase.CustomerID,
+			Months:          purchase.Month,
+			TariffID:        purchase.TariffID,
+			ExpiresAt:       expiresAt,
+		})
+		if errors.Is(err, database.ErrGiftCodeTaken) {
+			continue
+		}
+		return gift, err
+	}
+	return nil, errGiftCodeGenExhausted
+}
+
+// FindGift — подарок по коду (в любом регистре, с префиксом gift_ или без); nil, если не найден.
+func (s PaymentService) FindGift(ctx context.Context, code string) (*database.GiftSubscription, error) {
+	if s.giftRepository == nil {
+		return nil, ErrGiftsDisabled
+	}
+	code = NormalizeGiftCode(code)
+	if code == "" {
+		return nil, nil
+	}
+	return s.giftRepository.FindByCode(ctx, code)
+}
+
+// RedeemGift начисляет дни подарка получателю той же логикой, что и оплата подписки
+// (extendPaidSubscription → customer → лимит устройств → сброс трафика) и сообщает покупателю.
+func (s PaymentService) RedeemGift(ctx context.Context, code string, recipient *database.Customer) (*database.GiftSubscription, error) {
+	gift, err := s.FindGift(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if gift == nil {
+		return nil, ErrGiftNotFound
+	}
+	now := time.Now().UTC()
+	switch gift.EffectiveStatus(now) {
+	case database.GiftStatusRedeemed:
+		return nil, ErrGiftAlreadyRedeemed
+	case database.GiftStatusExpired, database.GiftStatusRevoked:
+		return nil, ErrGiftExpired
+	}
+
+	var profile *remnawave.TariffPaidProfile
+	var tariffID *int64
+	if config.SalesMode() == "tariffs" && gift.TariffID != nil && *gift.TariffID > 0 && s.tariffRepository != nil {
+		// Подарок на другой тариф поверх активной подписки переписал бы её профиль — активировать после окончания.
+		if recipient.ExpireAt != nil && recipient.ExpireAt.After(now) &&
+			recipient.CurrentTariffID != nil && *recipient.CurrentTariffID > 0 && *recipient.CurrentTariffID != *gift.TariffID {
+			return nil, ErrGiftTariffConflict
+		}
+		tariff, err := s.tariffRepository.GetByID(ctx, *gift.TariffID)
+		if err != nil {
+			return nil, err
+		}
+		if tariff != nil {
+			p := BuildRemnawaveTariffProfile(tariff)
+			profile = &p
+			tariffID = gift.TariffID
+		}
+	}
+
+	claimed, err := s.giftRepository.ClaimRedeem(ctx, gift.ID, recipient.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrGiftAlreadyRedeemed
+	}
+
+	ctx = s.ctxWithTelegramUsernameIfMissing(ctx, recipient)
+	user, err := s.extendPaidSubscription(ctx, recipient, gift.Months*config.DaysInMonth(), profile)
+	if err != nil {
+		if rerr := s.giftRepository.ReleaseRedeem(ctx, gift.ID); rerr != nil {
+			slog.Error("gift: release after failed redeem", "error", rerr, "gift_id", gift.ID)
+		}
+		return nil, err
+	}
+	if err := s.saveCustomerSubscription(ctx, recipient, user, tariffID, gift.Months); err != nil {
+		return nil, err
+	}
+	// Лимит устройств — как у оплаченной подписки того же тарифа (без доп. HWID).
+	asPurchase := &database.Purchase{CustomerID: recipient.ID, Month: gift.Months, TariffID: tariffID, PurchaseKind: database.PurchaseKindGift}
+	if err := s.applyExtraAfterSubscription(ctx, recipient, user, asPurchase); err != nil {
+		slog.Error("gift: apply device limit", "error", err, "gift_id", gift.ID)
+	}
+	if err := s.resetTrafficAfterSubscriptionPayment(ctx, user); err != nil {
+		slog.Error("gift: reset traffic", "error", err, "gift_id", gift.ID)
+	}
+	if err := s.syncFamilyAfterPurchase(ctx, asPurchase); err != nil {
+		slog.Error("gift: sync family", "error", err, "gift_id", gift.ID)
+	}
+
+	gift.Status = database.GiftStatusRedeemed
+	gift.RedeemedByCustomerID = &recipient.ID
+	gift.RedeemedAt = &now
+	slog.Info("gift redeemed", "gift_id", gift.ID, "customer_id", utils.MaskHalfInt64(recipient.ID))
+	s.notifyGiftRedeemed(ctx, gift, recipient)
+	return gift, nil
+}
+
+// revokeGiftAfterRefund — полный возврат денег за подарок отзывает неактивированный код.
+func (s PaymentService) revokeGiftAfterRefund(ctx context.Context, purchase *database.Purchase) {
+	if s.giftRepository == nil {
+		return
+	}
+	revoked, err := s.giftRepository.RevokeByPurchase(ctx, purchase.ID)
+	if err != nil {
+		slog.Error("refund: revoke gift", "error", err, "purchase_id", utils.MaskHalfInt64(purchase.ID))
+		return
+	}
+	if revoked {
+		slog.Info("refund: gift revoked", "purchase_id", utils.MaskHalfInt64(purchase.ID))
+	}
+}
+
+func (s PaymentService) notifyGiftIssued(ctx context.Context, buyer *database.Customer, gift *database.GiftSubscription) {
+	if skipTelegramCustomerDM(buyer) || s.telegramBot == nil {
+		return
+	}
+	lang := buyer.Language
+	link := GiftDeepLink(gift.Code)
+	text := fmt.Sprintf(s.translation.GetText(lang, "gift_purchased"), gift.Months, gift.Code, link, gift.ExpiresAt.Format("02.01.2006"))
+	var kb [][]models.InlineKeyboardButton
+	if link != "" {
+		share := "https://t.me/share/url?url=" + url.QueryEscape(link) + "&text=" + url.QueryEscape(s.translation.GetText(lang, "gift_share_text"))
+		kb = append(kb, []models.InlineKeyboardButton{
+			s.translation.WithButton(lang, "gift_share_button", models.InlineKeyboardButton{URL: share}),
+		})
+	}
+	kb = append(kb, []models.InlineKeyboardButton{
+		s.translation.WithButton(lang, "back_button", models.InlineKeyboardButton{CallbackData: "start"}),
+	})
+	isDisabled := true
+	_, err := s.telegramBot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:             buyer.TelegramID,
+		Text:               text,
+		ParseMode:          models.ParseModeHTML,
+		LinkPreviewOptions: &models.LinkPreviewOptions{IsDisabled: &isDisabled},
+		ReplyMarkup:        models.InlineKeyboardMarkup{InlineKeyboard: kb},
+	})
+	if err != nil {
+		slog.Error("gift: notify buyer issued", "error", err, "customer_id", utils.MaskHalfInt64(buyer.ID))
+	}
+}
+
+func (s PaymentService) notifyGiftRedeemed(ctx context.Context, gift *database.GiftSubscription, recipient *database.Customer) {
+	if s.telegramBot == nil {
+		return
+	}
+	buyer, err := s.customerRepository.FindById(ctx, gift.BuyerCustomerID)
+	if err != nil || buyer == nil {
+		slog.Error("gift: load buyer for redeemed notice", "error", err, "gift_id", gift.ID)
+		return
+	}
+	if skipTelegramCustomerDM(buyer) || buyer.ID == recipient.ID {
+		return
+	}
+	_, err = s.telegramBot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    buyer.TelegramID,
+		Text:      fmt.Sprintf(s.translation.GetText(buyer.Language, "gift_redeemed_notice"), gift.Code, gift.Months),
+		ParseMode: models.ParseModeHTML,
+	})
+	if err != nil {
+		slog.Error("gift: notify buyer redeemed", "error", err, "customer_id", utils.MaskHalfInt64(buyer.ID))
+	}
+}
+
+// ListGifts — последние подарки для админки; пустой status — все.
+func (s PaymentService) ListGifts(ctx context.Context, status database.GiftStatus, limit, offset int) ([]database.GiftSubscription, error) {
+	if s.giftRepository == nil {
+		return nil, nil
+	}
+	return s.giftRepository.ListRecent(ctx, status, limit, offset)
+}
@@ -0,0 +1,15 @@
+package payment
+
+import "testing"
+
+func TestNormalizeGiftCode(t *testing.T) {
+	for in, want := range map[string]string{
+		"abcd234567":        "ABCD234567",
+		" gift_abcd234567 ": "ABCD234567",
+		"":                  "",
+	} {
+		if got := NormalizeGiftCode(in); got != want {
+			t.Fatalf("NormalizeGiftCode(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
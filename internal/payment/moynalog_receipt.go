@@ -0,0 +1,294 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/moynalog"
+	"remnawave-tg-shop-bot/utils"
+)
+
+const (
+	moynalogReceiptBatchLimit = 20
+	// moynalogReceiptLease — на сколько чек «занят» попыткой: параллельный запуск cron его не возьмёт.
+	moynalogReceiptLease = 10 * time.Minute
+	// Пауза между попытками: moynalogRetryBaseDelay, удваивается с каждой неудачей (см. backoffDelay).
+	moynalogRetryBaseDelay = time.Minute
+	moynalogRetryMaxDelay  = 6 * time.Hour
+	moynalogCancelComment  = "Возврат средств"
+	// Сохранение UUID созданного чека повторяется сразу: чек, оставшийся в pending, после аренды отправился бы повторно.
+	moynalogSaveUUIDAttempts   = 3
+	moynalogSaveUUIDRetryDelay = 2 * time.Second
+)
+
+// ErrMoynalogDisabled — интеграция «Мой налог» выключена.
+var ErrMoynalogDisabled = errors.New("moynalog receipts disabled")
+
+// moynalogRetryDelay — пауза перед следующей попыткой после attempts неудач подряд.
+func moynalogRetryDelay(attempts int) time.Duration {
+	return backoffDelay(attempts, moynalogRetryBaseDelay, moynalogRetryMaxDelay)
+}
+
+// backoffDelay — экспоненциальная пауза: base после первой неудачи, удваивается с каждой следующей, не больше maxDelay.
+func backoffDelay(attempts int, base, maxDelay time.Duration) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	d := base
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= maxDelay {
+			return maxDelay
+		}
+	}
+	return d
+}
+
+func moynalogReceiptDescription(purchase *database.Purchase) string {
+	extras := &TariffPurchaseExtras{Kind: purchase.PurchaseKind, IsEarlyDowngrade: purchase.IsEarlyDowngrade, TrafficGB: purchase.TrafficGB}
+	return buildRubReceiptDescription(purchase.Month, purchase.ExtraHwid, extras, purchase.InvoiceType)
+}
+
+// enqueueMoynalogReceipt сохраняет чек в очередь и сразу делает первую попытку отправки.
+// Ошибка — только если чек не удалось поставить в очередь; неудачную отправку повторяет очередь.
+func (s PaymentService) enqueueMoynalogReceipt(ctx context.Context, purchase *database.Purchase, description string) error {
+	if s.receiptRepository == nil {
+		slog.Warn("moynalog: receipt queue not configured, sending once", "purchase_id", utils.MaskHalfInt64(purchase.ID))
+		if _, err := s.moynalogClient.CreateIncome(ctx, purchase.Amount, description, time.Now()); err != nil {
+			slog.Error("Failed to send receipt to moynalog", "error", err, "purchase_id", utils.MaskHalfInt64(purchase.ID))
+		}
+		return nil
+	}
+	now := time.Now().UTC()
+	operationTime := now
+	if purchase.PaidAt != nil {
+		operationTime = *purchase.PaidAt
+	}
+	rc, created, err := s.receiptRepository.Enqueue(ctx, purchase.ID, purchase.Amount, description, operationTime, now)
+	if err != nil {
+		return err
+	}
+	if !created {
+		slog.Info("moynalog: receipt already queued", "receipt_id", rc.ID, "status", rc.Status, "purchase_id", utils.MaskHalfInt64(purchase.ID))
+		return nil
+	}
+	slog.Info("Sending receipt to moynalog", "receipt_id", rc.ID, "purchase_id", utils.MaskHalfInt64(purchase.ID), "amount", purchase.Amount, "description", description)
+	s.processMoynalogReceipt(ctx, rc.ID, now)
+	return nil
+}
+
+// ProcessMoynalogReceipts отправляет и аннулирует чеки из очереди, у которых подошло время попытки (cron в main).
+func (s PaymentService) ProcessMoynalogReceipts(ctx context.Context) {
+	if s.moynalogClient == nil || s.receiptRepository == nil {
+		return
+	}
+	now := time.Now().UTC()
+	due, err := s.receiptRepository.FindDue(ctx, now, moynalogReceiptBatchLimit)
+	if err != nil {
+		slog.Error("moynalog: find due receipts", "error", err)
+		return
+	}
+	for i := range due {
+		s.processMoynalogReceipt(ctx, due[i].ID, now)
+	}
+}
+
+// processMoynalogReceipt делает одну попытку по чеку: отправку (pending) или аннулирование (cancel_pending).
+func (s PaymentService) processMoynalogReceipt(ctx context.Context, receiptID int64, now time.Time) {
+	rc, err := s.receiptRepository.Claim(ctx, receiptID, now, now.Add(moynalogReceiptLease))
+	if err != nil {
+		slog.Error("moynalog: claim receipt", "error", err, "receipt_id", receiptID)
+		return
+	}
+	if rc == nil {
+		return
+	}
+
+	switch rc.Status {
+	case database.MoynalogReceiptStatusPending:
+		receiptUUID, err := s.moynalogClient.CreateIncome(ctx, rc.Amount, rc.Description, rc.OperationTime)
+		if err != nil {
+			s.recordMoynalogFailure(ctx, rc, err, now)
+			return
+		}
+		sent, err := s.saveMoynalogReceiptUUID(ctx, rc.ID, receiptUUID)
+		if err != nil {
+			s.holdMoynalogReceipt(ctx, rc, receiptUUID, err)
+			return
+		}
+		if sent != nil && sent.Status == database.MoynalogReceiptStatusCancelPending {
+			slog.Info("moynalog: purchase refunded while receipt was being sent, cancel queued", "receipt_id", rc.ID)
+			return
+		}
+		slog.Info("Receipt sent to moynalog successfully", "receipt_id", rc.ID, "purchase_id", utils.MaskHalfInt64(rc.PurchaseID))
+
+	case database.MoynalogReceiptStatusCancelPending:
+		if rc.ReceiptUUID == nil || *rc.ReceiptUUID == "" {
+			if err := s.receiptRepository.MarkCanceled(ctx, rc.ID); err != nil {
+				slog.Error("moynalog: mark receipt canceled", "error", err, "receipt_id", rc.ID)
+			}
+			return
+		}
+		if err := s.moynalogClient.CancelIncome(ctx, *rc.ReceiptUUID, moynalogCancelComment); err != nil {
+			s.recordMoynalogFailure(ctx, rc, err, now)
+			return
+		}
+		if err := s.receiptRepository.MarkCanceled(ctx, rc.ID); err != nil {
+			slog.Error("moynalog: mark receipt canceled", "error", err, "receipt_id", rc.ID)
+			return
+		}
+		slog.Info("Receipt canceled in moynalog", "receipt_id", rc.ID, "purchase_id", utils.MaskHalfInt64(rc.PurchaseID))
+	}
+}
+
+// saveMoynalogReceiptUUID — MarkSent с повторами при ошибке БД.
+func (s PaymentService) saveMoynalogReceiptUUID(ctx context.Context, receiptID int64, receiptUUID string) (*database.MoynalogReceipt, error) {
+	var err error
+	for attempt := 1; attempt <= moynalogSaveUUIDAttempts; attempt++ {
+		var sent *database.MoynalogReceipt
+		if sent, err = s.receiptRepository.MarkSent(ctx, receiptID, receiptUUID); err == nil {
+			return sent, nil
+		}
+		slog.Warn("moynalog: save receipt uuid", "error", err, "receipt_id", receiptID, "attempt", attempt)
+		if attempt == moynalogSaveUUIDAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(moynalogSaveUUIDRetryDelay):
+		}
+	}
+	return nil, err
+}
+
+// holdMoynalogReceipt — чек создан в «Мой налог», но UUID не сохранился: чек уходит в needs_attention
+// (не в очередь — иначе доход создастся повторно) и админ получает уведомление с UUID.
+func (s PaymentService) holdMoynalogReceipt(ctx context.Context, rc *database.MoynalogReceipt, receiptUUID string, saveErr error) {
+	errText := fmt.Sprintf("receipt %s created, failed to save uuid: %v", receiptUUID, saveErr)
+	slog.Error("moynalog: receipt created but uuid not saved", "error", saveErr, "receipt_id", rc.ID, "receipt_uuid", receiptUUID)
+	held, err := s.receiptRepository.MarkNeedsAttention(ctx, rc.ID, receiptUUID, errText)
+	if err != nil {
+		slog.Error("moynalog: mark receipt needs attention", "error", err, "receipt_id", rc.ID, "receipt_uuid", receiptUUID)
+	}
+	if held != nil && held.Status != database.MoynalogReceiptStatusNeedsAttention {
+		return
+	}
+	if held == nil {
+		// Статус в БД записать не удалось — админ узнаёт о чеке хотя бы из уведомления.
+		copied := *rc
+		copied.Status = database.MoynalogReceiptStatusNeedsAttention
+		copied.ReceiptUUID = &receiptUUID
+		copied.LastError = &errText
+		held = &copied
+	}
+	s.notifyAdminMoynalogFailure(ctx, held)
+}
+
+// recordMoynalogFailure откладывает следующую попытку с экспоненциальной паузой. Когда попытки исчерпаны
+// (или «Мой налог» отклонил запрос как некорректный), чек уходит в failed / cancel_failed и админ получает уведомление.
+func (s PaymentService) recordMoynalogFailure(ctx context.Context, rc *database.MoynalogReceipt, opErr error, now time.Time) {
+	attempts := rc.Attempts + 1
+	final := attempts >= config.MoynalogReceiptMaxAttempts() || errors.Is(opErr, moynalog.ErrClient)
+	next := now.Add(moynalogRetryDelay(attempts))
+	slog.Error("moynalog: receipt attempt failed", "error", opErr, "receipt_id", rc.ID, "status", rc.Status, "attempts", attempts, "final", final)
+	updated, err := s.receiptRepository.RecordFailure(ctx, rc.ID, opErr.Error(), &next, final)
+	if err != nil {
+		slog.Error("moynalog: record receipt failure", "error", err, "receipt_id", rc.ID)
+		return
+	}
+	if final && updated != nil {
+		s.notifyAdminMoynalogFailure(ctx, updated)
+	}
+}
+
+// cancelMoynalogReceipt аннулирует чек полностью возвращённой покупки. «Мой налог» не умеет частичное
+// аннулирование, поэтому при частичном возврате чек остаётся.
+func (s PaymentService) cancelMoynalogReceipt(ctx context.Context, purchase *database.Purchase) {
+	if s.receiptRepository == nil {
+		return
+	}
+	now := time.Now().UTC()
+	rc, err := s.receiptRepository.RequestCancel(ctx, purchase.ID, now)
+	if err != nil {
+		slog.Error("moynalog: request receipt cancel", "error", err, "purchase_id", utils.MaskHalfInt64(purchase.ID))
+		return
+	}
+	if rc == nil {
+		return
+	}
+	if rc.Status == database.MoynalogReceiptStatusCanceled {
+		slog.Info("moynalog: unsent receipt closed after refund", "receipt_id", rc.ID, "purchase_id", utils.MaskHalfInt64(purchase.ID))
+		return
+	}
+	if s.moynalogClient == nil {
+		slog.Warn("moynalog: receipt cancel queued while integration is disabled", "receipt_id", rc.ID)
+		return
+	}
+	s.processMoynalogReceipt(ctx, rc.ID, now)
+}
+
+// RetryMoynalogReceipt — «повторить сейчас» из админки: возвращает failed / cancel_failed чек в очередь
+// и сразу делает попытку; needs_attention чек закрывается как отправленный. Возвращает чек после попытки.
+func (s PaymentService) RetryMoynalogReceipt(ctx context.Context, receiptID int64) (*database.MoynalogReceipt, error) {
+	if s.moynalogClient == nil || s.receiptRepository == nil {
+		return nil, ErrMoynalogDisabled
+	}
+	now := time.Now().UTC()
+	if _, err := s.receiptRepository.Retry(ctx, receiptID, now); err != nil {
+		return nil, err
+	}
+	s.processMoynalogReceipt(ctx, receiptID, now)
+	return s.receiptRepository.FindByID(ctx, receiptID)
+}
+
+// ListMoynalogReceipts — чеки в статусах statuses (пусто — все).
+func (s PaymentService) ListMoynalogReceipts(ctx context.Context, statuses []database.MoynalogReceiptStatus, limit, offset int) ([]database.MoynalogReceipt, error) {
+	if s.receiptRepository == nil {
+		return nil, ErrMoynalogDisabled
+	}
+	return s.receiptRepository.List(ctx, statuses, limit, offset)
+}
+
+func (s PaymentService) notifyAdminMoynalogFailure(ctx context.Context, rc *database.MoynalogReceipt) {
+	adminID := config.GetAdminTelegramId()
+	if s.telegramBot == nil || adminID == 0 {
+		return
+	}
+	lang := "ru"
+	lastErr := ""
+	if rc.LastError != nil {
+		lastErr = *rc.LastError
+	}
+	text := fmt.Sprintf(s.translation.GetText(lang, "admin_moynalog_receipt_failed"),
+		rc.ID,
+		s.translation.GetText(lang, "admin_moynalog_status_"+string(rc.Status)),
+		rc.PurchaseID,
+		rc.Amount,
+		html.EscapeString(rc.Description),
+		rc.Attempts,
+		html.EscapeString(lastErr),
+	)
+	if _, err := s.telegramBot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    adminID,
+		ParseMode: models.ParseModeHTML,
+		Text:      text,
+		ReplyMarkup: models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				s.translation.WithButton(lang, "admin_moynalog_retry_button", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("mnr%d", rc.ID)}),
+				s.translation.WithButton(lang, "admin_moynalog_receipts_button", models.InlineKeyboardButton{CallbackData: "admin_receipts"}),
+			},
+		}},
+	}); err != nil {
+		slog.Error("Failed to notify admin about moynalog receipt failure", "error", err, "receipt_id", rc.ID)
+	}
+}
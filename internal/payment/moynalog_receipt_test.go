@@ -0,0 +1,22 @@
+package payment
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMoynalogRetryDelay(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		0:  time.Minute,
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		4:  8 * time.Minute,
+		9:  256 * time.Minute,
+		10: moynalogRetryMaxDelay,
+		50: moynalogRetryMaxDelay,
+	} {
+		if got := moynalogRetryDelay(attempts); got != want {
+			t.Fatalf("moynalogRetryDelay(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
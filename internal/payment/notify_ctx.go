@@ -9,12 +9,16 @@ const (
 	notifyCtxKeyCrypto
 )
 
-// StarsNotifyMeta — поля SuccessfulPayment (Stars), без записи в БД; только для группового уведомления.
+// StarsNotifyMeta — поля SuccessfulPayment (Stars), без записи в БД; для группового уведомления
+// и подписок Stars (IsRecurring / IsFirstRecurring / SubscriptionExpirationDate).
 type StarsNotifyMeta struct {
-	TelegramPaymentChargeID string
-	ProviderPaymentChargeID string
-	TotalAmount             int
-	Currency                string
+	TelegramPaymentChargeID    string
+	ProviderPaymentChargeID    string
+	TotalAmount                int
+	Currency                   string
+	IsRecurring                bool
+	IsFirstRecurring           bool
+	SubscriptionExpirationDate int
 }
 
 // CryptoNotifyMeta — фрагмент ответа CryptoPay API по счёту; только для уведомления.
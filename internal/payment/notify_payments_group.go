@@ -163,6 +163,9 @@ func amountPeriodLine(p *database.Purchase) string {
 		}
 		return fmt.Sprintf("💳 %.0f ⭐ · %s", amt, cur)
 	}
+	if p.PurchaseKind == database.PurchaseKindTrafficPack {
+		return fmt.Sprintf("💳 %.2f %s · трафик +%d ГБ", amt, cur, p.TrafficGB)
+	}
 	if p.PurchaseKind == database.PurchaseKindExtraHwid || p.ExtraHwid > 0 && p.Month <= 0 {
 		return fmt.Sprintf("💳 %.2f %s · доп. устройства", amt, cur)
 	}
@@ -279,6 +282,9 @@ func providerExtrasFromCtx(ctx context.Context, p *database.Purchase) string {
 			b.WriteString("provider_charge_id: " + htmlCode(id) + "\n")
 		}
 		b.WriteString(fmt.Sprintf("successful_payment: %d %s", m.TotalAmount, html.EscapeString(strings.TrimSpace(m.Currency))))
+		if m.IsRecurring {
+			b.WriteString(fmt.Sprintf("\nis_recurring: true, is_first_recurring: %t", m.IsFirstRecurring))
+		}
 	case database.InvoiceTypeCrypto:
 		if !config.IsCryptoPayEnabled() {
 			return ""
@@ -361,6 +367,33 @@ func buildCancelGroupMessage(ctx context.Context, p *database.Purchase, c *datab
 	return b.String()
 }
 
+func buildRefundGroupMessage(ctx context.Context, p *database.Purchase, r *database.PurchaseRefund, c *database.Customer, cabinet bool, expireBefore, expireAfter *time.Time, tariffRepo *database.TariffRepository) string {
+	var b strings.Builder
+	b.WriteString("↩️ Возврат\n\n")
+	b.WriteString(fmt.Sprintf("🧾 #%d · %s · %s\n", p.ID, p.Status, invoiceTypeTitle(p.InvoiceType)))
+	b.WriteString(amountPeriodLine(p) + "\n")
+	b.WriteString(tariffLine(ctx, p, tariffRepo) + "\n")
+	b.WriteString(fmt.Sprintf("возврат: %s (%s)\n", html.EscapeString(formatPurchaseAmount(r.Amount, p)), r.Status))
+	if r.Status == database.RefundStatusManual {
+		b.WriteString("⚠️ у провайдера нет API возврата — верните деньги вручную\n")
+	}
+	b.WriteString(fmt.Sprintf("откат: −%d дн. · −%d HWID · −%d XP", r.DaysRevoked, r.HwidRevoked, r.XPRevoked))
+	if r.PromoRestored {
+		b.WriteString(" · промо восстановлено")
+	}
+	b.WriteString("\n")
+	if r.Reason != nil && strings.TrimSpace(*r.Reason) != "" {
+		b.WriteString("причина: " + html.EscapeString(strings.TrimSpace(*r.Reason)) + "\n")
+	}
+	b.WriteString("\n")
+	if c != nil {
+		b.WriteString(customerNotifyLineHTML(c))
+	}
+	b.WriteString(cabinetWebHint(cabinet, c) + "\n")
+	b.WriteString(expireChangeLine(expireBefore, expireAfter))
+	return b.String()
+}
+
 func (s *PaymentService) sendPaymentsGroupText(ctx context.Context, text string) {
 	s.sendPaymentsGroupHTML(ctx, text, nil)
 }
@@ -467,3 +500,21 @@ func (s *PaymentService) tryNotifyPurchaseCancel(ctx context.Context, p *databas
 	}
 	s.sendPaymentsGroupHTML(ctx, msg, markup)
 }
+
+// tryNotifyPurchaseRefund — уведомление о возврате (админ из бота или кабинета); шлётся вместе с отменами.
+func (s *PaymentService) tryNotifyPurchaseRefund(ctx context.Context, p *database.Purchase, r *database.PurchaseRefund, c *database.Customer, expireBefore, expireAfter *time.Time) {
+	if p == nil || r == nil || !config.PaymentsNotifySendCancel() {
+		return
+	}
+	cabinet, err := s.purchaseRepository.HasCabinetCheckoutForPurchase(ctx, p.ID)
+	if err != nil {
+		slog.Warn("payments notify refund: cabinet_checkout lookup", "error", err)
+		cabinet = false
+	}
+	msg := buildRefundGroupMessage(ctx, p, r, c, cabinet, expireBefore, expireAfter, s.tariffRepository)
+	var markup models.ReplyMarkup
+	if c != nil {
+		markup = s.paymentsNotifyToUserReplyMarkup(c.ID)
+	}
+	s.sendPaymentsGroupHTML(ctx, msg, markup)
+}
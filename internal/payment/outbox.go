@@ -0,0 +1,653 @@
+package payment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log/slog"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/loyalty"
+	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/internal/yookasa"
+	"remnawave-tg-shop-bot/utils"
+)
+
+// Должен совпадать с handler.CallbackAdminPurchaseOutboxRetryPrefix.
+const purchaseOutboxRetryCallbackPrefix = "pxr"
+
+const (
+	purchaseOutboxBatchLimit = 20
+	// purchaseOutboxLease — на сколько шаг «занят» попыткой: параллельный запуск воркера его не возьмёт.
+	purchaseOutboxLease = 5 * time.Minute
+	// Пауза между попытками шага: purchaseOutboxRetryBaseDelay, удваивается с каждой неудачей (см. backoffDelay).
+	purchaseOutboxRetryBaseDelay = 30 * time.Second
+	purchaseOutboxRetryMaxDelay  = time.Hour
+)
+
+// outboxCtx — значения ctx, которые читает проведение покупки. Сохраняются в шаг apply, чтобы повтор
+// из воркера после перезапуска видел то же, что и вебхук: username, мету для уведомления, способ оплаты ЮKassa.
+type outboxCtx struct {
+	Username       string               `json:"username,omitempty"`
+	Stars          *StarsNotifyMeta     `json:"stars,omitempty"`
+	Crypto         *CryptoNotifyMeta    `json:"crypto,omitempty"`
+	YookassaMethod *yookasa.PaymentType `json:"yookassa_method,omitempty"`
+}
+
+func captureOutboxCtx(ctx context.Context) outboxCtx {
+	c := outboxCtx{Username: remnawave.UsernameFromCtx(ctx)}
+	if m, ok := StarsNotifyMetaFromCtx(ctx); ok {
+		c.Stars = &m
+	}
+	if m, ok := CryptoNotifyMetaFromCtx(ctx); ok {
+		c.Crypto = &m
+	}
+	if m, ok := yookasa.PaidPaymentMethodFromCtx(ctx); ok {
+		c.YookassaMethod = &m
+	}
+	return c
+}
+
+func (c outboxCtx) restore(ctx context.Context) context.Context {
+	if c.Username != "" {
+		ctx = context.WithValue(ctx, remnawave.CtxKeyUsername, c.Username)
+	}
+	if c.Stars != nil {
+		ctx = WithStarsNotifyMeta(ctx, *c.Stars)
+	}
+	if c.Crypto != nil {
+		ctx = WithCryptoNotifyMeta(ctx, *c.Crypto)
+	}
+	if c.YookassaMethod != nil {
+		ctx = yookasa.WithPaidPaymentType(ctx, *c.YookassaMethod)
+	}
+	return ctx
+}
+
+// outboxPayload — purchase_outbox.payload. У шага apply это план проведения (клиент до оплаты, ctx, срок)
+// и его результаты, которые читают следующие шаги; у остальных — только Done.
+type outboxPayload struct {
+	Ctx      *outboxCtx         `json:"ctx,omitempty"`
+	Customer *database.Customer `json:"customer,omitempty"`
+	// DropExtraHwid — доп. устройства в счёте не начисляются (HWID_EXTRA_DEVICES выключен).
+	DropExtraHwid bool `json:"drop_extra_hwid,omitempty"`
+	Days          int  `json:"days,omitempty"`
+	FromNow       bool `json:"from_now,omitempty"`
+	// TargetExpire — срок в панели, посчитанный до первого запроса: повтор выставляет его же, а не продлевает ещё раз.
+	TargetExpire      *time.Time `json:"target_expire,omitempty"`
+	DeviceLimitBefore *int       `json:"device_limit_before,omitempty"`
+	DeviceLimitAfter  *int       `json:"device_limit_after,omitempty"`
+	ExpireAfter       *time.Time `json:"expire_after,omitempty"`
+	BalanceAfter      *float64   `json:"balance_after,omitempty"`
+	// TrafficLimitBefore — лимит трафика в панели до пакета (сохраняется до первого изменения).
+	TrafficLimitBefore *int64 `json:"traffic_limit_before,omitempty"`
+	TrafficLimitAfter  *int64 `json:"traffic_limit_after,omitempty"`
+	// Done — части шага, уже выполненные прошлыми попытками (см. outboxRun.once).
+	Done []string `json:"done,omitempty"`
+}
+
+// outboxOnce выполняет часть шага, если она не выполнена прошлой попыткой.
+type outboxOnce func(mark string, fn func() error) error
+
+// purchaseOutboxTasks — шаги проведения покупки по её виду; порядок — порядок выполнения.
+func purchaseOutboxTasks(purchase *database.Purchase, plan *outboxPayload) ([]database.PurchaseOutboxTask, error) {
+	var kinds []database.PurchaseOutboxKind
+	switch {
+	case purchase.PurchaseKind == database.PurchaseKindGift:
+		kinds = []database.PurchaseOutboxKind{
+			database.PurchaseOutboxKindMoynalogReceipt,
+			database.PurchaseOutboxKindLoyaltyXP,
+			database.PurchaseOutboxKindReferralCommission,
+			database.PurchaseOutboxKindNotifyUser,
+			database.PurchaseOutboxKindNotifyAdmin,
+		}
+	case purchase.PurchaseKind == database.PurchaseKindBalanceTopUp:
+		// Чек «Мой налог» выбивается на пополнение; оплата с баланса его уже не создаёт.
+		kinds = []database.PurchaseOutboxKind{
+			database.PurchaseOutboxKindMoynalogReceipt,
+			database.PurchaseOutboxKindNotifyUser,
+			database.PurchaseOutboxKindNotifyAdmin,
+		}
+	case purchase.PurchaseKind == database.PurchaseKindTrafficPack:
+		kinds = []database.PurchaseOutboxKind{
+			database.PurchaseOutboxKindMoynalogReceipt,
+			database.PurchaseOutboxKindNotifyUser,
+			database.PurchaseOutboxKindLoyaltyXP,
+			database.PurchaseOutboxKindReferralCommission,
+			database.PurchaseOutboxKindNotifyAdmin,
+		}
+	case isDevicePurchase(purchase):
+		kinds = []database.PurchaseOutboxKind{
+			database.PurchaseOutboxKindMoynalogReceipt,
+			database.PurchaseOutboxKindNotifyUser,
+			database.PurchaseOutboxKindFamilySync,
+			database.PurchaseOutboxKindPromo,
+			database.PurchaseOutboxKindLoyaltyXP,
+			database.PurchaseOutboxKindReferralCommission,
+			database.PurchaseOutboxKindNotifyAdmin,
+		}
+	default:
+		kinds = []database.PurchaseOutboxKind{
+			database.PurchaseOutboxKindMoynalogReceipt,
+			database.PurchaseOutboxKindNotifyUser,
+			database.PurchaseOutboxKindFamilySync,
+			database.PurchaseOutboxKindReferralBonus,
+			database.PurchaseOutboxKindPromo,
+			database.PurchaseOutboxKindLoyaltyXP,
+			database.PurchaseOutboxKindReferralCommission,
+			database.PurchaseOutboxKindAutoRenewMethod,
+			database.PurchaseOutboxKindNotifyAdmin,
+		}
+	}
+	planJSON, err := json.Marshal(plan)
+	if err != nil {
+		return nil, fmt.Errorf("encode purchase outbox plan: %w", err)
+	}
+	tasks := []database.PurchaseOutboxTask{{Step: 0, Kind: database.PurchaseOutboxKindApply, Payload: planJSON}}
+	for i, k := range kinds {
+		tasks = append(tasks, database.PurchaseOutboxTask{Step: i + 1, Kind: k})
+	}
+	return tasks, nil
+}
+
+// ProcessPurchaseOutbox выполняет шаги проведения оплаченных покупок, у которых подошло время попытки (cron в main).
+func (s PaymentService) ProcessPurchaseOutbox(ctx context.Context) {
+	if s.outboxRepository == nil {
+		return
+	}
+	ids, err := s.outboxRepository.FindDuePurchaseIDs(ctx, time.Now().UTC(), purchaseOutboxBatchLimit)
+	if err != nil {
+		slog.Error("purchase outbox: find due purchases", "error", err)
+		return
+	}
+	for _, id := range ids {
+		_ = s.runPurchaseOutbox(ctx, id)
+	}
+}
+
+// RetryPurchaseOutbox — «повторить» из уведомления админу: возвращает failed-шаги покупки в очередь
+// и сразу выполняет их. Ошибка шага apply возвращается как есть.
+func (s PaymentService) RetryPurchaseOutbox(ctx context.Context, purchaseID int64) error {
+	if err := s.outboxRepository.Retry(ctx, purchaseID, time.Now().UTC()); err != nil {
+		return err
+	}
+	return s.runPurchaseOutbox(ctx, purchaseID)
+}
+
+// runPurchaseOutbox выполняет готовые шаги покупки: сначала apply, затем (только после него) остальные
+// по порядку. Неудачный шаг откладывается с экспоненциальной паузой и не мешает соседним.
+// Возвращает ошибку apply: без неё покупка оплачена, но в панели не применена.
+func (s PaymentService) runPurchaseOutbox(ctx context.Context, purchaseID int64) error {
+	tasks, err := s.outboxRepository.ListByPurchase(ctx, purchaseID)
+	if err != nil {
+		slog.Error("purchase outbox: list tasks", "error", err, "purchase_id", utils.MaskHalfInt64(purchaseID))
+		return err
+	}
+	idx := slices.IndexFunc(tasks, func(t database.PurchaseOutboxTask) bool { return t.Kind == database.PurchaseOutboxKindApply })
+	if idx < 0 {
+		return nil
+	}
+	apply := &tasks[idx]
+	plan := &outboxPayload{}
+	if err := json.Unmarshal(apply.Payload, plan); err != nil {
+		return fmt.Errorf("decode purchase outbox plan: %w", err)
+	}
+	purchase, err := s.purchaseRepository.FindById(ctx, purchaseID)
+	if err != nil {
+		return err
+	}
+	if purchase == nil {
+		return fmt.Errorf("purchase %s not found", utils.MaskHalfInt64(purchaseID))
+	}
+	if purchase.Status.IsRefunded() {
+		// Покупку вернули раньше, чем шаги успели выполниться: доначислять нечего.
+		slog.Warn("purchase outbox: purchase refunded, pending tasks canceled", "purchase_id", utils.MaskHalfInt64(purchaseID))
+		return s.outboxRepository.CancelPending(ctx, purchaseID)
+	}
+	if plan.DropExtraHwid {
+		purchase.ExtraHwid = 0
+	}
+	customer := plan.Customer
+	if customer == nil {
+		if customer, err = s.customerRepository.FindById(ctx, purchase.CustomerID); err != nil {
+			return err
+		}
+		if customer == nil {
+			return fmt.Errorf("customer %s not found", utils.MaskHalfInt64(purchase.CustomerID))
+		}
+	}
+	if plan.Ctx != nil {
+		ctx = plan.Ctx.restore(ctx)
+	}
+	ctx = s.ctxWithTelegramUsernameIfMissing(ctx, customer)
+
+	now := time.Now().UTC()
+	switch apply.Status {
+	case database.PurchaseOutboxStatusPending:
+		done, err := s.runOutboxTask(ctx, apply, plan, purchase, customer, now)
+		if err != nil || !done {
+			return err
+		}
+	case database.PurchaseOutboxStatusDone:
+	default:
+		return nil
+	}
+	for i := range tasks {
+		t := &tasks[i]
+		if t.Kind == database.PurchaseOutboxKindApply || t.Status != database.PurchaseOutboxStatusPending {
+			continue
+		}
+		_, _ = s.runOutboxTask(ctx, t, plan, purchase, customer, now)
+	}
+	return nil
+}
+
+// runOutboxTask делает одну попытку шага. false без ошибки — шаг не готов или его взял параллельный запуск.
+func (s PaymentService) runOutboxTask(ctx context.Context, task *database.PurchaseOutboxTask, plan *outboxPayload,
+	purchase *database.Purchase, customer *database.Customer, now time.Time) (bool, error) {
+	claimed, err := s.outboxRepository.Claim(ctx, task.ID, now, now.Add(purchaseOutboxLease))
+	if err != nil {
+		slog.Error("purchase outbox: claim task", "error", err, "task_id", task.ID)
+		return false, err
+	}
+	if claimed == nil {
+		return false, nil
+	}
+	state := plan
+	if claimed.Kind != database.PurchaseOutboxKindApply {
+		state = &outboxPayload{}
+		if err := json.Unmarshal(claimed.Payload, state); err != nil {
+			slog.Error("purchase outbox: decode task payload", "error", err, "task_id", claimed.ID)
+		}
+	}
+	r := &outboxRun{s: s, task: claimed, state: state, plan: plan, purchase: purchase, customer: customer}
+	if err := r.exec(ctx); err != nil {
+		s.recordOutboxFailure(ctx, claimed, err, now)
+		return false, err
+	}
+	if r.completed {
+		return true, nil
+	}
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return false, fmt.Errorf("encode purchase outbox payload: %w", err)
+	}
+	if err := s.outboxRepository.MarkDone(ctx, claimed.ID, payload); err != nil {
+		slog.Error("purchase outbox: mark task done", "error", err, "task_id", claimed.ID, "kind", claimed.Kind)
+		return false, err
+	}
+	return true, nil
+}
+
+// recordOutboxFailure откладывает следующую попытку. Когда попытки исчерпаны, шаг уходит в failed
+// и админ получает уведомление (кроме уведомлений: сообщение клиенту могло не дойти, например, из-за блокировки бота).
+func (s PaymentService) recordOutboxFailure(ctx context.Context, task *database.PurchaseOutboxTask, opErr error, now time.Time) {
+	attempts := task.Attempts + 1
+	final := attempts >= config.PurchaseOutboxMaxAttempts()
+	next := now.Add(backoffDelay(attempts, purchaseOutboxRetryBaseDelay, purchaseOutboxRetryMaxDelay))
+	slog.Error("purchase outbox: task failed", "error", opErr, "task_id", task.ID, "kind", task.Kind,
+		"purchase_id", utils.MaskHalfInt64(task.PurchaseID), "attempts", attempts, "final", final)
+	updated, err := s.outboxRepository.RecordFailure(ctx, task.ID, opErr.Error(), &next, final)
+	if err != nil {
+		slog.Error("purchase outbox: record task failure", "error", err, "task_id", task.ID)
+		return
+	}
+	if final && updated != nil && task.Kind != database.PurchaseOutboxKindNotifyUser && task.Kind != database.PurchaseOutboxKindNotifyAdmin {
+		s.notifyAdminOutboxFailure(ctx, updated)
+	}
+}
+
+func (s PaymentService) notifyAdminOutboxFailure(ctx context.Context, t *database.PurchaseOutboxTask) {
+	adminID := config.GetAdminTelegramId()
+	if s.telegramBot == nil || adminID == 0 {
+		return
+	}
+	lang := "ru"
+	lastErr := ""
+	if t.LastError != nil {
+		lastErr = *t.LastError
+	}
+	text := fmt.Sprintf(s.translation.GetText(lang, "admin_purchase_outbox_failed"),
+		t.PurchaseID,
+		s.translation.GetText(lang, "admin_purchase_outbox_kind_"+string(t.Kind)),
+		t.Attempts,
+		html.EscapeString(lastErr),
+	)
+	if _, err := s.telegramBot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    adminID,
+		ParseMode: models.ParseModeHTML,
+		Text:      text,
+		ReplyMarkup: models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
+			{s.translation.WithButton(lang, "admin_purchase_outbox_retry_button", models.InlineKeyboardButton{CallbackData: fmt.Sprintf("%s%d", purchaseOutboxRetryCallbackPrefix, t.PurchaseID)})},
+		}},
+	}); err != nil {
+		slog.Error("Failed to notify admin about purchase outbox failure", "error", err, "task_id", t.ID)
+	}
+}
+
+// outboxRun — одна попытка шага. plan — состояние apply (клиент до оплаты, результаты начисления),
+// state — состояние текущего шага (для apply совпадает с plan).
+type outboxRun struct {
+	s        PaymentService
+	task     *database.PurchaseOutboxTask
+	state    *outboxPayload
+	plan     *outboxPayload
+	purchase *database.Purchase
+	customer *database.Customer
+	// completed — шаг закрыт вместе со своим эффектом в одной транзакции (MarkDone не нужен).
+	completed bool
+}
+
+// save сохраняет состояние шага до следующего внешнего вызова.
+func (r *outboxRun) save(ctx context.Context) error {
+	payload, err := json.Marshal(r.state)
+	if err != nil {
+		return fmt.Errorf("encode purchase outbox payload: %w", err)
+	}
+	return r.s.outboxRepository.SavePayload(ctx, r.task.ID, payload)
+}
+
+// once выполняет fn, если часть mark не отмечена прошлой попыткой, и сразу сохраняет отметку:
+// повтор шага после ошибки в следующей части не выполнит эту второй раз.
+func (r *outboxRun) once(ctx context.Context) outboxOnce {
+	return func(mark string, fn func() error) error {
+		if slices.Contains(r.state.Done, mark) {
+			return nil
+		}
+		if err := fn(); err != nil {
+			return err
+		}
+		r.state.Done = append(r.state.Done, mark)
+		return r.save(ctx)
+	}
+}
+
+func (r *outboxRun) exec(ctx context.Context) error {
+	s, purchase, customer := r.s, r.purchase, r.customer
+	switch r.task.Kind {
+	case database.PurchaseOutboxKindApply:
+		return r.apply(ctx)
+	case database.PurchaseOutboxKindMoynalogReceipt:
+		return s.sendMoynalogReceipt(ctx, purchase)
+	case database.PurchaseOutboxKindNotifyUser:
+		return r.notifyUser(ctx)
+	case database.PurchaseOutboxKindReferralBonus:
+		return s.applyReferralBonus(ctx, purchase, customer, r.once(ctx))
+	case database.PurchaseOutboxKindPromo:
+		return s.clearPromoDiscountIfUsed(ctx, purchase, customer)
+	case database.PurchaseOutboxKindLoyaltyXP:
+		return r.awardLoyaltyXP(ctx)
+	case database.PurchaseOutboxKindReferralCommission:
+		return s.accrueReferralCommission(ctx, purchase, customer)
+	case database.PurchaseOutboxKindAutoRenewMethod:
+		s.rememberAutoRenewMethod(ctx, purchase, customer)
+		return nil
+	case database.PurchaseOutboxKindFamilySync:
+		return s.syncFamilyAfterPurchase(ctx, purchase)
+	case database.PurchaseOutboxKindNotifyAdmin:
+		expireAfter := customer.ExpireAt
+		if r.plan.ExpireAfter != nil {
+			expireAfter = r.plan.ExpireAfter
+		}
+		s.tryNotifyPurchasePaid(ctx, purchase, customer, customer.ExpireAt, expireAfter)
+		return nil
+	}
+	return fmt.Errorf("unknown purchase outbox task kind %q", r.task.Kind)
+}
+
+// apply — то, за что заплатили: подписка в панели, доп. устройства, пакет трафика, код подарка или зачисление на баланс.
+func (r *outboxRun) apply(ctx context.Context) error {
+	switch {
+	case r.purchase.PurchaseKind == database.PurchaseKindGift:
+		gift, err := r.s.issueGift(ctx, r.purchase)
+		if err != nil {
+			return err
+		}
+		slog.Info("gift purchase processed", "purchase_id", utils.MaskHalfInt64(r.purchase.ID), "gift_id", gift.ID, "customer_id", utils.MaskHalfInt64(r.customer.ID))
+		return nil
+	case r.purchase.PurchaseKind == database.PurchaseKindBalanceTopUp:
+		balance, err := r.s.creditBalanceTopUp(ctx, r.purchase)
+		if err != nil {
+			return err
+		}
+		r.plan.BalanceAfter = &balance
+		return nil
+	case r.purchase.PurchaseKind == database.PurchaseKindTrafficPack:
+		return r.applyTrafficPack(ctx)
+	case isDevicePurchase(r.purchase):
+		return r.applyDevices(ctx)
+	}
+	return r.applySubscription(ctx)
+}
+
+// applySubscription продлевает подписку до срока, посчитанного один раз перед первым запросом в панель.
+func (r *outboxRun) applySubscription(ctx context.Context) error {
+	s, purchase, customer, plan := r.s, r.purchase, r.customer, r.plan
+	var profile *remnawave.TariffPaidProfile
+	if config.SalesMode() == "tariffs" && purchase.TariffID != nil && *purchase.TariffID > 0 && s.tariffRepository != nil {
+		tariff, err := s.tariffRepository.GetByID(ctx, *purchase.TariffID)
+		if err != nil {
+			return err
+		}
+		if tariff == nil {
+			return fmt.Errorf("tariff %d not found", *purchase.TariffID)
+		}
+		tariffProfile := BuildRemnawaveTariffProfile(tariff)
+		profile = &tariffProfile
+	}
+
+	rwCtx := s.withRemnawavePanelUsername(ctx, customer)
+	if plan.TargetExpire == nil {
+		// Оплата во время паузы возобновляет подписку: продление считается от размороженного срока.
+		if err := s.resumeIfPaused(ctx, customer); err != nil {
+			return err
+		}
+		var base time.Time
+		if plan.FromNow {
+			base = time.Now().UTC().Add(-time.Second)
+		} else {
+			existing, err := s.remnawaveClient.FindUserForCustomer(rwCtx, customer.ID, customer.TelegramID)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				base = existing.ExpireAt
+			}
+		}
+		target := remnawave.ExpireAfterDays(plan.Days, base)
+		plan.TargetExpire = &target
+		if err := r.save(ctx); err != nil {
+			return err
+		}
+	}
+
+	var (
+		user *remnawave.User
+		err  error
+	)
+	if profile != nil {
+		user, err = s.remnawaveClient.CreateOrUpdateUserWithTariffProfileUntil(rwCtx, customer.ID, customer.TelegramID, *profile, *plan.TargetExpire)
+	} else {
+		user, err = s.remnawaveClient.CreateOrUpdateUserUntil(rwCtx, customer.ID, customer.TelegramID, config.TrafficLimit(), *plan.TargetExpire)
+	}
+	if err != nil {
+		return err
+	}
+	plan.ExpireAfter = ptrTimeIfValid(user.ExpireAt)
+	if err := s.saveCustomerSubscription(ctx, customer, user, purchase.TariffID, purchase.Month); err != nil {
+		return err
+	}
+
+	if plan.DeviceLimitBefore == nil {
+		userInfo, err := s.remnawaveClient.GetUserTrafficInfo(ctx, customer.TelegramID)
+		if err != nil {
+			return err
+		}
+		limit := resolveDeviceLimit(userInfo)
+		plan.DeviceLimitBefore = &limit
+		if err := r.save(ctx); err != nil {
+			return err
+		}
+	}
+	if err := s.applyExtraWithDeviceLimit(ctx, customer, user, purchase, *plan.DeviceLimitBefore); err != nil {
+		return err
+	}
+	if err := r.once(ctx)("traffic_reset", func() error { return s.resetTrafficAfterSubscriptionPayment(ctx, user) }); err != nil {
+		return err
+	}
+	if err := s.closeTrafficPacks(ctx, customer.ID); err != nil {
+		return err
+	}
+	slog.Info("purchase processed", "purchase_id", utils.MaskHalfInt64(purchase.ID), "type", purchase.InvoiceType, "customer_id", utils.MaskHalfInt64(customer.ID))
+	return nil
+}
+
+// applyDevices — докупка устройств: лимит в панели = лимит до оплаты + купленные (лимит до оплаты сохраняется
+// перед первым изменением, повтор не прибавит устройства второй раз).
+func (r *outboxRun) applyDevices(ctx context.Context) error {
+	s, purchase, customer, plan := r.s, r.purchase, r.customer, r.plan
+	if customer.ExpireAt == nil {
+		return fmt.Errorf("subscription expire_at is not set")
+	}
+	paidAt := time.Now()
+	if purchase.PaidAt != nil {
+		paidAt = *purchase.PaidAt
+	}
+
+	currentExtra := 0
+	if customer.ExtraHwid > 0 && customer.ExtraHwidExpiresAt != nil && customer.ExtraHwidExpiresAt.After(paidAt) {
+		currentExtra = customer.ExtraHwid
+	} else if customer.ExtraHwid > 0 && customer.ExtraHwidExpiresAt != nil {
+		err := r.once(ctx)("expired_extra_reset", func() error {
+			fallback := config.GetHwidFallbackDeviceLimit()
+			if fallback < 1 {
+				fallback = 1
+			}
+			if _, err := s.remnawaveClient.UpdateUserDeviceLimit(ctx, customer.TelegramID, fallback); err != nil {
+				return err
+			}
+			return s.customerRepository.UpdateFields(ctx, customer.ID, map[string]interface{}{
+				"extra_hwid":            0,
+				"extra_hwid_expires_at": nil,
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if plan.DeviceLimitBefore == nil {
+		userInfo, err := s.remnawaveClient.GetUserTrafficInfo(ctx, customer.TelegramID)
+		if err != nil {
+			return err
+		}
+		limit := resolveDeviceLimit(userInfo)
+		plan.DeviceLimitBefore = &limit
+		if err := r.save(ctx); err != nil {
+			return err
+		}
+	}
+	newLimit := *plan.DeviceLimitBefore + purchase.ExtraHwid
+	if maxLimit := config.HwidMaxDevices(); maxLimit > 0 && newLimit > maxLimit {
+		newLimit = maxLimit
+	}
+	plan.DeviceLimitAfter = &newLimit
+
+	updatedUser, err := s.remnawaveClient.UpdateUserDeviceLimit(ctx, customer.TelegramID, newLimit)
+	if err != nil {
+		return err
+	}
+	if err := s.customerRepository.UpdateFields(ctx, customer.ID, map[string]interface{}{
+		"extra_hwid":            currentExtra + purchase.ExtraHwid,
+		"extra_hwid_expires_at": customer.ExpireAt,
+	}); err != nil {
+		return err
+	}
+	if updatedUser != nil {
+		if err := s.customerRepository.UpdateFields(ctx, customer.ID, map[string]interface{}{
+			"subscription_link": updatedUser.SubscriptionUrl,
+			"expire_at":         updatedUser.ExpireAt,
+		}); err != nil {
+			return err
+		}
+		plan.ExpireAfter = ptrTimeIfValid(updatedUser.ExpireAt)
+	}
+	return nil
+}
+
+// notifyUser — сообщение покупателю о проведённой оплате.
+func (r *outboxRun) notifyUser(ctx context.Context) error {
+	s, purchase, customer, plan := r.s, r.purchase, r.customer, r.plan
+	switch {
+	case purchase.PurchaseKind == database.PurchaseKindGift:
+		gift, err := s.giftRepository.FindByPurchaseID(ctx, purchase.ID)
+		if err != nil {
+			return err
+		}
+		if gift == nil {
+			return fmt.Errorf("gift for purchase %d not found", purchase.ID)
+		}
+		s.notifyGiftIssued(ctx, customer, gift)
+		return nil
+	case purchase.PurchaseKind == database.PurchaseKindBalanceTopUp:
+		balance := 0.0
+		if plan.BalanceAfter != nil {
+			balance = *plan.BalanceAfter
+		}
+		s.notifyBalanceToppedUp(ctx, customer, purchase.Amount, balance)
+		return nil
+	case purchase.PurchaseKind == database.PurchaseKindTrafficPack:
+		s.notifyTrafficPackApplied(ctx, customer, purchase.TrafficGB, plan.TrafficLimitAfter)
+		return nil
+	}
+	if skipTelegramCustomerDM(customer) {
+		return nil
+	}
+	text := s.translation.GetText(customer.Language, "subscription_activated")
+	switch {
+	case isDevicePurchase(purchase) && plan.DeviceLimitBefore != nil && plan.DeviceLimitAfter != nil:
+		text = fmt.Sprintf(s.translation.GetText(customer.Language, "hwid_change_success_paid"),
+			*plan.DeviceLimitBefore, *plan.DeviceLimitAfter, int(math.Ceil(purchase.Amount)))
+	case purchase.StarsSubscriptionID != nil && purchase.IsAutoRenew:
+		text = fmt.Sprintf(s.translation.GetText(customer.Language, "stars_subscription_renewed"), int(purchase.Amount))
+	case purchase.StarsSubscriptionID != nil:
+		text += "\n\n" + s.translation.GetText(customer.Language, "stars_subscription_started")
+	}
+	_, err := s.telegramBot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: customer.TelegramID,
+		Text:   text,
+		ReplyMarkup: models.InlineKeyboardMarkup{
+			InlineKeyboard: s.createConnectKeyboard(customer),
+		},
+	})
+	return err
+}
+
+// awardLoyaltyXP начисляет XP за покупку вместе с закрытием шага (одна транзакция): повтор не задвоит XP.
+func (r *outboxRun) awardLoyaltyXP(ctx context.Context) error {
+	if !config.LoyaltyEnabled() {
+		return nil
+	}
+	gain := loyalty.XPRubEquivalentForPurchase(r.purchase)
+	if gain <= 0 {
+		return nil
+	}
+	applied, err := r.s.outboxRepository.CompleteLoyaltyXP(ctx, r.task.ID, r.customer.ID, gain)
+	if err != nil {
+		return err
+	}
+	r.completed = true
+	if applied {
+		oldXP := r.customer.LoyaltyXP
+		r.s.maybeNotifyLoyaltyLevelUp(ctx, r.customer, oldXP, oldXP+gain)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package payment
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/database"
+)
+
+func TestPurchaseOutboxTasks(t *testing.T) {
+	tests := []struct {
+		name     string
+		purchase database.Purchase
+		want     []database.PurchaseOutboxKind
+	}{
+		{
+			name:     "subscription",
+			purchase: database.Purchase{Month: 1},
+			want: []database.PurchaseOutboxKind{
+				database.PurchaseOutboxKindApply,
+				database.PurchaseOutboxKindMoynalogReceipt,
+				database.PurchaseOutboxKindNotifyUser,
+				database.PurchaseOutboxKindFamilySync,
+				database.PurchaseOutboxKindReferralBonus,
+				database.PurchaseOutboxKindPromo,
+				database.PurchaseOutboxKindLoyaltyXP,
+				database.PurchaseOutboxKindReferralCommission,
+				database.PurchaseOutboxKindAutoRenewMethod,
+				database.PurchaseOutboxKindNotifyAdmin,
+			},
+		},
+		{
+			name:     "devices",
+			purchase: database.Purchase{ExtraHwid: 2},
+			want: []database.PurchaseOutboxKind{
+				database.PurchaseOutboxKindApply,
+				database.PurchaseOutboxKindMoynalogReceipt,
+				database.PurchaseOutboxKindNotifyUser,
+				database.PurchaseOutboxKindFamilySync,
+				database.PurchaseOutboxKindPromo,
+				database.PurchaseOutboxKindLoyaltyXP,
+				database.PurchaseOutboxKindReferralCommission,
+				database.PurchaseOutboxKindNotifyAdmin,
+			},
+		},
+		{
+			name:     "gift",
+			purchase: database.Purchase{Month: 3, PurchaseKind: database.PurchaseKindGift},
+			want: []database.PurchaseOutboxKind{
+				database.PurchaseOutboxKindApply,
+				database.PurchaseOutboxKindMoynalogReceipt,
+				database.PurchaseOutboxKindLoyaltyXP,
+				database.PurchaseOutboxKindReferralCommission,
+				database.PurchaseOutboxKindNotifyUser,
+				database.PurchaseOutboxKindNotifyAdmin,
+			},
+		},
+		{
+			name:     "balance top-up",
+			purchase: database.Purchase{PurchaseKind: database.PurchaseKindBalanceTopUp},
+			want: []database.PurchaseOutboxKind{
+				database.PurchaseOutboxKindApply,
+				database.PurchaseOutboxKindMoynalogReceipt,
+				database.PurchaseOutboxKindNotifyUser,
+				database.PurchaseOutboxKindNotifyAdmin,
+			},
+		},
+		{
+			name:     "traffic pack",
+			purchase: database.Purchase{TrafficGB: 50, PurchaseKind: database.PurchaseKindTrafficPack},
+			want: []database.PurchaseOutboxKind{
+				database.PurchaseOutboxKindApply,
+				database.PurchaseOutboxKindMoynalogReceipt,
+				database.PurchaseOutboxKindNotifyUser,
+				database.PurchaseOutboxKindLoyaltyXP,
+				database.PurchaseOutboxKindReferralCommission,
+				database.PurchaseOutboxKindNotifyAdmin,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks, err := purchaseOutboxTasks(&tt.purchase, &outboxPayload{Days: 30})
+			if err != nil {
+				t.Fatalf("purchaseOutboxTasks: %v", err)
+			}
+			if len(tasks) != len(tt.want) {
+				t.Fatalf("got %d tasks, want %d", len(tasks), len(tt.want))
+			}
+			for i, task := range tasks {
+				if task.Kind != tt.want[i] || task.Step != i {
+					t.Fatalf("task %d = %s (step %d), want %s (step %d)", i, task.Kind, task.Step, tt.want[i], i)
+				}
+			}
+			var plan outboxPayload
+			if err := json.Unmarshal(tasks[0].Payload, &plan); err != nil || plan.Days != 30 {
+				t.Fatalf("apply payload = %s, err %v", tasks[0].Payload, err)
+			}
+		})
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		0:  purchaseOutboxRetryBaseDelay,
+		1:  purchaseOutboxRetryBaseDelay,
+		3:  4 * purchaseOutboxRetryBaseDelay,
+		7:  32 * time.Minute,
+		8:  purchaseOutboxRetryMaxDelay,
+		50: purchaseOutboxRetryMaxDelay,
+	} {
+		if got := backoffDelay(attempts, purchaseOutboxRetryBaseDelay, purchaseOutboxRetryMaxDelay); got != want {
+			t.Fatalf("backoffDelay(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
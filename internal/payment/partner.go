@@ -0,0 +1,444 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/utils"
+)
+
+var (
+	ErrPartnerDisabled = errors.New("referral partner program is disabled")
+	// ErrPartnerNotPartner — у клиента нулевая ставка: ему нечего показывать и выплачивать.
+	ErrPartnerNotPartner = errors.New("customer is not a referral partner")
+	ErrPartnerPercent    = errors.New("commission percent must be between 0 and 100")
+	// ErrPayoutAmount — сумма меньше REFERRAL_PARTNER_MIN_PAYOUT или больше доступной.
+	ErrPayoutAmount = errors.New("payout amount is out of range")
+	ErrPayoutMethod = errors.New("payout method is not available")
+	// ErrPayoutNotFound — запроса нет; повторная обработка возвращает database.ErrPayoutStatusConflict.
+	ErrPayoutNotFound = errors.New("payout request not found")
+)
+
+// PartnerDashboard — сводка партнёра для бота и кабинета.
+type PartnerDashboard struct {
+	Percent   float64
+	Summary   database.PartnerSummary
+	MinPayout int
+	HoldDays  int
+}
+
+// Available — доступно к выплате (не меньше нуля).
+func (d *PartnerDashboard) Available() float64 {
+	return math.Max(d.Summary.Available(), 0)
+}
+
+// CanRequestPayout — можно ли сейчас запросить выплату.
+func (d *PartnerDashboard) CanRequestPayout() bool {
+	return d.Summary.PayoutPending == 0 && d.Available() >= float64(d.MinPayout)
+}
+
+// referralCommissionAmount — комиссия с оплаты, до копеек.
+func referralCommissionAmount(purchaseAmount, percent float64) float64 {
+	if purchaseAmount <= 0 || percent <= 0 {
+		return 0
+	}
+	return math.Round(purchaseAmount*percent) / 100
+}
+
+// commissionReversalTarget — сколько комиссии должно быть сторнировано после возврата refundedTotal из purchaseAmount.
+// Считаем от накопленной доли, как planRefundReversal: серия частичных возвратов в сумме снимает всю комиссию.
+func commissionReversalTarget(commission, purchaseAmount, refundedTotal float64, final bool) float64 {
+	if final || purchaseAmount <= 0 {
+		return commission
+	}
+	frac := math.Min(refundedTotal/purchaseAmount, 1)
+	return math.Round(commission*frac*100) / 100
+}
+
+// PartnerCommissionPercent — ставка реферера: персональная (если назначена) или REFERRAL_PARTNER_DEFAULT_PERCENT.
+func (s PaymentService) PartnerCommissionPercent(ctx context.Context, customerID int64) (float64, error) {
+	if s.partnerRepository == nil {
+		return 0, ErrPartnerDisabled
+	}
+	p, err := s.partnerRepository.GetPartner(ctx, customerID)
+	if err != nil {
+		return 0, err
+	}
+	if p != nil {
+		if !p.Enabled {
+			return 0, nil
+		}
+		return p.CommissionPercent, nil
+	}
+	return float64(config.ReferralPartnerDefaultPercent()), nil
+}
+
+// accrueReferralCommission начисляет комиссию пригласившему за оплату реферала.
+// Начисляются только рублёвые покупки; пополнение баланса не в счёт — комиссия берётся при трате с баланса.
+// Комиссия одна на покупку (uq_referral_commission_purchase): повтор шага outbox после ошибки её не задвоит.
+func (s PaymentService) accrueReferralCommission(ctx context.Context, purchase *database.Purchase, customer *database.Customer) error {
+	if !config.ReferralPartnerEnabled() || s.partnerRepository == nil || s.referralRepository == nil {
+		return nil
+	}
+	if purchase.PurchaseKind == database.PurchaseKindBalanceTopUp || !purchaseCurrencyRubForMoynalog(purchase) || purchase.Amount <= 0 {
+		return nil
+	}
+	referral, err := s.referralRepository.FindByReferee(ctx, customer.TelegramID)
+	if err != nil {
+		return fmt.Errorf("partner: find referral: %w", err)
+	}
+	if referral == nil || referral.ReferrerID == customer.TelegramID {
+		return nil
+	}
+	partner, err := s.customerRepository.FindByTelegramId(ctx, referral.ReferrerID)
+	if err != nil {
+		return fmt.Errorf("partner: find referrer: %w", err)
+	}
+	if partner == nil {
+		return nil
+	}
+	percent, err := s.PartnerCommissionPercent(ctx, partner.ID)
+	if err != nil {
+		return fmt.Errorf("partner: commission percent: %w", err)
+	}
+	amount := referralCommissionAmount(purchase.Amount, percent)
+	if amount <= 0 {
+		return nil
+	}
+	c, created, err := s.partnerRepository.CreateCommission(ctx, &database.ReferralCommission{
+		PartnerCustomerID: partner.ID,
+		RefereeCustomerID: customer.ID,
+		PurchaseID:        purchase.ID,
+		PurchaseAmount:    purchase.Amount,
+		CommissionPercent: percent,
+		Amount:            amount,
+		AvailableAt:       time.Now().UTC().AddDate(0, 0, config.ReferralPartnerHoldDays()),
+	})
+	if err != nil {
+		return fmt.Errorf("partner: create commission: %w", err)
+	}
+	if !created {
+		return nil
+	}
+	slog.Info("partner commission accrued", "partner_id", utils.MaskHalfInt64(partner.ID), "purchase_id", utils.MaskHalfInt64(purchase.ID), "amount", c.Amount)
+	s.notifyPartnerCommission(ctx, partner, c)
+	return nil
+}
+
+// reverseReferralCommission сторнирует комиссию после возврата или отмены покупки.
+// refundedTotal — сколько всего вернули по покупке (с учётом текущего возврата).
+func (s PaymentService) reverseReferralCommission(ctx context.Context, purchase *database.Purchase, refundedTotal float64, final bool) {
+	if s.partnerRepository == nil {
+		return
+	}
+	c, err := s.partnerRepository.FindCommissionByPurchase(ctx, purchase.ID)
+	if err != nil {
+		slog.Error("partner: find commission", "error", err, "purchase_id", utils.MaskHalfInt64(purchase.ID))
+		return
+	}
+	if c == nil {
+		return
+	}
+	target := commissionReversalTarget(c.Amount, purchase.Amount, refundedTotal, final)
+	if target <= c.ReversedAmount {
+		return
+	}
+	if _, err := s.partnerRepository.ReverseCommission(ctx, purchase.ID, target); err != nil {
+		slog.Error("partner: reverse commission", "error", err, "purchase_id", utils.MaskHalfInt64(purchase.ID))
+		return
+	}
+	slog.Info("partner commission reversed", "partner_id", utils.MaskHalfInt64(c.PartnerCustomerID), "purchase_id", utils.MaskHalfInt64(purchase.ID),
+		"reversed", target-c.ReversedAmount)
+}
+
+// PartnerDashboard — ставка и суммы партнёра. ErrPartnerNotPartner — ставка нулевая и начислений не было.
+func (s PaymentService) PartnerDashboard(ctx context.Context, customerID int64) (*PartnerDashboard, error) {
+	if !config.ReferralPartnerEnabled() || s.partnerRepository == nil {
+		return nil, ErrPartnerDisabled
+	}
+	percent, err := s.PartnerCommissionPercent(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := s.partnerRepository.Summary(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	if percent <= 0 && summary.Earned == 0 && summary.PaidOut == 0 {
+		return nil, ErrPartnerNotPartner
+	}
+	return &PartnerDashboard{
+		Percent:   percent,
+		Summary:   summary,
+		MinPayout: config.ReferralPartnerMinPayout(),
+		HoldDays:  config.ReferralPartnerHoldDays(),
+	}, nil
+}
+
+// PartnerCommissions — начисления партнёра (новые сверху).
+func (s PaymentService) PartnerCommissions(ctx context.Context, customerID int64, limit, offset int) ([]database.ReferralCommission, error) {
+	if s.partnerRepository == nil {
+		return nil, ErrPartnerDisabled
+	}
+	return s.partnerRepository.ListCommissions(ctx, customerID, limit, offset)
+}
+
+// PartnerPayouts — запросы на выплату; customerID 0 — все партнёры (очередь админа).
+func (s PaymentService) PartnerPayouts(ctx context.Context, status database.PayoutStatus, customerID int64, limit, offset int) ([]database.ReferralPayout, error) {
+	if s.partnerRepository == nil {
+		return nil, ErrPartnerDisabled
+	}
+	return s.partnerRepository.ListPayouts(ctx, status, customerID, limit, offset)
+}
+
+// PayoutMethodAvailable — доступен ли способ выплаты (на баланс — только при BALANCE_ENABLED).
+func PayoutMethodAvailable(m database.PayoutMethod) bool {
+	switch m {
+	case database.PayoutMethodBalance:
+		return config.BalanceEnabled()
+	case database.PayoutMethodManual:
+		return true
+	}
+	return false
+}
+
+// RequestPartnerPayout создаёт запрос на выплату; amount 0 — всё доступное.
+// database.ErrPayoutPendingExists — предыдущий запрос ещё в очереди.
+func (s PaymentService) RequestPartnerPayout(ctx context.Context, customer *database.Customer, amount float64, method database.PayoutMethod, details string) (*database.ReferralPayout, error) {
+	dash, err := s.PartnerDashboard(ctx, customer.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !PayoutMethodAvailable(method) {
+		return nil, ErrPayoutMethod
+	}
+	if dash.Summary.PayoutPending > 0 {
+		return nil, database.ErrPayoutPendingExists
+	}
+	available := math.Floor(dash.Available()*100) / 100
+	if amount <= 0 {
+		amount = available
+	}
+	amount = math.Round(amount*100) / 100
+	if amount < float64(dash.MinPayout) || amount > available {
+		return nil, ErrPayoutAmount
+	}
+	p := &database.ReferralPayout{
+		PartnerCustomerID: customer.ID,
+		Amount:            amount,
+		Method:            method,
+	}
+	if d := strings.TrimSpace(details); d != "" {
+		p.Details = &d
+	}
+	out, err := s.partnerRepository.CreatePayout(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("partner payout requested", "payout_id", out.ID, "partner_id", utils.MaskHalfInt64(customer.ID), "amount", out.Amount, "method", out.Method)
+	s.notifyAdminPayoutRequested(ctx, customer, out)
+	return out, nil
+}
+
+// ApprovePartnerPayout — админ подтверждает выплату. Для method=balance сумма сразу зачисляется на баланс;
+// manual — админ уже перевёл деньги сам. Если зачисление не прошло, запрос возвращается в очередь.
+func (s PaymentService) ApprovePartnerPayout(ctx context.Context, payoutID int64, comment string, adminTelegramID, adminAccountID int64) (*database.ReferralPayout, error) {
+	if s.partnerRepository == nil {
+		return nil, ErrPartnerDisabled
+	}
+	existing, err := s.partnerRepository.FindPayout(ctx, payoutID)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, ErrPayoutNotFound
+	}
+	if existing.Method == database.PayoutMethodBalance && (!config.BalanceEnabled() || s.balanceRepository == nil) {
+		return nil, ErrBalanceDisabled
+	}
+	c, tg, acc := payoutAdminFields(comment, adminTelegramID, adminAccountID)
+	p, err := s.partnerRepository.TransitionPayout(ctx, payoutID, database.PayoutStatusPending, database.PayoutStatusPaid, c, tg, acc)
+	if err != nil {
+		return nil, err
+	}
+	if p.Method == database.PayoutMethodBalance {
+		txComment := fmt.Sprintf("partner payout #%d", p.ID)
+		if _, err := s.balanceRepository.Apply(ctx, &database.BalanceTx{
+			CustomerID:      p.PartnerCustomerID,
+			Type:            database.BalanceTxReferral,
+			Amount:          p.Amount,
+			Comment:         &txComment,
+			AdminTelegramID: tg,
+			AdminAccountID:  acc,
+		}); err != nil {
+			if _, rerr := s.partnerRepository.TransitionPayout(ctx, p.ID, database.PayoutStatusPaid, database.PayoutStatusPending, nil, nil, nil); rerr != nil {
+				slog.Error("partner: return payout to queue", "error", rerr, "payout_id", p.ID)
+			}
+			return nil, err
+		}
+	}
+	slog.Info("partner payout approved", "payout_id", p.ID, "partner_id", utils.MaskHalfInt64(p.PartnerCustomerID), "amount", p.Amount, "method", p.Method)
+	s.notifyPartnerPayout(ctx, p)
+	return p, nil
+}
+
+// RejectPartnerPayout — админ отклоняет запрос; сумма снова доступна партнёру.
+func (s PaymentService) RejectPartnerPayout(ctx context.Context, payoutID int64, comment string, adminTelegramID, adminAccountID int64) (*database.ReferralPayout, error) {
+	if s.partnerRepository == nil {
+		return nil, ErrPartnerDisabled
+	}
+	existing, err := s.partnerRepository.FindPayout(ctx, payoutID)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, ErrPayoutNotFound
+	}
+	c, tg, acc := payoutAdminFields(comment, adminTelegramID, adminAccountID)
+	p, err := s.partnerRepository.TransitionPayout(ctx, payoutID, database.PayoutStatusPending, database.PayoutStatusRejected, c, tg, acc)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("partner payout rejected", "payout_id", p.ID, "partner_id", utils.MaskHalfInt64(p.PartnerCustomerID))
+	s.notifyPartnerPayout(ctx, p)
+	return p, nil
+}
+
+func payoutAdminFields(comment string, adminTelegramID, adminAccountID int64) (c *string, tg, acc *int64) {
+	if v := strings.TrimSpace(comment); v != "" {
+		c = &v
+	}
+	if adminTelegramID != 0 {
+		tg = &adminTelegramID
+	}
+	if adminAccountID != 0 {
+		acc = &adminAccountID
+	}
+	return c, tg, acc
+}
+
+// PartnerSettings — персональная ставка (nil, если не назначена) и итоговая ставка реферера.
+func (s PaymentService) PartnerSettings(ctx context.Context, customerID int64) (*database.ReferralPartner, float64, error) {
+	if s.partnerRepository == nil {
+		return nil, 0, ErrPartnerDisabled
+	}
+	p, err := s.partnerRepository.GetPartner(ctx, customerID)
+	if err != nil {
+		return nil, 0, err
+	}
+	percent, err := s.PartnerCommissionPercent(ctx, customerID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return p, percent, nil
+}
+
+// SetPartnerRate назначает рефереру персональную ставку. Уже начисленные комиссии не пересчитываются.
+func (s PaymentService) SetPartnerRate(ctx context.Context, customerID int64, percent float64, enabled bool, note string) (*database.ReferralPartner, error) {
+	if s.partnerRepository == nil {
+		return nil, ErrPartnerDisabled
+	}
+	if percent < 0 || percent > 100 || math.IsNaN(percent) {
+		return nil, ErrPartnerPercent
+	}
+	p := &database.ReferralPartner{
+		CustomerID:        customerID,
+		CommissionPercent: math.Round(percent*100) / 100,
+		Enabled:           enabled,
+	}
+	if n := strings.TrimSpace(note); n != "" {
+		p.Note = &n
+	}
+	out, err := s.partnerRepository.UpsertPartner(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("partner rate set", "customer_id", utils.MaskHalfInt64(customerID), "percent", out.CommissionPercent, "enabled", out.Enabled)
+	return out, nil
+}
+
+// ListPartners — рефереры с персональной ставкой.
+func (s PaymentService) ListPartners(ctx context.Context, limit, offset int) ([]database.ReferralPartner, error) {
+	if s.partnerRepository == nil {
+		return nil, ErrPartnerDisabled
+	}
+	return s.partnerRepository.ListPartners(ctx, limit, offset)
+}
+
+func (s PaymentService) notifyPartnerCommission(ctx context.Context, partner *database.Customer, c *database.ReferralCommission) {
+	if s.telegramBot == nil || skipTelegramCustomerDM(partner) {
+		return
+	}
+	_, err := s.telegramBot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    partner.TelegramID,
+		ParseMode: models.ParseModeHTML,
+		Text:      fmt.Sprintf(s.translation.GetText(partner.Language, "partner_commission_accrued"), FormatBalanceRub(c.Amount), FormatPartnerPercent(c.CommissionPercent)),
+		ReplyMarkup: models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
+			{s.translation.WithButton(partner.Language, "partner_button", models.InlineKeyboardButton{CallbackData: "partner"})},
+		}},
+	})
+	if err != nil {
+		slog.Warn("partner: commission notify", "error", err, "partner_id", utils.MaskHalfInt64(partner.ID))
+	}
+}
+
+func (s PaymentService) notifyPartnerPayout(ctx context.Context, p *database.ReferralPayout) {
+	if s.telegramBot == nil {
+		return
+	}
+	partner, err := s.customerRepository.FindById(ctx, p.PartnerCustomerID)
+	if err != nil || skipTelegramCustomerDM(partner) {
+		return
+	}
+	key := "partner_payout_paid"
+	if p.Status == database.PayoutStatusRejected {
+		key = "partner_payout_rejected"
+	}
+	text := fmt.Sprintf(s.translation.GetText(partner.Language, key), FormatBalanceRub(p.Amount))
+	if p.AdminComment != nil {
+		text += "\n\n" + html.EscapeString(*p.AdminComment)
+	}
+	if _, err := s.telegramBot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    partner.TelegramID,
+		ParseMode: models.ParseModeHTML,
+		Text:      text,
+	}); err != nil {
+		slog.Warn("partner: payout notify", "error", err, "partner_id", utils.MaskHalfInt64(partner.ID))
+	}
+}
+
+func (s PaymentService) notifyAdminPayoutRequested(ctx context.Context, partner *database.Customer, p *database.ReferralPayout) {
+	adminID := config.GetAdminTelegramId()
+	if s.telegramBot == nil || adminID == 0 {
+		return
+	}
+	lang := "ru"
+	text := fmt.Sprintf(s.translation.GetText(lang, "admin_partner_payout_requested"),
+		p.ID, FormatBalanceRub(p.Amount), s.translation.GetText(lang, "admin_partner_payout_method_"+string(p.Method)), partner.ID)
+	if _, err := s.telegramBot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    adminID,
+		ParseMode: models.ParseModeHTML,
+		Text:      text,
+		ReplyMarkup: models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
+			{s.translation.WithButton(lang, "admin_partner_payouts_button", models.InlineKeyboardButton{CallbackData: "admin_payouts"})},
+		}},
+	}); err != nil {
+		slog.Warn("partner: admin payout notify", "error", err, "payout_id", p.ID)
+	}
+}
+
+// FormatPartnerPercent — ставка партнёра без лишних нулей: «10», «7.5».
+func FormatPartnerPercent(v float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", v), "0"), ".")
+}
@@ -0,0 +1,75 @@
+package payment
+
+import (
+	"testing"
+
+	"remnawave-tg-shop-bot/internal/database"
+)
+
+func TestReferralCommissionAmount(t *testing.T) {
+	cases := []struct {
+		amount, percent, want float64
+	}{
+		{1000, 10, 100},
+		{299, 15, 44.85},
+		{199, 7.5, 14.93},
+		{1000, 0, 0},
+		{0, 10, 0},
+	}
+	for _, c := range cases {
+		if got := referralCommissionAmount(c.amount, c.percent); got != c.want {
+			t.Errorf("referralCommissionAmount(%v, %v) = %v, want %v", c.amount, c.percent, got, c.want)
+		}
+	}
+}
+
+func TestCommissionReversalTarget(t *testing.T) {
+	// Два частичных возврата по 30% и 70% в сумме снимают всю комиссию.
+	if got := commissionReversalTarget(100, 1000, 300, false); got != 30 {
+		t.Fatalf("after 30%% refund: got %v", got)
+	}
+	if got := commissionReversalTarget(100, 1000, 1000, true); got != 100 {
+		t.Fatalf("after final refund: got %v", got)
+	}
+	// Финальный возврат снимает всё даже при округлении суммы.
+	if got := commissionReversalTarget(44.85, 299, 298.99, true); got != 44.85 {
+		t.Fatalf("final refund must reverse whole commission, got %v", got)
+	}
+	if got := commissionReversalTarget(33.33, 333.3, 111.1, false); got != 11.11 {
+		t.Fatalf("partial refund: got %v", got)
+	}
+}
+
+func TestPartnerDashboardAvailable(t *testing.T) {
+	d := &PartnerDashboard{
+		MinPayout: 1000,
+		Summary: database.PartnerSummary{
+			Earned:  3000,
+			OnHold:  500,
+			PaidOut: 1000,
+		},
+	}
+	if got := d.Available(); got != 1500 {
+		t.Fatalf("available = %v, want 1500", got)
+	}
+	if !d.CanRequestPayout() {
+		t.Fatal("payout must be allowed")
+	}
+	d.Summary.PayoutPending = 1500
+	if d.CanRequestPayout() {
+		t.Fatal("payout must be blocked while a request is pending")
+	}
+	// Сторно после выплаты: доступно 0, а не минус.
+	d.Summary = database.PartnerSummary{Earned: 800, PaidOut: 1000}
+	if got := d.Available(); got != 0 {
+		t.Fatalf("available = %v, want 0", got)
+	}
+}
+
+func TestFormatPartnerPercent(t *testing.T) {
+	for in, want := range map[float64]string{10: "10", 7.5: "7.5", 12.25: "12.25", 0: "0"} {
+		if got := FormatPartnerPercent(in); got != want {
+			t.Errorf("FormatPartnerPercent(%v) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,370 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/utils"
+)
+
+var (
+	ErrPauseDisabled       = errors.New("subscription pause is disabled")
+	ErrPauseNoSubscription = errors.New("no active paid subscription to pause")
+	ErrPauseAlreadyPaused  = database.ErrSubscriptionPauseActive
+	ErrPauseLimit          = errors.New("subscription pause limit reached")
+	ErrPauseDays           = errors.New("pause length is out of range")
+	ErrPauseFamily         = errors.New("family subscriptions cannot be paused")
+	ErrPauseNotActive      = errors.New("subscription is not paused")
+	ErrPauseTooLittleLeft  = errors.New("too little subscription time left to pause")
+)
+
+const (
+	// pauseMinRemaining — меньше суток остатка замораживать бессмысленно.
+	pauseMinRemaining = 24 * time.Hour
+	pauseResumeBatch  = 100
+)
+
+// PauseStatus — состояние паузы для бота и кабинета. Reason — почему новая пауза недоступна (nil — можно).
+type PauseStatus struct {
+	Enabled    bool
+	Active     *database.SubscriptionPause
+	MinDays    int
+	MaxDays    int
+	Limit      int
+	PeriodDays int
+	Used       int
+	Reason     error
+}
+
+// CanPause — можно ли поставить подписку на паузу прямо сейчас.
+func (st *PauseStatus) CanPause() bool {
+	return st.Enabled && st.Active == nil && st.Reason == nil
+}
+
+// PauseDayOptions — варианты длительности паузы для кнопок бота в пределах min..max.
+func PauseDayOptions(minDays, maxDays int) []int {
+	var out []int
+	for _, d := range []int{7, 14, 30, 60, 90} {
+		if d > minDays && d < maxDays {
+			out = append(out, d)
+		}
+	}
+	out = append([]int{minDays}, out...)
+	if maxDays > minDays {
+		out = append(out, maxDays)
+	}
+	return out
+}
+
+// SubscriptionPause — активная пауза клиента; nil, если подписка не на паузе.
+func (s PaymentService) SubscriptionPause(ctx context.Context, customer *database.Customer) (*database.SubscriptionPause, error) {
+	if s.subscriptionPauseRepository == nil || customer == nil || customer.PausedUntil == nil {
+		return nil, nil
+	}
+	return s.subscriptionPauseRepository.FindActive(ctx, customer.ID)
+}
+
+// PauseStatus — текущая пауза, лимиты из настроек и доступность новой паузы.
+func (s PaymentService) PauseStatus(ctx context.Context, customer *database.Customer) (*PauseStatus, error) {
+	st := &PauseStatus{
+		Enabled:    config.SubscriptionPauseEnabled() && s.subscriptionPauseRepository != nil,
+		MinDays:    config.SubscriptionPauseMinDays(),
+		MaxDays:    config.SubscriptionPauseMaxDays(),
+		Limit:      config.SubscriptionPauseLimit(),
+		PeriodDays: config.SubscriptionPausePeriodDays(),
+	}
+	if s.subscriptionPauseRepository == nil {
+		st.Reason = ErrPauseDisabled
+		return st, nil
+	}
+	active, err := s.subscriptionPauseRepository.FindActive(ctx, customer.ID)
+	if err != nil {
+		return nil, err
+	}
+	st.Active = active
+	if active != nil {
+		st.Reason = ErrPauseAlreadyPaused
+		return st, nil
+	}
+	st.Used, err = s.subscriptionPauseRepository.CountSince(ctx, customer.ID, pausePeriodStart(st.PeriodDays))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.fillPauseReason(ctx, customer, st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func pausePeriodStart(periodDays int) time.Time {
+	return time.Now().UTC().AddDate(0, 0, -periodDays)
+}
+
+// fillPauseReason выставляет st.Reason — почему клиенту нельзя начать паузу (nil — можно).
+func (s PaymentService) fillPauseReason(ctx context.Context, customer *database.Customer, st *PauseStatus) error {
+	if !st.Enabled {
+		st.Reason = ErrPauseDisabled
+		return nil
+	}
+	now := time.Now()
+	if customer.ExpireAt == nil || !customer.ExpireAt.After(now) {
+		st.Reason = ErrPauseNoSubscription
+		return nil
+	}
+	paid, err := s.hasPaidDays(ctx, customer)
+	if err != nil {
+		return err
+	}
+	if !paid {
+		st.Reason = ErrPauseNoSubscription
+		return nil
+	}
+	if customer.ExpireAt.Sub(now) < pauseMinRemaining {
+		st.Reason = ErrPauseTooLittleLeft
+		return nil
+	}
+	// Срок семьи общий: пауза владельца или участника рассинхронизировала бы подписки.
+	if s.familyRepository != nil {
+		m, err := s.familyRepository.FindMembership(ctx, customer.ID)
+		if err != nil {
+			return err
+		}
+		overview, err := s.Family(ctx, customer)
+		if err != nil && !errors.Is(err, ErrFamilyNotAvailable) && !errors.Is(err, ErrFamilyDisabled) {
+			return err
+		}
+		if m != nil || (overview != nil && len(overview.Members) > 0) {
+			st.Reason = ErrPauseFamily
+			return nil
+		}
+	}
+	if st.Limit > 0 && st.Used >= st.Limit {
+		st.Reason = ErrPauseLimit
+	}
+	return nil
+}
+
+// hasPaidDays — у клиента оплаченная подписка, а не только триал (тариф, оплата или подарок).
+func (s PaymentService) hasPaidDays(ctx context.Context, customer *database.Customer) (bool, error) {
+	if customer.CurrentTariffID != nil && *customer.CurrentTariffID > 0 {
+		return true, nil
+	}
+	paid, err := s.purchaseRepository.HasPaidSubscription(ctx, customer.ID)
+	if err != nil || paid {
+		return paid, err
+	}
+	if s.giftRepository != nil {
+		return s.giftRepository.HasRedeemed(ctx, customer.ID)
+	}
+	return false, nil
+}
+
+// PauseSubscription замораживает остаток подписки на days дней: пользователь Remnawave отключается,
+// срок сдвигается на длину паузы. Возобновление — досрочно клиентом или кроном в resume_at.
+func (s PaymentService) PauseSubscription(ctx context.Context, customer *database.Customer, days int) (*database.SubscriptionPause, error) {
+	st, err := s.PauseStatus(ctx, customer)
+	if err != nil {
+		return nil, err
+	}
+	if st.Reason != nil {
+		return nil, st.Reason
+	}
+	if days < st.MinDays || days > st.MaxDays {
+		return nil, ErrPauseDays
+	}
+
+	user, err := s.remnawaveClient.FindUserForCustomer(ctx, customer.ID, customer.TelegramID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrPauseNoSubscription
+	}
+	now := time.Now().UTC()
+	remaining := user.ExpireAt.Sub(now)
+	if remaining < pauseMinRemaining {
+		return nil, ErrPauseTooLittleLeft
+	}
+	resumeAt := now.AddDate(0, 0, days)
+	newExpire := resumeAt.Add(remaining)
+
+	// Сначала строка паузы: уникальный индекс не даёт двум запросам заморозить подписку дважды.
+	pause, err := s.subscriptionPauseRepository.Create(ctx, customer.ID, resumeAt, remaining)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.remnawaveClient.PatchUser(ctx, &remnawave.UpdateUserRequest{
+		UUID:     &user.UUID,
+		Status:   "DISABLED",
+		ExpireAt: &newExpire,
+	}); err != nil {
+		if derr := s.subscriptionPauseRepository.Delete(ctx, pause.ID); derr != nil {
+			slog.Error("pause: rollback", "error", derr, "customer_id", utils.MaskHalfInt64(customer.ID))
+		}
+		return nil, err
+	}
+	updates := map[string]interface{}{"expire_at": newExpire, "paused_until": resumeAt}
+	shiftExtraHwidExpiry(updates, customer, newExpire.Sub(user.ExpireAt))
+	if err := s.customerRepository.UpdateFields(ctx, customer.ID, updates); err != nil {
+		// Без paused_until оплата не снимет паузу, а уведомления об окончании не пропустят её: откатываем панель и паузу.
+		s.rollbackPausedUser(ctx, customer, pause, user)
+		return nil, err
+	}
+	customer.ExpireAt, customer.PausedUntil = &newExpire, &resumeAt
+	slog.Info("subscription paused", "customer_id", utils.MaskHalfInt64(customer.ID), "days", days)
+	return pause, nil
+}
+
+// rollbackPausedUser возвращает пользователю Remnawave статус и срок до паузы и удаляет строку паузы.
+// Если панель не ответила, строка остаётся: пауза снимется в срок или досрочно, как обычная.
+func (s PaymentService) rollbackPausedUser(ctx context.Context, customer *database.Customer, pause *database.SubscriptionPause, user *remnawave.User) {
+	status := user.Status
+	if status == "" {
+		status = "ACTIVE"
+	}
+	expire := user.ExpireAt
+	if _, err := s.remnawaveClient.PatchUser(ctx, &remnawave.UpdateUserRequest{
+		UUID:     &user.UUID,
+		Status:   status,
+		ExpireAt: &expire,
+	}); err != nil {
+		slog.Error("pause: rollback panel user", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID), "pause_id", pause.ID)
+		return
+	}
+	if err := s.subscriptionPauseRepository.Delete(ctx, pause.ID); err != nil {
+		slog.Error("pause: rollback", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+	}
+}
+
+// ResumeSubscription досрочно снимает паузу по запросу клиента.
+func (s PaymentService) ResumeSubscription(ctx context.Context, customer *database.Customer) error {
+	if s.subscriptionPauseRepository == nil {
+		return ErrPauseNotActive
+	}
+	pause, err := s.subscriptionPauseRepository.FindActive(ctx, customer.ID)
+	if err != nil {
+		return err
+	}
+	if pause == nil {
+		return ErrPauseNotActive
+	}
+	return s.resumePause(ctx, customer, pause)
+}
+
+// resumePause включает пользователя Remnawave. Неиспользованные дни паузы вычитаются из срока в панели,
+// поэтому дни, начисленные во время паузы (бонусы, админ), сохраняются.
+func (s PaymentService) resumePause(ctx context.Context, customer *database.Customer, pause *database.SubscriptionPause) error {
+	now := time.Now().UTC()
+	user, err := s.remnawaveClient.FindUserForCustomer(ctx, customer.ID, customer.TelegramID)
+	if err != nil {
+		return err
+	}
+	// Сначала закрываем паузу: крон и клиент не должны вычесть дни паузы дважды.
+	resumed, err := s.subscriptionPauseRepository.MarkResumed(ctx, pause.ID, now)
+	if err != nil {
+		return err
+	}
+	if !resumed {
+		return nil
+	}
+	newExpire := now.Add(pause.Remaining())
+	var shift time.Duration
+	if user != nil {
+		newExpire = user.ExpireAt.Add(-pause.ResumeAt.Sub(now))
+		shift = newExpire.Sub(user.ExpireAt)
+		if _, err := s.remnawaveClient.PatchUser(ctx, &remnawave.UpdateUserRequest{
+			UUID:     &user.UUID,
+			Status:   "ACTIVE",
+			ExpireAt: &newExpire,
+		}); err != nil {
+			if rerr := s.subscriptionPauseRepository.Reopen(ctx, pause.ID); rerr != nil {
+				slog.Error("pause: reopen after panel error", "error", rerr, "customer_id", utils.MaskHalfInt64(customer.ID))
+			}
+			return err
+		}
+	}
+	updates := map[string]interface{}{"expire_at": newExpire, "paused_until": nil}
+	shiftExtraHwidExpiry(updates, customer, shift)
+	if err := s.customerRepository.UpdateFields(ctx, customer.ID, updates); err != nil {
+		return err
+	}
+	customer.ExpireAt, customer.PausedUntil = &newExpire, nil
+	slog.Info("subscription resumed", "customer_id", utils.MaskHalfInt64(customer.ID), "pause_id", pause.ID)
+	return nil
+}
+
+// shiftExtraHwidExpiry сдвигает срок доп. устройств вместе со сроком подписки.
+func shiftExtraHwidExpiry(updates map[string]interface{}, customer *database.Customer, shift time.Duration) {
+	if customer.ExtraHwidExpiresAt == nil || shift == 0 {
+		return
+	}
+	updates["extra_hwid_expires_at"] = customer.ExtraHwidExpiresAt.Add(shift)
+}
+
+// resumeIfPaused снимает паузу перед продлением: оплата во время паузы сразу возобновляет подписку,
+// иначе продление посчиталось бы от срока «после паузы».
+func (s PaymentService) resumeIfPaused(ctx context.Context, customer *database.Customer) error {
+	pause, err := s.SubscriptionPause(ctx, customer)
+	if err != nil || pause == nil {
+		return err
+	}
+	if err := s.resumePause(ctx, customer, pause); err != nil {
+		return fmt.Errorf("resume paused subscription: %w", err)
+	}
+	return nil
+}
+
+// ResumeDuePauses — крон: снимает паузы, у которых наступил срок, и уведомляет клиентов.
+func (s PaymentService) ResumeDuePauses(ctx context.Context) {
+	if s.subscriptionPauseRepository == nil {
+		return
+	}
+	due, err := s.subscriptionPauseRepository.FindDue(ctx, time.Now().UTC(), pauseResumeBatch)
+	if err != nil {
+		slog.Error("pause: find due", "error", err)
+		return
+	}
+	for i := range due {
+		pause := &due[i]
+		customer, err := s.customerRepository.FindById(ctx, pause.CustomerID)
+		if err != nil {
+			slog.Error("pause: load customer", "error", err, "customer_id", utils.MaskHalfInt64(pause.CustomerID))
+			continue
+		}
+		if customer == nil {
+			continue
+		}
+		if err := s.resumePause(ctx, customer, pause); err != nil {
+			slog.Error("pause: resume", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+			continue
+		}
+		s.notifyPauseResumed(ctx, customer)
+	}
+}
+
+func (s PaymentService) notifyPauseResumed(ctx context.Context, c *database.Customer) {
+	if s.telegramBot == nil || skipTelegramCustomerDM(c) || c.ExpireAt == nil {
+		return
+	}
+	lang := c.Language
+	_, err := s.telegramBot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    c.TelegramID,
+		Text:      fmt.Sprintf(s.translation.GetText(lang, "pause_resumed_notify"), c.ExpireAt.Format("02.01.2006")),
+		ParseMode: models.ParseModeHTML,
+		ReplyMarkup: models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
+			{s.translation.WithButton(lang, "connect_button", models.InlineKeyboardButton{CallbackData: "connect"})},
+		}},
+	})
+	if err != nil {
+		slog.Error("pause: notify resumed", "error", err, "customer_id", utils.MaskHalfInt64(c.ID))
+	}
+}
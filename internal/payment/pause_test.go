@@ -0,0 +1,23 @@
+package payment
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPauseDayOptions(t *testing.T) {
+	tests := []struct {
+		min, max int
+		want     []int
+	}{
+		{7, 30, []int{7, 14, 30}},
+		{7, 7, []int{7}},
+		{1, 90, []int{1, 7, 14, 30, 60, 90}},
+		{10, 45, []int{10, 14, 30, 45}},
+	}
+	for _, tt := range tests {
+		if got := PauseDayOptions(tt.min, tt.max); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("PauseDayOptions(%d, %d) = %v, want %v", tt.min, tt.max, got, tt.want)
+		}
+	}
+}
@@ -5,13 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"math"
 	cabcfg "remnawave-tg-shop-bot/internal/cabinet/config"
 	"remnawave-tg-shop-bot/internal/cache"
 	"remnawave-tg-shop-bot/internal/config"
 	"remnawave-tg-shop-bot/internal/cryptopay"
 	"remnawave-tg-shop-bot/internal/database"
-	"remnawave-tg-shop-bot/internal/loyalty"
 	"remnawave-tg-shop-bot/internal/moynalog"
 	"remnawave-tg-shop-bot/internal/platega"
 	"remnawave-tg-shop-bot/internal/promo"
@@ -118,28 +116,55 @@ func (s PaymentService) ctxWithTelegramUsernameIfMissing(ctx context.Context, cu
 }
 
 type PaymentService struct {
-	purchaseRepository    *database.PurchaseRepository
-	tariffRepository      *database.TariffRepository
-	remnawaveClient       *remnawave.Client
-	customerRepository    *database.CustomerRepository
-	telegramBot           *bot.Bot
-	translation           *translation.Manager
-	cryptoPayClient       *cryptopay.Client
-	yookasaClient         *yookasa.Client
-	plategaClient         *platega.Client
-	referralRepository    *database.ReferralRepository
-	cache                 *cache.Cache
-	moynalogClient        *moynalog.Client
-	promoService          *promo.Service
-	loyaltyTierRepository *database.LoyaltyTierRepository
+	purchaseRepository          *database.PurchaseRepository
+	tariffRepository            *database.TariffRepository
+	remnawaveClient             *remnawave.Client
+	customerRepository          *database.CustomerRepository
+	telegramBot                 *bot.Bot
+	translation                 *translation.Manager
+	cryptoPayClient             *cryptopay.Client
+	yookasaClient               *yookasa.Client
+	plategaClient               *platega.Client
+	referralRepository          *database.ReferralRepository
+	cache                       *cache.Cache
+	moynalogClient              *moynalog.Client
+	promoService                *promo.Service
+	loyaltyTierRepository       *database.LoyaltyTierRepository
+	refundRepository            *database.PurchaseRefundRepository
+	autoRenewRepository         *database.AutoRenewRepository
+	giftRepository              *database.GiftRepository
+	balanceRepository           *database.BalanceRepository
+	partnerRepository           *database.PartnerRepository
+	receiptRepository           *database.MoynalogReceiptRepository
+	outboxRepository            *database.PurchaseOutboxRepository
+	starsSubscriptionRepository *database.StarsSubscriptionRepository
+	familyRepository            *database.FamilyRepository
+	subscriptionPauseRepository *database.SubscriptionPauseRepository
+	trafficPackRepository       *database.CustomerTrafficPackRepository
+	providers                   *ProviderRegistry
 }
 
-// PromoMeta attaches an activated percent discount to a new purchase row (optional).
+// PromoMeta attaches an activated discount to a new purchase row (optional).
+// DiscountPercentApplied is nil for a fixed-amount discount.
 type PromoMeta struct {
 	PromoCodeID            *int64
 	DiscountPercentApplied *int
 }
 
+// PromoMetaFor — PromoMeta для purchase, если pending-скидка применена к заказу; иначе nil.
+func PromoMetaFor(d *promo.CheckoutDiscount) *PromoMeta {
+	if !d.Applied() || d.PromoCodeID == 0 {
+		return nil
+	}
+	id := d.PromoCodeID
+	meta := &PromoMeta{PromoCodeID: &id}
+	if d.Percent > 0 {
+		pct := d.Percent
+		meta.DiscountPercentApplied = &pct
+	}
+	return meta
+}
+
 func NewPaymentService(
 	translation *translation.Manager,
 	purchaseRepository *database.PurchaseRepository,
@@ -155,23 +180,48 @@ func NewPaymentService(
 	moynalogClient *moynalog.Client,
 	promoService *promo.Service,
 	loyaltyTierRepository *database.LoyaltyTierRepository,
+	refundRepository *database.PurchaseRefundRepository,
+	autoRenewRepository *database.AutoRenewRepository,
+	giftRepository *database.GiftRepository,
+	balanceRepository *database.BalanceRepository,
+	partnerRepository *database.PartnerRepository,
+	receiptRepository *database.MoynalogReceiptRepository,
+	outboxRepository *database.PurchaseOutboxRepository,
+	starsSubscriptionRepository *database.StarsSubscriptionRepository,
+	familyRepository *database.FamilyRepository,
+	subscriptionPauseRepository *database.SubscriptionPauseRepository,
+	trafficPackRepository *database.CustomerTrafficPackRepository,
 ) *PaymentService {
-	return &PaymentService{
-		purchaseRepository:    purchaseRepository,
-		tariffRepository:      tariffRepository,
-		remnawaveClient:       remnawaveClient,
-		customerRepository:    customerRepository,
-		telegramBot:           telegramBot,
-		translation:           translation,
-		cryptoPayClient:       cryptoPayClient,
-		yookasaClient:         yookasaClient,
-		plategaClient:         plategaClient,
-		referralRepository:    referralRepository,
-		cache:                 cache,
-		moynalogClient:        moynalogClient,
-		promoService:          promoService,
-		loyaltyTierRepository: loyaltyTierRepository,
-	}
+	s := &PaymentService{
+		purchaseRepository:          purchaseRepository,
+		tariffRepository:            tariffRepository,
+		remnawaveClient:             remnawaveClient,
+		customerRepository:          customerRepository,
+		telegramBot:                 telegramBot,
+		translation:                 translation,
+		cryptoPayClient:             cryptoPayClient,
+		yookasaClient:               yookasaClient,
+		plategaClient:               plategaClient,
+		referralRepository:          referralRepository,
+		cache:                       cache,
+		moynalogClient:              moynalogClient,
+		promoService:                promoService,
+		loyaltyTierRepository:       loyaltyTierRepository,
+		refundRepository:            refundRepository,
+		autoRenewRepository:         autoRenewRepository,
+		giftRepository:              giftRepository,
+		balanceRepository:           balanceRepository,
+		partnerRepository:           partnerRepository,
+		receiptRepository:           receiptRepository,
+		outboxRepository:            outboxRepository,
+		starsSubscriptionRepository: starsSubscriptionRepository,
+		familyRepository:            familyRepository,
+		subscriptionPauseRepository: subscriptionPauseRepository,
+		trafficPackRepository:       trafficPackRepository,
+		providers:                   NewProviderRegistry(),
+	}
+	registerBuiltinProviders(s.providers, s)
+	return s
 }
 
 func (s PaymentService) ProcessPurchaseById(ctx context.Context, purchaseId int64) error {
@@ -182,11 +232,20 @@ func (s PaymentService) ProcessPurchaseById(ctx context.Context, purchaseId int6
 	if purchase == nil {
 		return fmt.Errorf("purchase with crypto invoice id %s not found", utils.MaskHalfInt64(purchaseId))
 	}
-	if purchase.Status == database.PurchaseStatusPaid {
+	if purchase.Status == database.PurchaseStatusPaid || purchase.Status.IsRefunded() {
 		// Защита от повторной обработки paid-покупки (поллеры, повторы webhook'ов).
 		return nil
 	}
 
+	// charge id Stars нужен для refundStarPayment при возврате.
+	if m, ok := StarsNotifyMetaFromCtx(ctx); ok && strings.TrimSpace(m.TelegramPaymentChargeID) != "" {
+		if err := s.purchaseRepository.UpdateFields(ctx, purchase.ID, map[string]interface{}{
+			"telegram_charge_id": m.TelegramPaymentChargeID,
+		}); err != nil {
+			slog.Error("save telegram charge id", "error", err, "purchase_id", utils.MaskHalfInt64(purchase.ID))
+		}
+	}
+
 	customer, err := s.customerRepository.FindById(ctx, purchase.CustomerID)
 	if err != nil {
 		return err
@@ -197,13 +256,6 @@ func (s PaymentService) ProcessPurchaseById(ctx context.Context, purchaseId int6
 
 	ctx = s.ctxWithTelegramUsernameIfMissing(ctx, customer)
 
-	if !config.HwidExtraDevicesEnabled() && purchase.ExtraHwid > 0 {
-		if purchase.Month <= 0 {
-			return fmt.Errorf("extra hwid purchase is disabled")
-		}
-		purchase.ExtraHwid = 0
-	}
-
 	if messageId, b := s.cache.Get(purchase.ID); b && !skipTelegramCustomerDM(customer) {
 		_, err = s.telegramBot.DeleteMessage(ctx, &bot.DeleteMessageParams{
 			ChatID:    customer.TelegramID,
@@ -214,20 +266,69 @@ func (s PaymentService) ProcessPurchaseById(ctx context.Context, purchaseId int6
 	}
 	}
 
-	if purchase.Month <= 0 && purchase.ExtraHwid > 0 {
-		return s.processDevicePurchase(ctx, purchase, customer)
+	plan, err := s.planPurchaseOutbox(ctx, purchase, customer)
+	if err != nil {
+		return err
+	}
+	tasks, err := purchaseOutboxTasks(purchase, plan)
+	if err != nil {
+		return err
+	}
+	paid, err := s.outboxRepository.MarkPaid(ctx, purchase.ID, time.Now().UTC(), tasks)
+	if err != nil {
+		return err
+	}
+	if !paid {
+		// Покупку провёл параллельный вебхук / поллер.
+		return nil
 	}
+	return s.runPurchaseOutbox(ctx, purchase.ID)
+}
 
-	daysToAdd := purchase.Month * config.DaysInMonth()
+// planPurchaseOutbox проверяет, что покупку можно провести, и фиксирует всё, что зависит от состояния до оплаты:
+// клиента, значения ctx, срок продления. Считается до перехода в paid (paidTermStartsNow смотрит на число оплат).
+func (s PaymentService) planPurchaseOutbox(ctx context.Context, purchase *database.Purchase, customer *database.Customer) (*outboxPayload, error) {
+	snapshot := captureOutboxCtx(ctx)
+	customerBefore := *customer
+	plan := &outboxPayload{Ctx: &snapshot, Customer: &customerBefore}
+
+	if !config.HwidExtraDevicesEnabled() && purchase.ExtraHwid > 0 {
+		if purchase.Month <= 0 {
+			return nil, fmt.Errorf("extra hwid purchase is disabled")
+		}
+		purchase.ExtraHwid = 0
+		plan.DropExtraHwid = true
+	}
+
+	switch {
+	case purchase.PurchaseKind == database.PurchaseKindGift:
+		if s.giftRepository == nil {
+			return nil, ErrGiftsDisabled
+		}
+		return plan, nil
+	case purchase.PurchaseKind == database.PurchaseKindBalanceTopUp:
+		if s.balanceRepository == nil {
+			return nil, ErrBalanceDisabled
+		}
+		return plan, nil
+	case purchase.PurchaseKind == database.PurchaseKindTrafficPack:
+		if s.trafficPackRepository == nil {
+			return nil, ErrTrafficPacksDisabled
+		}
+		return plan, nil
+	case isDevicePurchase(purchase):
+		return plan, nil
+	}
+
+	plan.Days = purchase.Month * config.DaysInMonth()
 	if config.SalesMode() == "tariffs" && purchase.TariffID != nil && *purchase.TariffID > 0 && s.tariffRepository != nil {
 		tariff, err := s.tariffRepository.GetByID(ctx, *purchase.TariffID)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if tariff == nil {
-			return fmt.Errorf("tariff %d not found", *purchase.TariffID)
+			return nil, fmt.Errorf("tariff %d not found", *purchase.TariffID)
 		}
-		profile := BuildRemnawaveTariffProfile(tariff)
 
 		// Апгрейд и досрочный даунгрейд: срок от момента оплаты. Остаток старого тарифа уже учтён в bonus
 		// (пересчёт «дневной» стоимости); нельзя прибавлять дни к текущему expire_at — иначе остаток считается дважды.
@@ -235,10 +336,10 @@ func (s PaymentService) ProcessPurchaseById(ctx context.Context, purchaseId int6
 			now := time.Now().UTC()
 			tpNew, err := s.tariffRepository.GetPrice(ctx, *purchase.TariffID, purchase.Month)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			if tpNew == nil {
-				return fmt.Errorf("no tariff_price for tariff %d months %d", *purchase.TariffID, purchase.Month)
+				return nil, fmt.Errorf("no tariff_price for tariff %d months %d", *purchase.TariffID, purchase.Month)
 			}
 			dim := config.DaysInMonth()
 			if dim <= 0 {
@@ -251,207 +352,81 @@ func (s PaymentService) ProcessPurchaseById(ctx context.Context, purchaseId int6
 					bonus = ComputeUpgradeBonusDays(customer, tpOld, tpNew, purchase.Month, now)
 				}
 			}
-			daysSwitch := purchase.Month*dim + bonus
-			rwCtx := s.withRemnawavePanelUsername(ctx, customer)
-			user, err := s.remnawaveClient.CreateOrUpdateUserWithTariffProfileFromNow(rwCtx, customer.ID, customer.TelegramID, daysSwitch, profile)
-			if err != nil {
-				return err
-			}
-			if err := s.finalizePurchase(ctx, purchase, customer, user); err != nil {
-				return err
-			}
-			if err := s.applyExtraAfterSubscription(ctx, customer, user, purchase); err != nil {
-				return err
-			}
-			return s.resetTrafficAfterSubscriptionPayment(ctx, user)
-		}
-
-		// Первый платёж «поверх» триала без current_tariff_id: при trialAddsToPaid=false
-		// срок от момента оплаты (не стакаем к expire_at). Если тариф уже привязан —
-		// это продление/оплата с тарифом, даже при paidCount==0 (промо/админ/расхождение
-		// счётчиков) продлеваем от expire в Remnawave, как в боте.
-		useFromNow := !config.TrialAddsToPaid() && customer.ExpireAt != nil && customer.ExpireAt.After(time.Now())
-		if useFromNow {
-			paidCount, err := s.purchaseRepository.CountPaidSubscriptionsByCustomer(ctx, customer.ID)
-			if err != nil {
-				return err
-			}
-			noPaidTariffYet := customer.CurrentTariffID == nil || *customer.CurrentTariffID == 0
-			if paidCount == 0 && noPaidTariffYet {
-				rwCtx := s.withRemnawavePanelUsername(ctx, customer)
-				user, err := s.remnawaveClient.CreateOrUpdateUserWithTariffProfileFromNow(rwCtx, customer.ID, customer.TelegramID, daysToAdd, profile)
-				if err != nil {
-					return err
-				}
-				if err := s.finalizePurchase(ctx, purchase, customer, user); err != nil {
-					return err
-				}
-				if err := s.applyExtraAfterSubscription(ctx, customer, user, purchase); err != nil {
-					return err
-				}
-				return s.resetTrafficAfterSubscriptionPayment(ctx, user)
-			}
-		}
-		rwCtx := s.withRemnawavePanelUsername(ctx, customer)
-		user, err := s.remnawaveClient.CreateOrUpdateUserWithTariffProfile(rwCtx, customer.ID, customer.TelegramID, daysToAdd, profile)
-		if err != nil {
-			return err
-		}
-		if err := s.finalizePurchase(ctx, purchase, customer, user); err != nil {
-			return err
-		}
-		if err := s.applyExtraAfterSubscription(ctx, customer, user, purchase); err != nil {
-			return err
+			plan.Days = purchase.Month*dim + bonus
+			plan.FromNow = true
+			return plan, nil
 		}
-		return s.resetTrafficAfterSubscriptionPayment(ctx, user)
 	}
 
-	useFromNow := !config.TrialAddsToPaid() && customer.ExpireAt != nil && customer.ExpireAt.After(time.Now())
-	if useFromNow {
-		paidCount, err := s.purchaseRepository.CountPaidSubscriptionsByCustomer(ctx, customer.ID)
-		if err != nil {
-			return err
-		}
-		noPaidTariffYet := customer.CurrentTariffID == nil || *customer.CurrentTariffID == 0
-		if paidCount == 0 && noPaidTariffYet {
-			rwCtx := s.withRemnawavePanelUsername(ctx, customer)
-			user, err := s.remnawaveClient.CreateOrUpdateUserFromNow(rwCtx, customer.ID, customer.TelegramID, config.TrafficLimit(), daysToAdd, false)
-			if err != nil {
-				return err
-			}
-			if err := s.finalizePurchase(ctx, purchase, customer, user); err != nil {
-				return err
-			}
-			if err := s.applyExtraAfterSubscription(ctx, customer, user, purchase); err != nil {
-				return err
-			}
-			return s.resetTrafficAfterSubscriptionPayment(ctx, user)
-		}
-	}
-	rwCtx := s.withRemnawavePanelUsername(ctx, customer)
-	user, err := s.remnawaveClient.CreateOrUpdateUser(rwCtx, customer.ID, customer.TelegramID, config.TrafficLimit(), daysToAdd, false)
+	fromNow, err := s.paidTermStartsNow(ctx, customer)
 	if err != nil {
-		return err
-	}
-	if err := s.finalizePurchase(ctx, purchase, customer, user); err != nil {
-		return err
+		return nil, err
 	}
-	if err := s.applyExtraAfterSubscription(ctx, customer, user, purchase); err != nil {
-		return err
-	}
-	return s.resetTrafficAfterSubscriptionPayment(ctx, user)
+	plan.FromNow = fromNow
+	return plan, nil
 }
 
-func (s PaymentService) resetTrafficAfterSubscriptionPayment(ctx context.Context, user *remnawave.User) error {
-	if user == nil || user.UUID == uuid.Nil {
-		return nil
-	}
-	if err := s.remnawaveClient.ResetUserTraffic(ctx, user.UUID); err != nil {
-		slog.Error("failed to reset user traffic after subscription payment", "error", err)
-		return err
-	}
-	return nil
+// isDevicePurchase — докупка устройств без продления подписки.
+func isDevicePurchase(p *database.Purchase) bool {
+	return p.Month <= 0 && p.ExtraHwid > 0
 }
 
-func (s PaymentService) processDevicePurchase(ctx context.Context, purchase *database.Purchase, customer *database.Customer) error {
-	err := s.purchaseRepository.MarkAsPaid(ctx, purchase.ID)
-	if err != nil {
-		return err
+// extendPaidSubscription продлевает (или создаёт) пользователя Remnawave на days дней после оплаты;
+// profile != nil — с профилем тарифа (режим tariffs). Используется и оплатой, и активацией подарка.
+func (s PaymentService) extendPaidSubscription(ctx context.Context, customer *database.Customer, days int, profile *remnawave.TariffPaidProfile) (*remnawave.User, error) {
+	if err := s.resumeIfPaused(ctx, customer); err != nil {
+		return nil, err
 	}
-	paidNow := time.Now().UTC()
-	purchase.Status = database.PurchaseStatusPaid
-	purchase.PaidAt = &paidNow
-	expireBefore := customer.ExpireAt
-
-	userInfo, err := s.remnawaveClient.GetUserTrafficInfo(ctx, customer.TelegramID)
+	fromNow, err := s.paidTermStartsNow(ctx, customer)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	currentExtra := 0
-	if customer.ExtraHwid > 0 && customer.ExtraHwidExpiresAt != nil && customer.ExtraHwidExpiresAt.After(time.Now()) {
-		currentExtra = customer.ExtraHwid
-	} else if customer.ExtraHwid > 0 && customer.ExtraHwidExpiresAt != nil && customer.ExtraHwidExpiresAt.Before(time.Now()) {
-		newLimit := config.GetHwidFallbackDeviceLimit()
-		if newLimit < 1 {
-			newLimit = 1
-		}
-		if _, err := s.remnawaveClient.UpdateUserDeviceLimit(ctx, customer.TelegramID, newLimit); err != nil {
-			return err
-		}
-		if err := s.customerRepository.UpdateFields(ctx, customer.ID, map[string]interface{}{
-			"extra_hwid":            0,
-			"extra_hwid_expires_at": nil,
-		}); err != nil {
-			return err
-		}
-		currentExtra = 0
+	rwCtx := s.withRemnawavePanelUsername(ctx, customer)
+	switch {
+	case profile != nil && fromNow:
+		return s.remnawaveClient.CreateOrUpdateUserWithTariffProfileFromNow(rwCtx, customer.ID, customer.TelegramID, days, *profile)
+	case profile != nil:
+		return s.remnawaveClient.CreateOrUpdateUserWithTariffProfile(rwCtx, customer.ID, customer.TelegramID, days, *profile)
+	case fromNow:
+		return s.remnawaveClient.CreateOrUpdateUserFromNow(rwCtx, customer.ID, customer.TelegramID, config.TrafficLimit(), days, false)
+	default:
+		return s.remnawaveClient.CreateOrUpdateUser(rwCtx, customer.ID, customer.TelegramID, config.TrafficLimit(), days, false)
 	}
+}
 
-	currentLimit := resolveDeviceLimit(userInfo)
-	newLimit := currentLimit + purchase.ExtraHwid
-	maxLimit := config.HwidMaxDevices()
-	if maxLimit > 0 && newLimit > maxLimit {
-		newLimit = maxLimit
+// paidTermStartsNow — первый платёж «поверх» триала без current_tariff_id: при trialAddsToPaid=false
+// срок от момента оплаты (не стакаем к expire_at). Если тариф уже привязан — это продление/оплата с тарифом,
+// даже при paidCount==0 (промо/админ/расхождение счётчиков) продлеваем от expire в Remnawave, как в боте.
+func (s PaymentService) paidTermStartsNow(ctx context.Context, customer *database.Customer) (bool, error) {
+	if config.TrialAddsToPaid() || customer.ExpireAt == nil || !customer.ExpireAt.After(time.Now()) {
+		return false, nil
 	}
-
-	updatedUser, err := s.remnawaveClient.UpdateUserDeviceLimit(ctx, customer.TelegramID, newLimit)
+	paidCount, err := s.purchaseRepository.CountPaidSubscriptionsByCustomer(ctx, customer.ID)
 	if err != nil {
-		return err
-	}
-
-	newExtra := currentExtra + purchase.ExtraHwid
-	if customer.ExpireAt == nil {
-		return fmt.Errorf("subscription expire_at is not set")
+		return false, err
 	}
-	if err := s.customerRepository.UpdateFields(ctx, customer.ID, map[string]interface{}{
-		"extra_hwid":            newExtra,
-		"extra_hwid_expires_at": customer.ExpireAt,
-	}); err != nil {
-		return err
-	}
-
-	if s.moynalogClient != nil && invoiceUsesMoynalogReceipt(purchase) {
-		description := buildRubReceiptDescription(0, purchase.ExtraHwid, nil, purchase.InvoiceType)
-		slog.Info("Sending receipt to moynalog", "purchase_id", utils.MaskHalfInt64(purchase.ID), "amount", purchase.Amount, "description", description)
-		if err := s.moynalogClient.CreateIncome(ctx, purchase.Amount, description); err != nil {
-			slog.Error("Failed to send receipt to moynalog", "error", err, "purchase_id", utils.MaskHalfInt64(purchase.ID))
-			notifyAdminMoynalogFailure(ctx, s.telegramBot, config.GetAdminTelegramId(), purchase, err, description)
-		}
-	}
-
-	if updatedUser != nil {
-		customerFilesToUpdate := map[string]interface{}{
-			"subscription_link": updatedUser.SubscriptionUrl,
-			"expire_at":         updatedUser.ExpireAt,
+	noPaidTariffYet := customer.CurrentTariffID == nil || *customer.CurrentTariffID == 0
+	if paidCount == 0 && noPaidTariffYet && s.giftRepository != nil {
+		// Активированный подарок — тоже оплаченные дни, их нельзя перезаписать как триал.
+		gifted, err := s.giftRepository.HasRedeemed(ctx, customer.ID)
+		if err != nil {
+			return false, err
 		}
-		if err := s.customerRepository.UpdateFields(ctx, customer.ID, customerFilesToUpdate); err != nil {
-			return err
+		if gifted {
+			return false, nil
 		}
 	}
+	return paidCount == 0 && noPaidTariffYet, nil
+}
 
-	if !skipTelegramCustomerDM(customer) {
-		successText := fmt.Sprintf(s.translation.GetText(customer.Language, "hwid_change_success_paid"), currentLimit, newLimit, int(math.Ceil(purchase.Amount)))
-		_, err = s.telegramBot.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID: customer.TelegramID,
-			Text:   successText,
-			ReplyMarkup: models.InlineKeyboardMarkup{
-				InlineKeyboard: s.createConnectKeyboard(customer),
-			},
-		})
-		if err != nil {
-			return err
-		}
+func (s PaymentService) resetTrafficAfterSubscriptionPayment(ctx context.Context, user *remnawave.User) error {
+	if user == nil || user.UUID == uuid.Nil {
+		return nil
 	}
-	s.clearPromoDiscountIfUsed(ctx, purchase, customer)
-	s.applyLoyaltyXPAfterPayment(ctx, purchase, customer)
-
-	var expireAfter *time.Time
-	if updatedUser != nil {
-		expireAfter = ptrTimeIfValid(updatedUser.ExpireAt)
+	if err := s.remnawaveClient.ResetUserTraffic(ctx, user.UUID); err != nil {
+		slog.Error("failed to reset user traffic after subscription payment", "error", err)
+		return err
 	}
-	s.tryNotifyPurchasePaid(ctx, purchase, customer, expireBefore, expireAfter)
-
 	return nil
 }
 
@@ -467,8 +442,12 @@ func (s PaymentService) applyExtraAfterSubscription(ctx context.Context, custome
 	if err != nil {
 		return err
 	}
+	return s.applyExtraWithDeviceLimit(ctx, customer, user, purchase, resolveDeviceLimit(userInfo))
+}
 
-	currentLimit := resolveDeviceLimit(userInfo)
+// applyExtraWithDeviceLimit — applyExtraAfterSubscription с уже прочитанным лимитом устройств в панели после продления.
+// Outbox сохраняет лимит до первой попытки: повтор шага не прибавит доп. устройства второй раз.
+func (s PaymentService) applyExtraWithDeviceLimit(ctx context.Context, customer *database.Customer, user *remnawave.User, purchase *database.Purchase, currentLimit int) error {
 	storedExtra := 0
 	if customer.ExtraHwid > 0 && customer.ExtraHwidExpiresAt != nil && customer.ExtraHwidExpiresAt.After(time.Now()) {
 		storedExtra = customer.ExtraHwid
@@ -637,95 +616,33 @@ func resolveDeviceLimit(userInfo *remnawave.User) int {
 	return fallback
 }
 
-func (s PaymentService) finalizePurchase(ctx context.Context, purchase *database.Purchase, customer *database.Customer, user *remnawave.User) error {
-	err := s.purchaseRepository.MarkAsPaid(ctx, purchase.ID)
-	if err != nil {
-		return err
+// sendMoynalogReceipt ставит доход в очередь «Мой налог» для способов из MOYNALOG_RECEIPT_FOR и сразу пробует отправить.
+// Ошибка отправки не прерывает обработку покупки: чек повторяется из очереди (см. ProcessMoynalogReceipts).
+func (s PaymentService) sendMoynalogReceipt(ctx context.Context, purchase *database.Purchase) error {
+	if s.moynalogClient == nil {
+		slog.Debug("Moynalog client not available, skipping receipt", "purchase_id", utils.MaskHalfInt64(purchase.ID))
+		return nil
 	}
-	paidNow := time.Now().UTC()
-	purchase.Status = database.PurchaseStatusPaid
-	purchase.PaidAt = &paidNow
-	expireBefore := customer.ExpireAt
-
-	// Отправка дохода в «Мой налог» для способов из MOYNALOG_RECEIPT_FOR (сразу после подтверждения оплаты).
-	if s.moynalogClient != nil && invoiceUsesMoynalogReceipt(purchase) {
-		slog.Debug("Attempting to send moynalog receipt", "invoice_type", purchase.InvoiceType, "purchase_id", utils.MaskHalfInt64(purchase.ID))
-
-		rcExtras := &TariffPurchaseExtras{Kind: purchase.PurchaseKind, IsEarlyDowngrade: purchase.IsEarlyDowngrade}
-		description := buildRubReceiptDescription(purchase.Month, purchase.ExtraHwid, rcExtras, purchase.InvoiceType)
-
-		slog.Info("Sending receipt to moynalog", "purchase_id", utils.MaskHalfInt64(purchase.ID), "amount", purchase.Amount, "description", description)
-
-		if err := s.moynalogClient.CreateIncome(ctx, purchase.Amount, description); err != nil {
-			slog.Error("Failed to send receipt to moynalog", "error", err, "purchase_id", utils.MaskHalfInt64(purchase.ID))
-			notifyAdminMoynalogFailure(ctx, s.telegramBot, config.GetAdminTelegramId(), purchase, err, description)
-			// Не прерываем обработку покупки при ошибке отправки чека
-		} else {
-			slog.Info("Receipt sent to moynalog successfully", "purchase_id", utils.MaskHalfInt64(purchase.ID))
-		}
-	} else {
-		if s.moynalogClient == nil {
-			slog.Debug("Moynalog client not available, skipping receipt", "purchase_id", utils.MaskHalfInt64(purchase.ID))
-		} else if !invoiceUsesMoynalogReceipt(purchase) {
-			slog.Debug("Invoice type skips moynalog receipt (config or currency)", "invoice_type", purchase.InvoiceType, "purchase_id", utils.MaskHalfInt64(purchase.ID))
-		}
+	if !invoiceUsesMoynalogReceipt(purchase) {
+		slog.Debug("Invoice type skips moynalog receipt (config or currency)", "invoice_type", purchase.InvoiceType, "purchase_id", utils.MaskHalfInt64(purchase.ID))
+		return nil
 	}
+	return s.enqueueMoynalogReceipt(ctx, purchase, moynalogReceiptDescription(purchase))
+}
 
+// saveCustomerSubscription записывает в customer срок и ссылку из Remnawave; для тарифной покупки — текущий тариф и период.
+func (s PaymentService) saveCustomerSubscription(ctx context.Context, customer *database.Customer, user *remnawave.User, tariffID *int64, months int) error {
 	customerFilesToUpdate := map[string]interface{}{
 		"subscription_link": user.SubscriptionUrl,
 		"expire_at":         user.ExpireAt,
 	}
-	if purchase.TariffID != nil && *purchase.TariffID > 0 {
-		customerFilesToUpdate["current_tariff_id"] = *purchase.TariffID
+	if tariffID != nil && *tariffID > 0 {
+		customerFilesToUpdate["current_tariff_id"] = *tariffID
 		now := time.Now().UTC()
 		customerFilesToUpdate["subscription_period_start"] = now
-		customerFilesToUpdate["subscription_period_months"] = purchase.Month
-	}
-
-	err = s.customerRepository.UpdateFields(ctx, customer.ID, customerFilesToUpdate)
-	if err != nil {
-		return err
+		customerFilesToUpdate["subscription_period_months"] = months
 	}
-
-	if !skipTelegramCustomerDM(customer) {
-		_, err = s.telegramBot.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID: customer.TelegramID,
-			Text:   s.translation.GetText(customer.Language, "subscription_activated"),
-			ReplyMarkup: models.InlineKeyboardMarkup{
-				InlineKeyboard: s.createConnectKeyboard(customer),
-			},
-		})
-		if err != nil {
-			return err
-		}
-	}
-
-	if err := s.applyReferralBonus(ctx, purchase, customer); err != nil {
-		return err
-	}
-	s.clearPromoDiscountIfUsed(ctx, purchase, customer)
-	s.applyLoyaltyXPAfterPayment(ctx, purchase, customer)
-	slog.Info("purchase processed", "purchase_id", utils.MaskHalfInt64(purchase.ID), "type", purchase.InvoiceType, "customer_id", utils.MaskHalfInt64(customer.ID))
-
-	s.tryNotifyPurchasePaid(ctx, purchase, customer, expireBefore, ptrTimeIfValid(user.ExpireAt))
-
-	return nil
-}
-
-func (s PaymentService) applyLoyaltyXPAfterPayment(ctx context.Context, purchase *database.Purchase, customer *database.Customer) {
-	if !config.LoyaltyEnabled() || purchase == nil || customer == nil {
-		return
-	}
-	gain := loyalty.XPRubEquivalentForPurchase(purchase)
-	if gain <= 0 {
-		return
-	}
-	oldXP := customer.LoyaltyXP
-	if err := s.customerRepository.IncrementLoyaltyXP(ctx, customer.ID, gain); err != nil {
-		slog.Error("loyalty xp increment", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID), "purchase_id", utils.MaskHalfInt64(purchase.ID))
-		return
-	}
-	s.maybeNotifyLoyaltyLevelUp(ctx, customer, oldXP, oldXP+gain)
+	return s.customerRepository.UpdateFields(ctx, customer.ID, customerFilesToUpdate)
 }
 
 // maybeNotifyLoyaltyLevelUp отправляет поздравление при повышении уровня (sort_order текущего tier).
@@ -784,13 +701,14 @@ func (s PaymentService) maybeNotifyLoyaltyLevelUp(ctx context.Context, customer
 	}
 }
 
-func (s *PaymentService) clearPromoDiscountIfUsed(ctx context.Context, purchase *database.Purchase, customer *database.Customer) {
+func (s *PaymentService) clearPromoDiscountIfUsed(ctx context.Context, purchase *database.Purchase, customer *database.Customer) error {
 	if s.promoService == nil || purchase == nil || customer == nil {
-		return
+		return nil
 	}
 	if purchase.PromoCodeID != nil && *purchase.PromoCodeID > 0 {
-		_ = s.promoService.OnSuccessfulSubscriptionDiscountPayment(ctx, purchase, customer.ID)
+		return s.promoService.OnSuccessfulSubscriptionDiscountPayment(ctx, purchase, customer.ID)
 	}
+	return nil
 }
 
 func (s PaymentService) createConnectKeyboard(customer *database.Customer) [][]models.InlineKeyboardButton {
@@ -816,7 +734,9 @@ func (s PaymentService) createConnectKeyboard(customer *database.Customer) [][]m
 	return inlineCustomerKeyboard
 }
 
-func (s PaymentService) applyReferralBonus(ctx context.Context, purchase *database.Purchase, customer *database.Customer) error {
+// applyReferralBonus начисляет реферальные бонусы за оплату. once пропускает уже выполненные при прошлой
+// попытке начисления и сообщения: шаг outbox повторяется целиком.
+func (s PaymentService) applyReferralBonus(ctx context.Context, purchase *database.Purchase, customer *database.Customer, once outboxOnce) error {
 	ctxReferee := context.Background()
 	referral, err := s.referralRepository.FindByReferee(ctxReferee, customer.TelegramID)
 	if err != nil || referral == nil {
@@ -832,12 +752,12 @@ func (s PaymentService) applyReferralBonus(ctx context.Context, purchase *databa
 
 	mode := config.ReferralMode()
 	if mode == "progressive" {
-		return s.applyProgressiveReferralBonus(ctxReferee, referral, purchase, customer)
+		return s.applyProgressiveReferralBonus(ctxReferee, referral, purchase, customer, once)
 	}
-	return s.applyDefaultReferralBonus(ctxReferee, referral)
+	return s.applyDefaultReferralBonus(ctxReferee, referral, once)
 }
 
-func (s PaymentService) applyDefaultReferralBonus(ctx context.Context, referral *database.Referral) error {
+func (s PaymentService) applyDefaultReferralBonus(ctx context.Context, referral *database.Referral, once outboxOnce) error {
 	if referral.BonusGranted {
 		return nil
 	}
@@ -848,7 +768,7 @@ func (s PaymentService) applyDefaultReferralBonus(ctx context.Context, referral
 	}
 
 	bonusDays := config.GetReferralDays()
-	if err := s.grantReferralDays(ctx, referrerCustomer, bonusDays); err != nil {
+	if err := once("referrer_days", func() error { return s.grantReferralDays(ctx, referrerCustomer, bonusDays) }); err != nil {
 		return err
 	}
 	if err := s.referralRepository.MarkBonusGranted(ctx, referral.ID); err != nil {
@@ -856,11 +776,10 @@ func (s PaymentService) applyDefaultReferralBonus(ctx context.Context, referral
 	}
 
 	slog.Info("Granted referral bonus", "customer_id", utils.MaskHalfInt64(referrerCustomer.ID))
-	err = s.sendReferralBonusMessage(ctx, referrerCustomer, bonusDays)
-	return err
+	return once("referrer_message", func() error { return s.sendReferralBonusMessage(ctx, referrerCustomer, bonusDays) })
 }
 
-func (s PaymentService) applyProgressiveReferralBonus(ctx context.Context, referral *database.Referral, purchase *database.Purchase, customer *database.Customer) error {
+func (s PaymentService) applyProgressiveReferralBonus(ctx context.Context, referral *database.Referral, purchase *database.Purchase, customer *database.Customer, once outboxOnce) error {
 	if purchase.Month < 1 {
 		return nil
 	}
@@ -878,45 +797,39 @@ func (s PaymentService) applyProgressiveReferralBonus(ctx context.Context, refer
 	bonusDays := 0
 	if paidCount == 1 {
 		refereeBonusDays := config.ReferralFirstRefereeDays()
-		if err := s.grantReferralDays(ctx, customer, refereeBonusDays); err != nil {
+		if err := once("referee_days", func() error { return s.grantReferralDays(ctx, customer, refereeBonusDays) }); err != nil {
 			return err
 		}
-		if err := s.sendReferralFirstBonusMessage(ctx, customer, refereeBonusDays); err != nil {
+		if err := once("referee_message", func() error { return s.sendReferralFirstBonusMessage(ctx, customer, refereeBonusDays) }); err != nil {
 			return err
 		}
 		bonusDays = config.ReferralFirstReferrerDays()
-		if err := s.grantReferralDays(ctx, referrerCustomer, bonusDays); err != nil {
-			return err
-		}
-		if !referral.BonusGranted {
-			if err := s.referralRepository.MarkBonusGranted(ctx, referral.ID); err != nil {
-				return err
-			}
-		}
 	} else {
 		bonusDays = config.ReferralRepeatReferrerDays()
-		if err := s.grantReferralDays(ctx, referrerCustomer, bonusDays); err != nil {
+	}
+	if err := once("referrer_days", func() error { return s.grantReferralDays(ctx, referrerCustomer, bonusDays) }); err != nil {
+		return err
+	}
+	if !referral.BonusGranted {
+		if err := s.referralRepository.MarkBonusGranted(ctx, referral.ID); err != nil {
 			return err
 		}
-		if !referral.BonusGranted {
-			if err := s.referralRepository.MarkBonusGranted(ctx, referral.ID); err != nil {
-				return err
-			}
-		}
 	}
 
 	slog.Info("Granted referral bonus", "customer_id", utils.MaskHalfInt64(referrerCustomer.ID))
 	if bonusDays <= 0 {
 		return nil
 	}
-	err = s.sendReferralBonusMessage(ctx, referrerCustomer, bonusDays)
-	return err
+	return once("referrer_message", func() error { return s.sendReferralBonusMessage(ctx, referrerCustomer, bonusDays) })
 }
 
 func (s PaymentService) grantReferralDays(ctx context.Context, customer *database.Customer, days int) error {
 	if days <= 0 {
 		return nil
 	}
+	if config.ReferralRewardToBalance() {
+		return s.grantReferralBalance(ctx, customer, days)
+	}
 	rwCtx := s.withRemnawavePanelUsername(ctx, customer)
 	user, err := s.remnawaveClient.ExtendSubscriptionByDaysPreserveSquads(rwCtx, customer.ID, customer.TelegramID, days)
 	if err != nil {
@@ -939,7 +852,7 @@ func (s PaymentService) sendReferralBonusMessage(ctx context.Context, customer *
 	_, err := s.telegramBot.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:    customer.TelegramID,
 		ParseMode: models.ParseModeHTML,
-		Text:      fmt.Sprintf(s.translation.GetText(customer.Language, "referral_bonus_granted"), days),
+		Text:      s.referralBonusText(customer.Language, "referral_bonus_granted", days),
 		ReplyMarkup: models.InlineKeyboardMarkup{
 			InlineKeyboard: s.createReferralBonusKeyboard(customer),
 		},
@@ -957,7 +870,7 @@ func (s PaymentService) sendReferralFirstBonusMessage(ctx context.Context, custo
 	_, err := s.telegramBot.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:    customer.TelegramID,
 		ParseMode: models.ParseModeHTML,
-		Text:      fmt.Sprintf(s.translation.GetText(customer.Language, "referral_first_bonus_granted"), days),
+		Text:      s.referralBonusText(customer.Language, "referral_first_bonus_granted", days),
 		ReplyMarkup: models.InlineKeyboardMarkup{
 			InlineKeyboard: s.createReferralBonusKeyboard(customer),
 		},
@@ -989,6 +902,7 @@ func applyTariffPurchaseExtras(pur *database.Purchase, extras *TariffPurchaseExt
 		pur.PurchaseKind = extras.Kind
 	}
 	pur.IsEarlyDowngrade = extras.IsEarlyDowngrade
+	pur.TrafficGB = extras.TrafficGB
 }
 
 // ensurePurchaseKindExtraHwidOnly помечает покупку «только доп. HWID» (month==0, extra>0),
@@ -1045,6 +959,12 @@ func buildRubReceiptDescription(months, extraHwid int, extras *TariffPurchaseExt
 	ms := rubMonthWord(months)
 	var base string
 	switch {
+	case extras != nil && extras.Kind == database.PurchaseKindBalanceTopUp:
+		base = "Пополнение баланса"
+	case extras != nil && extras.Kind == database.PurchaseKindTrafficPack:
+		base = fmt.Sprintf("Дополнительный трафик +%d ГБ", extras.TrafficGB)
+	case extras != nil && extras.Kind == database.PurchaseKindGift && months > 0:
+		base = fmt.Sprintf("Подарочная подписка на %d %s", months, ms)
 	case months > 0 && extraHwid > 0:
 		base = fmt.Sprintf("Подписка на %d %s + %d устр.", months, ms, extraHwid)
 	case extraHwid > 0 && months <= 0:
@@ -1086,21 +1006,9 @@ func invoiceDescriptionPlategaSuffix(t database.InvoiceType) string {
 }
 
 func (s PaymentService) CreatePurchase(ctx context.Context, amount float64, months int, customer *database.Customer, invoiceType database.InvoiceType, meta *PromoMeta, tariffID *int64, extras *TariffPurchaseExtras) (url string, purchaseId int64, err error) {
-	switch invoiceType {
-	case database.InvoiceTypeCrypto:
-		return s.createCryptoInvoice(ctx, amount, months, 0, customer, meta, tariffID, extras)
-	case database.InvoiceTypeYookasa:
-		return s.createYookasaInvoice(ctx, amount, months, 0, customer, meta, tariffID, extras)
-	case database.InvoiceTypePlategaSBP, database.InvoiceTypePlategaCards, database.InvoiceTypePlategaAcquiring,
-		database.InvoiceTypePlategaWorldwide, database.InvoiceTypePlategaCrypto:
-		return s.createPlategaInvoice(ctx, amount, months, 0, customer, meta, tariffID, extras, invoiceType)
-	case database.InvoiceTypeTelegram:
-		return s.createTelegramInvoice(ctx, amount, months, 0, customer, meta, tariffID, extras)
-	case database.InvoiceTypeTribute:
-		return s.createTributeInvoice(ctx, amount, months, 0, customer, nil, tariffID, extras)
-	default:
-		return "", 0, fmt.Errorf("unknown invoice type: %s", invoiceType)
-	}
+	return s.createInvoice(ctx, invoiceType, InvoiceParams{
+		Amount: amount, Months: months, Customer: customer, Promo: meta, TariffID: tariffID, Extras: extras,
+	})
 }
 
 func (s PaymentService) CreatePurchaseWithExtra(ctx context.Context, amount float64, months int, extraHwid int, customer *database.Customer, invoiceType database.InvoiceType, meta *PromoMeta, tariffID *int64, extras *TariffPurchaseExtras) (url string, purchaseId int64, err error) {
@@ -1110,21 +1018,9 @@ func (s PaymentService) CreatePurchaseWithExtra(ctx context.Context, amount floa
 	if !config.HwidExtraDevicesEnabled() && extraHwid > 0 {
 		return "", 0, fmt.Errorf("extra hwid purchases are disabled")
 	}
-	switch invoiceType {
-	case database.InvoiceTypeCrypto:
-		return s.createCryptoInvoice(ctx, amount, months, extraHwid, customer, meta, tariffID, extras)
-	case database.InvoiceTypeYookasa:
-		return s.createYookasaInvoice(ctx, amount, months, extraHwid, customer, meta, tariffID, extras)
-	case database.InvoiceTypePlategaSBP, database.InvoiceTypePlategaCards, database.InvoiceTypePlategaAcquiring,
-		database.InvoiceTypePlategaWorldwide, database.InvoiceTypePlategaCrypto:
-		return s.createPlategaInvoice(ctx, amount, months, extraHwid, customer, meta, tariffID, extras, invoiceType)
-	case database.InvoiceTypeTelegram:
-		return s.createTelegramInvoice(ctx, amount, months, extraHwid, customer, meta, tariffID, extras)
-	case database.InvoiceTypeTribute:
-		return s.createTributeInvoice(ctx, amount, months, extraHwid, customer, nil, tariffID, extras)
-	default:
-		return "", 0, fmt.Errorf("unknown invoice type: %s", invoiceType)
-	}
+	return s.createInvoice(ctx, invoiceType, InvoiceParams{
+		Amount: amount, Months: months, ExtraHwid: extraHwid, Customer: customer, Promo: meta, TariffID: tariffID, Extras: extras,
+	})
 }
 
 func (s PaymentService) CreateHwidPurchase(ctx context.Context, amount float64, extraHwid int, customer *database.Customer, invoiceType database.InvoiceType, meta *PromoMeta) (url string, purchaseId int64, err error) {
@@ -1134,21 +1030,21 @@ func (s PaymentService) CreateHwidPurchase(ctx context.Context, amount float64,
 	if !config.HwidExtraDevicesEnabled() {
 		return "", 0, fmt.Errorf("extra hwid purchases are disabled")
 	}
-	switch invoiceType {
-	case database.InvoiceTypeCrypto:
-		return s.createCryptoInvoice(ctx, amount, 0, extraHwid, customer, meta, nil, nil)
-	case database.InvoiceTypeYookasa:
-		return s.createYookasaInvoice(ctx, amount, 0, extraHwid, customer, meta, nil, nil)
-	case database.InvoiceTypePlategaSBP, database.InvoiceTypePlategaCards, database.InvoiceTypePlategaAcquiring,
-		database.InvoiceTypePlategaWorldwide, database.InvoiceTypePlategaCrypto:
-		return s.createPlategaInvoice(ctx, amount, 0, extraHwid, customer, meta, nil, nil, invoiceType)
-	case database.InvoiceTypeTelegram:
-		return s.createTelegramInvoice(ctx, amount, 0, extraHwid, customer, meta, nil, nil)
-	case database.InvoiceTypeTribute:
-		return s.createTributeInvoice(ctx, amount, 0, extraHwid, customer, nil, nil, nil)
-	default:
+	return s.createInvoice(ctx, invoiceType, InvoiceParams{
+		Amount: amount, ExtraHwid: extraHwid, Customer: customer, Promo: meta,
+	})
+}
+
+// createInvoice — создание счёта через провайдера из реестра.
+func (s PaymentService) createInvoice(ctx context.Context, invoiceType database.InvoiceType, params InvoiceParams) (string, int64, error) {
+	p, err := s.providerFor(invoiceType)
+	if err != nil {
 		return "", 0, fmt.Errorf("unknown invoice type: %s", invoiceType)
 	}
+	if params.Currency, err = invoiceCurrency(ctx, p.Info()); err != nil {
+		return "", 0, err
+	}
+	return p.CreateInvoice(ctx, params)
 }
 
 var ErrCustomerNotFound = errors.New("customer not found")
@@ -1186,6 +1082,7 @@ func (s PaymentService) CancelTributePurchase(ctx context.Context, telegramId in
 		return err
 	}
 	tributePurchase.Status = database.PurchaseStatusCancel
+	s.reverseReferralCommission(ctx, tributePurchase, tributePurchase.Amount, true)
 	s.tryNotifyPurchaseCancel(ctx, tributePurchase, customer)
 
 	if !utils.IsSyntheticTelegramID(telegramId) && !customer.IsWebOnly {
@@ -1202,12 +1099,12 @@ func (s PaymentService) CancelTributePurchase(ctx context.Context, telegramId in
 	return nil
 }
 
-func (s PaymentService) createCryptoInvoice(ctx context.Context, amount float64, months int, extraHwid int, customer *database.Customer, meta *PromoMeta, tariffID *int64, extras *TariffPurchaseExtras) (url string, purchaseId int64, err error) {
+func (s PaymentService) createCryptoInvoice(ctx context.Context, amount float64, months int, extraHwid int, customer *database.Customer, meta *PromoMeta, tariffID *int64, extras *TariffPurchaseExtras, currency string) (url string, purchaseId int64, err error) {
 	pur := &database.Purchase{
 		InvoiceType: database.InvoiceTypeCrypto,
 		Status:      database.PurchaseStatusNew,
 		Amount:      amount,
-		Currency:    "RUB",
+		Currency:    currency,
 		CustomerID:  customer.ID,
 		Month:       months,
 		ExtraHwid:   extraHwid,
@@ -1233,8 +1130,8 @@ func (s PaymentService) createCryptoInvoice(ctx context.Context, amount float64,
 	}
 	invoice, err := s.cryptoPayClient.CreateInvoice(&cryptopay.InvoiceRequest{
 		CurrencyType:   "fiat",
-		Fiat:           "RUB",
-		Amount:         fmt.Sprintf("%d", int(amount)),
+		Fiat:           currency,
+		Amount:         formatInvoiceAmount(amount),
 		AcceptedAssets: "USDT",
 		Payload:        fmt.Sprintf("purchaseId=%d&username=%s", purchaseId, username),
 		Description:    description,
@@ -1285,7 +1182,11 @@ func (s PaymentService) createYookasaInvoice(ctx context.Context, amount float64
 	}
 
 	invDesc := buildRubReceiptDescription(months, extraHwid, extras, database.InvoiceTypeYookasa)
-	invoice, err := s.yookasaClient.CreateInvoice(ctx, int(amount), invDesc, customer.ID, purchaseId)
+	saveCtx := ctx
+	if pur.PurchaseKind != database.PurchaseKindGift {
+		saveCtx = s.withSavePaymentMethod(ctx, months)
+	}
+	invoice, err := s.yookasaClient.CreateInvoice(saveCtx, int(amount), invDesc, customer.ID, purchaseId)
 	if err != nil {
 		slog.Error("Error creating invoice", "error", err)
 		return "", 0, err
@@ -1306,7 +1207,7 @@ func (s PaymentService) createYookasaInvoice(ctx context.Context, amount float64
 	return invoice.Confirmation.ConfirmationURL, purchaseId, nil
 }
 
-func (s PaymentService) createPlategaInvoice(ctx context.Context, amount float64, months int, extraHwid int, customer *database.Customer, meta *PromoMeta, tariffID *int64, extras *TariffPurchaseExtras, invoiceType database.InvoiceType) (url string, purchaseId int64, err error) {
+func (s PaymentService) createPlategaInvoice(ctx context.Context, amount float64, months int, extraHwid int, customer *database.Customer, meta *PromoMeta, tariffID *int64, extras *TariffPurchaseExtras, invoiceType database.InvoiceType, currency string) (url string, purchaseId int64, err error) {
 	if s.plategaClient == nil || !s.plategaClient.IsConfigured() {
 		return "", 0, fmt.Errorf("platega client not configured")
 	}
@@ -1318,7 +1219,7 @@ func (s PaymentService) createPlategaInvoice(ctx context.Context, amount float64
 		InvoiceType: invoiceType,
 		Status:      database.PurchaseStatusNew,
 		Amount:      amount,
-		Currency:    "RUB",
+		Currency:    currency,
 		CustomerID:  customer.ID,
 		Month:       months,
 		ExtraHwid:   extraHwid,
@@ -1342,7 +1243,7 @@ func (s PaymentService) createPlategaInvoice(ctx context.Context, amount float64
 	}
 	desc := buildRubReceiptDescription(months, extraHwid, extras, invoiceType)
 	username := remnawave.UsernameFromCtx(ctx)
-	redirectURL, transactionID, err := provider.CreateInvoice(ctx, purchaseId, amount, "RUB", desc, ret, username)
+	redirectURL, transactionID, err := provider.CreateInvoice(ctx, purchaseId, amount, currency, desc, ret, username)
 	if err != nil {
 		slog.Error("Error creating platega invoice", "error", err, "invoice_type", invoiceType)
 		return "", 0, err
@@ -1377,6 +1278,10 @@ func (s PaymentService) createTelegramInvoice(ctx context.Context, amount float6
 		pur.PromoCodeID = meta.PromoCodeID
 		pur.DiscountPercentApplied = meta.DiscountPercentApplied
 	}
+	subscription := starsSubscriptionRequested(ctx)
+	if subscription && !s.starsSubscriptionAllowed(months, amount, extras) {
+		return "", 0, ErrStarsSubscriptionUnavailable
+	}
 	purchaseId, err = s.purchaseRepository.Create(ctx, pur)
 	if err != nil {
 		slog.Error("Error creating purchase", "error", err)
@@ -1384,7 +1289,7 @@ func (s PaymentService) createTelegramInvoice(ctx context.Context, amount float6
 	}
 
 	username, _ := ctx.Value(remnawave.CtxKeyUsername).(string)
-	invoiceUrl, err := s.telegramBot.CreateInvoiceLink(ctx, &bot.CreateInvoiceLinkParams{
+	params := &bot.CreateInvoiceLinkParams{
 		Title:    s.translation.GetText(customer.Language, "invoice_title"),
 		Currency: "XTR",
 		Prices: []models.LabeledPrice{
@@ -1395,7 +1300,17 @@ func (s PaymentService) createTelegramInvoice(ctx context.Context, amount float6
 		},
 		Description: s.translation.GetText(customer.Language, "invoice_description"),
 		Payload:     fmt.Sprintf("%d&%s", purchaseId, username),
-	})
+	}
+	if subscription {
+		// Telegram будет сам списывать ту же сумму раз в 30 дней (successful_payment с is_recurring).
+		params.SubscriptionPeriod = starsSubscriptionPeriod
+		params.Description = s.translation.GetText(customer.Language, "invoice_description_stars_subscription")
+	}
+	invoiceUrl, err := s.telegramBot.CreateInvoiceLink(ctx, params)
+	if err != nil {
+		slog.Error("Error creating telegram invoice link", "error", err, "subscription", subscription)
+		return "", 0, err
+	}
 
 	updates := map[string]interface{}{
 		"status":             database.PurchaseStatusPending,
@@ -1437,6 +1352,9 @@ func (s PaymentService) ActivateTrial(ctx context.Context, telegramId int64) (st
 		"expire_at":                user.ExpireAt,
 		"subscription_period_start": now,
 	}
+	if customer.TrialActivatedAt == nil {
+		customerFilesToUpdate["trial_activated_at"] = now
+	}
 
 	err = s.customerRepository.UpdateFields(ctx, customer.ID, customerFilesToUpdate)
 	if err != nil {
@@ -1450,36 +1368,17 @@ func (s PaymentService) ActivateTrial(ctx context.Context, telegramId int64) (st
 func (s PaymentService) CancelYookassaPayment(purchaseId int64) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	purchase, err := s.purchaseRepository.FindById(ctx, purchaseId)
-	if err != nil {
-		return err
-	}
-	if purchase == nil {
-		return fmt.Errorf("purchase with crypto invoice id %s not found", utils.MaskHalfInt64(purchaseId))
-	}
-
-	purchaseFieldsToUpdate := map[string]interface{}{
-		"status": database.PurchaseStatusCancel,
-	}
-
-	err = s.purchaseRepository.UpdateFields(ctx, purchaseId, purchaseFieldsToUpdate)
-	if err != nil {
-		return err
-	}
-
-	purchase.Status = database.PurchaseStatusCancel
-	cust, ferr := s.customerRepository.FindById(ctx, purchase.CustomerID)
-	if ferr != nil {
-		slog.Warn("payments notify cancel: load customer", "error", ferr)
-	}
-	s.tryNotifyPurchaseCancel(ctx, purchase, cust)
-
-	return nil
+	return s.markPurchaseCanceled(ctx, purchaseId)
 }
 
 func (s PaymentService) CancelPlategaPayment(purchaseId int64) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+	return s.markPurchaseCanceled(ctx, purchaseId)
+}
+
+// markPurchaseCanceled переводит покупку в cancel и шлёт уведомление в группу платежей.
+func (s PaymentService) markPurchaseCanceled(ctx context.Context, purchaseId int64) error {
 	purchase, err := s.purchaseRepository.FindById(ctx, purchaseId)
 	if err != nil {
 		return err
@@ -1487,12 +1386,17 @@ func (s PaymentService) CancelPlategaPayment(purchaseId int64) error {
 	if purchase == nil {
 		return fmt.Errorf("purchase with id %s not found", utils.MaskHalfInt64(purchaseId))
 	}
+	wasPaid := purchase.Status == database.PurchaseStatusPaid
 	if err := s.purchaseRepository.UpdateFields(ctx, purchaseId, map[string]interface{}{
 		"status": database.PurchaseStatusCancel,
 	}); err != nil {
 		return err
 	}
 	purchase.Status = database.PurchaseStatusCancel
+	if wasPaid {
+		// Отмена уже проведённой оплаты (отзыв платежа у провайдера) — комиссия партнёра сторнируется целиком.
+		s.reverseReferralCommission(ctx, purchase, purchase.Amount, true)
+	}
 	cust, ferr := s.customerRepository.FindById(ctx, purchase.CustomerID)
 	if ferr != nil {
 		slog.Warn("payments notify cancel: load customer", "error", ferr)
@@ -1522,26 +1426,3 @@ func (s PaymentService) createTributeInvoice(ctx context.Context, amount float64
 
 	return "", purchaseId, nil
 }
-
-// notifyAdminMoynalogFailure отправляет админу уведомление о неуспешной отправке чека в МойНалог
-func notifyAdminMoynalogFailure(ctx context.Context, b *bot.Bot, adminID int64, purchase *database.Purchase, sendErr error, description string) {
-	if b == nil || adminID == 0 || purchase == nil {
-		return
-	}
-
-	msg := fmt.Sprintf(
-		"Не удалось создать чек в Мой Налог.\nПокупка ID: %d\nСумма: %.2f\nОписание: %s\nТип счета: %s\nОшибка: %v",
-		purchase.ID,
-		purchase.Amount,
-		description,
-		purchase.InvoiceType,
-		sendErr,
-	)
-
-	if _, err := b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID: adminID,
-		Text:   msg,
-	}); err != nil {
-		slog.Error("Failed to notify admin about moynalog receipt failure", "error", err, "purchase_id", utils.MaskHalfInt64(purchase.ID))
-	}
-}
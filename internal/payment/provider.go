@@ -0,0 +1,245 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/utils"
+)
+
+// ErrStatusCheckUnsupported — провайдер не умеет запрашивать статус (Stars, Tribute: оплата приходит push'ем).
+var ErrStatusCheckUnsupported = errors.New("provider does not support status check")
+
+// ErrUnknownProvider — invoice_type / ключ кабинета не зарегистрирован в реестре.
+var ErrUnknownProvider = errors.New("unknown payment provider")
+
+// InvoiceStatus — нормализованный статус счёта у провайдера.
+type InvoiceStatus int
+
+const (
+	InvoiceStatusPending InvoiceStatus = iota
+	InvoiceStatusPaid
+	InvoiceStatusCanceled
+	// InvoiceStatusExpired — срок счёта у провайдера истёк. Поллинг такие не трогает, закрывает сверка (ReconcileStalePurchases).
+	InvoiceStatusExpired
+)
+
+// ProviderInfo — метаданные для клавиатур бота, кабинета и админки.
+type ProviderInfo struct {
+	InvoiceType database.InvoiceType
+	// CheckoutKey — значение provider в API кабинета; пусто — в кабинете не предлагается.
+	CheckoutKey string
+	// ButtonKey — ключ перевода кнопки выбора способа оплаты в боте.
+	ButtonKey string
+	// ExternalURL — оплата по внешней ссылке (Tribute): кнопка-ссылка вместо callback.
+	ExternalURL string
+	// Pollable — статус можно опрашивать по расписанию, если вебхук не настроен.
+	Pollable bool
+	// Refundable — возврат уходит через API провайдера.
+	Refundable bool
+	// Currencies — валюты, в которых провайдер выставляет счёт; первая — по умолчанию. Пусто — только RUB.
+	Currencies []string
+}
+
+// InvoiceParams — параметры нового счёта (подписка, доп. HWID, тариф).
+type InvoiceParams struct {
+	Amount    float64
+	Months    int
+	ExtraHwid int
+	Customer  *database.Customer
+	Promo     *PromoMeta
+	TariffID  *int64
+	Extras    *TariffPurchaseExtras
+	// Currency — валюта счёта из ProviderInfo.Currencies (заполняет createInvoice, см. WithInvoiceCurrency).
+	Currency string
+}
+
+// StatusCheck — результат CheckStatus. PaidContext (если задан) дополняет ctx
+// перед ProcessPurchaseById: username из метаданных, мета для уведомлений в группу.
+type StatusCheck struct {
+	Status      InvoiceStatus
+	PaidContext func(context.Context) context.Context
+}
+
+// Provider — платёжный способ. Один invoice_type = один Provider (методы Platega — отдельные провайдеры).
+type Provider interface {
+	Info() ProviderInfo
+	Enabled() bool
+	CreateInvoice(ctx context.Context, params InvoiceParams) (url string, purchaseID int64, err error)
+	CheckStatus(ctx context.Context, p *database.Purchase) (StatusCheck, error)
+	Cancel(ctx context.Context, p *database.Purchase) error
+	// Refund возвращает деньги; refunded=false — API нет, деньги возвращаются вручную.
+	Refund(ctx context.Context, p *database.Purchase, c *database.Customer, refund *database.PurchaseRefund) (providerRefundID string, refunded bool, err error)
+	// Webhook — путь и обработчик входящих уведомлений; пустой путь — вебхук не настроен.
+	Webhook() (path string, handler http.Handler)
+}
+
+// BatchStatusChecker — необязательное расширение Provider: статус нескольких счетов
+// одним запросом (CryptoPay getInvoices). Ключ результата — purchase.ID; отсутствующие — pending.
+type BatchStatusChecker interface {
+	CheckStatuses(ctx context.Context, purchases []database.Purchase) (map[int64]StatusCheck, error)
+}
+
+// InvoiceExpirer — необязательное расширение Provider: аннулировать неоплаченный счёт у провайдера,
+// чтобы его нельзя было оплатить после того, как сверка закрыла покупку как просроченную.
+type InvoiceExpirer interface {
+	ExpireInvoice(ctx context.Context, p *database.Purchase) error
+}
+
+// ProviderRegistry хранит провайдеров в порядке регистрации (он же порядок кнопок).
+type ProviderRegistry struct {
+	mu        sync.RWMutex
+	providers []Provider
+	webhooks  map[database.InvoiceType]http.Handler
+}
+
+func NewProviderRegistry() *ProviderRegistry {
+	return &ProviderRegistry{webhooks: make(map[database.InvoiceType]http.Handler)}
+}
+
+// Register добавляет провайдера; повторная регистрация того же invoice_type заменяет прежнего на его месте.
+func (r *ProviderRegistry) Register(p Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := p.Info().InvoiceType
+	for i, existing := range r.providers {
+		if existing.Info().InvoiceType == t {
+			r.providers[i] = p
+			return
+		}
+	}
+	r.providers = append(r.providers, p)
+}
+
+// SetWebhookHandler подменяет обработчик вебхука провайдера. Нужен там, где пакет
+// обработчика сам зависит от payment (Tribute) и не может быть создан внутри реестра.
+func (r *ProviderRegistry) SetWebhookHandler(t database.InvoiceType, h http.Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.webhooks[t] = h
+}
+
+// WebhookHandler — обработчик вебхука с учётом SetWebhookHandler.
+func (r *ProviderRegistry) WebhookHandler(p Provider) (string, http.Handler) {
+	path, h := p.Webhook()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if override, ok := r.webhooks[p.Info().InvoiceType]; ok {
+		h = override
+	}
+	return path, h
+}
+
+func (r *ProviderRegistry) Get(t database.InvoiceType) (Provider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, p := range r.providers {
+		if p.Info().InvoiceType == t {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// ByCheckoutKey ищет провайдера по значению provider из API кабинета.
+func (r *ProviderRegistry) ByCheckoutKey(key string) (Provider, bool) {
+	if key == "" {
+		return nil, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, p := range r.providers {
+		if p.Info().CheckoutKey == key {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+func (r *ProviderRegistry) All() []Provider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Provider(nil), r.providers...)
+}
+
+// Enabled — включённые провайдеры в порядке регистрации.
+func (r *ProviderRegistry) Enabled() []Provider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]Provider, 0, len(r.providers))
+	for _, p := range r.providers {
+		if p.Enabled() {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Providers — реестр платёжных способов сервиса.
+func (s PaymentService) Providers() *ProviderRegistry {
+	return s.providers
+}
+
+func (s PaymentService) providerFor(t database.InvoiceType) (Provider, error) {
+	if s.providers == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, t)
+	}
+	p, ok := s.providers.Get(t)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, t)
+	}
+	return p, nil
+}
+
+// PollPendingInvoices опрашивает pending-покупки провайдера: оплаченные проводит, отменённые закрывает.
+// Используется cron'ом в main, когда вебхук провайдера не настроен.
+func (s PaymentService) PollPendingInvoices(ctx context.Context, p Provider) {
+	t := p.Info().InvoiceType
+	pending, err := s.purchaseRepository.FindByInvoiceTypeAndStatus(ctx, t, database.PurchaseStatusPending)
+	if err != nil {
+		slog.Error("poll: find pending purchases", "invoice_type", t, "error", err)
+		return
+	}
+	if len(*pending) == 0 {
+		return
+	}
+	var batch map[int64]StatusCheck
+	if bc, ok := p.(BatchStatusChecker); ok {
+		batch, err = bc.CheckStatuses(ctx, *pending)
+		if err != nil {
+			slog.Error("poll: check statuses", "invoice_type", t, "error", err)
+			return
+		}
+	}
+	for i := range *pending {
+		purchase := &(*pending)[i]
+		var check StatusCheck
+		if batch != nil {
+			check = batch[purchase.ID]
+		} else if check, err = p.CheckStatus(ctx, purchase); err != nil {
+			slog.Error("poll: check status", "invoice_type", t, "purchase_id", utils.MaskHalfInt64(purchase.ID), "error", err)
+			continue
+		}
+		switch check.Status {
+		case InvoiceStatusCanceled:
+			if err := p.Cancel(ctx, purchase); err != nil {
+				slog.Error("poll: cancel purchase", "invoice_type", t, "purchase_id", utils.MaskHalfInt64(purchase.ID), "error", err)
+			}
+		case InvoiceStatusPaid:
+			paidCtx := ctx
+			if check.PaidContext != nil {
+				paidCtx = check.PaidContext(ctx)
+			}
+			if err := s.ProcessPurchaseById(paidCtx, purchase.ID); err != nil {
+				slog.Error("poll: process purchase", "invoice_type", t, "purchase_id", utils.MaskHalfInt64(purchase.ID), "error", err)
+			} else {
+				slog.Info("poll: purchase processed", "invoice_type", t, "purchase_id", utils.MaskHalfInt64(purchase.ID))
+			}
+		}
+	}
+}
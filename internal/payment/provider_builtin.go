@@ -0,0 +1,434 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/cryptopay"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/platega"
+	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/internal/yookasa"
+
+	"github.com/go-telegram/bot"
+)
+
+// registerBuiltinProviders — встроенные способы оплаты в порядке кнопок бота:
+// внутренний баланс, CryptoPay, ЮKassa, методы Platega, Telegram Stars, Tribute.
+func registerBuiltinProviders(r *ProviderRegistry, s *PaymentService) {
+	r.Register(&balanceProvider{s: s})
+	r.Register(&cryptoPayProvider{s: s})
+	r.Register(&yookasaProvider{s: s})
+	for _, it := range database.PlategaInvoiceTypes() {
+		r.Register(&plategaProvider{s: s, invoiceType: it})
+	}
+	r.Register(&telegramStarsProvider{s: s})
+	r.Register(&tributeProvider{s: s})
+}
+
+// --- CryptoPay ---------------------------------------------------------------
+
+type cryptoPayProvider struct{ s *PaymentService }
+
+// cryptoPayFiatCurrencies — фиатные валюты счёта CryptoPay (currency_type=fiat); рубли — по умолчанию.
+var cryptoPayFiatCurrencies = []string{
+	"RUB", "USD", "EUR", "BYN", "UAH", "GBP", "CNY", "KZT", "UZS", "GEL", "TRY", "AMD",
+	"THB", "INR", "BRL", "IDR", "AZN", "AED", "PLN", "ILS",
+}
+
+func (p *cryptoPayProvider) Info() ProviderInfo {
+	return ProviderInfo{
+		InvoiceType: database.InvoiceTypeCrypto,
+		CheckoutKey: "cryptopay",
+		ButtonKey:   "crypto_button",
+		Pollable:    true,
+		Currencies:  cryptoPayFiatCurrencies,
+	}
+}
+
+func (p *cryptoPayProvider) Enabled() bool { return config.IsCryptoPayEnabled() }
+
+func (p *cryptoPayProvider) CreateInvoice(ctx context.Context, in InvoiceParams) (string, int64, error) {
+	return p.s.createCryptoInvoice(ctx, in.Amount, in.Months, in.ExtraHwid, in.Customer, in.Promo, in.TariffID, in.Extras, in.Currency)
+}
+
+func (p *cryptoPayProvider) CheckStatus(ctx context.Context, pur *database.Purchase) (StatusCheck, error) {
+	res, err := p.CheckStatuses(ctx, []database.Purchase{*pur})
+	if err != nil {
+		return StatusCheck{}, err
+	}
+	return res[pur.ID], nil
+}
+
+// CheckStatuses — все счета одним getInvoices. Просроченные отдаём как InvoiceStatusExpired: поллинг их не отменяет, как и раньше.
+func (p *cryptoPayProvider) CheckStatuses(_ context.Context, purchases []database.Purchase) (map[int64]StatusCheck, error) {
+	out := make(map[int64]StatusCheck, len(purchases))
+	byInvoice := make(map[int64]int64, len(purchases))
+	ids := make([]string, 0, len(purchases))
+	for _, pur := range purchases {
+		if pur.CryptoInvoiceID == nil {
+			continue
+		}
+		byInvoice[*pur.CryptoInvoiceID] = pur.ID
+		ids = append(ids, fmt.Sprintf("%d", *pur.CryptoInvoiceID))
+	}
+	if len(ids) == 0 || p.s.cryptoPayClient == nil {
+		return out, nil
+	}
+	invoices, err := p.s.cryptoPayClient.GetInvoices("", "", "", strings.Join(ids, ","), 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, inv := range *invoices {
+		if inv.InvoiceID == nil {
+			continue
+		}
+		purchaseID, ok := byInvoice[*inv.InvoiceID]
+		if !ok {
+			continue
+		}
+		if inv.IsExpired() {
+			out[purchaseID] = StatusCheck{Status: InvoiceStatusExpired}
+			continue
+		}
+		if !inv.IsPaid() {
+			continue
+		}
+		invoice := inv
+		out[purchaseID] = StatusCheck{
+			Status:      InvoiceStatusPaid,
+			PaidContext: func(ctx context.Context) context.Context { return CryptoPayPaidContext(ctx, invoice) },
+		}
+	}
+	return out, nil
+}
+
+func (p *cryptoPayProvider) Cancel(ctx context.Context, pur *database.Purchase) error {
+	return p.s.markPurchaseCanceled(ctx, pur.ID)
+}
+
+// ExpireInvoice удаляет счёт в CryptoPay: без expires_in он остаётся оплачиваемым бессрочно.
+func (p *cryptoPayProvider) ExpireInvoice(_ context.Context, pur *database.Purchase) error {
+	if pur.CryptoInvoiceID == nil || p.s.cryptoPayClient == nil {
+		return nil
+	}
+	return p.s.cryptoPayClient.DeleteInvoice(*pur.CryptoInvoiceID)
+}
+
+func (p *cryptoPayProvider) Refund(context.Context, *database.Purchase, *database.Customer, *database.PurchaseRefund) (string, bool, error) {
+	return "", false, nil
+}
+
+func (p *cryptoPayProvider) Webhook() (string, http.Handler) {
+	path := strings.TrimSpace(config.GetCryptoPayWebHookURL())
+	if path == "" {
+		return "", nil
+	}
+	return path, cryptopay.NewWebhookHandler(config.CryptoPayToken(), p.s.purchaseRepository, p.s, CryptoPayPaidContext)
+}
+
+// CryptoPayPaidContext — username и мета счёта для ProcessPurchaseById (поллинг и вебхук CryptoPay).
+func CryptoPayPaidContext(ctx context.Context, invoice cryptopay.InvoiceResponse) context.Context {
+	_, username, _ := cryptopay.ParseInvoicePayload(invoice.Payload)
+	ctx = context.WithValue(ctx, remnawave.CtxKeyUsername, username)
+	feeAmt := ""
+	if invoice.FeeAmount != nil {
+		feeAmt = *invoice.FeeAmount
+	}
+	return WithCryptoNotifyMeta(ctx, CryptoNotifyMeta{
+		Hash:          invoice.Hash,
+		Status:        invoice.Status,
+		CurrencyType:  invoice.CurrencyType,
+		Asset:         invoice.Asset,
+		PaidAsset:     invoice.PaidAsset,
+		PaidAmount:    invoice.PaidAmount,
+		PayUrl:        invoice.PayUrl,
+		BotInvoiceUrl: invoice.BotInvoiceUrl,
+		FeeAmount:     feeAmt,
+	})
+}
+
+// --- ЮKassa ------------------------------------------------------------------
+
+type yookasaProvider struct{ s *PaymentService }
+
+func (p *yookasaProvider) Info() ProviderInfo {
+	return ProviderInfo{
+		InvoiceType: database.InvoiceTypeYookasa,
+		CheckoutKey: "yookassa",
+		ButtonKey:   "card_button",
+		Pollable:    true,
+		Refundable:  true,
+	}
+}
+
+func (p *yookasaProvider) Enabled() bool { return config.IsYookasaEnabled() }
+
+func (p *yookasaProvider) CreateInvoice(ctx context.Context, in InvoiceParams) (string, int64, error) {
+	return p.s.createYookasaInvoice(ctx, in.Amount, in.Months, in.ExtraHwid, in.Customer, in.Promo, in.TariffID, in.Extras)
+}
+
+func (p *yookasaProvider) CheckStatus(ctx context.Context, pur *database.Purchase) (StatusCheck, error) {
+	if p.s.yookasaClient == nil {
+		return StatusCheck{Status: InvoiceStatusPending}, nil
+	}
+	var invoice *yookasa.Payment
+	if pur.YookasaID == nil {
+		if !pur.IsAutoRenew || pur.Status != database.PurchaseStatusNew {
+			return StatusCheck{Status: InvoiceStatusPending}, nil
+		}
+		found, err := p.s.findAutoRenewPayment(ctx, pur)
+		if err != nil || found == nil {
+			return StatusCheck{Status: InvoiceStatusPending}, err
+		}
+		invoice = found
+	} else {
+		got, err := p.s.yookasaClient.GetPayment(ctx, *pur.YookasaID)
+		if err != nil {
+			if errors.Is(err, yookasa.ErrPaymentNotFound) {
+				return StatusCheck{Status: InvoiceStatusCanceled}, nil
+			}
+			return StatusCheck{}, err
+		}
+		invoice = got
+	}
+	switch {
+	case invoice.IsCancelled():
+		return StatusCheck{Status: InvoiceStatusCanceled}, nil
+	case !invoice.Paid:
+		return StatusCheck{Status: InvoiceStatusPending}, nil
+	}
+	// Не зависим от metadata["purchaseId"]: некоторые ответы возвращают metadata в нестабильном формате.
+	username := ""
+	if invoice.Metadata != nil {
+		username = invoice.Metadata["username"]
+	}
+	return StatusCheck{
+		Status: InvoiceStatusPaid,
+		PaidContext: func(ctx context.Context) context.Context {
+			return yookasa.WithPaidPaymentMethod(context.WithValue(ctx, remnawave.CtxKeyUsername, username), invoice)
+		},
+	}, nil
+}
+
+func (p *yookasaProvider) Cancel(_ context.Context, pur *database.Purchase) error {
+	return p.s.CancelYookassaPayment(pur.ID)
+}
+
+func (p *yookasaProvider) Refund(ctx context.Context, pur *database.Purchase, _ *database.Customer, refund *database.PurchaseRefund) (string, bool, error) {
+	if p.s.yookasaClient == nil || pur.YookasaID == nil {
+		return "", false, fmt.Errorf("yookassa payment id is missing")
+	}
+	currency := strings.ToUpper(strings.TrimSpace(pur.Currency))
+	if currency == "" {
+		currency = "RUB"
+	}
+	r, err := p.s.yookasaClient.CreateRefund(ctx, yookasa.RefundRequest{
+		PaymentID:   *pur.YookasaID,
+		Amount:      yookasa.Amount{Value: fmt.Sprintf("%.2f", refund.Amount), Currency: currency},
+		Description: fmt.Sprintf("Refund #%d", pur.ID),
+	}, fmt.Sprintf("refund-%d", refund.ID))
+	if err != nil {
+		return "", false, err
+	}
+	if r.IsCanceled() {
+		return r.ID.String(), false, fmt.Errorf("yookassa refund %s canceled", r.ID)
+	}
+	return r.ID.String(), true, nil
+}
+
+func (p *yookasaProvider) Webhook() (string, http.Handler) {
+	path := strings.TrimSpace(config.GetYookasaWebHookURL())
+	if path == "" {
+		return "", nil
+	}
+	return path, yookasa.NewWebhookHandler(p.s.yookasaClient, p.s, p.s.purchaseRepository)
+}
+
+// --- Platega -----------------------------------------------------------------
+
+// plategaProvider — один метод Platega (СБП, карты, …); шлюз и вебхук у всех общий.
+type plategaProvider struct {
+	s           *PaymentService
+	invoiceType database.InvoiceType
+}
+
+var plategaCheckoutKeys = map[database.InvoiceType]string{
+	database.InvoiceTypePlategaSBP:       "platega_sbp",
+	database.InvoiceTypePlategaCards:     "platega_cards",
+	database.InvoiceTypePlategaAcquiring: "platega_acquiring",
+	database.InvoiceTypePlategaWorldwide: "platega_worldwide",
+	database.InvoiceTypePlategaCrypto:    "platega_crypto",
+}
+
+// plategaWorldwideCurrencies — валюты счёта метода Worldwide (оплата иностранными картами); остальные методы — только RUB.
+var plategaWorldwideCurrencies = []string{"RUB", "USD", "EUR"}
+
+func (p *plategaProvider) Info() ProviderInfo {
+	key := plategaCheckoutKeys[p.invoiceType]
+	info := ProviderInfo{
+		InvoiceType: p.invoiceType,
+		CheckoutKey: key,
+		ButtonKey:   key + "_button",
+		Pollable:    true,
+	}
+	if p.invoiceType == database.InvoiceTypePlategaWorldwide {
+		info.Currencies = plategaWorldwideCurrencies
+	}
+	return info
+}
+
+func (p *plategaProvider) Enabled() bool {
+	if !config.IsPlategaEnabled() || p.s.plategaClient == nil || !p.s.plategaClient.IsConfigured() {
+		return false
+	}
+	switch p.invoiceType {
+	case database.InvoiceTypePlategaSBP:
+		return config.IsPlategaSBPEnabled()
+	case database.InvoiceTypePlategaCards:
+		return config.IsPlategaCardsEnabled()
+	case database.InvoiceTypePlategaAcquiring:
+		return config.IsPlategaAcquiringEnabled()
+	case database.InvoiceTypePlategaWorldwide:
+		return config.IsPlategaWorldwideEnabled()
+	case database.InvoiceTypePlategaCrypto:
+		return config.IsPlategaCryptoEnabled()
+	default:
+		return false
+	}
+}
+
+func (p *plategaProvider) CreateInvoice(ctx context.Context, in InvoiceParams) (string, int64, error) {
+	return p.s.createPlategaInvoice(ctx, in.Amount, in.Months, in.ExtraHwid, in.Customer, in.Promo, in.TariffID, in.Extras, p.invoiceType, in.Currency)
+}
+
+func (p *plategaProvider) CheckStatus(ctx context.Context, pur *database.Purchase) (StatusCheck, error) {
+	if p.s.plategaClient == nil || pur.PlategaID == nil || strings.TrimSpace(*pur.PlategaID) == "" {
+		return StatusCheck{Status: InvoiceStatusPending}, nil
+	}
+	tx, err := p.s.plategaClient.GetTransaction(ctx, strings.TrimSpace(*pur.PlategaID))
+	if err != nil {
+		var apiErr *platega.APIError
+		if errors.As(err, &apiErr) && apiErr.IsNotFound() {
+			return StatusCheck{Status: InvoiceStatusCanceled}, nil
+		}
+		return StatusCheck{}, err
+	}
+	switch tx.Status {
+	case platega.StatusCanceled, platega.StatusChargebacked:
+		return StatusCheck{Status: InvoiceStatusCanceled}, nil
+	case platega.StatusConfirmed:
+		return StatusCheck{Status: InvoiceStatusPaid}, nil
+	default:
+		return StatusCheck{Status: InvoiceStatusPending}, nil
+	}
+}
+
+func (p *plategaProvider) Cancel(_ context.Context, pur *database.Purchase) error {
+	return p.s.CancelPlategaPayment(pur.ID)
+}
+
+// Refund — возврат через API Platega не интегрирован (клиент умеет только создавать и проверять транзакции):
+// возврат фиксируется как manual, деньги админ возвращает в личном кабинете Platega.
+func (p *plategaProvider) Refund(context.Context, *database.Purchase, *database.Customer, *database.PurchaseRefund) (string, bool, error) {
+	return "", false, nil
+}
+
+func (p *plategaProvider) Webhook() (string, http.Handler) {
+	path := strings.TrimSpace(config.GetPlategaWebHookURL())
+	if path == "" {
+		return "", nil
+	}
+	return path, platega.NewWebhookHandler(p.s.purchaseRepository, p.s, config.PlategaMerchantID(), config.PlategaSecret())
+}
+
+// --- Telegram Stars ----------------------------------------------------------
+
+type telegramStarsProvider struct{ s *PaymentService }
+
+func (p *telegramStarsProvider) Info() ProviderInfo {
+	return ProviderInfo{
+		InvoiceType: database.InvoiceTypeTelegram,
+		CheckoutKey: "telegram",
+		ButtonKey:   "stars_button",
+		Refundable:  true,
+		Currencies:  []string{config.CurrencyStars},
+	}
+}
+
+func (p *telegramStarsProvider) Enabled() bool { return config.IsTelegramStarsEnabled() }
+
+func (p *telegramStarsProvider) CreateInvoice(ctx context.Context, in InvoiceParams) (string, int64, error) {
+	return p.s.createTelegramInvoice(ctx, in.Amount, in.Months, in.ExtraHwid, in.Customer, in.Promo, in.TariffID, in.Extras)
+}
+
+// CheckStatus — оплата Stars приходит апдейтом SuccessfulPayment, опрашивать нечего.
+func (p *telegramStarsProvider) CheckStatus(context.Context, *database.Purchase) (StatusCheck, error) {
+	return StatusCheck{}, ErrStatusCheckUnsupported
+}
+
+func (p *telegramStarsProvider) Cancel(ctx context.Context, pur *database.Purchase) error {
+	return p.s.markPurchaseCanceled(ctx, pur.ID)
+}
+
+func (p *telegramStarsProvider) Refund(ctx context.Context, pur *database.Purchase, c *database.Customer, _ *database.PurchaseRefund) (string, bool, error) {
+	if p.s.telegramBot == nil || pur.TelegramChargeID == nil || strings.TrimSpace(*pur.TelegramChargeID) == "" {
+		return "", false, fmt.Errorf("telegram payment charge id is missing")
+	}
+	if skipTelegramCustomerDM(c) {
+		return "", false, fmt.Errorf("customer has no telegram account")
+	}
+	ok, err := p.s.telegramBot.RefundStarPayment(ctx, &bot.RefundStarPaymentParams{
+		UserID:                  c.TelegramID,
+		TelegramPaymentChargeID: *pur.TelegramChargeID,
+	})
+	if err != nil {
+		return "", false, err
+	}
+	if !ok {
+		return "", false, fmt.Errorf("refundStarPayment returned false")
+	}
+	return *pur.TelegramChargeID, true, nil
+}
+
+func (p *telegramStarsProvider) Webhook() (string, http.Handler) { return "", nil }
+
+// --- Tribute (deprecated) ----------------------------------------------------
+
+// tributeProvider — оплата по внешней ссылке; обработчик вебхука подключает main
+// через ProviderRegistry.SetWebhookHandler (пакет tribute сам зависит от payment).
+type tributeProvider struct{ s *PaymentService }
+
+func (p *tributeProvider) Info() ProviderInfo {
+	return ProviderInfo{
+		InvoiceType: database.InvoiceTypeTribute,
+		ButtonKey:   "tribute_button",
+		ExternalURL: config.GetTributePaymentUrl(),
+	}
+}
+
+func (p *tributeProvider) Enabled() bool { return config.GetTributeWebHookUrl() != "" }
+
+func (p *tributeProvider) CreateInvoice(ctx context.Context, in InvoiceParams) (string, int64, error) {
+	return p.s.createTributeInvoice(ctx, in.Amount, in.Months, in.ExtraHwid, in.Customer, nil, in.TariffID, in.Extras)
+}
+
+func (p *tributeProvider) CheckStatus(context.Context, *database.Purchase) (StatusCheck, error) {
+	return StatusCheck{}, ErrStatusCheckUnsupported
+}
+
+func (p *tributeProvider) Cancel(ctx context.Context, pur *database.Purchase) error {
+	return p.s.markPurchaseCanceled(ctx, pur.ID)
+}
+
+func (p *tributeProvider) Refund(context.Context, *database.Purchase, *database.Customer, *database.PurchaseRefund) (string, bool, error) {
+	return "", false, nil
+}
+
+func (p *tributeProvider) Webhook() (string, http.Handler) {
+	return strings.TrimSpace(config.GetTributeWebHookUrl()), nil
+}
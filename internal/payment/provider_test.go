@@ -0,0 +1,65 @@
+package payment
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"remnawave-tg-shop-bot/internal/database"
+)
+
+type stubProvider struct {
+	info    ProviderInfo
+	enabled bool
+}
+
+func (p stubProvider) Info() ProviderInfo { return p.info }
+func (p stubProvider) Enabled() bool      { return p.enabled }
+func (p stubProvider) CreateInvoice(context.Context, InvoiceParams) (string, int64, error) {
+	return "", 0, nil
+}
+func (p stubProvider) CheckStatus(context.Context, *database.Purchase) (StatusCheck, error) {
+	return StatusCheck{}, ErrStatusCheckUnsupported
+}
+func (p stubProvider) Cancel(context.Context, *database.Purchase) error { return nil }
+func (p stubProvider) Refund(context.Context, *database.Purchase, *database.Customer, *database.PurchaseRefund) (string, bool, error) {
+	return "", false, nil
+}
+func (p stubProvider) Webhook() (string, http.Handler) { return "", nil }
+
+func TestProviderRegistry_registerReplacesInPlace(t *testing.T) {
+	r := NewProviderRegistry()
+	r.Register(stubProvider{info: ProviderInfo{InvoiceType: "a", CheckoutKey: "key_a"}})
+	r.Register(stubProvider{info: ProviderInfo{InvoiceType: "b", CheckoutKey: "key_b"}, enabled: true})
+	r.Register(stubProvider{info: ProviderInfo{InvoiceType: "a", CheckoutKey: "key_a2"}, enabled: true})
+
+	all := r.All()
+	if len(all) != 2 || all[0].Info().CheckoutKey != "key_a2" || all[1].Info().InvoiceType != "b" {
+		t.Fatalf("unexpected order after replace: %+v", all)
+	}
+	if _, ok := r.ByCheckoutKey("key_a"); ok {
+		t.Fatal("replaced provider still resolvable by old key")
+	}
+	if p, ok := r.ByCheckoutKey("key_b"); !ok || p.Info().InvoiceType != "b" {
+		t.Fatal("key_b not resolved")
+	}
+	if _, ok := r.ByCheckoutKey(""); ok {
+		t.Fatal("empty checkout key must not match")
+	}
+	if len(r.Enabled()) != 2 {
+		t.Fatalf("expected 2 enabled providers, got %d", len(r.Enabled()))
+	}
+}
+
+func TestProviderRegistry_webhookOverride(t *testing.T) {
+	r := NewProviderRegistry()
+	p := stubProvider{info: ProviderInfo{InvoiceType: database.InvoiceTypeTribute}}
+	r.Register(p)
+	if _, h := r.WebhookHandler(p); h != nil {
+		t.Fatal("expected nil handler before override")
+	}
+	r.SetWebhookHandler(database.InvoiceTypeTribute, http.NotFoundHandler())
+	if _, h := r.WebhookHandler(p); h == nil {
+		t.Fatal("override not applied")
+	}
+}
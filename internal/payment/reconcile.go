@@ -0,0 +1,207 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/utils"
+)
+
+const (
+	// purchaseReconcileBatchLimit — сколько покупок сверяется за один запуск cron.
+	purchaseReconcileBatchLimit = 100
+	// reconcileReportMaxLines — строк в отчёте админу; остальное — «и ещё N» (лимит длины сообщения Telegram).
+	reconcileReportMaxLines = 30
+)
+
+// reconcileAction — что сверка делает с зависшей покупкой по ответу провайдера.
+type reconcileAction int
+
+const (
+	reconcileKeep reconcileAction = iota
+	// reconcileProcess — провайдер подтвердил оплату, а покупка не проведена (потерян вебхук).
+	reconcileProcess
+	// reconcileCancel — провайдер сам отменил или просрочил счёт.
+	reconcileCancel
+	// reconcileExpire — счёт не оплачен дольше PURCHASE_EXPIRE_HOURS, закрываем сами.
+	reconcileExpire
+)
+
+// reconcileActionFor выбирает действие по статусу у провайдера. canExpire — провайдер умеет аннулировать
+// счёт (InvoiceExpirer); без этого по сроку закрываются только покупки, счёт для которых так и не был
+// создан (status new): живой счёт можно оплатить и после закрытия, а вебхуки отменённые покупки пропускают.
+func reconcileActionFor(p *database.Purchase, status InvoiceStatus, expireBefore time.Time, canExpire bool) reconcileAction {
+	switch status {
+	case InvoiceStatusPaid:
+		return reconcileProcess
+	case InvoiceStatusCanceled, InvoiceStatusExpired:
+		return reconcileCancel
+	}
+	if !p.CreatedAt.Before(expireBefore) {
+		return reconcileKeep
+	}
+	if p.Status == database.PurchaseStatusNew || canExpire {
+		return reconcileExpire
+	}
+	return reconcileKeep
+}
+
+// reconcileLatePayment — оплата, которую нашла только сверка; Err — ошибка проведения.
+type reconcileLatePayment struct {
+	Purchase database.Purchase
+	Err      error
+}
+
+// ReconcileStalePurchases сверяет new/pending-покупки старше PURCHASE_RECONCILE_AFTER_MINUTES со статусом
+// у провайдера (cron в main). В отличие от PollPendingInvoices смотрит на все провайдеры с опросом статуса,
+// в том числе с настроенным вебхуком. Найденные оплаты проводит и отправляет админу отчёт.
+func (s PaymentService) ReconcileStalePurchases(ctx context.Context) {
+	after := config.PurchaseReconcileAfterMinutes()
+	if after <= 0 || s.providers == nil {
+		return
+	}
+	now := time.Now()
+	byType := make(map[database.InvoiceType]Provider)
+	var types []database.InvoiceType
+	for _, p := range s.providers.Enabled() {
+		if !p.Info().Pollable {
+			continue
+		}
+		t := p.Info().InvoiceType
+		byType[t] = p
+		types = append(types, t)
+	}
+	stale, err := s.purchaseRepository.FindStaleUnpaid(ctx, types, now.Add(-time.Duration(after)*time.Minute), purchaseReconcileBatchLimit)
+	if err != nil {
+		slog.Error("reconcile: find stale purchases", "error", err)
+		return
+	}
+	if len(stale) == 0 {
+		return
+	}
+	expireBefore := now.Add(-time.Duration(config.PurchaseExpireHours()) * time.Hour)
+
+	grouped := make(map[database.InvoiceType][]database.Purchase)
+	for _, pur := range stale {
+		grouped[pur.InvoiceType] = append(grouped[pur.InvoiceType], pur)
+	}
+	var (
+		checked []int64
+		late    []reconcileLatePayment
+	)
+	for _, t := range types {
+		group := grouped[t]
+		if len(group) == 0 {
+			continue
+		}
+		p := byType[t]
+		var batch map[int64]StatusCheck
+		if bc, ok := p.(BatchStatusChecker); ok {
+			batch, err = bc.CheckStatuses(ctx, group)
+			if err != nil {
+				slog.Error("reconcile: check statuses", "invoice_type", t, "error", err)
+				continue
+			}
+		}
+		expirer, canExpire := p.(InvoiceExpirer)
+		for i := range group {
+			purchase := &group[i]
+			var check StatusCheck
+			if batch != nil {
+				check = batch[purchase.ID]
+			} else if check, err = p.CheckStatus(ctx, purchase); err != nil {
+				slog.Error("reconcile: check status", "invoice_type", t, "purchase_id", utils.MaskHalfInt64(purchase.ID), "error", err)
+				continue
+			}
+			checked = append(checked, purchase.ID)
+
+			switch reconcileActionFor(purchase, check.Status, expireBefore, canExpire) {
+			case reconcileProcess:
+				slog.Warn("reconcile: provider reports paid, purchase was not processed",
+					"invoice_type", t, "purchase_id", utils.MaskHalfInt64(purchase.ID), "status", purchase.Status, "created_at", purchase.CreatedAt)
+				paidCtx := ctx
+				if check.PaidContext != nil {
+					paidCtx = check.PaidContext(ctx)
+				}
+				err := s.ProcessPurchaseById(paidCtx, purchase.ID)
+				if err != nil {
+					slog.Error("reconcile: process purchase", "invoice_type", t, "purchase_id", utils.MaskHalfInt64(purchase.ID), "error", err)
+				}
+				late = append(late, reconcileLatePayment{Purchase: *purchase, Err: err})
+			case reconcileCancel:
+				slog.Info("reconcile: invoice closed by provider", "invoice_type", t, "purchase_id", utils.MaskHalfInt64(purchase.ID), "status", purchase.Status)
+				if err := p.Cancel(ctx, purchase); err != nil {
+					slog.Error("reconcile: cancel purchase", "invoice_type", t, "purchase_id", utils.MaskHalfInt64(purchase.ID), "error", err)
+				}
+			case reconcileExpire:
+				if canExpire && purchase.Status != database.PurchaseStatusNew {
+					if err := expirer.ExpireInvoice(ctx, purchase); err != nil {
+						// Счёт мог быть оплачен между проверкой и удалением — покупку не трогаем, следующая сверка увидит оплату.
+						slog.Error("reconcile: expire invoice at provider", "invoice_type", t, "purchase_id", utils.MaskHalfInt64(purchase.ID), "error", err)
+						continue
+					}
+				}
+				slog.Info("reconcile: purchase expired", "invoice_type", t, "purchase_id", utils.MaskHalfInt64(purchase.ID), "status", purchase.Status, "created_at", purchase.CreatedAt)
+				if err := p.Cancel(ctx, purchase); err != nil {
+					slog.Error("reconcile: cancel expired purchase", "invoice_type", t, "purchase_id", utils.MaskHalfInt64(purchase.ID), "error", err)
+				}
+			default:
+				if purchase.CreatedAt.Before(expireBefore) {
+					slog.Warn("reconcile: invoice still pending at provider after expiry", "invoice_type", t, "purchase_id", utils.MaskHalfInt64(purchase.ID), "created_at", purchase.CreatedAt)
+				}
+			}
+		}
+	}
+	if err := s.purchaseRepository.MarkReconciled(ctx, checked, now); err != nil {
+		slog.Error("reconcile: mark purchases reconciled", "error", err)
+	}
+	if len(late) > 0 {
+		s.notifyAdminReconcileReport(ctx, late)
+	}
+}
+
+// notifyAdminReconcileReport — отчёт админу об оплатах, о которых база не знала до сверки.
+func (s PaymentService) notifyAdminReconcileReport(ctx context.Context, late []reconcileLatePayment) {
+	adminID := config.GetAdminTelegramId()
+	if s.telegramBot == nil || adminID == 0 {
+		return
+	}
+	lang := "ru"
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf(s.translation.GetText(lang, "admin_reconcile_report_title"), len(late)))
+	for i, l := range late {
+		if i == reconcileReportMaxLines {
+			sb.WriteString("\n")
+			sb.WriteString(fmt.Sprintf(s.translation.GetText(lang, "admin_reconcile_report_more"), len(late)-i))
+			break
+		}
+		result := s.translation.GetText(lang, "admin_reconcile_report_processed")
+		if l.Err != nil {
+			result = fmt.Sprintf(s.translation.GetText(lang, "admin_reconcile_report_failed"), html.EscapeString(l.Err.Error()))
+		}
+		sb.WriteString("\n")
+		sb.WriteString(fmt.Sprintf(s.translation.GetText(lang, "admin_reconcile_report_line"),
+			l.Purchase.ID,
+			string(l.Purchase.InvoiceType),
+			formatPurchaseAmount(l.Purchase.Amount, &l.Purchase),
+			l.Purchase.CustomerID,
+			result,
+		))
+	}
+	if _, err := s.telegramBot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    adminID,
+		ParseMode: models.ParseModeHTML,
+		Text:      sb.String(),
+	}); err != nil {
+		slog.Error("Failed to send reconcile report to admin", "error", err)
+	}
+}
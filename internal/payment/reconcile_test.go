@@ -0,0 +1,53 @@
+package payment
+
+import (
+	"testing"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/database"
+)
+
+func TestReconcileActionFor(t *testing.T) {
+	now := time.Now()
+	expireBefore := now.Add(-24 * time.Hour)
+	fresh := &database.Purchase{Status: database.PurchaseStatusPending, CreatedAt: now.Add(-time.Hour)}
+	old := &database.Purchase{Status: database.PurchaseStatusPending, CreatedAt: now.Add(-48 * time.Hour)}
+	oldNew := &database.Purchase{Status: database.PurchaseStatusNew, CreatedAt: now.Add(-48 * time.Hour)}
+
+	cases := []struct {
+		name      string
+		p         *database.Purchase
+		status    InvoiceStatus
+		canExpire bool
+		want      reconcileAction
+	}{
+		{"paid", old, InvoiceStatusPaid, false, reconcileProcess},
+		{"canceled", fresh, InvoiceStatusCanceled, false, reconcileCancel},
+		{"expired at provider", fresh, InvoiceStatusExpired, false, reconcileCancel},
+		{"fresh pending", fresh, InvoiceStatusPending, true, reconcileKeep},
+		{"old pending, provider can expire", old, InvoiceStatusPending, true, reconcileExpire},
+		{"old pending, live invoice kept", old, InvoiceStatusPending, false, reconcileKeep},
+		{"old new without invoice", oldNew, InvoiceStatusPending, false, reconcileExpire},
+	}
+	for _, c := range cases {
+		if got := reconcileActionFor(c.p, c.status, expireBefore, c.canExpire); got != c.want {
+			t.Fatalf("%s: got %d, want %d", c.name, got, c.want)
+		}
+	}
+}
+
+func TestFormatPurchaseAmount(t *testing.T) {
+	cases := []struct {
+		p    database.Purchase
+		want string
+	}{
+		{database.Purchase{InvoiceType: database.InvoiceTypeYookasa, Currency: "RUB"}, "150.00 RUB"},
+		{database.Purchase{InvoiceType: database.InvoiceTypeCrypto, Currency: "USDT"}, "150.00 USDT"},
+		{database.Purchase{InvoiceType: database.InvoiceTypeTelegram, Currency: "STARS"}, "150 ⭐"},
+	}
+	for _, c := range cases {
+		if got := formatPurchaseAmount(150, &c.p); got != c.want {
+			t.Fatalf("%s: got %q, want %q", c.p.InvoiceType, got, c.want)
+		}
+	}
+}
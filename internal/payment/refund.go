@@ -0,0 +1,377 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/loyalty"
+	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/utils"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+var (
+	ErrRefundPurchaseNotFound = errors.New("purchase not found")
+	// ErrRefundNotAllowed — покупка не оплачена или уже полностью возвращена.
+	ErrRefundNotAllowed    = errors.New("purchase is not refundable")
+	ErrRefundInvalidAmount = errors.New("invalid refund amount")
+	// ErrRefundPartialUnsupported — провайдер умеет только полный возврат (Telegram Stars).
+	ErrRefundPartialUnsupported = errors.New("partial refund is not supported by provider")
+	ErrRefundProviderFailed     = errors.New("provider refund failed")
+	// ErrRefundToBalanceUnsupported — на баланс возвращаются только рублёвые покупки (не пополнения и не Stars).
+	ErrRefundToBalanceUnsupported = errors.New("refund to balance is not supported for this purchase")
+)
+
+// RefundRequest — возврат, инициированный администратором (бот или кабинет).
+type RefundRequest struct {
+	PurchaseID int64
+	// Amount — сумма возврата в валюте покупки; 0 — весь невозвращённый остаток.
+	Amount          float64
+	Reason          string
+	Source          database.RefundSource
+	AdminTelegramID int64
+	AdminAccountID  int64
+	// ToBalance — зачислить сумму на внутренний баланс клиента вместо возврата через провайдера.
+	ToBalance bool
+}
+
+type RefundResult struct {
+	Refund   *database.PurchaseRefund
+	Purchase *database.Purchase
+	// ProviderRefunded — false, если у провайдера нет API и деньги нужно вернуть вручную.
+	ProviderRefunded bool
+}
+
+// refundReversal — что откатываем за конкретный возврат.
+type refundReversal struct {
+	Final        bool
+	Days         int
+	Hwid         int
+	XP           int64
+	RestorePromo bool
+}
+
+// planRefundReversal считает откат пропорционально доле возвращённой суммы.
+// Считаем от накопленной доли (до и после возврата), чтобы серия частичных возвратов
+// в сумме откатила ровно столько дней и XP, сколько дала покупка.
+// Доп. HWID и промо-скидка откатываются только финальным возвратом.
+func planRefundReversal(p *database.Purchase, amount float64, daysInMonth int, xpTotal int64) refundReversal {
+	prevFrac, newFrac := 1.0, 1.0
+	if p.Amount > 0 {
+		prevFrac = math.Min(p.RefundedAmount/p.Amount, 1)
+		newFrac = math.Min((p.RefundedAmount+amount)/p.Amount, 1)
+	}
+	final := database.RefundStatusAfter(p.Amount, p.RefundedAmount+amount) == database.PurchaseStatusRefunded
+	if final {
+		newFrac = 1
+	}
+
+	out := refundReversal{Final: final}
+	// Дни подарка начисляются получателю, а не покупателю: при возврате отзывается неактивированный код.
+	if p.Month > 0 && p.PurchaseKind != database.PurchaseKindGift {
+		totalDays := p.Month * daysInMonth
+		out.Days = int(math.Floor(float64(totalDays)*newFrac+1e-9)) - int(math.Floor(float64(totalDays)*prevFrac+1e-9))
+	}
+	if xpTotal > 0 {
+		out.XP = int64(math.Floor(float64(xpTotal)*newFrac+1e-9)) - int64(math.Floor(float64(xpTotal)*prevFrac+1e-9))
+	}
+	if final {
+		out.Hwid = p.ExtraHwid
+		out.RestorePromo = p.PromoCodeID != nil && *p.PromoCodeID > 0
+	}
+	return out
+}
+
+// RefundPurchase возвращает деньги через провайдера (где есть API) и откатывает эффекты ProcessPurchaseById:
+// дни подписки, доп. HWID, XP лояльности и израсходованную промо-скидку.
+func (s PaymentService) RefundPurchase(ctx context.Context, req RefundRequest) (*RefundResult, error) {
+	if s.refundRepository == nil {
+		return nil, fmt.Errorf("refund repository not configured")
+	}
+	purchase, err := s.purchaseRepository.FindById(ctx, req.PurchaseID)
+	if err != nil {
+		return nil, err
+	}
+	if purchase == nil {
+		return nil, ErrRefundPurchaseNotFound
+	}
+	if purchase.Status != database.PurchaseStatusPaid && purchase.Status != database.PurchaseStatusPartiallyRefunded {
+		return nil, ErrRefundNotAllowed
+	}
+	rest := database.RefundableRemainder(purchase)
+	if rest <= 0 {
+		return nil, ErrRefundNotAllowed
+	}
+	amount := req.Amount
+	if amount <= 0 {
+		amount = rest
+	}
+	if amount > rest+0.005 {
+		return nil, ErrRefundInvalidAmount
+	}
+	if amount > rest {
+		amount = rest
+	}
+	if purchase.InvoiceType == database.InvoiceTypeTelegram && (purchase.RefundedAmount > 0 || amount+0.005 < purchase.Amount) {
+		return nil, ErrRefundPartialUnsupported
+	}
+	isTopUp := purchase.PurchaseKind == database.PurchaseKindBalanceTopUp
+	if req.ToBalance && (!config.BalanceEnabled() || isTopUp || !purchaseCurrencyRubForMoynalog(purchase)) {
+		return nil, ErrRefundToBalanceUnsupported
+	}
+
+	customer, err := s.customerRepository.FindById(ctx, purchase.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	if customer == nil {
+		return nil, ErrCustomerNotFound
+	}
+
+	refund := &database.PurchaseRefund{
+		PurchaseID: purchase.ID,
+		CustomerID: customer.ID,
+		Amount:     amount,
+		Currency:   purchase.Currency,
+		Source:     req.Source,
+	}
+	if r := strings.TrimSpace(req.Reason); r != "" {
+		refund.Reason = &r
+	}
+	if req.AdminTelegramID != 0 {
+		refund.AdminTelegramID = &req.AdminTelegramID
+	}
+	if req.AdminAccountID != 0 {
+		refund.AdminAccountID = &req.AdminAccountID
+	}
+	refund.ID, err = s.refundRepository.CreatePending(ctx, refund)
+	if err != nil {
+		return nil, err
+	}
+
+	if isTopUp {
+		// Возврат пополнения: сначала снимаем сумму с баланса, иначе клиент успеет её потратить.
+		if err := s.debitTopUpForRefund(ctx, customer, refund); err != nil {
+			if mfErr := s.refundRepository.MarkFailed(ctx, refund.ID, err); mfErr != nil {
+				slog.Error("refund: mark failed", "error", mfErr, "refund_id", refund.ID)
+			}
+			return nil, err
+		}
+	}
+
+	var providerRefundID string
+	var providerRefunded bool
+	if req.ToBalance {
+		providerRefundID, err = s.refundToBalance(ctx, customer, refund)
+		providerRefunded = err == nil
+	} else {
+		providerRefundID, providerRefunded, err = s.refundAtProvider(ctx, purchase, customer, refund)
+	}
+	if err != nil {
+		if isTopUp {
+			s.restoreTopUpAfterFailedRefund(ctx, customer, refund)
+		}
+		if mfErr := s.refundRepository.MarkFailed(ctx, refund.ID, err); mfErr != nil {
+			slog.Error("refund: mark failed", "error", mfErr, "refund_id", refund.ID)
+		}
+		slog.Error("refund: provider call failed", "error", err, "purchase_id", utils.MaskHalfInt64(purchase.ID), "invoice_type", purchase.InvoiceType)
+		return nil, fmt.Errorf("%w: %v", ErrRefundProviderFailed, err)
+	}
+
+	plan := planRefundReversal(purchase, amount, config.DaysInMonth(), s.loyaltyXPForRefund(purchase))
+
+	newStatus, err := s.purchaseRepository.ApplyRefundToPurchase(ctx, purchase.ID, amount)
+	if err != nil {
+		// Деньги уже ушли у провайдера — фиксируем строку, чтобы админ увидел расхождение.
+		_ = s.refundRepository.MarkFailed(ctx, refund.ID, err)
+		return nil, err
+	}
+	expireBefore := customer.ExpireAt
+	s.reverseRefundEffects(ctx, purchase, customer, &plan)
+	s.reverseReferralCommission(ctx, purchase, purchase.RefundedAmount+amount, plan.Final)
+	if plan.Final && purchase.PurchaseKind == database.PurchaseKindGift {
+		s.revokeGiftAfterRefund(ctx, purchase)
+	}
+	if plan.Final && purchase.PurchaseKind == database.PurchaseKindTrafficPack {
+		s.revokeTrafficPackAfterRefund(ctx, purchase, customer)
+	}
+	if plan.Final {
+		s.cancelMoynalogReceipt(ctx, purchase)
+		s.cancelStarsSubscriptionAfterRefund(ctx, purchase, customer)
+	}
+
+	status := database.RefundStatusSucceeded
+	if !providerRefunded {
+		status = database.RefundStatusManual
+	}
+	now := time.Now().UTC()
+	updates := map[string]interface{}{
+		"status":         status,
+		"days_revoked":   plan.Days,
+		"hwid_revoked":   plan.Hwid,
+		"xp_revoked":     plan.XP,
+		"promo_restored": plan.RestorePromo,
+		"completed_at":   now,
+	}
+	if providerRefundID != "" {
+		updates["provider_refund_id"] = providerRefundID
+	}
+	if err := s.refundRepository.UpdateFields(ctx, refund.ID, updates); err != nil {
+		slog.Error("refund: update row", "error", err, "refund_id", refund.ID)
+	}
+
+	purchase.Status = newStatus
+	purchase.RefundedAmount += amount
+	purchase.RefundedAt = &now
+	refund.Status = status
+	refund.DaysRevoked = plan.Days
+	refund.HwidRevoked = plan.Hwid
+	refund.XPRevoked = plan.XP
+	refund.PromoRestored = plan.RestorePromo
+	refund.CompletedAt = &now
+	if providerRefundID != "" {
+		refund.ProviderRefundID = &providerRefundID
+	}
+
+	slog.Info("purchase refunded", "purchase_id", utils.MaskHalfInt64(purchase.ID), "amount", amount, "status", newStatus,
+		"provider_refunded", providerRefunded, "days", plan.Days, "hwid", plan.Hwid, "xp", plan.XP, "source", req.Source)
+
+	s.notifyCustomerRefund(ctx, customer, purchase, amount)
+	if fresh, err := s.customerRepository.FindById(ctx, customer.ID); err == nil && fresh != nil {
+		customer = fresh
+	}
+	s.tryNotifyPurchaseRefund(ctx, purchase, refund, customer, expireBefore, customer.ExpireAt)
+
+	return &RefundResult{Refund: refund, Purchase: purchase, ProviderRefunded: providerRefunded}, nil
+}
+
+// RefundSupported — true, если возврат уходит через API провайдера (ЮKassa, Telegram Stars).
+func (s PaymentService) RefundSupported(t database.InvoiceType) bool {
+	p, err := s.providerFor(t)
+	return err == nil && p.Info().Refundable
+}
+
+// refundAtProvider вызывает API возврата провайдера. Провайдеры без интеграции возврата (Platega,
+// CryptoPay, Tribute) отвечают refunded=false — деньги админ возвращает в кабинете провайдера.
+func (s PaymentService) refundAtProvider(ctx context.Context, p *database.Purchase, c *database.Customer, refund *database.PurchaseRefund) (providerRefundID string, providerRefunded bool, err error) {
+	provider, err := s.providerFor(p.InvoiceType)
+	if err != nil {
+		return "", false, nil
+	}
+	return provider.Refund(ctx, p, c, refund)
+}
+
+func (s PaymentService) loyaltyXPForRefund(p *database.Purchase) int64 {
+	if !config.LoyaltyEnabled() {
+		return 0
+	}
+	return loyalty.XPRubEquivalentForPurchase(p)
+}
+
+// reverseRefundEffects — откат эффектов оплаты. Ошибки логируются: деньги уже возвращены,
+// оставшийся ручной откат админ увидит по полям строки purchase_refund.
+func (s PaymentService) reverseRefundEffects(ctx context.Context, p *database.Purchase, c *database.Customer, plan *refundReversal) {
+	rwCtx := s.withRemnawavePanelUsername(s.ctxWithTelegramUsernameIfMissing(ctx, c), c)
+
+	if plan.Days > 0 {
+		user, err := s.remnawaveClient.ShrinkSubscriptionByDaysPreserveSquads(rwCtx, c.ID, c.TelegramID, plan.Days)
+		if err != nil {
+			slog.Error("refund: shrink subscription", "error", err, "customer_id", utils.MaskHalfInt64(c.ID))
+			plan.Days = 0
+		} else if err := s.customerRepository.UpdateFields(ctx, c.ID, map[string]interface{}{
+			"subscription_link": user.SubscriptionUrl,
+			"expire_at":         user.ExpireAt,
+		}); err != nil {
+			slog.Error("refund: sync customer expire", "error", err, "customer_id", utils.MaskHalfInt64(c.ID))
+		}
+	}
+
+	if plan.Hwid > 0 {
+		if err := s.revertExtraHwid(ctx, c, plan.Hwid); err != nil {
+			slog.Error("refund: revert extra hwid", "error", err, "customer_id", utils.MaskHalfInt64(c.ID))
+			plan.Hwid = 0
+		}
+	}
+
+	if plan.XP > 0 {
+		if err := s.customerRepository.DecrementLoyaltyXP(ctx, c.ID, plan.XP); err != nil {
+			slog.Error("refund: decrement loyalty xp", "error", err, "customer_id", utils.MaskHalfInt64(c.ID))
+			plan.XP = 0
+		}
+	}
+
+	if plan.RestorePromo {
+		restored := false
+		if s.promoService != nil {
+			var err error
+			restored, err = s.promoService.RestoreDiscountAfterRefund(ctx, p, c.ID)
+			if err != nil {
+				slog.Error("refund: restore promo discount", "error", err, "customer_id", utils.MaskHalfInt64(c.ID))
+			}
+		}
+		plan.RestorePromo = restored
+	}
+}
+
+// revertExtraHwid снимает купленные слоты: лимит в панели и extra_hwid в БД.
+func (s PaymentService) revertExtraHwid(ctx context.Context, c *database.Customer, delta int) error {
+	user, err := s.remnawaveClient.FindUserForAdminCustomer(ctx, c.ID, c.TelegramID, c.SubscriptionLink, c.IsWebOnly)
+	if err != nil && !errors.Is(err, remnawave.ErrUserNotFound) {
+		return err
+	}
+	if user != nil {
+		newLimit := resolveDeviceLimit(user) - delta
+		if newLimit < 1 {
+			newLimit = 1
+		}
+		if _, err := s.remnawaveClient.PatchUser(ctx, &remnawave.UpdateUserRequest{
+			UUID:            &user.UUID,
+			HwidDeviceLimit: &newLimit,
+		}); err != nil {
+			return err
+		}
+	}
+	newExtra := c.ExtraHwid - delta
+	updates := map[string]interface{}{"extra_hwid": newExtra}
+	if newExtra <= 0 {
+		updates["extra_hwid"] = 0
+		updates["extra_hwid_expires_at"] = nil
+	}
+	return s.customerRepository.UpdateFields(ctx, c.ID, updates)
+}
+
+func (s PaymentService) notifyCustomerRefund(ctx context.Context, c *database.Customer, p *database.Purchase, amount float64) {
+	if s.telegramBot == nil || skipTelegramCustomerDM(c) {
+		return
+	}
+	text := fmt.Sprintf(s.translation.GetText(c.Language, "purchase_refunded_notify"), p.ID, formatPurchaseAmount(amount, p))
+	_, err := s.telegramBot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    c.TelegramID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	})
+	if err != nil {
+		slog.Warn("refund: customer notify", "error", err, "customer_id", utils.MaskHalfInt64(c.ID))
+	}
+}
+
+// formatPurchaseAmount — сумма в валюте покупки (Stars — в звёздах).
+func formatPurchaseAmount(amount float64, p *database.Purchase) string {
+	if p.InvoiceType == database.InvoiceTypeTelegram {
+		return fmt.Sprintf("%d ⭐", int(math.Round(amount)))
+	}
+	cur := strings.TrimSpace(p.Currency)
+	if cur == "" {
+		cur = "RUB"
+	}
+	return fmt.Sprintf("%.2f %s", amount, cur)
+}
@@ -0,0 +1,54 @@
+package payment
+
+import (
+	"testing"
+
+	"remnawave-tg-shop-bot/internal/database"
+)
+
+func TestPlanRefundReversal_full(t *testing.T) {
+	promoID := int64(7)
+	p := &database.Purchase{Amount: 300, Month: 3, ExtraHwid: 2, PromoCodeID: &promoID}
+	plan := planRefundReversal(p, 300, 30, 300)
+	if !plan.Final || plan.Days != 90 || plan.XP != 300 || plan.Hwid != 2 || !plan.RestorePromo {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+}
+
+func TestPlanRefundReversal_partialSeriesSumsToTotal(t *testing.T) {
+	// 100 ₽ за месяц тремя возвратами: 33.33 + 33.33 + 33.34 — в сумме ровно 30 дн. и 100 XP.
+	p := &database.Purchase{Amount: 100, Month: 1, ExtraHwid: 1}
+	var days int
+	var xp int64
+	for i, amount := range []float64{33.33, 33.33, 33.34} {
+		plan := planRefundReversal(p, amount, 30, 100)
+		if plan.Final != (i == 2) {
+			t.Fatalf("step %d: final=%v", i, plan.Final)
+		}
+		if !plan.Final && plan.Hwid != 0 {
+			t.Fatalf("step %d: hwid reverted before final refund", i)
+		}
+		days += plan.Days
+		xp += plan.XP
+		p.RefundedAmount += amount
+	}
+	if days != 30 || xp != 100 {
+		t.Fatalf("days=%d xp=%d want 30/100", days, xp)
+	}
+}
+
+func TestPlanRefundReversal_hwidOnlyPurchase(t *testing.T) {
+	p := &database.Purchase{Amount: 50, ExtraHwid: 1}
+	plan := planRefundReversal(p, 50, 30, 0)
+	if plan.Days != 0 || plan.Hwid != 1 || plan.RestorePromo {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+}
+
+func TestPlanRefundReversal_giftKeepsBuyerDays(t *testing.T) {
+	p := &database.Purchase{Amount: 300, Month: 3, PurchaseKind: database.PurchaseKindGift}
+	plan := planRefundReversal(p, 300, 30, 300)
+	if !plan.Final || plan.Days != 0 || plan.XP != 300 {
+		t.Fatalf("unexpected plan: %+v", plan)
+	}
+}
@@ -0,0 +1,21 @@
+package payment
+
+import "crypto/rand"
+
+// Алфавит без похожих символов (0/O, 1/I): код удобно продиктовать. 32 символа — байт по модулю без перекоса.
+const (
+	shareCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	shareCodeLength   = 10
+)
+
+// generateShareCode — случайный код для ссылок-приглашений (подарки, семья). Уникальность проверяет вызывающий.
+func generateShareCode() (string, error) {
+	buf := make([]byte, shareCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = shareCodeAlphabet[int(b)%len(shareCodeAlphabet)]
+	}
+	return string(buf), nil
+}
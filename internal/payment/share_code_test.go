@@ -0,0 +1,28 @@
+package payment
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateShareCode(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		code, err := generateShareCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != shareCodeLength {
+			t.Fatalf("len(%q) = %d", code, len(code))
+		}
+		for _, r := range code {
+			if !strings.ContainsRune(shareCodeAlphabet, r) {
+				t.Fatalf("unexpected rune %q in %q", r, code)
+			}
+		}
+		if seen[code] {
+			t.Fatalf("duplicate code %q", code)
+		}
+		seen[code] = true
+	}
+}
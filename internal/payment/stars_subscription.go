@@ -0,0 +1,273 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-telegram/bot"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/utils"
+)
+
+const (
+	// starsSubscriptionPeriod — subscription_period счёта: Telegram принимает только 30 дней (2592000 секунд).
+	starsSubscriptionPeriod = 30 * 24 * 60 * 60
+	// starsSubscriptionMaxAmount — максимальная цена подписки Stars за период.
+	starsSubscriptionMaxAmount = 10000
+	// starsSubscriptionExpireGrace — сколько ждём опоздавшее списание, прежде чем считать подписку истёкшей.
+	starsSubscriptionExpireGrace = 24 * time.Hour
+)
+
+var (
+	// ErrStarsSubscriptionNotFound — подписки нет или она чужая.
+	ErrStarsSubscriptionNotFound = errors.New("stars subscription not found")
+	// ErrStarsSubscriptionExpired — подписка уже истекла: возобновить её нельзя, нужна новая оплата.
+	ErrStarsSubscriptionExpired = errors.New("stars subscription expired")
+	// ErrStarsSubscriptionUnavailable — подписку Stars нельзя оформить на эту покупку (период, цена, смена тарифа).
+	ErrStarsSubscriptionUnavailable = errors.New("stars subscription is not available for this purchase")
+)
+
+type starsSubscriptionCtxKey struct{}
+
+// WithStarsSubscription просит выставить счёт Stars подпиской с ежемесячным списанием.
+func WithStarsSubscription(ctx context.Context) context.Context {
+	return context.WithValue(ctx, starsSubscriptionCtxKey{}, true)
+}
+
+func starsSubscriptionRequested(ctx context.Context) bool {
+	v, _ := ctx.Value(starsSubscriptionCtxKey{}).(bool)
+	return v
+}
+
+// StarsSubscriptionOffered — показывать ли оплату Stars с автопродлением для периода months.
+// Подписка Telegram списывает одну и ту же сумму раз в 30 дней, поэтому только месячный период.
+func (s PaymentService) StarsSubscriptionOffered(months int) bool {
+	return config.IsTelegramStarsEnabled() && config.TelegramStarsSubscriptionEnabled() &&
+		s.starsSubscriptionRepository != nil && months == 1
+}
+
+// starsSubscriptionAllowed — можно ли выставить счёт подпиской: смена тарифа с бонусными днями
+// разовая, продления же проводятся обычной подпиской по цене первого счёта.
+func (s PaymentService) starsSubscriptionAllowed(months int, amount float64, extras *TariffPurchaseExtras) bool {
+	if !s.StarsSubscriptionOffered(months) || amount <= 0 || amount > starsSubscriptionMaxAmount {
+		return false
+	}
+	return extras == nil || (extras.Kind == database.PurchaseKindSubscription && !extras.IsEarlyDowngrade)
+}
+
+// ProcessStarsPayment проводит successful_payment Stars. Первая оплата подписки заводит stars_subscription,
+// очередное списание (is_recurring без is_first_recurring, payload первого счёта) — новую покупку продления.
+func (s PaymentService) ProcessStarsPayment(ctx context.Context, purchaseID int64, meta StarsNotifyMeta) error {
+	ctx = WithStarsNotifyMeta(ctx, meta)
+	if meta.IsRecurring && !meta.IsFirstRecurring {
+		return s.renewStarsSubscription(ctx, purchaseID, meta)
+	}
+	if meta.IsRecurring {
+		if _, err := s.startStarsSubscription(ctx, purchaseID, meta); err != nil {
+			// Оплата уже списана: покупку проводим, подписка заведётся с первым продлением.
+			slog.Error("stars subscription: start", "error", err, "purchase_id", utils.MaskHalfInt64(purchaseID))
+		}
+	}
+	return s.ProcessPurchaseById(ctx, purchaseID)
+}
+
+// startStarsSubscription заводит подписку по покупке, оплатившей первый период (повтор вернёт существующую).
+func (s PaymentService) startStarsSubscription(ctx context.Context, purchaseID int64, meta StarsNotifyMeta) (*database.StarsSubscription, error) {
+	if s.starsSubscriptionRepository == nil {
+		return nil, fmt.Errorf("stars subscriptions are not configured")
+	}
+	purchase, err := s.purchaseRepository.FindById(ctx, purchaseID)
+	if err != nil {
+		return nil, err
+	}
+	if purchase == nil {
+		return nil, fmt.Errorf("purchase %s not found", utils.MaskHalfInt64(purchaseID))
+	}
+	chargeID := strings.TrimSpace(meta.TelegramPaymentChargeID)
+	if purchase.TelegramChargeID != nil && *purchase.TelegramChargeID != "" {
+		chargeID = *purchase.TelegramChargeID
+	}
+	amount := meta.TotalAmount
+	if amount <= 0 {
+		amount = int(purchase.Amount)
+	}
+	sub, err := s.starsSubscriptionRepository.Create(ctx, &database.StarsSubscription{
+		CustomerID:       purchase.CustomerID,
+		PurchaseID:       purchase.ID,
+		TelegramChargeID: chargeID,
+		Amount:           amount,
+		Months:           purchase.Month,
+		TariffID:         purchase.TariffID,
+		ExtraHwid:        purchase.ExtraHwid,
+		ExpiresAt:        starsSubscriptionExpiresAt(meta, time.Now().UTC()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("stars subscription started", "subscription_id", sub.ID, "customer_id", utils.MaskHalfInt64(sub.CustomerID), "expires_at", sub.ExpiresAt)
+	return sub, nil
+}
+
+// renewStarsSubscription проводит очередное списание подписки, начатой покупкой originID.
+// Повторная доставка того же списания (тот же charge id) покупку не дублирует.
+func (s PaymentService) renewStarsSubscription(ctx context.Context, originID int64, meta StarsNotifyMeta) error {
+	if s.starsSubscriptionRepository == nil {
+		return fmt.Errorf("stars subscriptions are not configured")
+	}
+	chargeID := strings.TrimSpace(meta.TelegramPaymentChargeID)
+	if chargeID == "" {
+		return fmt.Errorf("stars renewal without telegram charge id")
+	}
+	if existing, err := s.purchaseRepository.FindByTelegramChargeID(ctx, chargeID); err != nil {
+		return err
+	} else if existing != nil {
+		return s.ProcessPurchaseById(ctx, existing.ID)
+	}
+
+	sub, err := s.starsSubscriptionRepository.FindByPurchaseID(ctx, originID)
+	if err != nil {
+		return err
+	}
+	if sub == nil {
+		// Первая оплата прошла до включения подписок или её не удалось записать — восстанавливаем по исходной покупке.
+		if sub, err = s.startStarsSubscription(ctx, originID, StarsNotifyMeta{TotalAmount: meta.TotalAmount}); err != nil {
+			return err
+		}
+	}
+
+	subID := sub.ID
+	renewal := &database.Purchase{
+		InvoiceType:         database.InvoiceTypeTelegram,
+		Status:              database.PurchaseStatusPending,
+		Amount:              float64(meta.TotalAmount),
+		Currency:            "STARS",
+		CustomerID:          sub.CustomerID,
+		Month:               sub.Months,
+		ExtraHwid:           sub.ExtraHwid,
+		TariffID:            sub.TariffID,
+		PurchaseKind:        database.PurchaseKindSubscription,
+		IsAutoRenew:         true,
+		TelegramChargeID:    &chargeID,
+		StarsSubscriptionID: &subID,
+	}
+	renewalID, err := s.purchaseRepository.Create(ctx, renewal)
+	if errors.Is(err, database.ErrPurchaseTelegramChargeTaken) {
+		existing, ferr := s.purchaseRepository.FindByTelegramChargeID(ctx, chargeID)
+		if ferr != nil || existing == nil {
+			return fmt.Errorf("stars renewal purchase vanished after conflict: %v", ferr)
+		}
+		return s.ProcessPurchaseById(ctx, existing.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("create stars renewal purchase: %w", err)
+	}
+	now := time.Now().UTC()
+	if err := s.starsSubscriptionRepository.RecordRenewal(ctx, sub.ID, starsSubscriptionExpiresAt(meta, now), now); err != nil {
+		slog.Error("stars subscription: record renewal", "error", err, "subscription_id", sub.ID)
+	}
+	slog.Info("stars subscription renewed", "subscription_id", sub.ID, "purchase_id", utils.MaskHalfInt64(renewalID), "amount", meta.TotalAmount)
+	return s.ProcessPurchaseById(ctx, renewalID)
+}
+
+// starsSubscriptionExpiresAt — конец оплаченного периода из subscription_expiration_date (или now + период).
+func starsSubscriptionExpiresAt(meta StarsNotifyMeta, now time.Time) time.Time {
+	if meta.SubscriptionExpirationDate > 0 {
+		return time.Unix(int64(meta.SubscriptionExpirationDate), 0).UTC()
+	}
+	return now.Add(starsSubscriptionPeriod * time.Second)
+}
+
+// StarsSubscriptions — подписки Stars клиента для бота и админки.
+func (s PaymentService) StarsSubscriptions(ctx context.Context, customerID int64) ([]database.StarsSubscription, error) {
+	if s.starsSubscriptionRepository == nil {
+		return nil, nil
+	}
+	return s.starsSubscriptionRepository.ListByCustomer(ctx, customerID)
+}
+
+// SetStarsSubscriptionCanceled отменяет (canceled=true) или возобновляет продление подписки клиента в Telegram.
+// Оплаченный период после отмены не сгорает: Telegram просто не спишет следующий.
+func (s PaymentService) SetStarsSubscriptionCanceled(ctx context.Context, customer *database.Customer, subscriptionID int64, canceled bool) (*database.StarsSubscription, error) {
+	if s.starsSubscriptionRepository == nil {
+		return nil, ErrStarsSubscriptionNotFound
+	}
+	sub, err := s.starsSubscriptionRepository.FindByID(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil || sub.CustomerID != customer.ID {
+		return nil, ErrStarsSubscriptionNotFound
+	}
+	if sub.Status == database.StarsSubscriptionStatusExpired {
+		return sub, ErrStarsSubscriptionExpired
+	}
+	if err := s.editStarsSubscription(ctx, customer, sub, canceled); err != nil {
+		return nil, err
+	}
+	if _, err := s.starsSubscriptionRepository.SetCanceled(ctx, sub.ID, canceled, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	slog.Info("stars subscription toggled", "subscription_id", sub.ID, "customer_id", utils.MaskHalfInt64(customer.ID), "canceled", canceled)
+	return s.starsSubscriptionRepository.FindByID(ctx, sub.ID)
+}
+
+func (s PaymentService) editStarsSubscription(ctx context.Context, customer *database.Customer, sub *database.StarsSubscription, canceled bool) error {
+	if s.telegramBot == nil {
+		return fmt.Errorf("telegram bot is not configured")
+	}
+	ok, err := s.telegramBot.EditUserStarSubscription(ctx, &bot.EditUserStarSubscriptionParams{
+		UserID:                  customer.TelegramID,
+		TelegramPaymentChargeID: sub.TelegramChargeID,
+		IsCanceled:              canceled,
+	})
+	if err != nil {
+		return fmt.Errorf("editUserStarSubscription: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("editUserStarSubscription returned false")
+	}
+	return nil
+}
+
+// cancelStarsSubscriptionAfterRefund отменяет продление подписки, чью оплату вернули полностью.
+func (s PaymentService) cancelStarsSubscriptionAfterRefund(ctx context.Context, purchase *database.Purchase, customer *database.Customer) {
+	if s.starsSubscriptionRepository == nil || purchase.StarsSubscriptionID == nil {
+		return
+	}
+	sub, err := s.starsSubscriptionRepository.FindByID(ctx, *purchase.StarsSubscriptionID)
+	if err != nil || sub == nil || sub.Status != database.StarsSubscriptionStatusActive {
+		if err != nil {
+			slog.Error("refund: find stars subscription", "error", err, "purchase_id", utils.MaskHalfInt64(purchase.ID))
+		}
+		return
+	}
+	if err := s.editStarsSubscription(ctx, customer, sub, true); err != nil {
+		slog.Error("refund: cancel stars subscription", "error", err, "subscription_id", sub.ID)
+		return
+	}
+	if _, err := s.starsSubscriptionRepository.SetCanceled(ctx, sub.ID, true, time.Now().UTC()); err != nil {
+		slog.Error("refund: mark stars subscription canceled", "error", err, "subscription_id", sub.ID)
+	}
+}
+
+// ExpireStarsSubscriptions помечает истёкшими подписки, продление которых так и не пришло (cron в main):
+// отмену в настройках Telegram бот не видит, её признак — пропущенное списание.
+func (s PaymentService) ExpireStarsSubscriptions(ctx context.Context) {
+	if s.starsSubscriptionRepository == nil {
+		return
+	}
+	n, err := s.starsSubscriptionRepository.ExpireLapsed(ctx, time.Now().UTC().Add(-starsSubscriptionExpireGrace))
+	if err != nil {
+		slog.Error("stars subscription: expire lapsed", "error", err)
+		return
+	}
+	if n > 0 {
+		slog.Info("stars subscriptions expired", "count", n)
+	}
+}
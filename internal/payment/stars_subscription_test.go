@@ -0,0 +1,17 @@
+package payment
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStarsSubscriptionExpiresAt(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	exp := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+	if got := starsSubscriptionExpiresAt(StarsNotifyMeta{SubscriptionExpirationDate: int(exp.Unix())}, now); !got.Equal(exp) {
+		t.Fatalf("with expiration date: got %v, want %v", got, exp)
+	}
+	if got := starsSubscriptionExpiresAt(StarsNotifyMeta{}, now); !got.Equal(now.Add(30 * 24 * time.Hour)) {
+		t.Fatalf("without expiration date: got %v, want now + 30 days", got)
+	}
+}
@@ -28,6 +28,8 @@ const (
 type TariffPurchaseExtras struct {
 	Kind             database.PurchaseKind
 	IsEarlyDowngrade bool
+	// TrafficGB — объём пакета трафика (Kind == PurchaseKindTrafficPack).
+	TrafficGB int
 }
 
 // ResolveTariffPurchase определяет сумму к оплате и вид покупки. invoiceStars — оплата в Telegram Stars.
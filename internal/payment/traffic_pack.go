@@ -0,0 +1,389 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/remnawave"
+	"remnawave-tg-shop-bot/utils"
+)
+
+var (
+	ErrTrafficPacksDisabled      = errors.New("traffic packs are disabled")
+	ErrTrafficPackNotFound       = errors.New("traffic pack is not for sale")
+	ErrTrafficPackNoSubscription = errors.New("no active tariff subscription for traffic pack")
+	ErrTrafficPackUnlimited      = errors.New("subscription traffic is unlimited")
+)
+
+const (
+	trafficPackBytesPerGB    int64 = 1 << 30
+	trafficPackRollbackBatch       = 100
+)
+
+// TrafficPackBytes — объём пакета в байтах.
+func TrafficPackBytes(trafficGB int) int64 {
+	return int64(trafficGB) * trafficPackBytesPerGB
+}
+
+// TrafficPackOffer — пакеты тарифа клиента и текущий лимит трафика в панели.
+// ExtraBytes — действующие (ещё не откатанные) пакеты, уже входящие в LimitBytes.
+type TrafficPackOffer struct {
+	Tariff      *database.Tariff
+	Packs       []database.TariffTrafficPack
+	LimitBytes  int64
+	UsedBytes   int64
+	ExtraBytes  int64
+	Strategy    string
+	LastResetAt *time.Time
+}
+
+// Exhausted — трафик текущего периода израсходован.
+func (o *TrafficPackOffer) Exhausted() bool {
+	return o.LimitBytes > 0 && o.UsedBytes >= o.LimitBytes
+}
+
+// TrafficPackPrice — цена пакета в валюте счёта: рубли или Stars (без своей цены — по RUB_PER_STAR).
+func TrafficPackPrice(pack database.TariffTrafficPack, invoiceType database.InvoiceType) (int, error) {
+	if invoiceType != database.InvoiceTypeTelegram {
+		return pack.PriceRub, nil
+	}
+	if pack.PriceStars > 0 {
+		return pack.PriceStars, nil
+	}
+	if config.RubPerStar() > 0 {
+		return int(math.Ceil(float64(pack.PriceRub) / config.RubPerStar())), nil
+	}
+	return 0, ErrTrafficPackNotFound
+}
+
+// HasTrafficPacks — показывать ли клиенту кнопку пакетов трафика (без запроса в панель).
+func (s PaymentService) HasTrafficPacks(ctx context.Context, customer *database.Customer) bool {
+	_, _, err := s.trafficPackTariff(ctx, customer)
+	if err != nil && !errors.Is(err, ErrTrafficPacksDisabled) && !errors.Is(err, ErrTrafficPackNoSubscription) &&
+		!errors.Is(err, ErrTrafficPackUnlimited) && !errors.Is(err, ErrTrafficPackNotFound) {
+		slog.Error("traffic packs: load tariff packs", "error", err, "customer_id", utils.MaskHalfInt64(customer.ID))
+	}
+	return err == nil
+}
+
+// trafficPackTariff — тариф клиента и его пакеты трафика. Пакеты продаются только в режиме tariffs,
+// при действующей подписке на тарифе с ограниченным трафиком.
+func (s PaymentService) trafficPackTariff(ctx context.Context, customer *database.Customer) (*database.Tariff, []database.TariffTrafficPack, error) {
+	if !config.TrafficPacksEnabled() || s.trafficPackRepository == nil || s.tariffRepository == nil || config.SalesMode() != "tariffs" {
+		return nil, nil, ErrTrafficPacksDisabled
+	}
+	if customer == nil || customer.ExpireAt == nil || !customer.ExpireAt.After(time.Now()) || customer.PausedUntil != nil ||
+		customer.CurrentTariffID == nil || *customer.CurrentTariffID <= 0 {
+		return nil, nil, ErrTrafficPackNoSubscription
+	}
+	tariff, err := s.tariffRepository.GetByID(ctx, *customer.CurrentTariffID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tariff == nil {
+		return nil, nil, ErrTrafficPackNoSubscription
+	}
+	if tariff.TrafficLimitBytes <= 0 {
+		return nil, nil, ErrTrafficPackUnlimited
+	}
+	packs, err := s.tariffRepository.ListTrafficPacks(ctx, tariff.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(packs) == 0 {
+		return nil, nil, ErrTrafficPackNotFound
+	}
+	return tariff, packs, nil
+}
+
+// TrafficPackOffer — пакеты трафика, которые клиент может купить сейчас, и лимит в панели.
+func (s PaymentService) TrafficPackOffer(ctx context.Context, customer *database.Customer) (*TrafficPackOffer, error) {
+	tariff, packs, err := s.trafficPackTariff(ctx, customer)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.remnawaveClient.FindUserForCustomer(ctx, customer.ID, customer.TelegramID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrTrafficPackNoSubscription
+	}
+	if user.TrafficLimitBytes <= 0 {
+		return nil, ErrTrafficPackUnlimited
+	}
+	active, err := s.trafficPackRepository.ListActive(ctx, customer.ID)
+	if err != nil {
+		return nil, err
+	}
+	offer := &TrafficPackOffer{
+		Tariff:      tariff,
+		Packs:       packs,
+		LimitBytes:  user.TrafficLimitBytes,
+		UsedBytes:   int64(user.UserTraffic.UsedTrafficBytes),
+		Strategy:    user.TrafficLimitStrategy,
+		LastResetAt: user.LastTrafficResetAt,
+	}
+	for _, p := range active {
+		offer.ExtraBytes += p.Bytes
+	}
+	return offer, nil
+}
+
+// CreateTrafficPackPurchase выставляет счёт на пакет трафика тарифа клиента. Цена берётся из tariff_traffic_pack,
+// а не из callback; объём фиксируется в покупке.
+func (s PaymentService) CreateTrafficPackPurchase(ctx context.Context, customer *database.Customer, invoiceType database.InvoiceType, trafficGB int) (url string, purchaseId int64, err error) {
+	offer, err := s.TrafficPackOffer(ctx, customer)
+	if err != nil {
+		return "", 0, err
+	}
+	var pack *database.TariffTrafficPack
+	for i := range offer.Packs {
+		if offer.Packs[i].TrafficGB == trafficGB {
+			pack = &offer.Packs[i]
+			break
+		}
+	}
+	if pack == nil {
+		return "", 0, ErrTrafficPackNotFound
+	}
+	price, err := TrafficPackPrice(*pack, invoiceType)
+	if err != nil {
+		return "", 0, err
+	}
+	tariffID := offer.Tariff.ID
+	return s.createInvoice(ctx, invoiceType, InvoiceParams{
+		Amount:   float64(price),
+		Customer: customer,
+		TariffID: &tariffID,
+		Extras:   &TariffPurchaseExtras{Kind: database.PurchaseKindTrafficPack, TrafficGB: pack.TrafficGB},
+	})
+}
+
+// applyTrafficPack — пакет трафика: лимит в панели = лимит до оплаты + пакет. Лимит до оплаты сохраняется
+// перед первым изменением — повтор шага не прибавит пакет второй раз.
+func (r *outboxRun) applyTrafficPack(ctx context.Context) error {
+	s, purchase, customer, plan := r.s, r.purchase, r.customer, r.plan
+	if purchase.TrafficGB <= 0 {
+		return fmt.Errorf("traffic pack purchase %d has no traffic_gb", purchase.ID)
+	}
+	if existing, err := s.trafficPackRepository.FindByPurchaseID(ctx, purchase.ID); err != nil || existing != nil {
+		return err
+	}
+	user, err := s.remnawaveClient.FindUserForCustomer(ctx, customer.ID, customer.TelegramID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("remnawave user for customer %d not found", customer.ID)
+	}
+	if plan.TrafficLimitBefore == nil {
+		limit := user.TrafficLimitBytes
+		plan.TrafficLimitBefore = &limit
+		if err := r.save(ctx); err != nil {
+			return err
+		}
+	}
+	if *plan.TrafficLimitBefore <= 0 {
+		// Лимит сняли между счётом и оплатой: прибавлять не к чему, безлимит не превращаем в лимит.
+		slog.Warn("traffic pack paid for unlimited user", "purchase_id", utils.MaskHalfInt64(purchase.ID), "customer_id", utils.MaskHalfInt64(customer.ID))
+		return nil
+	}
+	bytes := TrafficPackBytes(purchase.TrafficGB)
+	after := *plan.TrafficLimitBefore + bytes
+	plan.TrafficLimitAfter = &after
+	if user.TrafficLimitBytes != after {
+		req := &remnawave.UpdateUserRequest{UUID: &user.UUID, TrafficLimitBytes: &after}
+		// Исчерпавший трафик пользователь в панели LIMITED — с новым лимитом снова активен.
+		if user.Status == "LIMITED" {
+			req.Status = "ACTIVE"
+		}
+		if _, err := s.remnawaveClient.PatchUser(ctx, req); err != nil {
+			return err
+		}
+	}
+	if _, err := s.trafficPackRepository.Create(ctx, customer.ID, purchase.ID, bytes, user.LastTrafficResetAt); err != nil {
+		return err
+	}
+	slog.Info("traffic pack applied", "purchase_id", utils.MaskHalfInt64(purchase.ID), "customer_id", utils.MaskHalfInt64(customer.ID), "traffic_gb", purchase.TrafficGB)
+	return nil
+}
+
+// notifyTrafficPackApplied — сообщение покупателю о подключённом пакете; limitAfter == nil — лимит не менялся (безлимит).
+func (s PaymentService) notifyTrafficPackApplied(ctx context.Context, c *database.Customer, trafficGB int, limitAfter *int64) {
+	if s.telegramBot == nil || skipTelegramCustomerDM(c) {
+		return
+	}
+	lang := c.Language
+	text := fmt.Sprintf(s.translation.GetText(lang, "traffic_pack_applied_unlimited"), trafficGB)
+	if limitAfter != nil {
+		text = fmt.Sprintf(s.translation.GetText(lang, "traffic_pack_applied"), trafficGB, *limitAfter/trafficPackBytesPerGB)
+	}
+	_, err := s.telegramBot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    c.TelegramID,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+		ReplyMarkup: models.InlineKeyboardMarkup{InlineKeyboard: [][]models.InlineKeyboardButton{
+			{s.translation.WithButton(lang, "connect_button", models.InlineKeyboardButton{CallbackData: "connect"})},
+		}},
+	})
+	if err != nil {
+		slog.Error("traffic pack: notify applied", "error", err, "customer_id", utils.MaskHalfInt64(c.ID))
+	}
+}
+
+// closeTrafficPacks — продление подписки выставило лимит тарифа и сбросило трафик: действующие пакеты израсходованы.
+func (s PaymentService) closeTrafficPacks(ctx context.Context, customerID int64) error {
+	if s.trafficPackRepository == nil {
+		return nil
+	}
+	return s.trafficPackRepository.CloseAllActive(ctx, customerID, time.Now().UTC())
+}
+
+// RollbackTrafficPacks откатывает пакеты, после покупки которых панель сбросила трафик (cron в main):
+// лимит уменьшается на их объём, но не ниже лимита тарифа.
+func (s PaymentService) RollbackTrafficPacks(ctx context.Context) {
+	if s.trafficPackRepository == nil {
+		return
+	}
+	var afterCustomerID int64
+	for {
+		packs, err := s.trafficPackRepository.FindActiveBatch(ctx, afterCustomerID, trafficPackRollbackBatch)
+		if err != nil {
+			slog.Error("traffic packs: find active", "error", err)
+			return
+		}
+		if len(packs) == 0 {
+			return
+		}
+		for start := 0; start < len(packs); {
+			end := start
+			for end < len(packs) && packs[end].CustomerID == packs[start].CustomerID {
+				end++
+			}
+			s.rollbackTrafficPacksAfterReset(ctx, packs[start:end])
+			start = end
+		}
+		afterCustomerID = packs[len(packs)-1].CustomerID
+	}
+}
+
+// rollbackTrafficPacksAfterReset — пакеты одного клиента: откатываются те, что куплены до последнего сброса.
+func (s PaymentService) rollbackTrafficPacksAfterReset(ctx context.Context, packs []database.CustomerTrafficPack) {
+	customerID := packs[0].CustomerID
+	customer, err := s.customerRepository.FindById(ctx, customerID)
+	if err != nil || customer == nil {
+		if err != nil {
+			slog.Error("traffic packs: find customer", "error", err, "customer_id", utils.MaskHalfInt64(customerID))
+		}
+		return
+	}
+	user, err := s.remnawaveClient.FindUserForCustomer(ctx, customer.ID, customer.TelegramID)
+	if err != nil {
+		slog.Error("traffic packs: find panel user", "error", err, "customer_id", utils.MaskHalfInt64(customerID))
+		return
+	}
+	var due []database.CustomerTrafficPack
+	for _, p := range packs {
+		// Пользователя в панели больше нет — откатывать нечего, пакет просто закрывается.
+		if user == nil || trafficResetAfter(user, p.ResetMarker) {
+			due = append(due, p)
+		}
+	}
+	if len(due) == 0 {
+		return
+	}
+	if err := s.rollbackTrafficPacks(ctx, customer, user, due); err != nil {
+		slog.Error("traffic packs: rollback", "error", err, "customer_id", utils.MaskHalfInt64(customerID))
+	}
+}
+
+// trafficResetAfter — панель сбросила трафик после marker (lastTrafficResetAt на момент покупки пакета).
+func trafficResetAfter(user *remnawave.User, marker *time.Time) bool {
+	if user.LastTrafficResetAt == nil {
+		return false
+	}
+	return marker == nil || user.LastTrafficResetAt.After(*marker)
+}
+
+// rollbackTrafficPacks закрывает пакеты и уменьшает лимит в панели на их объём (не ниже лимита тарифа).
+// Пакеты закрываются до запроса в панель: параллельный откат не вычтет их второй раз; при ошибке панели
+// они снова становятся действующими.
+func (s PaymentService) rollbackTrafficPacks(ctx context.Context, customer *database.Customer, user *remnawave.User, packs []database.CustomerTrafficPack) error {
+	now := time.Now().UTC()
+	var (
+		ids   []int64
+		bytes int64
+	)
+	for _, p := range packs {
+		closed, err := s.trafficPackRepository.MarkRolledBack(ctx, p.ID, now)
+		if err != nil {
+			return err
+		}
+		if closed {
+			ids = append(ids, p.ID)
+			bytes += p.Bytes
+		}
+	}
+	if user == nil || bytes == 0 || user.TrafficLimitBytes <= 0 {
+		return nil
+	}
+	newLimit := user.TrafficLimitBytes - bytes
+	if base := s.tariffTrafficLimit(ctx, customer); newLimit < base {
+		newLimit = base
+	}
+	if newLimit <= 0 || newLimit == user.TrafficLimitBytes {
+		return nil
+	}
+	if _, err := s.remnawaveClient.PatchUser(ctx, &remnawave.UpdateUserRequest{UUID: &user.UUID, TrafficLimitBytes: &newLimit}); err != nil {
+		if rerr := s.trafficPackRepository.Reopen(ctx, ids); rerr != nil {
+			slog.Error("traffic packs: reopen after panel error", "error", rerr, "customer_id", utils.MaskHalfInt64(customer.ID))
+		}
+		return err
+	}
+	slog.Info("traffic packs rolled back", "customer_id", utils.MaskHalfInt64(customer.ID), "packs", len(ids))
+	return nil
+}
+
+// tariffTrafficLimit — лимит трафика текущего тарифа клиента; 0 — тариф не найден или безлимитный.
+func (s PaymentService) tariffTrafficLimit(ctx context.Context, customer *database.Customer) int64 {
+	if s.tariffRepository == nil || customer.CurrentTariffID == nil || *customer.CurrentTariffID <= 0 {
+		return 0
+	}
+	tariff, err := s.tariffRepository.GetByID(ctx, *customer.CurrentTariffID)
+	if err != nil || tariff == nil {
+		return 0
+	}
+	return tariff.TrafficLimitBytes
+}
+
+// revokeTrafficPackAfterRefund — полный возврат пакета трафика: пакет закрывается, лимит уменьшается.
+func (s PaymentService) revokeTrafficPackAfterRefund(ctx context.Context, purchase *database.Purchase, customer *database.Customer) {
+	if s.trafficPackRepository == nil {
+		return
+	}
+	pack, err := s.trafficPackRepository.FindByPurchaseID(ctx, purchase.ID)
+	if err != nil || pack == nil || pack.RolledBackAt != nil {
+		if err != nil {
+			slog.Error("refund: find traffic pack", "error", err, "purchase_id", utils.MaskHalfInt64(purchase.ID))
+		}
+		return
+	}
+	user, err := s.remnawaveClient.FindUserForCustomer(ctx, customer.ID, customer.TelegramID)
+	if err != nil {
+		slog.Error("refund: find panel user for traffic pack", "error", err, "purchase_id", utils.MaskHalfInt64(purchase.ID))
+		return
+	}
+	if err := s.rollbackTrafficPacks(ctx, customer, user, []database.CustomerTrafficPack{*pack}); err != nil {
+		slog.Error("refund: rollback traffic pack", "error", err, "purchase_id", utils.MaskHalfInt64(purchase.ID))
+	}
+}
@@ -0,0 +1,43 @@
+package payment
+
+import (
+	"testing"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/database"
+	"remnawave-tg-shop-bot/internal/remnawave"
+)
+
+func TestTrafficResetAfter(t *testing.T) {
+	bought := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	later := bought.Add(24 * time.Hour)
+	tests := []struct {
+		name    string
+		resetAt *time.Time
+		marker  *time.Time
+		want    bool
+	}{
+		{name: "never reset", resetAt: nil, marker: nil, want: false},
+		{name: "first reset after purchase", resetAt: &later, marker: nil, want: true},
+		{name: "same reset as at purchase", resetAt: &bought, marker: &bought, want: false},
+		{name: "new reset", resetAt: &later, marker: &bought, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &remnawave.User{LastTrafficResetAt: tt.resetAt}
+			if got := trafficResetAfter(user, tt.marker); got != tt.want {
+				t.Fatalf("trafficResetAfter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrafficPackPrice(t *testing.T) {
+	pack := database.TariffTrafficPack{TrafficGB: 50, PriceRub: 150, PriceStars: 90}
+	if got, err := TrafficPackPrice(pack, database.InvoiceTypeYookasa); err != nil || got != 150 {
+		t.Fatalf("rub price = %d, %v; want 150", got, err)
+	}
+	if got, err := TrafficPackPrice(pack, database.InvoiceTypeTelegram); err != nil || got != 90 {
+		t.Fatalf("stars price = %d, %v; want 90", got, err)
+	}
+}
@@ -13,6 +13,7 @@ type ActivateResult struct {
 	TrialDays              int
 	ExtraHwidDelta         int
 	DiscountPercent        int
+	DiscountAmount         int // фиксированная скидка в ₽ (вместо DiscountPercent)
 	TrialSkippedActiveSub  bool
 }
 
@@ -0,0 +1,129 @@
+package promo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/database"
+)
+
+// BatchMaxCodes — предел кодов в одном пакете.
+const BatchMaxCodes = 10000
+
+// Случайная часть кода: алфавит без похожих символов (0/O, 1/I), 32 символа — байт по модулю без перекоса.
+// 8 символов — 32^8 ≈ 10^12 вариантов, совпадения при генерации тысяч кодов практически исключены.
+const (
+	batchCodeAlphabet  = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	batchCodeRandomLen = 8
+	batchPrefixMaxLen  = 32
+)
+
+// ErrBatchInvalid — некорректные параметры пакета (сообщение можно показать админу).
+var ErrBatchInvalid = errors.New("invalid promo batch")
+
+// BatchRequest — параметры пакета одноразовых кодов. Template задаёт тип, дни / скидку, тариф и срок действия;
+// Code и MaxUses шаблона не используются.
+type BatchRequest struct {
+	Campaign string
+	Prefix   string
+	Count    int
+	Template database.PromoCode
+}
+
+func batchInvalidf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrBatchInvalid, fmt.Sprintf(format, args...))
+}
+
+// ValidateBatch проверяет параметры пакета и приводит кампанию и префикс к виду в БД.
+func ValidateBatch(req *BatchRequest) error {
+	req.Campaign = strings.TrimSpace(req.Campaign)
+	req.Prefix = NormalizeCode(req.Prefix)
+	if req.Campaign == "" || len([]rune(req.Campaign)) > 128 {
+		return batchInvalidf("campaign is required (up to 128 characters)")
+	}
+	if req.Prefix != "" && (!ValidCodePattern(req.Prefix) || len(req.Prefix) > batchPrefixMaxLen) {
+		return batchInvalidf("prefix must be Latin letters and digits, up to %d characters", batchPrefixMaxLen)
+	}
+	if req.Count < 1 || req.Count > BatchMaxCodes {
+		return batchInvalidf("count must be between 1 and %d", BatchMaxCodes)
+	}
+	t := &req.Template
+	switch t.Type {
+	case database.PromoTypeSubscriptionDays:
+		if t.SubscriptionDays == nil || *t.SubscriptionDays <= 0 {
+			return batchInvalidf("subscription_days must be positive")
+		}
+	case database.PromoTypeTrial:
+		if t.TrialDays == nil || *t.TrialDays <= 0 {
+			return batchInvalidf("trial_days must be positive")
+		}
+	case database.PromoTypeExtraHwid:
+		if t.ExtraHwidDelta == nil || *t.ExtraHwidDelta == 0 {
+			return batchInvalidf("extra_hwid_delta is required")
+		}
+	case database.PromoTypeDiscount:
+		if err := ValidateDiscount(t); err != nil {
+			return batchInvalidf("%s", err)
+		}
+		if t.DiscountTTLHours != nil && *t.DiscountTTLHours <= 0 {
+			return batchInvalidf("discount_ttl_hours must be positive")
+		}
+	default:
+		return batchInvalidf("unknown type %q", t.Type)
+	}
+	if t.ValidUntil != nil && !t.ValidUntil.After(time.Now()) {
+		return batchInvalidf("valid_until is in the past")
+	}
+	return nil
+}
+
+// GenerateBatch создаёт пакет из req.Count одноразовых кодов вида <префикс><8 случайных символов>.
+func (s *Service) GenerateBatch(ctx context.Context, req BatchRequest) (*database.PromoBatch, error) {
+	if err := ValidateBatch(&req); err != nil {
+		return nil, err
+	}
+	batch := &database.PromoBatch{Campaign: req.Campaign, Prefix: req.Prefix, CodeCount: req.Count}
+	return s.PromoRepo.CreateBatch(ctx, batch, &req.Template, func() (string, error) {
+		return generateBatchCode(req.Prefix)
+	})
+}
+
+func generateBatchCode(prefix string) (string, error) {
+	buf := make([]byte, batchCodeRandomLen)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = batchCodeAlphabet[int(b)%len(batchCodeAlphabet)]
+	}
+	return prefix + string(buf), nil
+}
+
+// WriteBatchCSV пишет коды пакета в CSV: code, active, uses_count, redeemed_at (RFC 3339, UTC), redeemed_telegram_id.
+func WriteBatchCSV(w io.Writer, codes []database.PromoBatchCode) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"code", "active", "uses_count", "redeemed_at", "redeemed_telegram_id"}); err != nil {
+		return err
+	}
+	for _, c := range codes {
+		var redeemedAt, telegramID string
+		if c.RedeemedAt != nil {
+			redeemedAt = c.RedeemedAt.UTC().Format(time.RFC3339)
+		}
+		if c.RedeemedTelegramID != nil {
+			telegramID = strconv.FormatInt(*c.RedeemedTelegramID, 10)
+		}
+		if err := cw.Write([]string{c.Code, strconv.FormatBool(c.Active), strconv.Itoa(c.UsesCount), redeemedAt, telegramID}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
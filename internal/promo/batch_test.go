@@ -0,0 +1,74 @@
+package promo
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"remnawave-tg-shop-bot/internal/database"
+)
+
+func TestGenerateBatchCode(t *testing.T) {
+	code, err := generateBatchCode("PARTNER")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(code, "PARTNER") || len(code) != len("PARTNER")+batchCodeRandomLen {
+		t.Fatalf("unexpected code %q", code)
+	}
+	for _, r := range strings.TrimPrefix(code, "PARTNER") {
+		if !strings.ContainsRune(batchCodeAlphabet, r) {
+			t.Fatalf("unexpected rune %q in %q", r, code)
+		}
+	}
+	if !ValidCodePattern(code) {
+		t.Fatalf("generated code %q fails ValidCodePattern", code)
+	}
+}
+
+func TestValidateBatch(t *testing.T) {
+	days := 30
+	req := BatchRequest{Campaign: " Giveaway ", Prefix: "gw", Count: 100,
+		Template: database.PromoCode{Type: database.PromoTypeSubscriptionDays, SubscriptionDays: &days}}
+	if err := ValidateBatch(&req); err != nil {
+		t.Fatalf("valid batch: %v", err)
+	}
+	if req.Campaign != "Giveaway" || req.Prefix != "GW" {
+		t.Fatalf("not normalized: %+v", req)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	bad := []BatchRequest{
+		{Campaign: "", Count: 1, Template: req.Template},
+		{Campaign: "x", Count: 0, Template: req.Template},
+		{Campaign: "x", Count: BatchMaxCodes + 1, Template: req.Template},
+		{Campaign: "x", Prefix: "GW-", Count: 1, Template: req.Template},
+		{Campaign: "x", Count: 1, Template: database.PromoCode{Type: database.PromoTypeDiscount}},
+		{Campaign: "x", Count: 1, Template: database.PromoCode{Type: database.PromoTypeSubscriptionDays, SubscriptionDays: &days, ValidUntil: &past}},
+	}
+	for i := range bad {
+		if err := ValidateBatch(&bad[i]); !errors.Is(err, ErrBatchInvalid) {
+			t.Errorf("case %d: err = %v, want ErrBatchInvalid", i, err)
+		}
+	}
+}
+
+func TestWriteBatchCSV(t *testing.T) {
+	at := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	tg := int64(12345)
+	var sb strings.Builder
+	err := WriteBatchCSV(&sb, []database.PromoBatchCode{
+		{Code: "GWAAAA", Active: true},
+		{Code: "GWBBBB", Active: false, UsesCount: 1, RedeemedAt: &at, RedeemedTelegramID: &tg},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "code,active,uses_count,redeemed_at,redeemed_telegram_id\n" +
+		"GWAAAA,true,0,,\n" +
+		"GWBBBB,false,1,2026-05-01T10:00:00Z,12345\n"
+	if sb.String() != want {
+		t.Fatalf("csv = %q, want %q", sb.String(), want)
+	}
+}
@@ -0,0 +1,160 @@
+package promo
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+)
+
+// Причины, по которым pending-скидка не применяется к заказу (CheckoutDiscount.Reject, превью кабинета).
+const (
+	DiscountRejectTariff    = "tariff"
+	DiscountRejectMonths    = "months"
+	DiscountRejectProvider  = "provider"
+	DiscountRejectMinAmount = "min_amount"
+	// DiscountRejectCurrency — фиксированная скидка или минимальная сумма в ₽, а курс валюты счёта к рублю неизвестен.
+	DiscountRejectCurrency = "currency"
+)
+
+// CheckoutOrder — заказ, к которому применяется pending-скидка.
+type CheckoutOrder struct {
+	InvoiceType database.InvoiceType
+	TariffID    *int64
+	// Months — период подписки; 0 — заказ без периода (докупка устройств).
+	Months int
+	// Amount — сумма до скидок в единицах счёта; RubPerUnit — сколько рублей в единице (0 — курс неизвестен).
+	Amount     float64
+	RubPerUnit float64
+}
+
+// NewCheckoutOrder — заказ в рублях или Stars (курс Stars — RUB_PER_STAR).
+func NewCheckoutOrder(invoiceType database.InvoiceType, tariffID *int64, months, amount int) CheckoutOrder {
+	rate := 1.0
+	if invoiceType == database.InvoiceTypeTelegram {
+		rate = config.RubPerStar()
+	}
+	return CheckoutOrder{InvoiceType: invoiceType, TariffID: tariffID, Months: months, Amount: float64(amount), RubPerUnit: rate}
+}
+
+// InCurrency — тот же заказ в валюте из PRICE_CURRENCIES: amount — его сумма в валюте, курс выводится из рублёвой суммы.
+func (o CheckoutOrder) InCurrency(amount float64) CheckoutOrder {
+	rub := o.AmountRub()
+	o.Amount = amount
+	o.RubPerUnit = 0
+	if amount > 0 && rub > 0 {
+		o.RubPerUnit = float64(rub) / amount
+	}
+	return o
+}
+
+// AmountRub — сумма заказа в рублях; 0, если курс неизвестен.
+func (o CheckoutOrder) AmountRub() int {
+	return int(math.Round(o.Amount * o.RubPerUnit))
+}
+
+// CheckoutDiscount — pending-скидка клиента применительно к конкретному заказу.
+type CheckoutDiscount struct {
+	PromoCodeID int64
+	Percent     int
+	// AmountRub — фиксированная скидка в ₽; Fixed — она же в единицах счёта заказа.
+	AmountRub int
+	Fixed     float64
+	// Reject — почему скидка не подходит к заказу (DiscountReject*); пусто — скидка применяется.
+	Reject string
+}
+
+// Applied — скидка участвует в расчёте заказа.
+func (d *CheckoutDiscount) Applied() bool {
+	return d != nil && d.Reject == ""
+}
+
+// DiscountRejectReason проверяет ограничения скидочного промокода для заказа; "" — скидка применима.
+func DiscountRejectReason(p *database.PromoCode, o CheckoutOrder) string {
+	if len(p.DiscountTariffIDs) > 0 && (o.TariffID == nil || !slices.Contains(p.DiscountTariffIDs, *o.TariffID)) {
+		return DiscountRejectTariff
+	}
+	if len(p.DiscountMonths) > 0 && !slices.Contains(p.DiscountMonths, o.Months) {
+		return DiscountRejectMonths
+	}
+	if len(p.DiscountInvoiceTypes) > 0 && !slices.Contains(p.DiscountInvoiceTypes, string(o.InvoiceType)) {
+		return DiscountRejectProvider
+	}
+	if p.DiscountMinAmount != nil && *p.DiscountMinAmount > 0 {
+		if o.RubPerUnit <= 0 {
+			return DiscountRejectCurrency
+		}
+		if o.AmountRub() < *p.DiscountMinAmount {
+			return DiscountRejectMinAmount
+		}
+	}
+	return ""
+}
+
+// ValidateDiscount проверяет скидку промокода типа discount: ровно одно из процента (1–100) и суммы в ₽,
+// корректные ограничения. Сообщение ошибки можно показать админу.
+func ValidateDiscount(p *database.PromoCode) error {
+	hasPct := p.DiscountPercent != nil
+	hasAmount := p.DiscountAmount != nil
+	switch {
+	case hasPct && hasAmount:
+		return errors.New("set either discount_percent or discount_amount, not both")
+	case hasPct:
+		if *p.DiscountPercent < 1 || *p.DiscountPercent > 100 {
+			return errors.New("discount_percent must be between 1 and 100")
+		}
+	case hasAmount:
+		if *p.DiscountAmount < 1 {
+			return errors.New("discount_amount must be positive")
+		}
+	default:
+		return errors.New("discount_percent or discount_amount is required")
+	}
+	for _, id := range p.DiscountTariffIDs {
+		if id <= 0 {
+			return errors.New("discount_tariff_ids must be positive")
+		}
+	}
+	for _, m := range p.DiscountMonths {
+		if m != 1 && m != 3 && m != 6 && m != 12 {
+			return errors.New("discount_months must be 1, 3, 6 or 12")
+		}
+	}
+	for _, t := range p.DiscountInvoiceTypes {
+		if t == "" || database.InvoiceType(t) == database.InvoiceTypeTribute {
+			return errors.New("discount_invoice_types: unknown or unsupported payment method")
+		}
+	}
+	if p.DiscountMinAmount != nil && *p.DiscountMinAmount < 0 {
+		return errors.New("discount_min_amount must not be negative")
+	}
+	return nil
+}
+
+// PendingDiscountForCheckout — pending-скидка клиента для заказа с учётом ограничений её промокода; nil — скидки нет.
+// Истёкшая скидка удаляется.
+func (s *Service) PendingDiscountForCheckout(ctx context.Context, customerID int64, o CheckoutOrder) (*CheckoutDiscount, error) {
+	d, err := s.getActivePendingDiscount(ctx, customerID)
+	if err != nil || d == nil || (d.Percent <= 0 && d.Amount <= 0) {
+		return nil, err
+	}
+	p, err := s.PromoRepo.FindByID(ctx, d.PromoCodeID)
+	if err != nil {
+		return nil, err
+	}
+	out := &CheckoutDiscount{PromoCodeID: d.PromoCodeID, Percent: d.Percent, AmountRub: d.Amount}
+	if p != nil {
+		out.Reject = DiscountRejectReason(p, o)
+	}
+	if out.Reject == "" && d.Amount > 0 {
+		if o.RubPerUnit <= 0 {
+			out.Reject = DiscountRejectCurrency
+		} else {
+			out.Fixed = float64(d.Amount) / o.RubPerUnit
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,76 @@
+package promo
+
+import (
+	"testing"
+
+	"remnawave-tg-shop-bot/internal/database"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestDiscountRejectReason(t *testing.T) {
+	tariff := int64(2)
+	order := CheckoutOrder{InvoiceType: database.InvoiceTypeYookasa, TariffID: &tariff, Months: 3, Amount: 500, RubPerUnit: 1}
+
+	tests := []struct {
+		name  string
+		promo database.PromoCode
+		order CheckoutOrder
+		want  string
+	}{
+		{"no restrictions", database.PromoCode{}, order, ""},
+		{"tariff match", database.PromoCode{DiscountTariffIDs: []int64{1, 2}}, order, ""},
+		{"tariff mismatch", database.PromoCode{DiscountTariffIDs: []int64{1}}, order, DiscountRejectTariff},
+		{"tariff required, order without tariff", database.PromoCode{DiscountTariffIDs: []int64{2}}, CheckoutOrder{InvoiceType: database.InvoiceTypeYookasa, Amount: 100, RubPerUnit: 1}, DiscountRejectTariff},
+		{"months mismatch", database.PromoCode{DiscountMonths: []int{6, 12}}, order, DiscountRejectMonths},
+		{"provider mismatch", database.PromoCode{DiscountInvoiceTypes: []string{string(database.InvoiceTypeCrypto)}}, order, DiscountRejectProvider},
+		{"provider match", database.PromoCode{DiscountInvoiceTypes: []string{string(database.InvoiceTypeYookasa)}}, order, ""},
+		{"below min amount", database.PromoCode{DiscountMinAmount: intPtr(600)}, order, DiscountRejectMinAmount},
+		{"min amount reached", database.PromoCode{DiscountMinAmount: intPtr(500)}, order, ""},
+		{"min amount, unknown rate", database.PromoCode{DiscountMinAmount: intPtr(100)}, CheckoutOrder{InvoiceType: database.InvoiceTypeTelegram, Amount: 100}, DiscountRejectCurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiscountRejectReason(&tt.promo, tt.order); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateDiscount(t *testing.T) {
+	tests := []struct {
+		name    string
+		promo   database.PromoCode
+		wantErr bool
+	}{
+		{"percent", database.PromoCode{DiscountPercent: intPtr(15)}, false},
+		{"amount", database.PromoCode{DiscountAmount: intPtr(150)}, false},
+		{"neither", database.PromoCode{}, true},
+		{"both", database.PromoCode{DiscountPercent: intPtr(15), DiscountAmount: intPtr(150)}, true},
+		{"percent out of range", database.PromoCode{DiscountPercent: intPtr(101)}, true},
+		{"zero amount", database.PromoCode{DiscountAmount: intPtr(0)}, true},
+		{"bad months", database.PromoCode{DiscountPercent: intPtr(10), DiscountMonths: []int{2}}, true},
+		{"tribute", database.PromoCode{DiscountPercent: intPtr(10), DiscountInvoiceTypes: []string{string(database.InvoiceTypeTribute)}}, true},
+		{"negative min amount", database.PromoCode{DiscountAmount: intPtr(100), DiscountMinAmount: intPtr(-1)}, true},
+		{"restricted", database.PromoCode{DiscountAmount: intPtr(100), DiscountTariffIDs: []int64{1}, DiscountMonths: []int{3, 12}, DiscountMinAmount: intPtr(300)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateDiscount(&tt.promo); (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckoutOrderInCurrency(t *testing.T) {
+	o := CheckoutOrder{InvoiceType: database.InvoiceTypeCrypto, Months: 1, Amount: 300, RubPerUnit: 1}
+	usd := o.InCurrency(3.5)
+	if usd.Amount != 3.5 || usd.AmountRub() != 300 {
+		t.Fatalf("unexpected order %+v (rub %d)", usd, usd.AmountRub())
+	}
+	if unknown := (CheckoutOrder{Amount: 100}).InCurrency(2); unknown.RubPerUnit != 0 || unknown.AmountRub() != 0 {
+		t.Fatalf("expected unknown rate, got %+v", unknown)
+	}
+}
@@ -1,5 +1,7 @@
 package promo
 
+import "math"
+
 // ApplyPercentDiscountInt returns base price reduced by percent (1–100). Minimum result is 1.
 func ApplyPercentDiscountInt(base int, percent int) int {
 	if percent <= 0 || percent > 100 {
@@ -11,3 +13,16 @@ func ApplyPercentDiscountInt(base int, percent int) int {
 	}
 	return out
 }
+
+// ApplyPercentDiscount is ApplyPercentDiscountInt for amounts with cents (foreign-currency invoices).
+// The result is rounded to cents; minimum is 0.01.
+func ApplyPercentDiscount(base float64, percent int) float64 {
+	if percent <= 0 || percent > 100 {
+		return base
+	}
+	out := math.Round(base*float64(100-percent)) / 100
+	if out < 0.01 {
+		return 0.01
+	}
+	return out
+}
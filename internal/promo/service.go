@@ -117,7 +117,13 @@ func (s *Service) Activate(ctx context.Context, telegramID int64, username strin
 		} else {
 			remaining = p.DiscountMaxSubscriptionPaymentsPerCustomer
 		}
-		if err := s.PromoRepo.UpsertPendingDiscount(ctx, tx, customer.ID, p.ID, *p.DiscountPercent, exp, untilFirst, remaining); err != nil {
+		percent, amount := 0, 0
+		if p.DiscountAmount != nil {
+			amount = *p.DiscountAmount
+		} else {
+			percent = *p.DiscountPercent
+		}
+		if err := s.PromoRepo.UpsertPendingDiscount(ctx, tx, customer.ID, p.ID, percent, amount, exp, untilFirst, remaining); err != nil {
 			return nil, err
 		}
 	}
@@ -128,7 +134,13 @@ func (s *Service) Activate(ctx context.Context, telegramID int64, username strin
 
 	if p.Type == database.PromoTypeDiscount {
 		slog.Info("promo activated", "promo_id", p.ID, "type", p.Type, "customer_id", utils.MaskHalfInt64(customer.ID))
-		return &ActivateResult{Type: database.PromoTypeDiscount, DiscountPercent: *p.DiscountPercent}, nil
+		res := &ActivateResult{Type: database.PromoTypeDiscount}
+		if p.DiscountAmount != nil {
+			res.DiscountAmount = *p.DiscountAmount
+		} else {
+			res.DiscountPercent = *p.DiscountPercent
+		}
+		return res, nil
 	}
 
 	ctxUser := context.WithValue(ctx, remnawave.CtxKeyUsername, username)
@@ -174,6 +186,15 @@ func (s *Service) validatePromoRow(ctx context.Context, p *database.PromoCode, c
 			return database.ValidationErrorf("first purchase only")
 		}
 	}
+	if p.SegmentID != nil {
+		in, err := s.CustomerRepo.InSegment(ctx, customer.ID, *p.SegmentID)
+		if err != nil {
+			return err
+		}
+		if !in {
+			return database.ValidationErrorf("segment mismatch")
+		}
+	}
 
 	switch p.Type {
 	case database.PromoTypeSubscriptionDays:
@@ -200,8 +221,8 @@ func (s *Service) validatePromoRow(ctx context.Context, p *database.PromoCode, c
 			return database.ValidationErrorf("extra_hwid needs active sub")
 		}
 	case database.PromoTypeDiscount:
-		if p.DiscountPercent == nil || *p.DiscountPercent < 1 || *p.DiscountPercent > 100 {
-			return database.ValidationErrorf("bad discount percent")
+		if err := ValidateDiscount(p); err != nil {
+			return database.ValidationErrorf("bad discount: %s", err)
 		}
 		if p.DiscountTTLHours == nil {
 			return database.ValidationErrorf("bad discount ttl")
@@ -297,11 +318,14 @@ func (s *Service) applyTrial(ctx context.Context, telegramID int64, customer *da
 	if err != nil {
 		return false, err
 	}
-	err = s.CustomerRepo.UpdateFields(ctx, customer.ID, map[string]interface{}{
+	updates := map[string]interface{}{
 		"subscription_link": user.SubscriptionUrl,
 		"expire_at":         user.ExpireAt,
-	})
-	if err != nil {
+	}
+	if customer.TrialActivatedAt == nil {
+		updates["trial_activated_at"] = time.Now().UTC()
+	}
+	if err := s.CustomerRepo.UpdateFields(ctx, customer.ID, updates); err != nil {
 		return false, err
 	}
 	return false, nil
@@ -366,22 +390,13 @@ func (s *Service) getActivePendingDiscount(ctx context.Context, customerID int64
 	return d, nil
 }
 
-// PendingDiscountForPayment returns percent 0-100 if a discount applies; clears expired pending discounts.
-func (s *Service) PendingDiscountForPayment(ctx context.Context, customerID int64) (percent int, promoCodeID int64, err error) {
+// PendingDiscountForConnectUI returns pending discount (percent or fixed ₽ amount) for the My VPN screen; expired rows are cleared.
+func (s *Service) PendingDiscountForConnectUI(ctx context.Context, customerID int64) (percent, amount int, untilFirst bool, expiresAt *time.Time, ok bool, err error) {
 	d, err := s.getActivePendingDiscount(ctx, customerID)
 	if err != nil || d == nil {
-		return 0, 0, err
+		return 0, 0, false, nil, false, err
 	}
-	return d.Percent, d.PromoCodeID, nil
-}
-
-// PendingDiscountForConnectUI returns pending discount for the My VPN screen; expired rows are cleared.
-func (s *Service) PendingDiscountForConnectUI(ctx context.Context, customerID int64) (percent int, untilFirst bool, expiresAt *time.Time, ok bool, err error) {
-	d, err := s.getActivePendingDiscount(ctx, customerID)
-	if err != nil || d == nil {
-		return 0, false, nil, false, err
-	}
-	return d.Percent, d.UntilFirstPurchase, d.ExpiresAt, true, nil
+	return d.Percent, d.Amount, d.UntilFirstPurchase, d.ExpiresAt, true, nil
 }
 
 // OnSuccessfulSubscriptionDiscountPayment вызывается после успешной оплаты с применённой промо-скидкой.
@@ -454,6 +469,7 @@ func (s *Service) GrantStackedPendingDiscount(ctx context.Context, customerID, p
 	}
 
 	newPercent := percentAdd
+	newAmount := 0
 	var newExpiry *time.Time
 	untilFirst := true // системные скидки — одноразовые (до первой оплаты)
 	remaining := 1
@@ -464,6 +480,8 @@ func (s *Service) GrantStackedPendingDiscount(ctx context.Context, customerID, p
 		if newPercent > 100 {
 			newPercent = 100
 		}
+		// Фиксированная скидка существующей акции сохраняется вместе с процентом.
+		newAmount = existing.Amount
 
 		// TTL: max из существующей и новой
 		now := time.Now().UTC()
@@ -489,10 +507,65 @@ func (s *Service) GrantStackedPendingDiscount(ctx context.Context, customerID, p
 		}
 	}
 
-	err = s.PromoRepo.UpsertPendingDiscount(ctx, tx, customerID, promoCodeID, newPercent, newExpiry, untilFirst, remaining)
+	err = s.PromoRepo.UpsertPendingDiscount(ctx, tx, customerID, promoCodeID, newPercent, newAmount, newExpiry, untilFirst, remaining)
 	if err != nil {
 		return err
 	}
 
 	return tx.Commit(ctx)
 }
+
+// RestoreDiscountAfterRefund возвращает клиенту промо-скидку, израсходованную оплатой purchase (полный возврат).
+// Если pending той же акции ещё активна — добавляет одну оплату к счётчику; если её уже удалили —
+// создаёт заново с процентом из покупки (фиксированную скидку — с суммой промокода). Чужую активную скидку не перезаписывает.
+func (s *Service) RestoreDiscountAfterRefund(ctx context.Context, purchase *database.Purchase, customerID int64) (bool, error) {
+	if s == nil || s.PromoRepo == nil || purchase == nil || purchase.PromoCodeID == nil || *purchase.PromoCodeID == 0 {
+		return false, nil
+	}
+	promoID := *purchase.PromoCodeID
+
+	tx, err := s.PromoRepo.Pool().Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	pd, err := s.PromoRepo.GetPendingDiscountByCustomerIDForUpdate(ctx, tx, customerID)
+	if err != nil {
+		return false, err
+	}
+	if pd != nil {
+		if pd.PromoCodeID != promoID || pd.SubscriptionPaymentsRemaining == database.PendingDiscountUnlimitedPayments {
+			return false, tx.Commit(ctx)
+		}
+		if err := s.PromoRepo.UpdatePendingDiscountRemainingTx(ctx, tx, customerID, pd.SubscriptionPaymentsRemaining+1); err != nil {
+			return false, err
+		}
+		return true, tx.Commit(ctx)
+	}
+
+	p, err := s.PromoRepo.FindByID(ctx, promoID)
+	if err != nil {
+		return false, err
+	}
+	percent, amount := 0, 0
+	if purchase.DiscountPercentApplied != nil {
+		percent = *purchase.DiscountPercentApplied
+	} else if p != nil && p.DiscountPercent != nil {
+		percent = *p.DiscountPercent
+	} else if p != nil && p.DiscountAmount != nil {
+		amount = *p.DiscountAmount
+	}
+	if percent <= 0 && amount <= 0 {
+		return false, tx.Commit(ctx)
+	}
+	var expiresAt *time.Time
+	if p != nil && p.DiscountTTLHours != nil && *p.DiscountTTLHours > 0 {
+		exp := time.Now().UTC().Add(time.Duration(*p.DiscountTTLHours) * time.Hour)
+		expiresAt = &exp
+	}
+	if err := s.PromoRepo.UpsertPendingDiscount(ctx, tx, customerID, promoID, percent, amount, expiresAt, true, 1); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
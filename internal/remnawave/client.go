@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"net/http"
 	"remnawave-tg-shop-bot/internal/config"
 	"remnawave-tg-shop-bot/utils"
@@ -405,6 +406,38 @@ func (r *Client) CreateOrUpdateUserFromNow(ctx context.Context, customerId int64
 	return r.updateUserWithBase(ctx, existingUser, trafficLimit, days, isTrialUser, &base)
 }
 
+// CreateOrUpdateUserUntil — как CreateOrUpdateUser, но срок задаётся абсолютным expireAt, а не прибавкой дней:
+// повтор запроса после сбоя не продлевает подписку второй раз.
+func (r *Client) CreateOrUpdateUserUntil(ctx context.Context, customerId int64, telegramId int64, trafficLimit int, expireAt time.Time) (*User, error) {
+	existingUser, err := r.findExistingUserForCustomer(ctx, customerId, telegramId)
+	if err != nil {
+		return nil, err
+	}
+	if existingUser == nil {
+		return r.createUser(ctx, customerId, telegramId, trafficLimit, daysUntil(expireAt), false)
+	}
+	return r.updateUserWithBase(ctx, existingUser, trafficLimit, 0, false, &expireAt)
+}
+
+// FindUserForCustomer — RW-профиль клиента; nil, если его ещё нет.
+func (r *Client) FindUserForCustomer(ctx context.Context, customerID int64, telegramID int64) (*User, error) {
+	return r.findExistingUserForCustomer(ctx, customerID, telegramID)
+}
+
+// ExpireAfterDays — срок, который выставит продление на days дней от base (истёкший или нулевой base — от текущего момента).
+func ExpireAfterDays(days int, base time.Time) time.Time {
+	return getNewExpire(days, base)
+}
+
+// daysUntil — сколько дней (с округлением вверх) до expireAt, но не меньше одного.
+func daysUntil(expireAt time.Time) int {
+	days := int(math.Ceil(time.Until(expireAt).Hours() / 24))
+	if days < 1 {
+		return 1
+	}
+	return days
+}
+
 // ---------------------------------------------------------------------------
 // Internal helpers
 // ---------------------------------------------------------------------------
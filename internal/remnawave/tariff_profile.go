@@ -54,6 +54,18 @@ func (r *Client) CreateOrUpdateUserWithTariffProfileFromNow(ctx context.Context,
 	return r.createOrUpdateUserWithTariffProfile(ctx, customerID, telegramID, days, profile, &base)
 }
 
+// CreateOrUpdateUserWithTariffProfileUntil — срок задаётся абсолютным expireAt (аналог CreateOrUpdateUserUntil).
+func (r *Client) CreateOrUpdateUserWithTariffProfileUntil(ctx context.Context, customerID int64, telegramID int64, profile TariffPaidProfile, expireAt time.Time) (*User, error) {
+	existingUser, err := r.findExistingUserForCustomer(ctx, customerID, telegramID)
+	if err != nil {
+		return nil, err
+	}
+	if existingUser == nil {
+		return r.createUserWithTariffProfile(ctx, customerID, telegramID, daysUntil(expireAt), profile)
+	}
+	return r.updateUserWithTariffProfile(ctx, existingUser, 0, profile, &expireAt)
+}
+
 func (r *Client) createOrUpdateUserWithTariffProfile(ctx context.Context, customerID int64, telegramID int64, days int, profile TariffPaidProfile, baseExpire *time.Time) (*User, error) {
 	existingUser, err := r.findExistingUserForCustomer(ctx, customerID, telegramID)
 	if err != nil {
@@ -0,0 +1,92 @@
+package remnawave
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// WebhookSignatureHeader — заголовок с HMAC-SHA256 (hex) тела вебхука, ключ — WEBHOOK_SECRET_HEADER панели.
+const WebhookSignatureHeader = "X-Remnawave-Signature"
+
+// События панели, на которые реагирует бот. user.traffic_reached и user.limited — одно и то же
+// в разных версиях Remnawave.
+const (
+	WebhookEventUserExpired        = "user.expired"
+	WebhookEventUserTrafficReached = "user.traffic_reached"
+	WebhookEventUserLimited        = "user.limited"
+	WebhookEventUserDisabled       = "user.disabled"
+	WebhookEventUserFirstConnected = "user.first_connected"
+	WebhookEventHwidDeviceAdded    = "user_hwid_devices.added"
+)
+
+// WebhookEvent — конверт вебхука панели; Data зависит от scope (user — карточка пользователя).
+type WebhookEvent struct {
+	Scope     string          `json:"scope"`
+	Event     string          `json:"event"`
+	Timestamp time.Time       `json:"timestamp"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// hwidDeviceEventData — data события user_hwid_devices.*.
+type hwidDeviceEventData struct {
+	User           User   `json:"user"`
+	HwidUserDevice Device `json:"hwidUserDevice"`
+}
+
+// VerifyWebhookSignature сверяет подпись тела с секретом; пустой секрет или подпись — отказ.
+func VerifyWebhookSignature(body []byte, signature, secret string) bool {
+	signature = strings.TrimSpace(signature)
+	if secret == "" || signature == "" {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
+
+// ParseWebhookEvent разбирает тело вебхука панели.
+func ParseWebhookEvent(body []byte) (*WebhookEvent, error) {
+	var ev WebhookEvent
+	if err := json.Unmarshal(body, &ev); err != nil {
+		return nil, err
+	}
+	if ev.Event == "" {
+		return nil, errors.New("remnawave webhook: empty event")
+	}
+	return &ev, nil
+}
+
+// User возвращает пользователя панели из события: data целиком для scope user,
+// data.user для событий устройств.
+func (e *WebhookEvent) User() (*User, error) {
+	if strings.HasPrefix(e.Event, "user_hwid_devices.") {
+		var d hwidDeviceEventData
+		if err := json.Unmarshal(e.Data, &d); err != nil {
+			return nil, err
+		}
+		return &d.User, nil
+	}
+	var u User
+	if err := json.Unmarshal(e.Data, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// Device возвращает устройство из события user_hwid_devices.*; nil — событие другого типа.
+func (e *WebhookEvent) Device() (*Device, error) {
+	if !strings.HasPrefix(e.Event, "user_hwid_devices.") {
+		return nil, nil
+	}
+	var d hwidDeviceEventData
+	if err := json.Unmarshal(e.Data, &d); err != nil {
+		return nil, err
+	}
+	return &d.HwidUserDevice, nil
+}
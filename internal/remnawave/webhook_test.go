@@ -0,0 +1,45 @@
+package remnawave
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"scope":"user","event":"user.expired","data":{}}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	sig := hex.EncodeToString(mac.Sum(nil))
+
+	if !VerifyWebhookSignature(body, sig, "secret") {
+		t.Fatal("expected valid signature")
+	}
+	if VerifyWebhookSignature(body, sig, "other") {
+		t.Fatal("expected invalid signature for another secret")
+	}
+	if VerifyWebhookSignature(body, "", "secret") {
+		t.Fatal("expected empty signature to be rejected")
+	}
+	if VerifyWebhookSignature(body, sig, "") {
+		t.Fatal("expected empty secret to be rejected")
+	}
+}
+
+func TestWebhookEventHwidDevice(t *testing.T) {
+	body := []byte(`{"scope":"user_hwid_devices","event":"user_hwid_devices.added","timestamp":"2025-01-02T03:04:05Z",` +
+		`"data":{"user":{"username":"7_100","telegramId":100},"hwidUserDevice":{"hwid":"abc","platform":"iOS"}}}`)
+	ev, err := ParseWebhookEvent(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := ev.User()
+	if err != nil || u.Username != "7_100" || u.TelegramID == nil || *u.TelegramID != 100 {
+		t.Fatalf("user=%+v err=%v", u, err)
+	}
+	d, err := ev.Device()
+	if err != nil || d == nil || d.Hwid != "abc" || d.Platform == nil || *d.Platform != "iOS" {
+		t.Fatalf("device=%+v err=%v", d, err)
+	}
+}
@@ -0,0 +1,289 @@
+// Package staff — роли и права сотрудников админки бота и кабинета.
+//
+// ADMIN_TELEGRAM_ID — всегда владелец со всеми правами и в таблице staff не хранится.
+// Остальные сотрудники читаются из БД в память при старте и после каждого изменения,
+// поэтому проверки прав в обработчиках не ходят в базу.
+package staff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+
+	"remnawave-tg-shop-bot/internal/config"
+	"remnawave-tg-shop-bot/internal/database"
+)
+
+// Permission — право на раздел админки.
+type Permission string
+
+const (
+	PermUsersRead  Permission = "users_read"
+	PermUsersWrite Permission = "users_write"
+	PermPayments   Permission = "payments"
+	PermBroadcasts Permission = "broadcasts"
+	PermPromos     Permission = "promos"
+	PermTariffs    Permission = "tariffs"
+	PermSettings   Permission = "settings"
+	PermInfra      Permission = "infra"
+	PermStats      Permission = "stats"
+	PermAudit      Permission = "audit"
+)
+
+// AllPermissions — все права в порядке показа в админке.
+var AllPermissions = []Permission{
+	PermUsersRead, PermUsersWrite, PermPayments, PermBroadcasts,
+	PermPromos, PermTariffs, PermSettings, PermInfra, PermStats, PermAudit,
+}
+
+// Role — роль сотрудника; задаёт набор прав по умолчанию.
+type Role string
+
+const (
+	RoleOwner    Role = "owner"
+	RoleAdmin    Role = "admin"
+	RoleSupport  Role = "support"
+	RoleMarketer Role = "marketer"
+)
+
+// Roles — все роли в порядке показа в админке.
+var Roles = []Role{RoleOwner, RoleAdmin, RoleSupport, RoleMarketer}
+
+var (
+	ErrInvalidRole       = errors.New("invalid staff role")
+	ErrInvalidPermission = errors.New("invalid staff permission")
+	ErrInvalidTelegramID = errors.New("invalid staff telegram id")
+	ErrPrimaryOwner      = errors.New("ADMIN_TELEGRAM_ID cannot be changed")
+)
+
+// RolePermissions — права роли по умолчанию. Владелец и администратор получают все права;
+// управлять сотрудниками может только владелец (см. IsOwner).
+func RolePermissions(role Role) []Permission {
+	switch role {
+	case RoleOwner, RoleAdmin:
+		return slices.Clone(AllPermissions)
+	case RoleSupport:
+		return []Permission{PermUsersRead, PermUsersWrite, PermPayments}
+	case RoleMarketer:
+		return []Permission{PermUsersRead, PermBroadcasts, PermPromos, PermStats}
+	}
+	return nil
+}
+
+// Member — сотрудник с действующими правами.
+type Member struct {
+	TelegramID  int64        `json:"telegram_id"`
+	Role        Role         `json:"role"`
+	Permissions []Permission `json:"permissions"`
+	Note        string       `json:"note"`
+}
+
+// Has — есть ли у сотрудника право; у владельца есть все.
+func (m Member) Has(perm Permission) bool {
+	return m.Role == RoleOwner || slices.Contains(m.Permissions, perm)
+}
+
+var (
+	mu      sync.RWMutex
+	repo    *database.StaffRepository
+	members = map[int64]Member{}
+)
+
+// Init подключает репозиторий и загружает сотрудников.
+func Init(ctx context.Context, r *database.StaffRepository) error {
+	mu.Lock()
+	repo = r
+	mu.Unlock()
+	return Reload(ctx)
+}
+
+// Reload перечитывает сотрудников из БД.
+func Reload(ctx context.Context) error {
+	mu.RLock()
+	r := repo
+	mu.RUnlock()
+	if r == nil {
+		return nil
+	}
+	rows, err := r.List(ctx)
+	if err != nil {
+		return err
+	}
+	next := make(map[int64]Member, len(rows))
+	for _, row := range rows {
+		m, err := memberFromRow(row)
+		if err != nil {
+			slog.Warn("staff: invalid row skipped", "telegram_id", row.TelegramID, "error", err)
+			continue
+		}
+		next[m.TelegramID] = m
+	}
+	mu.Lock()
+	members = next
+	mu.Unlock()
+	return nil
+}
+
+// Lookup — сотрудник по Telegram ID; ADMIN_TELEGRAM_ID — всегда владелец.
+func Lookup(telegramID int64) (Member, bool) {
+	if telegramID <= 0 {
+		return Member{}, false
+	}
+	if telegramID == config.GetAdminTelegramId() {
+		return Member{TelegramID: telegramID, Role: RoleOwner, Permissions: slices.Clone(AllPermissions)}, true
+	}
+	mu.RLock()
+	m, ok := members[telegramID]
+	mu.RUnlock()
+	return m, ok
+}
+
+// Configured — есть ли хоть один сотрудник, включая ADMIN_TELEGRAM_ID.
+func Configured() bool {
+	if config.GetAdminTelegramId() > 0 {
+		return true
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(members) > 0
+}
+
+// IsStaff — есть ли у пользователя доступ к админке хоть в каком-то разделе.
+func IsStaff(telegramID int64) bool {
+	_, ok := Lookup(telegramID)
+	return ok
+}
+
+// IsOwner — владелец: ADMIN_TELEGRAM_ID или сотрудник с ролью owner.
+func IsOwner(telegramID int64) bool {
+	m, ok := Lookup(telegramID)
+	return ok && m.Role == RoleOwner
+}
+
+// Can — есть ли у пользователя право perm.
+func Can(telegramID int64, perm Permission) bool {
+	m, ok := Lookup(telegramID)
+	return ok && m.Has(perm)
+}
+
+// List — сотрудники из таблицы staff (без ADMIN_TELEGRAM_ID).
+func List() []Member {
+	mu.RLock()
+	out := make([]Member, 0, len(members))
+	for _, m := range members {
+		out = append(out, m)
+	}
+	mu.RUnlock()
+	slices.SortFunc(out, func(a, b Member) int {
+		if a.TelegramID < b.TelegramID {
+			return -1
+		}
+		if a.TelegramID > b.TelegramID {
+			return 1
+		}
+		return 0
+	})
+	return out
+}
+
+// Save добавляет или обновляет сотрудника. Пустой список прав — права роли по умолчанию.
+func Save(ctx context.Context, m Member) (Member, error) {
+	m, err := Normalize(m)
+	if err != nil {
+		return Member{}, err
+	}
+	mu.RLock()
+	r := repo
+	mu.RUnlock()
+	if r == nil {
+		return Member{}, fmt.Errorf("staff repository is not initialized")
+	}
+	perms := make([]string, len(m.Permissions))
+	for i, p := range m.Permissions {
+		perms[i] = string(p)
+	}
+	row, err := r.Upsert(ctx, database.StaffMember{
+		TelegramID:  m.TelegramID,
+		Role:        string(m.Role),
+		Permissions: perms,
+		Note:        m.Note,
+	})
+	if err != nil {
+		return Member{}, err
+	}
+	if err := Reload(ctx); err != nil {
+		return Member{}, err
+	}
+	return memberFromRow(*row)
+}
+
+// Remove удаляет сотрудника; false — такого не было.
+func Remove(ctx context.Context, telegramID int64) (bool, error) {
+	if telegramID == config.GetAdminTelegramId() {
+		return false, ErrPrimaryOwner
+	}
+	mu.RLock()
+	r := repo
+	mu.RUnlock()
+	if r == nil {
+		return false, fmt.Errorf("staff repository is not initialized")
+	}
+	ok, err := r.Delete(ctx, telegramID)
+	if err != nil {
+		return false, err
+	}
+	return ok, Reload(ctx)
+}
+
+// Normalize проверяет роль и права сотрудника, убирает дубли и сортирует права в порядке AllPermissions.
+func Normalize(m Member) (Member, error) {
+	if m.TelegramID <= 0 {
+		return Member{}, ErrInvalidTelegramID
+	}
+	if m.TelegramID == config.GetAdminTelegramId() {
+		return Member{}, ErrPrimaryOwner
+	}
+	m.Role = Role(strings.ToLower(strings.TrimSpace(string(m.Role))))
+	if !slices.Contains(Roles, m.Role) {
+		return Member{}, fmt.Errorf("%w: %q", ErrInvalidRole, m.Role)
+	}
+	for _, p := range m.Permissions {
+		if !slices.Contains(AllPermissions, p) {
+			return Member{}, fmt.Errorf("%w: %q", ErrInvalidPermission, p)
+		}
+	}
+	if m.Role == RoleOwner || len(m.Permissions) == 0 {
+		m.Permissions = RolePermissions(m.Role)
+	}
+	perms := make([]Permission, 0, len(m.Permissions))
+	for _, p := range AllPermissions {
+		if slices.Contains(m.Permissions, p) {
+			perms = append(perms, p)
+		}
+	}
+	// Изменение пользователей без просмотра бессмысленно.
+	if slices.Contains(perms, PermUsersWrite) && !slices.Contains(perms, PermUsersRead) {
+		perms = append([]Permission{PermUsersRead}, perms...)
+	}
+	m.Permissions = perms
+	m.Note = strings.TrimSpace(m.Note)
+	return m, nil
+}
+
+func memberFromRow(row database.StaffMember) (Member, error) {
+	m := Member{TelegramID: row.TelegramID, Role: Role(row.Role), Note: row.Note}
+	for _, p := range row.Permissions {
+		m.Permissions = append(m.Permissions, Permission(p))
+	}
+	if !slices.Contains(Roles, m.Role) {
+		return Member{}, fmt.Errorf("%w: %q", ErrInvalidRole, m.Role)
+	}
+	if m.Role == RoleOwner {
+		m.Permissions = slices.Clone(AllPermissions)
+	}
+	return m, nil
+}
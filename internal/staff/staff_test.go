@@ -0,0 +1,71 @@
+package staff
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	m, err := Normalize(Member{TelegramID: 42, Role: " Support "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Role != RoleSupport || !reflect.DeepEqual(m.Permissions, RolePermissions(RoleSupport)) {
+		t.Fatalf("got %+v", m)
+	}
+
+	m, err = Normalize(Member{TelegramID: 42, Role: RoleMarketer, Permissions: []Permission{PermStats, PermUsersWrite, PermStats}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m.Permissions, []Permission{PermUsersRead, PermUsersWrite, PermStats}) {
+		t.Fatalf("got %v", m.Permissions)
+	}
+
+	m, err = Normalize(Member{TelegramID: 42, Role: RoleOwner, Permissions: []Permission{PermStats}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m.Permissions, AllPermissions) {
+		t.Fatalf("owner got %v", m.Permissions)
+	}
+
+	for _, tc := range []struct {
+		m    Member
+		want error
+	}{
+		{Member{TelegramID: 0, Role: RoleAdmin}, ErrInvalidTelegramID},
+		{Member{TelegramID: 42, Role: "root"}, ErrInvalidRole},
+		{Member{TelegramID: 42, Role: RoleAdmin, Permissions: []Permission{"everything"}}, ErrInvalidPermission},
+	} {
+		if _, err := Normalize(tc.m); !errors.Is(err, tc.want) {
+			t.Fatalf("%+v: got %v, want %v", tc.m, err, tc.want)
+		}
+	}
+}
+
+func TestCan(t *testing.T) {
+	mu.Lock()
+	prev := members
+	members = map[int64]Member{
+		10: {TelegramID: 10, Role: RoleSupport, Permissions: RolePermissions(RoleSupport)},
+		11: {TelegramID: 11, Role: RoleOwner},
+	}
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		members = prev
+		mu.Unlock()
+	})
+
+	if !Can(10, PermPayments) || Can(10, PermBroadcasts) {
+		t.Fatal("support permissions mismatch")
+	}
+	if !Can(11, PermSettings) || !IsOwner(11) || IsOwner(10) {
+		t.Fatal("owner must have all permissions")
+	}
+	if IsStaff(12) || Can(12, PermStats) {
+		t.Fatal("unknown user must not be staff")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"remnawave-tg-shop-bot/internal/database"
 	"remnawave-tg-shop-bot/internal/remnawave"
 	"remnawave-tg-shop-bot/utils"
+	"time"
 
 )
 
@@ -112,5 +113,35 @@ func (s SyncService) Sync() {
 			slog.Info("Updated clients", "count", len(toUpdate))
 		}
 	}
+	if err := s.customerRepository.UpdateOnlineAt(ctx, onlineAtByTelegramID(users)); err != nil {
+		slog.Error("Error while updating online_at", "error", err)
+	}
 	slog.Info("Synchronization completed")
 }
+
+// SyncOnlineAt обновляет customer.online_at (последнее подключение к VPN) по данным панели —
+// для фильтров сегментов «подключался / не подключался N дней».
+func (s SyncService) SyncOnlineAt(ctx context.Context) {
+	users, err := s.client.GetUsers(ctx)
+	if err != nil {
+		slog.Error("online_at sync: get remnawave users", "error", err)
+		return
+	}
+	if err := s.customerRepository.UpdateOnlineAt(ctx, onlineAtByTelegramID(users)); err != nil {
+		slog.Error("online_at sync: update customers", "error", err)
+	}
+}
+
+func onlineAtByTelegramID(users []remnawave.User) map[int64]time.Time {
+	out := make(map[int64]time.Time)
+	for _, u := range users {
+		if u.TelegramID == nil || u.UserTraffic.OnlineAt == nil || u.UserTraffic.OnlineAt.IsZero() {
+			continue
+		}
+		// У одного Telegram несколько пользователей панели — берём самое свежее подключение.
+		if prev, ok := out[*u.TelegramID]; !ok || u.UserTraffic.OnlineAt.After(prev) {
+			out[*u.TelegramID] = *u.UserTraffic.OnlineAt
+		}
+	}
+	return out
+}
@@ -10,6 +10,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"remnawave-tg-shop-bot/internal/config"
 	"remnawave-tg-shop-bot/internal/remnawave"
 	"strconv"
@@ -23,6 +24,7 @@ var ErrPaymentNotFound = errors.New("yookasa payment not found")
 type YookasaAPI interface {
 	CreatePayment(ctx context.Context, request PaymentRequest, idempotencyKey string) (*Payment, error)
 	GetPayment(ctx context.Context, paymentID uuid.UUID) (*Payment, error)
+	CreateRefund(ctx context.Context, request RefundRequest, idempotencyKey string) (*Refund, error)
 }
 
 // ctxKey изолирует ключи контекста от внешних пакетов.
@@ -41,8 +43,34 @@ const (
 	// Idempotence-Key. Без override клиент продолжает генерировать uuid,
 	// чтобы не ломать существующие вызовы из бота.
 	CtxKeyIdempotenceKey ctxKey = "yookasa.idempotence_key"
+	// CtxKeySavePaymentMethod — true: запросить save_payment_method, чтобы потом
+	// списывать продление без участия клиента (автопродление).
+	CtxKeySavePaymentMethod ctxKey = "yookasa.save_payment_method"
+	// ctxKeyPaidPaymentMethod — сохранённый способ оплаты из оплаченного платежа
+	// (кладут вебхук и поллер, читает обработка покупки).
+	ctxKeyPaidPaymentMethod ctxKey = "yookasa.paid_payment_method"
 )
 
+// WithPaidPaymentMethod добавляет в ctx сохранённый способ оплаты платежа, если он есть.
+func WithPaidPaymentMethod(ctx context.Context, p *Payment) context.Context {
+	if m, ok := p.SavedPaymentMethod(); ok {
+		return context.WithValue(ctx, ctxKeyPaidPaymentMethod, m)
+	}
+	return ctx
+}
+
+// WithPaidPaymentType восстанавливает в ctx способ оплаты, ранее прочитанный PaidPaymentMethodFromCtx
+// (повтор обработки покупки из outbox).
+func WithPaidPaymentType(ctx context.Context, m PaymentType) context.Context {
+	return context.WithValue(ctx, ctxKeyPaidPaymentMethod, m)
+}
+
+// PaidPaymentMethodFromCtx — способ оплаты, положенный WithPaidPaymentMethod.
+func PaidPaymentMethodFromCtx(ctx context.Context) (PaymentType, bool) {
+	m, ok := ctx.Value(ctxKeyPaidPaymentMethod).(PaymentType)
+	return m, ok
+}
+
 // returnURLFromCtx возвращает переопределение из контекста, либо пустую строку.
 func returnURLFromCtx(ctx context.Context) string {
 	if v, ok := ctx.Value(CtxKeyReturnURL).(string); ok {
@@ -83,6 +111,64 @@ func NewClient(baseURL, shopID, secretKey string) *Client {
 }
 
 func (c *Client) CreateInvoice(ctx context.Context, amount int, description string, customerId int64, purchaseId int64) (*Payment, error) {
+	rub, receipt := rubAmountAndReceipt(ctx, amount, description)
+	metaData := paymentMetadata(ctx, customerId, purchaseId)
+
+	returnURL := returnURLFromCtx(ctx)
+	if returnURL == "" {
+		returnURL = config.BotURL()
+	}
+
+	paymentRequest := NewPaymentRequest(
+		rub,
+		returnURL,
+		description,
+		receipt,
+		metaData,
+	)
+	if save, _ := ctx.Value(CtxKeySavePaymentMethod).(bool); save {
+		paymentRequest.SavePaymentMethod = true
+	}
+
+	idempotencyKey := idempotenceKeyFromCtx(ctx)
+	if idempotencyKey == "" {
+		idempotencyKey = uuid.New().String()
+	}
+
+	payment, err := c.CreatePayment(ctx, paymentRequest, idempotencyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payment: %w", err)
+	}
+
+	return payment, nil
+}
+
+// CreateRecurringPayment списывает сумму с сохранённого способа оплаты без подтверждения клиентом.
+// Ответ обычно уже succeeded или canceled (cancellation_details), реже — pending.
+func (c *Client) CreateRecurringPayment(ctx context.Context, amount int, description string, customerId int64, purchaseId int64, paymentMethodID uuid.UUID) (*Payment, error) {
+	rub, receipt := rubAmountAndReceipt(ctx, amount, description)
+	request := PaymentRequest{
+		Amount:          rub,
+		Capture:         true,
+		Description:     description,
+		PaymentMethodID: &paymentMethodID,
+		Receipt:         receipt,
+		Metadata:        paymentMetadata(ctx, customerId, purchaseId),
+	}
+
+	idempotencyKey := idempotenceKeyFromCtx(ctx)
+	if idempotencyKey == "" {
+		idempotencyKey = fmt.Sprintf("autorenew-%d", purchaseId)
+	}
+
+	payment, err := c.CreatePayment(ctx, request, idempotencyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create recurring payment: %w", err)
+	}
+	return payment, nil
+}
+
+func rubAmountAndReceipt(ctx context.Context, amount int, description string) (Amount, *Receipt) {
 	rub := Amount{
 		Value:    strconv.Itoa(amount),
 		Currency: "RUB",
@@ -105,38 +191,16 @@ func (c *Client) CreateInvoice(ctx context.Context, amount int, description stri
 			},
 		},
 	}
+	return rub, receipt
+}
 
+func paymentMetadata(ctx context.Context, customerId int64, purchaseId int64) map[string]any {
 	username, _ := ctx.Value(remnawave.CtxKeyUsername).(string)
-	metaData := map[string]any{
+	return map[string]any{
 		"customerId": customerId,
 		"purchaseId": purchaseId,
 		"username":   username,
 	}
-
-	returnURL := returnURLFromCtx(ctx)
-	if returnURL == "" {
-		returnURL = config.BotURL()
-	}
-
-	paymentRequest := NewPaymentRequest(
-		rub,
-		returnURL,
-		description,
-		receipt,
-		metaData,
-	)
-
-	idempotencyKey := idempotenceKeyFromCtx(ctx)
-	if idempotencyKey == "" {
-		idempotencyKey = uuid.New().String()
-	}
-
-	payment, err := c.CreatePayment(ctx, paymentRequest, idempotencyKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create payment: %w", err)
-	}
-
-	return payment, nil
 }
 
 func (c *Client) CreatePayment(ctx context.Context, request PaymentRequest, idempotencyKey string) (*Payment, error) {
@@ -224,3 +288,99 @@ func (c *Client) GetPayment(ctx context.Context, paymentID uuid.UUID) (*Payment,
 
 	return nil, fmt.Errorf("exceeded maximum retries due to 429 Too Many Requests")
 }
+
+// findPaymentPagesMax — сколько страниц списка платежей (по 100) просматривает FindPaymentByPurchase.
+const findPaymentPagesMax = 10
+
+type paymentList struct {
+	Items      []Payment `json:"items"`
+	NextCursor string    `json:"next_cursor"`
+}
+
+// FindPaymentByPurchase ищет платёж покупки по metadata.purchaseId среди платежей, созданных в [from, to].
+// Нужен, когда id платежа не удалось сохранить в покупку. nil, nil — платежа нет.
+func (c *Client) FindPaymentByPurchase(ctx context.Context, purchaseId int64, from, to time.Time) (*Payment, error) {
+	want := strconv.FormatInt(purchaseId, 10)
+	cursor := ""
+	for page := 0; page < findPaymentPagesMax; page++ {
+		q := url.Values{}
+		q.Set("created_at.gte", from.UTC().Format("2006-01-02T15:04:05.000Z"))
+		q.Set("created_at.lte", to.UTC().Format("2006-01-02T15:04:05.000Z"))
+		q.Set("limit", "100")
+		if cursor != "" {
+			q.Set("cursor", cursor)
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/payments?"+q.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Authorization", c.authHeader)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send request: %w", err)
+		}
+		var list paymentList
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, fmt.Errorf("API return error. Status: %d, Body: %s", resp.StatusCode, string(body))
+		}
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		for i := range list.Items {
+			if list.Items[i].Metadata["purchaseId"] == want {
+				return &list.Items[i], nil
+			}
+		}
+		if list.NextCursor == "" {
+			return nil, nil
+		}
+		cursor = list.NextCursor
+	}
+	return nil, fmt.Errorf("payment of purchase %d not found in %d pages", purchaseId, findPaymentPagesMax)
+}
+
+// CreateRefund создаёт возврат по платежу (полный или частичный — сумма в request.Amount).
+// idempotencyKey обязателен: повтор с тем же ключом не создаёт второй возврат.
+func (c *Client) CreateRefund(ctx context.Context, request RefundRequest, idempotencyKey string) (*Refund, error) {
+	refundURL := fmt.Sprintf("%s/refunds", c.baseURL)
+
+	reqBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal refund request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", refundURL, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", c.authHeader)
+	req.Header.Set("Idempotence-Key", idempotencyKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("error while reading refund resp: %w", err)
+		}
+		return nil, fmt.Errorf("API return error. Status: %d, Body: %s", resp.StatusCode, string(body))
+	}
+
+	var refund Refund
+	if err := json.NewDecoder(resp.Body).Decode(&refund); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &refund, nil
+}
@@ -21,20 +21,40 @@ type Payment struct {
 	Refundable    bool              `json:"refundable,omitempty"`
 	Test          bool              `json:"test,omitempty"`
 	RedirectURL   string            `json:"redirect_url,omitempty"`
+	// CancellationDetails — причина отмены (для автосписаний: insufficient_funds, permission_revoked и т.д.).
+	CancellationDetails *CancellationDetails `json:"cancellation_details,omitempty"`
 }
 
 func (p *Payment) IsCancelled() bool {
 	return p.Status == "canceled"
 }
 
+// SavedPaymentMethod — способ оплаты, сохранённый для автосписаний (save_payment_method=true), если ЮKassa его сохранила.
+func (p *Payment) SavedPaymentMethod() (PaymentType, bool) {
+	if p == nil || !p.Paid || !p.PaymentMethod.Saved || p.PaymentMethod.ID == uuid.Nil {
+		return PaymentType{}, false
+	}
+	return p.PaymentMethod, true
+}
+
+type CancellationDetails struct {
+	Party  string `json:"party"`
+	Reason string `json:"reason"`
+}
+
+// PermissionRevoked — клиент отозвал разрешение на автосписания: сохранённый способ больше не годится.
+func (d *CancellationDetails) PermissionRevoked() bool {
+	return d != nil && (d.Reason == "permission_revoked" || d.Reason == "payment_method_restricted")
+}
+
 type PaymentRequest struct {
 	Amount            Amount             `json:"amount"`
-	Confirmation      ConfirmationType   `json:"confirmation"`
+	Confirmation      *ConfirmationType  `json:"confirmation,omitempty"`
 	Capture           bool               `json:"capture"`
 	Description       string             `json:"description,omitempty"`
 	PaymentMethodData *PaymentMethodData `json:"payment_method_data,omitempty"`
 	SavePaymentMethod bool               `json:"save_payment_method"`
-	PaymentMethodID   *uuid.UUID         `json:"payment_method_id"`
+	PaymentMethodID   *uuid.UUID         `json:"payment_method_id,omitempty"`
 	Receipt           *Receipt           `json:"receipt,omitempty"`
 	Metadata          map[string]any     `json:"metadata,omitempty"`
 }
@@ -49,7 +69,7 @@ func NewPaymentRequest(
 		Amount:   amount,
 		Receipt:  receipt,
 		Metadata: metadata,
-		Confirmation: ConfirmationType{
+		Confirmation: &ConfirmationType{
 			Type:      "redirect",
 			ReturnURL: urlRedirect,
 		},
@@ -101,4 +121,31 @@ type PaymentType struct {
 	Type  string    `json:"type,omitempty"`
 	ID    uuid.UUID `json:"id,omitempty"`
 	Saved bool      `json:"saved,omitempty"`
+	// Title — подпись способа, например «Bank card *4444».
+	Title string `json:"title,omitempty"`
+}
+
+// RefundRequest — тело POST /refunds.
+type RefundRequest struct {
+	PaymentID   uuid.UUID `json:"payment_id"`
+	Amount      Amount    `json:"amount"`
+	Description string    `json:"description,omitempty"`
+}
+
+type Refund struct {
+	ID          uuid.UUID `json:"id"`
+	PaymentID   uuid.UUID `json:"payment_id"`
+	Status      string    `json:"status"`
+	Amount      Amount    `json:"amount"`
+	CreatedAt   time.Time `json:"created_at"`
+	Description string    `json:"description,omitempty"`
+}
+
+// IsSucceeded — возврат проведён (status=succeeded). pending тоже не ошибка: ЮKassa досписывает асинхронно.
+func (r *Refund) IsSucceeded() bool {
+	return r.Status == "succeeded"
+}
+
+func (r *Refund) IsCanceled() bool {
+	return r.Status == "canceled"
 }
@@ -129,7 +129,7 @@ func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if payment.Metadata != nil {
 		username = payment.Metadata["username"]
 	}
-	ctxWithUsername := context.WithValue(ctx, remnawave.CtxKeyUsername, username)
+	ctxWithUsername := WithPaidPaymentMethod(context.WithValue(ctx, remnawave.CtxKeyUsername, username), payment)
 	if err := h.processor.ProcessPurchaseById(ctxWithUsername, purchaseID); err != nil {
 		slog.Error("yookassa webhook: process purchase failed", "purchase_id", purchaseID, "error", err)
 		http.Error(w, "internal error", http.StatusInternalServerError)
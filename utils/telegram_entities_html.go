@@ -1,4 +1,4 @@
-package handler
+package utils
 
 import (
 	"html"
@@ -9,8 +9,8 @@ import (
 	"github.com/go-telegram/bot/models"
 )
 
-// utf16UnitsLen returns the number of UTF-16 code units in s (Telegram Bot API offsets use UTF-16).
-func utf16UnitsLen(s string) int {
+// UTF16UnitsLen returns the number of UTF-16 code units in s (Telegram Bot API offsets use UTF-16).
+func UTF16UnitsLen(s string) int {
 	return len(utf16.Encode([]rune(s)))
 }
 
@@ -50,9 +50,9 @@ func nestedInside(parent models.MessageEntity, all []models.MessageEntity) []mod
 	return out
 }
 
-// messageEntitiesToHTML converts Telegram message text + entities to HTML (ParseMode HTML).
+// MessageEntitiesToHTML converts Telegram message text + entities to HTML (ParseMode HTML).
 // Entity offsets and lengths are UTF-16 code units per Bot API.
-func messageEntitiesToHTML(text string, entities []models.MessageEntity) string {
+func MessageEntitiesToHTML(text string, entities []models.MessageEntity) string {
 	u16 := utf16.Encode([]rune(text))
 	valid := filterMessageEntities(len(u16), entities)
 	if len(valid) == 0 {
@@ -1,4 +1,4 @@
-package handler
+package utils
 
 import (
 	"testing"
@@ -7,14 +7,14 @@ import (
 )
 
 func TestMessageEntitiesToHTML_plainEscapes(t *testing.T) {
-	got := messageEntitiesToHTML(`a < b`, nil)
+	got := MessageEntitiesToHTML(`a < b`, nil)
 	if want := "a &lt; b"; got != want {
 		t.Fatalf("got %q want %q", got, want)
 	}
 }
 
 func TestMessageEntitiesToHTML_bold(t *testing.T) {
-	got := messageEntitiesToHTML("Hi", []models.MessageEntity{
+	got := MessageEntitiesToHTML("Hi", []models.MessageEntity{
 		{Type: models.MessageEntityTypeBold, Offset: 0, Length: 2},
 	})
 	if want := "<b>Hi</b>"; got != want {
@@ -23,7 +23,7 @@ func TestMessageEntitiesToHTML_bold(t *testing.T) {
 }
 
 func TestMessageEntitiesToHTML_nestedBoldItalic(t *testing.T) {
-	got := messageEntitiesToHTML("hello world", []models.MessageEntity{
+	got := MessageEntitiesToHTML("hello world", []models.MessageEntity{
 		{Type: models.MessageEntityTypeBold, Offset: 0, Length: 11},
 		{Type: models.MessageEntityTypeItalic, Offset: 6, Length: 5},
 	})
@@ -34,7 +34,7 @@ func TestMessageEntitiesToHTML_nestedBoldItalic(t *testing.T) {
 
 func TestMessageEntitiesToHTML_emojiSurrogateBold(t *testing.T) {
 	s := "a😀b"
-	got := messageEntitiesToHTML(s, []models.MessageEntity{
+	got := MessageEntitiesToHTML(s, []models.MessageEntity{
 		{Type: models.MessageEntityTypeBold, Offset: 1, Length: 2},
 	})
 	if want := "a<b>😀</b>b"; got != want {
@@ -44,7 +44,7 @@ func TestMessageEntitiesToHTML_emojiSurrogateBold(t *testing.T) {
 
 func TestMessageEntitiesToHTML_customEmoji(t *testing.T) {
 	s := "x🙂y"
-	got := messageEntitiesToHTML(s, []models.MessageEntity{
+	got := MessageEntitiesToHTML(s, []models.MessageEntity{
 		{Type: models.MessageEntityTypeCustomEmoji, Offset: 1, Length: 2, CustomEmojiID: "5447410659077661506"},
 	})
 	if want := `x<tg-emoji emoji-id="5447410659077661506">🙂</tg-emoji>y`; got != want {
@@ -21,6 +21,7 @@ export interface AdminPromoCode {
   created_at: string
   discount_max_subscription_payments_per_customer: number
   tariff_id?: number | null
+  segment_id?: number | null
 }
 
 interface PromoListResponse {
@@ -48,6 +49,7 @@ export interface CreatePromoInput {
   valid_until?: string | null
   first_purchase_only?: boolean
   tariff_id?: number | null
+  segment_id?: number | null
   discount_max_subscription_payments_per_customer?: number
 }
 
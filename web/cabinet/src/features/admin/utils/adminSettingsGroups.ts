@@ -12,6 +12,7 @@ import {
   Megaphone,
   MessageSquare,
   Package,
+  Pause,
   Percent,
   RefreshCw,
   Scale,
@@ -36,6 +37,7 @@ export const ADMIN_SETTINGS_GROUP_ORDER = [
   'tariffs',
   'trial',
   'hwid',
+  'pause',
   'referral',
   'stars',
   'loyalty',
@@ -53,6 +55,7 @@ export const ADMIN_SETTINGS_GROUP_ICONS: Record<AdminSettingsGroupId, LucideIcon
   trial: Gift,
   tariffs: BadgeRussianRuble,
   hwid: Smartphone,
+  pause: Pause,
   referral: Users,
   stars: Star,
   loyalty: Gem,
@@ -74,6 +77,7 @@ export const ADMIN_SETTINGS_GROUP_ICON_STYLES: Record<AdminSettingsGroupId, Admi
   trial: { box: 'bg-emerald-500/10 dark:bg-emerald-500/20', icon: 'text-emerald-500' },
   tariffs: { box: 'bg-lime-500/10 dark:bg-lime-500/20', icon: 'text-lime-600 dark:text-lime-400' },
   hwid: { box: 'bg-cyan-500/10 dark:bg-cyan-500/20', icon: 'text-cyan-500' },
+  pause: { box: 'bg-slate-500/10 dark:bg-slate-500/20', icon: 'text-slate-500' },
   referral: { box: 'bg-violet-500/10 dark:bg-violet-500/20', icon: 'text-violet-500' },
   stars: { box: 'bg-amber-500/10 dark:bg-amber-500/20', icon: 'text-amber-500' },
   loyalty: { box: 'bg-teal-500/10 dark:bg-teal-500/20', icon: 'text-teal-500' },
@@ -126,7 +130,7 @@ export const ADMIN_SETTINGS_CATEGORIES: AdminSettingsCategoryDef[] = [
     id: 'product',
     titleKey: 'admin.settings.categories.product',
     icon: Package,
-    groups: ['tariffs', 'trial', 'hwid', 'stars'],
+    groups: ['tariffs', 'trial', 'hwid', 'pause', 'stars'],
     iconStyle: { box: 'bg-emerald-500/10 dark:bg-emerald-500/20', icon: 'text-emerald-500' },
   },
   {
@@ -321,6 +325,12 @@ export const ADMIN_SETTINGS_SUBSECTIONS: Partial<Record<AdminSettingsGroupId, Ad
       icon: Link2,
       keys: ['LIFECYCLE_VIDEO_GUIDE_URL', 'LIFECYCLE_SUPPORT_CONTACT'],
     },
+    {
+      id: 'traffic',
+      titleKey: 'admin.settings.subsections.lifecycle.traffic',
+      icon: Gauge,
+      keys: ['TRAFFIC_NOTIFY_ENABLED', 'TRAFFIC_NOTIFY_THRESHOLDS'],
+    },
   ],
   fortune: [
     {
@@ -9,7 +9,7 @@
  * - При неудаче refresh — стор сбрасывает сессию
  */
 
-import { getCookie } from './utils'
+import { getCookie, getRegistrationUTM } from './utils'
 import type {
   AdminBootstrapDTO,
   AdminBroadcastAudienceDTO,
@@ -725,17 +725,20 @@ export const api = {
       turnstileToken ? { 'X-Turnstile-Token': turnstileToken } : undefined,
     ),
 
-  register: (email: string, password: string, referralCode?: string, turnstileToken?: string) =>
-    request<{ message?: string }>(
+  register: (email: string, password: string, referralCode?: string, turnstileToken?: string) => {
+    const utm = getRegistrationUTM()
+    return request<{ message?: string }>(
       'POST',
       '/auth/register',
       {
         email,
         password,
         ...(referralCode ? { referral_code: referralCode } : {}),
+        ...(utm ? { utm } : {}),
       },
       turnstileToken ? { 'X-Turnstile-Token': turnstileToken } : undefined,
-    ),
+    )
+  },
 
   logout: () =>
     request<void>('POST', '/auth/logout'),
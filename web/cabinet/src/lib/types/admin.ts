@@ -3,10 +3,63 @@
  * Синхронизированы с Go handlers в internal/cabinet/http/handlers/admin_*.go
  */
 
+export type StaffRole = 'owner' | 'admin' | 'support' | 'marketer'
+
+export type StaffPermission =
+  | 'users_read'
+  | 'users_write'
+  | 'payments'
+  | 'broadcasts'
+  | 'promos'
+  | 'tariffs'
+  | 'settings'
+  | 'infra'
+  | 'stats'
+  | 'audit'
+
 export interface AdminBootstrapDTO {
   sales_mode: string
   loyalty_enabled: boolean
   fortune_enabled: boolean
+  /** Роль и права сотрудника — недоступные разделы API отвечают 403. */
+  role: StaffRole
+  permissions: StaffPermission[]
+}
+
+export interface AdminStaffMemberDTO {
+  telegram_id: number
+  role: StaffRole
+  permissions: StaffPermission[]
+  note: string
+}
+
+export interface AdminStaffListDTO {
+  owner_telegram_id: number
+  members: AdminStaffMemberDTO[]
+  permissions: StaffPermission[]
+  role_presets: Record<StaffRole, StaffPermission[]>
+}
+
+/** Запись журнала действий сотрудников; before/after — только изменившиеся поля. */
+export interface AdminAuditEntryDTO {
+  id: number
+  created_at: string
+  source: 'bot' | 'cabinet'
+  actor_telegram_id: number | null
+  actor_account_id: number | null
+  action: string
+  customer_id: number | null
+  target_type: string
+  target_id: string
+  before: Record<string, unknown> | null
+  after: Record<string, unknown> | null
+}
+
+export interface AdminAuditListDTO {
+  items: AdminAuditEntryDTO[]
+  total: number
+  limit: number
+  offset: number
 }
 
 export interface AdminStatsDTO {
@@ -233,6 +286,7 @@ export interface AdminPromoCodeDTO {
   created_at: string
   discount_max_subscription_payments_per_customer: number
   tariff_id?: number | null
+  segment_id?: number | null
 }
 
 export interface AdminPromoListDTO {
@@ -289,17 +343,84 @@ export interface AdminTariffDTO {
 
 export interface AdminBroadcastPreviewDTO {
   recipient_count: number
+  segment_count?: AdminSegmentCountDTO
   status?: string
 }
 
+export type AdminSegmentChannel = 'telegram' | 'web'
+
+export interface AdminSegmentFilter {
+  audience?: string
+  languages?: string[]
+  tariff_ids?: number[]
+  expires_within_days?: number
+  expired_more_than_days?: number
+  loyalty_tier_ids?: number[]
+  paid_purchases_min?: number
+  paid_purchases_max?: number
+  referrals_min?: number
+  referrals_max?: number
+  acquisition_sources?: string[]
+  online_within_days?: number
+  offline_more_than_days?: number
+  channel?: AdminSegmentChannel
+}
+
+export interface AdminSegmentCountDTO {
+  total: number
+  telegram: number
+  /** web-only клиенты, которым рассылка уйдёт на email */
+  email: number
+}
+
+export interface AdminSegmentDTO {
+  id: number
+  name: string
+  filter: AdminSegmentFilter
+  count?: AdminSegmentCountDTO
+  created_at: string
+  updated_at: string
+}
+
 export interface AdminBroadcastMediaDTO {
   file_id: string
   as_photo: boolean
 }
 
+export type AdminBroadcastJobStatus = 'scheduled' | 'running' | 'paused' | 'canceled' | 'done'
+
+export interface AdminBroadcastJobDTO {
+  id: number
+  status: AdminBroadcastJobStatus
+  source: 'bot' | 'cabinet'
+  audience: string
+  tariff_id: number | null
+  segment_id: number | null
+  segment: AdminSegmentFilter | null
+  text: string
+  has_media: boolean
+  buttons: { buy?: boolean; main_menu?: boolean; promo?: boolean; connect?: boolean }
+  scheduled_at: string
+  started_at: string | null
+  finished_at: string | null
+  total_count: number
+  sent_count: number
+  failed_count: number
+  pending_count: number
+  created_at: string
+}
+
+export interface AdminBroadcastJobListDTO {
+  items: AdminBroadcastJobDTO[]
+  total: number
+  limit: number
+  offset: number
+}
+
 export interface AdminBroadcastSendDTO {
-  status: string
+  status: 'started' | 'scheduled'
   recipient_count: number
+  job: AdminBroadcastJobDTO
 }
 
 export interface AdminBroadcastAudienceDTO {
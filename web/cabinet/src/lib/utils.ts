@@ -113,6 +113,20 @@ export function getTelegramMiniAppStartParam(): string {
   return readUrlParamFromHashOrSearch('tgWebAppStartParam').trim()
 }
 
+const UTM_KEYS = ['utm_source', 'utm_medium', 'utm_campaign', 'utm_content', 'utm_term'] as const
+
+/**
+ * utm_* из адреса страницы регистрации — источник привлечения (сохраняется на бэкенде один раз).
+ */
+export function getRegistrationUTM(): Record<string, string> | undefined {
+  const out: Record<string, string> = {}
+  for (const key of UTM_KEYS) {
+    const v = readUrlParamFromHashOrSearch(key).trim()
+    if (v) out[key] = v
+  }
+  return Object.keys(out).length > 0 ? out : undefined
+}
+
 function readUrlParamFromHashOrSearch(name: string): string {
   if (typeof window === 'undefined') return ''
   const fromSearch = new URLSearchParams(window.location.search).get(name)